@@ -0,0 +1,285 @@
+// Package apikey issues and verifies api keys for machine to machine and
+// partner access. Only a hash of the secret of a key is stored so the key
+// is only known when issued.
+package apikey
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/micro/go-micro/v2/auth"
+	"github.com/micro/go-micro/v2/store"
+	"github.com/micro/go-micro/v2/store/memory"
+)
+
+const (
+	// KeyPrefix of the api keys
+	KeyPrefix = "mk_"
+	// AccountType of the accounts of the keys
+	AccountType = "apikey"
+)
+
+var (
+	// ErrInvalidKey is returned when a key is malformed or unknown
+	ErrInvalidKey = errors.New("invalid api key")
+	// ErrExpired is returned when a key has expired
+	ErrExpired = errors.New("api key expired")
+	// ErrRevoked is returned when a key has been revoked
+	ErrRevoked = errors.New("api key revoked")
+	// ErrNotFound is returned when a key doesn't exist
+	ErrNotFound = errors.New("api key not found")
+
+	// DefaultPrefix of the keys of the records
+	DefaultPrefix = "apikey/"
+	// LastUsedInterval is the interval the last use of a key is recorded at
+	// so verifying a key doesn't write to the store every request
+	LastUsedInterval = time.Minute
+)
+
+// Key is an issued api key, without its secret
+type Key struct {
+	ID       string            `json:"id"`
+	Name     string            `json:"name"`
+	Scopes   []string          `json:"scopes,omitempty"`
+	Metadata map[string]string `json:"metadata,omitempty"`
+	Created  time.Time         `json:"created"`
+	// Expiry of the key, never if zero
+	Expiry   time.Time `json:"expiry,omitempty"`
+	LastUsed time.Time `json:"last_used,omitempty"`
+	// Revoked keys are kept so they're listed
+	Revoked time.Time `json:"revoked,omitempty"`
+	// Hash of the secret
+	Hash string `json:"hash"`
+}
+
+// Account of the key
+func (k *Key) Account() *auth.Account {
+	md := map[string]string{"name": k.Name}
+	for key, v := range k.Metadata {
+		md[key] = v
+	}
+	return &auth.Account{
+		ID:       k.ID,
+		Type:     AccountType,
+		Scopes:   k.Scopes,
+		Metadata: md,
+	}
+}
+
+// Manager issues and verifies api keys
+type Manager interface {
+	// Issue a key, returning the key to hand out
+	Issue(name string, opts ...IssueOption) (string, *Key, error)
+	// Verify a key and record its use
+	Verify(key string) (*Key, error)
+	// Revoke a key by id
+	Revoke(id string) error
+	// Get a key by id
+	Get(id string) (*Key, error)
+	// List the keys including the revoked ones
+	List() ([]*Key, error)
+}
+
+type manager struct {
+	opts Options
+}
+
+// NewManager returns a manager keeping the keys in the store, a memory
+// store by default
+func NewManager(opts ...Option) Manager {
+	options := Options{
+		Prefix: DefaultPrefix,
+	}
+	for _, o := range opts {
+		o(&options)
+	}
+	if options.Store == nil {
+		options.Store = memory.NewStore()
+	}
+	return &manager{opts: options}
+}
+
+func random(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func hash(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+// parse the id and secret of a key
+func parse(key string) (string, string, error) {
+	if !strings.HasPrefix(key, KeyPrefix) {
+		return "", "", ErrInvalidKey
+	}
+	parts := strings.SplitN(strings.TrimPrefix(key, KeyPrefix), ".", 2)
+	if len(parts) != 2 || len(parts[0]) == 0 || len(parts[1]) == 0 {
+		return "", "", ErrInvalidKey
+	}
+	return parts[0], parts[1], nil
+}
+
+func (m *manager) Issue(name string, opts ...IssueOption) (string, *Key, error) {
+	var options IssueOptions
+	for _, o := range opts {
+		o(&options)
+	}
+
+	// the id is url safe base64 which has no dots
+	id, err := random(12)
+	if err != nil {
+		return "", nil, err
+	}
+	secret, err := random(32)
+	if err != nil {
+		return "", nil, err
+	}
+
+	k := &Key{
+		ID:       id,
+		Name:     name,
+		Scopes:   options.Scopes,
+		Metadata: options.Metadata,
+		Created:  time.Now(),
+		Expiry:   options.Expiry,
+		Hash:     hash(secret),
+	}
+	if err := m.save(k); err != nil {
+		return "", nil, err
+	}
+
+	return KeyPrefix + id + "." + secret, k, nil
+}
+
+func (m *manager) save(k *Key) error {
+	b, err := json.Marshal(k)
+	if err != nil {
+		return err
+	}
+	return m.opts.Store.Write(&store.Record{Key: m.opts.Prefix + k.ID, Value: b})
+}
+
+func (m *manager) Get(id string) (*Key, error) {
+	k, _, err := m.read(id)
+	return k, err
+}
+
+// read the key and its encoded value
+func (m *manager) read(id string) (*Key, []byte, error) {
+	recs, err := m.opts.Store.Read(m.opts.Prefix + id)
+	if err == store.ErrNotFound || (err == nil && len(recs) == 0) {
+		return nil, nil, ErrNotFound
+	} else if err != nil {
+		return nil, nil, err
+	}
+	k := new(Key)
+	if err := json.Unmarshal(recs[0].Value, k); err != nil {
+		return nil, nil, err
+	}
+	return k, recs[0].Value, nil
+}
+
+// used records the use of the key unless it changed since read e.g it was
+// revoked. The use isn't recorded in the stores which can't compare and swap
+// since writing the key read could undo a revoke.
+func (m *manager) used(k *Key, old []byte) {
+	b, err := json.Marshal(k)
+	if err != nil {
+		return
+	}
+	store.CompareAndSwap(m.opts.Store, &store.Record{Key: m.opts.Prefix + k.ID, Value: b}, old)
+}
+
+func (m *manager) Verify(key string) (*Key, error) {
+	id, secret, err := parse(key)
+	if err != nil {
+		return nil, err
+	}
+
+	k, old, err := m.read(id)
+	if err == ErrNotFound {
+		return nil, ErrInvalidKey
+	} else if err != nil {
+		return nil, err
+	}
+
+	if subtle.ConstantTimeCompare([]byte(hash(secret)), []byte(k.Hash)) != 1 {
+		return nil, ErrInvalidKey
+	}
+	if !k.Revoked.IsZero() {
+		return nil, ErrRevoked
+	}
+
+	now := time.Now()
+	if !k.Expiry.IsZero() && !now.Before(k.Expiry) {
+		return nil, ErrExpired
+	}
+
+	if now.Sub(k.LastUsed) >= LastUsedInterval {
+		k.LastUsed = now
+		// the use is best effort, the key is valid regardless
+		m.used(k, old)
+	}
+
+	return k, nil
+}
+
+func (m *manager) Revoke(id string) error {
+	for {
+		k, old, err := m.read(id)
+		if err != nil {
+			return err
+		}
+		if !k.Revoked.IsZero() {
+			return nil
+		}
+		k.Revoked = time.Now()
+
+		b, err := json.Marshal(k)
+		if err != nil {
+			return err
+		}
+		r := &store.Record{Key: m.opts.Prefix + k.ID, Value: b}
+
+		// retry if the key changed since read e.g its use was recorded
+		switch err := store.CompareAndSwap(m.opts.Store, r, old); err {
+		case nil:
+			return nil
+		case store.ErrConflict:
+			continue
+		case store.ErrCASNotSupported:
+			return m.opts.Store.Write(r)
+		default:
+			return err
+		}
+	}
+}
+
+func (m *manager) List() ([]*Key, error) {
+	recs, err := m.opts.Store.Read(m.opts.Prefix, store.ReadPrefix())
+	if err != nil && err != store.ErrNotFound {
+		return nil, err
+	}
+
+	keys := make([]*Key, 0, len(recs))
+	for _, r := range recs {
+		k := new(Key)
+		if err := json.Unmarshal(r.Value, k); err != nil {
+			return nil, err
+		}
+		keys = append(keys, k)
+	}
+	return keys, nil
+}
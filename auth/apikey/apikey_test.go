@@ -0,0 +1,154 @@
+package apikey
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/micro/go-micro/v2/auth"
+	"github.com/micro/go-micro/v2/metadata"
+	"github.com/micro/go-micro/v2/server"
+	"github.com/micro/go-micro/v2/store"
+	"github.com/micro/go-micro/v2/store/memory"
+)
+
+type testRequest struct {
+	server.Request
+}
+
+func (r testRequest) Service() string {
+	return "go.micro.srv.notes"
+}
+
+func (r testRequest) Endpoint() string {
+	return "Notes.List"
+}
+
+func TestManager(t *testing.T) {
+	m := NewManager()
+
+	key, k, err := m.Issue("ci", Scopes("notes.read"), Metadata(map[string]string{"team": "infra"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	v, err := m.Verify(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v.ID != k.ID || v.LastUsed.IsZero() {
+		t.Fatalf("Expected the use of key %v to be recorded, got %+v", k.ID, v)
+	}
+	if v, _ := m.Get(k.ID); v.LastUsed.IsZero() {
+		t.Fatal("Expected the last use to be stored")
+	}
+	acc := v.Account()
+	if acc.Type != AccountType || acc.Metadata["name"] != "ci" || acc.Metadata["team"] != "infra" {
+		t.Fatalf("Unexpected account %+v", acc)
+	}
+
+	if _, err := m.Verify(key + "x"); err != ErrInvalidKey {
+		t.Fatalf("Expected %v for the wrong secret, got %v", ErrInvalidKey, err)
+	}
+	if _, err := m.Verify("mk_missing.secret"); err != ErrInvalidKey {
+		t.Fatalf("Expected %v for an unknown key, got %v", ErrInvalidKey, err)
+	}
+	if _, err := m.Verify("bearer"); err != ErrInvalidKey {
+		t.Fatalf("Expected %v for a malformed key, got %v", ErrInvalidKey, err)
+	}
+
+	expired, _, err := m.Issue("old", ExpiresIn(-time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Verify(expired); err != ErrExpired {
+		t.Fatalf("Expected %v, got %v", ErrExpired, err)
+	}
+
+	if err := m.Revoke(k.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Verify(key); err != ErrRevoked {
+		t.Fatalf("Expected %v, got %v", ErrRevoked, err)
+	}
+	if err := m.Revoke("missing"); err != ErrNotFound {
+		t.Fatalf("Expected %v, got %v", ErrNotFound, err)
+	}
+
+	keys, err := m.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(keys) != 2 {
+		t.Fatalf("Expected the revoked and expired keys to be listed, got %d keys", len(keys))
+	}
+	for _, k := range keys {
+		if len(k.Hash) == 0 || k.Hash == key {
+			t.Fatalf("Expected only the hash of key %v to be stored", k.ID)
+		}
+	}
+}
+
+// noCASStore hides the compare and swap of the store
+type noCASStore struct {
+	store.Store
+}
+
+func TestManagerWithoutCAS(t *testing.T) {
+	m := NewManager(WithStore(noCASStore{memory.NewStore()}))
+
+	key, k, err := m.Issue("ci")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Verify(key); err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := m.Get(k.ID); !v.LastUsed.IsZero() {
+		t.Fatal("Expected the last use not to be stored without compare and swap")
+	}
+	if err := m.Revoke(k.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Verify(key); err != ErrRevoked {
+		t.Fatalf("Expected %v, got %v", ErrRevoked, err)
+	}
+}
+
+func TestHandlerWrapper(t *testing.T) {
+	m := NewManager()
+	key, k, err := m.Issue("ci")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var acc *auth.Account
+	var header string
+	h := NewHandlerWrapper(m)(func(ctx context.Context, req server.Request, rsp interface{}) error {
+		acc, _ = auth.AccountFromContext(ctx)
+		header, _ = metadata.Get(ctx, Header)
+		return nil
+	})
+
+	if err := h(metadata.Set(context.Background(), Header, key), testRequest{}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if acc == nil || acc.ID != k.ID {
+		t.Fatalf("Expected the account of key %v, got %+v", k.ID, acc)
+	}
+	if len(header) > 0 {
+		t.Fatal("Expected the key to be removed from the metadata")
+	}
+
+	acc = nil
+	if err := h(context.Background(), testRequest{}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if acc != nil {
+		t.Fatalf("Expected no account without a key, got %+v", acc)
+	}
+
+	if err := h(metadata.Set(context.Background(), Header, key+"x"), testRequest{}, nil); err == nil {
+		t.Fatal("Expected an invalid key to be rejected")
+	}
+}
@@ -0,0 +1,64 @@
+package apikey
+
+import (
+	"time"
+
+	"github.com/micro/go-micro/v2/store"
+)
+
+// Options of the manager
+type Options struct {
+	// Store the keys are kept in
+	Store store.Store
+	// Prefix of the keys of the records
+	Prefix string
+}
+
+type Option func(o *Options)
+
+// WithStore sets the store the keys are kept in
+func WithStore(s store.Store) Option {
+	return func(o *Options) {
+		o.Store = s
+	}
+}
+
+// WithPrefix sets the prefix of the keys of the records
+func WithPrefix(p string) Option {
+	return func(o *Options) {
+		o.Prefix = p
+	}
+}
+
+// IssueOptions of a key
+type IssueOptions struct {
+	// Scopes of the key
+	Scopes []string
+	// Expiry of the key, never if zero
+	Expiry time.Time
+	// Metadata of the key
+	Metadata map[string]string
+}
+
+type IssueOption func(o *IssueOptions)
+
+// Scopes of the key
+func Scopes(s ...string) IssueOption {
+	return func(o *IssueOptions) {
+		o.Scopes = s
+	}
+}
+
+// ExpiresIn sets the key to expire after the duration
+func ExpiresIn(d time.Duration) IssueOption {
+	return func(o *IssueOptions) {
+		o.Expiry = time.Now().Add(d)
+	}
+}
+
+// Metadata of the key e.g the partner it's issued to
+func Metadata(md map[string]string) IssueOption {
+	return func(o *IssueOptions) {
+		o.Metadata = md
+	}
+}
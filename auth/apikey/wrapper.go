@@ -0,0 +1,39 @@
+package apikey
+
+import (
+	"context"
+
+	"github.com/micro/go-micro/v2/auth"
+	"github.com/micro/go-micro/v2/errors"
+	"github.com/micro/go-micro/v2/metadata"
+	"github.com/micro/go-micro/v2/server"
+)
+
+// Header of the api key in the metadata of the requests
+const Header = "Micro-Api-Key"
+
+// NewHandlerWrapper authenticates the requests bearing an api key and sets
+// the account of the key in the context for the rbac wrapper. The requests
+// without a key are passed through so other auth can apply.
+func NewHandlerWrapper(m Manager) server.HandlerWrapper {
+	return func(h server.HandlerFunc) server.HandlerFunc {
+		return func(ctx context.Context, req server.Request, rsp interface{}) error {
+			key, ok := metadata.Get(ctx, Header)
+			if !ok || len(key) == 0 {
+				return h(ctx, req, rsp)
+			}
+
+			k, err := m.Verify(key)
+			if err != nil {
+				return errors.Unauthorized(req.Service(), "Unauthorized call made to %v:%v: %v", req.Service(), req.Endpoint(), err)
+			}
+
+			// the key isn't propagated to the calls made by the handler
+			md, _ := metadata.FromContext(ctx)
+			md.Delete(Header)
+			ctx = metadata.NewContext(ctx, md)
+
+			return h(auth.ContextWithAccount(ctx, k.Account()), req, rsp)
+		}
+	}
+}
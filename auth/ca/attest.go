@@ -0,0 +1,59 @@
+package ca
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"strings"
+
+	"github.com/micro/go-micro/v2/auth"
+)
+
+// ErrNotAttested is returned when no attestor accepts the credential
+var ErrNotAttested = errors.New("credential not attested")
+
+// Attestor verifies the credential of a service and returns the path of its
+// spiffe id e.g /service/go.micro.srv.notes
+type Attestor interface {
+	Attest(ctx context.Context, credential string) (string, error)
+}
+
+type bootstrapTokens map[string]string
+
+// BootstrapTokens attests the services by the tokens they're deployed with.
+// The tokens are the keys of the map and the services their values. The
+// tokens are reusable so they should be rotated with the deployments.
+func BootstrapTokens(tokens map[string]string) Attestor {
+	return bootstrapTokens(tokens)
+}
+
+func (b bootstrapTokens) Attest(ctx context.Context, credential string) (string, error) {
+	for token, service := range b {
+		if subtle.ConstantTimeCompare([]byte(token), []byte(credential)) == 1 {
+			return "/service/" + service, nil
+		}
+	}
+	return "", ErrNotAttested
+}
+
+type authAttestor struct {
+	auth auth.Auth
+}
+
+// AuthAttestor attests the services by a token the auth inspects e.g the
+// identity token of a cloud provider or kubernetes with the oidc auth. Only
+// the service accounts are attested and the path is /account/ followed by
+// the id of the account so they can't claim the id of a bootstrapped service.
+func AuthAttestor(a auth.Auth) Attestor {
+	return &authAttestor{auth: a}
+}
+
+func (a *authAttestor) Attest(ctx context.Context, credential string) (string, error) {
+	acc, err := a.auth.Inspect(credential)
+	if err != nil || acc == nil || len(acc.ID) == 0 || acc.Type != "service" {
+		return "", ErrNotAttested
+	}
+	// kubernetes service accounts e.g system:serviceaccount:ns:name are
+	// mapped to path segments
+	return "/account/" + strings.Replace(acc.ID, ":", "/", -1), nil
+}
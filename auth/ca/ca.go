@@ -0,0 +1,286 @@
+// Package ca is a certificate authority issuing short lived certificates to
+// the services of a fleet so mutual tls doesn't need an external PKI. The
+// services are attested by a bootstrap token or an identity token when first
+// issued a certificate and renew it with proof of the current one.
+package ca
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/url"
+	"time"
+
+	"github.com/micro/go-micro/v2/auth/mtls"
+	"github.com/micro/go-micro/v2/store"
+)
+
+var (
+	// DefaultTrustDomain of the spiffe ids issued
+	DefaultTrustDomain = "micro"
+	// DefaultTTL of the certificates issued
+	DefaultTTL = time.Hour
+	// DefaultRootTTL of the generated roots
+	DefaultRootTTL = 10 * 365 * 24 * time.Hour
+
+	// rootKey is the key of the generated root in the store
+	rootKey = "ca/root"
+)
+
+// CA issues certificates to the services
+type CA interface {
+	// Sign the certificate request of a service
+	Sign(ctx context.Context, req *Request) ([]*x509.Certificate, error)
+	// Roots trusted by the services
+	Roots() []*x509.Certificate
+}
+
+// Request for a certificate
+type Request struct {
+	// CSR is the DER encoded certificate request
+	CSR []byte
+	// Credential attesting the service when first issued a certificate
+	Credential string
+	// Chain of the current certificate of the service to renew it
+	Chain []*x509.Certificate
+	// Proof is the signature of the CSR with the key of the current
+	// certificate
+	Proof []byte
+}
+
+type ca struct {
+	opts  Options
+	roots *x509.CertPool
+}
+
+// NewCA returns a CA with the root of the options or a generated one
+func NewCA(opts ...Option) (CA, error) {
+	options := Options{
+		TrustDomain: DefaultTrustDomain,
+		TTL:         DefaultTTL,
+	}
+	for _, o := range opts {
+		o(&options)
+	}
+
+	if options.Certificate == nil || options.Key == nil {
+		cert, key, err := loadRoot(options)
+		if err != nil {
+			return nil, err
+		}
+		options.Certificate, options.Key = cert, key
+	}
+
+	roots := x509.NewCertPool()
+	roots.AddCert(options.Certificate)
+
+	return &ca{opts: options, roots: roots}, nil
+}
+
+// loadRoot loads the root from the store or generates it
+func loadRoot(options Options) (*x509.Certificate, crypto.Signer, error) {
+	if options.Store != nil {
+		recs, err := options.Store.Read(rootKey)
+		if err == nil && len(recs) > 0 {
+			return decodeRoot(recs[0].Value)
+		} else if err != nil && err != store.ErrNotFound {
+			return nil, nil, err
+		}
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, err
+	}
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, nil, err
+	}
+	now := time.Now()
+	template := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: options.TrustDomain},
+		URIs:                  []*url.URL{{Scheme: "spiffe", Host: options.TrustDomain}},
+		NotBefore:             now.Add(-time.Minute),
+		NotAfter:              now.Add(DefaultRootTTL),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return nil, nil, err
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if options.Store != nil {
+		b, err := x509.MarshalECPrivateKey(key)
+		if err != nil {
+			return nil, nil, err
+		}
+		value := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+		value = append(value, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: b})...)
+		if err := options.Store.Write(&store.Record{Key: rootKey, Value: value}); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	return cert, key, nil
+}
+
+func decodeRoot(b []byte) (*x509.Certificate, crypto.Signer, error) {
+	var cert *x509.Certificate
+	var key crypto.Signer
+
+	for {
+		var block *pem.Block
+		block, b = pem.Decode(b)
+		if block == nil {
+			break
+		}
+		var err error
+		switch block.Type {
+		case "CERTIFICATE":
+			cert, err = x509.ParseCertificate(block.Bytes)
+		case "EC PRIVATE KEY":
+			key, err = x509.ParseECPrivateKey(block.Bytes)
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if cert == nil || key == nil {
+		return nil, nil, errors.New("invalid stored root")
+	}
+	return cert, key, nil
+}
+
+func (c *ca) Roots() []*x509.Certificate {
+	return []*x509.Certificate{c.opts.Certificate}
+}
+
+func (c *ca) Sign(ctx context.Context, req *Request) ([]*x509.Certificate, error) {
+	csr, err := x509.ParseCertificateRequest(req.CSR)
+	if err != nil {
+		return nil, err
+	}
+	if err := csr.CheckSignature(); err != nil {
+		return nil, err
+	}
+
+	var path string
+	if len(req.Chain) > 0 {
+		path, err = c.renew(req, csr)
+	} else {
+		path, err = c.attest(ctx, req.Credential)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	id, err := mtls.ParseID("spiffe://" + c.opts.TrustDomain + path)
+	if err != nil {
+		return nil, err
+	}
+	uri, _ := url.Parse(id.String())
+
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	expiry := now.Add(c.opts.TTL)
+	if expiry.After(c.opts.Certificate.NotAfter) {
+		expiry = c.opts.Certificate.NotAfter
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		URIs:         []*url.URL{uri},
+		// allow for clock skew
+		NotBefore:   now.Add(-time.Minute),
+		NotAfter:    expiry,
+		KeyUsage:    x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, c.opts.Certificate, csr.PublicKey, c.opts.Key)
+	if err != nil {
+		return nil, err
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, err
+	}
+
+	return []*x509.Certificate{cert, c.opts.Certificate}, nil
+}
+
+// attest the credential by the first attestor accepting it
+func (c *ca) attest(ctx context.Context, credential string) (string, error) {
+	if len(credential) == 0 {
+		return "", ErrNotAttested
+	}
+	for _, a := range c.opts.Attestors {
+		if path, err := a.Attest(ctx, credential); err == nil {
+			return path, nil
+		}
+	}
+	return "", ErrNotAttested
+}
+
+// renew verifies the current certificate of the service and the proof of
+// its key, returning the path of its id
+func (c *ca) renew(req *Request, csr *x509.CertificateRequest) (string, error) {
+	cert := req.Chain[0]
+
+	intermediates := x509.NewCertPool()
+	for _, i := range req.Chain[1:] {
+		intermediates.AddCert(i)
+	}
+	if _, err := cert.Verify(x509.VerifyOptions{
+		Roots:         c.roots,
+		Intermediates: intermediates,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}); err != nil {
+		return "", err
+	}
+
+	id, err := mtls.IDFromCertificate(cert)
+	if err != nil {
+		return "", err
+	}
+	if id.TrustDomain != c.opts.TrustDomain {
+		return "", fmt.Errorf("untrusted domain %s", id.TrustDomain)
+	}
+
+	if err := cert.CheckSignature(proofAlgorithm(cert), req.CSR, req.Proof); err != nil {
+		return "", fmt.Errorf("invalid proof: %v", err)
+	}
+
+	return id.Path, nil
+}
+
+// proofAlgorithm is the algorithm of the proofs signed with the key of
+// the certificate
+func proofAlgorithm(cert *x509.Certificate) x509.SignatureAlgorithm {
+	switch cert.PublicKeyAlgorithm {
+	case x509.RSA:
+		return x509.SHA256WithRSA
+	case x509.Ed25519:
+		return x509.PureEd25519
+	}
+	return x509.ECDSAWithSHA256
+}
@@ -0,0 +1,136 @@
+package ca
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/micro/go-micro/v2/auth"
+	"github.com/micro/go-micro/v2/auth/mtls"
+	"github.com/micro/go-micro/v2/store/memory"
+)
+
+func TestIssue(t *testing.T) {
+	s := memory.NewStore()
+
+	c, err := NewCA(
+		TrustDomain("example.org"),
+		Store(s),
+		Attestors(BootstrapTokens(map[string]string{"secret": "go.micro.srv.notes"})),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the root is loaded from the store
+	c2, err := NewCA(Store(s))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !c2.Roots()[0].Equal(c.Roots()[0]) {
+		t.Fatal("Expected the stored root to be loaded")
+	}
+
+	srv := httptest.NewTLSServer(Handler(c))
+	defer srv.Close()
+
+	pin := PinRoots(c.Roots()...)
+	client := IssuerClient(srv.Client())
+
+	if _, err := NewIssuer(srv.URL, "wrong", pin, client).Issue(context.Background(), ""); err == nil {
+		t.Fatal("Expected the wrong token not to be attested")
+	}
+	if _, err := NewIssuer(srv.URL, "secret", client).Issue(context.Background(), ""); err != ErrNotPinned {
+		t.Fatalf("Expected %v got %v", ErrNotPinned, err)
+	}
+	other, err := NewCA()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewIssuer(srv.URL, "secret", PinRoots(other.Roots()...), client).Issue(context.Background(), ""); err != ErrUnpinnedRoot {
+		t.Fatalf("Expected %v got %v", ErrUnpinnedRoot, err)
+	}
+	plain := httptest.NewServer(Handler(c))
+	defer plain.Close()
+	if _, err := NewIssuer(plain.URL, "secret", pin).Issue(context.Background(), ""); err != ErrInsecureURL {
+		t.Fatalf("Expected %v got %v", ErrInsecureURL, err)
+	}
+
+	sum := sha256.Sum256(c.Roots()[0].Raw)
+	i := NewIssuer(srv.URL, "secret", PinHashes(hex.EncodeToString(sum[:])), client)
+	cert, err := i.Issue(context.Background(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	id, err := mtls.IDFromCertificate(cert.Leaf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id.String() != "spiffe://example.org/service/go.micro.srv.notes" {
+		t.Fatalf("Unexpected id %s", id)
+	}
+
+	// renewed with the current certificate rather than the token
+	i.(*issuer).credential = ""
+	renewed, err := i.Issue(context.Background(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if renewed.Leaf.SerialNumber.Cmp(cert.Leaf.SerialNumber) == 0 {
+		t.Fatal("Expected a new certificate")
+	}
+	if rid, _ := mtls.IDFromCertificate(renewed.Leaf); rid.String() != id.String() {
+		t.Fatalf("Expected the renewed id %s got %s", id, rid)
+	}
+
+	// the services trust each other with certificates of the CA
+	m, err := mtls.NewManager(context.Background(), "", i)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer m.Stop()
+	cert = m.Certificate()
+	if _, err := cert.Leaf.Verify(x509.VerifyOptions{
+		Roots:     cert.Roots,
+		KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+type testAuth struct {
+	auth.Auth
+	accounts map[string]*auth.Account
+}
+
+func (a *testAuth) Inspect(token string) (*auth.Account, error) {
+	acc, ok := a.accounts[token]
+	if !ok {
+		return nil, auth.ErrInvalidToken
+	}
+	return acc, nil
+}
+
+func TestAuthAttestor(t *testing.T) {
+	a := AuthAttestor(&testAuth{accounts: map[string]*auth.Account{
+		"sa":   {ID: "system:serviceaccount:default:notes", Type: "service"},
+		"user": {ID: "service:go.micro.srv.notes", Type: "user"},
+	}})
+
+	path, err := a.Attest(context.Background(), "sa")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if path != "/account/system/serviceaccount/default/notes" {
+		t.Fatalf("Unexpected path %s", path)
+	}
+	if _, err := a.Attest(context.Background(), "user"); err != ErrNotAttested {
+		t.Fatalf("Expected a user account not to be attested got %v", err)
+	}
+	if _, err := a.Attest(context.Background(), "missing"); err != ErrNotAttested {
+		t.Fatalf("Expected %v got %v", ErrNotAttested, err)
+	}
+}
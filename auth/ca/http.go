@@ -0,0 +1,279 @@
+package ca
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/micro/go-micro/v2/auth/mtls"
+)
+
+type signRequest struct {
+	CSR        []byte   `json:"csr"`
+	Credential string   `json:"credential,omitempty"`
+	Chain      [][]byte `json:"chain,omitempty"`
+	Proof      []byte   `json:"proof,omitempty"`
+}
+
+type signResponse struct {
+	Chain [][]byte `json:"chain"`
+	Roots [][]byte `json:"roots"`
+}
+
+// Handler serves the CA over http. The services post their certificate
+// requests to it e.g
+//
+//	http.Handle("/ca/sign", ca.Handler(c))
+func Handler(c CA) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		var req signRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		sr := &Request{CSR: req.CSR, Credential: req.Credential, Proof: req.Proof}
+		for _, b := range req.Chain {
+			cert, err := x509.ParseCertificate(b)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			sr.Chain = append(sr.Chain, cert)
+		}
+
+		chain, err := c.Sign(r.Context(), sr)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
+
+		var rsp signResponse
+		for _, cert := range chain {
+			rsp.Chain = append(rsp.Chain, cert.Raw)
+		}
+		for _, cert := range c.Roots() {
+			rsp.Roots = append(rsp.Roots, cert.Raw)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(&rsp)
+	})
+}
+
+var (
+	// ErrInsecureURL is returned when the CA isn't served over https
+	ErrInsecureURL = errors.New("ca: the url must be https")
+	// ErrNotPinned is returned when the issuer isn't pinned to the roots of
+	// the CA
+	ErrNotPinned = errors.New("ca: no roots pinned")
+	// ErrUnpinnedRoot is returned when the CA returns a root not pinned
+	ErrUnpinnedRoot = errors.New("ca: root not pinned")
+)
+
+type issuer struct {
+	url        string
+	credential string
+	opts       IssuerOptions
+
+	sync.Mutex
+	// current certificate proving the service when renewing
+	current *mtls.Certificate
+}
+
+// NewIssuer returns an issuer of certificates signed by the CA served at
+// the https url. The service is attested by the credential when first issued
+// a certificate and renews it with the current one. The roots of the CA must
+// be pinned since they're trusted by the service, e.g
+//
+//	i := ca.NewIssuer(url, os.Getenv("MICRO_CA_TOKEN"), ca.PinHashes(os.Getenv("MICRO_CA_HASH")))
+//	m, err := mtls.NewManager(ctx, id, i)
+func NewIssuer(url, credential string, opts ...IssuerOption) mtls.Issuer {
+	var options IssuerOptions
+	for _, o := range opts {
+		o(&options)
+	}
+	if options.Client == nil {
+		options.Client = &http.Client{Timeout: 10 * time.Second}
+	}
+
+	return &issuer{
+		url:        url,
+		credential: credential,
+		opts:       options,
+	}
+}
+
+// pinned returns true if the root is pinned
+func (i *issuer) pinned(root *x509.Certificate) bool {
+	for _, r := range i.opts.Roots {
+		if r.Equal(root) {
+			return true
+		}
+	}
+	sum := sha256.Sum256(root.Raw)
+	for _, h := range i.opts.Hashes {
+		if strings.EqualFold(h, hex.EncodeToString(sum[:])) {
+			return true
+		}
+	}
+	return false
+}
+
+func (i *issuer) Issue(ctx context.Context, id string) (*mtls.Certificate, error) {
+	// the credential is sent and the roots returned are trusted
+	if u, err := url.Parse(i.url); err != nil || u.Scheme != "https" {
+		return nil, ErrInsecureURL
+	}
+	if len(i.opts.Roots) == 0 && len(i.opts.Hashes) == 0 {
+		return nil, ErrNotPinned
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+
+	template := &x509.CertificateRequest{}
+	if u, err := url.Parse(id); err == nil && len(id) > 0 {
+		template.URIs = []*url.URL{u}
+	}
+	csr, err := x509.CreateCertificateRequest(rand.Reader, template, key)
+	if err != nil {
+		return nil, err
+	}
+
+	i.Lock()
+	current := i.current
+	i.Unlock()
+
+	var cert *mtls.Certificate
+
+	// renew with the current certificate while it's valid
+	if current != nil && time.Now().Before(current.Expiry) {
+		req := &signRequest{CSR: csr, Chain: current.Certificate.Certificate}
+		if req.Proof, err = prove(current, csr); err == nil {
+			cert, err = i.sign(ctx, key, req)
+		}
+	}
+	if cert == nil {
+		cert, err = i.sign(ctx, key, &signRequest{CSR: csr, Credential: i.credential})
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	i.Lock()
+	i.current = cert
+	i.Unlock()
+
+	return cert, nil
+}
+
+// prove the csr was requested by the holder of the current certificate
+func prove(current *mtls.Certificate, csr []byte) ([]byte, error) {
+	signer, ok := current.PrivateKey.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("unsupported key %T", current.PrivateKey)
+	}
+	sum := sha256.Sum256(csr)
+	return signer.Sign(rand.Reader, sum[:], crypto.SHA256)
+}
+
+func (i *issuer) sign(ctx context.Context, key *ecdsa.PrivateKey, req *signRequest) (*mtls.Certificate, error) {
+	b, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+	hreq, err := http.NewRequest(http.MethodPost, i.url, bytes.NewReader(b))
+	if err != nil {
+		return nil, err
+	}
+	hreq.Header.Set("Content-Type", "application/json")
+
+	rsp, err := i.opts.Client.Do(hreq.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer rsp.Body.Close()
+
+	if rsp.StatusCode != http.StatusOK {
+		msg, _ := ioutil.ReadAll(rsp.Body)
+		return nil, fmt.Errorf("ca: %s: %s", rsp.Status, strings.TrimSpace(string(msg)))
+	}
+
+	var sr signResponse
+	if err := json.NewDecoder(rsp.Body).Decode(&sr); err != nil {
+		return nil, err
+	}
+	if len(sr.Chain) == 0 {
+		return nil, fmt.Errorf("ca: no certificate issued")
+	}
+
+	leaf, err := x509.ParseCertificate(sr.Chain[0])
+	if err != nil {
+		return nil, err
+	}
+	if len(sr.Roots) == 0 {
+		return nil, fmt.Errorf("ca: no roots returned")
+	}
+	roots := x509.NewCertPool()
+	for _, r := range sr.Roots {
+		c, err := x509.ParseCertificate(r)
+		if err != nil {
+			return nil, err
+		}
+		if !i.pinned(c) {
+			return nil, ErrUnpinnedRoot
+		}
+		roots.AddCert(c)
+	}
+
+	// the certificate must be issued by the pinned roots
+	intermediates := x509.NewCertPool()
+	for _, b := range sr.Chain[1:] {
+		c, err := x509.ParseCertificate(b)
+		if err != nil {
+			return nil, err
+		}
+		intermediates.AddCert(c)
+	}
+	if _, err := leaf.Verify(x509.VerifyOptions{
+		Roots:         roots,
+		Intermediates: intermediates,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+	}); err != nil {
+		return nil, fmt.Errorf("ca: %v", err)
+	}
+
+	return &mtls.Certificate{
+		Certificate: tls.Certificate{
+			Certificate: sr.Chain,
+			PrivateKey:  key,
+			Leaf:        leaf,
+		},
+		Roots:  roots,
+		Expiry: leaf.NotAfter,
+	}, nil
+}
@@ -0,0 +1,100 @@
+package ca
+
+import (
+	"crypto"
+	"crypto/x509"
+	"net/http"
+	"time"
+
+	"github.com/micro/go-micro/v2/store"
+)
+
+// Options of the CA
+type Options struct {
+	// TrustDomain of the spiffe ids issued
+	TrustDomain string
+	// TTL of the certificates issued
+	TTL time.Duration
+	// Certificate and Key of the root. A root is generated if not set.
+	Certificate *x509.Certificate
+	Key         crypto.Signer
+	// Store the generated root is saved in and loaded from so it
+	// survives restarts
+	Store store.Store
+	// Attestors of the credentials of the services
+	Attestors []Attestor
+}
+
+type Option func(o *Options)
+
+// TrustDomain of the spiffe ids issued
+func TrustDomain(td string) Option {
+	return func(o *Options) {
+		o.TrustDomain = td
+	}
+}
+
+// TTL of the certificates issued
+func TTL(d time.Duration) Option {
+	return func(o *Options) {
+		o.TTL = d
+	}
+}
+
+// Root sets the certificate and key of the root
+func Root(cert *x509.Certificate, key crypto.Signer) Option {
+	return func(o *Options) {
+		o.Certificate = cert
+		o.Key = key
+	}
+}
+
+// Store the generated root is saved in
+func Store(s store.Store) Option {
+	return func(o *Options) {
+		o.Store = s
+	}
+}
+
+// Attestors of the credentials of the services
+func Attestors(a ...Attestor) Option {
+	return func(o *Options) {
+		o.Attestors = append(o.Attestors, a...)
+	}
+}
+
+// IssuerOptions of the issuer of the certificates signed by a CA over http
+type IssuerOptions struct {
+	// Roots the CA is pinned to. The roots it returns must be one of them.
+	Roots []*x509.Certificate
+	// Hashes the CA is pinned to, the hex encoded sha256 of the DER of its
+	// roots
+	Hashes []string
+	// Client of the CA, which must be served over https
+	Client *http.Client
+}
+
+type IssuerOption func(o *IssuerOptions)
+
+// PinRoots pins the CA to the roots e.g loaded from a bundle the services
+// are deployed with
+func PinRoots(certs ...*x509.Certificate) IssuerOption {
+	return func(o *IssuerOptions) {
+		o.Roots = append(o.Roots, certs...)
+	}
+}
+
+// PinHashes pins the CA to the roots with the hex encoded sha256 of their DER
+func PinHashes(hashes ...string) IssuerOption {
+	return func(o *IssuerOptions) {
+		o.Hashes = append(o.Hashes, hashes...)
+	}
+}
+
+// IssuerClient sets the http client of the CA e.g to trust its server
+// certificate
+func IssuerClient(c *http.Client) IssuerOption {
+	return func(o *IssuerOptions) {
+		o.Client = c
+	}
+}
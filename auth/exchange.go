@@ -0,0 +1,131 @@
+package auth
+
+import (
+	"errors"
+	"strings"
+	"time"
+)
+
+// ActorKey is the metadata key of the accounts which acted on behalf of the
+// account of a delegated token, comma separated with the latest last
+const ActorKey = "Actor"
+
+// ErrExchangeNotSupported is returned when the auth can't exchange tokens
+var ErrExchangeNotSupported = errors.New("token exchange not supported")
+
+// Exchanger is implemented by the auths which exchange the token of a subject
+// e.g an end user for a delegated token. The delegated token identifies the
+// subject and the actor calling on its behalf so the token of the subject
+// isn't forwarded to every service. The delegated token expires no later than
+// the token of the subject.
+type Exchanger interface {
+	Exchange(subject string, opts ...ExchangeOption) (*Token, error)
+}
+
+// ExchangeOptions of a token exchange
+type ExchangeOptions struct {
+	// Actor is the token of the account calling on behalf of the subject
+	Actor string
+	// Scopes limits the delegated token to a subset of the subject's scopes
+	Scopes []string
+	// Expiry is the time the delegated token should live for
+	Expiry time.Duration
+}
+
+type ExchangeOption func(o *ExchangeOptions)
+
+// WithActor sets the token of the account calling on behalf of the subject
+func WithActor(token string) ExchangeOption {
+	return func(o *ExchangeOptions) {
+		o.Actor = token
+	}
+}
+
+// ExchangeScopes limits the scopes of the delegated token
+func ExchangeScopes(s ...string) ExchangeOption {
+	return func(o *ExchangeOptions) {
+		o.Scopes = s
+	}
+}
+
+// ExchangeExpiry for the delegated token
+func ExchangeExpiry(ex time.Duration) ExchangeOption {
+	return func(o *ExchangeOptions) {
+		o.Expiry = ex
+	}
+}
+
+// NewExchangeOptions from a slice of options
+func NewExchangeOptions(opts ...ExchangeOption) ExchangeOptions {
+	var options ExchangeOptions
+	for _, o := range opts {
+		o(&options)
+	}
+
+	// delegated tokens are short lived and not refreshed
+	if options.Expiry == 0 {
+		options.Expiry = time.Minute
+	}
+
+	return options
+}
+
+// Exchange the token of the subject for a delegated token if the auth
+// supports it
+func Exchange(a Auth, subject string, opts ...ExchangeOption) (*Token, error) {
+	e, ok := a.(Exchanger)
+	if !ok {
+		return nil, ErrExchangeNotSupported
+	}
+	return e.Exchange(subject, opts...)
+}
+
+// Delegate returns the account of a delegated token for the subject acting
+// through the actor. The scopes are limited to the ones requested, which
+// must be held by the subject.
+func Delegate(subject, actor *Account, scopes ...string) (*Account, error) {
+	if len(scopes) == 0 {
+		scopes = subject.Scopes
+	}
+	for _, s := range scopes {
+		if !hasScope(subject.Scopes, s) {
+			return nil, ErrForbidden
+		}
+	}
+
+	md := make(map[string]string, len(subject.Metadata)+1)
+	for k, v := range subject.Metadata {
+		md[k] = v
+	}
+	if chain := md[ActorKey]; len(chain) > 0 {
+		md[ActorKey] = chain + "," + actor.ID
+	} else {
+		md[ActorKey] = actor.ID
+	}
+
+	return &Account{
+		ID:       subject.ID,
+		Type:     subject.Type,
+		Issuer:   subject.Issuer,
+		Scopes:   scopes,
+		Metadata: md,
+	}, nil
+}
+
+// Actors returns the accounts which acted on behalf of the account, the
+// latest last. It's empty unless the account is of a delegated token.
+func Actors(acc *Account) []string {
+	if acc == nil || len(acc.Metadata[ActorKey]) == 0 {
+		return nil
+	}
+	return strings.Split(acc.Metadata[ActorKey], ",")
+}
+
+func hasScope(scopes []string, s string) bool {
+	for _, scope := range scopes {
+		if scope == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,108 @@
+package identity
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+)
+
+var (
+	// DefaultEC2Endpoint of the ec2 instance metadata service
+	DefaultEC2Endpoint = "http://169.254.169.254"
+)
+
+type ec2 struct {
+	opts Options
+}
+
+type instanceDocument struct {
+	InstanceID string `json:"instanceId"`
+	AccountID  string `json:"accountId"`
+	Region     string `json:"region"`
+}
+
+func (e *ec2) do(ctx context.Context, method, path, token string) (string, error) {
+	req, err := http.NewRequest(method, e.opts.Endpoint+path, nil)
+	if err != nil {
+		return "", err
+	}
+	if len(token) > 0 {
+		req.Header.Set("X-aws-ec2-metadata-token", token)
+	} else {
+		req.Header.Set("X-aws-ec2-metadata-token-ttl-seconds", "21600")
+	}
+
+	rsp, err := http.DefaultClient.Do(req.WithContext(ctx))
+	if err != nil {
+		// the metadata service is only reachable on ec2
+		return "", ErrUnavailable
+	}
+	defer rsp.Body.Close()
+
+	b, err := ioutil.ReadAll(rsp.Body)
+	if err != nil {
+		return "", err
+	}
+
+	switch rsp.StatusCode {
+	case http.StatusOK:
+		return strings.TrimSpace(string(b)), nil
+	case http.StatusNotFound:
+		return "", ErrUnavailable
+	default:
+		return "", fmt.Errorf("metadata service returned %s: %s", rsp.Status, b)
+	}
+}
+
+// Identity of the instance. The token is the pkcs7 signature of the instance
+// identity document which includes the document and is verified with the
+// public certificate of the region.
+func (e *ec2) Identity(ctx context.Context) (*Identity, error) {
+	ctx, cancel := context.WithTimeout(ctx, e.opts.Timeout)
+	defer cancel()
+
+	// requests are authenticated with a session token (IMDSv2)
+	session, err := e.do(ctx, http.MethodPut, "/latest/api/token", "")
+	if err != nil {
+		return nil, err
+	}
+
+	doc, err := e.do(ctx, http.MethodGet, "/latest/dynamic/instance-identity/document", session)
+	if err != nil {
+		return nil, err
+	}
+
+	var d instanceDocument
+	if err := json.Unmarshal([]byte(doc), &d); err != nil {
+		return nil, err
+	}
+
+	sig, err := e.do(ctx, http.MethodGet, "/latest/dynamic/instance-identity/pkcs7", session)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Identity{
+		Source: e.String(),
+		ID:     d.InstanceID,
+		Token:  sig,
+		Metadata: map[string]string{
+			"account": d.AccountID,
+			"region":  d.Region,
+		},
+	}, nil
+}
+
+func (e *ec2) String() string {
+	return "ec2"
+}
+
+// NewEC2 returns a source reading the instance identity from the ec2 metadata service
+func NewEC2(opts ...Option) Source {
+	return &ec2{
+		opts: newOptions(DefaultEC2Endpoint, opts...),
+	}
+}
@@ -0,0 +1,97 @@
+package identity
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+var (
+	// DefaultGCPEndpoint of the gcp metadata server
+	DefaultGCPEndpoint = "http://metadata.google.internal"
+)
+
+type gcp struct {
+	opts Options
+}
+
+func (g *gcp) get(ctx context.Context, path string) (string, error) {
+	req, err := http.NewRequest(http.MethodGet, g.opts.Endpoint+"/computeMetadata/v1/"+path, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Metadata-Flavor", "Google")
+
+	rsp, err := http.DefaultClient.Do(req.WithContext(ctx))
+	if err != nil {
+		// the metadata server is only reachable on gcp
+		return "", ErrUnavailable
+	}
+	defer rsp.Body.Close()
+
+	b, err := ioutil.ReadAll(rsp.Body)
+	if err != nil {
+		return "", err
+	}
+
+	switch {
+	case rsp.StatusCode == http.StatusNotFound:
+		return "", ErrUnavailable
+	case rsp.StatusCode != http.StatusOK:
+		return "", fmt.Errorf("metadata server returned %s: %s", rsp.Status, b)
+	case rsp.Header.Get("Metadata-Flavor") != "Google":
+		return "", ErrUnavailable
+	}
+
+	return strings.TrimSpace(string(b)), nil
+}
+
+// Identity of the default service account of the instance. The token
+// is an identity token signed by google for the audience.
+func (g *gcp) Identity(ctx context.Context) (*Identity, error) {
+	ctx, cancel := context.WithTimeout(ctx, g.opts.Timeout)
+	defer cancel()
+
+	email, err := g.get(ctx, "instance/service-accounts/default/email")
+	if err != nil {
+		return nil, err
+	}
+
+	q := url.Values{}
+	q.Set("audience", g.opts.Audience)
+	q.Set("format", "full")
+
+	token, err := g.get(ctx, "instance/service-accounts/default/identity?"+q.Encode())
+	if err != nil {
+		return nil, err
+	}
+
+	id := &Identity{
+		Source:   g.String(),
+		ID:       email,
+		Token:    token,
+		Metadata: make(map[string]string),
+	}
+	if c, err := claims(token); err == nil {
+		id.Expiry = expiry(c)
+	}
+	if project, err := g.get(ctx, "project/project-id"); err == nil {
+		id.Metadata["project"] = project
+	}
+
+	return id, nil
+}
+
+func (g *gcp) String() string {
+	return "gcp"
+}
+
+// NewGCP returns a source requesting an identity token from the gcp metadata server
+func NewGCP(opts ...Option) Source {
+	return &gcp{
+		opts: newOptions(DefaultGCPEndpoint, opts...),
+	}
+}
@@ -0,0 +1,159 @@
+// Package identity bootstraps the credentials of a service from the environment
+// it runs in e.g a kubernetes service account or the spire agent
+package identity
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrUnavailable is returned by sources the environment doesn't provide
+	ErrUnavailable = errors.New("identity not available")
+
+	// DefaultAudience of the tokens requested
+	DefaultAudience = "micro"
+
+	// DefaultTimeout of requests to metadata servers and agents. It's
+	// short as the servers are only reachable in their environment.
+	DefaultTimeout = time.Second
+
+	// DefaultSources are tried in order by Bootstrap. Local sources
+	// come first as the metadata servers can only be probed by request.
+	DefaultSources = []Source{
+		NewKubernetes(),
+		NewSpire(),
+		NewGCP(),
+		NewEC2(),
+	}
+)
+
+// Source obtains an identity from the environment
+type Source interface {
+	// Identity returns ErrUnavailable if the environment doesn't provide it
+	Identity(ctx context.Context) (*Identity, error)
+	String() string
+}
+
+// Identity is the credentials of the service
+type Identity struct {
+	// Source of the identity e.g kubernetes
+	Source string
+	// ID of the service e.g the service account or spiffe id
+	ID string
+	// Token proves the identity to auth e.g a service account token
+	Token string
+	// Certificate used for mutual tls
+	Certificate *tls.Certificate
+	// CAs trusted to verify peers
+	CAs *x509.CertPool
+	// Expiry of the credentials
+	Expiry time.Time
+	// Metadata describing the environment e.g the region
+	Metadata map[string]string
+}
+
+// TLSConfig returns the config for mutual tls with the certificate of the
+// identity. It returns nil if the identity has no certificate.
+func (i *Identity) TLSConfig() *tls.Config {
+	if i.Certificate == nil {
+		return nil
+	}
+
+	return &tls.Config{
+		Certificates: []tls.Certificate{*i.Certificate},
+		ClientCAs:    i.CAs,
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		// workload certificates identify services by uri rather than
+		// host name so the chain is verified without the host name
+		InsecureSkipVerify:    true,
+		VerifyPeerCertificate: verifyChain(i.CAs),
+	}
+}
+
+// Bootstrap returns the identity of the first source available in the
+// environment. The default sources are used if none are given.
+func Bootstrap(ctx context.Context, sources ...Source) (*Identity, error) {
+	if len(sources) == 0 {
+		sources = DefaultSources
+	}
+
+	for _, s := range sources {
+		id, err := s.Identity(ctx)
+		if err == ErrUnavailable {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s identity: %v", s, err)
+		}
+		return id, nil
+	}
+
+	return nil, ErrUnavailable
+}
+
+// verifyChain verifies the certificates of the peer were issued by the cas
+func verifyChain(cas *x509.CertPool) func([][]byte, [][]*x509.Certificate) error {
+	return func(raw [][]byte, _ [][]*x509.Certificate) error {
+		if len(raw) == 0 {
+			return errors.New("no peer certificate")
+		}
+
+		certs := make([]*x509.Certificate, 0, len(raw))
+		for _, r := range raw {
+			c, err := x509.ParseCertificate(r)
+			if err != nil {
+				return err
+			}
+			certs = append(certs, c)
+		}
+
+		intermediates := x509.NewCertPool()
+		for _, c := range certs[1:] {
+			intermediates.AddCert(c)
+		}
+
+		_, err := certs[0].Verify(x509.VerifyOptions{
+			Roots:         cas,
+			Intermediates: intermediates,
+			KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+		})
+		return err
+	}
+}
+
+// claims decodes the claims of a jwt without verifying it, the
+// token is verified by the auth service it's presented to
+func claims(token string) (map[string]interface{}, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, errors.New("invalid token")
+	}
+
+	b, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, err
+	}
+
+	var c map[string]interface{}
+	if err := json.Unmarshal(b, &c); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// expiry returns the time of the exp claim
+func expiry(c map[string]interface{}) time.Time {
+	exp, ok := c["exp"].(float64)
+	if !ok {
+		return time.Time{}
+	}
+	return time.Unix(int64(exp), 0)
+}
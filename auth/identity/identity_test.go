@@ -0,0 +1,270 @@
+package identity
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+func testToken(claims string) string {
+	enc := base64.RawURLEncoding.EncodeToString
+	return enc([]byte(`{"alg":"RS256"}`)) + "." + enc([]byte(claims)) + ".signature"
+}
+
+func TestKubernetes(t *testing.T) {
+	dir, err := ioutil.TempDir("", "serviceaccount")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// no service account is mounted
+	if _, err := NewKubernetes(Endpoint(dir)).Identity(context.TODO()); err != ErrUnavailable {
+		t.Fatalf("Expected unavailable got %v", err)
+	}
+
+	token := testToken(`{"sub":"system:serviceaccount:default:foo","exp":1900000000}`)
+	ioutil.WriteFile(filepath.Join(dir, "token"), []byte(token+"\n"), 0600)
+	ioutil.WriteFile(filepath.Join(dir, "namespace"), []byte("default"), 0600)
+
+	id, err := NewKubernetes(Endpoint(dir)).Identity(context.TODO())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id.ID != "system:serviceaccount:default:foo" {
+		t.Fatalf("Expected the subject as id got %v", id.ID)
+	}
+	if id.Token != token {
+		t.Fatalf("Expected token %v got %v", token, id.Token)
+	}
+	if id.Expiry.Unix() != 1900000000 {
+		t.Fatalf("Expected expiry from the token got %v", id.Expiry)
+	}
+	if id.Metadata["namespace"] != "default" {
+		t.Fatalf("Expected namespace default got %v", id.Metadata["namespace"])
+	}
+}
+
+func TestGCP(t *testing.T) {
+	token := testToken(`{"email":"foo@bar.iam.gserviceaccount.com","aud":"micro"}`)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Metadata-Flavor") != "Google" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		w.Header().Set("Metadata-Flavor", "Google")
+
+		switch r.URL.Path {
+		case "/computeMetadata/v1/instance/service-accounts/default/email":
+			fmt.Fprint(w, "foo@bar.iam.gserviceaccount.com")
+		case "/computeMetadata/v1/instance/service-accounts/default/identity":
+			if r.URL.Query().Get("audience") != "micro" {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			fmt.Fprint(w, token)
+		case "/computeMetadata/v1/project/project-id":
+			fmt.Fprint(w, "bar")
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	id, err := NewGCP(Endpoint(srv.URL)).Identity(context.TODO())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id.ID != "foo@bar.iam.gserviceaccount.com" || id.Token != token {
+		t.Fatalf("Unexpected identity %+v", id)
+	}
+	if id.Metadata["project"] != "bar" {
+		t.Fatalf("Expected project bar got %v", id.Metadata["project"])
+	}
+}
+
+func TestEC2(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/latest/api/token" && r.Method == http.MethodPut {
+			fmt.Fprint(w, "session")
+			return
+		}
+		if r.Header.Get("X-aws-ec2-metadata-token") != "session" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.URL.Path {
+		case "/latest/dynamic/instance-identity/document":
+			fmt.Fprint(w, `{"instanceId":"i-1234","accountId":"5678","region":"eu-west-1"}`)
+		case "/latest/dynamic/instance-identity/pkcs7":
+			fmt.Fprint(w, "signature")
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	id, err := NewEC2(Endpoint(srv.URL)).Identity(context.TODO())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id.ID != "i-1234" || id.Token != "signature" {
+		t.Fatalf("Unexpected identity %+v", id)
+	}
+	if id.Metadata["region"] != "eu-west-1" || id.Metadata["account"] != "5678" {
+		t.Fatalf("Unexpected metadata %v", id.Metadata)
+	}
+}
+
+func testSVID(t *testing.T) *x509SVID {
+	caKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	ca := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "ca"},
+		NotBefore:             time.Now().Add(-time.Minute),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, ca, ca, &caKey.PublicKey, caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ca, _ = x509.ParseCertificate(caDER)
+
+	spiffeID, _ := url.Parse("spiffe://example.org/foo")
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	leaf := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		NotBefore:    time.Now().Add(-time.Minute),
+		NotAfter:     time.Now().Add(time.Hour),
+		URIs:         []*url.URL{spiffeID},
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	leafDER, err := x509.CreateCertificate(rand.Reader, leaf, ca, &key.PublicKey, caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &x509SVID{
+		SpiffeId:    spiffeID.String(),
+		X509Svid:    leafDER,
+		X509SvidKey: keyDER,
+		Bundle:      caDER,
+	}
+}
+
+func TestSpire(t *testing.T) {
+	dir, err := ioutil.TempDir("", "spire")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	socket := filepath.Join(dir, "agent.sock")
+	source := NewSpire(Endpoint("unix://" + socket))
+
+	if _, err := source.Identity(context.TODO()); err != ErrUnavailable {
+		t.Fatalf("Expected unavailable got %v", err)
+	}
+
+	l, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	svid := testSVID(t)
+
+	srv := grpc.NewServer(grpc.UnknownServiceHandler(func(_ interface{}, stream grpc.ServerStream) error {
+		method, _ := grpc.MethodFromServerStream(stream)
+		if method != "/SpiffeWorkloadAPI/FetchX509SVID" {
+			return fmt.Errorf("unexpected method %s", method)
+		}
+		md, _ := metadata.FromIncomingContext(stream.Context())
+		if v := md.Get("workload.spiffe.io"); len(v) == 0 || v[0] != "true" {
+			return fmt.Errorf("missing security header")
+		}
+		if err := stream.RecvMsg(&x509SVIDRequest{}); err != nil {
+			return err
+		}
+		return stream.SendMsg(&x509SVIDResponse{Svids: []*x509SVID{svid}})
+	}))
+	go srv.Serve(l)
+	defer srv.Stop()
+
+	id, err := source.Identity(context.TODO())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id.ID != "spiffe://example.org/foo" {
+		t.Fatalf("Expected the spiffe id got %v", id.ID)
+	}
+	if id.Certificate == nil || id.Certificate.Leaf.URIs[0].String() != id.ID {
+		t.Fatal("Expected the svid as certificate")
+	}
+
+	cfg := id.TLSConfig()
+	if cfg == nil {
+		t.Fatal("Expected a tls config")
+	}
+	// certificates of the trust domain are verified
+	if err := cfg.VerifyPeerCertificate(id.Certificate.Certificate, nil); err != nil {
+		t.Fatalf("Expected the svid to be verified: %v", err)
+	}
+	other := testSVID(t)
+	if err := cfg.VerifyPeerCertificate([][]byte{other.X509Svid}, nil); err == nil {
+		t.Fatal("Expected a certificate of another ca to be rejected")
+	}
+}
+
+func TestBootstrap(t *testing.T) {
+	dir, err := ioutil.TempDir("", "serviceaccount")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	empty := NewKubernetes(Endpoint(filepath.Join(dir, "missing")))
+
+	if _, err := Bootstrap(context.TODO(), empty); err != ErrUnavailable {
+		t.Fatalf("Expected unavailable got %v", err)
+	}
+
+	ioutil.WriteFile(filepath.Join(dir, "token"), []byte(testToken(`{"sub":"foo"}`)), 0600)
+
+	id, err := Bootstrap(context.TODO(), empty, NewKubernetes(Endpoint(dir)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id.Source != "kubernetes" || id.ID != "foo" {
+		t.Fatalf("Unexpected identity %+v", id)
+	}
+	if id.TLSConfig() != nil {
+		t.Fatal("Expected no tls config without a certificate")
+	}
+}
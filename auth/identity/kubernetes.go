@@ -0,0 +1,61 @@
+package identity
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+var (
+	// DefaultServiceAccountPath is where kubernetes mounts the service account
+	DefaultServiceAccountPath = "/var/run/secrets/kubernetes.io/serviceaccount"
+)
+
+type kubernetes struct {
+	opts Options
+}
+
+// Identity of the service account mounted in the pod. The ID is the
+// subject of the token e.g system:serviceaccount:default:foo.
+func (k *kubernetes) Identity(ctx context.Context) (*Identity, error) {
+	b, err := ioutil.ReadFile(filepath.Join(k.opts.Endpoint, "token"))
+	if os.IsNotExist(err) {
+		return nil, ErrUnavailable
+	} else if err != nil {
+		return nil, err
+	}
+
+	token := strings.TrimSpace(string(b))
+	c, err := claims(token)
+	if err != nil {
+		return nil, err
+	}
+
+	id := &Identity{
+		Source:   k.String(),
+		Token:    token,
+		Expiry:   expiry(c),
+		Metadata: make(map[string]string),
+	}
+	id.ID, _ = c["sub"].(string)
+
+	if ns, err := ioutil.ReadFile(filepath.Join(k.opts.Endpoint, "namespace")); err == nil {
+		id.Metadata["namespace"] = strings.TrimSpace(string(ns))
+	}
+
+	return id, nil
+}
+
+func (k *kubernetes) String() string {
+	return "kubernetes"
+}
+
+// NewKubernetes returns a source reading the service account token of the pod.
+// The endpoint is the directory the service account is mounted at.
+func NewKubernetes(opts ...Option) Source {
+	return &kubernetes{
+		opts: newOptions(DefaultServiceAccountPath, opts...),
+	}
+}
@@ -0,0 +1,48 @@
+package identity
+
+import "time"
+
+type Options struct {
+	// Endpoint of the metadata server, address of the
+	// agent or directory of the service account
+	Endpoint string
+	// Audience of the tokens requested
+	Audience string
+	// Timeout of requests to metadata servers and agents
+	Timeout time.Duration
+}
+
+type Option func(o *Options)
+
+// Endpoint sets the address of the metadata server or agent
+func Endpoint(e string) Option {
+	return func(o *Options) {
+		o.Endpoint = e
+	}
+}
+
+// Audience sets the audience of the tokens requested
+func Audience(a string) Option {
+	return func(o *Options) {
+		o.Audience = a
+	}
+}
+
+// Timeout sets the timeout of requests to metadata servers and agents
+func Timeout(t time.Duration) Option {
+	return func(o *Options) {
+		o.Timeout = t
+	}
+}
+
+func newOptions(endpoint string, opts ...Option) Options {
+	options := Options{
+		Endpoint: endpoint,
+		Audience: DefaultAudience,
+		Timeout:  DefaultTimeout,
+	}
+	for _, o := range opts {
+		o(&options)
+	}
+	return options
+}
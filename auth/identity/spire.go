@@ -0,0 +1,170 @@
+package identity
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"net"
+	"os"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+var (
+	// DefaultSpireEndpoint is the address of the workload api if the
+	// SPIFFE_ENDPOINT_SOCKET env var is not set
+	DefaultSpireEndpoint = "unix:///run/spire/sockets/agent.sock"
+)
+
+// x509SVIDRequest and x509SVIDResponse are the messages of the FetchX509SVID
+// method of the SPIFFE workload api
+type x509SVIDRequest struct{}
+
+func (m *x509SVIDRequest) Reset()         { *m = x509SVIDRequest{} }
+func (m *x509SVIDRequest) String() string { return "x509SVIDRequest{}" }
+func (*x509SVIDRequest) ProtoMessage()    {}
+
+type x509SVIDResponse struct {
+	Svids []*x509SVID `protobuf:"bytes,1,rep,name=svids,proto3" json:"svids,omitempty"`
+}
+
+func (m *x509SVIDResponse) Reset()         { *m = x509SVIDResponse{} }
+func (m *x509SVIDResponse) String() string { return "x509SVIDResponse{}" }
+func (*x509SVIDResponse) ProtoMessage()    {}
+
+type x509SVID struct {
+	// SpiffeId of the workload
+	SpiffeId string `protobuf:"bytes,1,opt,name=spiffe_id,json=spiffeId,proto3" json:"spiffe_id,omitempty"`
+	// X509Svid is the DER encoded certificate chain
+	X509Svid []byte `protobuf:"bytes,2,opt,name=x509_svid,json=x509Svid,proto3" json:"x509_svid,omitempty"`
+	// X509SvidKey is the DER encoded PKCS#8 private key
+	X509SvidKey []byte `protobuf:"bytes,3,opt,name=x509_svid_key,json=x509SvidKey,proto3" json:"x509_svid_key,omitempty"`
+	// Bundle is the DER encoded CA certificates of the trust domain
+	Bundle []byte `protobuf:"bytes,4,opt,name=bundle,proto3" json:"bundle,omitempty"`
+}
+
+func (m *x509SVID) Reset()         { *m = x509SVID{} }
+func (m *x509SVID) String() string { return "x509SVID{" + m.SpiffeId + "}" }
+func (*x509SVID) ProtoMessage()    {}
+
+type spire struct {
+	opts Options
+}
+
+func (s *spire) endpoint() string {
+	if len(s.opts.Endpoint) > 0 {
+		return s.opts.Endpoint
+	}
+	if e := os.Getenv("SPIFFE_ENDPOINT_SOCKET"); len(e) > 0 {
+		return e
+	}
+	return DefaultSpireEndpoint
+}
+
+// Identity of the workload issued by the spire agent. The ID is the spiffe id
+// and the certificate is the x509 svid used for mutual tls.
+func (s *spire) Identity(ctx context.Context) (*Identity, error) {
+	addr := strings.TrimPrefix(s.endpoint(), "unix://")
+	if _, err := os.Stat(addr); err != nil {
+		return nil, ErrUnavailable
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, s.opts.Timeout)
+	defer cancel()
+
+	conn, err := grpc.DialContext(ctx, addr,
+		grpc.WithInsecure(),
+		grpc.WithBlock(),
+		grpc.WithContextDialer(func(ctx context.Context, addr string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, "unix", addr)
+		}),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	// the agent only serves requests with the security header
+	ctx = metadata.AppendToOutgoingContext(ctx, "workload.spiffe.io", "true")
+
+	stream, err := conn.NewStream(ctx, &grpc.StreamDesc{ServerStreams: true}, "/SpiffeWorkloadAPI/FetchX509SVID")
+	if err != nil {
+		return nil, err
+	}
+	if err := stream.SendMsg(&x509SVIDRequest{}); err != nil {
+		return nil, err
+	}
+	if err := stream.CloseSend(); err != nil {
+		return nil, err
+	}
+
+	// the first response has the current svids, later ones rotate them
+	rsp := new(x509SVIDResponse)
+	if err := stream.RecvMsg(rsp); err != nil {
+		return nil, err
+	}
+	if len(rsp.Svids) == 0 {
+		return nil, errors.New("no svid issued")
+	}
+
+	return svidIdentity(s.String(), rsp.Svids[0])
+}
+
+func (s *spire) String() string {
+	return "spire"
+}
+
+func svidIdentity(source string, svid *x509SVID) (*Identity, error) {
+	chain, err := x509.ParseCertificates(svid.X509Svid)
+	if err != nil {
+		return nil, err
+	}
+	if len(chain) == 0 {
+		return nil, errors.New("svid has no certificate")
+	}
+
+	key, err := x509.ParsePKCS8PrivateKey(svid.X509SvidKey)
+	if err != nil {
+		return nil, err
+	}
+
+	bundle, err := x509.ParseCertificates(svid.Bundle)
+	if err != nil {
+		return nil, err
+	}
+
+	cert := &tls.Certificate{
+		PrivateKey: key,
+		Leaf:       chain[0],
+	}
+	for _, c := range chain {
+		cert.Certificate = append(cert.Certificate, c.Raw)
+	}
+
+	cas := x509.NewCertPool()
+	for _, c := range bundle {
+		cas.AddCert(c)
+	}
+
+	return &Identity{
+		Source:      source,
+		ID:          svid.SpiffeId,
+		Certificate: cert,
+		CAs:         cas,
+		Expiry:      chain[0].NotAfter,
+		Metadata:    make(map[string]string),
+	}, nil
+}
+
+// NewSpire returns a source fetching the x509 svid of the workload from the spire
+// agent. The endpoint is the workload api socket e.g unix:///run/spire/agent.sock
+// and defaults to the SPIFFE_ENDPOINT_SOCKET env var.
+func NewSpire(opts ...Option) Source {
+	return &spire{
+		opts: newOptions("", opts...),
+	}
+}
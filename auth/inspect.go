@@ -0,0 +1,19 @@
+package auth
+
+import "context"
+
+// ContextInspector is implemented by the auths which identify the caller by
+// the request rather than only its token e.g by the certificate the peer
+// presented for mutual tls
+type ContextInspector interface {
+	InspectContext(ctx context.Context, token string) (*Account, error)
+}
+
+// InspectContext returns the account of the caller of the request. The token
+// is inspected if the auth doesn't identify callers by the request.
+func InspectContext(ctx context.Context, a Auth, token string) (*Account, error) {
+	if i, ok := a.(ContextInspector); ok {
+		return i.InspectContext(ctx, token)
+	}
+	return a.Inspect(token)
+}
@@ -0,0 +1,34 @@
+package jwt
+
+import (
+	"net/http"
+
+	"github.com/micro/go-micro/v2/auth"
+)
+
+// KeySet is implemented by auths publishing the keys verifying their tokens
+type KeySet interface {
+	// JWKS returns the json web key set of the keys
+	JWKS() ([]byte, error)
+}
+
+// Handler serves the json web key set of the auth so other services can
+// verify its tokens e.g
+//
+//	http.Handle("/.well-known/jwks.json", jwt.Handler(a))
+func Handler(a auth.Auth) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ks, ok := a.(KeySet)
+		if !ok {
+			http.Error(w, a.String()+" auth has no key set", http.StatusNotImplemented)
+			return
+		}
+		b, err := ks.JWKS()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(b)
+	})
+}
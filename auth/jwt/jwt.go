@@ -8,8 +8,12 @@ import (
 	"github.com/micro/go-micro/v2/auth/rules"
 	"github.com/micro/go-micro/v2/auth/token"
 	jwtToken "github.com/micro/go-micro/v2/auth/token/jwt"
+	"github.com/micro/go-micro/v2/logger"
 )
 
+// DefaultKeyRetention is the time the retired keys still verify tokens for
+var DefaultKeyRetention = 24 * time.Hour
+
 // NewAuth returns a new instance of the Auth service
 func NewAuth(opts ...auth.Option) auth.Auth {
 	j := new(jwt)
@@ -19,9 +23,14 @@ func NewAuth(opts ...auth.Option) auth.Auth {
 
 type jwt struct {
 	options auth.Options
-	jwt     token.Provider
+	jwt     *jwtToken.JWT
 	rules   []*auth.Rule
 
+	// interval the keys are rotated at and retained for
+	rotate, retain time.Duration
+	// stops the rotation
+	exit chan bool
+
 	sync.Mutex
 }
 
@@ -33,14 +42,85 @@ func (j *jwt) Init(opts ...auth.Option) {
 	j.Lock()
 	defer j.Unlock()
 
+	prev := j.options
 	for _, o := range opts {
 		o(&j.options)
 	}
 
-	j.jwt = jwtToken.NewTokenProvider(
-		token.WithPrivateKey(j.options.PrivateKey),
-		token.WithPublicKey(j.options.PublicKey),
-	)
+	rotate, retain := time.Duration(0), DefaultKeyRetention
+	if ctx := j.options.Context; ctx != nil {
+		if d, ok := ctx.Value(rotateKey{}).(time.Duration); ok {
+			rotate = d
+		}
+		if d, ok := ctx.Value(retainKey{}).(time.Duration); ok {
+			retain = d
+		}
+	}
+
+	// the keys are kept unless changed e.g when only the client token is
+	// refreshed, else the tokens signed with the generated keys are lost
+	keysChanged := j.jwt == nil || prev.PrivateKey != j.options.PrivateKey || prev.PublicKey != j.options.PublicKey
+	if !keysChanged && rotate == j.rotate && retain == j.retain {
+		return
+	}
+
+	if keysChanged {
+		j.jwt = jwtToken.NewTokenProvider(
+			token.WithPrivateKey(j.options.PrivateKey),
+			token.WithPublicKey(j.options.PublicKey),
+		).(*jwtToken.JWT)
+	}
+	j.rotate, j.retain = rotate, retain
+
+	if j.exit != nil {
+		close(j.exit)
+		j.exit = nil
+	}
+	if j.rotate <= 0 {
+		return
+	}
+
+	if keysChanged && len(j.options.PrivateKey) == 0 {
+		if err := j.jwt.Rotate(j.retain); err != nil {
+			logger.Errorf("Error generating the jwt signing key: %v", err)
+		}
+	}
+
+	j.exit = make(chan bool)
+	go j.run(j.jwt, j.rotate, j.retain, j.exit)
+}
+
+// run rotates the keys every interval until stopped
+func (j *jwt) run(p *jwtToken.JWT, every, retain time.Duration, exit chan bool) {
+	t := time.NewTicker(every)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-t.C:
+			if err := p.Rotate(retain); err != nil {
+				logger.Errorf("Error rotating the jwt signing key: %v", err)
+			}
+		case <-exit:
+			return
+		}
+	}
+}
+
+// Rotate generates a new signing key, retiring the current one
+func (j *jwt) Rotate() error {
+	j.Lock()
+	p, retain := j.jwt, j.retain
+	j.Unlock()
+	return p.Rotate(retain)
+}
+
+// JWKS returns the json web key set of the keys verifying the tokens
+func (j *jwt) JWKS() ([]byte, error) {
+	j.Lock()
+	p := j.jwt
+	j.Unlock()
+	return p.JWKS()
 }
 
 func (j *jwt) Options() auth.Options {
@@ -112,7 +192,10 @@ func (j *jwt) Rules(opts ...auth.RulesOption) ([]*auth.Rule, error) {
 }
 
 func (j *jwt) Inspect(token string) (*auth.Account, error) {
-	return j.jwt.Inspect(token)
+	j.Lock()
+	p := j.jwt
+	j.Unlock()
+	return p.Inspect(token)
 }
 
 func (j *jwt) Token(opts ...auth.TokenOption) (*auth.Token, error) {
@@ -145,3 +228,49 @@ func (j *jwt) Token(opts ...auth.TokenOption) (*auth.Token, error) {
 		RefreshToken: refresh.Token,
 	}, nil
 }
+
+// Exchange the token of the subject for a token of the actor calling on its
+// behalf. The delegated token can't be refreshed.
+func (j *jwt) Exchange(subject string, opts ...auth.ExchangeOption) (*auth.Token, error) {
+	options := auth.NewExchangeOptions(opts...)
+
+	j.Lock()
+	p := j.jwt
+	j.Unlock()
+
+	sub, err := p.Inspect(subject)
+	if err != nil {
+		return nil, err
+	}
+	exp, err := p.Expiry(subject)
+	if err != nil {
+		return nil, err
+	}
+	act, err := p.Inspect(options.Actor)
+	if err != nil {
+		return nil, err
+	}
+
+	account, err := auth.Delegate(sub, act, options.Scopes...)
+	if err != nil {
+		return nil, err
+	}
+
+	// the delegated token can't outlive the subject's e.g when exchanged
+	// again before it expires
+	expiry := options.Expiry
+	if d := time.Until(exp); d < expiry {
+		expiry = d
+	}
+
+	access, err := p.Generate(account, token.WithExpiry(expiry))
+	if err != nil {
+		return nil, err
+	}
+
+	return &auth.Token{
+		Created:     access.Created,
+		Expiry:      access.Expiry,
+		AccessToken: access.Token,
+	}, nil
+}
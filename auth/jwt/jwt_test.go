@@ -0,0 +1,139 @@
+package jwt
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/micro/go-micro/v2/auth"
+)
+
+func TestRotate(t *testing.T) {
+	a := NewAuth(RotateEvery(time.Hour), KeyRetention(time.Hour))
+
+	acc, err := a.Generate("alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := a.(*jwt).Rotate(); err != nil {
+		t.Fatal(err)
+	}
+
+	// the secret signed with the retired key is still valid
+	if _, err := a.Inspect(acc.Secret); err != nil {
+		t.Fatalf("Expected the retired key to verify the token got %v", err)
+	}
+	tok, err := a.Token(auth.WithCredentials("alice", acc.Secret))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := a.Inspect(tok.AccessToken); err != nil {
+		t.Fatal(err)
+	}
+
+	rsp := httptest.NewRecorder()
+	Handler(a).ServeHTTP(rsp, httptest.NewRequest("GET", "/.well-known/jwks.json", nil))
+
+	var set struct {
+		Keys []struct {
+			Kid string `json:"kid"`
+		} `json:"keys"`
+	}
+	if err := json.Unmarshal(rsp.Body.Bytes(), &set); err != nil {
+		t.Fatal(err)
+	}
+	if len(set.Keys) != 2 || set.Keys[0].Kid == set.Keys[1].Kid {
+		t.Fatalf("Expected 2 keys got %+v", set.Keys)
+	}
+
+	// the retired keys are dropped once past the retention
+	if err := a.(*jwt).jwt.Rotate(0); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := a.Inspect(tok.AccessToken); err == nil {
+		t.Fatal("Expected the dropped key not to verify the token")
+	}
+}
+
+func TestRotateInit(t *testing.T) {
+	a := NewAuth(RotateEvery(time.Hour))
+
+	acc, err := a.Generate("alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// refreshing the client token keeps the generated keys
+	a.Init(auth.ClientToken(&auth.Token{AccessToken: acc.Secret}))
+	if _, err := a.Inspect(acc.Secret); err != nil {
+		t.Fatalf("Expected the generated key to be kept got %v", err)
+	}
+
+	j := a.(*jwt)
+	j.Lock()
+	exit := j.exit
+	j.Unlock()
+	a.Init(auth.ClientToken(&auth.Token{AccessToken: acc.Secret}))
+	j.Lock()
+	restarted := j.exit != exit
+	j.Unlock()
+	if restarted {
+		t.Fatal("Expected the rotation not to be restarted with unchanged settings")
+	}
+}
+
+func TestExchange(t *testing.T) {
+	a := NewAuth(RotateEvery(time.Hour))
+
+	user, err := a.Generate("alice", auth.WithScopes("notes.read", "notes.write"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	svc, err := a.Generate("go.micro.srv.notes", auth.WithType("service"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tok, err := auth.Exchange(a, user.Secret, auth.WithActor(svc.Secret), auth.ExchangeScopes("notes.read"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tok.RefreshToken) > 0 {
+		t.Fatal("Expected the delegated token not to be refreshable")
+	}
+
+	acc, err := a.Inspect(tok.AccessToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if acc.ID != "alice" || len(acc.Scopes) != 1 || acc.Scopes[0] != "notes.read" {
+		t.Fatalf("Expected alice limited to notes.read got %+v", acc)
+	}
+	if actors := auth.Actors(acc); len(actors) != 1 || actors[0] != "go.micro.srv.notes" {
+		t.Fatalf("Expected the service to be the actor got %v", actors)
+	}
+
+	// the delegated token is exchanged again on the next hop, it can't
+	// outlive the token it was exchanged for
+	expiry := tok.Expiry
+	tok, err = auth.Exchange(a, tok.AccessToken, auth.WithActor(svc.Secret), auth.ExchangeExpiry(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	acc, _ = a.Inspect(tok.AccessToken)
+	if actors := auth.Actors(acc); len(actors) != 2 {
+		t.Fatalf("Expected the chain of actors got %v", actors)
+	}
+	if tok.Expiry.After(expiry) {
+		t.Fatalf("Expected the token to expire by %v got %v", expiry, tok.Expiry)
+	}
+
+	if _, err := auth.Exchange(a, user.Secret, auth.WithActor(svc.Secret), auth.ExchangeScopes("admin")); err != auth.ErrForbidden {
+		t.Fatalf("Expected %v for scopes the subject doesn't hold got %v", auth.ErrForbidden, err)
+	}
+	if _, err := auth.Exchange(a, user.Secret); err == nil {
+		t.Fatal("Expected an exchange without an actor to fail")
+	}
+}
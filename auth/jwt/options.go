@@ -0,0 +1,33 @@
+package jwt
+
+import (
+	"context"
+	"time"
+
+	"github.com/micro/go-micro/v2/auth"
+)
+
+type rotateKey struct{}
+type retainKey struct{}
+
+// RotateEvery generates a new signing key every interval. A key is
+// generated on Init if no private key is set.
+func RotateEvery(d time.Duration) auth.Option {
+	return func(o *auth.Options) {
+		if o.Context == nil {
+			o.Context = context.Background()
+		}
+		o.Context = context.WithValue(o.Context, rotateKey{}, d)
+	}
+}
+
+// KeyRetention is the time the retired keys still verify tokens for. It
+// should be longer than the tokens live.
+func KeyRetention(d time.Duration) auth.Option {
+	return func(o *auth.Options) {
+		if o.Context == nil {
+			o.Context = context.Background()
+		}
+		o.Context = context.WithValue(o.Context, retainKey{}, d)
+	}
+}
@@ -0,0 +1,149 @@
+package mtls
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/micro/go-micro/v2/auth"
+	"github.com/micro/go-micro/v2/auth/rules"
+)
+
+// ErrNotSupported is returned by Generate and Token
+var ErrNotSupported = errors.New("mtls: callers are identified by their certificates")
+
+type optionsKey struct{}
+
+func setAuthOption(k, v interface{}) auth.Option {
+	return func(o *auth.Options) {
+		if o.Context == nil {
+			o.Context = context.Background()
+		}
+		o.Context = context.WithValue(o.Context, k, v)
+	}
+}
+
+// WithOptions sets the trust domains and roots the certificates of the
+// callers are verified with
+func WithOptions(opts ...Option) auth.Option {
+	var options Options
+	for _, o := range opts {
+		o(&options)
+	}
+	return setAuthOption(optionsKey{}, options)
+}
+
+type mtlsAuth struct {
+	sync.Mutex
+	options auth.Options
+	peer    Options
+	rules   []*auth.Rule
+}
+
+// NewAuth returns an auth identifying the callers by the spiffe ids of the
+// certificates they presented over mutual tls. The issuer is the trust
+// domain the callers are accepted from unless others are set e.g
+//
+//	mtls.NewAuth(
+//		auth.Issuer("example.org"),
+//		mtls.WithOptions(mtls.Roots(pool)),
+//	)
+//
+// The callers have no token so the accounts are only returned by
+// InspectContext, and Generate and Token aren't supported.
+func NewAuth(opts ...auth.Option) auth.Auth {
+	a := new(mtlsAuth)
+	a.Init(opts...)
+	return a
+}
+
+func (a *mtlsAuth) String() string {
+	return "mtls"
+}
+
+func (a *mtlsAuth) Init(opts ...auth.Option) {
+	a.Lock()
+	defer a.Unlock()
+
+	for _, o := range opts {
+		o(&a.options)
+	}
+
+	a.peer = Options{}
+	if ctx := a.options.Context; ctx != nil {
+		if o, ok := ctx.Value(optionsKey{}).(Options); ok {
+			a.peer = o
+		}
+	}
+	if len(a.peer.TrustDomains) == 0 && len(a.options.Issuer) > 0 {
+		a.peer.TrustDomains = []string{a.options.Issuer}
+	}
+}
+
+func (a *mtlsAuth) Options() auth.Options {
+	a.Lock()
+	defer a.Unlock()
+	return a.options
+}
+
+func (a *mtlsAuth) Generate(id string, opts ...auth.GenerateOption) (*auth.Account, error) {
+	return nil, ErrNotSupported
+}
+
+func (a *mtlsAuth) Token(opts ...auth.TokenOption) (*auth.Token, error) {
+	return nil, ErrNotSupported
+}
+
+func (a *mtlsAuth) Grant(rule *auth.Rule) error {
+	a.Lock()
+	defer a.Unlock()
+	a.rules = append(a.rules, rule)
+	return nil
+}
+
+func (a *mtlsAuth) Revoke(rule *auth.Rule) error {
+	a.Lock()
+	defer a.Unlock()
+
+	rules := []*auth.Rule{}
+	for _, r := range a.rules {
+		if r.ID != rule.ID {
+			rules = append(rules, r)
+		}
+	}
+
+	a.rules = rules
+	return nil
+}
+
+func (a *mtlsAuth) Verify(acc *auth.Account, res *auth.Resource, opts ...auth.VerifyOption) error {
+	a.Lock()
+	defer a.Unlock()
+	return rules.Verify(a.rules, acc, res)
+}
+
+func (a *mtlsAuth) Rules(opts ...auth.RulesOption) ([]*auth.Rule, error) {
+	a.Lock()
+	defer a.Unlock()
+	return a.rules, nil
+}
+
+// Inspect always fails, a certificate only identifies the caller which
+// proved it holds its key during the tls handshake
+func (a *mtlsAuth) Inspect(token string) (*auth.Account, error) {
+	return nil, auth.ErrInvalidToken
+}
+
+// InspectContext returns the account of the verified certificate of the
+// peer of the request, the token is ignored
+func (a *mtlsAuth) InspectContext(ctx context.Context, token string) (*auth.Account, error) {
+	a.Lock()
+	options := a.peer
+	a.Unlock()
+
+	id, err := peerID(ctx, options)
+	if err != nil {
+		return nil, auth.ErrInvalidToken
+	}
+	return Account(id), nil
+}
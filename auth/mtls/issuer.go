@@ -0,0 +1,132 @@
+package mtls
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"math/big"
+	"net/url"
+	"time"
+
+	"github.com/micro/go-micro/v2/auth/identity"
+)
+
+// DefaultTTL of the certificates issued by a CA
+var DefaultTTL = time.Hour
+
+// Certificate is an issued certificate and the roots verifying the peers
+type Certificate struct {
+	tls.Certificate
+	// Roots of the trust domain
+	Roots *x509.CertPool
+	// Expiry of the certificate
+	Expiry time.Time
+}
+
+// Issuer issues the certificates of the services
+type Issuer interface {
+	// Issue a certificate for the spiffe id
+	Issue(ctx context.Context, id string) (*Certificate, error)
+}
+
+type ca struct {
+	cert  *x509.Certificate
+	key   crypto.Signer
+	ttl   time.Duration
+	roots *x509.CertPool
+}
+
+// NewCA returns an issuer signing certificates with the key of the CA
+// certificate which live for the ttl, DefaultTTL if zero
+func NewCA(cert *x509.Certificate, key crypto.Signer, ttl time.Duration) Issuer {
+	if ttl <= 0 {
+		ttl = DefaultTTL
+	}
+	roots := x509.NewCertPool()
+	roots.AddCert(cert)
+	return &ca{cert: cert, key: key, ttl: ttl, roots: roots}
+}
+
+func (c *ca) Issue(ctx context.Context, id string) (*Certificate, error) {
+	if _, err := ParseID(id); err != nil {
+		return nil, err
+	}
+	uri, err := url.Parse(id)
+	if err != nil {
+		return nil, err
+	}
+
+	priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	expiry := now.Add(c.ttl)
+	if expiry.After(c.cert.NotAfter) {
+		expiry = c.cert.NotAfter
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		URIs:         []*url.URL{uri},
+		// allow for clock skew
+		NotBefore:   now.Add(-time.Minute),
+		NotAfter:    expiry,
+		KeyUsage:    x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, c.cert, &priv.PublicKey, c.key)
+	if err != nil {
+		return nil, err
+	}
+	leaf, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Certificate{
+		Certificate: tls.Certificate{
+			Certificate: [][]byte{der, c.cert.Raw},
+			PrivateKey:  priv,
+			Leaf:        leaf,
+		},
+		Roots:  c.roots,
+		Expiry: expiry,
+	}, nil
+}
+
+type sourceIssuer struct {
+	source identity.Source
+}
+
+// SourceIssuer returns an issuer of the certificates of an identity source
+// e.g the spire agent. The id of the certificates is the one the source
+// assigns, the id requested is ignored.
+func SourceIssuer(s identity.Source) Issuer {
+	return &sourceIssuer{source: s}
+}
+
+func (s *sourceIssuer) Issue(ctx context.Context, id string) (*Certificate, error) {
+	i, err := s.source.Identity(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if i.Certificate == nil {
+		return nil, identity.ErrUnavailable
+	}
+	return &Certificate{
+		Certificate: *i.Certificate,
+		Roots:       i.CAs,
+		Expiry:      i.Expiry,
+	}, nil
+}
@@ -0,0 +1,174 @@
+package mtls
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/micro/go-micro/v2/logger"
+)
+
+// RetryInterval is the interval a failed renewal is retried at
+var RetryInterval = 5 * time.Second
+
+// Manager obtains the certificate of a service and renews it before it
+// expires
+type Manager interface {
+	// Certificate currently issued
+	Certificate() *Certificate
+	// TLSConfig for mutual tls with the current certificate, used both to
+	// serve and to dial. The peers are verified by the current roots.
+	TLSConfig() *tls.Config
+	// Stop renewing the certificate
+	Stop()
+}
+
+type manager struct {
+	id     string
+	issuer Issuer
+	exit   chan bool
+
+	sync.RWMutex
+	cert *Certificate
+}
+
+// NewManager issues the certificate of the spiffe id and renews it at two
+// thirds of its lifetime until stopped
+func NewManager(ctx context.Context, id string, issuer Issuer) (Manager, error) {
+	cert, err := issuer.Issue(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	m := &manager{
+		id:     id,
+		issuer: issuer,
+		exit:   make(chan bool),
+		cert:   cert,
+	}
+	go m.run()
+
+	return m, nil
+}
+
+// renewIn returns the time until the certificate should be renewed
+func renewIn(c *Certificate) time.Duration {
+	if c.Expiry.IsZero() {
+		// the expiry is unknown
+		return DefaultTTL
+	}
+	start := time.Now()
+	if c.Leaf != nil {
+		start = c.Leaf.NotBefore
+	}
+	d := time.Until(start.Add(c.Expiry.Sub(start) * 2 / 3))
+	if d < 0 {
+		return 0
+	}
+	return d
+}
+
+func (m *manager) run() {
+	t := time.NewTimer(renewIn(m.Certificate()))
+	defer t.Stop()
+
+	for {
+		select {
+		case <-m.exit:
+			return
+		case <-t.C:
+		}
+
+		cert, err := m.issuer.Issue(context.Background(), m.id)
+		if err != nil {
+			logger.Errorf("Error renewing the certificate of %s: %v", m.id, err)
+			t.Reset(RetryInterval)
+			continue
+		}
+
+		m.Lock()
+		m.cert = cert
+		m.Unlock()
+
+		t.Reset(renewIn(cert))
+	}
+}
+
+func (m *manager) Certificate() *Certificate {
+	m.RLock()
+	defer m.RUnlock()
+	return m.cert
+}
+
+func (m *manager) getCertificate() (*tls.Certificate, error) {
+	c := m.Certificate()
+	return &c.Certificate, nil
+}
+
+func (m *manager) TLSConfig() *tls.Config {
+	return &tls.Config{
+		GetClientCertificate: func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			return m.getCertificate()
+		},
+		// spiffe ids aren't host names so the servers are verified by
+		// their chain rather than their name
+		InsecureSkipVerify:    true,
+		VerifyPeerCertificate: m.verifyServer,
+		GetCertificate: func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+			return m.getCertificate()
+		},
+		// the clients are verified by the current roots
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			return &tls.Config{
+				GetCertificate: func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+					return m.getCertificate()
+				},
+				ClientAuth: tls.RequireAndVerifyClientCert,
+				ClientCAs:  m.Certificate().Roots,
+			}, nil
+		},
+	}
+}
+
+// verifyServer verifies the chain of the server and that it has a spiffe id
+func (m *manager) verifyServer(raw [][]byte, _ [][]*x509.Certificate) error {
+	if len(raw) == 0 {
+		return errors.New("no server certificate")
+	}
+
+	certs := make([]*x509.Certificate, 0, len(raw))
+	for _, r := range raw {
+		c, err := x509.ParseCertificate(r)
+		if err != nil {
+			return err
+		}
+		certs = append(certs, c)
+	}
+
+	intermediates := x509.NewCertPool()
+	for _, c := range certs[1:] {
+		intermediates.AddCert(c)
+	}
+
+	if _, err := certs[0].Verify(x509.VerifyOptions{
+		Roots:         m.Certificate().Roots,
+		Intermediates: intermediates,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}); err != nil {
+		return err
+	}
+
+	_, err := IDFromCertificate(certs[0])
+	return err
+}
+
+func (m *manager) Stop() {
+	select {
+	case <-m.exit:
+	default:
+		close(m.exit)
+	}
+}
@@ -0,0 +1,202 @@
+// Package mtls identifies services by the SPIFFE ids of the certificates
+// they present for mutual tls. A Manager obtains and renews the certificate
+// of a service from an Issuer e.g a CA or the spire agent, and the handler
+// wrapper and the auth derive the account of the caller from its
+// certificate.
+package mtls
+
+import (
+	"context"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/micro/go-micro/v2/auth"
+	merrors "github.com/micro/go-micro/v2/errors"
+	"github.com/micro/go-micro/v2/metadata"
+	"github.com/micro/go-micro/v2/server"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+)
+
+// IdentityHeader is the metadata key of the spiffe id of the caller. It's
+// set by the handler wrapper so it's propagated to the calls made by the
+// handler, and deleted if the caller has no verified identity.
+const IdentityHeader = "Micro-Spiffe-Id"
+
+var (
+	// ErrNoIdentity is returned when the peer has no verified certificate
+	ErrNoIdentity = errors.New("no peer identity")
+)
+
+// ID is a SPIFFE id e.g spiffe://example.org/ns/prod/sa/notes
+type ID struct {
+	TrustDomain string
+	Path        string
+}
+
+// ParseID parses a spiffe id
+func ParseID(s string) (*ID, error) {
+	u, err := url.Parse(s)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "spiffe" || len(u.Host) == 0 {
+		return nil, fmt.Errorf("invalid spiffe id %q", s)
+	}
+	if len(u.RawQuery) > 0 || len(u.Fragment) > 0 || u.User != nil || len(u.Port()) > 0 {
+		return nil, fmt.Errorf("invalid spiffe id %q", s)
+	}
+	return &ID{TrustDomain: strings.ToLower(u.Host), Path: u.Path}, nil
+}
+
+func (i *ID) String() string {
+	return "spiffe://" + i.TrustDomain + i.Path
+}
+
+// IDFromCertificate returns the spiffe id of the uri of the certificate
+func IDFromCertificate(c *x509.Certificate) (*ID, error) {
+	var ids []*ID
+	for _, u := range c.URIs {
+		if id, err := ParseID(u.String()); err == nil {
+			ids = append(ids, id)
+		}
+	}
+	// a certificate has exactly one spiffe id
+	if len(ids) != 1 {
+		return nil, ErrNoIdentity
+	}
+	return ids[0], nil
+}
+
+type certsKey struct{}
+
+// NewContext returns a context with the certificates of the peer verified by
+// a server, for the servers which don't set the grpc peer or the tls state
+// in the context.
+// The first is the certificate of the peer.
+func NewContext(ctx context.Context, certs []*x509.Certificate) context.Context {
+	return context.WithValue(ctx, certsKey{}, certs)
+}
+
+// certificates of the peer and whether the tls handshake verified them
+func certificates(ctx context.Context) ([]*x509.Certificate, bool) {
+	if certs, ok := ctx.Value(certsKey{}).([]*x509.Certificate); ok {
+		return certs, true
+	}
+	// served by the mucp server over tls
+	if state, ok := server.TLSFromContext(ctx); ok {
+		return state.PeerCertificates, len(state.VerifiedChains) > 0
+	}
+	if p, ok := peer.FromContext(ctx); ok {
+		if info, ok := p.AuthInfo.(credentials.TLSInfo); ok {
+			return info.State.PeerCertificates, len(info.State.VerifiedChains) > 0
+		}
+	}
+	return nil, false
+}
+
+// FromContext returns the verified identity of the peer of the request
+func FromContext(ctx context.Context, opts ...Option) (*ID, error) {
+	var options Options
+	for _, o := range opts {
+		o(&options)
+	}
+	return peerID(ctx, options)
+}
+
+func peerID(ctx context.Context, options Options) (*ID, error) {
+	certs, verified := certificates(ctx)
+	if len(certs) == 0 {
+		return nil, ErrNoIdentity
+	}
+
+	if options.Roots != nil {
+		intermediates := x509.NewCertPool()
+		for _, c := range certs[1:] {
+			intermediates.AddCert(c)
+		}
+		_, err := certs[0].Verify(x509.VerifyOptions{
+			Roots:         options.Roots,
+			Intermediates: intermediates,
+			KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		})
+		if err != nil {
+			return nil, err
+		}
+	} else if !verified {
+		return nil, ErrNoIdentity
+	}
+
+	id, err := IDFromCertificate(certs[0])
+	if err != nil {
+		return nil, err
+	}
+
+	if len(options.TrustDomains) > 0 {
+		trusted := false
+		for _, td := range options.TrustDomains {
+			if strings.EqualFold(td, id.TrustDomain) {
+				trusted = true
+				break
+			}
+		}
+		if !trusted {
+			return nil, fmt.Errorf("untrusted domain %s", id.TrustDomain)
+		}
+	}
+
+	return id, nil
+}
+
+// Account of the identity
+func Account(id *ID) *auth.Account {
+	return &auth.Account{
+		ID:     id.String(),
+		Type:   "service",
+		Issuer: id.TrustDomain,
+		Metadata: map[string]string{
+			"trust_domain": id.TrustDomain,
+			"path":         id.Path,
+		},
+	}
+}
+
+// NewHandlerWrapper sets the account of the caller identified by its
+// certificate in the context and its spiffe id in the metadata. It must
+// wrap the handlers before the auth and rbac wrappers so they use it.
+func NewHandlerWrapper(opts ...Option) server.HandlerWrapper {
+	var options Options
+	for _, o := range opts {
+		o(&options)
+	}
+
+	return func(h server.HandlerFunc) server.HandlerFunc {
+		return func(ctx context.Context, req server.Request, rsp interface{}) error {
+			// the header is only trusted when set by the wrapper
+			md, ok := metadata.FromContext(ctx)
+			if !ok {
+				md = make(metadata.Metadata)
+			}
+			md.Delete(IdentityHeader)
+
+			id, err := peerID(ctx, options)
+			if err != nil {
+				if options.Require {
+					return merrors.Unauthorized(req.Service(), "Unauthorized call made to %v:%v: %v", req.Service(), req.Endpoint(), err)
+				}
+				return h(metadata.NewContext(ctx, md), req, rsp)
+			}
+
+			md.Set(IdentityHeader, id.String())
+			ctx = metadata.NewContext(ctx, md)
+			if _, ok := auth.AccountFromContext(ctx); !ok {
+				ctx = auth.ContextWithAccount(ctx, Account(id))
+			}
+
+			return h(ctx, req, rsp)
+		}
+	}
+}
@@ -0,0 +1,190 @@
+package mtls
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/micro/go-micro/v2/auth"
+	"github.com/micro/go-micro/v2/metadata"
+	"github.com/micro/go-micro/v2/server"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+)
+
+type testRequest struct {
+	server.Request
+}
+
+func (r testRequest) Service() string {
+	return "go.micro.srv.notes"
+}
+
+func (r testRequest) Endpoint() string {
+	return "Notes.List"
+}
+
+func newCA(t *testing.T) Issuer {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "example.org"},
+		NotBefore:             time.Now().Add(-time.Minute),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewCA(cert, key, time.Minute)
+}
+
+func TestMutualTLS(t *testing.T) {
+	ca := newCA(t)
+
+	srv, err := NewManager(context.Background(), "spiffe://example.org/ns/prod/sa/notes", ca)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer srv.Stop()
+	cli, err := NewManager(context.Background(), "spiffe://example.org/ns/prod/sa/web", ca)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cli.Stop()
+
+	if _, err := NewManager(context.Background(), "https://example.org/web", ca); err == nil {
+		t.Fatal("Expected an invalid spiffe id error")
+	}
+
+	l, err := tls.Listen("tcp", "127.0.0.1:0", srv.TLSConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	states := make(chan tls.ConnectionState, 1)
+	go func() {
+		c, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer c.Close()
+		tc := c.(*tls.Conn)
+		if err := tc.Handshake(); err != nil {
+			t.Error(err)
+		}
+		states <- tc.ConnectionState()
+	}()
+
+	c, err := tls.Dial("tcp", l.Addr().String(), cli.TLSConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	var state tls.ConnectionState
+	select {
+	case state = <-states:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for the handshake")
+	}
+
+	// the caller identity is derived from the verified certificate
+	ctx := peer.NewContext(context.Background(), &peer.Peer{
+		Addr:     &net.TCPAddr{},
+		AuthInfo: credentials.TLSInfo{State: state},
+	})
+	// a spoofed header is replaced
+	ctx = metadata.Set(ctx, IdentityHeader, "spiffe://example.org/ns/prod/sa/admin")
+
+	var acc *auth.Account
+	var header string
+
+	h := NewHandlerWrapper(TrustDomains("example.org"), Require())(func(ctx context.Context, req server.Request, rsp interface{}) error {
+		acc, _ = auth.AccountFromContext(ctx)
+		header, _ = metadata.Get(ctx, IdentityHeader)
+		return nil
+	})
+	if err := h(ctx, testRequest{}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if acc == nil || acc.ID != "spiffe://example.org/ns/prod/sa/web" || acc.Type != "service" {
+		t.Fatalf("Unexpected account %+v", acc)
+	}
+	if header != acc.ID {
+		t.Fatalf("Expected the identity header %s got %s", acc.ID, header)
+	}
+
+	// untrusted domain
+	h = NewHandlerWrapper(TrustDomains("example.com"), Require())(func(ctx context.Context, req server.Request, rsp interface{}) error {
+		return nil
+	})
+	if err := h(ctx, testRequest{}, nil); err == nil {
+		t.Fatal("Expected the untrusted domain to be rejected")
+	}
+
+	// no certificate
+	header = ""
+	h = NewHandlerWrapper()(func(ctx context.Context, req server.Request, rsp interface{}) error {
+		header, _ = metadata.Get(ctx, IdentityHeader)
+		return nil
+	})
+	if err := h(metadata.Set(context.Background(), IdentityHeader, "spiffe://example.org/x"), testRequest{}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if len(header) > 0 {
+		t.Fatalf("Expected the spoofed header to be deleted got %s", header)
+	}
+
+	// the tls state set by the mucp server
+	tctx := server.NewTLSContext(context.Background(), &state)
+	id, err := FromContext(tctx, TrustDomains("example.org"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id.String() != "spiffe://example.org/ns/prod/sa/web" {
+		t.Fatalf("Unexpected identity %s", id)
+	}
+
+	// the auth identifies the caller by the request only
+	a := NewAuth(auth.Issuer("example.org"))
+	if _, err := a.Inspect("spiffe://example.org/ns/prod/sa/web"); err == nil {
+		t.Fatal("Expected a token to be rejected")
+	}
+	acc, err = auth.InspectContext(tctx, a, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if acc.ID != id.String() || acc.Issuer != "example.org" {
+		t.Fatalf("Unexpected account %+v", acc)
+	}
+	if _, err := auth.InspectContext(context.Background(), a, ""); err != auth.ErrInvalidToken {
+		t.Fatalf("Expected an invalid token error got %v", err)
+	}
+	a = NewAuth(auth.Issuer("example.com"))
+	if _, err := auth.InspectContext(tctx, a, ""); err == nil {
+		t.Fatal("Expected the untrusted domain to be rejected")
+	}
+	if _, err := a.Generate("web"); err != ErrNotSupported {
+		t.Fatalf("Expected %v got %v", ErrNotSupported, err)
+	}
+}
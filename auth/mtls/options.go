@@ -0,0 +1,37 @@
+package mtls
+
+import "crypto/x509"
+
+// Options of the handler wrapper
+type Options struct {
+	// TrustDomains the callers are accepted from, any if empty
+	TrustDomains []string
+	// Require a caller identity, the requests without one are rejected
+	Require bool
+	// Roots verifying the certificates of the callers. If not set the
+	// certificates must have been verified by the tls handshake.
+	Roots *x509.CertPool
+}
+
+type Option func(o *Options)
+
+// TrustDomains the callers are accepted from
+func TrustDomains(td ...string) Option {
+	return func(o *Options) {
+		o.TrustDomains = td
+	}
+}
+
+// Require a caller identity
+func Require() Option {
+	return func(o *Options) {
+		o.Require = true
+	}
+}
+
+// Roots verifying the certificates of the callers
+func Roots(p *x509.CertPool) Option {
+	return func(o *Options) {
+		o.Roots = p
+	}
+}
@@ -0,0 +1,210 @@
+package oidc
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// minRefresh is the minimum interval the keys are fetched again at when a
+// token is signed with an unknown key
+const minRefresh = 10 * time.Second
+
+type jwk struct {
+	Kid string `json:"kid"`
+	Kty string `json:"kty"`
+	Use string `json:"use"`
+	// rsa
+	N string `json:"n"`
+	E string `json:"e"`
+	// ecdsa
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// publicKey of the jwk
+func (k *jwk) publicKey() (interface{}, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeInt(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeInt(k.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %s", k.Crv)
+		}
+		x, err := decodeInt(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeInt(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	}
+	return nil, fmt.Errorf("unsupported key type %s", k.Kty)
+}
+
+func decodeInt(s string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(s, "="))
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetBytes(b), nil
+}
+
+// keySet caches the keys of the issuer by id
+type keySet struct {
+	client *http.Client
+	issuer string
+	ttl    time.Duration
+
+	sync.Mutex
+	// url of the keys, discovered from the issuer if not set
+	url  string
+	keys map[string]interface{}
+	// time the keys were fetched and last attempted to be
+	fetched, attempted time.Time
+	// fetch in flight, shared by the callers waiting for it
+	inflight *fetchCall
+}
+
+type fetchCall struct {
+	done chan struct{}
+	err  error
+}
+
+// key returns the public key with the id, fetching the keys again if
+// they're stale or the key is unknown. The keys are fetched once at a time
+// without holding the lock.
+func (s *keySet) key(kid string) (interface{}, error) {
+	s.Lock()
+	k, ok := s.keys[kid]
+	if ok && time.Since(s.fetched) < s.ttl {
+		s.Unlock()
+		return k, nil
+	}
+
+	call := s.inflight
+	// limit the fetches for the tokens signed with unknown keys
+	if call == nil && time.Since(s.attempted) >= minRefresh {
+		s.attempted = time.Now()
+		call = &fetchCall{done: make(chan struct{})}
+		s.inflight = call
+		url := s.url
+		s.Unlock()
+
+		url, keys, err := s.fetch(url)
+
+		s.Lock()
+		if err == nil {
+			s.url, s.keys, s.fetched = url, keys, time.Now()
+		}
+		s.inflight = nil
+		s.Unlock()
+
+		call.err = err
+		close(call.done)
+	} else {
+		s.Unlock()
+	}
+
+	if call != nil {
+		<-call.done
+		if call.err != nil {
+			// use the stale key while the issuer is unavailable
+			if ok {
+				return k, nil
+			}
+			return nil, call.err
+		}
+		s.Lock()
+		k, ok = s.keys[kid]
+		s.Unlock()
+	}
+
+	if !ok {
+		return nil, fmt.Errorf("unknown key %s", kid)
+	}
+	return k, nil
+}
+
+// fetch the keys from the url, discovered from the issuer if not set
+func (s *keySet) fetch(url string) (string, map[string]interface{}, error) {
+	if len(url) == 0 {
+		var conf struct {
+			Issuer  string `json:"issuer"`
+			JWKSURI string `json:"jwks_uri"`
+		}
+		if err := s.get(strings.TrimSuffix(s.issuer, "/")+"/.well-known/openid-configuration", &conf); err != nil {
+			return "", nil, err
+		}
+		if conf.Issuer != s.issuer {
+			return "", nil, fmt.Errorf("discovered issuer %s doesn't match %s", conf.Issuer, s.issuer)
+		}
+		if len(conf.JWKSURI) == 0 {
+			return "", nil, fmt.Errorf("issuer %s has no jwks_uri", s.issuer)
+		}
+		url = conf.JWKSURI
+	}
+
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := s.get(url, &set); err != nil {
+		return "", nil, err
+	}
+
+	keys := make(map[string]interface{}, len(set.Keys))
+	for _, k := range set.Keys {
+		// skip the encryption keys
+		if len(k.Use) > 0 && k.Use != "sig" {
+			continue
+		}
+		pub, err := k.publicKey()
+		if err != nil {
+			// skip the keys of unsupported types
+			continue
+		}
+		keys[k.Kid] = pub
+	}
+
+	return url, keys, nil
+}
+
+func (s *keySet) get(url string, v interface{}) error {
+	rsp, err := s.client.Get(url)
+	if err != nil {
+		return err
+	}
+	defer rsp.Body.Close()
+
+	if rsp.StatusCode != http.StatusOK {
+		return fmt.Errorf("error fetching %s: %s", url, rsp.Status)
+	}
+	return json.NewDecoder(rsp.Body).Decode(v)
+}
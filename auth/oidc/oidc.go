@@ -0,0 +1,251 @@
+// Package oidc is an auth implementation validating the OIDC ID tokens and
+// OAuth2 JWT access tokens of an identity provider. The keys of the issuer
+// are discovered from its openid configuration and cached. The accounts and
+// tokens are issued by the identity provider so Generate and Token aren't
+// supported.
+package oidc
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/micro/go-micro/v2/auth"
+	"github.com/micro/go-micro/v2/auth/rules"
+)
+
+var (
+	// ErrNotSupported is returned by Generate and Token
+	ErrNotSupported = errors.New("oidc: accounts and tokens are issued by the identity provider")
+
+	// DefaultCacheTTL is the time the keys of the issuer are cached for
+	DefaultCacheTTL = time.Hour
+
+	// DefaultClient fetches the configuration and keys of the issuer
+	DefaultClient = &http.Client{Timeout: 10 * time.Second}
+
+	// signing methods accepted, the tokens signed with a shared secret or
+	// unsigned are rejected
+	validMethods = []string{"RS256", "RS384", "RS512", "ES256", "ES384", "ES512", "PS256", "PS384", "PS512"}
+)
+
+// metadataClaims are the claims copied to the metadata of the accounts
+var metadataClaims = []string{"email", "name", "preferred_username"}
+
+type oidc struct {
+	sync.Mutex
+	options     auth.Options
+	audience    []string
+	scopesClaim string
+	mapper      Mapper
+	keys        *keySet
+	rules       []*auth.Rule
+}
+
+// NewAuth returns an auth validating the tokens of the issuer e.g
+//
+//	oidc.NewAuth(
+//		auth.Issuer("https://accounts.google.com"),
+//		oidc.Audience("my-client-id"),
+//	)
+func NewAuth(opts ...auth.Option) auth.Auth {
+	o := new(oidc)
+	o.Init(opts...)
+	return o
+}
+
+func (o *oidc) String() string {
+	return "oidc"
+}
+
+func (o *oidc) Init(opts ...auth.Option) {
+	o.Lock()
+	defer o.Unlock()
+
+	for _, opt := range opts {
+		opt(&o.options)
+	}
+
+	keys := &keySet{
+		client: DefaultClient,
+		issuer: o.options.Issuer,
+		ttl:    DefaultCacheTTL,
+	}
+	o.audience, o.scopesClaim, o.mapper = nil, "", nil
+
+	if ctx := o.options.Context; ctx != nil {
+		if aud, ok := ctx.Value(audienceKey{}).([]string); ok {
+			o.audience = aud
+		}
+		if u, ok := ctx.Value(jwksURLKey{}).(string); ok {
+			keys.url = u
+		}
+		if c, ok := ctx.Value(scopesClaimKey{}).(string); ok {
+			o.scopesClaim = c
+		}
+		if m, ok := ctx.Value(mapperKey{}).(Mapper); ok {
+			o.mapper = m
+		}
+		if d, ok := ctx.Value(cacheTTLKey{}).(time.Duration); ok {
+			keys.ttl = d
+		}
+		if c, ok := ctx.Value(httpClientKey{}).(*http.Client); ok {
+			keys.client = c
+		}
+	}
+
+	o.keys = keys
+}
+
+func (o *oidc) Options() auth.Options {
+	o.Lock()
+	defer o.Unlock()
+	return o.options
+}
+
+func (o *oidc) Generate(id string, opts ...auth.GenerateOption) (*auth.Account, error) {
+	return nil, ErrNotSupported
+}
+
+func (o *oidc) Token(opts ...auth.TokenOption) (*auth.Token, error) {
+	return nil, ErrNotSupported
+}
+
+func (o *oidc) Grant(rule *auth.Rule) error {
+	o.Lock()
+	defer o.Unlock()
+	o.rules = append(o.rules, rule)
+	return nil
+}
+
+func (o *oidc) Revoke(rule *auth.Rule) error {
+	o.Lock()
+	defer o.Unlock()
+
+	rules := []*auth.Rule{}
+	for _, r := range o.rules {
+		if r.ID != rule.ID {
+			rules = append(rules, r)
+		}
+	}
+
+	o.rules = rules
+	return nil
+}
+
+func (o *oidc) Verify(acc *auth.Account, res *auth.Resource, opts ...auth.VerifyOption) error {
+	o.Lock()
+	defer o.Unlock()
+	return rules.Verify(o.rules, acc, res)
+}
+
+func (o *oidc) Rules(opts ...auth.RulesOption) ([]*auth.Rule, error) {
+	o.Lock()
+	defer o.Unlock()
+	return o.rules, nil
+}
+
+// Inspect validates the signature, expiry, issuer and audience of the token
+// and maps its claims to an account
+func (o *oidc) Inspect(token string) (*auth.Account, error) {
+	o.Lock()
+	issuer, audience, keys := o.options.Issuer, o.audience, o.keys
+	scopesClaim, mapper := o.scopesClaim, o.mapper
+	o.Unlock()
+
+	if len(issuer) == 0 {
+		return nil, errors.New("oidc: issuer not set")
+	}
+
+	parser := &jwt.Parser{ValidMethods: validMethods}
+	claims := jwt.MapClaims{}
+
+	_, err := parser.ParseWithClaims(token, claims, func(t *jwt.Token) (interface{}, error) {
+		kid, _ := t.Header["kid"].(string)
+		return keys.key(kid)
+	})
+	if err != nil {
+		return nil, auth.ErrInvalidToken
+	}
+
+	// the tokens must expire
+	if _, ok := claims["exp"]; !ok {
+		return nil, auth.ErrInvalidToken
+	}
+	if iss, _ := claims["iss"].(string); iss != issuer {
+		return nil, auth.ErrInvalidToken
+	}
+	if len(audience) > 0 && !matchAudience(claims["aud"], audience) {
+		return nil, auth.ErrInvalidToken
+	}
+
+	if mapper != nil {
+		return mapper(claims)
+	}
+	return account(claims, scopesClaim)
+}
+
+// matchAudience returns whether the aud claim has any of the audiences
+func matchAudience(aud interface{}, audience []string) bool {
+	for _, a := range claimStrings(aud) {
+		for _, b := range audience {
+			if a == b {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// account of the claims with the scopes of the scope and scp claims and
+// the claim set
+func account(claims jwt.MapClaims, scopesClaim string) (*auth.Account, error) {
+	sub, _ := claims["sub"].(string)
+	if len(sub) == 0 {
+		return nil, auth.ErrInvalidToken
+	}
+	iss, _ := claims["iss"].(string)
+
+	acc := &auth.Account{
+		ID:       sub,
+		Type:     "user",
+		Issuer:   iss,
+		Metadata: make(map[string]string),
+	}
+
+	// scope is space separated in access tokens
+	if s, ok := claims["scope"].(string); ok {
+		acc.Scopes = append(acc.Scopes, strings.Fields(s)...)
+	}
+	acc.Scopes = append(acc.Scopes, claimStrings(claims["scp"])...)
+	if len(scopesClaim) > 0 {
+		acc.Scopes = append(acc.Scopes, claimStrings(claims[scopesClaim])...)
+	}
+
+	for _, c := range metadataClaims {
+		if v, ok := claims[c].(string); ok {
+			acc.Metadata[c] = v
+		}
+	}
+
+	return acc, nil
+}
+
+// claimStrings of a claim which is a string or an array
+func claimStrings(v interface{}) []string {
+	switch s := v.(type) {
+	case string:
+		return []string{s}
+	case []interface{}:
+		list := make([]string, 0, len(s))
+		for _, i := range s {
+			list = append(list, fmt.Sprint(i))
+		}
+		return list
+	}
+	return nil
+}
@@ -0,0 +1,180 @@
+package oidc
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/micro/go-micro/v2/auth"
+)
+
+func TestInspect(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var fetches int
+	var issuer string
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":   issuer,
+			"jwks_uri": issuer + "/keys",
+		})
+	})
+	mux.HandleFunc("/keys", func(w http.ResponseWriter, r *http.Request) {
+		fetches++
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"keys": []map[string]string{{
+				"kid": "1",
+				"kty": "RSA",
+				"use": "sig",
+				"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			}},
+		})
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+	issuer = srv.URL
+
+	a := NewAuth(auth.Issuer(issuer), Audience("api"), ScopesClaim("groups"))
+
+	sign := func(kid string, claims jwt.MapClaims) string {
+		tok := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+		tok.Header["kid"] = kid
+		s, err := tok.SignedString(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return s
+	}
+	claims := func(mod func(jwt.MapClaims)) jwt.MapClaims {
+		c := jwt.MapClaims{
+			"iss":    issuer,
+			"sub":    "alice",
+			"aud":    []string{"web", "api"},
+			"exp":    time.Now().Add(time.Minute).Unix(),
+			"scope":  "read write",
+			"groups": []string{"admin"},
+			"email":  "alice@example.com",
+		}
+		if mod != nil {
+			mod(c)
+		}
+		return c
+	}
+
+	acc, err := a.Inspect(sign("1", claims(nil)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if acc.ID != "alice" || acc.Issuer != issuer || acc.Metadata["email"] != "alice@example.com" {
+		t.Fatalf("Unexpected account %+v", acc)
+	}
+	if len(acc.Scopes) != 3 || acc.Scopes[0] != "read" || acc.Scopes[1] != "write" || acc.Scopes[2] != "admin" {
+		t.Fatalf("Unexpected scopes %v", acc.Scopes)
+	}
+
+	invalid := map[string]string{
+		"audience":    sign("1", claims(func(c jwt.MapClaims) { c["aud"] = "web" })),
+		"issuer":      sign("1", claims(func(c jwt.MapClaims) { c["iss"] = "https://evil.example.com" })),
+		"expired":     sign("1", claims(func(c jwt.MapClaims) { c["exp"] = time.Now().Add(-time.Minute).Unix() })),
+		"no expiry":   sign("1", claims(func(c jwt.MapClaims) { delete(c, "exp") })),
+		"unknown key": sign("2", claims(nil)),
+	}
+
+	hmac, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, claims(nil)).SignedString([]byte("secret"))
+	invalid["shared secret"] = hmac
+
+	for name, tok := range invalid {
+		if _, err := a.Inspect(tok); err != auth.ErrInvalidToken {
+			t.Fatalf("Expected %s to be invalid got %v", name, err)
+		}
+	}
+
+	// the unknown key is fetched again at most once per interval
+	if fetches != 1 {
+		t.Fatalf("Expected the keys to be fetched once got %d", fetches)
+	}
+
+	if _, err := a.Generate("bob"); err != ErrNotSupported {
+		t.Fatalf("Expected not supported got %v", err)
+	}
+}
+
+func TestKeyFetch(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var fetches int32
+	release := make(chan struct{})
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&fetches, 1)
+		<-release
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"keys": []map[string]string{{
+				"kid": "1",
+				"kty": "RSA",
+				"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			}},
+		})
+	}))
+	defer srv.Close()
+
+	s := &keySet{client: DefaultClient, url: srv.URL, ttl: time.Hour}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 10)
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := s.key("1")
+			errs <- err
+		}()
+	}
+
+	// the lock isn't held while the keys are fetched
+	for atomic.LoadInt32(&fetches) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	locked := make(chan struct{})
+	go func() {
+		s.Lock()
+		s.Unlock()
+		close(locked)
+	}()
+	select {
+	case <-locked:
+	case <-time.After(time.Second):
+		t.Fatal("Expected the lock to be released during the fetch")
+	}
+
+	close(release)
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := atomic.LoadInt32(&fetches); n != 1 {
+		t.Fatalf("Expected the keys to be fetched once got %d", n)
+	}
+}
@@ -0,0 +1,61 @@
+package oidc
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/micro/go-micro/v2/auth"
+)
+
+type audienceKey struct{}
+type jwksURLKey struct{}
+type scopesClaimKey struct{}
+type mapperKey struct{}
+type cacheTTLKey struct{}
+type httpClientKey struct{}
+
+// Mapper maps the claims of a validated token to an account
+type Mapper func(claims map[string]interface{}) (*auth.Account, error)
+
+func setAuthOption(k, v interface{}) auth.Option {
+	return func(o *auth.Options) {
+		if o.Context == nil {
+			o.Context = context.Background()
+		}
+		o.Context = context.WithValue(o.Context, k, v)
+	}
+}
+
+// Audience of the tokens. A token is valid if any of its audiences is one
+// of them. The audience isn't checked unless set.
+func Audience(aud ...string) auth.Option {
+	return setAuthOption(audienceKey{}, aud)
+}
+
+// JWKSURL sets the url of the keys of the issuer rather than discovering it
+// from its openid configuration
+func JWKSURL(url string) auth.Option {
+	return setAuthOption(jwksURLKey{}, url)
+}
+
+// ScopesClaim sets a claim the scopes of the accounts are read from in
+// addition to the scope and scp claims e.g groups or roles
+func ScopesClaim(name string) auth.Option {
+	return setAuthOption(scopesClaimKey{}, name)
+}
+
+// WithMapper sets the mapper of the claims to accounts
+func WithMapper(m Mapper) auth.Option {
+	return setAuthOption(mapperKey{}, m)
+}
+
+// CacheTTL sets the time the keys of the issuer are cached for
+func CacheTTL(d time.Duration) auth.Option {
+	return setAuthOption(cacheTTLKey{}, d)
+}
+
+// HTTPClient sets the client the configuration and keys are fetched with
+func HTTPClient(c *http.Client) auth.Option {
+	return setAuthOption(httpClientKey{}, c)
+}
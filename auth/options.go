@@ -44,6 +44,8 @@ type Options struct {
 	Client client.Client
 	// Addrs sets the addresses of auth
 	Addrs []string
+	// Context should contain all implementation specific options
+	Context context.Context
 }
 
 type Option func(o *Options)
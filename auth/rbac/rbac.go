@@ -0,0 +1,278 @@
+// Package rbac is a rules engine authorizing requests to endpoints by the
+// service, endpoint and verb called, the scopes of the caller and the
+// metadata of the request. The policies can be loaded from a config or a
+// store and are reloaded when they change.
+package rbac
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/micro/go-micro/v2/auth"
+	"github.com/micro/go-micro/v2/metadata"
+)
+
+var (
+	// ErrForbidden is returned when a policy denies the request or none
+	// allows it
+	ErrForbidden = auth.ErrForbidden
+
+	// ReadPrefixes are the prefixes of the methods of the endpoints which
+	// are read, the other endpoints are write
+	ReadPrefixes = []string{"Get", "List", "Read", "Search", "Query", "Watch", "Stream"}
+)
+
+// Effect of a policy
+type Effect string
+
+const (
+	// Allow the matching requests
+	Allow Effect = "allow"
+	// Deny the matching requests
+	Deny Effect = "deny"
+)
+
+// Verbs of the endpoints
+const (
+	VerbRead  = "read"
+	VerbWrite = "write"
+)
+
+// Operators of the conditions
+const (
+	// OpEquals matches a value equal to any of the values
+	OpEquals = "eq"
+	// OpNotEquals matches a value equal to none of the values
+	OpNotEquals = "neq"
+	// OpPrefix matches a value with any of the values as a prefix
+	OpPrefix = "prefix"
+	// OpExists matches any value
+	OpExists = "exists"
+)
+
+// Policy allows or denies the requests it matches. The empty fields match
+// every request.
+type Policy struct {
+	ID string `json:"id"`
+	// Service called, a glob e.g go.micro.srv.*
+	Service string `json:"service,omitempty"`
+	// Endpoint called, a glob e.g Notes.*
+	Endpoint string `json:"endpoint,omitempty"`
+	// Verbs of the endpoint called e.g read
+	Verbs []string `json:"verbs,omitempty"`
+	// Scopes the caller has any of, * matches any account
+	Scopes []string `json:"scopes,omitempty"`
+	// Conditions on the metadata of the request, all must match
+	Conditions []Condition `json:"conditions,omitempty"`
+	// Effect of the policy
+	Effect Effect `json:"effect"`
+	// Priority of the policy, the policies with a higher priority are
+	// evaluated first and deny before allow at the same priority
+	Priority int `json:"priority,omitempty"`
+}
+
+// Condition on a metadata value of the request
+type Condition struct {
+	Key    string   `json:"key"`
+	Op     string   `json:"op"`
+	Values []string `json:"values,omitempty"`
+}
+
+// Request to authorize
+type Request struct {
+	Service  string
+	Endpoint string
+	// Account of the caller, nil if anonymous
+	Account  *auth.Account
+	Metadata metadata.Metadata
+}
+
+// Engine evaluates the policies
+type Engine interface {
+	// Load validates and replaces the policies
+	Load(policies ...*Policy) error
+	// Policies loaded ordered by evaluation
+	Policies() []*Policy
+	// Authorize the request, ErrForbidden if denied
+	Authorize(ctx context.Context, req *Request) error
+}
+
+type engine struct {
+	sync.RWMutex
+	policies []*Policy
+}
+
+// NewEngine returns an engine with the policies. It denies every request
+// until policies are loaded.
+func NewEngine(policies ...*Policy) (Engine, error) {
+	e := new(engine)
+	if err := e.Load(policies...); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// Verb of the endpoint by the prefix of its method
+func Verb(endpoint string) string {
+	method := endpoint
+	if i := strings.LastIndex(endpoint, "."); i >= 0 {
+		method = endpoint[i+1:]
+	}
+	for _, p := range ReadPrefixes {
+		if strings.HasPrefix(method, p) {
+			return VerbRead
+		}
+	}
+	return VerbWrite
+}
+
+// Validate the policy
+func (p *Policy) Validate() error {
+	if p.Effect != Allow && p.Effect != Deny {
+		return fmt.Errorf("policy %s: invalid effect %q", p.ID, p.Effect)
+	}
+	for _, g := range []string{p.Service, p.Endpoint} {
+		if _, err := path.Match(g, ""); err != nil {
+			return fmt.Errorf("policy %s: invalid pattern %q", p.ID, g)
+		}
+	}
+	for _, c := range p.Conditions {
+		switch c.Op {
+		case OpEquals, OpNotEquals, OpPrefix, OpExists:
+		default:
+			return fmt.Errorf("policy %s: invalid operator %q", p.ID, c.Op)
+		}
+		if len(c.Key) == 0 {
+			return fmt.Errorf("policy %s: condition without a key", p.ID)
+		}
+	}
+	return nil
+}
+
+// Match returns whether the policy applies to the request
+func (p *Policy) Match(req *Request) bool {
+	if !glob(p.Service, req.Service) || !glob(p.Endpoint, req.Endpoint) {
+		return false
+	}
+	if len(p.Verbs) > 0 && !include(p.Verbs, Verb(req.Endpoint)) {
+		return false
+	}
+	if len(p.Scopes) > 0 {
+		if req.Account == nil {
+			return false
+		}
+		if !include(p.Scopes, auth.ScopeAccount) && !includeAny(p.Scopes, req.Account.Scopes) {
+			return false
+		}
+	}
+	for _, c := range p.Conditions {
+		if !c.Match(req.Metadata) {
+			return false
+		}
+	}
+	return true
+}
+
+// Match returns whether the metadata meets the condition
+func (c *Condition) Match(md metadata.Metadata) bool {
+	v, ok := md.Get(c.Key)
+
+	switch c.Op {
+	case OpExists:
+		return ok
+	case OpEquals:
+		return ok && include(c.Values, v)
+	case OpNotEquals:
+		return !ok || !include(c.Values, v)
+	case OpPrefix:
+		if !ok {
+			return false
+		}
+		for _, p := range c.Values {
+			if strings.HasPrefix(v, p) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (e *engine) Load(policies ...*Policy) error {
+	sorted := make([]*Policy, 0, len(policies))
+	for _, p := range policies {
+		if p == nil {
+			continue
+		}
+		if err := p.Validate(); err != nil {
+			return err
+		}
+		sorted = append(sorted, p)
+	}
+
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].Priority != sorted[j].Priority {
+			return sorted[i].Priority > sorted[j].Priority
+		}
+		return sorted[i].Effect == Deny && sorted[j].Effect == Allow
+	})
+
+	e.Lock()
+	e.policies = sorted
+	e.Unlock()
+
+	return nil
+}
+
+func (e *engine) Policies() []*Policy {
+	e.RLock()
+	defer e.RUnlock()
+	return e.policies
+}
+
+func (e *engine) Authorize(ctx context.Context, req *Request) error {
+	e.RLock()
+	policies := e.policies
+	e.RUnlock()
+
+	for _, p := range policies {
+		if !p.Match(req) {
+			continue
+		}
+		if p.Effect == Allow {
+			return nil
+		}
+		return ErrForbidden
+	}
+
+	return ErrForbidden
+}
+
+func glob(pattern, s string) bool {
+	if len(pattern) == 0 || pattern == "*" {
+		return true
+	}
+	ok, _ := path.Match(pattern, s)
+	return ok
+}
+
+func include(slice []string, v string) bool {
+	for _, s := range slice {
+		if s == v {
+			return true
+		}
+	}
+	return false
+}
+
+func includeAny(slice []string, values []string) bool {
+	for _, v := range values {
+		if include(slice, v) {
+			return true
+		}
+	}
+	return false
+}
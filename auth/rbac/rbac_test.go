@@ -0,0 +1,120 @@
+package rbac
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/micro/go-micro/v2/auth"
+	"github.com/micro/go-micro/v2/config"
+	"github.com/micro/go-micro/v2/config/source/memory"
+	"github.com/micro/go-micro/v2/metadata"
+	"github.com/micro/go-micro/v2/store"
+	mstore "github.com/micro/go-micro/v2/store/memory"
+)
+
+func TestAuthorize(t *testing.T) {
+	e, err := NewEngine(
+		&Policy{ID: "readers", Service: "go.micro.srv.*", Verbs: []string{VerbRead}, Scopes: []string{"*"}, Effect: Allow},
+		&Policy{ID: "admins", Service: "go.micro.srv.notes", Scopes: []string{"admin"}, Effect: Allow},
+		&Policy{ID: "frozen", Endpoint: "Notes.Delete", Effect: Deny, Conditions: []Condition{
+			{Key: "Micro-Region", Op: OpEquals, Values: []string{"eu"}},
+		}},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	alice := &auth.Account{ID: "alice"}
+	admin := &auth.Account{ID: "bob", Scopes: []string{"admin"}}
+
+	tests := []struct {
+		name     string
+		endpoint string
+		account  *auth.Account
+		md       metadata.Metadata
+		allowed  bool
+	}{
+		{"anonymous read", "Notes.List", nil, nil, false},
+		{"account read", "Notes.List", alice, nil, true},
+		{"account write", "Notes.Create", alice, nil, false},
+		{"admin write", "Notes.Create", admin, nil, true},
+		{"admin delete", "Notes.Delete", admin, metadata.Metadata{"Micro-Region": "us"}, true},
+		{"admin delete frozen", "Notes.Delete", admin, metadata.Metadata{"Micro-Region": "eu"}, false},
+	}
+
+	for _, tt := range tests {
+		err := e.Authorize(context.Background(), &Request{
+			Service:  "go.micro.srv.notes",
+			Endpoint: tt.endpoint,
+			Account:  tt.account,
+			Metadata: tt.md,
+		})
+		if allowed := err == nil; allowed != tt.allowed {
+			t.Errorf("%s: expected allowed %v got %v", tt.name, tt.allowed, allowed)
+		}
+	}
+
+	if err := e.Load(&Policy{ID: "bad", Effect: "maybe"}); err == nil {
+		t.Fatal("Expected an invalid effect error")
+	}
+	if len(e.Policies()) != 3 {
+		t.Fatal("Expected the invalid policies not to be loaded")
+	}
+}
+
+func TestWatch(t *testing.T) {
+	req := &Request{Service: "go.micro.srv.notes", Endpoint: "Notes.List"}
+
+	// config
+	c, err := config.NewConfig(config.WithSource(memory.NewSource(memory.WithJSON([]byte(
+		`{"rbac": [{"id": "public", "effect": "deny"}]}`,
+	)))))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	e, _ := NewEngine()
+	if err := WatchConfig(e, c, "rbac"); err != nil {
+		t.Fatal(err)
+	}
+	if err := e.Authorize(context.Background(), req); err != ErrForbidden {
+		t.Fatalf("Expected forbidden got %v", err)
+	}
+	err = c.Stage().Set([]*Policy{{ID: "public", Effect: Allow}}, "rbac").Apply()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := e.Authorize(context.Background(), req); err != nil {
+		t.Fatalf("Expected the reloaded policy to allow got %v", err)
+	}
+	// rejected
+	if err := c.Stage().Set([]*Policy{{ID: "bad"}}, "rbac").Apply(); err == nil {
+		t.Fatal("Expected the invalid policies to be rejected")
+	}
+
+	// store
+	s := mstore.NewStore()
+	e, _ = NewEngine()
+	w, err := WatchStore(e, s, "rbac/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Stop()
+
+	b, _ := json.Marshal(&Policy{Effect: Allow})
+	s.Write(&store.Record{Key: "rbac/public", Value: b})
+
+	deadline := time.Now().Add(time.Second)
+	for e.Authorize(context.Background(), req) != nil {
+		if time.Now().After(deadline) {
+			t.Fatal("Timed out waiting for the policies to reload")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if p := e.Policies(); p[0].ID != "rbac/public" {
+		t.Fatalf("Expected the policy id to default to its key got %s", p[0].ID)
+	}
+}
@@ -0,0 +1,78 @@
+package rbac
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/micro/go-micro/v2/config"
+	"github.com/micro/go-micro/v2/config/reader"
+	"github.com/micro/go-micro/v2/logger"
+	"github.com/micro/go-micro/v2/store"
+)
+
+// WatchConfig loads the policies at the path of the config into the engine
+// every time they change. The policies are a list e.g
+//
+//	{"rbac": [{"id": "readers", "verbs": ["read"], "scopes": ["*"], "effect": "allow"}]}
+//
+// A change with invalid policies is rejected.
+func WatchConfig(e Engine, c config.Config, path ...string) error {
+	return c.Subscribe(config.OnChange(func(ch []config.Change, v reader.Values) error {
+		var policies []*Policy
+		if err := v.Get(path...).Scan(&policies); err != nil {
+			return err
+		}
+		return e.Load(policies...)
+	}, path...))
+}
+
+// WatchStore loads the policies with the key prefix in the store into the
+// engine and again every time they change until the watcher is stopped.
+// A policy is a json record.
+func WatchStore(e Engine, s store.Store, prefix string) (store.Watcher, error) {
+	w, err := store.Watch(s, store.WatchPrefix(prefix))
+	if err != nil {
+		return nil, err
+	}
+	if err := loadStore(e, s, prefix); err != nil {
+		w.Stop()
+		return nil, err
+	}
+
+	go func() {
+		for {
+			if _, err := w.Next(); err != nil {
+				if err != store.ErrWatcherStopped {
+					logger.Errorf("Error watching the rbac policies: %v", err)
+				}
+				return
+			}
+			if err := loadStore(e, s, prefix); err != nil {
+				logger.Errorf("Error loading the rbac policies: %v", err)
+			}
+		}
+	}()
+
+	return w, nil
+}
+
+func loadStore(e Engine, s store.Store, prefix string) error {
+	recs, err := s.Read(prefix, store.ReadPrefix())
+	if err != nil && err != store.ErrNotFound {
+		return err
+	}
+
+	policies := make([]*Policy, 0, len(recs))
+	for _, r := range recs {
+		p := new(Policy)
+		if err := json.Unmarshal(r.Value, p); err != nil {
+			return fmt.Errorf("policy %s: %v", r.Key, err)
+		}
+		if len(p.ID) == 0 {
+			p.ID = r.Key
+		}
+		policies = append(policies, p)
+	}
+
+	return e.Load(policies...)
+}
@@ -0,0 +1,42 @@
+package rbac
+
+import (
+	"context"
+	"strings"
+
+	"github.com/micro/go-micro/v2/auth"
+	"github.com/micro/go-micro/v2/errors"
+	"github.com/micro/go-micro/v2/metadata"
+	"github.com/micro/go-micro/v2/server"
+)
+
+// NewHandlerWrapper authorizes the requests to the handlers with the
+// engine. It uses the account set in the context by the auth wrapper so
+// it must wrap the handlers after it.
+func NewHandlerWrapper(e Engine) server.HandlerWrapper {
+	return func(h server.HandlerFunc) server.HandlerFunc {
+		return func(ctx context.Context, req server.Request, rsp interface{}) error {
+			// debug endpoints are excluded like auth
+			if strings.HasPrefix(req.Endpoint(), "Debug.") {
+				return h(ctx, req, rsp)
+			}
+
+			acc, _ := auth.AccountFromContext(ctx)
+			md, _ := metadata.FromContext(ctx)
+
+			err := e.Authorize(ctx, &Request{
+				Service:  req.Service(),
+				Endpoint: req.Endpoint(),
+				Account:  acc,
+				Metadata: md,
+			})
+			if err != nil && acc != nil {
+				return errors.Forbidden(req.Service(), "Forbidden call made to %v:%v by %v", req.Service(), req.Endpoint(), acc.ID)
+			} else if err != nil {
+				return errors.Unauthorized(req.Service(), "Unauthorized call made to %v:%v", req.Service(), req.Endpoint())
+			}
+
+			return h(ctx, req, rsp)
+		}
+	}
+}
@@ -1,7 +1,14 @@
 package jwt
 
 import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
 	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"sync"
 	"time"
 
 	"github.com/dgrijalva/jwt-go"
@@ -9,6 +16,9 @@ import (
 	"github.com/micro/go-micro/v2/auth/token"
 )
 
+// KeyBits is the size of the keys generated by Rotate
+var KeyBits = 2048
+
 // authClaims to be encoded in the JWT
 type authClaims struct {
 	Type     string            `json:"type"`
@@ -18,29 +28,83 @@ type authClaims struct {
 	jwt.StandardClaims
 }
 
+// key used to sign or verify tokens
+type key struct {
+	id string
+	// private key, nil if only used to verify
+	private *rsa.PrivateKey
+	public  *rsa.PublicKey
+	// time the key stopped signing tokens
+	retired time.Time
+}
+
 // JWT implementation of token provider
 type JWT struct {
 	opts token.Options
+
+	sync.RWMutex
+	// keys by age, the last key with a private key signs the tokens
+	keys []*key
 }
 
 // NewTokenProvider returns an initialized basic provider
 func NewTokenProvider(opts ...token.Option) token.Provider {
-	return &JWT{
+	j := &JWT{
 		opts: token.NewOptions(opts...),
 	}
+	if k := parseKey(j.opts.PrivateKey, j.opts.PublicKey); k != nil {
+		j.keys = append(j.keys, k)
+	}
+	return j
 }
 
-// Generate a new JWT
-func (j *JWT) Generate(acc *auth.Account, opts ...token.GenerateOption) (*token.Token, error) {
-	// decode the private key
-	priv, err := base64.StdEncoding.DecodeString(j.opts.PrivateKey)
-	if err != nil {
-		return nil, err
+// parseKey parses the base64 encoded pem keys, nil if neither are valid
+func parseKey(private, public string) *key {
+	k := new(key)
+
+	if priv, err := base64.StdEncoding.DecodeString(private); err == nil && len(priv) > 0 {
+		if pk, err := jwt.ParseRSAPrivateKeyFromPEM(priv); err == nil {
+			k.private = pk
+			k.public = &pk.PublicKey
+		}
+	}
+	if pub, err := base64.StdEncoding.DecodeString(public); err == nil && len(pub) > 0 {
+		if pk, err := jwt.ParseRSAPublicKeyFromPEM(pub); err == nil {
+			k.public = pk
+		}
 	}
 
-	// parse the private key
-	key, err := jwt.ParseRSAPrivateKeyFromPEM(priv)
-	if err != nil {
+	if k.public == nil {
+		return nil
+	}
+	k.id = keyID(k.public)
+	return k
+}
+
+// keyID is derived from the public key so every service loading the same
+// key uses the same id
+func keyID(pub *rsa.PublicKey) string {
+	der, _ := x509.MarshalPKIXPublicKey(pub)
+	sum := sha256.Sum256(der)
+	return base64.RawURLEncoding.EncodeToString(sum[:12])
+}
+
+// signer returns the key signing the tokens
+func (j *JWT) signer() *key {
+	j.RLock()
+	defer j.RUnlock()
+	for i := len(j.keys) - 1; i >= 0; i-- {
+		if j.keys[i].private != nil {
+			return j.keys[i]
+		}
+	}
+	return nil
+}
+
+// Generate a new JWT
+func (j *JWT) Generate(acc *auth.Account, opts ...token.GenerateOption) (*token.Token, error) {
+	k := j.signer()
+	if k == nil {
 		return nil, token.ErrEncodingToken
 	}
 
@@ -56,7 +120,8 @@ func (j *JWT) Generate(acc *auth.Account, opts ...token.GenerateOption) (*token.
 			ExpiresAt: expiry.Unix(),
 		},
 	})
-	tok, err := t.SignedString(key)
+	t.Header["kid"] = k.id
+	tok, err := t.SignedString(k.private)
 	if err != nil {
 		return nil, err
 	}
@@ -71,15 +136,37 @@ func (j *JWT) Generate(acc *auth.Account, opts ...token.GenerateOption) (*token.
 
 // Inspect a JWT
 func (j *JWT) Inspect(t string) (*auth.Account, error) {
-	// decode the public key
-	pub, err := base64.StdEncoding.DecodeString(j.opts.PublicKey)
+	claims, err := j.parse(t)
 	if err != nil {
 		return nil, err
 	}
 
-	// parse the public key
-	res, err := jwt.ParseWithClaims(t, &authClaims{}, func(token *jwt.Token) (interface{}, error) {
-		return jwt.ParseRSAPublicKeyFromPEM(pub)
+	// return the token
+	return &auth.Account{
+		ID:       claims.Subject,
+		Issuer:   claims.Issuer,
+		Type:     claims.Type,
+		Scopes:   claims.Scopes,
+		Metadata: claims.Metadata,
+	}, nil
+}
+
+// Expiry of a valid JWT
+func (j *JWT) Expiry(t string) (time.Time, error) {
+	claims, err := j.parse(t)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.Unix(claims.ExpiresAt, 0), nil
+}
+
+// parse and validate the claims of a JWT
+func (j *JWT) parse(t string) (*authClaims, error) {
+	// parse the token, verifying it with the key of its id
+	parser := &jwt.Parser{ValidMethods: []string{jwt.SigningMethodRS256.Alg()}}
+	res, err := parser.ParseWithClaims(t, &authClaims{}, func(tok *jwt.Token) (interface{}, error) {
+		kid, _ := tok.Header["kid"].(string)
+		return j.publicKey(kid)
 	})
 	if err != nil {
 		return nil, token.ErrInvalidToken
@@ -93,15 +180,94 @@ func (j *JWT) Inspect(t string) (*auth.Account, error) {
 	if !ok {
 		return nil, token.ErrInvalidToken
 	}
+	return claims, nil
+}
 
-	// return the token
-	return &auth.Account{
-		ID:       claims.Subject,
-		Issuer:   claims.Issuer,
-		Type:     claims.Type,
-		Scopes:   claims.Scopes,
-		Metadata: claims.Metadata,
-	}, nil
+// publicKey with the id. The tokens without an id were signed before the
+// keys had ids so they're verified with the first key.
+func (j *JWT) publicKey(kid string) (*rsa.PublicKey, error) {
+	j.RLock()
+	defer j.RUnlock()
+
+	if len(j.keys) == 0 {
+		return nil, token.ErrInvalidToken
+	}
+	if len(kid) == 0 {
+		return j.keys[0].public, nil
+	}
+	for _, k := range j.keys {
+		if k.id == kid {
+			return k.public, nil
+		}
+	}
+	return nil, token.ErrInvalidToken
+}
+
+// Rotate generates a new key to sign the tokens. The retired keys still
+// verify the tokens they signed for the retention period.
+func (j *JWT) Rotate(retain time.Duration) error {
+	priv, err := rsa.GenerateKey(rand.Reader, KeyBits)
+	if err != nil {
+		return err
+	}
+	k := &key{
+		id:      keyID(&priv.PublicKey),
+		private: priv,
+		public:  &priv.PublicKey,
+	}
+
+	j.Lock()
+	defer j.Unlock()
+
+	now := time.Now()
+	keys := make([]*key, 0, len(j.keys)+1)
+
+	for _, old := range j.keys {
+		if old.retired.IsZero() {
+			old.retired = now
+		}
+		if now.Sub(old.retired) < retain {
+			keys = append(keys, old)
+		}
+	}
+
+	j.keys = append(keys, k)
+	return nil
+}
+
+// jwk is the json web key of a public key
+type jwk struct {
+	Kty string `json:"kty"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+}
+
+// JWKS returns the json web key set of the keys verifying the tokens
+func (j *JWT) JWKS() ([]byte, error) {
+	j.RLock()
+	defer j.RUnlock()
+
+	set := struct {
+		Keys []jwk `json:"keys"`
+	}{
+		Keys: make([]jwk, 0, len(j.keys)),
+	}
+
+	for _, k := range j.keys {
+		set.Keys = append(set.Keys, jwk{
+			Kty: "RSA",
+			Use: "sig",
+			Alg: jwt.SigningMethodRS256.Alg(),
+			Kid: k.id,
+			N:   base64.RawURLEncoding.EncodeToString(k.public.N.Bytes()),
+			E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(k.public.E)).Bytes()),
+		})
+	}
+
+	return json.Marshal(set)
 }
 
 // String returns JWT
@@ -9,6 +9,8 @@ type Broker interface {
 	Connect() error
 	Disconnect() error
 	Publish(topic string, m *Message, opts ...PublishOption) error
+	// Subscribe to a topic or a topic pattern e.g "orders.*" or "audit.#"
+	// matched natively or by the broker filtering the topics (see MatchTopic)
 	Subscribe(topic string, h Handler, opts ...SubscribeOption) (Subscriber, error)
 	String() string
 }
@@ -27,7 +29,14 @@ type Message struct {
 type Event interface {
 	Topic() string
 	Message() *Message
+	// Ack acknowledges the message was processed
 	Ack() error
+	// Nack rejects the message to be redelivered according
+	// to the redelivery policy of the subscription
+	Nack() error
+	// Requeue returns the message to be redelivered immediately
+	// without counting it as a failed delivery attempt
+	Requeue() error
 	Error() error
 }
 
@@ -0,0 +1,98 @@
+package broker
+
+import (
+	"fmt"
+
+	"github.com/micro/go-micro/v2/util/compress"
+)
+
+var (
+	// HeaderContentEncoding is the compression of the message body
+	HeaderContentEncoding = "Content-Encoding"
+
+	// Compressors are the compressions supported by the Compress publish option
+	// keyed by content encoding. Messages are decompressed before they're
+	// handled if their content encoding is known.
+	Compressors = compress.Compressors
+)
+
+// Compressor compresses message bodies
+type Compressor = compress.Compressor
+
+// CompressMessage returns a copy of the message with the body compressed
+// with the content encoding of the Compress publish option. The message is
+// returned as is without the option or if it's already compressed.
+func CompressMessage(msg *Message, opts ...PublishOption) (*Message, error) {
+	var options PublishOptions
+	for _, o := range opts {
+		o(&options)
+	}
+
+	if len(options.Compression) == 0 || len(msg.Header[HeaderContentEncoding]) > 0 {
+		return msg, nil
+	}
+
+	c, ok := Compressors[options.Compression]
+	if !ok {
+		return nil, fmt.Errorf("unknown compression %s", options.Compression)
+	}
+
+	body, err := c.Compress(msg.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	m := &Message{
+		Header: make(map[string]string, len(msg.Header)+1),
+		Body:   body,
+	}
+	for k, v := range msg.Header {
+		m.Header[k] = v
+	}
+	m.Header[HeaderContentEncoding] = c.String()
+
+	return m, nil
+}
+
+// decompressedEvent is an event with the message decompressed
+type decompressedEvent struct {
+	Event
+	message *Message
+}
+
+func (e *decompressedEvent) Message() *Message {
+	return e.message
+}
+
+// DecompressHandler decompresses the messages with a known content encoding
+// before passing them to the handler. Brokers wrap subscription handlers with it.
+func DecompressHandler(h Handler) Handler {
+	return func(e Event) error {
+		msg := e.Message()
+		if msg == nil || len(msg.Header[HeaderContentEncoding]) == 0 {
+			return h(e)
+		}
+
+		c, ok := Compressors[msg.Header[HeaderContentEncoding]]
+		if !ok {
+			return h(e)
+		}
+
+		body, err := c.Decompress(msg.Body)
+		if err != nil {
+			return fmt.Errorf("error decompressing message: %v", err)
+		}
+
+		m := &Message{
+			Header: make(map[string]string, len(msg.Header)),
+			Body:   body,
+		}
+		for k, v := range msg.Header {
+			if k != HeaderContentEncoding {
+				m.Header[k] = v
+			}
+		}
+
+		return h(&decompressedEvent{Event: e, message: m})
+	}
+}
@@ -0,0 +1,86 @@
+package broker
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+const (
+	// DeadLetterTopicHeader is the topic the message was published to
+	DeadLetterTopicHeader = "Micro-Dead-Letter-Topic"
+	// DeadLetterQueueHeader is the queue of the subscriber which failed
+	DeadLetterQueueHeader = "Micro-Dead-Letter-Queue"
+	// DeadLetterErrorHeader is the error of the last attempt
+	DeadLetterErrorHeader = "Micro-Dead-Letter-Error"
+	// DeadLetterAttemptsHeader is the number of times the handler failed
+	DeadLetterAttemptsHeader = "Micro-Dead-Letter-Attempts"
+	// DeadLetterTimeHeader is the time the message was dead lettered
+	DeadLetterTimeHeader = "Micro-Dead-Letter-Time"
+)
+
+var (
+	// DefaultMaxAttempts is the number of times a handler is invoked
+	// before a message is dead lettered if the attempts are not set
+	DefaultMaxAttempts = 3
+)
+
+// DeadLetterHandler wraps the handler of a subscription to publish the messages it
+// fails to process to the dead letter topic of the options. The handler is invoked up
+// to the max attempts, a panic counting as a failure. Once published the message is
+// acked. Brokers call it when subscribing so the option works with every broker.
+func DeadLetterHandler(b Broker, h Handler, opts SubscribeOptions) Handler {
+	if len(opts.DeadLetterTopic) == 0 {
+		return h
+	}
+
+	attempts := opts.MaxAttempts
+	if attempts <= 0 {
+		attempts = DefaultMaxAttempts
+	}
+
+	return func(e Event) error {
+		var err error
+
+		for i := 0; i < attempts; i++ {
+			if err = safeHandle(h, e); err == nil {
+				return nil
+			}
+		}
+
+		msg := e.Message()
+		header := make(map[string]string, len(msg.Header)+5)
+		for k, v := range msg.Header {
+			header[k] = v
+		}
+		header[DeadLetterTopicHeader] = e.Topic()
+		header[DeadLetterErrorHeader] = err.Error()
+		header[DeadLetterAttemptsHeader] = strconv.Itoa(attempts)
+		header[DeadLetterTimeHeader] = time.Now().Format(time.RFC3339)
+		if len(opts.Queue) > 0 {
+			header[DeadLetterQueueHeader] = opts.Queue
+		}
+
+		// the broker handles the error as usual if the message can't be published
+		if perr := b.Publish(opts.DeadLetterTopic, &Message{Header: header, Body: msg.Body}); perr != nil {
+			return fmt.Errorf("%v: failed to publish to dead letter topic %s: %v", err, opts.DeadLetterTopic, perr)
+		}
+
+		// messages are only acked by the broker with auto ack
+		if !opts.AutoAck {
+			return e.Ack()
+		}
+
+		return nil
+	}
+}
+
+// safeHandle calls the handler returning panics as errors
+func safeHandle(h Handler, e Event) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic recovered: %v", r)
+		}
+	}()
+	return h(e)
+}
@@ -0,0 +1,110 @@
+// Package dedupe suppresses the handling of duplicate messages. Brokers deliver
+// messages at least once so a message may be redelivered after it was handled
+// e.g when consumers rebalance. The ids of handled messages are tracked in the
+// store for a TTL and messages with an id already handled are acked without
+// calling the handler.
+package dedupe
+
+import (
+	"time"
+
+	"github.com/micro/go-micro/v2/broker"
+	"github.com/micro/go-micro/v2/broker/outbox"
+	"github.com/micro/go-micro/v2/logger"
+	"github.com/micro/go-micro/v2/store"
+)
+
+var (
+	// DefaultPrefix of the keys of the message ids
+	DefaultPrefix = "dedupe/"
+	// DefaultTTL of the message ids
+	DefaultTTL = 24 * time.Hour
+	// DefaultHeaders are the headers used as the id of a message. The outbox id
+	// is set by the outbox relay and the micro id by the client on publish.
+	DefaultHeaders = []string{outbox.HeaderID, "Micro-Id"}
+)
+
+type dedupe struct {
+	opts Options
+}
+
+func (d *dedupe) key(topic, id string) string {
+	return d.opts.Prefix + topic + "/" + id
+}
+
+// handled returns true if the message with the key was handled
+func (d *dedupe) handled(key string) (bool, error) {
+	_, err := d.opts.Store.Read(key, store.ReadFrom(d.opts.Database, d.opts.Table))
+	if err == store.ErrNotFound {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (d *dedupe) wrap(h broker.Handler) broker.Handler {
+	return func(e broker.Event) error {
+		msg := e.Message()
+		if msg == nil {
+			return h(e)
+		}
+
+		id := d.opts.ID(e.Topic(), msg)
+		if len(id) == 0 {
+			return h(e)
+		}
+
+		key := d.key(e.Topic(), id)
+
+		// a failing store fails the handling so the message is redelivered
+		ok, err := d.handled(key)
+		if err != nil {
+			return err
+		}
+		if ok {
+			if logger.V(logger.DebugLevel, logger.DefaultLogger) {
+				logger.Debugf("Discarding duplicate message %s on topic %s", id, e.Topic())
+			}
+			return nil
+		}
+
+		if err := h(e); err != nil {
+			return err
+		}
+
+		// the message was handled so the error is only logged
+		if err := d.opts.Store.Write(&store.Record{
+			Key:   key,
+			Value: []byte(id),
+		}, store.WriteTo(d.opts.Database, d.opts.Table), store.WriteTTL(d.opts.TTL)); err != nil {
+			logger.Errorf("Error writing id of message %s on topic %s: %v", id, e.Topic(), err)
+		}
+
+		return nil
+	}
+}
+
+// NewHandlerWrapper returns a handler wrapper which suppresses the handling
+// of messages with an id already handled. Messages are only marked as handled
+// once the handler succeeds so duplicates delivered while the message is
+// being handled are handled too.
+func NewHandlerWrapper(opts ...Option) broker.HandlerWrapper {
+	options := Options{
+		Store:  store.DefaultStore,
+		Prefix: DefaultPrefix,
+		TTL:    DefaultTTL,
+	}
+
+	for _, o := range opts {
+		o(&options)
+	}
+
+	if options.ID == nil {
+		Header(DefaultHeaders...)(&options)
+	}
+
+	d := &dedupe{opts: options}
+
+	return d.wrap
+}
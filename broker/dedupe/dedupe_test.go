@@ -0,0 +1,66 @@
+package dedupe
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/micro/go-micro/v2/broker"
+	"github.com/micro/go-micro/v2/broker/memory"
+	mstore "github.com/micro/go-micro/v2/store/memory"
+)
+
+func TestDedupe(t *testing.T) {
+	b := broker.WrapHandler(NewHandlerWrapper(Store(mstore.NewStore())))(memory.NewBroker())
+	if err := b.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	defer b.Disconnect()
+
+	fail := true
+	var handled []string
+	if _, err := b.Subscribe("test", func(e broker.Event) error {
+		if fail {
+			fail = false
+			return errors.New("failed")
+		}
+		handled = append(handled, string(e.Message().Body))
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	publish := func(id, body string) error {
+		return b.Publish("test", &broker.Message{
+			Header: map[string]string{"Micro-Id": id},
+			Body:   []byte(body),
+		})
+	}
+
+	// failed messages aren't marked as handled
+	if err := publish("1", "foo"); err == nil {
+		t.Fatal("Expected the handler error")
+	}
+
+	for _, id := range []string{"1", "1", "2", "1", "2"} {
+		if err := publish(id, id); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// messages without an id are always handled
+	for i := 0; i < 2; i++ {
+		if err := b.Publish("test", &broker.Message{Body: []byte("none")}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	expected := []string{"1", "2", "none", "none"}
+	if len(handled) != len(expected) {
+		t.Fatalf("Expected %v got %v", expected, handled)
+	}
+	for i, h := range handled {
+		if h != expected[i] {
+			t.Fatalf("Expected %v got %v", expected, handled)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package dedupe
+
+import (
+	"time"
+
+	"github.com/micro/go-micro/v2/broker"
+	"github.com/micro/go-micro/v2/store"
+)
+
+type Options struct {
+	// Store the handled message ids are written to
+	Store store.Store
+	// Database and Table of the message ids,
+	// the defaults of the store are used if blank
+	Database, Table string
+	// Prefix of the keys of the message ids
+	Prefix string
+	// TTL of the message ids. Duplicates delivered
+	// later than the TTL are handled again.
+	TTL time.Duration
+	// ID returns the id of a message. Messages
+	// without an id are always handled.
+	ID func(topic string, msg *broker.Message) string
+}
+
+type Option func(o *Options)
+
+// Store the handled message ids are written to
+func Store(s store.Store) Option {
+	return func(o *Options) {
+		o.Store = s
+	}
+}
+
+// Table of the message ids
+func Table(database, table string) Option {
+	return func(o *Options) {
+		o.Database = database
+		o.Table = table
+	}
+}
+
+// Prefix of the keys of the message ids. Subscribers sharing a store
+// which should each handle the messages need a different prefix.
+func Prefix(p string) Option {
+	return func(o *Options) {
+		o.Prefix = p
+	}
+}
+
+// TTL of the message ids
+func TTL(d time.Duration) Option {
+	return func(o *Options) {
+		o.TTL = d
+	}
+}
+
+// ID sets the func returning the id of a message
+func ID(fn func(topic string, msg *broker.Message) string) Option {
+	return func(o *Options) {
+		o.ID = fn
+	}
+}
+
+// Header uses the first of the headers set as the id of a message
+func Header(keys ...string) Option {
+	return func(o *Options) {
+		o.ID = func(topic string, msg *broker.Message) string {
+			for _, k := range keys {
+				if v := msg.Header[k]; len(v) > 0 {
+					return v
+				}
+			}
+			return ""
+		}
+	}
+}
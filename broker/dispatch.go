@@ -0,0 +1,152 @@
+package broker
+
+import (
+	"errors"
+	"hash/fnv"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrHandlerTimeout is returned when a handler runs longer than the timeout
+	// of the subscription. The message is handled as if the handler failed.
+	ErrHandlerTimeout = errors.New("handler timed out")
+	// ErrDispatcherStopped is returned when waiting for a message
+	// to be handled by a dispatcher which was stopped
+	ErrDispatcherStopped = errors.New("dispatcher stopped")
+)
+
+// Dispatcher runs the handling of the messages of a subscription on a pool of
+// workers. It's used by brokers to handle messages concurrently. Dispatching
+// blocks while every worker is busy and MaxInFlight messages are queued. The
+// messages with the same partition key of an ordered subscription are queued
+// for the same worker so they're handled one at a time in order.
+type Dispatcher struct {
+	ordered bool
+	// queue of any worker
+	queue chan func()
+	// queues of the workers by partition key
+	workers []chan func()
+
+	once sync.Once
+	exit chan bool
+}
+
+func (d *Dispatcher) run(own chan func()) {
+	for {
+		select {
+		case <-d.exit:
+			return
+		case fn := <-own:
+			fn()
+		case fn := <-d.queue:
+			fn()
+		}
+	}
+}
+
+// Dispatch the handling of a message with the partition key to a worker.
+// The message is handled before returning without concurrency.
+func (d *Dispatcher) Dispatch(key string, fn func()) {
+	if d.queue == nil {
+		fn()
+		return
+	}
+
+	q := d.queue
+	if d.ordered && len(key) > 0 {
+		h := fnv.New32a()
+		h.Write([]byte(key))
+		q = d.workers[h.Sum32()%uint32(len(d.workers))]
+	}
+
+	select {
+	case q <- fn:
+	case <-d.exit:
+	}
+}
+
+// Stop the workers. Queued messages aren't handled.
+func (d *Dispatcher) Stop() {
+	d.once.Do(func() {
+		close(d.exit)
+	})
+}
+
+// NewDispatcher returns a dispatcher with the concurrency of the subscription
+func NewDispatcher(opts SubscribeOptions) *Dispatcher {
+	d := &Dispatcher{
+		ordered: opts.Ordered,
+		exit:    make(chan bool),
+	}
+
+	if opts.Concurrency <= 1 {
+		return d
+	}
+
+	// messages are queued up to the max in flight
+	var size int
+	if opts.MaxInFlight > opts.Concurrency {
+		size = opts.MaxInFlight - opts.Concurrency
+	}
+
+	d.queue = make(chan func(), size)
+	d.workers = make([]chan func(), opts.Concurrency)
+
+	for i := range d.workers {
+		d.workers[i] = make(chan func())
+		go d.run(d.workers[i])
+	}
+
+	return d
+}
+
+// Wait dispatches the handling of a message and waits for it to be handled.
+// It's used by brokers which deliver the messages synchronously.
+func (d *Dispatcher) Wait(key string, fn func() error) error {
+	errCh := make(chan error, 1)
+	d.Dispatch(key, func() {
+		errCh <- fn()
+	})
+
+	select {
+	case err := <-errCh:
+		return err
+	case <-d.exit:
+	}
+
+	// the message may have been handled before stopping
+	select {
+	case err := <-errCh:
+		return err
+	default:
+		return ErrDispatcherStopped
+	}
+}
+
+// TimeoutHandler fails the handling of a message with ErrHandlerTimeout if the
+// handler runs longer than the timeout of the subscription. The handler can't
+// be interrupted so it keeps running in the background. Brokers wrap
+// subscription handlers with it.
+func TimeoutHandler(h Handler, opts SubscribeOptions) Handler {
+	if opts.Timeout <= 0 {
+		return h
+	}
+
+	return func(e Event) error {
+		errCh := make(chan error, 1)
+		go func() {
+			errCh <- safeHandle(h, e)
+		}()
+
+		t := time.NewTimer(opts.Timeout)
+		defer t.Stop()
+
+		select {
+		case err := <-errCh:
+			return err
+		case <-t.C:
+			return ErrHandlerTimeout
+		}
+	}
+}
@@ -0,0 +1,140 @@
+// Package encrypt encrypts the bodies of messages. Messages are encrypted on
+// publish and decrypted before they're handled with keys of the secrets
+// package e.g aesgcm. The id of the key is set in a header so keys can be
+// rotated while there are messages encrypted with the previous key in flight.
+package encrypt
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/micro/go-micro/v2/broker"
+	"github.com/micro/go-micro/v2/config/secrets"
+)
+
+var (
+	// HeaderKeyID is the id of the key the body of a message is encrypted with
+	HeaderKeyID = "Micro-Encryption-Key-Id"
+
+	// ErrNotEncrypted is returned when receiving a message which
+	// isn't encrypted while encryption is required
+	ErrNotEncrypted = errors.New("message not encrypted")
+)
+
+type encryptBroker struct {
+	broker.Broker
+	opts Options
+}
+
+// Encrypt the body of the message with the key
+func Encrypt(msg *broker.Message, id string, s secrets.Secrets) (*broker.Message, error) {
+	body, err := s.Encrypt(msg.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	m := &broker.Message{
+		Header: make(map[string]string, len(msg.Header)+1),
+		Body:   body,
+	}
+	for k, v := range msg.Header {
+		m.Header[k] = v
+	}
+	m.Header[HeaderKeyID] = id
+
+	return m, nil
+}
+
+// Decrypt the body of the message with the key of its id. The
+// header of the key id is removed from the decrypted message.
+func Decrypt(msg *broker.Message, keys map[string]secrets.Secrets) (*broker.Message, error) {
+	id := msg.Header[HeaderKeyID]
+	s, ok := keys[id]
+	if !ok {
+		return nil, fmt.Errorf("unknown encryption key %s", id)
+	}
+
+	body, err := s.Decrypt(msg.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	m := &broker.Message{
+		Header: make(map[string]string, len(msg.Header)),
+		Body:   body,
+	}
+	for k, v := range msg.Header {
+		if k != HeaderKeyID {
+			m.Header[k] = v
+		}
+	}
+
+	return m, nil
+}
+
+// event is a received event with the decrypted message
+type event struct {
+	broker.Event
+	msg *broker.Message
+}
+
+func (e *event) Message() *broker.Message {
+	return e.msg
+}
+
+func (b *encryptBroker) Publish(topic string, msg *broker.Message, opts ...broker.PublishOption) error {
+	s, ok := b.opts.Keys[b.opts.KeyID]
+	if !ok {
+		return fmt.Errorf("unknown encryption key %s", b.opts.KeyID)
+	}
+
+	msg, err := Encrypt(msg, b.opts.KeyID, s)
+	if err != nil {
+		return err
+	}
+
+	return b.Broker.Publish(topic, msg, opts...)
+}
+
+func (b *encryptBroker) Subscribe(topic string, h broker.Handler, opts ...broker.SubscribeOption) (broker.Subscriber, error) {
+	return b.Broker.Subscribe(topic, func(e broker.Event) error {
+		msg := e.Message()
+		if msg == nil {
+			return h(e)
+		}
+
+		if _, ok := msg.Header[HeaderKeyID]; !ok {
+			if b.opts.Required {
+				return ErrNotEncrypted
+			}
+			return h(e)
+		}
+
+		msg, err := Decrypt(msg, b.opts.Keys)
+		if err != nil {
+			return err
+		}
+
+		return h(&event{Event: e, msg: msg})
+	}, opts...)
+}
+
+// NewWrapper returns a broker wrapper encrypting the bodies of published
+// messages and decrypting the bodies of received messages. Encrypted bodies
+// don't compress so messages shouldn't be published with compression.
+func NewWrapper(opts ...Option) broker.Wrapper {
+	options := Options{
+		Keys: make(map[string]secrets.Secrets),
+	}
+
+	for _, o := range opts {
+		o(&options)
+	}
+
+	return func(b broker.Broker) broker.Broker {
+		return &encryptBroker{
+			Broker: b,
+			opts:   options,
+		}
+	}
+}
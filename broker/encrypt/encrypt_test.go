@@ -0,0 +1,87 @@
+package encrypt
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/micro/go-micro/v2/broker"
+	"github.com/micro/go-micro/v2/broker/memory"
+	"github.com/micro/go-micro/v2/config/secrets"
+	"github.com/micro/go-micro/v2/config/secrets/aesgcm"
+)
+
+func newKey(t *testing.T, key string) secrets.Secrets {
+	s := aesgcm.NewSecrets()
+	if err := s.Init(secrets.Key([]byte(key))); err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestEncrypt(t *testing.T) {
+	oldKey := newKey(t, "0123456789abcdef")
+	newKey := newKey(t, "fedcba9876543210")
+
+	m := memory.NewBroker()
+	if err := m.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	defer m.Disconnect()
+
+	var raw []*broker.Message
+	if _, err := m.Subscribe("test", func(e broker.Event) error {
+		raw = append(raw, e.Message())
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	sub := NewWrapper(Key("new", newKey), DecryptKey("old", oldKey), Required())(m)
+
+	var received []*broker.Message
+	if _, err := sub.Subscribe("test", func(e broker.Event) error {
+		received = append(received, e.Message())
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	// messages encrypted with both keys are decrypted
+	for _, pub := range []broker.Broker{
+		NewWrapper(Key("old", oldKey))(m),
+		NewWrapper(Key("new", newKey))(m),
+	} {
+		if err := pub.Publish("test", &broker.Message{
+			Header: map[string]string{"foo": "bar"},
+			Body:   []byte("hello"),
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if len(received) != 2 || len(raw) != 2 {
+		t.Fatalf("Expected 2 messages got %d", len(received))
+	}
+	for i, id := range []string{"old", "new"} {
+		if raw[i].Header[HeaderKeyID] != id || bytes.Contains(raw[i].Body, []byte("hello")) {
+			t.Fatalf("Expected a message encrypted with key %s got %v", id, raw[i])
+		}
+		msg := received[i]
+		if string(msg.Body) != "hello" || msg.Header["foo"] != "bar" {
+			t.Fatalf("Unexpected decrypted message %v", msg)
+		}
+		if _, ok := msg.Header[HeaderKeyID]; ok {
+			t.Fatal("Expected the key id header to be removed")
+		}
+	}
+
+	// messages which aren't encrypted are rejected
+	if err := m.Publish("test", &broker.Message{Body: []byte("hello")}); err != ErrNotEncrypted {
+		t.Fatalf("Expected %v got %v", ErrNotEncrypted, err)
+	}
+
+	// messages with unknown keys fail
+	if err := NewWrapper(Key("unknown", oldKey))(m).Publish("test", &broker.Message{Body: []byte("hello")}); err == nil {
+		t.Fatal("Expected an unknown key error")
+	}
+}
@@ -0,0 +1,39 @@
+package encrypt
+
+import (
+	"github.com/micro/go-micro/v2/config/secrets"
+)
+
+type Options struct {
+	// KeyID is the id of the key messages are encrypted with
+	KeyID string
+	// Keys are the keys by id messages are decrypted with
+	Keys map[string]secrets.Secrets
+	// Required rejects messages which aren't encrypted
+	Required bool
+}
+
+type Option func(o *Options)
+
+// Key encrypts messages with the key. Rotated keys are
+// added with DecryptKey to decrypt in-flight messages.
+func Key(id string, s secrets.Secrets) Option {
+	return func(o *Options) {
+		o.KeyID = id
+		o.Keys[id] = s
+	}
+}
+
+// DecryptKey adds a key only used to decrypt messages
+func DecryptKey(id string, s secrets.Secrets) Option {
+	return func(o *Options) {
+		o.Keys[id] = s
+	}
+}
+
+// Required rejects received messages which aren't encrypted
+func Required() Option {
+	return func(o *Options) {
+		o.Required = true
+	}
+}
@@ -0,0 +1,167 @@
+package googlepubsub
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+)
+
+// pubsubMessage is a message of the pub/sub v1 REST api. The
+// data is base64 encoded by encoding/json.
+type pubsubMessage struct {
+	Data        []byte            `json:"data,omitempty"`
+	Attributes  map[string]string `json:"attributes,omitempty"`
+	MessageID   string            `json:"messageId,omitempty"`
+	OrderingKey string            `json:"orderingKey,omitempty"`
+}
+
+type receivedMessage struct {
+	AckID   string        `json:"ackId"`
+	Message pubsubMessage `json:"message"`
+}
+
+type subscriptionConfig struct {
+	Topic                 string `json:"topic"`
+	AckDeadlineSeconds    int    `json:"ackDeadlineSeconds,omitempty"`
+	EnableMessageOrdering bool   `json:"enableMessageOrdering,omitempty"`
+}
+
+type apiError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+	Status  string `json:"status"`
+}
+
+func (e *apiError) Error() string {
+	return fmt.Sprintf("pubsub: %d %s: %s", e.Code, e.Status, e.Message)
+}
+
+// isCode returns true if err is an api error with the http status code
+func isCode(err error, code int) bool {
+	e, ok := err.(*apiError)
+	return ok && e.Code == code
+}
+
+// client makes requests to the pub/sub v1 REST api
+type client struct {
+	endpoint string
+	project  string
+	http     *http.Client
+}
+
+func (c *client) topic(name string) string {
+	return fmt.Sprintf("projects/%s/topics/%s", c.project, name)
+}
+
+func (c *client) subscription(name string) string {
+	return fmt.Sprintf("projects/%s/subscriptions/%s", c.project, name)
+}
+
+func (c *client) do(ctx context.Context, method, path string, in, out interface{}) error {
+	var body []byte
+	if in != nil {
+		b, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = b
+	}
+
+	req, err := http.NewRequest(method, c.endpoint+"/v1/"+path, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", "application/json")
+
+	rsp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer rsp.Body.Close()
+
+	b, err := ioutil.ReadAll(rsp.Body)
+	if err != nil {
+		return err
+	}
+
+	if rsp.StatusCode < 200 || rsp.StatusCode > 299 {
+		var e struct {
+			Error *apiError `json:"error"`
+		}
+		if err := json.Unmarshal(b, &e); err != nil || e.Error == nil {
+			return &apiError{Code: rsp.StatusCode, Status: rsp.Status, Message: string(b)}
+		}
+		e.Error.Code = rsp.StatusCode
+		return e.Error
+	}
+
+	if out == nil {
+		return nil
+	}
+
+	return json.Unmarshal(b, out)
+}
+
+// createTopic creates the topic if it doesn't exist
+func (c *client) createTopic(ctx context.Context, topic string) error {
+	err := c.do(ctx, http.MethodPut, c.topic(topic), struct{}{}, nil)
+	if isCode(err, http.StatusConflict) {
+		return nil
+	}
+	return err
+}
+
+// createSubscription creates the subscription if it doesn't exist
+func (c *client) createSubscription(ctx context.Context, name string, cfg subscriptionConfig) error {
+	err := c.do(ctx, http.MethodPut, c.subscription(name), cfg, nil)
+	if isCode(err, http.StatusConflict) {
+		return nil
+	}
+	return err
+}
+
+func (c *client) deleteSubscription(ctx context.Context, name string) error {
+	return c.do(ctx, http.MethodDelete, c.subscription(name), nil, nil)
+}
+
+func (c *client) publish(ctx context.Context, topic string, msg pubsubMessage) error {
+	in := map[string]interface{}{
+		"messages": []pubsubMessage{msg},
+	}
+	return c.do(ctx, http.MethodPost, c.topic(topic)+":publish", in, nil)
+}
+
+// pull blocks until messages are available or the request times out
+func (c *client) pull(ctx context.Context, name string, max int) ([]receivedMessage, error) {
+	in := map[string]interface{}{
+		"maxMessages": max,
+	}
+	var out struct {
+		ReceivedMessages []receivedMessage `json:"receivedMessages"`
+	}
+	if err := c.do(ctx, http.MethodPost, c.subscription(name)+":pull", in, &out); err != nil {
+		return nil, err
+	}
+	return out.ReceivedMessages, nil
+}
+
+func (c *client) acknowledge(ctx context.Context, name string, ackIDs ...string) error {
+	in := map[string]interface{}{
+		"ackIds": ackIDs,
+	}
+	return c.do(ctx, http.MethodPost, c.subscription(name)+":acknowledge", in, nil)
+}
+
+// modifyAckDeadline sets the deadline of the messages. A deadline
+// of 0 makes them available for redelivery immediately.
+func (c *client) modifyAckDeadline(ctx context.Context, name string, seconds int, ackIDs ...string) error {
+	in := map[string]interface{}{
+		"ackIds":             ackIDs,
+		"ackDeadlineSeconds": seconds,
+	}
+	return c.do(ctx, http.MethodPost, c.subscription(name)+":modifyAckDeadline", in, nil)
+}
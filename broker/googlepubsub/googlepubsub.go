@@ -0,0 +1,443 @@
+// Package googlepubsub provides a google cloud pub/sub broker
+package googlepubsub
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/micro/go-micro/v2/broker"
+	"github.com/micro/go-micro/v2/logger"
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/google"
+)
+
+var (
+	errNotConnected = errors.New("not connected")
+
+	// DefaultEndpoint of the pub/sub api
+	DefaultEndpoint = "https://pubsub.googleapis.com"
+
+	// DefaultMaxMessages is the number of messages pulled at once
+	DefaultMaxMessages = 10
+
+	// scope required to publish and subscribe
+	scope = "https://www.googleapis.com/auth/pubsub"
+
+	// requestTimeout of requests other than pulls
+	requestTimeout = 30 * time.Second
+)
+
+type pubsubBroker struct {
+	sync.RWMutex
+	opts   broker.Options
+	client *client
+}
+
+type subscriber struct {
+	c            *client
+	topic        string
+	subscription string
+	opts         broker.SubscribeOptions
+	handler      broker.Handler
+	errHandler   broker.Handler
+	dispatcher   *broker.Dispatcher
+	maxMessages  int
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	done   chan bool
+}
+
+type publication struct {
+	s     *subscriber
+	ackID string
+	m     *broker.Message
+	err   error
+}
+
+func (p *publication) Ack() error {
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	defer cancel()
+	return p.s.c.acknowledge(ctx, p.s.subscription, p.ackID)
+}
+
+// Nack makes the message available for redelivery immediately
+func (p *publication) Nack() error {
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	defer cancel()
+	return p.s.c.modifyAckDeadline(ctx, p.s.subscription, 0, p.ackID)
+}
+
+// Requeue makes the message available for redelivery immediately
+func (p *publication) Requeue() error {
+	return p.Nack()
+}
+
+func (p *publication) Error() error {
+	return p.err
+}
+
+func (p *publication) Topic() string {
+	return p.s.topic
+}
+
+func (p *publication) Message() *broker.Message {
+	return p.m
+}
+
+func (s *subscriber) Options() broker.SubscribeOptions {
+	return s.opts
+}
+
+func (s *subscriber) Topic() string {
+	return s.topic
+}
+
+func (s *subscriber) Unsubscribe() error {
+	s.cancel()
+	<-s.done
+	s.dispatcher.Stop()
+
+	// subscriptions without a queue are only used by the subscriber
+	if len(s.opts.Queue) > 0 {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	defer cancel()
+	return s.c.deleteSubscription(ctx, s.subscription)
+}
+
+func (s *subscriber) run() {
+	defer close(s.done)
+
+	for {
+		msgs, err := s.c.pull(s.ctx, s.subscription, s.maxMessages)
+		if s.ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			if logger.V(logger.ErrorLevel, logger.DefaultLogger) {
+				logger.Errorf("[googlepubsub] failed to pull from %s: %v", s.subscription, err)
+			}
+			select {
+			case <-s.ctx.Done():
+				return
+			case <-time.After(time.Second):
+			}
+			continue
+		}
+
+		for _, m := range msgs {
+			s.handle(m)
+		}
+	}
+}
+
+func (s *subscriber) handle(m receivedMessage) {
+	header := m.Message.Attributes
+	if header == nil {
+		header = make(map[string]string)
+	}
+
+	p := &publication{
+		s:     s,
+		ackID: m.AckID,
+		m: &broker.Message{
+			Header: header,
+			Body:   m.Message.Data,
+		},
+	}
+
+	s.dispatcher.Dispatch(header[broker.HeaderPartitionKey], func() {
+		p.err = s.handler(p)
+		if p.err == nil && s.opts.AutoAck {
+			if err := p.Ack(); err != nil && logger.V(logger.ErrorLevel, logger.DefaultLogger) {
+				logger.Errorf("[googlepubsub] failed to ack message %s: %v", m.Message.MessageID, err)
+			}
+		} else if p.err != nil {
+			if logger.V(logger.ErrorLevel, logger.DefaultLogger) {
+				logger.Errorf("[googlepubsub] subscriber error: %v", p.err)
+			}
+			if s.errHandler != nil {
+				s.errHandler(p)
+			}
+			if s.opts.AutoAck {
+				p.Nack()
+			}
+		}
+	})
+}
+
+func (b *pubsubBroker) Address() string {
+	for _, a := range b.opts.Addrs {
+		if len(a) > 0 {
+			return a
+		}
+	}
+	return DefaultEndpoint
+}
+
+func (b *pubsubBroker) Connect() error {
+	b.Lock()
+	defer b.Unlock()
+
+	if b.client != nil {
+		return nil
+	}
+
+	c, err := b.newClient(context.Background())
+	if err != nil {
+		return err
+	}
+	b.client = c
+
+	return nil
+}
+
+func (b *pubsubBroker) Disconnect() error {
+	b.Lock()
+	b.client = nil
+	b.Unlock()
+	return nil
+}
+
+func (b *pubsubBroker) Init(opts ...broker.Option) error {
+	b.Lock()
+	defer b.Unlock()
+
+	for _, o := range opts {
+		o(&b.opts)
+	}
+
+	return nil
+}
+
+func (b *pubsubBroker) Options() broker.Options {
+	return b.opts
+}
+
+func (b *pubsubBroker) Publish(topic string, msg *broker.Message, opts ...broker.PublishOption) error {
+	// messages delivered later are published by the scheduler
+	if broker.Deferred(b, topic, msg, opts...) {
+		return nil
+	}
+
+	msg, err := broker.EncodeMessage(msg, opts...)
+	if err != nil {
+		return err
+	}
+
+	options := broker.PublishOptions{
+		Context: context.Background(),
+	}
+	for _, o := range opts {
+		o(&options)
+	}
+
+	b.RLock()
+	c := b.client
+	b.RUnlock()
+
+	if c == nil {
+		return errNotConnected
+	}
+
+	m := pubsubMessage{
+		Data:       msg.Body,
+		Attributes: msg.Header,
+	}
+	if k, ok := options.Context.Value(orderingKeyKey{}).(string); ok {
+		m.OrderingKey = k
+	} else if len(options.Key) > 0 {
+		m.OrderingKey = options.Key
+	}
+
+	ctx, cancel := context.WithTimeout(options.Context, requestTimeout)
+	defer cancel()
+
+	err = c.publish(ctx, topic, m)
+	if isCode(err, http.StatusNotFound) && b.autoCreate() {
+		if err := c.createTopic(ctx, topic); err != nil {
+			return err
+		}
+		err = c.publish(ctx, topic, m)
+	}
+
+	return err
+}
+
+func (b *pubsubBroker) Subscribe(topic string, handler broker.Handler, opts ...broker.SubscribeOption) (broker.Subscriber, error) {
+	// subscriptions are bound to a single topic
+	if broker.IsPattern(topic) {
+		return nil, broker.ErrPatternNotSupported
+	}
+
+	opt := broker.SubscribeOptions{
+		AutoAck: true,
+		Context: context.Background(),
+	}
+	for _, o := range opts {
+		o(&opt)
+	}
+
+	// handlers running longer than the timeout fail
+	handler = broker.TimeoutHandler(handler, opt)
+	// messages the handler keeps failing on are dead lettered
+	handler = broker.DeadLetterHandler(b, broker.DecompressHandler(handler), opt)
+	// messages with the same partition key are handled in order
+	handler = broker.OrderedHandler(handler, opt)
+
+	b.RLock()
+	c := b.client
+	b.RUnlock()
+
+	if c == nil {
+		return nil, errNotConnected
+	}
+
+	sub := newSubscriber(c, topic, handler, opt)
+	sub.errHandler = b.opts.ErrorHandler
+
+	if b.autoCreate() {
+		ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+		defer cancel()
+
+		if err := c.createTopic(ctx, topic); err != nil {
+			sub.dispatcher.Stop()
+			return nil, err
+		}
+		if err := c.createSubscription(ctx, sub.subscription, subscriptionConfigFor(c, topic, opt)); err != nil {
+			sub.dispatcher.Stop()
+			return nil, err
+		}
+	}
+
+	go sub.run()
+
+	return sub, nil
+}
+
+func (b *pubsubBroker) String() string {
+	return "googlepubsub"
+}
+
+func (b *pubsubBroker) autoCreate() bool {
+	disabled, _ := b.opts.Context.Value(disableAutoCreateKey{}).(bool)
+	return !disabled
+}
+
+// newClient returns a client authenticated with the token source or the
+// default credentials. Requests to the emulator aren't authenticated.
+func (b *pubsubBroker) newClient(ctx context.Context) (*client, error) {
+	c := &client{
+		endpoint: strings.TrimSuffix(b.Address(), "/"),
+	}
+
+	if id, ok := b.opts.Context.Value(projectIDKey{}).(string); ok {
+		c.project = id
+	}
+
+	if host := os.Getenv("PUBSUB_EMULATOR_HOST"); len(host) > 0 {
+		c.endpoint = "http://" + host
+		c.http = http.DefaultClient
+	} else if ts, ok := b.opts.Context.Value(tokenSourceKey{}).(oauth2.TokenSource); ok {
+		c.http = oauth2.NewClient(ctx, ts)
+	} else {
+		creds, err := google.FindDefaultCredentials(ctx, scope)
+		if err != nil {
+			return nil, err
+		}
+		if len(c.project) == 0 {
+			c.project = creds.ProjectID
+		}
+		c.http = oauth2.NewClient(ctx, creds.TokenSource)
+	}
+
+	if len(c.project) == 0 {
+		c.project = os.Getenv("GOOGLE_CLOUD_PROJECT")
+	}
+	if len(c.project) == 0 {
+		return nil, errors.New("project id not set")
+	}
+
+	return c, nil
+}
+
+// subscriptionConfigFor returns the config used to create the subscription
+func subscriptionConfigFor(c *client, topic string, opt broker.SubscribeOptions) subscriptionConfig {
+	cfg := subscriptionConfig{
+		Topic: c.topic(topic),
+	}
+
+	if opt.Context == nil {
+		return cfg
+	}
+
+	if d, ok := opt.Context.Value(ackDeadlineKey{}).(time.Duration); ok {
+		cfg.AckDeadlineSeconds = int(d.Seconds())
+	}
+	if ordering, ok := opt.Context.Value(messageOrderingKey{}).(bool); ok {
+		cfg.EnableMessageOrdering = ordering
+	} else if opt.Ordered {
+		cfg.EnableMessageOrdering = true
+	}
+
+	return cfg
+}
+
+func newSubscriber(c *client, topic string, handler broker.Handler, opt broker.SubscribeOptions) *subscriber {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	sub := &subscriber{
+		c:            c,
+		topic:        topic,
+		subscription: opt.Queue,
+		opts:         opt,
+		handler:      handler,
+		dispatcher:   broker.NewDispatcher(opt),
+		maxMessages:  DefaultMaxMessages,
+		ctx:          ctx,
+		cancel:       cancel,
+		done:         make(chan bool),
+	}
+
+	// every subscriber without a queue gets all messages
+	if len(sub.subscription) == 0 {
+		sub.subscription = topic + "-" + uuid.New().String()
+	}
+
+	// messages are pulled up to the max in flight
+	if opt.MaxInFlight > 0 {
+		sub.maxMessages = opt.MaxInFlight
+	}
+
+	if opt.Context != nil {
+		if n, ok := opt.Context.Value(maxMessagesKey{}).(int); ok && n > 0 {
+			sub.maxMessages = n
+		}
+	}
+
+	return sub
+}
+
+// NewBroker returns a google cloud pub/sub broker
+func NewBroker(opts ...broker.Option) broker.Broker {
+	options := broker.Options{
+		Context: context.Background(),
+	}
+
+	for _, o := range opts {
+		o(&options)
+	}
+
+	return &pubsubBroker{
+		opts: options,
+	}
+}
@@ -0,0 +1,138 @@
+package googlepubsub
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/micro/go-micro/v2/broker"
+)
+
+// emulator is a minimal pub/sub api with a single message queue per subscription
+type emulator struct {
+	sync.Mutex
+	topics map[string]bool
+	subs   map[string]string
+	queued map[string][]pubsubMessage
+	acked  []string
+}
+
+func (e *emulator) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	e.Lock()
+	defer e.Unlock()
+
+	path := strings.TrimPrefix(r.URL.Path, "/v1/projects/test/")
+	parts := strings.SplitN(path, ":", 2)
+
+	switch {
+	case r.Method == http.MethodPut && strings.HasPrefix(path, "topics/"):
+		e.topics[path] = true
+	case r.Method == http.MethodPut && strings.HasPrefix(path, "subscriptions/"):
+		var cfg subscriptionConfig
+		json.NewDecoder(r.Body).Decode(&cfg)
+		e.subs[path] = strings.TrimPrefix(cfg.Topic, "projects/test/")
+	case strings.HasSuffix(path, ":publish"):
+		if !e.topics[parts[0]] {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"error":{"code":404,"status":"NOT_FOUND"}}`))
+			return
+		}
+		var in struct {
+			Messages []pubsubMessage `json:"messages"`
+		}
+		json.NewDecoder(r.Body).Decode(&in)
+		for sub, topic := range e.subs {
+			if topic == parts[0] {
+				e.queued[sub] = append(e.queued[sub], in.Messages...)
+			}
+		}
+	case strings.HasSuffix(path, ":pull"):
+		var rsp struct {
+			ReceivedMessages []receivedMessage `json:"receivedMessages"`
+		}
+		for _, m := range e.queued[parts[0]] {
+			rsp.ReceivedMessages = append(rsp.ReceivedMessages, receivedMessage{AckID: "ack-" + m.OrderingKey, Message: m})
+		}
+		delete(e.queued, parts[0])
+		json.NewEncoder(w).Encode(rsp)
+		return
+	case strings.HasSuffix(path, ":acknowledge"):
+		var in struct {
+			AckIDs []string `json:"ackIds"`
+		}
+		json.NewDecoder(r.Body).Decode(&in)
+		e.acked = append(e.acked, in.AckIDs...)
+	case r.Method == http.MethodDelete:
+		delete(e.subs, path)
+	}
+
+	w.Write([]byte(`{}`))
+}
+
+func TestBroker(t *testing.T) {
+	e := &emulator{
+		topics: make(map[string]bool),
+		subs:   make(map[string]string),
+		queued: make(map[string][]pubsubMessage),
+	}
+	srv := httptest.NewServer(e)
+	defer srv.Close()
+
+	os.Setenv("PUBSUB_EMULATOR_HOST", strings.TrimPrefix(srv.URL, "http://"))
+	defer os.Unsetenv("PUBSUB_EMULATOR_HOST")
+
+	b := NewBroker(ProjectID("test"))
+	if err := b.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	defer b.Disconnect()
+
+	// the topic is created on publish
+	if err := b.Publish("events", &broker.Message{Body: []byte("first")}); err != nil {
+		t.Fatal(err)
+	}
+
+	msgs := make(chan *broker.Message, 1)
+	sub, err := b.Subscribe("events", func(p broker.Event) error {
+		msgs <- p.Message()
+		return nil
+	}, broker.Queue("workers"), AckDeadline(time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := b.Publish("events", &broker.Message{
+		Header: map[string]string{"Foo": "Bar"},
+		Body:   []byte("hello"),
+	}, OrderingKey("key")); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case m := <-msgs:
+		if string(m.Body) != "hello" || m.Header["Foo"] != "Bar" {
+			t.Fatalf("Unexpected message %+v", m)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for message")
+	}
+
+	if err := sub.Unsubscribe(); err != nil {
+		t.Fatal(err)
+	}
+
+	e.Lock()
+	defer e.Unlock()
+	if len(e.acked) != 1 || e.acked[0] != "ack-key" {
+		t.Fatalf("Expected the message to be acked got %v", e.acked)
+	}
+	// queue subscriptions are shared so aren't deleted
+	if _, ok := e.subs["subscriptions/workers"]; !ok {
+		t.Fatal("Expected the subscription to exist")
+	}
+}
@@ -0,0 +1,91 @@
+package googlepubsub
+
+import (
+	"context"
+	"time"
+
+	"github.com/micro/go-micro/v2/broker"
+	"golang.org/x/oauth2"
+)
+
+type projectIDKey struct{}
+
+type tokenSourceKey struct{}
+
+type disableAutoCreateKey struct{}
+
+type orderingKeyKey struct{}
+
+type ackDeadlineKey struct{}
+
+type maxMessagesKey struct{}
+
+type messageOrderingKey struct{}
+
+// ProjectID sets the project of the topics and subscriptions. Defaults to
+// the project of the default credentials or $GOOGLE_CLOUD_PROJECT.
+func ProjectID(id string) broker.Option {
+	return setBrokerOption(projectIDKey{}, id)
+}
+
+// TokenSource sets the source of the tokens used to authenticate requests.
+// Defaults to the application default credentials.
+func TokenSource(ts oauth2.TokenSource) broker.Option {
+	return setBrokerOption(tokenSourceKey{}, ts)
+}
+
+// DisableAutoCreate stops topics and subscriptions being created when
+// they don't exist
+func DisableAutoCreate() broker.Option {
+	return setBrokerOption(disableAutoCreateKey{}, true)
+}
+
+// OrderingKey sets the ordering key of the message. Messages with the same
+// key are delivered in order to subscriptions with message ordering enabled.
+func OrderingKey(k string) broker.PublishOption {
+	return setPublishOption(orderingKeyKey{}, k)
+}
+
+// AckDeadline sets how long pub/sub waits for an ack before redelivering
+// a message. It's only applied when the subscription is created.
+func AckDeadline(d time.Duration) broker.SubscribeOption {
+	return setSubscribeOption(ackDeadlineKey{}, d)
+}
+
+// MaxMessages sets the maximum number of messages pulled at once
+func MaxMessages(n int) broker.SubscribeOption {
+	return setSubscribeOption(maxMessagesKey{}, n)
+}
+
+// MessageOrdering enables the delivery of messages with the same ordering
+// key in order. It's only applied when the subscription is created.
+func MessageOrdering() broker.SubscribeOption {
+	return setSubscribeOption(messageOrderingKey{}, true)
+}
+
+func setBrokerOption(k, v interface{}) broker.Option {
+	return func(o *broker.Options) {
+		if o.Context == nil {
+			o.Context = context.Background()
+		}
+		o.Context = context.WithValue(o.Context, k, v)
+	}
+}
+
+func setSubscribeOption(k, v interface{}) broker.SubscribeOption {
+	return func(o *broker.SubscribeOptions) {
+		if o.Context == nil {
+			o.Context = context.Background()
+		}
+		o.Context = context.WithValue(o.Context, k, v)
+	}
+}
+
+func setPublishOption(k, v interface{}) broker.PublishOption {
+	return func(o *broker.PublishOptions) {
+		if o.Context == nil {
+			o.Context = context.Background()
+		}
+		o.Context = context.WithValue(o.Context, k, v)
+	}
+}
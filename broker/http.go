@@ -56,6 +56,8 @@ type httpSubscriber struct {
 	fn    Handler
 	svc   *registry.Service
 	hb    *httpBroker
+	// bounds the concurrency of the requests
+	dispatcher *Dispatcher
 }
 
 type httpEvent struct {
@@ -157,6 +159,15 @@ func (h *httpEvent) Ack() error {
 	return nil
 }
 
+func (h *httpEvent) Nack() error {
+	// there is no redelivery support
+	return nil
+}
+
+func (h *httpEvent) Requeue() error {
+	return nil
+}
+
 func (h *httpEvent) Error() error {
 	return h.err
 }
@@ -178,6 +189,7 @@ func (h *httpSubscriber) Topic() string {
 }
 
 func (h *httpSubscriber) Unsubscribe() error {
+	h.dispatcher.Stop()
 	return h.hb.unsubscribe(h)
 }
 
@@ -330,20 +342,27 @@ func (h *httpBroker) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 	id := req.Form.Get("id")
 
 	//nolint:prealloc
-	var subs []Handler
+	var subs []*httpSubscriber
 
 	h.RLock()
-	for _, subscriber := range h.subscribers[topic] {
-		if id != subscriber.id {
+	for t, subscribers := range h.subscribers {
+		if !MatchTopic(t, topic) {
 			continue
 		}
-		subs = append(subs, subscriber.fn)
+		for _, subscriber := range subscribers {
+			if id != subscriber.id {
+				continue
+			}
+			subs = append(subs, subscriber)
+		}
 	}
 	h.RUnlock()
 
 	// execute the handler
-	for _, fn := range subs {
-		p.err = fn(p)
+	for _, sub := range subs {
+		p.err = sub.dispatcher.Wait(m.Header[HeaderPartitionKey], func() error {
+			return sub.fn(p)
+		})
 	}
 }
 
@@ -510,6 +529,16 @@ func (h *httpBroker) Options() Options {
 }
 
 func (h *httpBroker) Publish(topic string, msg *Message, opts ...PublishOption) error {
+	// messages delivered later are published by the scheduler
+	if Deferred(h, topic, msg, opts...) {
+		return nil
+	}
+
+	msg, err := EncodeMessage(msg, opts...)
+	if err != nil {
+		return err
+	}
+
 	// create the message first
 	m := &Message{
 		Header: make(map[string]string),
@@ -573,8 +602,8 @@ func (h *httpBroker) Publish(topic string, msg *Message, opts ...PublishOption)
 					continue
 				}
 
-				// look for nodes for the topic
-				if node.Metadata["topic"] != topic {
+				// look for nodes for the topic or a pattern matching it
+				if !MatchTopic(node.Metadata["topic"], topic) {
 					continue
 				}
 
@@ -642,6 +671,13 @@ func (h *httpBroker) Subscribe(topic string, handler Handler, opts ...SubscribeO
 	var host, port string
 	options := NewSubscribeOptions(opts...)
 
+	// handlers running longer than the timeout fail
+	handler = TimeoutHandler(handler, options)
+	// messages the handler keeps failing on are dead lettered
+	handler = DeadLetterHandler(h, DecompressHandler(handler), options)
+	// messages with the same partition key are handled in order
+	handler = OrderedHandler(handler, options)
+
 	// parse address for host, port
 	host, port, err = net.SplitHostPort(h.Address())
 	if err != nil {
@@ -690,6 +726,8 @@ func (h *httpBroker) Subscribe(topic string, handler Handler, opts ...SubscribeO
 		topic: topic,
 		fn:    handler,
 		svc:   service,
+
+		dispatcher: NewDispatcher(options),
 	}
 
 	// subscribe now
@@ -0,0 +1,172 @@
+package jetstream
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// JetStream is managed through a JSON request/reply api on the $JS.API subjects
+
+const (
+	ackAck = "+ACK"
+	ackNak = "-NAK"
+
+	// the deliver policies of a consumer
+	deliverAll      = "all"
+	deliverNew      = "new"
+	deliverLast     = "last"
+	deliverSequence = "by_start_sequence"
+	deliverTime     = "by_start_time"
+)
+
+// StreamConfig is the configuration of a stream. Zero values
+// are replaced with the defaults of the server.
+type StreamConfig struct {
+	// Name of the stream
+	Name string `json:"name"`
+	// Subjects stored in the stream
+	Subjects []string `json:"subjects,omitempty"`
+	// Retention is the retention policy: limits, interest or workqueue
+	Retention string `json:"retention,omitempty"`
+	// MaxMsgs is the maximum number of messages stored
+	MaxMsgs int64 `json:"max_msgs,omitempty"`
+	// MaxBytes is the maximum size of the stream
+	MaxBytes int64 `json:"max_bytes,omitempty"`
+	// MaxAge is the maximum age of a stored message
+	MaxAge time.Duration `json:"max_age,omitempty"`
+	// Storage is the storage type: file or memory
+	Storage string `json:"storage,omitempty"`
+	// Replicas is the number of replicas in a cluster
+	Replicas int `json:"num_replicas,omitempty"`
+}
+
+type consumerConfig struct {
+	Durable        string        `json:"durable_name,omitempty"`
+	DeliverSubject string        `json:"deliver_subject"`
+	DeliverGroup   string        `json:"deliver_group,omitempty"`
+	DeliverPolicy  string        `json:"deliver_policy"`
+	OptStartSeq    uint64        `json:"opt_start_seq,omitempty"`
+	OptStartTime   *time.Time    `json:"opt_start_time,omitempty"`
+	AckPolicy      string        `json:"ack_policy"`
+	AckWait        time.Duration `json:"ack_wait,omitempty"`
+	MaxDeliver     int           `json:"max_deliver,omitempty"`
+	MaxAckPending  int           `json:"max_ack_pending,omitempty"`
+	FilterSubject  string        `json:"filter_subject,omitempty"`
+	ReplayPolicy   string        `json:"replay_policy"`
+}
+
+type createConsumerRequest struct {
+	Stream string          `json:"stream_name"`
+	Config *consumerConfig `json:"config"`
+}
+
+type streamNamesRequest struct {
+	Subject string `json:"subject"`
+}
+
+type apiError struct {
+	Code        int    `json:"code"`
+	Description string `json:"description"`
+}
+
+func (e *apiError) Error() string {
+	return fmt.Sprintf("jetstream: %s (%d)", e.Description, e.Code)
+}
+
+type apiResponse struct {
+	Error *apiError `json:"error,omitempty"`
+}
+
+type pubAck struct {
+	apiResponse
+	Stream   string `json:"stream"`
+	Sequence uint64 `json:"seq"`
+}
+
+type streamNamesResponse struct {
+	apiResponse
+	Streams []string `json:"streams"`
+}
+
+// parsePubAck returns the error of a publish ack
+func parsePubAck(data []byte) error {
+	var ack pubAck
+	if err := json.Unmarshal(data, &ack); err != nil {
+		return err
+	}
+	if ack.Error != nil {
+		return ack.Error
+	}
+	return nil
+}
+
+// request makes an api request and decodes the response into rsp
+func (j *jsBroker) request(subject string, req, rsp interface{}) error {
+	var b []byte
+	if req != nil {
+		var err error
+		if b, err = json.Marshal(req); err != nil {
+			return err
+		}
+	}
+
+	j.RLock()
+	conn := j.conn
+	j.RUnlock()
+
+	if conn == nil {
+		return errNotConnected
+	}
+
+	msg, err := conn.Request(j.apiPrefix+"."+subject, b, j.timeout)
+	if err != nil {
+		return err
+	}
+
+	var ar apiResponse
+	if err := json.Unmarshal(msg.Data, &ar); err != nil {
+		return err
+	}
+	if ar.Error != nil {
+		return ar.Error
+	}
+	if rsp == nil {
+		return nil
+	}
+	return json.Unmarshal(msg.Data, rsp)
+}
+
+// ensureStream creates the stream if it doesn't exist
+func (j *jsBroker) ensureStream(cfg StreamConfig) error {
+	err := j.request("STREAM.INFO."+cfg.Name, nil, nil)
+	if err == nil {
+		return nil
+	}
+	if aerr, ok := err.(*apiError); !ok || aerr.Code != 404 {
+		return err
+	}
+	return j.request("STREAM.CREATE."+cfg.Name, cfg, nil)
+}
+
+// lookupStream returns the name of the stream storing the subject
+func (j *jsBroker) lookupStream(subject string) (string, error) {
+	var rsp streamNamesResponse
+	if err := j.request("STREAM.NAMES", &streamNamesRequest{Subject: subject}, &rsp); err != nil {
+		return "", err
+	}
+	if len(rsp.Streams) == 0 {
+		return "", fmt.Errorf("jetstream: no stream for subject %s", subject)
+	}
+	return rsp.Streams[0], nil
+}
+
+// createConsumer creates a consumer of the stream. Durable
+// consumers which already exist are bound to.
+func (j *jsBroker) createConsumer(stream string, cfg *consumerConfig) error {
+	subject := "CONSUMER.CREATE." + stream
+	if len(cfg.Durable) > 0 {
+		subject = "CONSUMER.DURABLE.CREATE." + stream + "." + cfg.Durable
+	}
+	return j.request(subject, &createConsumerRequest{Stream: stream, Config: cfg}, nil)
+}
@@ -0,0 +1,433 @@
+// Package jetstream provides a NATS JetStream broker with durable consumers
+package jetstream
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/micro/go-micro/v2/broker"
+	"github.com/micro/go-micro/v2/codec/json"
+	"github.com/micro/go-micro/v2/logger"
+	nats "github.com/nats-io/nats.go"
+)
+
+var (
+	errNotConnected = errors.New("not connected")
+)
+
+type jsBroker struct {
+	sync.Once
+	sync.RWMutex
+
+	connected bool
+
+	addrs []string
+	conn  *nats.Conn
+	opts  broker.Options
+	nopts nats.Options
+
+	apiPrefix string
+	timeout   time.Duration
+}
+
+type subscriber struct {
+	t    string
+	s    *nats.Subscription
+	d    *broker.Dispatcher
+	opts broker.SubscribeOptions
+}
+
+type publication struct {
+	t   string
+	err error
+	m   *broker.Message
+	msg *nats.Msg
+}
+
+func (p *publication) Topic() string {
+	return p.t
+}
+
+func (p *publication) Message() *broker.Message {
+	return p.m
+}
+
+func (p *publication) Ack() error {
+	return p.msg.Respond([]byte(ackAck))
+}
+
+// Nack asks the server to redeliver the message
+func (p *publication) Nack() error {
+	return p.msg.Respond([]byte(ackNak))
+}
+
+// Requeue asks the server to redeliver the message
+func (p *publication) Requeue() error {
+	return p.msg.Respond([]byte(ackNak))
+}
+
+func (p *publication) Error() error {
+	return p.err
+}
+
+func (s *subscriber) Options() broker.SubscribeOptions {
+	return s.opts
+}
+
+func (s *subscriber) Topic() string {
+	return s.t
+}
+
+func (s *subscriber) Unsubscribe() error {
+	// durable consumers are kept by the server so
+	// they can be resumed from where they left off
+	s.d.Stop()
+	return s.s.Unsubscribe()
+}
+
+func (j *jsBroker) Address() string {
+	if j.conn != nil && j.conn.IsConnected() {
+		return j.conn.ConnectedUrl()
+	}
+
+	if len(j.addrs) > 0 {
+		return j.addrs[0]
+	}
+
+	return ""
+}
+
+func (j *jsBroker) setAddrs(addrs []string) []string {
+	//nolint:prealloc
+	var cAddrs []string
+	for _, addr := range addrs {
+		if len(addr) == 0 {
+			continue
+		}
+		if !strings.HasPrefix(addr, "nats://") {
+			addr = "nats://" + addr
+		}
+		cAddrs = append(cAddrs, addr)
+	}
+	if len(cAddrs) == 0 {
+		cAddrs = []string{nats.DefaultURL}
+	}
+	return cAddrs
+}
+
+func (j *jsBroker) Connect() error {
+	j.Lock()
+	defer j.Unlock()
+
+	if j.connected {
+		return nil
+	}
+
+	opts := j.nopts
+	opts.Servers = j.addrs
+	opts.Secure = j.opts.Secure
+	opts.TLSConfig = j.opts.TLSConfig
+
+	// secure might not be set
+	if j.opts.TLSConfig != nil {
+		opts.Secure = true
+	}
+
+	c, err := opts.Connect()
+	if err != nil {
+		return err
+	}
+	j.conn = c
+	j.connected = true
+	return nil
+}
+
+func (j *jsBroker) Disconnect() error {
+	j.Lock()
+	defer j.Unlock()
+
+	if j.conn != nil {
+		j.conn.Close()
+	}
+	j.connected = false
+
+	return nil
+}
+
+func (j *jsBroker) Init(opts ...broker.Option) error {
+	j.setOption(opts...)
+	return nil
+}
+
+func (j *jsBroker) Options() broker.Options {
+	return j.opts
+}
+
+// Publish a message to a stream and wait for the server to ack it
+func (j *jsBroker) Publish(topic string, msg *broker.Message, opts ...broker.PublishOption) error {
+	// messages delivered later are published by the scheduler
+	if broker.Deferred(j, topic, msg, opts...) {
+		return nil
+	}
+
+	msg, err := broker.EncodeMessage(msg, opts...)
+	if err != nil {
+		return err
+	}
+
+	j.RLock()
+	conn := j.conn
+	j.RUnlock()
+
+	if conn == nil {
+		return errNotConnected
+	}
+
+	b, err := j.opts.Codec.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	rsp, err := conn.Request(topic, b, j.timeout)
+	if err != nil {
+		return err
+	}
+
+	return parsePubAck(rsp.Data)
+}
+
+func (j *jsBroker) Subscribe(topic string, handler broker.Handler, opts ...broker.SubscribeOption) (broker.Subscriber, error) {
+	j.RLock()
+	conn := j.conn
+	j.RUnlock()
+
+	if conn == nil {
+		return nil, errNotConnected
+	}
+
+	opt := broker.SubscribeOptions{
+		AutoAck: true,
+		Context: context.Background(),
+	}
+
+	for _, o := range opts {
+		o(&opt)
+	}
+
+	// handlers running longer than the timeout fail
+	handler = broker.TimeoutHandler(handler, opt)
+	// messages the handler keeps failing on are dead lettered
+	handler = broker.DeadLetterHandler(j, broker.DecompressHandler(handler), opt)
+	// messages with the same partition key are handled in order
+	handler = broker.OrderedHandler(handler, opt)
+
+	// find or create the stream
+	var stream string
+	if cfg, ok := opt.Context.Value(streamKey{}).(StreamConfig); ok {
+		if err := j.ensureStream(cfg); err != nil {
+			return nil, err
+		}
+		stream = cfg.Name
+	} else {
+		var err error
+		if stream, err = j.lookupStream(subject(topic)); err != nil {
+			return nil, err
+		}
+	}
+
+	cfg := newConsumerConfig(subject(topic), opt)
+	if len(cfg.Durable) > 0 {
+		// durable consumers need a stable deliver subject to be resumed
+		cfg.DeliverSubject = "_JS_DELIVER." + stream + "." + cfg.Durable
+	} else {
+		cfg.DeliverSubject = nats.NewInbox()
+	}
+
+	// messages are handled with the concurrency of the subscription
+	d := broker.NewDispatcher(opt)
+
+	fn := func(msg *nats.Msg) {
+		var m broker.Message
+		pub := &publication{t: topic, msg: msg}
+		if broker.IsPattern(topic) {
+			// messages are delivered with the subject they were published to
+			pub.t = msg.Subject
+			if !broker.MatchTopic(topic, msg.Subject) {
+				pub.Ack()
+				return
+			}
+		}
+		eh := j.opts.ErrorHandler
+		err := j.opts.Codec.Unmarshal(msg.Data, &m)
+		pub.err = err
+		pub.m = &m
+		if err != nil {
+			m.Body = msg.Data
+			if logger.V(logger.ErrorLevel, logger.DefaultLogger) {
+				logger.Error(err)
+			}
+			if eh != nil {
+				eh(pub)
+			}
+			return
+		}
+		d.Dispatch(m.Header[broker.HeaderPartitionKey], func() {
+			if err := handler(pub); err != nil {
+				pub.err = err
+				if logger.V(logger.ErrorLevel, logger.DefaultLogger) {
+					logger.Error(err)
+				}
+				if eh != nil {
+					eh(pub)
+				}
+				if opt.AutoAck {
+					pub.Nack()
+				}
+				return
+			}
+			if opt.AutoAck {
+				pub.Ack()
+			}
+		})
+	}
+
+	// subscribe before creating the consumer so no messages are missed
+	var sub *nats.Subscription
+	var err error
+	if len(cfg.DeliverGroup) > 0 {
+		sub, err = conn.QueueSubscribe(cfg.DeliverSubject, cfg.DeliverGroup, fn)
+	} else {
+		sub, err = conn.Subscribe(cfg.DeliverSubject, fn)
+	}
+	if err != nil {
+		d.Stop()
+		return nil, err
+	}
+
+	if err := j.createConsumer(stream, cfg); err != nil {
+		sub.Unsubscribe()
+		d.Stop()
+		return nil, err
+	}
+
+	return &subscriber{t: topic, s: sub, d: d, opts: opt}, nil
+}
+
+func (j *jsBroker) String() string {
+	return "jetstream"
+}
+
+func (j *jsBroker) setOption(opts ...broker.Option) {
+	for _, o := range opts {
+		o(&j.opts)
+	}
+
+	j.Once.Do(func() {
+		j.nopts = nats.GetDefaultOptions()
+	})
+
+	if nopts, ok := j.opts.Context.Value(optionsKey{}).(nats.Options); ok {
+		j.nopts = nopts
+	}
+
+	if len(j.opts.Addrs) == 0 {
+		j.opts.Addrs = j.nopts.Servers
+	}
+
+	if !j.opts.Secure {
+		j.opts.Secure = j.nopts.Secure
+	}
+
+	if j.opts.TLSConfig == nil {
+		j.opts.TLSConfig = j.nopts.TLSConfig
+	}
+	j.addrs = j.setAddrs(j.opts.Addrs)
+
+	j.apiPrefix = "$JS.API"
+	if p, ok := j.opts.Context.Value(apiPrefixKey{}).(string); ok {
+		j.apiPrefix = p
+	}
+
+	j.timeout = 5 * time.Second
+	if t, ok := j.opts.Context.Value(timeoutKey{}).(time.Duration); ok {
+		j.timeout = t
+	}
+}
+
+// newConsumerConfig returns the config of the consumer of a subscriber
+func newConsumerConfig(topic string, opt broker.SubscribeOptions) *consumerConfig {
+	cfg := &consumerConfig{
+		DeliverPolicy: deliverNew,
+		AckPolicy:     "explicit",
+		ReplayPolicy:  "instant",
+		FilterSubject: topic,
+	}
+
+	if opt.Context == nil {
+		opt.Context = context.Background()
+	}
+
+	// subscribers sharing a queue share a durable consumer
+	if len(opt.Queue) > 0 {
+		cfg.Durable = opt.Queue
+		cfg.DeliverGroup = opt.Queue
+	}
+	if name, ok := opt.Context.Value(durableKey{}).(string); ok {
+		cfg.Durable = name
+	}
+	// durable names can't contain subject tokens
+	cfg.Durable = strings.NewReplacer(".", "_", "*", "_", ">", "_").Replace(cfg.Durable)
+
+	if d, ok := opt.Context.Value(deliverKey{}).(deliver); ok {
+		cfg.DeliverPolicy = d.policy
+		cfg.OptStartSeq = d.seq
+		if !d.time.IsZero() {
+			t := d.time
+			cfg.OptStartTime = &t
+		}
+	}
+	if d, ok := opt.Context.Value(ackWaitKey{}).(time.Duration); ok {
+		cfg.AckWait = d
+	}
+	if n, ok := opt.Context.Value(maxDeliverKey{}).(int); ok {
+		cfg.MaxDeliver = n
+	}
+	// the server stops delivering once the max messages are unacked
+	if opt.MaxInFlight > 0 {
+		cfg.MaxAckPending = opt.MaxInFlight
+	}
+
+	return cfg
+}
+
+// NewBroker returns a JetStream broker
+func NewBroker(opts ...broker.Option) broker.Broker {
+	options := broker.Options{
+		// Default codec
+		Codec:   json.Marshaler{},
+		Context: context.Background(),
+	}
+
+	j := &jsBroker{
+		opts: options,
+	}
+	j.setOption(opts...)
+
+	return j
+}
+
+// subject returns the nats subject of the topic. The "*" wildcard is native and
+// "#" maps to ">" which matches one or more words. Patterns with "#" before
+// the last word are subscribed to from there and filtered by the subscriber.
+func subject(topic string) string {
+	words := strings.Split(topic, ".")
+	for i, w := range words {
+		if w == "#" {
+			return strings.Join(append(words[:i], ">"), ".")
+		}
+	}
+	return topic
+}
@@ -0,0 +1,58 @@
+package jetstream
+
+import (
+	"testing"
+	"time"
+
+	"github.com/micro/go-micro/v2/broker"
+)
+
+func TestConsumerConfig(t *testing.T) {
+	cfg := newConsumerConfig("orders.created", broker.NewSubscribeOptions(
+		broker.Queue("go.micro.srv.orders"),
+	))
+	if cfg.Durable != "go_micro_srv_orders" {
+		t.Fatalf("Expected durable named after the queue got %s", cfg.Durable)
+	}
+	if cfg.DeliverGroup != "go.micro.srv.orders" {
+		t.Fatalf("Expected deliver group go.micro.srv.orders got %s", cfg.DeliverGroup)
+	}
+	if cfg.DeliverPolicy != deliverNew || cfg.AckPolicy != "explicit" {
+		t.Fatalf("Unexpected policies %s %s", cfg.DeliverPolicy, cfg.AckPolicy)
+	}
+
+	cfg = newConsumerConfig("orders.created", broker.NewSubscribeOptions(
+		Durable("audit"),
+		StartSequence(10),
+		AckWait(time.Minute),
+		MaxDeliver(5),
+	))
+	if cfg.Durable != "audit" || len(cfg.DeliverGroup) > 0 {
+		t.Fatalf("Unexpected durable %s group %s", cfg.Durable, cfg.DeliverGroup)
+	}
+	if cfg.DeliverPolicy != deliverSequence || cfg.OptStartSeq != 10 {
+		t.Fatalf("Unexpected deliver policy %s seq %d", cfg.DeliverPolicy, cfg.OptStartSeq)
+	}
+	if cfg.AckWait != time.Minute || cfg.MaxDeliver != 5 {
+		t.Fatalf("Unexpected ack wait %v max deliver %d", cfg.AckWait, cfg.MaxDeliver)
+	}
+
+	now := time.Now()
+	cfg = newConsumerConfig("orders.created", broker.NewSubscribeOptions(StartTime(now)))
+	if len(cfg.Durable) > 0 {
+		t.Fatalf("Expected an ephemeral consumer got %s", cfg.Durable)
+	}
+	if cfg.DeliverPolicy != deliverTime || cfg.OptStartTime == nil || !cfg.OptStartTime.Equal(now) {
+		t.Fatalf("Unexpected deliver policy %s time %v", cfg.DeliverPolicy, cfg.OptStartTime)
+	}
+}
+
+func TestParsePubAck(t *testing.T) {
+	if err := parsePubAck([]byte(`{"stream":"ORDERS","seq":1}`)); err != nil {
+		t.Fatal(err)
+	}
+	err := parsePubAck([]byte(`{"error":{"code":503,"description":"jetstream not enabled"}}`))
+	if aerr, ok := err.(*apiError); !ok || aerr.Code != 503 {
+		t.Fatalf("Expected api error got %v", err)
+	}
+}
@@ -0,0 +1,113 @@
+package jetstream
+
+import (
+	"context"
+	"time"
+
+	"github.com/micro/go-micro/v2/broker"
+	nats "github.com/nats-io/nats.go"
+)
+
+type optionsKey struct{}
+
+type apiPrefixKey struct{}
+
+type timeoutKey struct{}
+
+type streamKey struct{}
+
+type durableKey struct{}
+
+type deliverKey struct{}
+
+type ackWaitKey struct{}
+
+type maxDeliverKey struct{}
+
+// deliver is where a consumer starts in the stream
+type deliver struct {
+	policy string
+	seq    uint64
+	time   time.Time
+}
+
+// Options accepts nats.Options
+func Options(opts nats.Options) broker.Option {
+	return setBrokerOption(optionsKey{}, opts)
+}
+
+// APIPrefix sets the prefix of the JetStream api subjects, $JS.API by default
+func APIPrefix(p string) broker.Option {
+	return setBrokerOption(apiPrefixKey{}, p)
+}
+
+// Timeout sets the timeout of api requests and publish acks
+func Timeout(d time.Duration) broker.Option {
+	return setBrokerOption(timeoutKey{}, d)
+}
+
+// Stream creates the stream the subscriber consumes from if it doesn't
+// exist. Without it the stream storing the topic is looked up.
+func Stream(cfg StreamConfig) broker.SubscribeOption {
+	return setSubscribeOption(streamKey{}, cfg)
+}
+
+// Durable sets the name of a durable consumer which keeps its position in
+// the stream across restarts. Subscribers with a queue default to a durable
+// consumer named after the queue.
+func Durable(name string) broker.SubscribeOption {
+	return setSubscribeOption(durableKey{}, name)
+}
+
+// DeliverAll delivers every message in the stream to a new consumer
+func DeliverAll() broker.SubscribeOption {
+	return setSubscribeOption(deliverKey{}, deliver{policy: deliverAll})
+}
+
+// DeliverNew delivers messages published after the consumer is created. This is the default.
+func DeliverNew() broker.SubscribeOption {
+	return setSubscribeOption(deliverKey{}, deliver{policy: deliverNew})
+}
+
+// DeliverLast delivers the last message in the stream and every message after it
+func DeliverLast() broker.SubscribeOption {
+	return setSubscribeOption(deliverKey{}, deliver{policy: deliverLast})
+}
+
+// StartSequence replays the stream from the sequence
+func StartSequence(seq uint64) broker.SubscribeOption {
+	return setSubscribeOption(deliverKey{}, deliver{policy: deliverSequence, seq: seq})
+}
+
+// StartTime replays the stream from the time
+func StartTime(t time.Time) broker.SubscribeOption {
+	return setSubscribeOption(deliverKey{}, deliver{policy: deliverTime, time: t})
+}
+
+// AckWait sets how long the server waits for an ack before redelivering a message
+func AckWait(d time.Duration) broker.SubscribeOption {
+	return setSubscribeOption(ackWaitKey{}, d)
+}
+
+// MaxDeliver sets the maximum number of times a message is delivered
+func MaxDeliver(n int) broker.SubscribeOption {
+	return setSubscribeOption(maxDeliverKey{}, n)
+}
+
+func setBrokerOption(k, v interface{}) broker.Option {
+	return func(o *broker.Options) {
+		if o.Context == nil {
+			o.Context = context.Background()
+		}
+		o.Context = context.WithValue(o.Context, k, v)
+	}
+}
+
+func setSubscribeOption(k, v interface{}) broker.SubscribeOption {
+	return func(o *broker.SubscribeOptions) {
+		if o.Context == nil {
+			o.Context = context.Background()
+		}
+		o.Context = context.WithValue(o.Context, k, v)
+	}
+}
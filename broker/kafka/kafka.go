@@ -0,0 +1,518 @@
+// Package kafka provides a kafka broker using sarama
+package kafka
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/Shopify/sarama"
+	"github.com/google/uuid"
+	"github.com/micro/go-micro/v2/broker"
+	"github.com/micro/go-micro/v2/codec/json"
+	"github.com/micro/go-micro/v2/logger"
+)
+
+type kBroker struct {
+	addrs []string
+
+	sync.RWMutex
+	connected bool
+	c         sarama.Client
+	p         sarama.SyncProducer
+
+	// clients of the subscribers
+	scMutex sync.Mutex
+	sc      []sarama.Client
+
+	opts broker.Options
+}
+
+type subscriber struct {
+	k    *kBroker
+	t    string
+	opts broker.SubscribeOptions
+	d    *broker.Dispatcher
+
+	cg     sarama.ConsumerGroup
+	c      sarama.Client
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+type publication struct {
+	t    string
+	err  error
+	m    *broker.Message
+	km   *sarama.ConsumerMessage
+	sess sarama.ConsumerGroupSession
+	mark bool
+}
+
+// consumerGroupHandler is the sarama.ConsumerGroupHandler of a subscriber
+type consumerGroupHandler struct {
+	handler    broker.Handler
+	subopts    broker.SubscribeOptions
+	kopts      broker.Options
+	strategy   CommitStrategy
+	dispatcher *broker.Dispatcher
+}
+
+func (p *publication) Topic() string {
+	return p.t
+}
+
+func (p *publication) Message() *broker.Message {
+	return p.m
+}
+
+func (p *publication) Ack() error {
+	if p.mark {
+		p.sess.MarkMessage(p.km, "")
+	}
+	return nil
+}
+
+// Nack leaves the offset of the message unmarked. Kafka has no redelivery of
+// single messages so it's only redelivered if no later message is acked
+// before the partition is consumed again.
+func (p *publication) Nack() error {
+	return nil
+}
+
+func (p *publication) Requeue() error {
+	return nil
+}
+
+func (p *publication) Error() error {
+	return p.err
+}
+
+func (s *subscriber) Options() broker.SubscribeOptions {
+	return s.opts
+}
+
+func (s *subscriber) Topic() string {
+	return s.t
+}
+
+func (s *subscriber) Unsubscribe() error {
+	s.cancel()
+	err := s.cg.Close()
+	<-s.done
+	s.d.Stop()
+
+	s.k.scMutex.Lock()
+	for i, c := range s.k.sc {
+		if c == s.c {
+			s.k.sc = append(s.k.sc[:i], s.k.sc[i+1:]...)
+			break
+		}
+	}
+	s.k.scMutex.Unlock()
+
+	if cerr := s.c.Close(); err == nil && cerr != sarama.ErrClosedClient {
+		err = cerr
+	}
+	return err
+}
+
+func (h *consumerGroupHandler) Setup(sarama.ConsumerGroupSession) error {
+	return nil
+}
+
+func (h *consumerGroupHandler) Cleanup(sarama.ConsumerGroupSession) error {
+	return nil
+}
+
+func (h *consumerGroupHandler) ConsumeClaim(sess sarama.ConsumerGroupSession, claim sarama.ConsumerGroupClaim) error {
+	for msg := range claim.Messages() {
+		var m broker.Message
+		p := &publication{
+			t:    msg.Topic,
+			m:    &m,
+			km:   msg,
+			sess: sess,
+			mark: h.strategy == CommitOnAck,
+		}
+		eh := h.kopts.ErrorHandler
+
+		if h.strategy == CommitOnReceive {
+			sess.MarkMessage(msg, "")
+		}
+
+		if err := h.kopts.Codec.Unmarshal(msg.Value, &m); err != nil {
+			p.err = err
+			p.m.Body = msg.Value
+			if eh != nil {
+				eh(p)
+			} else if logger.V(logger.ErrorLevel, logger.DefaultLogger) {
+				logger.Errorf("[kafka]: failed to unmarshal: %v", err)
+			}
+			continue
+		}
+
+		h.dispatcher.Dispatch(m.Header[broker.HeaderPartitionKey], func() {
+			err := h.handler(p)
+			if err == nil && h.subopts.AutoAck {
+				p.Ack()
+			} else if err != nil {
+				p.err = err
+				if eh != nil {
+					eh(p)
+				} else if logger.V(logger.ErrorLevel, logger.DefaultLogger) {
+					logger.Errorf("[kafka]: subscriber error: %v", err)
+				}
+			}
+		})
+	}
+	return nil
+}
+
+func (k *kBroker) Address() string {
+	if len(k.addrs) > 0 {
+		return k.addrs[0]
+	}
+	return "127.0.0.1:9092"
+}
+
+func (k *kBroker) Connect() error {
+	k.Lock()
+	defer k.Unlock()
+
+	if k.connected {
+		return nil
+	}
+
+	c, err := sarama.NewClient(k.addrs, k.config())
+	if err != nil {
+		return err
+	}
+
+	p, err := sarama.NewSyncProducerFromClient(c)
+	if err != nil {
+		c.Close()
+		return err
+	}
+
+	k.c = c
+	k.p = p
+	k.connected = true
+
+	return nil
+}
+
+func (k *kBroker) Disconnect() error {
+	k.Lock()
+	defer k.Unlock()
+
+	if !k.connected {
+		return nil
+	}
+
+	k.scMutex.Lock()
+	for _, client := range k.sc {
+		client.Close()
+	}
+	k.sc = nil
+	k.scMutex.Unlock()
+
+	k.p.Close()
+	err := k.c.Close()
+	k.connected = false
+
+	return err
+}
+
+func (k *kBroker) Init(opts ...broker.Option) error {
+	for _, o := range opts {
+		o(&k.opts)
+	}
+	k.addrs = setAddrs(k.opts.Addrs)
+	return nil
+}
+
+func (k *kBroker) Options() broker.Options {
+	return k.opts
+}
+
+func (k *kBroker) Publish(topic string, msg *broker.Message, opts ...broker.PublishOption) error {
+	// messages delivered later are published by the scheduler
+	if broker.Deferred(k, topic, msg, opts...) {
+		return nil
+	}
+
+	msg, err := broker.EncodeMessage(msg, opts...)
+	if err != nil {
+		return err
+	}
+
+	k.RLock()
+	defer k.RUnlock()
+
+	if !k.connected {
+		return errors.New("not connected")
+	}
+
+	options := broker.PublishOptions{
+		Context: context.Background(),
+	}
+	for _, o := range opts {
+		o(&options)
+	}
+
+	b, err := k.opts.Codec.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	pm := &sarama.ProducerMessage{
+		Topic: topic,
+		Value: sarama.ByteEncoder(b),
+	}
+	if key := k.partitionKey(msg, options); len(key) > 0 {
+		pm.Key = sarama.StringEncoder(key)
+	}
+
+	_, _, err = k.p.SendMessage(pm)
+	return err
+}
+
+func (k *kBroker) Subscribe(topic string, handler broker.Handler, opts ...broker.SubscribeOption) (broker.Subscriber, error) {
+	k.RLock()
+	connected := k.connected
+	k.RUnlock()
+
+	if !connected {
+		return nil, errors.New("not connected")
+	}
+
+	opt := broker.SubscribeOptions{
+		AutoAck: true,
+		Context: context.Background(),
+	}
+	for _, o := range opts {
+		o(&opt)
+	}
+
+	// handlers running longer than the timeout fail
+	handler = broker.TimeoutHandler(handler, opt)
+	// messages the handler keeps failing on are dead lettered
+	handler = broker.DeadLetterHandler(k, broker.DecompressHandler(handler), opt)
+	// messages with the same partition key are handled in order
+	handler = broker.OrderedHandler(handler, opt)
+
+	// subscribers without a queue receive every message
+	// so they each need their own consumer group
+	group := opt.Queue
+	if len(group) == 0 {
+		group = uuid.New().String()
+	}
+
+	c, err := sarama.NewClient(k.addrs, k.subscribeConfig(opt))
+	if err != nil {
+		return nil, err
+	}
+
+	cg, err := sarama.NewConsumerGroupFromClient(group, c)
+	if err != nil {
+		c.Close()
+		return nil, err
+	}
+
+	k.scMutex.Lock()
+	k.sc = append(k.sc, c)
+	k.scMutex.Unlock()
+
+	h := &consumerGroupHandler{
+		handler:    handler,
+		subopts:    opt,
+		kopts:      k.opts,
+		strategy:   CommitOnAck,
+		dispatcher: broker.NewDispatcher(opt),
+	}
+	if s, ok := opt.Context.Value(commitStrategyKey{}).(CommitStrategy); ok {
+		h.strategy = s
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	sub := &subscriber{
+		k:      k,
+		t:      topic,
+		opts:   opt,
+		d:      h.dispatcher,
+		cg:     cg,
+		c:      c,
+		cancel: cancel,
+		done:   make(chan struct{}),
+	}
+
+	go func() {
+		for err := range cg.Errors() {
+			if logger.V(logger.ErrorLevel, logger.DefaultLogger) {
+				logger.Errorf("[kafka]: consumer group error: %v", err)
+			}
+		}
+	}()
+
+	go func() {
+		defer close(sub.done)
+		for {
+			// topics matching a pattern are listed on every rebalance
+			topics, err := matchTopics(c, topic)
+			if err == nil {
+				// consume returns when the group rebalances
+				err = cg.Consume(ctx, topics, h)
+			}
+			if ctx.Err() != nil {
+				return
+			}
+			switch err {
+			case nil:
+				continue
+			case sarama.ErrClosedConsumerGroup:
+				return
+			default:
+				if logger.V(logger.ErrorLevel, logger.DefaultLogger) {
+					logger.Errorf("[kafka]: consume error: %v", err)
+				}
+				time.Sleep(time.Second)
+			}
+		}
+	}()
+
+	return sub, nil
+}
+
+func (k *kBroker) String() string {
+	return "kafka"
+}
+
+func setAddrs(addrs []string) []string {
+	//nolint:prealloc
+	var cAddrs []string
+	for _, addr := range addrs {
+		if len(addr) == 0 {
+			continue
+		}
+		cAddrs = append(cAddrs, addr)
+	}
+	if len(cAddrs) == 0 {
+		cAddrs = []string{"127.0.0.1:9092"}
+	}
+	return cAddrs
+}
+
+// partitionKey returns the key of the message, either set explicitly
+// when publishing or taken from the configured key header
+func (k *kBroker) partitionKey(msg *broker.Message, opts broker.PublishOptions) string {
+	if opts.Context != nil {
+		if key, ok := opts.Context.Value(keyKey{}).(string); ok {
+			return key
+		}
+	}
+	if len(opts.Key) > 0 {
+		return opts.Key
+	}
+	if h, ok := k.opts.Context.Value(keyHeaderKey{}).(string); ok {
+		return msg.Header[h]
+	}
+	return ""
+}
+
+// config returns the sarama config of the broker
+func (k *kBroker) config() *sarama.Config {
+	var cfg sarama.Config
+
+	if c, ok := k.opts.Context.Value(brokerConfigKey{}).(*sarama.Config); ok {
+		cfg = *c
+	} else {
+		cfg = *sarama.NewConfig()
+		// consumer groups need at least 0.10.2
+		cfg.Version = sarama.V0_10_2_0
+	}
+
+	// the sync producer needs the successes
+	cfg.Producer.Return.Successes = true
+	cfg.Producer.Return.Errors = true
+
+	if k.opts.Secure || k.opts.TLSConfig != nil {
+		cfg.Net.TLS.Enable = true
+		cfg.Net.TLS.Config = k.opts.TLSConfig
+	}
+
+	if sp, ok := k.opts.Context.Value(saslPlainKey{}).(saslPlain); ok {
+		cfg.Net.SASL.Enable = true
+		cfg.Net.SASL.Mechanism = sarama.SASLTypePlaintext
+		cfg.Net.SASL.User = sp.user
+		cfg.Net.SASL.Password = sp.password
+	}
+
+	return &cfg
+}
+
+// subscribeConfig returns the sarama config of a subscriber
+func (k *kBroker) subscribeConfig(opt broker.SubscribeOptions) *sarama.Config {
+	cfg := k.config()
+
+	cfg.Consumer.Return.Errors = true
+	if o, ok := opt.Context.Value(initialOffsetKey{}).(int64); ok {
+		cfg.Consumer.Offsets.Initial = o
+	}
+	if d, ok := opt.Context.Value(commitIntervalKey{}).(time.Duration); ok {
+		cfg.Consumer.Offsets.AutoCommit.Interval = d
+	}
+	// messages are fetched up to the max in flight
+	if opt.MaxInFlight > 0 {
+		cfg.ChannelBufferSize = opt.MaxInFlight
+	}
+
+	return cfg
+}
+
+// NewBroker returns a kafka broker
+func NewBroker(opts ...broker.Option) broker.Broker {
+	options := broker.Options{
+		// default to json codec
+		Codec:   json.Marshaler{},
+		Context: context.Background(),
+	}
+
+	for _, o := range opts {
+		o(&options)
+	}
+
+	return &kBroker{
+		addrs: setAddrs(options.Addrs),
+		opts:  options,
+	}
+}
+
+// matchTopics returns the topics of the cluster matching the topic pattern
+func matchTopics(c sarama.Client, topic string) ([]string, error) {
+	if !broker.IsPattern(topic) {
+		return []string{topic}, nil
+	}
+
+	if err := c.RefreshMetadata(); err != nil {
+		return nil, err
+	}
+	all, err := c.Topics()
+	if err != nil {
+		return nil, err
+	}
+
+	var topics []string
+	for _, t := range all {
+		if broker.MatchTopic(topic, t) {
+			topics = append(topics, t)
+		}
+	}
+	if len(topics) == 0 {
+		return nil, fmt.Errorf("no topics match %s", topic)
+	}
+
+	return topics, nil
+}
@@ -0,0 +1,57 @@
+package kafka
+
+import (
+	"testing"
+
+	"github.com/Shopify/sarama"
+	"github.com/micro/go-micro/v2/broker"
+)
+
+func TestPartitionKey(t *testing.T) {
+	k := NewBroker(KeyHeader("Micro-Id")).(*kBroker)
+	msg := &broker.Message{Header: map[string]string{"Micro-Id": "1"}}
+
+	var opts broker.PublishOptions
+	if key := k.partitionKey(msg, opts); key != "1" {
+		t.Fatalf("Expected key from header got %q", key)
+	}
+
+	Key("2")(&opts)
+	if key := k.partitionKey(msg, opts); key != "2" {
+		t.Fatalf("Expected explicit key got %q", key)
+	}
+
+	k = NewBroker().(*kBroker)
+	if key := k.partitionKey(msg, broker.PublishOptions{}); len(key) > 0 {
+		t.Fatalf("Expected no key got %q", key)
+	}
+}
+
+func TestConfig(t *testing.T) {
+	k := NewBroker(
+		broker.Addrs("kafka:9092"),
+		broker.Secure(true),
+		SASLPlain("user", "pass"),
+	).(*kBroker)
+
+	if addr := k.Address(); addr != "kafka:9092" {
+		t.Fatalf("Expected address kafka:9092 got %s", addr)
+	}
+
+	cfg := k.config()
+	if !cfg.Net.TLS.Enable {
+		t.Fatal("Expected tls to be enabled")
+	}
+	if !cfg.Net.SASL.Enable || cfg.Net.SASL.User != "user" || cfg.Net.SASL.Password != "pass" {
+		t.Fatalf("Unexpected sasl config %+v", cfg.Net.SASL)
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	opts := broker.NewSubscribeOptions(InitialOffset(sarama.OffsetOldest), Commit(CommitOnReceive))
+	cfg = k.subscribeConfig(opts)
+	if cfg.Consumer.Offsets.Initial != sarama.OffsetOldest {
+		t.Fatalf("Expected oldest initial offset got %d", cfg.Consumer.Offsets.Initial)
+	}
+}
@@ -0,0 +1,105 @@
+package kafka
+
+import (
+	"context"
+	"time"
+
+	"github.com/Shopify/sarama"
+	"github.com/micro/go-micro/v2/broker"
+)
+
+// CommitStrategy decides when the offset of a message is marked for commit
+type CommitStrategy int
+
+const (
+	// CommitOnAck marks the offset when the message is acked. Messages which
+	// aren't acked are redelivered after a restart giving at-least-once delivery.
+	CommitOnAck CommitStrategy = iota
+	// CommitOnReceive marks the offset before the handler is called giving
+	// at-most-once delivery.
+	CommitOnReceive
+)
+
+type brokerConfigKey struct{}
+
+type keyHeaderKey struct{}
+
+type saslPlainKey struct{}
+
+type saslPlain struct {
+	user, password string
+}
+
+type keyKey struct{}
+
+type initialOffsetKey struct{}
+
+type commitStrategyKey struct{}
+
+type commitIntervalKey struct{}
+
+// BrokerConfig sets the sarama config used by the producer and consumers.
+// Use it for settings which have no option e.g the kafka version or SCRAM auth.
+func BrokerConfig(c *sarama.Config) broker.Option {
+	return setBrokerOption(brokerConfigKey{}, c)
+}
+
+// KeyHeader sets the message header used as the partitioning key when
+// a message is published without a Key
+func KeyHeader(h string) broker.Option {
+	return setBrokerOption(keyHeaderKey{}, h)
+}
+
+// SASLPlain authenticates with the brokers using SASL/PLAIN
+func SASLPlain(user, password string) broker.Option {
+	return setBrokerOption(saslPlainKey{}, saslPlain{user, password})
+}
+
+// Key sets the partitioning key of the message. Messages with
+// the same key are published to the same partition.
+func Key(k string) broker.PublishOption {
+	return setPublishOption(keyKey{}, k)
+}
+
+// InitialOffset sets where a new consumer group starts consuming,
+// either sarama.OffsetNewest (the default) or sarama.OffsetOldest
+func InitialOffset(o int64) broker.SubscribeOption {
+	return setSubscribeOption(initialOffsetKey{}, o)
+}
+
+// Commit sets the offset commit strategy of the subscriber
+func Commit(s CommitStrategy) broker.SubscribeOption {
+	return setSubscribeOption(commitStrategyKey{}, s)
+}
+
+// CommitInterval sets how often the marked offsets are committed
+func CommitInterval(d time.Duration) broker.SubscribeOption {
+	return setSubscribeOption(commitIntervalKey{}, d)
+}
+
+func setBrokerOption(k, v interface{}) broker.Option {
+	return func(o *broker.Options) {
+		if o.Context == nil {
+			o.Context = context.Background()
+		}
+		o.Context = context.WithValue(o.Context, k, v)
+	}
+}
+
+func setPublishOption(k, v interface{}) broker.PublishOption {
+	return func(o *broker.PublishOptions) {
+		if o.Context == nil {
+			o.Context = context.Background()
+		}
+		o.Context = context.WithValue(o.Context, k, v)
+	}
+}
+
+func setSubscribeOption(k, v interface{}) broker.SubscribeOption {
+	return func(o *broker.SubscribeOptions) {
+		if o.Context == nil {
+			o.Context = context.Background()
+		}
+		o.Context = context.WithValue(o.Context, k, v)
+	}
+}
@@ -32,11 +32,13 @@ type memoryEvent struct {
 }
 
 type memorySubscriber struct {
-	id      string
-	topic   string
-	exit    chan bool
-	handler broker.Handler
-	opts    broker.SubscribeOptions
+	id          string
+	topic       string
+	exit        chan bool
+	handler     broker.Handler
+	redeliverer *broker.Redeliverer
+	dispatcher  *broker.Dispatcher
+	opts        broker.SubscribeOptions
 }
 
 func (m *memoryBroker) Options() broker.Options {
@@ -91,15 +93,31 @@ func (m *memoryBroker) Init(opts ...broker.Option) error {
 }
 
 func (m *memoryBroker) Publish(topic string, msg *broker.Message, opts ...broker.PublishOption) error {
+	// messages delivered later are published by the scheduler
+	if broker.Deferred(m, topic, msg, opts...) {
+		return nil
+	}
+
+	msg, err := broker.EncodeMessage(msg, opts...)
+	if err != nil {
+		return err
+	}
+
 	m.RLock()
 	if !m.connected {
 		m.RUnlock()
 		return errors.New("not connected")
 	}
 
-	subs, ok := m.Subscribers[topic]
+	// subscribers of the topic and of patterns matching it
+	var subs []*memorySubscriber
+	for t, ts := range m.Subscribers {
+		if broker.MatchTopic(t, topic) {
+			subs = append(subs, ts...)
+		}
+	}
 	m.RUnlock()
-	if !ok {
+	if len(subs) == 0 {
 		return nil
 	}
 
@@ -120,8 +138,12 @@ func (m *memoryBroker) Publish(topic string, msg *broker.Message, opts ...broker
 		opts:    m.opts,
 	}
 
+	key := msg.Header[broker.HeaderPartitionKey]
+
 	for _, sub := range subs {
-		if err := sub.handler(p); err != nil {
+		if err := sub.dispatcher.Wait(key, func() error {
+			return sub.handler(p)
+		}); err != nil {
 			p.err = err
 			if eh := m.opts.ErrorHandler; eh != nil {
 				eh(p)
@@ -147,12 +169,24 @@ func (m *memoryBroker) Subscribe(topic string, handler broker.Handler, opts ...b
 		o(&options)
 	}
 
+	// handlers running longer than the timeout fail
+	handler = broker.TimeoutHandler(handler, options)
+	// messages the handler keeps failing on are dead lettered
+	handler = broker.DeadLetterHandler(m, broker.DecompressHandler(handler), options)
+	// messages with the same partition key are handled in order
+	handler = broker.OrderedHandler(handler, options)
+
+	// nacked and requeued messages are redelivered from memory
+	rd := broker.NewRedeliverer(handler, options)
+
 	sub := &memorySubscriber{
-		exit:    make(chan bool, 1),
-		id:      uuid.New().String(),
-		topic:   topic,
-		handler: handler,
-		opts:    options,
+		exit:        make(chan bool, 1),
+		id:          uuid.New().String(),
+		topic:       topic,
+		handler:     rd.Handle,
+		redeliverer: rd,
+		dispatcher:  broker.NewDispatcher(options),
+		opts:        options,
 	}
 
 	m.Lock()
@@ -206,6 +240,15 @@ func (m *memoryEvent) Ack() error {
 	return nil
 }
 
+// Nack and Requeue are handled by the redeliverer of the subscriber
+func (m *memoryEvent) Nack() error {
+	return nil
+}
+
+func (m *memoryEvent) Requeue() error {
+	return nil
+}
+
 func (m *memoryEvent) Error() error {
 	return m.err
 }
@@ -219,6 +262,8 @@ func (m *memorySubscriber) Topic() string {
 }
 
 func (m *memorySubscriber) Unsubscribe() error {
+	m.redeliverer.Stop()
+	m.dispatcher.Stop()
 	m.exit <- true
 	return nil
 }
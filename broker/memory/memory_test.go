@@ -1,8 +1,10 @@
 package memory
 
 import (
+	"errors"
 	"fmt"
 	"testing"
+	"time"
 
 	"github.com/micro/go-micro/v2/broker"
 )
@@ -48,3 +50,408 @@ func TestMemoryBroker(t *testing.T) {
 		t.Fatalf("Unexpected connect error %v", err)
 	}
 }
+
+func TestMemoryBrokerDeadLetter(t *testing.T) {
+	b := NewBroker()
+
+	if err := b.Connect(); err != nil {
+		t.Fatalf("Unexpected connect error %v", err)
+	}
+	defer b.Disconnect()
+
+	var dead []*broker.Message
+	if _, err := b.Subscribe("test.dead", func(p broker.Event) error {
+		dead = append(dead, p.Message())
+		return nil
+	}); err != nil {
+		t.Fatalf("Unexpected error subscribing %v", err)
+	}
+
+	var attempts int
+	fn := func(p broker.Event) error {
+		attempts++
+		if string(p.Message().Body) == "panic" {
+			panic("poisoned")
+		}
+		return errors.New("failed")
+	}
+
+	if _, err := b.Subscribe("test", fn, broker.DeadLetter("test.dead", 2)); err != nil {
+		t.Fatalf("Unexpected error subscribing %v", err)
+	}
+
+	for _, body := range []string{"error", "panic"} {
+		message := &broker.Message{
+			Header: map[string]string{"foo": "bar"},
+			Body:   []byte(body),
+		}
+		if err := b.Publish("test", message); err != nil {
+			t.Fatalf("Unexpected error publishing %v", err)
+		}
+	}
+
+	if attempts != 4 {
+		t.Fatalf("Expected 4 attempts got %d", attempts)
+	}
+	if len(dead) != 2 {
+		t.Fatalf("Expected 2 dead letters got %d", len(dead))
+	}
+
+	for _, m := range dead {
+		if m.Header["foo"] != "bar" {
+			t.Fatalf("Expected the original headers got %v", m.Header)
+		}
+		if m.Header[broker.DeadLetterTopicHeader] != "test" {
+			t.Fatalf("Expected dead letter topic test got %v", m.Header[broker.DeadLetterTopicHeader])
+		}
+		if m.Header[broker.DeadLetterAttemptsHeader] != "2" {
+			t.Fatalf("Expected 2 attempts got %v", m.Header[broker.DeadLetterAttemptsHeader])
+		}
+	}
+
+	if e := dead[1].Header[broker.DeadLetterErrorHeader]; e != "panic recovered: poisoned" {
+		t.Fatalf("Expected the panic as error got %v", e)
+	}
+}
+
+func TestMemoryBrokerDelay(t *testing.T) {
+	b := NewBroker()
+
+	if err := b.Connect(); err != nil {
+		t.Fatalf("Unexpected connect error %v", err)
+	}
+	defer b.Disconnect()
+
+	received := make(chan *broker.Message, 2)
+	if _, err := b.Subscribe("test", func(p broker.Event) error {
+		received <- p.Message()
+		return nil
+	}); err != nil {
+		t.Fatalf("Unexpected error subscribing %v", err)
+	}
+
+	message := &broker.Message{
+		Header: map[string]string{"id": "1"},
+		Body:   []byte(`hello world`),
+	}
+
+	started := time.Now()
+	if err := b.Publish("test", message, broker.Delay(50*time.Millisecond)); err != nil {
+		t.Fatalf("Unexpected error publishing %v", err)
+	}
+	if len(received) != 0 {
+		t.Fatal("Expected the message to be delayed")
+	}
+
+	// the caller may reuse the message
+	message.Header["id"] = "2"
+
+	// messages scheduled in the past are delivered now
+	if err := b.Publish("test", message, broker.Schedule(started.Add(-time.Second))); err != nil {
+		t.Fatalf("Unexpected error publishing %v", err)
+	}
+	if m := <-received; m.Header["id"] != "2" {
+		t.Fatalf("Expected message 2 got %v", m.Header["id"])
+	}
+
+	select {
+	case m := <-received:
+		if m.Header["id"] != "1" {
+			t.Fatalf("Expected message 1 got %v", m.Header["id"])
+		}
+		if time.Since(started) < 50*time.Millisecond {
+			t.Fatal("Expected the message to be delivered after the delay")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected the delayed message to be delivered")
+	}
+}
+
+func TestMemoryBrokerRedelivery(t *testing.T) {
+	b := NewBroker()
+
+	if err := b.Connect(); err != nil {
+		t.Fatalf("Unexpected connect error %v", err)
+	}
+	defer b.Disconnect()
+
+	attempts := make(chan int, 10)
+	var count int
+
+	// the handler nacks the first delivery and requeues the second
+	sub, err := b.Subscribe("nack", func(p broker.Event) error {
+		count++
+		attempts <- count
+		switch count {
+		case 1:
+			return p.Nack()
+		case 2:
+			return p.Requeue()
+		}
+		return p.Ack()
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error subscribing %v", err)
+	}
+
+	if err := b.Publish("nack", &broker.Message{Body: []byte(`hello`)}); err != nil {
+		t.Fatalf("Unexpected error publishing %v", err)
+	}
+
+	for i := 1; i <= 3; i++ {
+		select {
+		case n := <-attempts:
+			if n != i {
+				t.Fatalf("Expected delivery %d got %d", i, n)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("Expected delivery %d", i)
+		}
+	}
+	sub.Unsubscribe()
+
+	// handler errors are redelivered until the attempts are exhausted
+	policy := broker.RedeliveryPolicy{
+		MaxAttempts: 3,
+		Backoff:     broker.ExponentialBackoff(time.Millisecond, 10*time.Millisecond),
+	}
+	failures := make(chan error, 10)
+	if _, err := b.Subscribe("error", func(p broker.Event) error {
+		err := errors.New("failed")
+		failures <- err
+		return err
+	}, broker.Redelivery(policy)); err != nil {
+		t.Fatalf("Unexpected error subscribing %v", err)
+	}
+
+	b.Publish("error", &broker.Message{Body: []byte(`hello`)})
+
+	for i := 0; i < policy.MaxAttempts; i++ {
+		select {
+		case <-failures:
+		case <-time.After(time.Second):
+			t.Fatalf("Expected %d deliveries got %d", policy.MaxAttempts, i)
+		}
+	}
+	select {
+	case <-failures:
+		t.Fatal("Expected no delivery after the max attempts")
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestRedeliveryPolicy(t *testing.T) {
+	backoff := broker.ExponentialBackoff(time.Second, 5*time.Second)
+
+	for attempt, d := range []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second} {
+		if v := backoff(attempt + 1); v != d {
+			t.Fatalf("Expected backoff %v for attempt %d got %v", d, attempt+1, v)
+		}
+	}
+
+	p := broker.RedeliveryPolicy{MaxAttempts: 2, Backoff: backoff}
+	if d, ok := p.Next(1); !ok || d != time.Second {
+		t.Fatalf("Expected redelivery after 1s got %v %v", d, ok)
+	}
+	if _, ok := p.Next(2); ok {
+		t.Fatal("Expected no redelivery after the max attempts")
+	}
+}
+
+func TestMemoryBrokerCompression(t *testing.T) {
+	b := NewBroker()
+
+	if err := b.Connect(); err != nil {
+		t.Fatalf("Unexpected connect error %v", err)
+	}
+	defer b.Disconnect()
+
+	received := make(chan *broker.Message, 1)
+	if _, err := b.Subscribe("test", func(p broker.Event) error {
+		received <- p.Message()
+		return nil
+	}); err != nil {
+		t.Fatalf("Unexpected error subscribing %v", err)
+	}
+
+	body := []byte(`{"message":"hello world hello world hello world"}`)
+
+	for _, encoding := range []string{"gzip", "snappy", "zstd"} {
+		msg := &broker.Message{
+			Header: map[string]string{"id": encoding},
+			Body:   body,
+		}
+
+		compressed, err := broker.CompressMessage(msg, broker.Compress(encoding))
+		if err != nil {
+			t.Fatalf("Unexpected error compressing with %s: %v", encoding, err)
+		}
+		if compressed.Header[broker.HeaderContentEncoding] != encoding {
+			t.Fatalf("Expected content encoding %s got %v", encoding, compressed.Header)
+		}
+
+		if err := b.Publish("test", msg, broker.Compress(encoding)); err != nil {
+			t.Fatalf("Unexpected error publishing %v", err)
+		}
+
+		m := <-received
+		if string(m.Body) != string(body) {
+			t.Fatalf("Expected decompressed body with %s got %q", encoding, m.Body)
+		}
+		if _, ok := m.Header[broker.HeaderContentEncoding]; ok || m.Header["id"] != encoding {
+			t.Fatalf("Unexpected header %v", m.Header)
+		}
+	}
+
+	if err := b.Publish("test", &broker.Message{Body: body}, broker.Compress("unknown")); err == nil {
+		t.Fatal("Expected an unknown compression error")
+	}
+}
+
+func TestMemoryBrokerWrapper(t *testing.T) {
+	var calls []string
+
+	publishWrapper := func(name string) broker.PublishWrapper {
+		return func(fn broker.PublishFunc) broker.PublishFunc {
+			return func(topic string, msg *broker.Message, opts ...broker.PublishOption) error {
+				calls = append(calls, name)
+				msg.Header["wrapped"] = "true"
+				return fn(topic, msg, opts...)
+			}
+		}
+	}
+
+	handlerWrapper := func(name string) broker.HandlerWrapper {
+		return func(h broker.Handler) broker.Handler {
+			return func(e broker.Event) error {
+				calls = append(calls, name)
+				return h(e)
+			}
+		}
+	}
+
+	b := broker.WrapPublish(publishWrapper("publish1"), publishWrapper("publish2"))(NewBroker())
+	b = broker.WrapHandler(handlerWrapper("handler1"), handlerWrapper("handler2"))(b)
+
+	if err := b.Connect(); err != nil {
+		t.Fatalf("Unexpected connect error %v", err)
+	}
+	defer b.Disconnect()
+
+	var received *broker.Message
+	if _, err := b.Subscribe("test", func(p broker.Event) error {
+		received = p.Message()
+		return nil
+	}); err != nil {
+		t.Fatalf("Unexpected error subscribing %v", err)
+	}
+
+	if err := b.Publish("test", &broker.Message{Header: map[string]string{}}); err != nil {
+		t.Fatalf("Unexpected error publishing %v", err)
+	}
+
+	if received == nil || received.Header["wrapped"] != "true" {
+		t.Fatalf("Expected the message of the publish wrapper got %v", received)
+	}
+
+	expect := []string{"publish1", "publish2", "handler1", "handler2"}
+	if fmt.Sprint(calls) != fmt.Sprint(expect) {
+		t.Fatalf("Expected wrappers called %v got %v", expect, calls)
+	}
+}
+
+func TestMemoryBrokerPattern(t *testing.T) {
+	b := NewBroker()
+
+	if err := b.Connect(); err != nil {
+		t.Fatalf("Unexpected connect error %v", err)
+	}
+	defer b.Disconnect()
+
+	var topics []string
+	if _, err := b.Subscribe("orders.*", func(p broker.Event) error {
+		topics = append(topics, p.Topic())
+		return nil
+	}); err != nil {
+		t.Fatalf("Unexpected error subscribing %v", err)
+	}
+
+	for _, topic := range []string{"orders.created", "orders.created.eu", "audit.login", "orders.deleted"} {
+		if err := b.Publish(topic, &broker.Message{Body: []byte(topic)}); err != nil {
+			t.Fatalf("Unexpected error publishing %v", err)
+		}
+	}
+
+	expect := []string{"orders.created", "orders.deleted"}
+	if fmt.Sprint(topics) != fmt.Sprint(expect) {
+		t.Fatalf("Expected topics %v got %v", expect, topics)
+	}
+}
+
+func TestMemoryBrokerConcurrency(t *testing.T) {
+	b := NewBroker()
+
+	if err := b.Connect(); err != nil {
+		t.Fatalf("Unexpected connect error %v", err)
+	}
+	defer b.Disconnect()
+
+	// both messages are only handled if they're handled at once
+	started := make(chan bool)
+	release := make(chan bool)
+	sub, err := b.Subscribe("test", func(p broker.Event) error {
+		started <- true
+		<-release
+		return nil
+	}, broker.Concurrency(2))
+	if err != nil {
+		t.Fatalf("Unexpected error subscribing %v", err)
+	}
+	defer sub.Unsubscribe()
+
+	errCh := make(chan error, 2)
+	for i := 0; i < 2; i++ {
+		go func() {
+			errCh <- b.Publish("test", &broker.Message{Body: []byte(`hello`)})
+		}()
+	}
+
+	for i := 0; i < 2; i++ {
+		select {
+		case <-started:
+		case <-time.After(time.Second):
+			t.Fatal("Expected the messages to be handled concurrently")
+		}
+	}
+	close(release)
+
+	for i := 0; i < 2; i++ {
+		if err := <-errCh; err != nil {
+			t.Fatalf("Unexpected error publishing %v", err)
+		}
+	}
+}
+
+func TestMemoryBrokerHandlerTimeout(t *testing.T) {
+	b := NewBroker()
+
+	if err := b.Connect(); err != nil {
+		t.Fatalf("Unexpected connect error %v", err)
+	}
+	defer b.Disconnect()
+
+	release := make(chan bool)
+	defer close(release)
+
+	if _, err := b.Subscribe("test", func(p broker.Event) error {
+		<-release
+		return nil
+	}, broker.HandlerTimeout(10*time.Millisecond)); err != nil {
+		t.Fatalf("Unexpected error subscribing %v", err)
+	}
+
+	err := b.Publish("test", &broker.Message{Body: []byte(`hello`)})
+	if err != broker.ErrHandlerTimeout {
+		t.Fatalf("Expected handler timeout error got %v", err)
+	}
+}
@@ -0,0 +1,24 @@
+package broker
+
+// EncodeMessage returns the message as it's published with the options. The
+// partition key is set as a header and the body is compressed.
+func EncodeMessage(msg *Message, opts ...PublishOption) (*Message, error) {
+	var options PublishOptions
+	for _, o := range opts {
+		o(&options)
+	}
+
+	if len(options.Key) > 0 && msg.Header[HeaderPartitionKey] != options.Key {
+		m := &Message{
+			Header: make(map[string]string, len(msg.Header)+1),
+			Body:   msg.Body,
+		}
+		for k, v := range msg.Header {
+			m.Header[k] = v
+		}
+		m.Header[HeaderPartitionKey] = options.Key
+		msg = m
+	}
+
+	return CompressMessage(msg, opts...)
+}
@@ -0,0 +1,63 @@
+package broker
+
+import (
+	"time"
+
+	"github.com/micro/go-micro/v2/metrics"
+)
+
+const (
+	// MetricPublished is the count of the messages published by topic and status
+	MetricPublished = "micro_broker_published"
+	// MetricPublishedBytes is the count of the bytes of the bodies published
+	MetricPublishedBytes = "micro_broker_published_bytes"
+	// MetricReceived is the count of the messages handled by the topic
+	// subscribed to and status
+	MetricReceived = "micro_broker_received"
+	// MetricHandleDuration is the timing of the messages handled
+	MetricHandleDuration = "micro_broker_handle_duration"
+)
+
+type metricsBroker struct {
+	Broker
+	r metrics.Reporter
+}
+
+func (m *metricsBroker) Publish(topic string, msg *Message, opts ...PublishOption) error {
+	err := m.Broker.Publish(topic, msg, opts...)
+
+	tags := metrics.Tags{
+		"topic":  topic,
+		"status": metrics.Status(err),
+	}
+	m.r.Count(MetricPublished, 1, tags)
+	if err == nil {
+		m.r.Count(MetricPublishedBytes, int64(len(msg.Body)), metrics.Tags{"topic": topic})
+	}
+
+	return err
+}
+
+func (m *metricsBroker) Subscribe(topic string, h Handler, opts ...SubscribeOption) (Subscriber, error) {
+	// labeled by the topic subscribed to rather than the topics of the
+	// events so patterns don't make a metric of each topic matched
+	return m.Broker.Subscribe(topic, func(e Event) error {
+		start := time.Now()
+		err := h(e)
+
+		tags := metrics.Tags{
+			"topic":  topic,
+			"status": metrics.Status(err),
+		}
+		m.r.Count(MetricReceived, 1, tags)
+		m.r.Timing(MetricHandleDuration, time.Since(start), tags)
+
+		return err
+	}, opts...)
+}
+
+// Instrument returns a broker reporting the throughput of the messages
+// published and handled
+func Instrument(b Broker, r metrics.Reporter) Broker {
+	return &metricsBroker{Broker: b, r: r}
+}
@@ -0,0 +1,435 @@
+// Package mqtt provides a mqtt broker
+package mqtt
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	paho "github.com/eclipse/paho.mqtt.golang"
+	"github.com/google/uuid"
+	"github.com/micro/go-micro/v2/broker"
+	"github.com/micro/go-micro/v2/codec/json"
+	"github.com/micro/go-micro/v2/logger"
+)
+
+var (
+	errNotConnected = errors.New("not connected")
+
+	// DefaultAddress of the server
+	DefaultAddress = "tcp://127.0.0.1:1883"
+
+	// DefaultQoS of publishes and subscriptions
+	DefaultQoS byte = 1
+
+	// timeout of connecting, publishing and subscribing
+	timeout = 10 * time.Second
+)
+
+type mqttBroker struct {
+	sync.RWMutex
+	opts   broker.Options
+	addrs  []string
+	client paho.Client
+	subs   map[*subscriber]bool
+}
+
+type subscriber struct {
+	b       *mqttBroker
+	topic   string
+	filter  string
+	qos     byte
+	opts    broker.SubscribeOptions
+	handler paho.MessageHandler
+	// dispatches the messages to the handler
+	dispatcher *broker.Dispatcher
+}
+
+type publication struct {
+	topic string
+	m     *broker.Message
+	msg   paho.Message
+	err   error
+}
+
+func (p *publication) Ack() error {
+	p.msg.Ack()
+	return nil
+}
+
+func (p *publication) Nack() error {
+	// mqtt has no redelivery of received messages
+	return nil
+}
+
+func (p *publication) Requeue() error {
+	return nil
+}
+
+func (p *publication) Error() error {
+	return p.err
+}
+
+func (p *publication) Topic() string {
+	return p.topic
+}
+
+func (p *publication) Message() *broker.Message {
+	return p.m
+}
+
+func (s *subscriber) Options() broker.SubscribeOptions {
+	return s.opts
+}
+
+func (s *subscriber) Topic() string {
+	return s.topic
+}
+
+func (s *subscriber) Unsubscribe() error {
+	s.b.Lock()
+	delete(s.b.subs, s)
+	client := s.b.client
+	s.b.Unlock()
+
+	if client == nil {
+		s.dispatcher.Stop()
+		return nil
+	}
+
+	err := wait(client.Unsubscribe(s.filter))
+	s.dispatcher.Stop()
+	return err
+}
+
+func (m *mqttBroker) Address() string {
+	if len(m.addrs) > 0 {
+		return m.addrs[0]
+	}
+	return DefaultAddress
+}
+
+func (m *mqttBroker) Connect() error {
+	m.Lock()
+	defer m.Unlock()
+
+	if m.client != nil && m.client.IsConnected() {
+		return nil
+	}
+
+	m.client = paho.NewClient(m.clientOptions())
+
+	return wait(m.client.Connect())
+}
+
+func (m *mqttBroker) Disconnect() error {
+	m.Lock()
+	defer m.Unlock()
+
+	if m.client == nil {
+		return nil
+	}
+
+	m.client.Disconnect(250)
+	m.client = nil
+
+	return nil
+}
+
+func (m *mqttBroker) Init(opts ...broker.Option) error {
+	m.Lock()
+	defer m.Unlock()
+
+	for _, o := range opts {
+		o(&m.opts)
+	}
+	m.addrs = m.setAddrs(m.opts.Addrs)
+
+	return nil
+}
+
+func (m *mqttBroker) Options() broker.Options {
+	return m.opts
+}
+
+func (m *mqttBroker) Publish(topic string, msg *broker.Message, opts ...broker.PublishOption) error {
+	// messages delivered later are published by the scheduler
+	if broker.Deferred(m, topic, msg, opts...) {
+		return nil
+	}
+
+	msg, err := broker.EncodeMessage(msg, opts...)
+	if err != nil {
+		return err
+	}
+
+	options := broker.PublishOptions{
+		Context: context.Background(),
+	}
+	for _, o := range opts {
+		o(&options)
+	}
+
+	m.RLock()
+	client := m.client
+	m.RUnlock()
+
+	if client == nil {
+		return errNotConnected
+	}
+
+	payload, err := m.payload(msg)
+	if err != nil {
+		return err
+	}
+
+	qos := m.qos()
+	if q, ok := options.Context.Value(qosKey{}).(byte); ok {
+		qos = q
+	}
+	retained, _ := options.Context.Value(retainedKey{}).(bool)
+
+	return wait(client.Publish(topic, qos, retained, payload))
+}
+
+func (m *mqttBroker) Subscribe(topic string, handler broker.Handler, opts ...broker.SubscribeOption) (broker.Subscriber, error) {
+	opt := broker.SubscribeOptions{
+		AutoAck: true,
+		Context: context.Background(),
+	}
+	for _, o := range opts {
+		o(&opt)
+	}
+
+	// handlers running longer than the timeout fail
+	handler = broker.TimeoutHandler(handler, opt)
+	// messages the handler keeps failing on are dead lettered
+	handler = broker.DeadLetterHandler(m, broker.DecompressHandler(handler), opt)
+	// messages with the same partition key are handled in order
+	handler = broker.OrderedHandler(handler, opt)
+
+	m.RLock()
+	client := m.client
+	m.RUnlock()
+
+	if client == nil {
+		return nil, errNotConnected
+	}
+
+	sub := &subscriber{
+		b:      m,
+		topic:  topic,
+		filter: topicFilter(topic, opt.Queue),
+		qos:    m.qos(),
+		opts:   opt,
+
+		dispatcher: broker.NewDispatcher(opt),
+	}
+	if q, ok := opt.Context.Value(qosKey{}).(byte); ok {
+		sub.qos = q
+	}
+
+	sub.handler = func(c paho.Client, msg paho.Message) {
+		if broker.IsPattern(topic) && !broker.MatchTopic(topic, msg.Topic()) {
+			msg.Ack()
+			return
+		}
+
+		p := &publication{
+			topic: msg.Topic(),
+			m:     m.message(msg),
+			msg:   msg,
+		}
+
+		sub.dispatcher.Dispatch(p.m.Header[broker.HeaderPartitionKey], func() {
+			p.err = handler(p)
+			if p.err == nil && opt.AutoAck {
+				p.Ack()
+			} else if p.err != nil {
+				if logger.V(logger.ErrorLevel, logger.DefaultLogger) {
+					logger.Errorf("[mqtt] subscriber error: %v", p.err)
+				}
+				if m.opts.ErrorHandler != nil {
+					m.opts.ErrorHandler(p)
+				}
+			}
+		})
+	}
+
+	if err := wait(client.Subscribe(sub.filter, sub.qos, sub.handler)); err != nil {
+		sub.dispatcher.Stop()
+		return nil, err
+	}
+
+	m.Lock()
+	m.subs[sub] = true
+	m.Unlock()
+
+	return sub, nil
+}
+
+func (m *mqttBroker) String() string {
+	return "mqtt"
+}
+
+func (m *mqttBroker) qos() byte {
+	if q, ok := m.opts.Context.Value(qosKey{}).(byte); ok {
+		return q
+	}
+	return DefaultQoS
+}
+
+func (m *mqttBroker) raw() bool {
+	raw, _ := m.opts.Context.Value(rawMessagesKey{}).(bool)
+	return raw
+}
+
+// payload returns the encoded message or its body for raw messages
+func (m *mqttBroker) payload(msg *broker.Message) ([]byte, error) {
+	if m.raw() {
+		return msg.Body, nil
+	}
+	return m.opts.Codec.Marshal(msg)
+}
+
+// message decodes the payload. Payloads which aren't encoded messages
+// e.g published by devices are the body of the message.
+func (m *mqttBroker) message(msg paho.Message) *broker.Message {
+	if !m.raw() {
+		var bm broker.Message
+		if err := m.opts.Codec.Unmarshal(msg.Payload(), &bm); err == nil && bm.Header != nil {
+			return &bm
+		}
+	}
+
+	return &broker.Message{
+		Header: map[string]string{
+			"Micro-Topic": msg.Topic(),
+		},
+		Body: msg.Payload(),
+	}
+}
+
+// resubscribe restores the subscriptions after reconnecting
+// as the server discards them with clean sessions
+func (m *mqttBroker) resubscribe(c paho.Client) {
+	m.RLock()
+	subs := make([]*subscriber, 0, len(m.subs))
+	for s := range m.subs {
+		subs = append(subs, s)
+	}
+	m.RUnlock()
+
+	for _, s := range subs {
+		if err := wait(c.Subscribe(s.filter, s.qos, s.handler)); err != nil {
+			if logger.V(logger.ErrorLevel, logger.DefaultLogger) {
+				logger.Errorf("[mqtt] failed to resubscribe to %s: %v", s.filter, err)
+			}
+		}
+	}
+}
+
+func (m *mqttBroker) clientOptions() *paho.ClientOptions {
+	opts := paho.NewClientOptions()
+
+	for _, addr := range m.addrs {
+		if m.opts.Secure || m.opts.TLSConfig != nil {
+			addr = strings.Replace(addr, "tcp://", "ssl://", 1)
+		}
+		opts.AddBroker(addr)
+	}
+
+	if m.opts.TLSConfig != nil {
+		opts.SetTLSConfig(m.opts.TLSConfig)
+	}
+
+	id, ok := m.opts.Context.Value(clientIDKey{}).(string)
+	if !ok || len(id) == 0 {
+		// the client id is limited to 23 characters by 3.1.1 servers
+		id = strings.Replace(uuid.New().String(), "-", "", -1)[:23]
+	}
+	opts.SetClientID(id)
+
+	if c, ok := m.opts.Context.Value(authKey{}).(credentials); ok {
+		opts.SetUsername(c.username)
+		opts.SetPassword(c.password)
+	}
+	if clean, ok := m.opts.Context.Value(cleanSessionKey{}).(bool); ok {
+		opts.SetCleanSession(clean)
+	}
+	if v, ok := m.opts.Context.Value(protocolVersionKey{}).(uint); ok {
+		opts.SetProtocolVersion(v)
+	}
+	if w, ok := m.opts.Context.Value(willKey{}).(will); ok {
+		opts.SetBinaryWill(w.topic, w.payload, w.qos, w.retained)
+	}
+
+	opts.SetAutoReconnect(true)
+	opts.SetConnectTimeout(timeout)
+	opts.SetOnConnectHandler(m.resubscribe)
+
+	return opts
+}
+
+func (m *mqttBroker) setAddrs(addrs []string) []string {
+	//nolint:prealloc
+	var cAddrs []string
+	for _, addr := range addrs {
+		if len(addr) == 0 {
+			continue
+		}
+		if !strings.Contains(addr, "://") {
+			addr = "tcp://" + addr
+		}
+		cAddrs = append(cAddrs, addr)
+	}
+	if len(cAddrs) == 0 {
+		cAddrs = []string{DefaultAddress}
+	}
+	return cAddrs
+}
+
+// topicFilter returns the filter subscribed to. Subscribers with a queue
+// share a subscription which servers supporting shared subscriptions
+// deliver each message of to one of the subscribers. Topics are separated
+// by "/" in mqtt so the subscribers of topic patterns filter every topic.
+func topicFilter(topic, queue string) string {
+	if broker.IsPattern(topic) {
+		topic = "#"
+	}
+	if len(queue) == 0 {
+		return topic
+	}
+	return fmt.Sprintf("$share/%s/%s", queue, topic)
+}
+
+// wait for the token to complete
+func wait(t paho.Token) error {
+	if !t.WaitTimeout(timeout) {
+		return errors.New("timed out")
+	}
+	return t.Error()
+}
+
+// NewBroker returns a mqtt broker
+func NewBroker(opts ...broker.Option) broker.Broker {
+	options := broker.Options{
+		Codec:   json.Marshaler{},
+		Context: context.Background(),
+	}
+
+	for _, o := range opts {
+		o(&options)
+	}
+
+	m := &mqttBroker{
+		opts: options,
+		subs: make(map[*subscriber]bool),
+	}
+	m.addrs = m.setAddrs(options.Addrs)
+
+	return m
+}
@@ -0,0 +1,65 @@
+package mqtt
+
+import (
+	"testing"
+
+	"github.com/micro/go-micro/v2/broker"
+)
+
+type message struct {
+	topic   string
+	payload []byte
+}
+
+func (m *message) Duplicate() bool   { return false }
+func (m *message) Qos() byte         { return 1 }
+func (m *message) Retained() bool    { return false }
+func (m *message) Topic() string     { return m.topic }
+func (m *message) MessageID() uint16 { return 1 }
+func (m *message) Payload() []byte   { return m.payload }
+func (m *message) Ack()              {}
+
+func TestAddrs(t *testing.T) {
+	m := NewBroker(broker.Addrs("mqtt:1883", "ssl://secure:8883")).(*mqttBroker)
+	if len(m.addrs) != 2 || m.addrs[0] != "tcp://mqtt:1883" || m.addrs[1] != "ssl://secure:8883" {
+		t.Fatalf("Unexpected addrs %v", m.addrs)
+	}
+
+	if addr := NewBroker().Address(); addr != DefaultAddress {
+		t.Fatalf("Expected the default address got %s", addr)
+	}
+}
+
+func TestTopicFilter(t *testing.T) {
+	if f := topicFilter("sensors/+/temp", ""); f != "sensors/+/temp" {
+		t.Fatalf("Unexpected filter %s", f)
+	}
+	if f := topicFilter("sensors/+/temp", "workers"); f != "$share/workers/sensors/+/temp" {
+		t.Fatalf("Unexpected shared filter %s", f)
+	}
+}
+
+func TestMessage(t *testing.T) {
+	m := NewBroker().(*mqttBroker)
+
+	payload, err := m.payload(&broker.Message{Header: map[string]string{"Foo": "Bar"}, Body: []byte("hello")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg := m.message(&message{topic: "events", payload: payload})
+	if msg.Header["Foo"] != "Bar" || string(msg.Body) != "hello" {
+		t.Fatalf("Unexpected message %+v", msg)
+	}
+
+	// payloads published by devices are the body
+	msg = m.message(&message{topic: "sensors/1/temp", payload: []byte("21.5")})
+	if string(msg.Body) != "21.5" || msg.Header["Micro-Topic"] != "sensors/1/temp" {
+		t.Fatalf("Unexpected raw message %+v", msg)
+	}
+
+	m = NewBroker(RawMessages()).(*mqttBroker)
+	payload, _ = m.payload(&broker.Message{Header: map[string]string{"Foo": "Bar"}, Body: []byte("hello")})
+	if string(payload) != "hello" {
+		t.Fatalf("Expected the raw body got %s", payload)
+	}
+}
@@ -0,0 +1,118 @@
+package mqtt
+
+import (
+	"context"
+
+	"github.com/micro/go-micro/v2/broker"
+)
+
+type clientIDKey struct{}
+
+type authKey struct{}
+
+type cleanSessionKey struct{}
+
+type protocolVersionKey struct{}
+
+type willKey struct{}
+
+type qosKey struct{}
+
+type retainedKey struct{}
+
+type rawMessagesKey struct{}
+
+type credentials struct {
+	username string
+	password string
+}
+
+type will struct {
+	topic    string
+	payload  []byte
+	qos      byte
+	retained bool
+}
+
+// ClientID sets the id of the client. Defaults to a random id.
+func ClientID(id string) broker.Option {
+	return setBrokerOption(clientIDKey{}, id)
+}
+
+// Auth sets the username and password used to connect
+func Auth(username, password string) broker.Option {
+	return setBrokerOption(authKey{}, credentials{username, password})
+}
+
+// CleanSession sets whether the server discards the session of the client
+// when it disconnects. Defaults to true.
+func CleanSession(clean bool) broker.Option {
+	return setBrokerOption(cleanSessionKey{}, clean)
+}
+
+// ProtocolVersion sets the protocol version, 3 for MQTT 3.1 or 4 for
+// MQTT 3.1.1. Defaults to 3.1.1 falling back to 3.1.
+func ProtocolVersion(v uint) broker.Option {
+	return setBrokerOption(protocolVersionKey{}, v)
+}
+
+// Will sets the message the server publishes when the client
+// disconnects without disconnecting cleanly
+func Will(topic string, payload []byte, qos byte, retained bool) broker.Option {
+	return setBrokerOption(willKey{}, will{topic, payload, qos, retained})
+}
+
+// QoS sets the default quality of service of publishes and subscriptions:
+// 0 at most once, 1 at least once or 2 exactly once. Defaults to 1.
+func QoS(qos byte) broker.Option {
+	return setBrokerOption(qosKey{}, qos)
+}
+
+// RawMessages publishes the body of messages as the payload rather than the
+// encoded message so devices which don't use go-micro can read them
+func RawMessages() broker.Option {
+	return setBrokerOption(rawMessagesKey{}, true)
+}
+
+// PublishQoS sets the quality of service of the message
+func PublishQoS(qos byte) broker.PublishOption {
+	return setPublishOption(qosKey{}, qos)
+}
+
+// Retained asks the server to keep the message and deliver it to
+// subscribers of the topic when they subscribe
+func Retained() broker.PublishOption {
+	return setPublishOption(retainedKey{}, true)
+}
+
+// SubscribeQoS sets the maximum quality of service of the messages received
+func SubscribeQoS(qos byte) broker.SubscribeOption {
+	return setSubscribeOption(qosKey{}, qos)
+}
+
+func setBrokerOption(k, v interface{}) broker.Option {
+	return func(o *broker.Options) {
+		if o.Context == nil {
+			o.Context = context.Background()
+		}
+		o.Context = context.WithValue(o.Context, k, v)
+	}
+}
+
+func setSubscribeOption(k, v interface{}) broker.SubscribeOption {
+	return func(o *broker.SubscribeOptions) {
+		if o.Context == nil {
+			o.Context = context.Background()
+		}
+		o.Context = context.WithValue(o.Context, k, v)
+	}
+}
+
+func setPublishOption(k, v interface{}) broker.PublishOption {
+	return func(o *broker.PublishOptions) {
+		if o.Context == nil {
+			o.Context = context.Background()
+		}
+		o.Context = context.WithValue(o.Context, k, v)
+	}
+}
@@ -0,0 +1,72 @@
+package nats
+
+import (
+	"errors"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/micro/go-micro/v2/broker"
+	"github.com/nats-io/nats-server/v2/server"
+)
+
+type embeddedKey struct{}
+
+// Embedded runs a nats server in process which the broker connects to so
+// no external server is required e.g for development and tests. The server
+// listens on the address of the broker if set, otherwise on a random port
+// of localhost. It's started on connect and shut down on disconnect.
+func Embedded() broker.Option {
+	return setBrokerOption(embeddedKey{}, true)
+}
+
+func (n *natsBroker) embedded() bool {
+	e, _ := n.opts.Context.Value(embeddedKey{}).(bool)
+	return e
+}
+
+// startServer starts the embedded server and sets the address of the broker
+func (n *natsBroker) startServer() error {
+	opts := &server.Options{
+		Host:   "127.0.0.1",
+		Port:   server.RANDOM_PORT,
+		NoLog:  true,
+		NoSigs: true,
+	}
+
+	if len(n.opts.Addrs) > 0 {
+		host, port, err := net.SplitHostPort(strings.TrimPrefix(n.opts.Addrs[0], "nats://"))
+		if err != nil {
+			return err
+		}
+		p, err := strconv.Atoi(port)
+		if err != nil {
+			return err
+		}
+		opts.Host = host
+		opts.Port = p
+	}
+
+	s, err := server.NewServer(opts)
+	if err != nil {
+		return err
+	}
+
+	go s.Start()
+
+	if !s.ReadyForConnections(10 * time.Second) {
+		s.Shutdown()
+		return errors.New("embedded nats server failed to start")
+	}
+
+	n.server = s
+	n.addrs = []string{s.ClientURL()}
+
+	return nil
+}
+
+// NewEmbeddedBroker returns a nats broker running an embedded server
+func NewEmbeddedBroker(opts ...broker.Option) broker.Broker {
+	return NewBroker(append([]broker.Option{Embedded()}, opts...)...)
+}
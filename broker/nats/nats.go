@@ -11,6 +11,7 @@ import (
 	"github.com/micro/go-micro/v2/codec/json"
 	"github.com/micro/go-micro/v2/logger"
 	"github.com/micro/go-micro/v2/registry"
+	"github.com/nats-io/nats-server/v2/server"
 	nats "github.com/nats-io/nats.go"
 )
 
@@ -26,6 +27,9 @@ type natsBroker struct {
 	opts  broker.Options
 	nopts nats.Options
 
+	// the embedded server
+	server *server.Server
+
 	// should we drain the connection
 	drain   bool
 	closeCh chan (error)
@@ -33,6 +37,8 @@ type natsBroker struct {
 
 type subscriber struct {
 	s    *nats.Subscription
+	rd   *broker.Redeliverer
+	d    *broker.Dispatcher
 	opts broker.SubscribeOptions
 }
 
@@ -55,6 +61,15 @@ func (p *publication) Ack() error {
 	return nil
 }
 
+// Nack and Requeue are handled by the redeliverer of the subscriber
+func (p *publication) Nack() error {
+	return nil
+}
+
+func (p *publication) Requeue() error {
+	return nil
+}
+
 func (p *publication) Error() error {
 	return p.err
 }
@@ -68,6 +83,8 @@ func (s *subscriber) Topic() string {
 }
 
 func (s *subscriber) Unsubscribe() error {
+	s.rd.Stop()
+	s.d.Stop()
 	return s.s.Unsubscribe()
 }
 
@@ -119,6 +136,12 @@ func (n *natsBroker) Connect() error {
 		n.connected = true
 		return nil
 	default: // DISCONNECTED or CLOSED or DRAINING
+		if n.embedded() && n.server == nil {
+			if err := n.startServer(); err != nil {
+				return err
+			}
+		}
+
 		opts := n.nopts
 		opts.Servers = n.addrs
 		opts.Secure = n.opts.Secure
@@ -155,6 +178,12 @@ func (n *natsBroker) Disconnect() error {
 	// set not connected
 	n.connected = false
 
+	// shut down the embedded server
+	if n.server != nil {
+		n.server.Shutdown()
+		n.server = nil
+	}
+
 	return nil
 }
 
@@ -168,6 +197,16 @@ func (n *natsBroker) Options() broker.Options {
 }
 
 func (n *natsBroker) Publish(topic string, msg *broker.Message, opts ...broker.PublishOption) error {
+	// messages delivered later are published by the scheduler
+	if broker.Deferred(n, topic, msg, opts...) {
+		return nil
+	}
+
+	msg, err := broker.EncodeMessage(msg, opts...)
+	if err != nil {
+		return err
+	}
+
 	n.RLock()
 	defer n.RUnlock()
 
@@ -199,7 +238,25 @@ func (n *natsBroker) Subscribe(topic string, handler broker.Handler, opts ...bro
 		o(&opt)
 	}
 
+	// handlers running longer than the timeout fail
+	handler = broker.TimeoutHandler(handler, opt)
+	// messages the handler keeps failing on are dead lettered
+	handler = broker.DeadLetterHandler(n, broker.DecompressHandler(handler), opt)
+	// messages with the same partition key are handled in order
+	handler = broker.OrderedHandler(handler, opt)
+
+	// nats can't redeliver messages so nacked
+	// and requeued messages are redelivered from memory
+	rd := broker.NewRedeliverer(handler, opt)
+	// messages are handled with the concurrency of the subscription
+	d := broker.NewDispatcher(opt)
+
 	fn := func(msg *nats.Msg) {
+		// the subject subscribed to may match more than the pattern
+		if broker.IsPattern(topic) && !broker.MatchTopic(topic, msg.Subject) {
+			return
+		}
+
 		var m broker.Message
 		pub := &publication{t: msg.Subject}
 		eh := n.opts.ErrorHandler
@@ -216,15 +273,17 @@ func (n *natsBroker) Subscribe(topic string, handler broker.Handler, opts ...bro
 			}
 			return
 		}
-		if err := handler(pub); err != nil {
-			pub.err = err
-			if logger.V(logger.ErrorLevel, logger.DefaultLogger) {
-				logger.Error(err)
+		d.Dispatch(m.Header[broker.HeaderPartitionKey], func() {
+			if err := rd.Handle(pub); err != nil {
+				pub.err = err
+				if logger.V(logger.ErrorLevel, logger.DefaultLogger) {
+					logger.Error(err)
+				}
+				if eh != nil {
+					eh(pub)
+				}
 			}
-			if eh != nil {
-				eh(pub)
-			}
-		}
+		})
 	}
 
 	var sub *nats.Subscription
@@ -232,15 +291,17 @@ func (n *natsBroker) Subscribe(topic string, handler broker.Handler, opts ...bro
 
 	n.RLock()
 	if len(opt.Queue) > 0 {
-		sub, err = n.conn.QueueSubscribe(topic, opt.Queue, fn)
+		sub, err = n.conn.QueueSubscribe(subject(topic), opt.Queue, fn)
 	} else {
-		sub, err = n.conn.Subscribe(topic, fn)
+		sub, err = n.conn.Subscribe(subject(topic), fn)
 	}
 	n.RUnlock()
 	if err != nil {
+		rd.Stop()
+		d.Stop()
 		return nil, err
 	}
-	return &subscriber{s: sub, opts: opt}, nil
+	return &subscriber{s: sub, rd: rd, d: d, opts: opt}, nil
 }
 
 func (n *natsBroker) String() string {
@@ -316,3 +377,16 @@ func NewBroker(opts ...broker.Option) broker.Broker {
 
 	return n
 }
+
+// subject returns the nats subject of the topic. The "*" wildcard is native and
+// "#" maps to ">" which matches one or more words. Patterns with "#" before
+// the last word are subscribed to from there and filtered by the subscriber.
+func subject(topic string) string {
+	words := strings.Split(topic, ".")
+	for i, w := range words {
+		if w == "#" {
+			return strings.Join(append(words[:i], ">"), ".")
+		}
+	}
+	return topic
+}
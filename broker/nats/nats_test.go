@@ -3,6 +3,7 @@ package nats
 import (
 	"fmt"
 	"testing"
+	"time"
 
 	"github.com/micro/go-micro/v2/broker"
 	nats "github.com/nats-io/nats.go"
@@ -96,3 +97,51 @@ func TestInitAddrs(t *testing.T) {
 		})
 	}
 }
+
+func TestSubject(t *testing.T) {
+	testData := map[string]string{
+		"orders.created": "orders.created",
+		"orders.*":       "orders.*",
+		"audit.#":        "audit.>",
+		"audit.#.failed": "audit.>",
+		"#":              ">",
+	}
+
+	for topic, expect := range testData {
+		if s := subject(topic); s != expect {
+			t.Fatalf("Expected subject %s for %s got %s", expect, topic, s)
+		}
+	}
+}
+
+func TestEmbedded(t *testing.T) {
+	b := NewBroker(Embedded())
+	if err := b.Connect(); err != nil {
+		t.Fatalf("Unexpected connect error %v", err)
+	}
+	defer b.Disconnect()
+
+	received := make(chan *broker.Message, 1)
+	if _, err := b.Subscribe("test", func(e broker.Event) error {
+		received <- e.Message()
+		return nil
+	}); err != nil {
+		t.Fatalf("Unexpected error subscribing %v", err)
+	}
+
+	if err := b.Publish("test", &broker.Message{
+		Header: map[string]string{"foo": "bar"},
+		Body:   []byte("hello"),
+	}); err != nil {
+		t.Fatalf("Unexpected error publishing %v", err)
+	}
+
+	select {
+	case m := <-received:
+		if string(m.Body) != "hello" || m.Header["foo"] != "bar" {
+			t.Fatalf("Unexpected message %v", m)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected the message to be received")
+	}
+}
@@ -3,6 +3,7 @@ package broker
 import (
 	"context"
 	"crypto/tls"
+	"time"
 
 	"github.com/micro/go-micro/v2/codec"
 	"github.com/micro/go-micro/v2/registry"
@@ -26,6 +27,17 @@ type Options struct {
 }
 
 type PublishOptions struct {
+	// DeliverAt is the time the message is delivered
+	// at. It's delivered immediately if not set.
+	DeliverAt time.Time
+	// Compression is the content encoding of the
+	// message body e.g gzip, snappy or zstd
+	Compression string
+	// Key orders the message after the messages
+	// published with the same key. It's used as
+	// the partition key by partitioned brokers.
+	Key string
+
 	// Other options for implementations of the interface
 	// can be stored in a context
 	Context context.Context
@@ -39,6 +51,27 @@ type SubscribeOptions struct {
 	// will create a shared subscription where each
 	// receives a subset of messages.
 	Queue string
+	// DeadLetterTopic receives the messages the handler
+	// failed to process MaxAttempts times.
+	DeadLetterTopic string
+	// MaxAttempts is the number of times the handler is
+	// invoked before a message is dead lettered.
+	MaxAttempts int
+	// Redelivery policy of nacked messages. Messages the
+	// handler returns an error for are nacked if it's set.
+	Redelivery *RedeliveryPolicy
+	// Ordered handles the messages with the same
+	// partition key one at a time in order.
+	Ordered bool
+	// Concurrency is the number of messages handled
+	// at the same time. Defaults to one at a time.
+	Concurrency int
+	// MaxInFlight is the max number of messages
+	// received and not yet handled or acked.
+	MaxInFlight int
+	// Timeout of the handling of a message. The
+	// handler fails with ErrHandlerTimeout if set.
+	Timeout time.Duration
 
 	// Other options for implementations of the interface
 	// can be stored in a context
@@ -85,6 +118,59 @@ func Codec(c codec.Marshaler) Option {
 	}
 }
 
+// DeadLetter publishes the messages the handler failed to process
+// after the number of attempts to the dead letter topic
+func DeadLetter(topic string, attempts int) SubscribeOption {
+	return func(o *SubscribeOptions) {
+		o.DeadLetterTopic = topic
+		o.MaxAttempts = attempts
+	}
+}
+
+// Ordered handles the messages with the same partition key one at a time in the
+// order they're received. Messages without a key aren't ordered.
+func Ordered() SubscribeOption {
+	return func(o *SubscribeOptions) {
+		o.Ordered = true
+	}
+}
+
+// Concurrency is the number of messages handled at the same time
+func Concurrency(n int) SubscribeOption {
+	return func(o *SubscribeOptions) {
+		o.Concurrency = n
+	}
+}
+
+// MaxInFlight limits the messages received and not yet handled or acked. It's
+// the prefetch count of brokers which prefetch messages.
+func MaxInFlight(n int) SubscribeOption {
+	return func(o *SubscribeOptions) {
+		o.MaxInFlight = n
+	}
+}
+
+// HandlerTimeout fails the handling of a message after the duration
+func HandlerTimeout(d time.Duration) SubscribeOption {
+	return func(o *SubscribeOptions) {
+		o.Timeout = d
+	}
+}
+
+// PartitionKey orders the message after the messages published with the key
+func PartitionKey(key string) PublishOption {
+	return func(o *PublishOptions) {
+		o.Key = key
+	}
+}
+
+// Delay delivers the message after the duration
+func Delay(d time.Duration) PublishOption {
+	return func(o *PublishOptions) {
+		o.DeliverAt = time.Now().Add(d)
+	}
+}
+
 // DisableAutoAck will disable auto acking of messages
 // after they have been handled.
 func DisableAutoAck() SubscribeOption {
@@ -108,12 +194,35 @@ func Queue(name string) SubscribeOption {
 	}
 }
 
+// Redelivery sets the policy used to redeliver nacked messages. Messages the
+// handler returns an error for are nacked rather than dropped.
+func Redelivery(p RedeliveryPolicy) SubscribeOption {
+	return func(o *SubscribeOptions) {
+		o.Redelivery = &p
+	}
+}
+
 func Registry(r registry.Registry) Option {
 	return func(o *Options) {
 		o.Registry = r
 	}
 }
 
+// Schedule delivers the message at the time
+func Schedule(t time.Time) PublishOption {
+	return func(o *PublishOptions) {
+		o.DeliverAt = t
+	}
+}
+
+// Compress the message body with the content encoding e.g gzip, snappy
+// or zstd. Subscribers decompress messages before they're handled.
+func Compress(encoding string) PublishOption {
+	return func(o *PublishOptions) {
+		o.Compression = encoding
+	}
+}
+
 // Secure communication with the broker
 func Secure(b bool) Option {
 	return func(o *Options) {
@@ -0,0 +1,55 @@
+package broker
+
+import "sync"
+
+var (
+	// HeaderPartitionKey is the partition key of the message
+	HeaderPartitionKey = "Micro-Partition-Key"
+)
+
+// OrderedHandler handles the messages with the same partition key one at a
+// time in the order they're received if the subscription is ordered. Every key
+// has a queue of the messages waiting for the one before them to be handled.
+// Brokers wrap subscription handlers with it.
+func OrderedHandler(h Handler, opts SubscribeOptions) Handler {
+	if !opts.Ordered {
+		return h
+	}
+
+	var mtx sync.Mutex
+	// the last message queued by key, closed once it's handled
+	last := make(map[string]chan bool)
+
+	return func(e Event) error {
+		var key string
+		if m := e.Message(); m != nil {
+			key = m.Header[HeaderPartitionKey]
+		}
+		if len(key) == 0 {
+			return h(e)
+		}
+
+		done := make(chan bool)
+
+		mtx.Lock()
+		prev := last[key]
+		last[key] = done
+		mtx.Unlock()
+
+		// wait for the previous message of the key
+		if prev != nil {
+			<-prev
+		}
+
+		defer func() {
+			mtx.Lock()
+			if last[key] == done {
+				delete(last, key)
+			}
+			mtx.Unlock()
+			close(done)
+		}()
+
+		return h(e)
+	}
+}
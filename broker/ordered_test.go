@@ -0,0 +1,74 @@
+package broker
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+type testEvent struct {
+	message *Message
+}
+
+func (e *testEvent) Topic() string     { return "test" }
+func (e *testEvent) Message() *Message { return e.message }
+func (e *testEvent) Ack() error        { return nil }
+func (e *testEvent) Nack() error       { return nil }
+func (e *testEvent) Requeue() error    { return nil }
+func (e *testEvent) Error() error      { return nil }
+
+func TestOrderedHandler(t *testing.T) {
+	var mtx sync.Mutex
+	var handled []string
+
+	started := make(chan bool)
+	release := make(chan bool)
+
+	h := OrderedHandler(func(e Event) error {
+		id := e.Message().Header["id"]
+		if id == "a1" {
+			started <- true
+			<-release
+		}
+		mtx.Lock()
+		handled = append(handled, id)
+		mtx.Unlock()
+		return nil
+	}, SubscribeOptions{Ordered: true})
+
+	deliver := func(id, key string) *testEvent {
+		return &testEvent{message: &Message{
+			Header: map[string]string{"id": id, HeaderPartitionKey: key},
+		}}
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		h(deliver("a1", "a"))
+	}()
+	<-started
+
+	go func() {
+		defer wg.Done()
+		h(deliver("a2", "a"))
+	}()
+
+	// messages of other keys aren't blocked
+	h(deliver("b1", "b"))
+	time.Sleep(10 * time.Millisecond)
+
+	mtx.Lock()
+	if len(handled) != 1 || handled[0] != "b1" {
+		t.Fatalf("Expected only b1 handled got %v", handled)
+	}
+	mtx.Unlock()
+
+	close(release)
+	wg.Wait()
+
+	if len(handled) != 3 || handled[1] != "a1" || handled[2] != "a2" {
+		t.Fatalf("Expected the messages of key a in order got %v", handled)
+	}
+}
@@ -0,0 +1,81 @@
+package outbox
+
+import (
+	"time"
+
+	"github.com/micro/go-micro/v2/broker"
+	"github.com/micro/go-micro/v2/store"
+	"github.com/micro/go-micro/v2/sync"
+)
+
+type Options struct {
+	// Store the messages are written to
+	Store store.Store
+	// Broker the messages are relayed to
+	Broker broker.Broker
+	// Sync is used to lock the relay when the outbox is
+	// shared by more than one instance of a service
+	Sync sync.Sync
+	// Database and Table of the outbox records,
+	// the defaults of the store are used if blank
+	Database, Table string
+	// Prefix of the keys of the outbox records
+	Prefix string
+	// Interval between relays
+	Interval time.Duration
+	// BatchSize is the max number of messages per relay
+	BatchSize int
+}
+
+type Option func(o *Options)
+
+// Store the messages are written to
+func Store(s store.Store) Option {
+	return func(o *Options) {
+		o.Store = s
+	}
+}
+
+// Broker the messages are relayed to
+func Broker(b broker.Broker) Option {
+	return func(o *Options) {
+		o.Broker = b
+	}
+}
+
+// Sync locks the relay so one instance relays the messages at a time
+func Sync(s sync.Sync) Option {
+	return func(o *Options) {
+		o.Sync = s
+	}
+}
+
+// Table of the outbox records. The state of the service should be written to
+// the same table for stores which don't support transactions across tables.
+func Table(database, table string) Option {
+	return func(o *Options) {
+		o.Database = database
+		o.Table = table
+	}
+}
+
+// Prefix of the keys of the outbox records
+func Prefix(p string) Option {
+	return func(o *Options) {
+		o.Prefix = p
+	}
+}
+
+// Interval between relays
+func Interval(d time.Duration) Option {
+	return func(o *Options) {
+		o.Interval = d
+	}
+}
+
+// BatchSize is the max number of messages per relay
+func BatchSize(n int) Option {
+	return func(o *Options) {
+		o.BatchSize = n
+	}
+}
@@ -0,0 +1,219 @@
+// Package outbox is a transactional outbox for publishing messages. Messages are
+// written to the store in the same transaction as the state they relate to and
+// relayed to the broker asynchronously.
+package outbox
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/micro/go-micro/v2/broker"
+	"github.com/micro/go-micro/v2/logger"
+	"github.com/micro/go-micro/v2/store"
+)
+
+var (
+	// DefaultPrefix of the keys of outbox records
+	DefaultPrefix = "outbox/"
+	// DefaultInterval between relays
+	DefaultInterval = time.Second
+	// DefaultBatchSize is the max number of messages per relay
+	DefaultBatchSize = 100
+
+	// HeaderID is set to the key of the outbox record on relayed
+	// messages so subscribers can discard duplicates
+	HeaderID = "Micro-Outbox-Id"
+)
+
+// Outbox writes messages to the store within a transaction and relays them to
+// the broker. A record is deleted once its message is published so every
+// record is relayed once, unless the relay fails between the publish and the
+// delete in which case it's published again with the same HeaderID.
+type Outbox interface {
+	// Publish writes the message to the outbox within the transaction.
+	// It's relayed to the broker once the transaction is committed.
+	Publish(tx store.Tx, topic string, msg *broker.Message) error
+	// Relay publishes the pending messages in the order they were written
+	Relay() error
+	// Start relaying messages on an interval
+	Start() error
+	// Stop relaying messages
+	Stop() error
+	String() string
+}
+
+// record is the value of an outbox record
+type record struct {
+	Topic  string            `json:"topic"`
+	Header map[string]string `json:"header"`
+	Body   []byte            `json:"body"`
+}
+
+type outbox struct {
+	opts Options
+
+	// relays are serialised
+	relaying sync.Mutex
+
+	sync.Mutex
+	running bool
+	exit    chan bool
+}
+
+func (o *outbox) key() string {
+	// keys are ordered by the time they're written
+	return fmt.Sprintf("%s%020d-%s", o.opts.Prefix, time.Now().UnixNano(), uuid.New().String())
+}
+
+func (o *outbox) Publish(tx store.Tx, topic string, msg *broker.Message) error {
+	b, err := json.Marshal(&record{
+		Topic:  topic,
+		Header: msg.Header,
+		Body:   msg.Body,
+	})
+	if err != nil {
+		return err
+	}
+
+	return tx.Write(&store.Record{
+		Key:   o.key(),
+		Value: b,
+	}, store.WriteTo(o.opts.Database, o.opts.Table))
+}
+
+func (o *outbox) Relay() error {
+	o.relaying.Lock()
+	defer o.relaying.Unlock()
+
+	// lock the outbox of other instances
+	if o.opts.Sync != nil {
+		id := "outbox:" + o.opts.Database + ":" + o.opts.Table
+		if err := o.opts.Sync.Lock(id); err != nil {
+			return err
+		}
+		defer o.opts.Sync.Unlock(id)
+	}
+
+	keys, err := o.opts.Store.List(
+		store.ListFrom(o.opts.Database, o.opts.Table),
+		store.ListPrefix(o.opts.Prefix),
+	)
+	if err != nil {
+		return err
+	}
+	sort.Strings(keys)
+
+	if o.opts.BatchSize > 0 && len(keys) > o.opts.BatchSize {
+		keys = keys[:o.opts.BatchSize]
+	}
+
+	for _, k := range keys {
+		recs, err := o.opts.Store.Read(k, store.ReadFrom(o.opts.Database, o.opts.Table))
+		if err == store.ErrNotFound {
+			continue
+		} else if err != nil {
+			return err
+		}
+
+		var r record
+		if err := json.Unmarshal(recs[0].Value, &r); err != nil {
+			// the record can never be relayed
+			logger.Errorf("Discarding outbox record %s: %v", k, err)
+			o.opts.Store.Delete(k, store.DeleteFrom(o.opts.Database, o.opts.Table))
+			continue
+		}
+
+		header := make(map[string]string, len(r.Header)+1)
+		for hk, hv := range r.Header {
+			header[hk] = hv
+		}
+		header[HeaderID] = k
+
+		// stop on the first error to keep the messages ordered
+		if err := o.opts.Broker.Publish(r.Topic, &broker.Message{
+			Header: header,
+			Body:   r.Body,
+		}); err != nil {
+			return err
+		}
+
+		if err := o.opts.Store.Delete(k, store.DeleteFrom(o.opts.Database, o.opts.Table)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (o *outbox) run(exit chan bool) {
+	t := time.NewTicker(o.opts.Interval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-exit:
+			return
+		case <-t.C:
+			if err := o.Relay(); err != nil {
+				logger.Errorf("Error relaying outbox: %v", err)
+			}
+		}
+	}
+}
+
+func (o *outbox) Start() error {
+	o.Lock()
+	defer o.Unlock()
+
+	if o.running {
+		return nil
+	}
+
+	o.exit = make(chan bool)
+	o.running = true
+	go o.run(o.exit)
+
+	return nil
+}
+
+func (o *outbox) Stop() error {
+	o.Lock()
+	defer o.Unlock()
+
+	if !o.running {
+		return nil
+	}
+
+	close(o.exit)
+	o.running = false
+
+	return nil
+}
+
+func (o *outbox) String() string {
+	return "outbox"
+}
+
+// NewOutbox returns an outbox writing messages to the store and relaying them
+// to the broker. The store must implement store.Transactional.
+func NewOutbox(opts ...Option) Outbox {
+	options := Options{
+		Store:     store.DefaultStore,
+		Broker:    broker.DefaultBroker,
+		Prefix:    DefaultPrefix,
+		Interval:  DefaultInterval,
+		BatchSize: DefaultBatchSize,
+	}
+
+	for _, o := range opts {
+		o(&options)
+	}
+
+	return &outbox{
+		opts: options,
+	}
+}
@@ -0,0 +1,83 @@
+package outbox
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/micro/go-micro/v2/broker"
+	"github.com/micro/go-micro/v2/broker/memory"
+	"github.com/micro/go-micro/v2/store"
+	mstore "github.com/micro/go-micro/v2/store/memory"
+)
+
+func TestOutbox(t *testing.T) {
+	b := memory.NewBroker()
+	if err := b.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	defer b.Disconnect()
+
+	s := mstore.NewStore()
+	o := NewOutbox(Store(s), Broker(b))
+
+	var received []*broker.Message
+	if _, err := b.Subscribe("test", func(e broker.Event) error {
+		received = append(received, e.Message())
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	// messages of rolled back transactions are discarded
+	err := store.Update(s, func(tx store.Tx) error {
+		tx.Write(&store.Record{Key: "foo", Value: []byte("bar")})
+		o.Publish(tx, "test", &broker.Message{Body: []byte("rolled back")})
+		return errors.New("failed")
+	})
+	if err == nil {
+		t.Fatal("Expected the update error")
+	}
+	if err := o.Relay(); err != nil {
+		t.Fatal(err)
+	}
+	if len(received) != 0 {
+		t.Fatalf("Expected no messages got %d", len(received))
+	}
+
+	for _, body := range []string{"1", "2"} {
+		if err := store.Update(s, func(tx store.Tx) error {
+			if err := tx.Write(&store.Record{Key: "foo", Value: []byte(body)}); err != nil {
+				return err
+			}
+			return o.Publish(tx, "test", &broker.Message{
+				Header: map[string]string{"foo": "bar"},
+				Body:   []byte(body),
+			})
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := o.Relay(); err != nil {
+		t.Fatal(err)
+	}
+	if len(received) != 2 {
+		t.Fatalf("Expected 2 messages got %d", len(received))
+	}
+	for i, m := range received {
+		if string(m.Body) != []string{"1", "2"}[i] {
+			t.Fatalf("Expected the messages in order got %s at %d", m.Body, i)
+		}
+		if m.Header["foo"] != "bar" || len(m.Header[HeaderID]) == 0 {
+			t.Fatalf("Unexpected header %v", m.Header)
+		}
+	}
+
+	// every record is relayed once
+	if err := o.Relay(); err != nil {
+		t.Fatal(err)
+	}
+	if len(received) != 2 {
+		t.Fatalf("Expected the messages to be relayed once got %d", len(received))
+	}
+}
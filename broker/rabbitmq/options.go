@@ -0,0 +1,133 @@
+package rabbitmq
+
+import (
+	"context"
+
+	"github.com/micro/go-micro/v2/broker"
+	"github.com/streadway/amqp"
+)
+
+type exchangeKey struct{}
+
+type exchangeTypeKey struct{}
+
+type durableExchangeKey struct{}
+
+type delayedExchangeKey struct{}
+
+type prefetchCountKey struct{}
+
+type prefetchGlobalKey struct{}
+
+type durableQueueKey struct{}
+
+type queueArgumentsKey struct{}
+
+type deadLetterExchangeKey struct{}
+
+type deadLetterRoutingKeyKey struct{}
+
+type requeueOnErrorKey struct{}
+
+type deliveryModeKey struct{}
+
+type priorityKey struct{}
+
+// Exchange sets the name of the exchange messages are published to
+func Exchange(name string) broker.Option {
+	return setBrokerOption(exchangeKey{}, name)
+}
+
+// ExchangeType sets the type of the exchange: direct, fanout, topic or headers.
+// Defaults to topic.
+func ExchangeType(kind string) broker.Option {
+	return setBrokerOption(exchangeTypeKey{}, kind)
+}
+
+// DurableExchange declares the exchange as durable so it survives a server restart
+func DurableExchange() broker.Option {
+	return setBrokerOption(durableExchangeKey{}, true)
+}
+
+// DelayedExchange declares the exchange as a delayed message exchange so messages
+// published with a delay are delayed by the server. It requires the
+// rabbitmq_delayed_message_exchange plugin. Otherwise delayed messages are kept
+// in memory by the publisher until due.
+func DelayedExchange() broker.Option {
+	return setBrokerOption(delayedExchangeKey{}, true)
+}
+
+// PrefetchCount sets how many unacked messages are delivered to a subscriber
+func PrefetchCount(n int) broker.Option {
+	return setBrokerOption(prefetchCountKey{}, n)
+}
+
+// PrefetchGlobal applies the prefetch count to the connection rather than each subscriber
+func PrefetchGlobal() broker.Option {
+	return setBrokerOption(prefetchGlobalKey{}, true)
+}
+
+// DurableQueue declares the queue of the subscriber as durable
+func DurableQueue() broker.SubscribeOption {
+	return setSubscribeOption(durableQueueKey{}, true)
+}
+
+// QueueArguments sets the arguments used to declare the queue e.g x-max-length
+func QueueArguments(args amqp.Table) broker.SubscribeOption {
+	return setSubscribeOption(queueArgumentsKey{}, args)
+}
+
+// DeadLetterExchange routes rejected and expired messages to the exchange
+func DeadLetterExchange(name string) broker.SubscribeOption {
+	return setSubscribeOption(deadLetterExchangeKey{}, name)
+}
+
+// DeadLetterRoutingKey sets the routing key of dead lettered messages.
+// Defaults to the routing key of the message.
+func DeadLetterRoutingKey(key string) broker.SubscribeOption {
+	return setSubscribeOption(deadLetterRoutingKeyKey{}, key)
+}
+
+// RequeueOnError requeues messages when the handler returns an error
+// rather than rejecting them to the dead letter exchange
+func RequeueOnError() broker.SubscribeOption {
+	return setSubscribeOption(requeueOnErrorKey{}, true)
+}
+
+// DeliveryMode sets the delivery mode of the message, amqp.Persistent
+// or amqp.Transient
+func DeliveryMode(mode uint8) broker.PublishOption {
+	return setPublishOption(deliveryModeKey{}, mode)
+}
+
+// Priority sets the priority of the message
+func Priority(p uint8) broker.PublishOption {
+	return setPublishOption(priorityKey{}, p)
+}
+
+func setBrokerOption(k, v interface{}) broker.Option {
+	return func(o *broker.Options) {
+		if o.Context == nil {
+			o.Context = context.Background()
+		}
+		o.Context = context.WithValue(o.Context, k, v)
+	}
+}
+
+func setSubscribeOption(k, v interface{}) broker.SubscribeOption {
+	return func(o *broker.SubscribeOptions) {
+		if o.Context == nil {
+			o.Context = context.Background()
+		}
+		o.Context = context.WithValue(o.Context, k, v)
+	}
+}
+
+func setPublishOption(k, v interface{}) broker.PublishOption {
+	return func(o *broker.PublishOptions) {
+		if o.Context == nil {
+			o.Context = context.Background()
+		}
+		o.Context = context.WithValue(o.Context, k, v)
+	}
+}
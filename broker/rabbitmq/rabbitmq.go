@@ -0,0 +1,420 @@
+// Package rabbitmq provides a RabbitMQ broker
+package rabbitmq
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/micro/go-micro/v2/broker"
+	"github.com/micro/go-micro/v2/codec/json"
+	"github.com/micro/go-micro/v2/logger"
+	"github.com/streadway/amqp"
+)
+
+type rbroker struct {
+	sync.RWMutex
+	conn  *rabbitMQConn
+	addrs []string
+	opts  broker.Options
+}
+
+type subscriber struct {
+	topic      string
+	opts       broker.SubscribeOptions
+	dispatcher *broker.Dispatcher
+
+	sync.Mutex
+	ch     *amqp.Channel
+	closed bool
+	done   chan struct{}
+}
+
+type publication struct {
+	d   amqp.Delivery
+	m   *broker.Message
+	t   string
+	err error
+}
+
+func (p *publication) Ack() error {
+	return p.d.Ack(false)
+}
+
+// Nack rejects the message which is routed to the
+// dead letter exchange of the queue if it has one
+func (p *publication) Nack() error {
+	return p.d.Nack(false, false)
+}
+
+// Requeue rejects the message which is requeued
+func (p *publication) Requeue() error {
+	return p.d.Nack(false, true)
+}
+
+func (p *publication) Error() error {
+	return p.err
+}
+
+func (p *publication) Topic() string {
+	return p.t
+}
+
+func (p *publication) Message() *broker.Message {
+	return p.m
+}
+
+func (s *subscriber) Options() broker.SubscribeOptions {
+	return s.opts
+}
+
+func (s *subscriber) Topic() string {
+	return s.topic
+}
+
+func (s *subscriber) Unsubscribe() error {
+	s.Lock()
+	if s.closed {
+		s.Unlock()
+		return nil
+	}
+	s.closed = true
+	close(s.done)
+	ch := s.ch
+	s.Unlock()
+
+	// unacked messages are requeued when the channel is closed
+	s.dispatcher.Stop()
+
+	if ch != nil {
+		return ch.Close()
+	}
+	return nil
+}
+
+func (s *subscriber) isClosed() bool {
+	s.Lock()
+	defer s.Unlock()
+	return s.closed
+}
+
+func (r *rbroker) Address() string {
+	if len(r.addrs) > 0 {
+		return r.addrs[0]
+	}
+	return DefaultURL
+}
+
+func (r *rbroker) Connect() error {
+	r.Lock()
+	defer r.Unlock()
+
+	if r.conn == nil {
+		r.conn = r.newConn()
+	}
+
+	return r.conn.Connect(r.opts.TLSConfig)
+}
+
+func (r *rbroker) Disconnect() error {
+	r.Lock()
+	defer r.Unlock()
+
+	if r.conn == nil {
+		return nil
+	}
+
+	return r.conn.Close()
+}
+
+func (r *rbroker) Init(opts ...broker.Option) error {
+	r.Lock()
+	defer r.Unlock()
+
+	for _, o := range opts {
+		o(&r.opts)
+	}
+	r.addrs = r.setAddrs(r.opts.Addrs)
+
+	// recreate the connection with the new options on connect
+	if r.conn != nil && !r.conn.isConnected() {
+		r.conn = nil
+	}
+
+	return nil
+}
+
+func (r *rbroker) Options() broker.Options {
+	return r.opts
+}
+
+func (r *rbroker) Publish(topic string, msg *broker.Message, opts ...broker.PublishOption) error {
+	// messages are delayed by the server with a delayed message exchange
+	if !r.delayed() && broker.Deferred(r, topic, msg, opts...) {
+		return nil
+	}
+
+	msg, err := broker.EncodeMessage(msg, opts...)
+	if err != nil {
+		return err
+	}
+
+	r.RLock()
+	conn := r.conn
+	r.RUnlock()
+
+	if conn == nil {
+		return errNotConnected
+	}
+
+	options := broker.PublishOptions{
+		Context: context.Background(),
+	}
+	for _, o := range opts {
+		o(&options)
+	}
+
+	m := amqp.Publishing{
+		Body:    msg.Body,
+		Headers: amqp.Table{},
+	}
+	for k, v := range msg.Header {
+		m.Headers[k] = v
+	}
+	if ct, ok := msg.Header["Content-Type"]; ok {
+		m.ContentType = ct
+	}
+	if mode, ok := options.Context.Value(deliveryModeKey{}).(uint8); ok {
+		m.DeliveryMode = mode
+	}
+	if p, ok := options.Context.Value(priorityKey{}).(uint8); ok {
+		m.Priority = p
+	}
+	if d := time.Until(options.DeliverAt); d > 0 {
+		m.Headers["x-delay"] = d.Milliseconds()
+	}
+
+	return conn.Publish(topic, m)
+}
+
+func (r *rbroker) Subscribe(topic string, handler broker.Handler, opts ...broker.SubscribeOption) (broker.Subscriber, error) {
+	r.RLock()
+	conn := r.conn
+	r.RUnlock()
+
+	if conn == nil {
+		return nil, errNotConnected
+	}
+
+	opt := broker.SubscribeOptions{
+		AutoAck: true,
+		Context: context.Background(),
+	}
+	for _, o := range opts {
+		o(&opt)
+	}
+
+	// handlers running longer than the timeout fail
+	handler = broker.TimeoutHandler(handler, opt)
+	// messages the handler keeps failing on are dead lettered
+	handler = broker.DeadLetterHandler(r, broker.DecompressHandler(handler), opt)
+	// messages with the same partition key are handled in order
+	handler = broker.OrderedHandler(handler, opt)
+
+	durable, _ := opt.Context.Value(durableQueueKey{}).(bool)
+	requeue, _ := opt.Context.Value(requeueOnErrorKey{}).(bool)
+	args := queueArguments(opt)
+
+	sub := &subscriber{
+		topic:      topic,
+		opts:       opt,
+		dispatcher: broker.NewDispatcher(opt),
+		done:       make(chan struct{}),
+	}
+
+	fn := func(d amqp.Delivery) {
+		header := make(map[string]string, len(d.Headers))
+		for k, v := range d.Headers {
+			header[k] = fmt.Sprintf("%v", v)
+		}
+		p := &publication{
+			d: d,
+			t: d.RoutingKey,
+			m: &broker.Message{Header: header, Body: d.Body},
+		}
+
+		p.err = handler(p)
+		if p.err == nil && opt.AutoAck {
+			p.Ack()
+		} else if p.err != nil {
+			if logger.V(logger.ErrorLevel, logger.DefaultLogger) {
+				logger.Errorf("[rabbitmq] subscriber error: %v", p.err)
+			}
+			if r.opts.ErrorHandler != nil {
+				r.opts.ErrorHandler(p)
+			}
+			if opt.AutoAck && requeue {
+				p.Requeue()
+			} else if opt.AutoAck {
+				p.Nack()
+			}
+		}
+	}
+
+	// declare the queue before returning so messages
+	// published after subscribing aren't missed
+	ch, deliveries, err := conn.Consume(opt.Queue, topic, durable, opt.MaxInFlight, args)
+	if err != nil {
+		sub.dispatcher.Stop()
+		return nil, err
+	}
+	sub.ch = ch
+
+	go func() {
+		for {
+			for d := range deliveries {
+				delivery := d
+				key, _ := d.Headers[broker.HeaderPartitionKey].(string)
+				sub.dispatcher.Dispatch(key, func() {
+					fn(delivery)
+				})
+			}
+
+			// resubscribe when the connection is lost
+			for {
+				if sub.isClosed() {
+					return
+				}
+
+				select {
+				case <-sub.done:
+					return
+				case <-conn.wait():
+				}
+
+				ch, deliveries, err = conn.Consume(opt.Queue, topic, durable, opt.MaxInFlight, args)
+				if err == nil {
+					break
+				}
+				if logger.V(logger.ErrorLevel, logger.DefaultLogger) {
+					logger.Errorf("[rabbitmq] failed to resubscribe to %s: %v", topic, err)
+				}
+				time.Sleep(reconnectDelay)
+			}
+
+			sub.Lock()
+			sub.ch = ch
+			closed := sub.closed
+			sub.Unlock()
+
+			if closed {
+				ch.Close()
+				return
+			}
+		}
+	}()
+
+	return sub, nil
+}
+
+func (r *rbroker) delayed() bool {
+	delayed, _ := r.opts.Context.Value(delayedExchangeKey{}).(bool)
+	return delayed
+}
+
+func (r *rbroker) String() string {
+	return "rabbitmq"
+}
+
+func (r *rbroker) setAddrs(addrs []string) []string {
+	//nolint:prealloc
+	var cAddrs []string
+	for _, addr := range addrs {
+		if len(addr) == 0 {
+			continue
+		}
+		if !strings.HasPrefix(addr, "amqp://") && !strings.HasPrefix(addr, "amqps://") {
+			addr = "amqp://" + addr
+		}
+		cAddrs = append(cAddrs, addr)
+	}
+	if len(cAddrs) == 0 {
+		cAddrs = []string{DefaultURL}
+	}
+	return cAddrs
+}
+
+func (r *rbroker) newConn() *rabbitMQConn {
+	ex := exchange{
+		name: "micro",
+		kind: amqp.ExchangeTopic,
+	}
+	if name, ok := r.opts.Context.Value(exchangeKey{}).(string); ok {
+		ex.name = name
+	}
+	if kind, ok := r.opts.Context.Value(exchangeTypeKey{}).(string); ok {
+		ex.kind = kind
+	}
+	if durable, ok := r.opts.Context.Value(durableExchangeKey{}).(bool); ok {
+		ex.durable = durable
+	}
+	if delayed, ok := r.opts.Context.Value(delayedExchangeKey{}).(bool); ok {
+		ex.delayed = delayed
+	}
+
+	prefetchCount, _ := r.opts.Context.Value(prefetchCountKey{}).(int)
+	prefetchGlobal, _ := r.opts.Context.Value(prefetchGlobalKey{}).(bool)
+
+	url := r.Address()
+	// tls is only used for amqps urls
+	if r.opts.Secure || r.opts.TLSConfig != nil {
+		url = strings.Replace(url, "amqp://", "amqps://", 1)
+	}
+
+	return newRabbitMQConn(ex, url, prefetchCount, prefetchGlobal)
+}
+
+// queueArguments returns the arguments used to declare the queue of a subscriber
+func queueArguments(opt broker.SubscribeOptions) amqp.Table {
+	if opt.Context == nil {
+		return nil
+	}
+
+	args := amqp.Table{}
+	if a, ok := opt.Context.Value(queueArgumentsKey{}).(amqp.Table); ok {
+		for k, v := range a {
+			args[k] = v
+		}
+	}
+	if dlx, ok := opt.Context.Value(deadLetterExchangeKey{}).(string); ok {
+		args["x-dead-letter-exchange"] = dlx
+	}
+	if key, ok := opt.Context.Value(deadLetterRoutingKeyKey{}).(string); ok {
+		args["x-dead-letter-routing-key"] = key
+	}
+	if len(args) == 0 {
+		return nil
+	}
+	return args
+}
+
+// NewBroker returns a RabbitMQ broker
+func NewBroker(opts ...broker.Option) broker.Broker {
+	options := broker.Options{
+		Codec:   json.Marshaler{},
+		Context: context.Background(),
+	}
+
+	for _, o := range opts {
+		o(&options)
+	}
+
+	r := &rbroker{
+		opts: options,
+	}
+	r.addrs = r.setAddrs(options.Addrs)
+
+	return r
+}
@@ -0,0 +1,194 @@
+package broker
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+var (
+	// DefaultRedeliveryPolicy is used for messages nacked by handlers
+	// of subscriptions without a redelivery policy
+	DefaultRedeliveryPolicy = RedeliveryPolicy{
+		MaxAttempts: 5,
+		Backoff:     ExponentialBackoff(100*time.Millisecond, 30*time.Second),
+	}
+
+	// ErrMaxAttempts is returned once a message was delivered the max attempts
+	ErrMaxAttempts = errors.New("max delivery attempts reached")
+)
+
+// RedeliveryPolicy controls the redelivery of messages which are nacked
+type RedeliveryPolicy struct {
+	// MaxAttempts is the number of times a message is
+	// delivered. Messages are redelivered forever if 0.
+	MaxAttempts int
+	// Backoff returns the delay before the redelivery
+	// following the attempt, the first attempt being 1.
+	Backoff func(attempt int) time.Duration
+}
+
+// Next returns the delay before redelivering a message nacked on the
+// attempt. It returns false if the attempts are exhausted.
+func (p RedeliveryPolicy) Next(attempt int) (time.Duration, bool) {
+	if p.MaxAttempts > 0 && attempt >= p.MaxAttempts {
+		return 0, false
+	}
+	if p.Backoff == nil {
+		return 0, true
+	}
+	return p.Backoff(attempt), true
+}
+
+// ExponentialBackoff doubles the delay from min on every attempt up to max
+func ExponentialBackoff(min, max time.Duration) func(int) time.Duration {
+	return func(attempt int) time.Duration {
+		d := min
+		for i := 1; i < attempt && d < max; i++ {
+			d *= 2
+		}
+		if d > max {
+			d = max
+		}
+		return d
+	}
+}
+
+// Redeliverer redelivers the messages nacked or requeued by the handler of a
+// subscription. It's used by brokers which can't redeliver messages themselves,
+// the messages are kept in memory until redelivered. With a redelivery policy
+// set on the subscription the messages the handler returns an error for are
+// nacked rather than lost.
+type Redeliverer struct {
+	handler     Handler
+	policy      RedeliveryPolicy
+	nackOnError bool
+
+	sync.Mutex
+	stopped bool
+	timers  map[*time.Timer]bool
+}
+
+type settlement int
+
+const (
+	unsettled settlement = iota
+	acked
+	nacked
+	requeued
+)
+
+// delivery is an event delivered by the redeliverer
+type delivery struct {
+	Event
+	attempt int
+
+	sync.Mutex
+	state settlement
+}
+
+func (d *delivery) settle(s settlement) {
+	d.Lock()
+	d.state = s
+	d.Unlock()
+}
+
+func (d *delivery) Ack() error {
+	d.settle(acked)
+	return d.Event.Ack()
+}
+
+func (d *delivery) Nack() error {
+	d.settle(nacked)
+	return nil
+}
+
+func (d *delivery) Requeue() error {
+	d.settle(requeued)
+	return nil
+}
+
+// Handle delivers the event to the handler
+func (r *Redeliverer) Handle(e Event) error {
+	return r.deliver(e, 1)
+}
+
+func (r *Redeliverer) deliver(e Event, attempt int) error {
+	d := &delivery{Event: e, attempt: attempt}
+	err := r.handler(d)
+
+	d.Lock()
+	state := d.state
+	d.Unlock()
+
+	if state == unsettled && err != nil && r.nackOnError {
+		state = nacked
+	}
+
+	switch state {
+	case requeued:
+		// requeued messages aren't counted as an attempt
+		r.schedule(0, e, attempt)
+	case nacked:
+		delay, ok := r.policy.Next(attempt)
+		if !ok {
+			if err == nil {
+				err = ErrMaxAttempts
+			}
+			return err
+		}
+		r.schedule(delay, e, attempt+1)
+	}
+
+	return err
+}
+
+func (r *Redeliverer) schedule(delay time.Duration, e Event, attempt int) {
+	r.Lock()
+	defer r.Unlock()
+
+	if r.stopped {
+		return
+	}
+
+	var t *time.Timer
+	t = time.AfterFunc(delay, func() {
+		r.Lock()
+		delete(r.timers, t)
+		stopped := r.stopped
+		r.Unlock()
+
+		if !stopped {
+			r.deliver(e, attempt)
+		}
+	})
+	r.timers[t] = true
+}
+
+// Stop discards the messages waiting to be redelivered
+func (r *Redeliverer) Stop() {
+	r.Lock()
+	defer r.Unlock()
+
+	r.stopped = true
+	for t := range r.timers {
+		t.Stop()
+	}
+	r.timers = make(map[*time.Timer]bool)
+}
+
+// NewRedeliverer returns a redeliverer for the handler of the subscription
+func NewRedeliverer(h Handler, opts SubscribeOptions) *Redeliverer {
+	r := &Redeliverer{
+		handler: h,
+		policy:  DefaultRedeliveryPolicy,
+		timers:  make(map[*time.Timer]bool),
+	}
+
+	if opts.Redelivery != nil {
+		r.policy = *opts.Redelivery
+		r.nackOnError = true
+	}
+
+	return r
+}
@@ -0,0 +1,77 @@
+package redis
+
+import (
+	"context"
+	"time"
+
+	"github.com/micro/go-micro/v2/broker"
+)
+
+type passwordKey struct{}
+
+type databaseKey struct{}
+
+type maxLenKey struct{}
+
+type claimIdleKey struct{}
+
+type readCountKey struct{}
+
+// Password to authenticate with the redis server
+func Password(p string) broker.Option {
+	return setBrokerOption(passwordKey{}, p)
+}
+
+// Database selects the redis database to use
+func Database(db int) broker.Option {
+	return setBrokerOption(databaseKey{}, db)
+}
+
+// MaxLen caps the length of the streams published to. Trimming is
+// approximate so the streams may be slightly longer.
+func MaxLen(n int64) broker.Option {
+	return setBrokerOption(maxLenKey{}, n)
+}
+
+// PublishMaxLen caps the length of the stream the message is published to
+func PublishMaxLen(n int64) broker.PublishOption {
+	return setPublishOption(maxLenKey{}, n)
+}
+
+// ClaimIdle sets how long a message is pending before it is claimed from
+// a consumer which has likely crashed. Defaults to 30 seconds.
+func ClaimIdle(d time.Duration) broker.SubscribeOption {
+	return setSubscribeOption(claimIdleKey{}, d)
+}
+
+// ReadCount sets the maximum number of messages read at once
+func ReadCount(n int) broker.SubscribeOption {
+	return setSubscribeOption(readCountKey{}, n)
+}
+
+func setBrokerOption(k, v interface{}) broker.Option {
+	return func(o *broker.Options) {
+		if o.Context == nil {
+			o.Context = context.Background()
+		}
+		o.Context = context.WithValue(o.Context, k, v)
+	}
+}
+
+func setSubscribeOption(k, v interface{}) broker.SubscribeOption {
+	return func(o *broker.SubscribeOptions) {
+		if o.Context == nil {
+			o.Context = context.Background()
+		}
+		o.Context = context.WithValue(o.Context, k, v)
+	}
+}
+
+func setPublishOption(k, v interface{}) broker.PublishOption {
+	return func(o *broker.PublishOptions) {
+		if o.Context == nil {
+			o.Context = context.Background()
+		}
+		o.Context = context.WithValue(o.Context, k, v)
+	}
+}
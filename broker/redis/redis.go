@@ -0,0 +1,595 @@
+// Package redis provides a redis streams broker
+package redis
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+	"github.com/google/uuid"
+	"github.com/micro/go-micro/v2/broker"
+	"github.com/micro/go-micro/v2/codec/json"
+	"github.com/micro/go-micro/v2/logger"
+)
+
+var (
+	errNotConnected = errors.New("not connected")
+
+	// DefaultClaimIdle is how long a message is pending before it is claimed
+	DefaultClaimIdle = 30 * time.Second
+
+	// DefaultReadCount is the number of messages read at once
+	DefaultReadCount = 10
+
+	defaultAddress = "127.0.0.1:6379"
+
+	// blockTimeout is how long a read blocks waiting for messages
+	blockTimeout = time.Second
+)
+
+type rbroker struct {
+	sync.RWMutex
+	opts    broker.Options
+	pool    *redis.Pool
+	timeout time.Duration
+	subs    map[*subscriber]bool
+}
+
+type subscriber struct {
+	b        *rbroker
+	pool     *redis.Pool
+	topic    string
+	group    string
+	consumer string
+	opts     broker.SubscribeOptions
+	handler  broker.Handler
+
+	// handles the messages with the concurrency of the subscription
+	dispatcher *broker.Dispatcher
+
+	claimIdle time.Duration
+	count     int
+
+	once sync.Once
+	exit chan bool
+	done chan bool
+}
+
+type publication struct {
+	s   *subscriber
+	id  string
+	m   *broker.Message
+	err error
+}
+
+type entry struct {
+	id     string
+	fields map[string][]byte
+}
+
+func (p *publication) Ack() error {
+	conn := p.s.pool.Get()
+	defer conn.Close()
+	_, err := conn.Do("XACK", p.s.topic, p.s.group, p.id)
+	return err
+}
+
+// Nack leaves the message pending so it's claimed by
+// a subscriber of the group after the claim idle time
+func (p *publication) Nack() error {
+	return nil
+}
+
+func (p *publication) Requeue() error {
+	return nil
+}
+
+func (p *publication) Error() error {
+	return p.err
+}
+
+func (p *publication) Topic() string {
+	return p.s.topic
+}
+
+func (p *publication) Message() *broker.Message {
+	return p.m
+}
+
+func (s *subscriber) Options() broker.SubscribeOptions {
+	return s.opts
+}
+
+func (s *subscriber) Topic() string {
+	return s.topic
+}
+
+func (s *subscriber) Unsubscribe() error {
+	s.stop()
+
+	s.b.Lock()
+	delete(s.b.subs, s)
+	s.b.Unlock()
+
+	conn := s.pool.Get()
+	defer conn.Close()
+
+	// the group of a subscriber without a queue is its own
+	if len(s.opts.Queue) == 0 {
+		_, err := conn.Do("XGROUP", "DESTROY", s.topic, s.group)
+		return err
+	}
+
+	_, err := conn.Do("XGROUP", "DELCONSUMER", s.topic, s.group, s.consumer)
+	return err
+}
+
+func (s *subscriber) stop() {
+	s.once.Do(func() {
+		close(s.exit)
+	})
+	<-s.done
+	s.dispatcher.Stop()
+}
+
+// run reads new messages of the group and claims messages left pending
+// by consumers which have crashed
+func (s *subscriber) run() {
+	defer close(s.done)
+
+	var lastClaim time.Time
+
+	for {
+		select {
+		case <-s.exit:
+			return
+		default:
+		}
+
+		if time.Since(lastClaim) >= s.claimIdle {
+			lastClaim = time.Now()
+			if err := s.claim(); err != nil && logger.V(logger.ErrorLevel, logger.DefaultLogger) {
+				logger.Errorf("[redis] failed to claim pending messages of %s: %v", s.topic, err)
+			}
+		}
+
+		entries, err := s.read()
+		if err != nil {
+			if logger.V(logger.ErrorLevel, logger.DefaultLogger) {
+				logger.Errorf("[redis] failed to read from %s: %v", s.topic, err)
+			}
+			select {
+			case <-s.exit:
+				return
+			case <-time.After(blockTimeout):
+			}
+			continue
+		}
+
+		for _, e := range entries {
+			s.handle(e)
+		}
+	}
+}
+
+func (s *subscriber) read() ([]entry, error) {
+	conn := s.pool.Get()
+	defer conn.Close()
+
+	reply, err := redis.Values(conn.Do(
+		"XREADGROUP", "GROUP", s.group, s.consumer,
+		"COUNT", s.count,
+		"BLOCK", blockTimeout.Milliseconds(),
+		"STREAMS", s.topic, ">",
+	))
+	if err == redis.ErrNil {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	var entries []entry
+	for _, r := range reply {
+		stream, err := redis.Values(r, nil)
+		if err != nil || len(stream) != 2 {
+			continue
+		}
+		e, err := parseEntries(stream[1], nil)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, e...)
+	}
+
+	return entries, nil
+}
+
+// claim takes over the messages which have been pending longer than the
+// claim idle time and handles them
+func (s *subscriber) claim() error {
+	conn := s.pool.Get()
+	defer conn.Close()
+
+	pending, err := redis.Values(conn.Do("XPENDING", s.topic, s.group, "-", "+", s.count))
+	if err != nil {
+		return err
+	}
+
+	args := redis.Args{s.topic, s.group, s.consumer, s.claimIdle.Milliseconds()}
+	var ids int
+
+	for _, p := range pending {
+		// [id, consumer, idle, deliveries]
+		v, err := redis.Values(p, nil)
+		if err != nil || len(v) < 3 {
+			continue
+		}
+		id, _ := redis.String(v[0], nil)
+		idle, _ := redis.Int64(v[2], nil)
+		if time.Duration(idle)*time.Millisecond < s.claimIdle {
+			continue
+		}
+		args = args.Add(id)
+		ids++
+	}
+
+	if ids == 0 {
+		return nil
+	}
+
+	entries, err := parseEntries(conn.Do("XCLAIM", args...))
+	if err != nil {
+		return err
+	}
+
+	for _, e := range entries {
+		s.handle(e)
+	}
+
+	return nil
+}
+
+func (s *subscriber) handle(e entry) {
+	p := &publication{s: s, id: e.id}
+
+	var m broker.Message
+	if err := s.b.opts.Codec.Unmarshal(e.fields["message"], &m); err != nil {
+		p.m = &broker.Message{Body: e.fields["message"]}
+		p.err = err
+		if logger.V(logger.ErrorLevel, logger.DefaultLogger) {
+			logger.Errorf("[redis] failed to unmarshal message %s: %v", e.id, err)
+		}
+		if s.b.opts.ErrorHandler != nil {
+			s.b.opts.ErrorHandler(p)
+		}
+		// the message can't be handled so drop it
+		p.Ack()
+		return
+	}
+	p.m = &m
+
+	s.dispatcher.Dispatch(m.Header[broker.HeaderPartitionKey], func() {
+		p.err = s.handler(p)
+		if p.err == nil && s.opts.AutoAck {
+			if err := p.Ack(); err != nil && logger.V(logger.ErrorLevel, logger.DefaultLogger) {
+				logger.Errorf("[redis] failed to ack message %s: %v", e.id, err)
+			}
+		} else if p.err != nil {
+			if logger.V(logger.ErrorLevel, logger.DefaultLogger) {
+				logger.Errorf("[redis] subscriber error: %v", p.err)
+			}
+			if s.b.opts.ErrorHandler != nil {
+				s.b.opts.ErrorHandler(p)
+			}
+			// unacked messages stay pending and are claimed again
+		}
+	})
+}
+
+// parseEntries parses a reply of stream entries [[id, [field, value...]]...]
+func parseEntries(reply interface{}, err error) ([]entry, error) {
+	vals, err := redis.Values(reply, err)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]entry, 0, len(vals))
+	for _, v := range vals {
+		e, err := redis.Values(v, nil)
+		if err != nil || len(e) != 2 {
+			continue
+		}
+		id, err := redis.String(e[0], nil)
+		if err != nil {
+			continue
+		}
+		// entries deleted by trimming have no fields
+		fields, err := redis.ByteSlices(e[1], nil)
+		if err != nil {
+			continue
+		}
+		en := entry{id: id, fields: make(map[string][]byte, len(fields)/2)}
+		for i := 0; i+1 < len(fields); i += 2 {
+			en.fields[string(fields[i])] = fields[i+1]
+		}
+		entries = append(entries, en)
+	}
+
+	return entries, nil
+}
+
+func (r *rbroker) Address() string {
+	for _, a := range r.opts.Addrs {
+		if len(a) > 0 {
+			return a
+		}
+	}
+	return defaultAddress
+}
+
+func (r *rbroker) Connect() error {
+	r.Lock()
+	defer r.Unlock()
+
+	if r.pool != nil {
+		return nil
+	}
+
+	r.pool = &redis.Pool{
+		MaxIdle:     16,
+		IdleTimeout: time.Minute,
+		Dial:        r.dial,
+		TestOnBorrow: func(c redis.Conn, t time.Time) error {
+			if time.Since(t) < time.Minute {
+				return nil
+			}
+			_, err := c.Do("PING")
+			return err
+		},
+	}
+
+	conn := r.pool.Get()
+	defer conn.Close()
+
+	_, err := conn.Do("PING")
+	return err
+}
+
+func (r *rbroker) Disconnect() error {
+	r.Lock()
+	subs := r.subs
+	r.subs = make(map[*subscriber]bool)
+	pool := r.pool
+	r.pool = nil
+	r.Unlock()
+
+	for s := range subs {
+		s.stop()
+	}
+
+	if pool == nil {
+		return nil
+	}
+
+	return pool.Close()
+}
+
+func (r *rbroker) Init(opts ...broker.Option) error {
+	r.Lock()
+	defer r.Unlock()
+
+	for _, o := range opts {
+		o(&r.opts)
+	}
+
+	return nil
+}
+
+func (r *rbroker) Options() broker.Options {
+	return r.opts
+}
+
+func (r *rbroker) Publish(topic string, msg *broker.Message, opts ...broker.PublishOption) error {
+	// messages delivered later are published by the scheduler
+	if broker.Deferred(r, topic, msg, opts...) {
+		return nil
+	}
+
+	msg, err := broker.EncodeMessage(msg, opts...)
+	if err != nil {
+		return err
+	}
+
+	options := broker.PublishOptions{
+		Context: context.Background(),
+	}
+	for _, o := range opts {
+		o(&options)
+	}
+
+	r.RLock()
+	pool := r.pool
+	r.RUnlock()
+
+	if pool == nil {
+		return errNotConnected
+	}
+
+	b, err := r.opts.Codec.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	conn := pool.Get()
+	defer conn.Close()
+
+	_, err = conn.Do("XADD", r.publishArgs(topic, b, options)...)
+	return err
+}
+
+// publishArgs returns the arguments of XADD, trimming the stream
+// when a max length is set
+func (r *rbroker) publishArgs(topic string, b []byte, options broker.PublishOptions) redis.Args {
+	maxLen, ok := options.Context.Value(maxLenKey{}).(int64)
+	if !ok {
+		maxLen, _ = r.opts.Context.Value(maxLenKey{}).(int64)
+	}
+
+	args := redis.Args{topic}
+	if maxLen > 0 {
+		args = args.Add("MAXLEN", "~", maxLen)
+	}
+	return args.Add("*", "message", b)
+}
+
+func (r *rbroker) Subscribe(topic string, handler broker.Handler, opts ...broker.SubscribeOption) (broker.Subscriber, error) {
+	// every topic is a separate stream read by the subscriber
+	if broker.IsPattern(topic) {
+		return nil, broker.ErrPatternNotSupported
+	}
+
+	opt := broker.SubscribeOptions{
+		AutoAck: true,
+		Context: context.Background(),
+	}
+	for _, o := range opts {
+		o(&opt)
+	}
+
+	// handlers running longer than the timeout fail
+	handler = broker.TimeoutHandler(handler, opt)
+	// messages the handler keeps failing on are dead lettered
+	handler = broker.DeadLetterHandler(r, broker.DecompressHandler(handler), opt)
+	// messages with the same partition key are handled in order
+	handler = broker.OrderedHandler(handler, opt)
+
+	r.RLock()
+	pool := r.pool
+	r.RUnlock()
+
+	if pool == nil {
+		return nil, errNotConnected
+	}
+
+	sub := newSubscriber(r, pool, topic, handler, opt)
+
+	// create the group before returning so messages
+	// published after subscribing aren't missed
+	conn := pool.Get()
+	_, err := conn.Do("XGROUP", "CREATE", topic, sub.group, "$", "MKSTREAM")
+	conn.Close()
+	if err != nil && !strings.HasPrefix(err.Error(), "BUSYGROUP") {
+		sub.dispatcher.Stop()
+		return nil, err
+	}
+
+	r.Lock()
+	r.subs[sub] = true
+	r.Unlock()
+
+	go sub.run()
+
+	return sub, nil
+}
+
+func (r *rbroker) String() string {
+	return "redis"
+}
+
+func (r *rbroker) dial() (redis.Conn, error) {
+	address := r.Address()
+
+	// reads block for up to the block timeout
+	dOpts := []redis.DialOption{
+		redis.DialConnectTimeout(r.timeout),
+		redis.DialReadTimeout(r.timeout + blockTimeout),
+		redis.DialWriteTimeout(r.timeout),
+	}
+
+	if p, ok := r.opts.Context.Value(passwordKey{}).(string); ok && len(p) > 0 {
+		dOpts = append(dOpts, redis.DialPassword(p))
+	}
+	if db, ok := r.opts.Context.Value(databaseKey{}).(int); ok {
+		dOpts = append(dOpts, redis.DialDatabase(db))
+	}
+
+	if r.opts.Secure || r.opts.TLSConfig != nil {
+		dOpts = append(dOpts, redis.DialUseTLS(true))
+		if r.opts.TLSConfig != nil {
+			dOpts = append(dOpts, redis.DialTLSConfig(r.opts.TLSConfig))
+		} else {
+			dOpts = append(dOpts, redis.DialTLSSkipVerify(true))
+		}
+	}
+
+	if strings.HasPrefix(address, "redis://") || strings.HasPrefix(address, "rediss://") {
+		return redis.DialURL(address, dOpts...)
+	}
+
+	if _, _, err := net.SplitHostPort(address); err != nil {
+		address = net.JoinHostPort(address, "6379")
+	}
+
+	return redis.Dial("tcp", address, dOpts...)
+}
+
+func newSubscriber(r *rbroker, pool *redis.Pool, topic string, handler broker.Handler, opt broker.SubscribeOptions) *subscriber {
+	sub := &subscriber{
+		b:         r,
+		pool:      pool,
+		topic:     topic,
+		group:     opt.Queue,
+		consumer:  uuid.New().String(),
+		opts:      opt,
+		handler:   handler,
+		claimIdle: DefaultClaimIdle,
+		count:     DefaultReadCount,
+		exit:      make(chan bool),
+		done:      make(chan bool),
+	}
+
+	// messages are read up to the max in flight
+	if opt.MaxInFlight > 0 {
+		sub.count = opt.MaxInFlight
+	}
+
+	// every subscriber without a queue gets all messages
+	if len(sub.group) == 0 {
+		sub.group = uuid.New().String()
+	}
+
+	sub.dispatcher = broker.NewDispatcher(opt)
+
+	if opt.Context != nil {
+		if d, ok := opt.Context.Value(claimIdleKey{}).(time.Duration); ok && d > 0 {
+			sub.claimIdle = d
+		}
+		if n, ok := opt.Context.Value(readCountKey{}).(int); ok && n > 0 {
+			sub.count = n
+		}
+	}
+
+	return sub
+}
+
+// NewBroker returns a redis streams broker
+func NewBroker(opts ...broker.Option) broker.Broker {
+	options := broker.Options{
+		Codec:   json.Marshaler{},
+		Context: context.Background(),
+	}
+
+	for _, o := range opts {
+		o(&options)
+	}
+
+	return &rbroker{
+		opts:    options,
+		timeout: 5 * time.Second,
+		subs:    make(map[*subscriber]bool),
+	}
+}
@@ -0,0 +1,75 @@
+package redis
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/micro/go-micro/v2/broker"
+)
+
+func publishOptions(opts ...broker.PublishOption) broker.PublishOptions {
+	options := broker.PublishOptions{Context: context.Background()}
+	for _, o := range opts {
+		o(&options)
+	}
+	return options
+}
+
+func TestPublishArgs(t *testing.T) {
+	r := NewBroker().(*rbroker)
+
+	args := r.publishArgs("events", []byte("msg"), publishOptions())
+	if len(args) != 4 || args[1] != "*" {
+		t.Fatalf("Unexpected args %v", args)
+	}
+
+	r = NewBroker(MaxLen(1000)).(*rbroker)
+	args = r.publishArgs("events", []byte("msg"), publishOptions())
+	if len(args) != 7 || args[1] != "MAXLEN" || args[3] != int64(1000) {
+		t.Fatalf("Unexpected args %v", args)
+	}
+
+	args = r.publishArgs("events", []byte("msg"), publishOptions(PublishMaxLen(10)))
+	if args[3] != int64(10) {
+		t.Fatalf("Expected max len 10 got %v", args[3])
+	}
+}
+
+func TestSubscriberOptions(t *testing.T) {
+	r := NewBroker().(*rbroker)
+
+	a := newSubscriber(r, nil, "events", nil, broker.NewSubscribeOptions())
+	b := newSubscriber(r, nil, "events", nil, broker.NewSubscribeOptions())
+	if a.group == b.group {
+		t.Fatal("Expected subscribers without a queue to have their own group")
+	}
+	if a.claimIdle != DefaultClaimIdle || a.count != DefaultReadCount {
+		t.Fatalf("Unexpected defaults %v %d", a.claimIdle, a.count)
+	}
+
+	s := newSubscriber(r, nil, "events", nil, broker.NewSubscribeOptions(
+		broker.Queue("workers"),
+		ClaimIdle(time.Minute),
+		ReadCount(100),
+	))
+	if s.group != "workers" || s.claimIdle != time.Minute || s.count != 100 {
+		t.Fatalf("Unexpected subscriber %s %v %d", s.group, s.claimIdle, s.count)
+	}
+}
+
+func TestParseEntries(t *testing.T) {
+	reply := []interface{}{
+		[]interface{}{[]byte("1-0"), []interface{}{[]byte("message"), []byte("{}")}},
+		// deleted entry
+		[]interface{}{[]byte("2-0"), nil},
+	}
+
+	entries, err := parseEntries(reply, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].id != "1-0" || string(entries[0].fields["message"]) != "{}" {
+		t.Fatalf("Unexpected entries %+v", entries)
+	}
+}
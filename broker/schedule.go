@@ -0,0 +1,102 @@
+package broker
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/micro/go-micro/v2/logger"
+)
+
+var (
+	// DefaultScheduler publishes the delayed messages of brokers
+	DefaultScheduler = NewScheduler()
+)
+
+// Scheduler calls functions at a later time. It's used to deliver messages later
+// for brokers which can't. The functions are kept in memory until they're called
+// so messages are lost if the process exits before they are due.
+type Scheduler struct {
+	sync.Mutex
+	timers map[*time.Timer]bool
+}
+
+// Schedule calls the function at the time
+func (s *Scheduler) Schedule(at time.Time, fn func()) {
+	s.Lock()
+	defer s.Unlock()
+
+	var t *time.Timer
+	t = time.AfterFunc(time.Until(at), func() {
+		s.Lock()
+		delete(s.timers, t)
+		s.Unlock()
+		fn()
+	})
+	s.timers[t] = true
+}
+
+// Pending returns the number of functions not yet called
+func (s *Scheduler) Pending() int {
+	s.Lock()
+	defer s.Unlock()
+	return len(s.timers)
+}
+
+// Stop discards the functions not yet called
+func (s *Scheduler) Stop() {
+	s.Lock()
+	defer s.Unlock()
+
+	for t := range s.timers {
+		t.Stop()
+	}
+	s.timers = make(map[*time.Timer]bool)
+}
+
+// Deferred publishes the message with the default scheduler if the options deliver
+// it later. Brokers which can't delay messages call it when publishing and return
+// if the message was deferred.
+func Deferred(b Broker, topic string, msg *Message, opts ...PublishOption) bool {
+	options := PublishOptions{
+		Context: context.Background(),
+	}
+	for _, o := range opts {
+		o(&options)
+	}
+
+	if !options.DeliverAt.After(time.Now()) {
+		return false
+	}
+
+	// the caller may reuse the message once published
+	m := &Message{
+		Header: make(map[string]string, len(msg.Header)),
+		Body:   msg.Body,
+	}
+	for k, v := range msg.Header {
+		m.Header[k] = v
+	}
+
+	// publish the message without the delay when due
+	popts := make([]PublishOption, 0, len(opts)+1)
+	popts = append(popts, opts...)
+	popts = append(popts, Schedule(time.Time{}))
+
+	DefaultScheduler.Schedule(options.DeliverAt, func() {
+		if err := b.Publish(topic, m, popts...); err != nil {
+			if logger.V(logger.ErrorLevel, logger.DefaultLogger) {
+				logger.Errorf("Failed to publish delayed message to %s: %v", topic, err)
+			}
+		}
+	})
+
+	return true
+}
+
+// NewScheduler returns a scheduler
+func NewScheduler() *Scheduler {
+	return &Scheduler{
+		timers: make(map[*time.Timer]bool),
+	}
+}
@@ -45,10 +45,20 @@ func (b *serviceBroker) Options() broker.Options {
 }
 
 func (b *serviceBroker) Publish(topic string, msg *broker.Message, opts ...broker.PublishOption) error {
+	// messages delivered later are published by the scheduler
+	if broker.Deferred(b, topic, msg, opts...) {
+		return nil
+	}
+
+	msg, err := broker.EncodeMessage(msg, opts...)
+	if err != nil {
+		return err
+	}
+
 	if logger.V(logger.DebugLevel, logger.DefaultLogger) {
 		logger.Debugf("Publishing to topic %s broker %v", topic, b.Addrs)
 	}
-	_, err := b.Client.Publish(context.TODO(), &pb.PublishRequest{
+	_, err = b.Client.Publish(context.TODO(), &pb.PublishRequest{
 		Topic: topic,
 		Message: &pb.Message{
 			Header: msg.Header,
@@ -63,6 +73,13 @@ func (b *serviceBroker) Subscribe(topic string, handler broker.Handler, opts ...
 	for _, o := range opts {
 		o(&options)
 	}
+
+	// handlers running longer than the timeout fail
+	handler = broker.TimeoutHandler(handler, options)
+	// messages the handler keeps failing on are dead lettered
+	handler = broker.DeadLetterHandler(b, broker.DecompressHandler(handler), options)
+	// messages with the same partition key are handled in order
+	handler = broker.OrderedHandler(handler, options)
 	if logger.V(logger.DebugLevel, logger.DefaultLogger) {
 		logger.Debugf("Subscribing to topic %s queue %s broker %v", topic, options.Queue, b.Addrs)
 	}
@@ -81,6 +98,8 @@ func (b *serviceBroker) Subscribe(topic string, handler broker.Handler, opts ...
 		stream:  stream,
 		closed:  make(chan bool),
 		options: options,
+
+		dispatcher: broker.NewDispatcher(options),
 	}
 
 	go func() {
@@ -13,6 +13,8 @@ type serviceSub struct {
 	stream  pb.Broker_SubscribeService
 	closed  chan bool
 	options broker.SubscribeOptions
+	// dispatches the messages to the handler
+	dispatcher *broker.Dispatcher
 }
 
 type serviceEvent struct {
@@ -33,6 +35,15 @@ func (s *serviceEvent) Ack() error {
 	return nil
 }
 
+func (s *serviceEvent) Nack() error {
+	// there is no redelivery support
+	return nil
+}
+
+func (s *serviceEvent) Requeue() error {
+	return nil
+}
+
 func (s *serviceEvent) Error() error {
 	return s.err
 }
@@ -85,7 +96,9 @@ func (s *serviceSub) run() error {
 				Body:   msg.Body,
 			},
 		}
-		p.err = s.handler(p)
+		s.dispatcher.Dispatch(msg.Header[broker.HeaderPartitionKey], func() {
+			p.err = s.handler(p)
+		})
 	}
 }
 
@@ -103,6 +116,7 @@ func (s *serviceSub) Unsubscribe() error {
 		return nil
 	default:
 		close(s.closed)
+		s.dispatcher.Stop()
 	}
 	return nil
 }
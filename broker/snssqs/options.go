@@ -0,0 +1,98 @@
+package snssqs
+
+import (
+	"context"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/micro/go-micro/v2/broker"
+)
+
+type sessionKey struct{}
+
+type regionKey struct{}
+
+type fifoKey struct{}
+
+type messageGroupIDKey struct{}
+
+type deduplicationIDKey struct{}
+
+type waitTimeKey struct{}
+
+type visibilityTimeoutKey struct{}
+
+type maxMessagesKey struct{}
+
+// Session sets the aws session. Defaults to a session
+// configured from the environment and shared config.
+func Session(s *session.Session) broker.Option {
+	return setBrokerOption(sessionKey{}, s)
+}
+
+// Region sets the aws region
+func Region(r string) broker.Option {
+	return setBrokerOption(regionKey{}, r)
+}
+
+// FIFO uses fifo topics and queues which deliver messages with the
+// same group id in order and deduplicate messages by their content
+func FIFO() broker.Option {
+	return setBrokerOption(fifoKey{}, true)
+}
+
+// MessageGroupID sets the group of a message published to a fifo
+// topic. Defaults to the topic so all messages are ordered.
+func MessageGroupID(id string) broker.PublishOption {
+	return setPublishOption(messageGroupIDKey{}, id)
+}
+
+// DeduplicationID sets the id used to deduplicate a message published
+// to a fifo topic. Defaults to a hash of the message.
+func DeduplicationID(id string) broker.PublishOption {
+	return setPublishOption(deduplicationIDKey{}, id)
+}
+
+// WaitTime sets how long a receive waits for messages to arrive. Defaults
+// to the maximum of 20 seconds.
+func WaitTime(d time.Duration) broker.SubscribeOption {
+	return setSubscribeOption(waitTimeKey{}, d)
+}
+
+// VisibilityTimeout sets how long a received message is hidden from other
+// subscribers before it's redelivered if not acked
+func VisibilityTimeout(d time.Duration) broker.SubscribeOption {
+	return setSubscribeOption(visibilityTimeoutKey{}, d)
+}
+
+// MaxMessages sets the maximum number of messages received at once, up to 10
+func MaxMessages(n int) broker.SubscribeOption {
+	return setSubscribeOption(maxMessagesKey{}, n)
+}
+
+func setBrokerOption(k, v interface{}) broker.Option {
+	return func(o *broker.Options) {
+		if o.Context == nil {
+			o.Context = context.Background()
+		}
+		o.Context = context.WithValue(o.Context, k, v)
+	}
+}
+
+func setSubscribeOption(k, v interface{}) broker.SubscribeOption {
+	return func(o *broker.SubscribeOptions) {
+		if o.Context == nil {
+			o.Context = context.Background()
+		}
+		o.Context = context.WithValue(o.Context, k, v)
+	}
+}
+
+func setPublishOption(k, v interface{}) broker.PublishOption {
+	return func(o *broker.PublishOptions) {
+		if o.Context == nil {
+			o.Context = context.Background()
+		}
+		o.Context = context.WithValue(o.Context, k, v)
+	}
+}
@@ -0,0 +1,579 @@
+// Package snssqs provides a broker which publishes to aws sns topics and
+// subscribes with sqs queues subscribed to the topics
+package snssqs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/sns"
+	"github.com/aws/aws-sdk-go/service/sqs"
+	"github.com/google/uuid"
+	"github.com/micro/go-micro/v2/broker"
+	"github.com/micro/go-micro/v2/codec/json"
+	"github.com/micro/go-micro/v2/logger"
+)
+
+var (
+	errNotConnected = errors.New("not connected")
+
+	// DefaultWaitTime of long polling receives
+	DefaultWaitTime = 20 * time.Second
+
+	// DefaultMaxMessages received at once
+	DefaultMaxMessages = 10
+
+	// maxQueueName is the maximum length of a queue name
+	maxQueueName = 80
+)
+
+type snsBroker struct {
+	sync.RWMutex
+	opts   broker.Options
+	sns    *sns.SNS
+	sqs    *sqs.SQS
+	topics map[string]string
+}
+
+type subscriber struct {
+	b               *snsBroker
+	sns             *sns.SNS
+	sqs             *sqs.SQS
+	topic           string
+	queueURL        string
+	subscriptionArn string
+	opts            broker.SubscribeOptions
+	handler         broker.Handler
+	dispatcher      *broker.Dispatcher
+
+	waitTime          int64
+	visibilityTimeout int64
+	maxMessages       int64
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	done   chan bool
+}
+
+type publication struct {
+	s       *subscriber
+	receipt *string
+	m       *broker.Message
+	err     error
+}
+
+func (p *publication) Ack() error {
+	_, err := p.s.sqs.DeleteMessage(&sqs.DeleteMessageInput{
+		QueueUrl:      aws.String(p.s.queueURL),
+		ReceiptHandle: p.receipt,
+	})
+	return err
+}
+
+// Nack makes the message visible to subscribers again immediately
+func (p *publication) Nack() error {
+	_, err := p.s.sqs.ChangeMessageVisibility(&sqs.ChangeMessageVisibilityInput{
+		QueueUrl:          aws.String(p.s.queueURL),
+		ReceiptHandle:     p.receipt,
+		VisibilityTimeout: aws.Int64(0),
+	})
+	return err
+}
+
+// Requeue makes the message visible to subscribers again immediately
+func (p *publication) Requeue() error {
+	return p.Nack()
+}
+
+func (p *publication) Error() error {
+	return p.err
+}
+
+func (p *publication) Topic() string {
+	return p.s.topic
+}
+
+func (p *publication) Message() *broker.Message {
+	return p.m
+}
+
+func (s *subscriber) Options() broker.SubscribeOptions {
+	return s.opts
+}
+
+func (s *subscriber) Topic() string {
+	return s.topic
+}
+
+func (s *subscriber) Unsubscribe() error {
+	s.cancel()
+	<-s.done
+	s.dispatcher.Stop()
+
+	// queues of subscribers without a queue name are their own
+	if len(s.opts.Queue) > 0 {
+		return nil
+	}
+
+	if _, err := s.sns.Unsubscribe(&sns.UnsubscribeInput{
+		SubscriptionArn: aws.String(s.subscriptionArn),
+	}); err != nil {
+		return err
+	}
+
+	_, err := s.sqs.DeleteQueue(&sqs.DeleteQueueInput{
+		QueueUrl: aws.String(s.queueURL),
+	})
+	return err
+}
+
+func (s *subscriber) run() {
+	defer close(s.done)
+
+	for {
+		out, err := s.sqs.ReceiveMessageWithContext(s.ctx, &sqs.ReceiveMessageInput{
+			QueueUrl:            aws.String(s.queueURL),
+			MaxNumberOfMessages: aws.Int64(s.maxMessages),
+			WaitTimeSeconds:     aws.Int64(s.waitTime),
+			VisibilityTimeout:   visibilityTimeout(s.visibilityTimeout),
+		})
+		if s.ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			if logger.V(logger.ErrorLevel, logger.DefaultLogger) {
+				logger.Errorf("[snssqs] failed to receive from %s: %v", s.queueURL, err)
+			}
+			select {
+			case <-s.ctx.Done():
+				return
+			case <-time.After(time.Second):
+			}
+			continue
+		}
+
+		for _, m := range out.Messages {
+			s.handle(m)
+		}
+	}
+}
+
+func (s *subscriber) handle(sm *sqs.Message) {
+	p := &publication{s: s, receipt: sm.ReceiptHandle}
+
+	var m broker.Message
+	if err := s.b.opts.Codec.Unmarshal([]byte(aws.StringValue(sm.Body)), &m); err != nil {
+		p.m = &broker.Message{Body: []byte(aws.StringValue(sm.Body))}
+		p.err = err
+		if logger.V(logger.ErrorLevel, logger.DefaultLogger) {
+			logger.Errorf("[snssqs] failed to unmarshal message %s: %v", aws.StringValue(sm.MessageId), err)
+		}
+		if s.b.opts.ErrorHandler != nil {
+			s.b.opts.ErrorHandler(p)
+		}
+		// the message can't be handled so drop it
+		p.Ack()
+		return
+	}
+	p.m = &m
+
+	s.dispatcher.Dispatch(m.Header[broker.HeaderPartitionKey], func() {
+		p.err = s.handler(p)
+		if p.err == nil && s.opts.AutoAck {
+			if err := p.Ack(); err != nil && logger.V(logger.ErrorLevel, logger.DefaultLogger) {
+				logger.Errorf("[snssqs] failed to ack message %s: %v", aws.StringValue(sm.MessageId), err)
+			}
+		} else if p.err != nil {
+			if logger.V(logger.ErrorLevel, logger.DefaultLogger) {
+				logger.Errorf("[snssqs] subscriber error: %v", p.err)
+			}
+			if s.b.opts.ErrorHandler != nil {
+				s.b.opts.ErrorHandler(p)
+			}
+			if s.opts.AutoAck {
+				p.Nack()
+			}
+		}
+	})
+}
+
+func (b *snsBroker) Address() string {
+	for _, a := range b.opts.Addrs {
+		if len(a) > 0 {
+			return a
+		}
+	}
+	return ""
+}
+
+func (b *snsBroker) Connect() error {
+	b.Lock()
+	defer b.Unlock()
+
+	if b.sns != nil {
+		return nil
+	}
+
+	sess, ok := b.opts.Context.Value(sessionKey{}).(*session.Session)
+	if !ok {
+		s, err := session.NewSessionWithOptions(session.Options{
+			SharedConfigState: session.SharedConfigEnable,
+		})
+		if err != nil {
+			return err
+		}
+		sess = s
+	}
+
+	cfg := aws.NewConfig()
+	if r, ok := b.opts.Context.Value(regionKey{}).(string); ok {
+		cfg = cfg.WithRegion(r)
+	}
+	// e.g. localstack
+	if addr := b.Address(); len(addr) > 0 {
+		cfg = cfg.WithEndpoint(addr)
+	}
+
+	b.sns = sns.New(sess, cfg)
+	b.sqs = sqs.New(sess, cfg)
+
+	return nil
+}
+
+func (b *snsBroker) Disconnect() error {
+	b.Lock()
+	b.sns = nil
+	b.sqs = nil
+	b.topics = make(map[string]string)
+	b.Unlock()
+	return nil
+}
+
+func (b *snsBroker) Init(opts ...broker.Option) error {
+	b.Lock()
+	defer b.Unlock()
+
+	for _, o := range opts {
+		o(&b.opts)
+	}
+
+	return nil
+}
+
+func (b *snsBroker) Options() broker.Options {
+	return b.opts
+}
+
+func (b *snsBroker) Publish(topic string, msg *broker.Message, opts ...broker.PublishOption) error {
+	// messages delivered later are published by the scheduler
+	if broker.Deferred(b, topic, msg, opts...) {
+		return nil
+	}
+
+	msg, err := broker.EncodeMessage(msg, opts...)
+	if err != nil {
+		return err
+	}
+
+	options := broker.PublishOptions{
+		Context: context.Background(),
+	}
+	for _, o := range opts {
+		o(&options)
+	}
+
+	arn, err := b.topicArn(topic)
+	if err != nil {
+		return err
+	}
+
+	body, err := b.opts.Codec.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	input := &sns.PublishInput{
+		TopicArn: aws.String(arn),
+		Message:  aws.String(string(body)),
+	}
+
+	if b.fifo() {
+		group := topic
+		if len(options.Key) > 0 {
+			group = options.Key
+		}
+		if id, ok := options.Context.Value(messageGroupIDKey{}).(string); ok && len(id) > 0 {
+			group = id
+		}
+		input.MessageGroupId = aws.String(group)
+
+		if id, ok := options.Context.Value(deduplicationIDKey{}).(string); ok && len(id) > 0 {
+			input.MessageDeduplicationId = aws.String(id)
+		}
+	}
+
+	b.RLock()
+	client := b.sns
+	b.RUnlock()
+
+	if client == nil {
+		return errNotConnected
+	}
+
+	_, err = client.PublishWithContext(options.Context, input)
+	return err
+}
+
+func (b *snsBroker) Subscribe(topic string, handler broker.Handler, opts ...broker.SubscribeOption) (broker.Subscriber, error) {
+	// subscriptions are bound to a single topic
+	if broker.IsPattern(topic) {
+		return nil, broker.ErrPatternNotSupported
+	}
+
+	opt := broker.SubscribeOptions{
+		AutoAck: true,
+		Context: context.Background(),
+	}
+	for _, o := range opts {
+		o(&opt)
+	}
+
+	// handlers running longer than the timeout fail
+	handler = broker.TimeoutHandler(handler, opt)
+	// messages the handler keeps failing on are dead lettered
+	handler = broker.DeadLetterHandler(b, broker.DecompressHandler(handler), opt)
+	// messages with the same partition key are handled in order
+	handler = broker.OrderedHandler(handler, opt)
+
+	topicArn, err := b.topicArn(topic)
+	if err != nil {
+		return nil, err
+	}
+
+	b.RLock()
+	snsClient, sqsClient := b.sns, b.sqs
+	b.RUnlock()
+
+	sub := newSubscriber(b, topic, handler, opt)
+	sub.sns = snsClient
+	sub.sqs = sqsClient
+
+	queue := opt.Queue
+	if len(queue) == 0 {
+		queue = topic + "-" + uuid.New().String()
+	}
+
+	attrs := map[string]*string{}
+	if b.fifo() {
+		attrs[sqs.QueueAttributeNameFifoQueue] = aws.String("true")
+	}
+
+	q, err := sqsClient.CreateQueue(&sqs.CreateQueueInput{
+		QueueName:  aws.String(b.queueName(queue)),
+		Attributes: attrs,
+	})
+	if err != nil {
+		return nil, err
+	}
+	sub.queueURL = aws.StringValue(q.QueueUrl)
+
+	qa, err := sqsClient.GetQueueAttributes(&sqs.GetQueueAttributesInput{
+		QueueUrl:       q.QueueUrl,
+		AttributeNames: []*string{aws.String(sqs.QueueAttributeNameQueueArn)},
+	})
+	if err != nil {
+		return nil, err
+	}
+	queueArn := aws.StringValue(qa.Attributes[sqs.QueueAttributeNameQueueArn])
+
+	// allow the topics of the account to send to the queue
+	if _, err := sqsClient.SetQueueAttributes(&sqs.SetQueueAttributesInput{
+		QueueUrl: q.QueueUrl,
+		Attributes: map[string]*string{
+			sqs.QueueAttributeNamePolicy: aws.String(queuePolicy(queueArn, topicArn)),
+		},
+	}); err != nil {
+		return nil, err
+	}
+
+	// raw delivery so the body of the sqs message is the published message
+	out, err := snsClient.Subscribe(&sns.SubscribeInput{
+		TopicArn: aws.String(topicArn),
+		Protocol: aws.String("sqs"),
+		Endpoint: aws.String(queueArn),
+		Attributes: map[string]*string{
+			"RawMessageDelivery": aws.String("true"),
+		},
+		ReturnSubscriptionArn: aws.Bool(true),
+	})
+	if err != nil {
+		return nil, err
+	}
+	sub.subscriptionArn = aws.StringValue(out.SubscriptionArn)
+	sub.dispatcher = broker.NewDispatcher(opt)
+
+	go sub.run()
+
+	return sub, nil
+}
+
+func (b *snsBroker) String() string {
+	return "snssqs"
+}
+
+func (b *snsBroker) fifo() bool {
+	fifo, _ := b.opts.Context.Value(fifoKey{}).(bool)
+	return fifo
+}
+
+// topicArn creates the topic if it doesn't exist and returns its arn
+func (b *snsBroker) topicArn(topic string) (string, error) {
+	b.RLock()
+	client := b.sns
+	arn, ok := b.topics[topic]
+	b.RUnlock()
+
+	if client == nil {
+		return "", errNotConnected
+	}
+	if ok {
+		return arn, nil
+	}
+
+	attrs := map[string]*string{}
+	if b.fifo() {
+		attrs["FifoTopic"] = aws.String("true")
+		attrs["ContentBasedDeduplication"] = aws.String("true")
+	}
+
+	// create topic is idempotent and returns the arn of existing topics
+	out, err := client.CreateTopic(&sns.CreateTopicInput{
+		Name:       aws.String(b.topicName(topic)),
+		Attributes: attrs,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	arn = aws.StringValue(out.TopicArn)
+
+	b.Lock()
+	b.topics[topic] = arn
+	b.Unlock()
+
+	return arn, nil
+}
+
+func (b *snsBroker) topicName(topic string) string {
+	name := sanitize(topic)
+	if b.fifo() {
+		name += ".fifo"
+	}
+	return name
+}
+
+func (b *snsBroker) queueName(queue string) string {
+	name := sanitize(queue)
+	max := maxQueueName
+	if b.fifo() {
+		max -= len(".fifo")
+	}
+	// keep the end which is unique for generated names
+	if len(name) > max {
+		name = name[len(name)-max:]
+	}
+	if b.fifo() {
+		name += ".fifo"
+	}
+	return name
+}
+
+// sanitize replaces the characters not allowed in topic and queue names
+func sanitize(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_':
+			return r
+		default:
+			return '-'
+		}
+	}, s)
+}
+
+// queuePolicy allows the topics in the account of the topic to send to the queue
+func queuePolicy(queueArn, topicArn string) string {
+	source := topicArn
+	if i := strings.LastIndex(topicArn, ":"); i > 0 {
+		source = topicArn[:i+1] + "*"
+	}
+	return fmt.Sprintf(`{"Version":"2012-10-17","Statement":[{"Effect":"Allow",`+
+		`"Principal":{"Service":"sns.amazonaws.com"},"Action":"sqs:SendMessage","Resource":"%s",`+
+		`"Condition":{"ArnLike":{"aws:SourceArn":"%s"}}}]}`, queueArn, source)
+}
+
+func visibilityTimeout(seconds int64) *int64 {
+	if seconds <= 0 {
+		return nil
+	}
+	return aws.Int64(seconds)
+}
+
+func newSubscriber(b *snsBroker, topic string, handler broker.Handler, opt broker.SubscribeOptions) *subscriber {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	sub := &subscriber{
+		b:           b,
+		topic:       topic,
+		opts:        opt,
+		handler:     handler,
+		waitTime:    int64(DefaultWaitTime.Seconds()),
+		maxMessages: int64(DefaultMaxMessages),
+		ctx:         ctx,
+		cancel:      cancel,
+		done:        make(chan bool),
+	}
+
+	// at most 10 messages are received at once
+	if opt.MaxInFlight > 0 && opt.MaxInFlight <= 10 {
+		sub.maxMessages = int64(opt.MaxInFlight)
+	}
+
+	if opt.Context == nil {
+		return sub
+	}
+
+	if d, ok := opt.Context.Value(waitTimeKey{}).(time.Duration); ok {
+		sub.waitTime = int64(d.Seconds())
+	}
+	if d, ok := opt.Context.Value(visibilityTimeoutKey{}).(time.Duration); ok {
+		sub.visibilityTimeout = int64(d.Seconds())
+	}
+	if n, ok := opt.Context.Value(maxMessagesKey{}).(int); ok && n > 0 && n <= 10 {
+		sub.maxMessages = int64(n)
+	}
+
+	return sub
+}
+
+// NewBroker returns a sns/sqs broker
+func NewBroker(opts ...broker.Option) broker.Broker {
+	options := broker.Options{
+		Codec:   json.Marshaler{},
+		Context: context.Background(),
+	}
+
+	for _, o := range opts {
+		o(&options)
+	}
+
+	return &snsBroker{
+		opts:   options,
+		topics: make(map[string]string),
+	}
+}
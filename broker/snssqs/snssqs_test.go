@@ -0,0 +1,51 @@
+package snssqs
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/micro/go-micro/v2/broker"
+)
+
+func TestNames(t *testing.T) {
+	b := NewBroker().(*snsBroker)
+	if name := b.topicName("go.micro.events"); name != "go-micro-events" {
+		t.Fatalf("Unexpected topic name %s", name)
+	}
+
+	b = NewBroker(FIFO()).(*snsBroker)
+	if name := b.topicName("go.micro.events"); name != "go-micro-events.fifo" {
+		t.Fatalf("Unexpected fifo topic name %s", name)
+	}
+
+	name := b.queueName(strings.Repeat("a", 100) + "-unique")
+	if len(name) != maxQueueName || !strings.HasSuffix(name, "-unique.fifo") {
+		t.Fatalf("Unexpected queue name %s", name)
+	}
+}
+
+func TestQueuePolicy(t *testing.T) {
+	policy := queuePolicy("arn:aws:sqs:eu-west-1:123:queue", "arn:aws:sns:eu-west-1:123:topic")
+	if !strings.Contains(policy, `"aws:SourceArn":"arn:aws:sns:eu-west-1:123:*"`) {
+		t.Fatalf("Expected the policy to allow the topics of the account got %s", policy)
+	}
+}
+
+func TestSubscriberOptions(t *testing.T) {
+	b := NewBroker().(*snsBroker)
+
+	s := newSubscriber(b, "events", nil, broker.NewSubscribeOptions())
+	if s.waitTime != 20 || s.maxMessages != 10 || s.visibilityTimeout != 0 {
+		t.Fatalf("Unexpected defaults %d %d %d", s.waitTime, s.maxMessages, s.visibilityTimeout)
+	}
+
+	s = newSubscriber(b, "events", nil, broker.NewSubscribeOptions(
+		WaitTime(5*time.Second),
+		VisibilityTimeout(time.Minute),
+		MaxMessages(5),
+	))
+	if s.waitTime != 5 || s.maxMessages != 5 || s.visibilityTimeout != 60 {
+		t.Fatalf("Unexpected options %d %d %d", s.waitTime, s.maxMessages, s.visibilityTimeout)
+	}
+}
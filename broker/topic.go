@@ -0,0 +1,57 @@
+package broker
+
+import (
+	"errors"
+	"strings"
+)
+
+var (
+	// ErrPatternNotSupported is returned when subscribing to a topic pattern
+	// with a broker which can't match topics
+	ErrPatternNotSupported = errors.New("topic patterns not supported")
+)
+
+// IsPattern returns true if the topic is a pattern matching other topics.
+// Topics are dot separated words, in a pattern "*" matches exactly one word
+// and "#" matches zero or more words e.g "orders.*" or "audit.#".
+func IsPattern(topic string) bool {
+	for _, w := range strings.Split(topic, ".") {
+		if w == "*" || w == "#" {
+			return true
+		}
+	}
+	return false
+}
+
+// MatchTopic returns true if the topic matches the pattern. A pattern without
+// wildcards only matches itself.
+func MatchTopic(pattern, topic string) bool {
+	if pattern == topic {
+		return true
+	}
+	return match(strings.Split(pattern, "."), strings.Split(topic, "."))
+}
+
+func match(pattern, topic []string) bool {
+	for i, w := range pattern {
+		switch w {
+		case "#":
+			// the rest of the pattern matches any of the remaining words
+			for j := i; j <= len(topic); j++ {
+				if match(pattern[i+1:], topic[j:]) {
+					return true
+				}
+			}
+			return false
+		case "*":
+			if i >= len(topic) {
+				return false
+			}
+		default:
+			if i >= len(topic) || topic[i] != w {
+				return false
+			}
+		}
+	}
+	return len(pattern) == len(topic)
+}
@@ -0,0 +1,38 @@
+package broker
+
+import "testing"
+
+func TestMatchTopic(t *testing.T) {
+	testData := []struct {
+		pattern string
+		topic   string
+		match   bool
+	}{
+		{"orders", "orders", true},
+		{"orders", "orders.created", false},
+		{"orders.*", "orders.created", true},
+		{"orders.*", "orders", false},
+		{"orders.*", "orders.created.eu", false},
+		{"orders.*.eu", "orders.created.eu", true},
+		{"orders.*.eu", "orders.created.us", false},
+		{"audit.#", "audit", true},
+		{"audit.#", "audit.login", true},
+		{"audit.#", "audit.login.failed", true},
+		{"audit.#", "orders.created", false},
+		{"#.failed", "audit.login.failed", true},
+		{"#.failed", "audit.login", false},
+		{"audit.#.failed", "audit.failed", true},
+		{"audit.#.failed", "audit.login.user.failed", true},
+		{"#", "anything.at.all", true},
+	}
+
+	for _, d := range testData {
+		if m := MatchTopic(d.pattern, d.topic); m != d.match {
+			t.Fatalf("Expected %s matching %s to be %v got %v", d.pattern, d.topic, d.match, m)
+		}
+	}
+
+	if IsPattern("orders.created") || !IsPattern("orders.*") || !IsPattern("audit.#") {
+		t.Fatal("Expected only topics with wildcard words to be patterns")
+	}
+}
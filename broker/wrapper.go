@@ -0,0 +1,56 @@
+package broker
+
+// PublishFunc publishes a message to a topic. It's used primarily for the wrappers.
+type PublishFunc func(topic string, msg *Message, opts ...PublishOption) error
+
+// PublishWrapper wraps the PublishFunc and returns the equivalent
+type PublishWrapper func(PublishFunc) PublishFunc
+
+// HandlerWrapper wraps the Handler of a subscription and returns the equivalent
+type HandlerWrapper func(Handler) Handler
+
+// Wrapper wraps a broker and returns a broker
+type Wrapper func(Broker) Broker
+
+type wrapper struct {
+	Broker
+	publish PublishFunc
+	wrap    []HandlerWrapper
+}
+
+func (w *wrapper) Publish(topic string, msg *Message, opts ...PublishOption) error {
+	return w.publish(topic, msg, opts...)
+}
+
+func (w *wrapper) Subscribe(topic string, h Handler, opts ...SubscribeOption) (Subscriber, error) {
+	// apply in reverse so the first wrapper is called first
+	for i := len(w.wrap); i > 0; i-- {
+		h = w.wrap[i-1](h)
+	}
+	return w.Broker.Subscribe(topic, h, opts...)
+}
+
+// WrapPublish returns a Wrapper calling the publish wrappers on Publish
+func WrapPublish(pw ...PublishWrapper) Wrapper {
+	return func(b Broker) Broker {
+		publish := b.Publish
+		for i := len(pw); i > 0; i-- {
+			publish = pw[i-1](publish)
+		}
+		return &wrapper{
+			Broker:  b,
+			publish: publish,
+		}
+	}
+}
+
+// WrapHandler returns a Wrapper wrapping the handlers passed to Subscribe
+func WrapHandler(hw ...HandlerWrapper) Wrapper {
+	return func(b Broker) Broker {
+		return &wrapper{
+			Broker:  b,
+			publish: b.Publish,
+			wrap:    hw,
+		}
+	}
+}
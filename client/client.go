@@ -95,6 +95,9 @@ type MessageOption func(*MessageOptions)
 // RequestOption used by NewRequest
 type RequestOption func(*RequestOptions)
 
+// IdempotencyKeyHeader is the header of the idempotency key of a call
+const IdempotencyKeyHeader = "Micro-Idempotency-Key"
+
 var (
 	// DefaultClient is a default client to use out of the box
 	DefaultClient Client = newRpcClient()
@@ -110,6 +113,8 @@ var (
 	DefaultPoolSize = 100
 	// DefaultPoolTTL sets the connection pool ttl
 	DefaultPoolTTL = time.Minute
+	// DefaultPoolKeepAlive sets the interval idle connections are probed at
+	DefaultPoolKeepAlive = time.Second * 30
 
 	// NewClient returns a new client
 	NewClient func(...Option) Client = newRpcClient
@@ -2,6 +2,9 @@ package client
 
 import (
 	"context"
+	"time"
+
+	"github.com/micro/go-micro/v2/errors"
 )
 
 type clientKey struct{}
@@ -14,3 +17,26 @@ func FromContext(ctx context.Context) (Client, bool) {
 func NewContext(ctx context.Context, c Client) context.Context {
 	return context.WithValue(ctx, clientKey{}, c)
 }
+
+// RemainingTimeout returns the time remaining before the deadline of the
+// context. Servers set the deadline of the caller on the context of a
+// request so calls made with it share what's left of the caller's timeout.
+func RemainingTimeout(ctx context.Context) (time.Duration, bool) {
+	d, ok := ctx.Deadline()
+	if !ok {
+		return 0, false
+	}
+	return time.Until(d), true
+}
+
+// callTimeout returns the timeout of a call sent with the context
+func callTimeout(ctx context.Context, timeout time.Duration) (time.Duration, error) {
+	d, ok := RemainingTimeout(ctx)
+	if !ok {
+		return timeout, nil
+	}
+	if d <= 0 {
+		return 0, errors.Timeout("go.micro.client", "deadline exceeded")
+	}
+	return d, nil
+}
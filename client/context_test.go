@@ -0,0 +1,34 @@
+package client
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/micro/go-micro/v2/errors"
+)
+
+func TestCallTimeout(t *testing.T) {
+	// no deadline uses the request timeout
+	d, err := callTimeout(context.Background(), time.Second)
+	if err != nil || d != time.Second {
+		t.Fatalf("Expected the request timeout got %v %v", d, err)
+	}
+
+	// the remaining time of the deadline is propagated
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	d, err = callTimeout(ctx, time.Minute)
+	if err != nil || d <= 0 || d > 100*time.Millisecond {
+		t.Fatalf("Expected the remaining time of the deadline got %v %v", d, err)
+	}
+
+	// the deadline passed
+	ctx, cancel = context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+	defer cancel()
+
+	if _, err := callTimeout(ctx, time.Minute); err == nil || errors.FromError(err).Code != 408 {
+		t.Fatalf("Expected a timeout error got %v", err)
+	}
+}
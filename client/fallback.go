@@ -0,0 +1,11 @@
+package client
+
+import (
+	"context"
+)
+
+// FallbackFunc is called with the error of a call which failed once its
+// retries are exhausted or it was rejected e.g by the limits of the service.
+// It can set a degraded response, such as cached data or defaults, and
+// return nil or return the error of the call.
+type FallbackFunc func(ctx context.Context, req Request, rsp interface{}, err error) error
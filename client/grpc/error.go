@@ -1,7 +1,10 @@
 package grpc
 
 import (
+	"net/http"
+
 	"github.com/micro/go-micro/v2/errors"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 )
 
@@ -12,7 +15,7 @@ func microError(err error) error {
 		return nil
 	}
 
-	if verr, ok := err.(*errors.Error); ok {
+	if verr, ok := errors.As(err); ok {
 		return verr
 	}
 
@@ -23,8 +26,10 @@ func microError(err error) error {
 	}
 
 	// return first error from details
-	if details := s.Details(); len(details) > 0 {
-		return microError(details[0].(error))
+	for _, detail := range s.Details() {
+		if derr, ok := detail.(error); ok {
+			return microError(derr)
+		}
 	}
 
 	// try to decode micro *errors.Error
@@ -32,6 +37,41 @@ func microError(err error) error {
 		return e // actually a micro error
 	}
 
+	// keep the code of the grpc error
+	if code := microCode(s.Code()); code != http.StatusInternalServerError {
+		return errors.New("go.micro.client", s.Message(), code)
+	}
+
 	// fallback
 	return errors.InternalServerError("go.micro.client", s.Message())
 }
+
+// microCode returns the code of the errors with the grpc code
+func microCode(code codes.Code) int32 {
+	switch code {
+	case codes.OK:
+		return http.StatusOK
+	case codes.InvalidArgument, codes.OutOfRange:
+		return http.StatusBadRequest
+	case codes.DeadlineExceeded:
+		return http.StatusRequestTimeout
+	case codes.NotFound:
+		return http.StatusNotFound
+	case codes.AlreadyExists, codes.Aborted:
+		return http.StatusConflict
+	case codes.PermissionDenied:
+		return http.StatusForbidden
+	case codes.Unauthenticated:
+		return http.StatusUnauthorized
+	case codes.FailedPrecondition:
+		return http.StatusPreconditionFailed
+	case codes.Unimplemented:
+		return http.StatusNotImplemented
+	case codes.ResourceExhausted:
+		return http.StatusTooManyRequests
+	case codes.Unavailable:
+		return http.StatusServiceUnavailable
+	}
+
+	return http.StatusInternalServerError
+}
@@ -15,12 +15,14 @@ import (
 	"github.com/micro/go-micro/v2/client"
 	"github.com/micro/go-micro/v2/client/selector"
 	raw "github.com/micro/go-micro/v2/codec/bytes"
+	"github.com/micro/go-micro/v2/debug/trace"
 	"github.com/micro/go-micro/v2/errors"
 	"github.com/micro/go-micro/v2/metadata"
 	"github.com/micro/go-micro/v2/registry"
 	pnet "github.com/micro/go-micro/v2/util/net"
 
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/connectivity"
 	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/encoding"
 	gmetadata "google.golang.org/grpc/metadata"
@@ -111,8 +113,16 @@ func (g *grpcClient) call(ctx context.Context, node *registry.Node, req client.R
 		header = make(map[string]string)
 	}
 
-	// set timeout in nanoseconds
-	header["timeout"] = fmt.Sprintf("%d", opts.RequestTimeout)
+	// set the time remaining before the deadline in nanoseconds
+	// so the server gives up along with the caller
+	timeout := opts.RequestTimeout
+	if d, ok := client.RemainingTimeout(ctx); ok {
+		if d <= 0 {
+			return errors.Timeout("go.micro.client", "deadline exceeded")
+		}
+		timeout = d
+	}
+	header["timeout"] = fmt.Sprintf("%d", timeout)
 	// set the content type for the request
 	header["x-content-type"] = req.ContentType()
 
@@ -153,6 +163,9 @@ func (g *grpcClient) call(ctx context.Context, node *registry.Node, req client.R
 
 	ch := make(chan error, 1)
 
+	// the response headers, only read once the call returned
+	var rspHeader gmetadata.MD
+
 	go func() {
 		grpcCallOptions := []grpc.CallOption{
 			grpc.ForceCodec(cf),
@@ -160,6 +173,10 @@ func (g *grpcClient) call(ctx context.Context, node *registry.Node, req client.R
 		if opts := g.getGrpcCallOptions(); opts != nil {
 			grpcCallOptions = append(grpcCallOptions, opts...)
 		}
+		// read the response headers
+		if opts.ResponseMetadata != nil {
+			grpcCallOptions = append(grpcCallOptions, grpc.Header(&rspHeader))
+		}
 		err := cc.Invoke(ctx, methodToGRPC(req.Service(), req.Endpoint()), req.Body(), rsp, grpcCallOptions...)
 		ch <- microError(err)
 	}()
@@ -167,6 +184,15 @@ func (g *grpcClient) call(ctx context.Context, node *registry.Node, req client.R
 	select {
 	case err := <-ch:
 		grr = err
+		// return the response headers to the caller, not written once the
+		// caller gave up
+		if opts.ResponseMetadata != nil {
+			md := make(metadata.Metadata, len(rspHeader))
+			for k, v := range rspHeader {
+				md[k] = strings.Join(v, ",")
+			}
+			*opts.ResponseMetadata = md
+		}
 	case <-ctx.Done():
 		grr = errors.Timeout("go.micro.client", "%v", ctx.Err())
 	}
@@ -363,9 +389,53 @@ func (g *grpcClient) Init(opts ...client.Option) error {
 		g.pool.Unlock()
 	}
 
+	if len(g.opts.Warmup) > 0 {
+		go client.Warm(g.opts, g.dial)
+	}
+
 	return nil
 }
 
+// dial establishes a pooled connection to the node
+func (g *grpcClient) dial(service string, node *registry.Node) error {
+	grpcDialOptions := []grpc.DialOption{
+		grpc.WithTimeout(g.opts.CallOptions.DialTimeout),
+		g.secure(node.Address),
+		grpc.WithDefaultCallOptions(
+			grpc.MaxCallRecvMsgSize(g.maxRecvMsgSizeValue()),
+			grpc.MaxCallSendMsgSize(g.maxSendMsgSizeValue()),
+		),
+	}
+
+	if opts := g.getGrpcDialOptions(); opts != nil {
+		grpcDialOptions = append(grpcDialOptions, opts...)
+	}
+
+	cc, err := g.pool.getConn(node.Address, grpcDialOptions...)
+	if err != nil {
+		return err
+	}
+
+	// wait for the connection to be established
+	ctx, cancel := context.WithTimeout(context.Background(), g.opts.CallOptions.DialTimeout)
+	defer cancel()
+
+	for {
+		state := cc.GetState()
+		if state == connectivity.Ready {
+			break
+		}
+		if !cc.WaitForStateChange(ctx, state) {
+			err = errors.Timeout("go.micro.client", "connecting to %s: %v", node.Address, ctx.Err())
+			break
+		}
+	}
+
+	g.pool.release(node.Address, cc, err)
+
+	return err
+}
+
 func (g *grpcClient) Options() client.Options {
 	return g.opts
 }
@@ -378,7 +448,7 @@ func (g *grpcClient) NewRequest(service, method string, req interface{}, reqOpts
 	return newGRPCRequest(service, method, req, g.opts.ContentType, reqOpts...)
 }
 
-func (g *grpcClient) Call(ctx context.Context, req client.Request, rsp interface{}, opts ...client.CallOption) error {
+func (g *grpcClient) Call(ctx context.Context, req client.Request, rsp interface{}, opts ...client.CallOption) (err error) {
 	if req == nil {
 		return errors.InternalServerError("go.micro.client", "req is nil")
 	} else if rsp == nil {
@@ -390,6 +460,21 @@ func (g *grpcClient) Call(ctx context.Context, req client.Request, rsp interface
 		opt(&callOpts)
 	}
 
+	// degrade the failed call with the fallback
+	if callOpts.Fallback != nil {
+		fctx := ctx
+		defer func() {
+			if err != nil {
+				err = callOpts.Fallback(fctx, req, rsp, err)
+			}
+		}()
+	}
+
+	// the retries of the call share the idempotency key
+	if len(callOpts.IdempotencyKey) > 0 {
+		ctx = metadata.Set(ctx, client.IdempotencyKeyHeader, callOpts.IdempotencyKey)
+	}
+
 	next, err := g.next(req, callOpts)
 	if err != nil {
 		return err
@@ -425,11 +510,10 @@ func (g *grpcClient) Call(ctx context.Context, req client.Request, rsp interface
 	}
 
 	// return errors.New("go.micro.client", "request timeout", 408)
-	call := func(i int) error {
-		// call backoff first. Someone may want an initial start delay
-		t, err := callOpts.Backoff(ctx, req, i)
-		if err != nil {
-			return errors.InternalServerError("go.micro.client", err.Error())
+	call := func(t time.Duration, prev error) error {
+		// the server may ask to wait longer when rate limiting or shedding load
+		if d := client.RetryDelay(prev); d > t {
+			t = d
 		}
 
 		// only sleep if greater than 0
@@ -437,9 +521,16 @@ func (g *grpcClient) Call(ctx context.Context, req client.Request, rsp interface
 			time.Sleep(t)
 		}
 
+		// limit the calls to the service
+		service := req.Service()
+		release, err := g.opts.Limiter.Acquire(ctx, service)
+		if err != nil {
+			return err
+		}
+		defer release()
+
 		// select next node
 		node, err := next()
-		service := req.Service()
 		if err != nil {
 			if err == selector.ErrNotFound {
 				return errors.InternalServerError("go.micro.client", "service %s: %s", service, err.Error())
@@ -450,6 +541,12 @@ func (g *grpcClient) Call(ctx context.Context, req client.Request, rsp interface
 		// make the call
 		err = gcall(ctx, node, req, rsp, callOpts)
 		g.opts.Selector.Mark(service, node, err)
+
+		// back off from the service which asked to be retried later
+		if err != nil {
+			g.opts.Limiter.Throttle(service, errors.RetryAfter(errors.FromError(err)))
+		}
+
 		if verr, ok := err.(*errors.Error); ok {
 			return verr
 		}
@@ -457,13 +554,22 @@ func (g *grpcClient) Call(ctx context.Context, req client.Request, rsp interface
 		return err
 	}
 
-	ch := make(chan error, callOpts.Retries+1)
+	// call backoff first. Someone may want an initial start delay
+	delay, err := callOpts.Backoff(ctx, req, 0)
+	if err != nil {
+		return errors.InternalServerError("go.micro.client", err.Error())
+	}
+
+	policy := client.CallRetryPolicy(callOpts)
+	start := time.Now()
+
+	ch := make(chan error, 1)
 	var gerr error
 
-	for i := 0; i <= callOpts.Retries; i++ {
-		go func(i int) {
-			ch <- call(i)
-		}(i)
+	for i := 0; ; i++ {
+		go func(delay time.Duration, prev error) {
+			ch <- call(delay, prev)
+		}(delay, gerr)
 
 		select {
 		case <-ctx.Done():
@@ -474,7 +580,7 @@ func (g *grpcClient) Call(ctx context.Context, req client.Request, rsp interface
 				return nil
 			}
 
-			retry, rerr := callOpts.Retry(ctx, req, i, err)
+			retry, d, rerr := policy.Retry(ctx, req, i, time.Since(start), err)
 			if rerr != nil {
 				return rerr
 			}
@@ -483,11 +589,9 @@ func (g *grpcClient) Call(ctx context.Context, req client.Request, rsp interface
 				return err
 			}
 
-			gerr = err
+			delay, gerr = d, err
 		}
 	}
-
-	return gerr
 }
 
 func (g *grpcClient) Stream(ctx context.Context, req client.Request, opts ...client.CallOption) (client.Stream, error) {
@@ -497,6 +601,11 @@ func (g *grpcClient) Stream(ctx context.Context, req client.Request, opts ...cli
 		opt(&callOpts)
 	}
 
+	// reconnect the stream when its connection fails
+	if callOpts.Resume != nil {
+		return client.NewResumeStream(ctx, g, req, opts...)
+	}
+
 	next, err := g.next(req, callOpts)
 	if err != nil {
 		return nil, err
@@ -519,11 +628,10 @@ func (g *grpcClient) Stream(ctx context.Context, req client.Request, opts ...cli
 		gstream = callOpts.CallWrappers[i-1](gstream)
 	}
 
-	call := func(i int) (client.Stream, error) {
-		// call backoff first. Someone may want an initial start delay
-		t, err := callOpts.Backoff(ctx, req, i)
-		if err != nil {
-			return nil, errors.InternalServerError("go.micro.client", err.Error())
+	call := func(t time.Duration, prev error) (client.Stream, error) {
+		// the server may ask to wait longer when rate limiting or shedding load
+		if d := client.RetryDelay(prev); d > t {
+			t = d
 		}
 
 		// only sleep if greater than 0
@@ -553,14 +661,23 @@ func (g *grpcClient) Stream(ctx context.Context, req client.Request, opts ...cli
 		err    error
 	}
 
-	ch := make(chan response, callOpts.Retries+1)
+	// call backoff first. Someone may want an initial start delay
+	delay, err := callOpts.Backoff(ctx, req, 0)
+	if err != nil {
+		return nil, errors.InternalServerError("go.micro.client", err.Error())
+	}
+
+	policy := client.CallRetryPolicy(callOpts)
+	start := time.Now()
+
+	ch := make(chan response, 1)
 	var grr error
 
-	for i := 0; i <= callOpts.Retries; i++ {
-		go func(i int) {
-			s, err := call(i)
+	for i := 0; ; i++ {
+		go func(delay time.Duration, prev error) {
+			s, err := call(delay, prev)
 			ch <- response{s, err}
-		}(i)
+		}(delay, grr)
 
 		select {
 		case <-ctx.Done():
@@ -571,7 +688,7 @@ func (g *grpcClient) Stream(ctx context.Context, req client.Request, opts ...cli
 				return rsp.stream, nil
 			}
 
-			retry, rerr := callOpts.Retry(ctx, req, i, err)
+			retry, d, rerr := policy.Retry(ctx, req, i, time.Since(start), rsp.err)
 			if rerr != nil {
 				return nil, rerr
 			}
@@ -580,11 +697,9 @@ func (g *grpcClient) Stream(ctx context.Context, req client.Request, opts ...cli
 				return nil, rsp.err
 			}
 
-			grr = rsp.err
+			delay, grr = d, rsp.err
 		}
 	}
-
-	return nil, grr
 }
 
 func (g *grpcClient) Publish(ctx context.Context, p client.Message, opts ...client.PublishOption) error {
@@ -600,6 +715,9 @@ func (g *grpcClient) Publish(ctx context.Context, p client.Message, opts ...clie
 	md["Content-Type"] = p.ContentType()
 	md["Micro-Topic"] = p.Topic()
 
+	// propagate the trace context and request id
+	trace.Inject(ctx, md)
+
 	cf, err := g.newGRPCCodec(p.ContentType())
 	if err != nil {
 		return errors.InternalServerError("go.micro.client", err.Error())
@@ -636,7 +754,12 @@ func (g *grpcClient) Publish(ctx context.Context, p client.Message, opts ...clie
 	return g.opts.Broker.Publish(topic, &broker.Message{
 		Header: md,
 		Body:   body,
-	}, broker.PublishContext(options.Context))
+	},
+		broker.PublishContext(options.Context),
+		broker.Schedule(options.DeliverAt),
+		broker.Compress(options.Compression),
+		broker.PartitionKey(options.Key),
+	)
 }
 
 func (g *grpcClient) String() string {
@@ -699,6 +822,10 @@ func newClient(opts ...client.Option) client.Client {
 
 	rc.pool = newPool(options.PoolSize, options.PoolTTL, rc.poolMaxIdle(), rc.poolMaxStreams())
 
+	if len(options.Warmup) > 0 {
+		go client.Warm(options, rc.dial)
+	}
+
 	c := client.Client(rc)
 
 	// wrap in reverse
@@ -0,0 +1,188 @@
+package client
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/micro/go-micro/v2/errors"
+	"golang.org/x/time/rate"
+)
+
+// Limit is the limit of the calls made to a service
+type Limit struct {
+	// Rate is the max number of calls per second, zero is unlimited
+	Rate float64
+	// Burst is the number of calls which can be made at once above the rate
+	Burst int
+	// MaxInFlight is the max number of calls in progress, zero is unlimited
+	MaxInFlight int
+	// Wait queues the calls above the limit until they're allowed or their
+	// context is done rather than rejecting them with a 429 error
+	Wait bool
+}
+
+// Limiter enforces the limits of the calls made to each service
+type Limiter struct {
+	sync.Mutex
+	limits   map[string]Limit
+	services map[string]*serviceLimiter
+	// services throttled until the time
+	throttled map[string]time.Time
+}
+
+type serviceLimiter struct {
+	limit    Limit
+	rate     *rate.Limiter
+	inflight chan struct{}
+}
+
+// Set the limit of the calls to the service. The limit
+// of the "*" service applies to services without one.
+func (l *Limiter) Set(service string, limit Limit) {
+	l.Lock()
+	defer l.Unlock()
+	l.limits[service] = limit
+	delete(l.services, service)
+	if service == "*" {
+		l.services = make(map[string]*serviceLimiter)
+	}
+}
+
+func (l *Limiter) get(service string) *serviceLimiter {
+	l.Lock()
+	defer l.Unlock()
+
+	if s, ok := l.services[service]; ok {
+		return s
+	}
+
+	limit, ok := l.limits[service]
+	if !ok {
+		limit, ok = l.limits["*"]
+	}
+
+	// services without a limit are stored as nil
+	var s *serviceLimiter
+	if ok {
+		s = &serviceLimiter{limit: limit}
+		if limit.Rate > 0 {
+			burst := limit.Burst
+			if burst < 1 {
+				burst = 1
+			}
+			s.rate = rate.NewLimiter(rate.Limit(limit.Rate), burst)
+		}
+		if limit.MaxInFlight > 0 {
+			s.inflight = make(chan struct{}, limit.MaxInFlight)
+		}
+	}
+	l.services[service] = s
+
+	return s
+}
+
+// Throttle the calls to the service for the duration, as asked by a
+// service rate limiting the client or shedding load with a retry after
+func (l *Limiter) Throttle(service string, d time.Duration) {
+	if l == nil || d <= 0 {
+		return
+	}
+
+	l.Lock()
+	defer l.Unlock()
+
+	until := time.Now().Add(d)
+	if t, ok := l.throttled[service]; !ok || t.Before(until) {
+		l.throttled[service] = until
+	}
+}
+
+// throttle returns the time left before the calls to the service resume
+func (l *Limiter) throttle(service string) time.Duration {
+	l.Lock()
+	defer l.Unlock()
+
+	t, ok := l.throttled[service]
+	if !ok {
+		return 0
+	}
+	d := time.Until(t)
+	if d <= 0 {
+		delete(l.throttled, service)
+	}
+	return d
+}
+
+func noRelease() {}
+
+// Acquire the permission to call the service. The func returned
+// releases it once the call is done.
+func (l *Limiter) Acquire(ctx context.Context, service string) (func(), error) {
+	if l == nil {
+		return noRelease, nil
+	}
+
+	s := l.get(service)
+
+	// the service asked to wait before calling it again
+	if d := l.throttle(service); d > 0 {
+		if s == nil || !s.limit.Wait {
+			return nil, errors.TooManyRequests("go.micro.client", d, "%s throttled", service)
+		}
+		t := time.NewTimer(d)
+		select {
+		case <-t.C:
+		case <-ctx.Done():
+			t.Stop()
+			return nil, errors.Timeout("go.micro.client", "%s throttled: %v", service, ctx.Err())
+		}
+	}
+
+	if s == nil {
+		return noRelease, nil
+	}
+
+	if s.rate != nil {
+		if s.limit.Wait {
+			if err := s.rate.Wait(ctx); err != nil {
+				return nil, errors.Timeout("go.micro.client", "rate limit of %s: %v", service, err)
+			}
+		} else if r := s.rate.Reserve(); !r.OK() || r.Delay() > 0 {
+			d := r.Delay()
+			r.Cancel()
+			return nil, errors.TooManyRequests("go.micro.client", d, "rate limit of %s exceeded", service)
+		}
+	}
+
+	if s.inflight == nil {
+		return noRelease, nil
+	}
+
+	if s.limit.Wait {
+		select {
+		case s.inflight <- struct{}{}:
+		case <-ctx.Done():
+			return nil, errors.Timeout("go.micro.client", "max in flight calls of %s: %v", service, ctx.Err())
+		}
+	} else {
+		select {
+		case s.inflight <- struct{}{}:
+		default:
+			return nil, errors.TooManyRequests("go.micro.client", 0, "max in flight calls of %s exceeded", service)
+		}
+	}
+
+	return func() {
+		<-s.inflight
+	}, nil
+}
+
+// NewLimiter returns a limiter without any limits
+func NewLimiter() *Limiter {
+	return &Limiter{
+		limits:    make(map[string]Limit),
+		services:  make(map[string]*serviceLimiter),
+		throttled: make(map[string]time.Time),
+	}
+}
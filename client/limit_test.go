@@ -0,0 +1,106 @@
+package client
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/micro/go-micro/v2/errors"
+)
+
+func TestLimiterRate(t *testing.T) {
+	l := NewLimiter()
+	l.Set("foo", Limit{Rate: 1})
+	l.Set("bar", Limit{Rate: 100, Wait: true})
+
+	if _, err := l.Acquire(context.TODO(), "foo"); err != nil {
+		t.Fatalf("Unexpected err: %v", err)
+	}
+
+	// rejected above the rate
+	_, err := l.Acquire(context.TODO(), "foo")
+	if e := errors.FromError(err); e.Code != 429 || e.RetryAfter <= 0 {
+		t.Fatalf("Expected a 429 error with a retry after got %v", err)
+	}
+
+	// services without a limit
+	if _, err := l.Acquire(context.TODO(), "baz"); err != nil {
+		t.Fatalf("Unexpected err: %v", err)
+	}
+
+	// queued above the rate
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		if _, err := l.Acquire(context.TODO(), "bar"); err != nil {
+			t.Fatalf("Unexpected err: %v", err)
+		}
+	}
+	if time.Since(start) < 15*time.Millisecond {
+		t.Fatal("Expected the calls to wait for the rate")
+	}
+}
+
+func TestLimiterInFlight(t *testing.T) {
+	l := NewLimiter()
+	l.Set("*", Limit{MaxInFlight: 1})
+
+	release, err := l.Acquire(context.TODO(), "foo")
+	if err != nil {
+		t.Fatalf("Unexpected err: %v", err)
+	}
+
+	// rejected while the call is in flight
+	if _, err := l.Acquire(context.TODO(), "foo"); errors.FromError(err).Code != 429 {
+		t.Fatalf("Expected a 429 error got %v", err)
+	}
+
+	// the limit is per service
+	if _, err := l.Acquire(context.TODO(), "bar"); err != nil {
+		t.Fatalf("Unexpected err: %v", err)
+	}
+
+	release()
+	if _, err := l.Acquire(context.TODO(), "foo"); err != nil {
+		t.Fatalf("Unexpected err: %v", err)
+	}
+
+	// queued until the context is done
+	l.Set("baz", Limit{MaxInFlight: 1, Wait: true})
+	if _, err := l.Acquire(context.TODO(), "baz"); err != nil {
+		t.Fatalf("Unexpected err: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.TODO(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := l.Acquire(ctx, "baz"); errors.FromError(err).Code != 408 {
+		t.Fatalf("Expected a 408 error got %v", err)
+	}
+}
+
+func TestLimiterThrottle(t *testing.T) {
+	l := NewLimiter()
+	l.Throttle("foo", 50*time.Millisecond)
+
+	_, err := l.Acquire(context.TODO(), "foo")
+	if verr := errors.FromError(err); verr.Code != 429 || errors.RetryAfter(verr) <= 0 {
+		t.Fatalf("Expected a 429 error with a retry after got %v", err)
+	}
+
+	// the other services aren't throttled
+	if _, err := l.Acquire(context.TODO(), "bar"); err != nil {
+		t.Fatalf("Unexpected err: %v", err)
+	}
+
+	// queued calls wait for the throttle to end
+	l.Set("foo", Limit{Wait: true})
+	start := time.Now()
+	if _, err := l.Acquire(context.TODO(), "foo"); err != nil {
+		t.Fatalf("Unexpected err: %v", err)
+	}
+	if time.Since(start) < 20*time.Millisecond {
+		t.Fatal("Expected the call to wait for the throttle")
+	}
+
+	if _, err := l.Acquire(context.TODO(), "foo"); err != nil {
+		t.Fatalf("Unexpected err: %v", err)
+	}
+}
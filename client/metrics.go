@@ -0,0 +1,55 @@
+package client
+
+import (
+	"context"
+	"time"
+
+	"github.com/micro/go-micro/v2/metrics"
+)
+
+const (
+	// MetricRequests is the count of the requests made by service, endpoint
+	// and status
+	MetricRequests = "micro_client_requests"
+	// MetricRequestDuration is the timing of the requests made including
+	// the retries
+	MetricRequestDuration = "micro_client_request_duration"
+	// MetricPublished is the count of the messages published by topic and
+	// status
+	MetricPublished = "micro_client_published"
+)
+
+type metricsClient struct {
+	Client
+	r metrics.Reporter
+}
+
+func (m *metricsClient) Call(ctx context.Context, req Request, rsp interface{}, opts ...CallOption) error {
+	start := time.Now()
+	err := m.Client.Call(ctx, req, rsp, opts...)
+
+	tags := metrics.Tags{
+		"service":  req.Service(),
+		"endpoint": req.Endpoint(),
+		"status":   metrics.Status(err),
+	}
+	m.r.Count(MetricRequests, 1, tags)
+	m.r.Timing(MetricRequestDuration, time.Since(start), tags)
+
+	return err
+}
+
+func (m *metricsClient) Publish(ctx context.Context, msg Message, opts ...PublishOption) error {
+	err := m.Client.Publish(ctx, msg, opts...)
+	m.r.Count(MetricPublished, 1, metrics.Tags{
+		"topic":  msg.Topic(),
+		"status": metrics.Status(err),
+	})
+	return err
+}
+
+// Instrument returns a client reporting the rate, errors and duration of
+// the requests made and the messages published
+func Instrument(c Client, r metrics.Reporter) Client {
+	return &metricsClient{Client: c, r: r}
+}
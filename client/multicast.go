@@ -0,0 +1,110 @@
+package client
+
+import (
+	"context"
+	"math/rand"
+	"sync"
+
+	"github.com/micro/go-micro/v2/client/selector"
+	"github.com/micro/go-micro/v2/errors"
+	"github.com/micro/go-micro/v2/registry"
+)
+
+// Result is the response or the error of a node called by CallAll or Multicast
+type Result struct {
+	Node     *registry.Node
+	Response interface{}
+	Error    error
+}
+
+// CallAll calls every node of the service of the request at once and returns
+// the result of each node. The response of each node is decoded into a value
+// returned by newRsp. The calls share the deadline of the context, or the
+// request timeout if it has none, so they finish within it.
+func CallAll(ctx context.Context, c Client, req Request, newRsp func() interface{}, opts ...CallOption) ([]*Result, error) {
+	return Multicast(ctx, c, req, 0, newRsp, opts...)
+}
+
+// Multicast calls n random nodes of the service of the request at once, or
+// all of them if n is zero, and returns the result of each node called.
+func Multicast(ctx context.Context, c Client, req Request, n int, newRsp func() interface{}, opts ...CallOption) ([]*Result, error) {
+	callOpts := c.Options().CallOptions
+	for _, o := range opts {
+		o(&callOpts)
+	}
+
+	nodes, err := lookupNodes(c, req.Service(), callOpts)
+	if err != nil {
+		return nil, err
+	}
+
+	if n > 0 && n < len(nodes) {
+		rand.Shuffle(len(nodes), func(i, j int) {
+			nodes[i], nodes[j] = nodes[j], nodes[i]
+		})
+		nodes = nodes[:n]
+	}
+
+	// the overall deadline of the calls
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, callOpts.RequestTimeout)
+		defer cancel()
+	}
+
+	results := make([]*Result, len(nodes))
+
+	var wg sync.WaitGroup
+	for i, node := range nodes {
+		wg.Add(1)
+		go func(i int, node *registry.Node) {
+			defer wg.Done()
+
+			copts := append([]CallOption{}, opts...)
+			copts = append(copts, WithAddress(node.Address))
+
+			rsp := newRsp()
+			err := c.Call(ctx, req, rsp, copts...)
+			results[i] = &Result{
+				Node:     node,
+				Response: rsp,
+				Error:    err,
+			}
+		}(i, node)
+	}
+	wg.Wait()
+
+	return results, nil
+}
+
+// lookupNodes returns the nodes of the service passing the select filters
+func lookupNodes(c Client, service string, opts CallOptions) ([]*registry.Node, error) {
+	reg := c.Options().Registry
+	if reg == nil {
+		return nil, errors.InternalServerError("go.micro.client", "service %s: no registry", service)
+	}
+
+	services, err := reg.GetService(service)
+	if err != nil && err != registry.ErrNotFound {
+		return nil, errors.InternalServerError("go.micro.client", "error getting %s nodes: %s", service, err.Error())
+	}
+
+	var sopts selector.SelectOptions
+	for _, o := range opts.SelectOptions {
+		o(&sopts)
+	}
+	for _, filter := range sopts.Filters {
+		services = filter(services)
+	}
+
+	var nodes []*registry.Node
+	for _, s := range services {
+		nodes = append(nodes, s.Nodes...)
+	}
+
+	if len(nodes) == 0 {
+		return nil, errors.InternalServerError("go.micro.client", "service %s: %s", service, selector.ErrNotFound.Error())
+	}
+
+	return nodes, nil
+}
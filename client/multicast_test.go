@@ -0,0 +1,85 @@
+package client
+
+import (
+	"context"
+	"testing"
+
+	"github.com/micro/go-micro/v2/client/selector"
+	"github.com/micro/go-micro/v2/errors"
+	"github.com/micro/go-micro/v2/registry"
+)
+
+func TestCallAll(t *testing.T) {
+	wrap := func(cf CallFunc) CallFunc {
+		return func(ctx context.Context, node *registry.Node, req Request, rsp interface{}, opts CallOptions) error {
+			if node.Address == "localhost:6666" {
+				return errors.InternalServerError("test.error", "node failed")
+			}
+			*(rsp.(*string)) = node.Address
+			return nil
+		}
+	}
+
+	r := newTestRegistry()
+	c := NewClient(
+		Registry(r),
+		WrapCall(wrap),
+	)
+	c.Options().Selector.Init(selector.Registry(r))
+
+	req := c.NewRequest("foo", "Foo.Bar", nil)
+	newRsp := func() interface{} { return new(string) }
+
+	services, _ := r.GetService("foo")
+	var nodes int
+	for _, s := range services {
+		nodes += len(s.Nodes)
+	}
+
+	results, err := CallAll(context.Background(), c, req, newRsp)
+	if err != nil {
+		t.Fatalf("Unexpected call err: %v", err)
+	}
+	if len(results) != nodes {
+		t.Fatalf("Expected %d results got %d", nodes, len(results))
+	}
+
+	for _, res := range results {
+		if res.Node.Address == "localhost:6666" {
+			if res.Error == nil {
+				t.Fatalf("Expected the error of node %s", res.Node.Id)
+			}
+			continue
+		}
+		if res.Error != nil {
+			t.Fatalf("Unexpected err of node %s: %v", res.Node.Id, res.Error)
+		}
+		if rsp := *(res.Response.(*string)); rsp != res.Node.Address {
+			t.Fatalf("Expected the response of %s got %s", res.Node.Address, rsp)
+		}
+	}
+
+	// call some of the nodes
+	results, err = Multicast(context.Background(), c, req, 2, newRsp)
+	if err != nil {
+		t.Fatalf("Unexpected multicast err: %v", err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("Expected 2 results got %d", len(results))
+	}
+
+	// the select filters apply
+	results, err = CallAll(context.Background(), c, req, newRsp, WithSelectOption(selector.WithFilter(selector.FilterVersion("1.0.0"))))
+	if err != nil {
+		t.Fatalf("Unexpected call err: %v", err)
+	}
+	for _, res := range results {
+		if res.Node.Address != "localhost:9999" {
+			t.Fatalf("Expected the nodes of version 1.0.0 got %s", res.Node.Address)
+		}
+	}
+
+	if _, err := CallAll(context.Background(), c, c.NewRequest("missing", "Foo.Bar", nil), newRsp); err == nil {
+		t.Fatal("Expected an error calling a missing service")
+	}
+}
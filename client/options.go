@@ -7,6 +7,8 @@ import (
 	"github.com/micro/go-micro/v2/broker"
 	"github.com/micro/go-micro/v2/client/selector"
 	"github.com/micro/go-micro/v2/codec"
+	"github.com/micro/go-micro/v2/metadata"
+	"github.com/micro/go-micro/v2/metrics"
 	"github.com/micro/go-micro/v2/registry"
 	"github.com/micro/go-micro/v2/transport"
 )
@@ -26,12 +28,21 @@ type Options struct {
 	Router Router
 
 	// Connection Pool
-	PoolSize int
-	PoolTTL  time.Duration
+	PoolSize        int
+	PoolTTL         time.Duration
+	PoolIdleTimeout time.Duration
+	PoolKeepAlive   time.Duration
+	PoolMetrics     metrics.Reporter
+
+	// Services resolved and connected to on startup
+	Warmup []string
 
 	// Response cache
 	Cache *Cache
 
+	// Limits of the calls to each service
+	Limiter *Limiter
+
 	// Middleware for client
 	Wrappers []Wrapper
 
@@ -56,14 +67,30 @@ type CallOptions struct {
 	DialTimeout time.Duration
 	// Number of Call attempts
 	Retries int
+	// RetryPolicy decides whether and when failed calls are retried.
+	// It replaces the Retries, Retry and Backoff funcs if set.
+	RetryPolicy RetryPolicy
 	// Request/Response timeout
 	RequestTimeout time.Duration
 	// Stream timeout for the stream
 	StreamTimeout time.Duration
+	// Resume reconnects the stream when its connection fails
+	Resume ResumeFunc
+	// StreamWindow is the number of messages the server sends on a stream
+	// before the client receives them, zero is unlimited
+	StreamWindow int
 	// Use the services own auth token
 	ServiceToken bool
 	// Duration to cache the response for
 	CacheExpiry time.Duration
+	// ResponseMetadata is populated with the headers returned by the server
+	ResponseMetadata *metadata.Metadata
+	// IdempotencyKey identifies the call and its retries to the server
+	IdempotencyKey string
+	// Accept is the content types of the response in order of preference
+	Accept []string
+	// Fallback is called when the call fails
+	Fallback FallbackFunc
 
 	// Middleware for low level call func
 	CallWrappers []CallWrapper
@@ -76,6 +103,12 @@ type CallOptions struct {
 type PublishOptions struct {
 	// Exchange is the routing exchange for the message
 	Exchange string
+	// DeliverAt is the time the message is delivered at
+	DeliverAt time.Time
+	// Compression is the content encoding of the message body
+	Compression string
+	// Key is the partition key the message is ordered by
+	Key string
 	// Other options for implementations of the interface
 	// can be stored in a context
 	Context context.Context
@@ -97,6 +130,7 @@ type RequestOptions struct {
 func NewOptions(options ...Option) Options {
 	opts := Options{
 		Cache:       NewCache(),
+		Limiter:     NewLimiter(),
 		Context:     context.Background(),
 		ContentType: DefaultContentType,
 		Codecs:      make(map[string]codec.NewCodec),
@@ -107,12 +141,13 @@ func NewOptions(options ...Option) Options {
 			RequestTimeout: DefaultRequestTimeout,
 			DialTimeout:    transport.DefaultDialTimeout,
 		},
-		PoolSize:  DefaultPoolSize,
-		PoolTTL:   DefaultPoolTTL,
-		Broker:    broker.DefaultBroker,
-		Selector:  selector.DefaultSelector,
-		Registry:  registry.DefaultRegistry,
-		Transport: transport.DefaultTransport,
+		PoolSize:      DefaultPoolSize,
+		PoolTTL:       DefaultPoolTTL,
+		PoolKeepAlive: DefaultPoolKeepAlive,
+		Broker:        broker.DefaultBroker,
+		Selector:      selector.DefaultSelector,
+		Registry:      registry.DefaultRegistry,
+		Transport:     transport.DefaultTransport,
 	}
 
 	for _, o := range options {
@@ -157,6 +192,28 @@ func PoolTTL(d time.Duration) Option {
 	}
 }
 
+// PoolIdleTimeout sets the max time a connection is idle in the pool
+func PoolIdleTimeout(d time.Duration) Option {
+	return func(o *Options) {
+		o.PoolIdleTimeout = d
+	}
+}
+
+// PoolKeepAlive sets the interval idle connections in the pool are probed
+// at to evict broken connections. Zero disables the probes.
+func PoolKeepAlive(d time.Duration) Option {
+	return func(o *Options) {
+		o.PoolKeepAlive = d
+	}
+}
+
+// PoolMetrics reports the idle connections in the pool of each address
+func PoolMetrics(r metrics.Reporter) Option {
+	return func(o *Options) {
+		o.PoolMetrics = r
+	}
+}
+
 // Registry to find nodes for a given service
 func Registry(r registry.Registry) Option {
 	return func(o *Options) {
@@ -217,6 +274,33 @@ func Retry(fn RetryFunc) Option {
 	}
 }
 
+// Warmup resolves the services and connects to their nodes on startup
+// so the first calls to them are not slowed down by the discovery
+func Warmup(services ...string) Option {
+	return func(o *Options) {
+		o.Warmup = services
+	}
+}
+
+// Limits sets the rate limit and the max in flight calls to the service.
+// The limit of the "*" service applies to the services without one.
+func Limits(service string, l Limit) Option {
+	return func(o *Options) {
+		if o.Limiter == nil {
+			o.Limiter = NewLimiter()
+		}
+		o.Limiter.Set(service, l)
+	}
+}
+
+// RetryWith sets the retry policy of calls which
+// replaces the retries, retry and backoff funcs
+func RetryWith(p RetryPolicy) Option {
+	return func(o *Options) {
+		o.CallOptions.RetryPolicy = p
+	}
+}
+
 // The request timeout.
 // Should this be a Call Option?
 func RequestTimeout(d time.Duration) Option {
@@ -248,6 +332,35 @@ func WithExchange(e string) PublishOption {
 	}
 }
 
+// WithDelay delivers the message after the duration
+func WithDelay(d time.Duration) PublishOption {
+	return func(o *PublishOptions) {
+		o.DeliverAt = time.Now().Add(d)
+	}
+}
+
+// WithSchedule delivers the message at the time
+func WithSchedule(t time.Time) PublishOption {
+	return func(o *PublishOptions) {
+		o.DeliverAt = t
+	}
+}
+
+// WithCompression compresses the message body with the content
+// encoding e.g gzip, snappy or zstd
+func WithCompression(encoding string) PublishOption {
+	return func(o *PublishOptions) {
+		o.Compression = encoding
+	}
+}
+
+// WithPartitionKey orders the message after the messages published with the key
+func WithPartitionKey(key string) PublishOption {
+	return func(o *PublishOptions) {
+		o.Key = key
+	}
+}
+
 // PublishContext sets the context in publish options
 func PublishContext(ctx context.Context) PublishOption {
 	return func(o *PublishOptions) {
@@ -268,6 +381,14 @@ func WithSelectOption(so ...selector.SelectOption) CallOption {
 	}
 }
 
+// WithRoutingKey routes the calls with the same key to the same node
+// when the selector balances them by key e.g selector.NewConsistentHash
+func WithRoutingKey(key string) CallOption {
+	return func(o *CallOptions) {
+		o.SelectOptions = append(o.SelectOptions, selector.WithKey(key))
+	}
+}
+
 // WithCallWrapper is a CallOption which adds to the existing CallFunc wrappers
 func WithCallWrapper(cw ...CallWrapper) CallOption {
 	return func(o *CallOptions) {
@@ -299,6 +420,14 @@ func WithRetries(i int) CallOption {
 	}
 }
 
+// WithRetryPolicy is a CallOption which overrides that which
+// set in Options.CallOptions
+func WithRetryPolicy(p RetryPolicy) CallOption {
+	return func(o *CallOptions) {
+		o.RetryPolicy = p
+	}
+}
+
 // WithRequestTimeout is a CallOption which overrides that which
 // set in Options.CallOptions
 func WithRequestTimeout(d time.Duration) CallOption {
@@ -314,6 +443,25 @@ func WithStreamTimeout(d time.Duration) CallOption {
 	}
 }
 
+// WithResume reconnects the stream when its connection fails, retrying as
+// many times as the retries of the call. The stream is resumed with the token
+// of the last message received, returned by fn, in the ResumeHeader so the
+// server sends the messages after it.
+func WithResume(fn ResumeFunc) CallOption {
+	return func(o *CallOptions) {
+		o.Resume = fn
+	}
+}
+
+// WithStreamWindow limits the messages the server sends on the stream to the
+// window until the client receives them, so the messages of slow consumers
+// wait in the server rather than buffering. The server must support it.
+func WithStreamWindow(n int) CallOption {
+	return func(o *CallOptions) {
+		o.StreamWindow = n
+	}
+}
+
 // WithDialTimeout is a CallOption which overrides that which
 // set in Options.CallOptions
 func WithDialTimeout(d time.Duration) CallOption {
@@ -338,6 +486,39 @@ func WithCache(c time.Duration) CallOption {
 	}
 }
 
+// WithResponseMetadata is a CallOption which populates md with
+// the headers returned by the server along with the response
+func WithResponseMetadata(md *metadata.Metadata) CallOption {
+	return func(o *CallOptions) {
+		o.ResponseMetadata = md
+	}
+}
+
+// WithFallback sets the func called with the error of a failed call
+// to return a degraded response in place of the error
+func WithFallback(fn FallbackFunc) CallOption {
+	return func(o *CallOptions) {
+		o.Fallback = fn
+	}
+}
+
+// WithIdempotencyKey sets the key of the call so the servers deduplicating the
+// requests return the result of the first call made with the key to its retries
+func WithIdempotencyKey(key string) CallOption {
+	return func(o *CallOptions) {
+		o.IdempotencyKey = key
+	}
+}
+
+// WithAccept sets the content types the server may respond with in order of
+// preference. The server responds with the content type of the request if it
+// supports none of them.
+func WithAccept(ct ...string) CallOption {
+	return func(o *CallOptions) {
+		o.Accept = ct
+	}
+}
+
 func WithMessageContentType(ct string) MessageOption {
 	return func(o *MessageOptions) {
 		o.ContentType = ct
@@ -0,0 +1,200 @@
+package client
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/micro/go-micro/v2/errors"
+	"github.com/micro/go-micro/v2/metadata"
+)
+
+// ResumeHeader is the header of the resume token of a stream reconnected.
+// Servers resume the stream after the message of the token.
+const ResumeHeader = "Micro-Resume-Token"
+
+// ResumeFunc returns the resume token of a message received on a stream
+type ResumeFunc func(msg interface{}) string
+
+// resumeStream is a stream reconnected when its connection fails
+type resumeStream struct {
+	sync.RWMutex
+	ctx    context.Context
+	req    Request
+	opts   CallOptions
+	resume ResumeFunc
+	dial   func(ctx context.Context) (Stream, error)
+
+	stream Stream
+	// token of the last message received
+	token  string
+	err    error
+	closed bool
+}
+
+// reconnectable returns whether the stream failed with its connection
+func reconnectable(err error) bool {
+	if err == io.ErrUnexpectedEOF || err == errShutdown {
+		return true
+	}
+	if verr, ok := errors.As(err); ok {
+		return verr.Id == "go.micro.client.transport"
+	}
+	return false
+}
+
+func (r *resumeStream) Context() context.Context {
+	return r.ctx
+}
+
+func (r *resumeStream) Request() Request {
+	r.RLock()
+	defer r.RUnlock()
+	return r.stream.Request()
+}
+
+func (r *resumeStream) Response() Response {
+	r.RLock()
+	defer r.RUnlock()
+	return r.stream.Response()
+}
+
+// reconnect the stream failed with err, resuming it after the token
+func (r *resumeStream) reconnect(failed Stream, err error) error {
+	r.Lock()
+	defer r.Unlock()
+
+	if r.closed {
+		return err
+	}
+
+	// reconnected by a concurrent send or recv
+	if r.stream != failed {
+		return nil
+	}
+
+	failed.Close()
+
+	ctx := r.ctx
+	if len(r.token) > 0 {
+		ctx = metadata.Set(ctx, ResumeHeader, r.token)
+	}
+
+	for i := 0; i <= r.opts.Retries; i++ {
+		d, berr := r.opts.Backoff(ctx, r.req, i)
+		if berr != nil {
+			return berr
+		}
+
+		select {
+		case <-r.ctx.Done():
+			return errors.Timeout("go.micro.client", "reconnecting stream: %v", r.ctx.Err())
+		case <-time.After(d):
+		}
+
+		stream, derr := r.dial(ctx)
+		if derr != nil {
+			err = derr
+			continue
+		}
+
+		r.stream = stream
+		return nil
+	}
+
+	r.err = err
+	return err
+}
+
+func (r *resumeStream) current() Stream {
+	r.RLock()
+	defer r.RUnlock()
+	return r.stream
+}
+
+func (r *resumeStream) Send(msg interface{}) error {
+	for {
+		stream := r.current()
+
+		err := stream.Send(msg)
+		if err == nil || !reconnectable(err) {
+			return err
+		}
+
+		if err := r.reconnect(stream, err); err != nil {
+			return err
+		}
+	}
+}
+
+func (r *resumeStream) Recv(msg interface{}) error {
+	for {
+		stream := r.current()
+
+		err := stream.Recv(msg)
+		if err == nil {
+			if token := r.resume(msg); len(token) > 0 {
+				r.Lock()
+				r.token = token
+				r.Unlock()
+			}
+			return nil
+		}
+
+		if !reconnectable(err) {
+			return err
+		}
+
+		if err := r.reconnect(stream, err); err != nil {
+			return err
+		}
+	}
+}
+
+func (r *resumeStream) Error() error {
+	r.RLock()
+	defer r.RUnlock()
+	if r.err != nil {
+		return r.err
+	}
+	return r.stream.Error()
+}
+
+func (r *resumeStream) Close() error {
+	r.Lock()
+	defer r.Unlock()
+	r.closed = true
+	return r.stream.Close()
+}
+
+// NewResumeStream opens a stream with the client which is reconnected when
+// its connection fails, the server resuming it after the last message
+// received. It's used by the clients to support the WithResume option.
+func NewResumeStream(ctx context.Context, c Client, req Request, opts ...CallOption) (Stream, error) {
+	callOpts := c.Options().CallOptions
+	for _, o := range opts {
+		o(&callOpts)
+	}
+
+	// the streams reconnected aren't resumed again
+	opts = append(append([]CallOption{}, opts...), WithResume(nil))
+
+	r := &resumeStream{
+		ctx:    ctx,
+		req:    req,
+		opts:   callOpts,
+		resume: callOpts.Resume,
+		dial: func(ctx context.Context) (Stream, error) {
+			return c.Stream(ctx, req, opts...)
+		},
+	}
+
+	stream, err := r.dial(ctx)
+	if err != nil {
+		return nil, err
+	}
+	r.stream = stream
+
+	return r, nil
+}
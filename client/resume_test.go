@@ -0,0 +1,81 @@
+package client
+
+import (
+	"context"
+	"io"
+	"strconv"
+	"testing"
+
+	"github.com/micro/go-micro/v2/metadata"
+)
+
+type resumeTestStream struct {
+	Stream
+	// messages sent before the connection fails
+	seq, fail int
+}
+
+func (s *resumeTestStream) Recv(msg interface{}) error {
+	if s.seq == s.fail {
+		return io.ErrUnexpectedEOF
+	}
+	*(msg.(*int)) = s.seq
+	s.seq++
+	return nil
+}
+
+func (s *resumeTestStream) Close() error {
+	return nil
+}
+
+type resumeTestClient struct {
+	Client
+	tokens []string
+}
+
+func (c *resumeTestClient) Stream(ctx context.Context, req Request, opts ...CallOption) (Stream, error) {
+	token, _ := metadata.Get(ctx, ResumeHeader)
+	c.tokens = append(c.tokens, token)
+
+	// resume after the token
+	seq := 0
+	if len(token) > 0 {
+		seq, _ = strconv.Atoi(token)
+		seq++
+	}
+	return &resumeTestStream{seq: seq, fail: seq + 3}, nil
+}
+
+func TestResumeStream(t *testing.T) {
+	c := &resumeTestClient{Client: NewClient()}
+	req := c.NewRequest("foo", "Foo.Watch", nil)
+
+	resume := func(msg interface{}) string {
+		return strconv.Itoa(*(msg.(*int)))
+	}
+
+	stream, err := NewResumeStream(context.Background(), c, req, WithResume(resume), WithRetries(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 10; i++ {
+		var seq int
+		if err := stream.Recv(&seq); err != nil {
+			t.Fatal(err)
+		}
+		if seq != i {
+			t.Fatalf("Expected message %d got %d", i, seq)
+		}
+	}
+
+	expected := []string{"", "2", "5", "8"}
+	if len(c.tokens) != len(expected) {
+		t.Fatalf("Expected %d streams got %d", len(expected), len(c.tokens))
+	}
+	for i, token := range expected {
+		if c.tokens[i] != token {
+			t.Fatalf("Expected stream %d resumed with %q got %q", i, token, c.tokens[i])
+		}
+	}
+}
@@ -2,10 +2,129 @@ package client
 
 import (
 	"context"
+	"math/rand"
+	"time"
 
 	"github.com/micro/go-micro/v2/errors"
 )
 
+// RetryPolicy decides whether a failed call is retried and the delay before
+// retrying it. It's used rather than the Retries, Retry and Backoff funcs.
+type RetryPolicy interface {
+	// Retry is called with the error of a failed attempt, counted from 0,
+	// and the time elapsed since the call started. It returns whether the
+	// call is retried and the delay before retrying it. An error fails the
+	// call with the error.
+	Retry(ctx context.Context, req Request, attempt int, elapsed time.Duration, err error) (bool, time.Duration, error)
+}
+
+// retryFuncs is the retry policy of the Retries, Retry and Backoff funcs
+type retryFuncs struct {
+	opts CallOptions
+}
+
+func (r *retryFuncs) Retry(ctx context.Context, req Request, attempt int, elapsed time.Duration, err error) (bool, time.Duration, error) {
+	retry, rerr := r.opts.Retry(ctx, req, attempt, err)
+	if rerr != nil {
+		return false, 0, rerr
+	}
+	if !retry || attempt >= r.opts.Retries {
+		return false, 0, nil
+	}
+
+	d, err := r.opts.Backoff(ctx, req, attempt+1)
+	if err != nil {
+		return false, 0, errors.InternalServerError("go.micro.client", "backoff error: %v", err.Error())
+	}
+	return true, d, nil
+}
+
+// CallRetryPolicy returns the retry policy of the call options. The
+// policy of the Retries, Retry and Backoff funcs is used if not set.
+func CallRetryPolicy(o CallOptions) RetryPolicy {
+	if o.RetryPolicy != nil {
+		return o.RetryPolicy
+	}
+	return &retryFuncs{opts: o}
+}
+
+// BackoffPolicy is a retry policy which retries the calls failing with a
+// retryable error after an exponential backoff with jitter until the
+// retries or the budget of the call are exhausted
+type BackoffPolicy struct {
+	// Retries is the max number of retries of a call
+	Retries int
+	// Base is the delay before the first retry which is
+	// doubled on every retry up to the Max delay
+	Base time.Duration
+	Max  time.Duration
+	// Jitter is the share of the delay which is random, from 0 to 1.
+	// It spreads the retries of the clients which failed at once.
+	Jitter float64
+	// Budget is the max time spent on a call including its retries.
+	// Zero leaves the call to be limited by its deadline.
+	Budget time.Duration
+	// Retryable tells which errors are retried, defaults to RetryOnError
+	Retryable RetryFunc
+}
+
+// Delay returns the delay before the retry after the failed attempt
+func (b *BackoffPolicy) Delay(attempt int) time.Duration {
+	d := b.Base
+	for i := 0; i < attempt && d < b.Max; i++ {
+		d *= 2
+	}
+	if b.Max > 0 && d > b.Max {
+		d = b.Max
+	}
+	if b.Jitter > 0 {
+		d -= time.Duration(rand.Float64() * b.Jitter * float64(d))
+	}
+	return d
+}
+
+func (b *BackoffPolicy) Retry(ctx context.Context, req Request, attempt int, elapsed time.Duration, err error) (bool, time.Duration, error) {
+	if attempt >= b.Retries {
+		return false, 0, nil
+	}
+
+	retryable := b.Retryable
+	if retryable == nil {
+		retryable = RetryOnError
+	}
+	retry, rerr := retryable(ctx, req, attempt, err)
+	if rerr != nil || !retry {
+		return false, 0, rerr
+	}
+
+	d := b.Delay(attempt)
+	// the server may ask to wait longer
+	if rd := RetryDelay(err); rd > d {
+		d = rd
+	}
+
+	// don't retry past the budget or deadline of the call
+	if b.Budget > 0 && elapsed+d >= b.Budget {
+		return false, 0, nil
+	}
+	if dl, ok := ctx.Deadline(); ok && time.Until(dl) <= d {
+		return false, 0, nil
+	}
+
+	return true, d, nil
+}
+
+// NewBackoffPolicy returns a backoff policy retrying calls up to the
+// retries after a delay from 100ms doubled up to 10s with 50% jitter
+func NewBackoffPolicy(retries int) *BackoffPolicy {
+	return &BackoffPolicy{
+		Retries: retries,
+		Base:    100 * time.Millisecond,
+		Max:     10 * time.Second,
+		Jitter:  0.5,
+	}
+}
+
 // note that returning either false or a non-nil error will result in the call not being retried
 type RetryFunc func(ctx context.Context, req Request, retryCount int, err error) (bool, error)
 
@@ -14,7 +133,9 @@ func RetryAlways(ctx context.Context, req Request, retryCount int, err error) (b
 	return true, nil
 }
 
-// RetryOnError retries a request on a 500 or timeout error
+// RetryOnError retries a request on a 500 or timeout error. Requests rejected with
+// a 429 or 503 error are retried if the server told the client when to retry and
+// the context deadline is not reached by then.
 func RetryOnError(ctx context.Context, req Request, retryCount int, err error) (bool, error) {
 	if err == nil {
 		return false, nil
@@ -29,7 +150,42 @@ func RetryOnError(ctx context.Context, req Request, retryCount int, err error) (
 	// retry on timeout or internal server error
 	case 408, 500:
 		return true, nil
+	// retry when rate limited or overloaded if the server asked for it
+	case 429, 503:
+		if e.RetryAfter <= 0 {
+			return false, nil
+		}
+		if d, ok := ctx.Deadline(); ok && time.Until(d) < errors.RetryAfter(e) {
+			return false, nil
+		}
+		return true, nil
 	default:
 		return false, nil
 	}
 }
+
+// RetryDelay returns the minimum delay before retrying a request which failed with
+// the error. It's the retry after duration set by the server plus a random share of
+// it up to the load of the server, which spreads the retries of the clients rejected
+// by a busy server rather than all of them retrying at once.
+func RetryDelay(err error) time.Duration {
+	if err == nil {
+		return 0
+	}
+
+	e := errors.FromError(err)
+	d := errors.RetryAfter(e)
+	if d <= 0 {
+		return 0
+	}
+
+	if e.Load > 0 {
+		load := float64(e.Load)
+		if load > 1 {
+			load = 1
+		}
+		d += time.Duration(rand.Float64() * load * float64(d))
+	}
+
+	return d
+}
@@ -0,0 +1,105 @@
+package client
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/micro/go-micro/v2/errors"
+)
+
+func TestRetryOnError(t *testing.T) {
+	req := NewClient().NewRequest("test", "test", nil)
+
+	testData := []struct {
+		err   error
+		retry bool
+	}{
+		{errors.InternalServerError("test", "error"), true},
+		{errors.Timeout("test", "timeout"), true},
+		{errors.NotFound("test", "not found"), false},
+		{errors.New("test", "overloaded", 503), false},
+		{errors.Overloaded("test", time.Millisecond, 0.9, "overloaded"), true},
+		{errors.TooManyRequests("test", time.Millisecond, "rate limited"), true},
+	}
+
+	for _, d := range testData {
+		retry, err := RetryOnError(context.TODO(), req, 0, d.err)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if retry != d.retry {
+			t.Fatalf("Expected retry %v for %v got %v", d.retry, d.err, retry)
+		}
+	}
+
+	// the server asks to retry after the deadline
+	ctx, cancel := context.WithTimeout(context.TODO(), time.Second)
+	defer cancel()
+
+	retry, _ := RetryOnError(ctx, req, 0, errors.TooManyRequests("test", time.Minute, "rate limited"))
+	if retry {
+		t.Fatal("Expected no retry beyond the deadline")
+	}
+}
+
+func TestRetryDelay(t *testing.T) {
+	if d := RetryDelay(errors.InternalServerError("test", "error")); d != 0 {
+		t.Fatalf("Expected no delay got %v", d)
+	}
+
+	for i := 0; i < 10; i++ {
+		d := RetryDelay(errors.Overloaded("test", time.Second, 0.5, "overloaded"))
+		if d < time.Second || d > 1500*time.Millisecond {
+			t.Fatalf("Expected delay between 1s and 1.5s got %v", d)
+		}
+	}
+}
+
+func TestBackoffPolicy(t *testing.T) {
+	req := NewClient().NewRequest("test", "test", nil)
+	err := errors.InternalServerError("test", "error")
+
+	p := &BackoffPolicy{
+		Retries: 3,
+		Base:    10 * time.Millisecond,
+		Max:     30 * time.Millisecond,
+		Jitter:  0.5,
+	}
+
+	testData := []struct {
+		attempt int
+		min     time.Duration
+		max     time.Duration
+	}{
+		{0, 5 * time.Millisecond, 10 * time.Millisecond},
+		{1, 10 * time.Millisecond, 20 * time.Millisecond},
+		{2, 15 * time.Millisecond, 30 * time.Millisecond},
+	}
+
+	for _, d := range testData {
+		retry, delay, rerr := p.Retry(context.TODO(), req, d.attempt, 0, err)
+		if rerr != nil || !retry {
+			t.Fatalf("Expected attempt %d to be retried got %v %v", d.attempt, retry, rerr)
+		}
+		if delay < d.min || delay > d.max {
+			t.Fatalf("Expected delay of attempt %d between %v and %v got %v", d.attempt, d.min, d.max, delay)
+		}
+	}
+
+	// the retries are exhausted
+	if retry, _, _ := p.Retry(context.TODO(), req, 3, 0, err); retry {
+		t.Fatal("Expected no retry after the max retries")
+	}
+
+	// errors which aren't retryable
+	if retry, _, _ := p.Retry(context.TODO(), req, 0, 0, errors.NotFound("test", "not found")); retry {
+		t.Fatal("Expected no retry of a not found error")
+	}
+
+	// the budget of the call is spent
+	p.Budget = 100 * time.Millisecond
+	if retry, _, _ := p.Retry(context.TODO(), req, 0, 95*time.Millisecond, err); retry {
+		t.Fatal("Expected no retry past the budget")
+	}
+}
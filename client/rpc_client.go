@@ -3,6 +3,7 @@ package client
 import (
 	"context"
 	"fmt"
+	"strconv"
 	"sync/atomic"
 	"time"
 
@@ -11,6 +12,7 @@ import (
 	"github.com/micro/go-micro/v2/client/selector"
 	"github.com/micro/go-micro/v2/codec"
 	raw "github.com/micro/go-micro/v2/codec/bytes"
+	"github.com/micro/go-micro/v2/debug/trace"
 	"github.com/micro/go-micro/v2/errors"
 	"github.com/micro/go-micro/v2/metadata"
 	"github.com/micro/go-micro/v2/registry"
@@ -33,7 +35,10 @@ func newRpcClient(opt ...Option) Client {
 	p := pool.NewPool(
 		pool.Size(opts.PoolSize),
 		pool.TTL(opts.PoolTTL),
+		pool.IdleTimeout(opts.PoolIdleTimeout),
+		pool.KeepAlive(opts.PoolKeepAlive),
 		pool.Transport(opts.Transport),
+		pool.Metrics(opts.PoolMetrics),
 	)
 
 	rc := &rpcClient{
@@ -43,6 +48,10 @@ func newRpcClient(opt ...Option) Client {
 	}
 	rc.once.Store(false)
 
+	if len(opts.Warmup) > 0 {
+		go Warm(opts, rc.dial)
+	}
+
 	c := Client(rc)
 
 	// wrap in reverse
@@ -82,16 +91,24 @@ func (r *rpcClient) call(ctx context.Context, node *registry.Node, req Request,
 		}
 	}
 
-	// set timeout in nanoseconds
-	msg.Header["Timeout"] = fmt.Sprintf("%d", opts.RequestTimeout)
+	// set the time remaining before the deadline in nanoseconds
+	// so the server gives up along with the caller
+	timeout, err := callTimeout(ctx, opts.RequestTimeout)
+	if err != nil {
+		return err
+	}
+	msg.Header["Timeout"] = fmt.Sprintf("%d", timeout)
 	// set the content type for the request
 	msg.Header["Content-Type"] = req.ContentType()
 	// set the accept header
-	msg.Header["Accept"] = req.ContentType()
+	msg.Header["Accept"] = accept(req, opts)
 
 	// setup old protocol
 	cf := setupProtocol(msg, node)
 
+	// the codecs of the responses the server negotiates, not the old protocol
+	var newCodec func(string) (codec.NewCodec, error)
+
 	// no codec specified
 	if cf == nil {
 		var err error
@@ -99,10 +116,12 @@ func (r *rpcClient) call(ctx context.Context, node *registry.Node, req Request,
 		if err != nil {
 			return errors.InternalServerError("go.micro.client", err.Error())
 		}
+		newCodec = r.newCodec
 	}
 
 	dOpts := []transport.DialOption{
 		transport.WithStream(),
+		transport.WithService(req.Service()),
 	}
 
 	if opts.DialTimeout >= 0 {
@@ -115,7 +134,7 @@ func (r *rpcClient) call(ctx context.Context, node *registry.Node, req Request,
 	}
 
 	seq := atomic.AddUint64(&r.seq, 1) - 1
-	codec := newRpcCodec(msg, c, cf, "")
+	codec := newRpcCodec(msg, c, cf, "", newCodec)
 
 	rsp := &rpcResponse{
 		socket: c,
@@ -165,6 +184,15 @@ func (r *rpcClient) call(ctx context.Context, node *registry.Node, req Request,
 
 	select {
 	case err := <-ch:
+		// return the response headers to the caller, only once returned
+		// so they're not written after the caller gave up
+		if opts.ResponseMetadata != nil {
+			md := make(metadata.Metadata, len(rsp.header))
+			for k, v := range rsp.header {
+				md[k] = v
+			}
+			*opts.ResponseMetadata = md
+		}
 		return err
 	case <-ctx.Done():
 		grr = errors.Timeout("go.micro.client", fmt.Sprintf("%v", ctx.Err()))
@@ -203,11 +231,18 @@ func (r *rpcClient) stream(ctx context.Context, node *registry.Node, req Request
 	// set the content type for the request
 	msg.Header["Content-Type"] = req.ContentType()
 	// set the accept header
-	msg.Header["Accept"] = req.ContentType()
+	msg.Header["Accept"] = accept(req, opts)
+	// the server sends the messages of the window before waiting for them
+	if opts.StreamWindow > 0 {
+		msg.Header["Micro-Window"] = strconv.Itoa(opts.StreamWindow)
+	}
 
 	// set old codecs
 	cf := setupProtocol(msg, node)
 
+	// the codecs of the responses the server negotiates, not the old protocol
+	var newCodec func(string) (codec.NewCodec, error)
+
 	// no codec specified
 	if cf == nil {
 		var err error
@@ -215,10 +250,12 @@ func (r *rpcClient) stream(ctx context.Context, node *registry.Node, req Request
 		if err != nil {
 			return nil, errors.InternalServerError("go.micro.client", err.Error())
 		}
+		newCodec = r.newCodec
 	}
 
 	dOpts := []transport.DialOption{
 		transport.WithStream(),
+		transport.WithService(req.Service()),
 	}
 
 	if opts.DialTimeout >= 0 {
@@ -235,7 +272,7 @@ func (r *rpcClient) stream(ctx context.Context, node *registry.Node, req Request
 	id := fmt.Sprintf("%v", seq)
 
 	// create codec with stream id
-	codec := newRpcCodec(msg, c, cf, id)
+	codec := newRpcCodec(msg, c, cf, id, newCodec)
 
 	rsp := &rpcResponse{
 		socket: c,
@@ -257,6 +294,8 @@ func (r *rpcClient) stream(ctx context.Context, node *registry.Node, req Request
 		closed: make(chan bool),
 		// signal the end of stream,
 		sendEOS: true,
+		// flow control of the messages received
+		window: opts.StreamWindow,
 		// release func
 		release: func(err error) { c.Close() },
 	}
@@ -295,27 +334,57 @@ func (r *rpcClient) stream(ctx context.Context, node *registry.Node, req Request
 func (r *rpcClient) Init(opts ...Option) error {
 	size := r.opts.PoolSize
 	ttl := r.opts.PoolTTL
+	idle := r.opts.PoolIdleTimeout
+	keepAlive := r.opts.PoolKeepAlive
 	tr := r.opts.Transport
+	poolMetrics := r.opts.PoolMetrics
 
 	for _, o := range opts {
 		o(&r.opts)
 	}
 
 	// update pool configuration if the options changed
-	if size != r.opts.PoolSize || ttl != r.opts.PoolTTL || tr != r.opts.Transport {
+	if size != r.opts.PoolSize || ttl != r.opts.PoolTTL || idle != r.opts.PoolIdleTimeout ||
+		keepAlive != r.opts.PoolKeepAlive || tr != r.opts.Transport || poolMetrics != r.opts.PoolMetrics {
 		// close existing pool
 		r.pool.Close()
 		// create new pool
 		r.pool = pool.NewPool(
 			pool.Size(r.opts.PoolSize),
 			pool.TTL(r.opts.PoolTTL),
+			pool.IdleTimeout(r.opts.PoolIdleTimeout),
+			pool.KeepAlive(r.opts.PoolKeepAlive),
 			pool.Transport(r.opts.Transport),
+			pool.Metrics(r.opts.PoolMetrics),
 		)
 	}
 
+	if len(r.opts.Warmup) > 0 {
+		go Warm(r.opts, r.dial)
+	}
+
 	return nil
 }
 
+// dial establishes a pooled connection to the node
+func (r *rpcClient) dial(service string, node *registry.Node) error {
+	dOpts := []transport.DialOption{
+		transport.WithStream(),
+		transport.WithService(service),
+	}
+
+	if r.opts.CallOptions.DialTimeout >= 0 {
+		dOpts = append(dOpts, transport.WithTimeout(r.opts.CallOptions.DialTimeout))
+	}
+
+	c, err := r.pool.Get(node.Address, dOpts...)
+	if err != nil {
+		return err
+	}
+
+	return r.pool.Release(c, nil)
+}
+
 func (r *rpcClient) Options() Options {
 	return r.opts
 }
@@ -357,13 +426,28 @@ func (r *rpcClient) next(request Request, opts CallOptions) (selector.Next, erro
 	return next, nil
 }
 
-func (r *rpcClient) Call(ctx context.Context, request Request, response interface{}, opts ...CallOption) error {
+func (r *rpcClient) Call(ctx context.Context, request Request, response interface{}, opts ...CallOption) (err error) {
 	// make a copy of call opts
 	callOpts := r.opts.CallOptions
 	for _, opt := range opts {
 		opt(&callOpts)
 	}
 
+	// degrade the failed call with the fallback
+	if callOpts.Fallback != nil {
+		fctx := ctx
+		defer func() {
+			if err != nil {
+				err = callOpts.Fallback(fctx, request, response, err)
+			}
+		}()
+	}
+
+	// the retries of the call share the idempotency key
+	if len(callOpts.IdempotencyKey) > 0 {
+		ctx = metadata.Set(ctx, IdempotencyKeyHeader, callOpts.IdempotencyKey)
+	}
+
 	next, err := r.next(request, callOpts)
 	if err != nil {
 		return err
@@ -399,11 +483,10 @@ func (r *rpcClient) Call(ctx context.Context, request Request, response interfac
 	}
 
 	// return errors.New("go.micro.client", "request timeout", 408)
-	call := func(i int) error {
-		// call backoff first. Someone may want an initial start delay
-		t, err := callOpts.Backoff(ctx, request, i)
-		if err != nil {
-			return errors.InternalServerError("go.micro.client", "backoff error: %v", err.Error())
+	call := func(t time.Duration, prev error) error {
+		// the server may ask to wait longer when rate limiting or shedding load
+		if d := RetryDelay(prev); d > t {
+			t = d
 		}
 
 		// only sleep if greater than 0
@@ -411,9 +494,16 @@ func (r *rpcClient) Call(ctx context.Context, request Request, response interfac
 			time.Sleep(t)
 		}
 
+		// limit the calls to the service
+		service := request.Service()
+		release, err := r.opts.Limiter.Acquire(ctx, service)
+		if err != nil {
+			return err
+		}
+		defer release()
+
 		// select next node
 		node, err := next()
-		service := request.Service()
 		if err != nil {
 			if err == selector.ErrNotFound {
 				return errors.InternalServerError("go.micro.client", "service %s: %s", service, err.Error())
@@ -424,24 +514,37 @@ func (r *rpcClient) Call(ctx context.Context, request Request, response interfac
 		// make the call
 		err = rcall(ctx, node, request, response, callOpts)
 		r.opts.Selector.Mark(service, node, err)
+
+		// back off from the service which asked to be retried later
+		if err != nil {
+			r.opts.Limiter.Throttle(service, errors.RetryAfter(errors.FromError(err)))
+		}
+
 		return err
 	}
 
-	// get the retries
-	retries := callOpts.Retries
-
 	// disable retries when using a proxy
 	if _, _, ok := net.Proxy(request.Service(), callOpts.Address); ok {
-		retries = 0
+		callOpts.Retries = 0
+		callOpts.RetryPolicy = nil
+	}
+
+	// call backoff first. Someone may want an initial start delay
+	delay, err := callOpts.Backoff(ctx, request, 0)
+	if err != nil {
+		return errors.InternalServerError("go.micro.client", "backoff error: %v", err.Error())
 	}
 
-	ch := make(chan error, retries+1)
+	policy := CallRetryPolicy(callOpts)
+	start := time.Now()
+
+	ch := make(chan error, 1)
 	var gerr error
 
-	for i := 0; i <= retries; i++ {
-		go func(i int) {
-			ch <- call(i)
-		}(i)
+	for i := 0; ; i++ {
+		go func(delay time.Duration, prev error) {
+			ch <- call(delay, prev)
+		}(delay, gerr)
 
 		select {
 		case <-ctx.Done():
@@ -452,7 +555,7 @@ func (r *rpcClient) Call(ctx context.Context, request Request, response interfac
 				return nil
 			}
 
-			retry, rerr := callOpts.Retry(ctx, request, i, err)
+			retry, d, rerr := policy.Retry(ctx, request, i, time.Since(start), err)
 			if rerr != nil {
 				return rerr
 			}
@@ -461,11 +564,9 @@ func (r *rpcClient) Call(ctx context.Context, request Request, response interfac
 				return err
 			}
 
-			gerr = err
+			delay, gerr = d, err
 		}
 	}
-
-	return gerr
 }
 
 func (r *rpcClient) Stream(ctx context.Context, request Request, opts ...CallOption) (Stream, error) {
@@ -475,6 +576,11 @@ func (r *rpcClient) Stream(ctx context.Context, request Request, opts ...CallOpt
 		opt(&callOpts)
 	}
 
+	// reconnect the stream when its connection fails
+	if callOpts.Resume != nil {
+		return NewResumeStream(ctx, r, request, opts...)
+	}
+
 	next, err := r.next(request, callOpts)
 	if err != nil {
 		return nil, err
@@ -487,11 +593,10 @@ func (r *rpcClient) Stream(ctx context.Context, request Request, opts ...CallOpt
 	default:
 	}
 
-	call := func(i int) (Stream, error) {
-		// call backoff first. Someone may want an initial start delay
-		t, err := callOpts.Backoff(ctx, request, i)
-		if err != nil {
-			return nil, errors.InternalServerError("go.micro.client", "backoff error: %v", err.Error())
+	call := func(t time.Duration, prev error) (Stream, error) {
+		// the server may ask to wait longer when rate limiting or shedding load
+		if d := RetryDelay(prev); d > t {
+			t = d
 		}
 
 		// only sleep if greater than 0
@@ -518,22 +623,29 @@ func (r *rpcClient) Stream(ctx context.Context, request Request, opts ...CallOpt
 		err    error
 	}
 
-	// get the retries
-	retries := callOpts.Retries
-
 	// disable retries when using a proxy
 	if _, _, ok := net.Proxy(request.Service(), callOpts.Address); ok {
-		retries = 0
+		callOpts.Retries = 0
+		callOpts.RetryPolicy = nil
 	}
 
-	ch := make(chan response, retries+1)
+	// call backoff first. Someone may want an initial start delay
+	delay, err := callOpts.Backoff(ctx, request, 0)
+	if err != nil {
+		return nil, errors.InternalServerError("go.micro.client", "backoff error: %v", err.Error())
+	}
+
+	policy := CallRetryPolicy(callOpts)
+	start := time.Now()
+
+	ch := make(chan response, 1)
 	var grr error
 
-	for i := 0; i <= retries; i++ {
-		go func(i int) {
-			s, err := call(i)
+	for i := 0; ; i++ {
+		go func(delay time.Duration, prev error) {
+			s, err := call(delay, prev)
 			ch <- response{s, err}
-		}(i)
+		}(delay, grr)
 
 		select {
 		case <-ctx.Done():
@@ -544,7 +656,7 @@ func (r *rpcClient) Stream(ctx context.Context, request Request, opts ...CallOpt
 				return rsp.stream, nil
 			}
 
-			retry, rerr := callOpts.Retry(ctx, request, i, rsp.err)
+			retry, d, rerr := policy.Retry(ctx, request, i, time.Since(start), rsp.err)
 			if rerr != nil {
 				return nil, rerr
 			}
@@ -553,11 +665,9 @@ func (r *rpcClient) Stream(ctx context.Context, request Request, opts ...CallOpt
 				return nil, rsp.err
 			}
 
-			grr = rsp.err
+			delay, grr = d, rsp.err
 		}
 	}
-
-	return nil, grr
 }
 
 func (r *rpcClient) Publish(ctx context.Context, msg Message, opts ...PublishOption) error {
@@ -573,6 +683,9 @@ func (r *rpcClient) Publish(ctx context.Context, msg Message, opts ...PublishOpt
 		md = make(map[string]string)
 	}
 
+	// propagate the trace context and request id
+	trace.Inject(ctx, md)
+
 	id := uuid.New().String()
 	md["Content-Type"] = msg.ContentType()
 	md["Micro-Topic"] = msg.Topic()
@@ -601,17 +714,26 @@ func (r *rpcClient) Publish(ctx context.Context, msg Message, opts ...PublishOpt
 		// new buffer
 		b := buf.New(nil)
 
+		hdr := map[string]string{
+			"Micro-Id":    id,
+			"Micro-Topic": msg.Topic(),
+		}
+
 		if err := cf(b).Write(&codec.Message{
 			Target: topic,
 			Type:   codec.Event,
-			Header: map[string]string{
-				"Micro-Id":    id,
-				"Micro-Topic": msg.Topic(),
-			},
+			Header: hdr,
 		}, msg.Payload()); err != nil {
 			return errors.InternalServerError("go.micro.client", err.Error())
 		}
 
+		// the headers set by the codec e.g the payload transforms
+		for k, v := range hdr {
+			if _, ok := md[k]; !ok {
+				md[k] = v
+			}
+		}
+
 		// set the body
 		body = b.Bytes()
 	}
@@ -626,7 +748,12 @@ func (r *rpcClient) Publish(ctx context.Context, msg Message, opts ...PublishOpt
 	return r.opts.Broker.Publish(topic, &broker.Message{
 		Header: md,
 		Body:   body,
-	}, broker.PublishContext(options.Context))
+	},
+		broker.PublishContext(options.Context),
+		broker.Schedule(options.DeliverAt),
+		broker.Compress(options.Compression),
+		broker.PartitionKey(options.Key),
+	)
 }
 
 func (r *rpcClient) NewMessage(topic string, message interface{}, opts ...MessageOption) Message {
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"testing"
+	"time"
 
 	"github.com/micro/go-micro/v2/client/selector"
 	"github.com/micro/go-micro/v2/errors"
@@ -101,6 +102,136 @@ func TestCallRetry(t *testing.T) {
 	}
 }
 
+func TestCallRetryPolicy(t *testing.T) {
+	var called int
+
+	wrap := func(cf CallFunc) CallFunc {
+		return func(ctx context.Context, node *registry.Node, req Request, rsp interface{}, opts CallOptions) error {
+			called++
+			if called < 3 {
+				return errors.InternalServerError("test.error", "retry request")
+			}
+			return nil
+		}
+	}
+
+	r := newTestRegistry()
+	c := NewClient(
+		Registry(r),
+		WrapCall(wrap),
+		RetryWith(&BackoffPolicy{Retries: 2, Base: time.Millisecond}),
+	)
+	c.Options().Selector.Init(selector.Registry(r))
+
+	req := c.NewRequest("test.service", "Test.Endpoint", nil)
+
+	if err := c.Call(context.Background(), req, nil, WithAddress("10.1.10.1")); err != nil {
+		t.Fatal("call with address error", err)
+	}
+	if called != 3 {
+		t.Fatalf("Expected 3 attempts got %d", called)
+	}
+
+	// the retries of the call policy are exhausted
+	called = 0
+	err := c.Call(context.Background(), req, nil, WithAddress("10.1.10.1"), WithRetryPolicy(&BackoffPolicy{Retries: 1}))
+	if err == nil || called != 2 {
+		t.Fatalf("Expected the call to fail after 2 attempts got %d %v", called, err)
+	}
+}
+
+func TestCallFallback(t *testing.T) {
+	var called int
+
+	wrap := func(cf CallFunc) CallFunc {
+		return func(ctx context.Context, node *registry.Node, req Request, rsp interface{}, opts CallOptions) error {
+			called++
+			return errors.InternalServerError("test.error", "failed request")
+		}
+	}
+
+	r := newTestRegistry()
+	c := NewClient(
+		Registry(r),
+		WrapCall(wrap),
+		Retries(2),
+		Backoff(func(ctx context.Context, req Request, attempts int) (time.Duration, error) {
+			return 0, nil
+		}),
+	)
+	c.Options().Selector.Init(selector.Registry(r))
+
+	req := c.NewRequest("test.service", "Test.Endpoint", nil)
+
+	var fallbacks int
+	fallback := func(ctx context.Context, req Request, rsp interface{}, err error) error {
+		fallbacks++
+		if called != 3 {
+			t.Fatalf("Expected the fallback after 3 attempts got %d", called)
+		}
+		*(rsp.(*string)) = "cached"
+		return nil
+	}
+
+	var rsp string
+	if err := c.Call(context.Background(), req, &rsp, WithAddress("10.1.10.1"), WithFallback(fallback)); err != nil {
+		t.Fatal("Expected the fallback response got", err)
+	}
+	if rsp != "cached" || fallbacks != 1 {
+		t.Fatalf("Expected the fallback response once got %q %d", rsp, fallbacks)
+	}
+
+	// the fallback can return the error
+	called = 0
+	err := c.Call(context.Background(), req, &rsp, WithAddress("10.1.10.1"), WithFallback(func(ctx context.Context, req Request, rsp interface{}, err error) error {
+		return err
+	}))
+	if err == nil {
+		t.Fatal("Expected the call error")
+	}
+}
+
+func TestCallThrottle(t *testing.T) {
+	var called int
+
+	wrap := func(cf CallFunc) CallFunc {
+		return func(ctx context.Context, node *registry.Node, req Request, rsp interface{}, opts CallOptions) error {
+			called++
+			if called == 1 {
+				return errors.TooManyRequests("test.error", 50*time.Millisecond, "slow down")
+			}
+			return nil
+		}
+	}
+
+	r := newTestRegistry()
+	c := NewClient(
+		Registry(r),
+		WrapCall(wrap),
+	)
+	c.Options().Selector.Init(selector.Registry(r))
+
+	req := c.NewRequest("test.service", "Test.Endpoint", nil)
+
+	// the call is retried once the service allows it
+	start := time.Now()
+	if err := c.Call(context.Background(), req, nil, WithAddress("10.1.10.1")); err != nil {
+		t.Fatal("Unexpected call err", err)
+	}
+	if called != 2 || time.Since(start) < 50*time.Millisecond {
+		t.Fatalf("Expected the call retried after 50ms got %d calls in %v", called, time.Since(start))
+	}
+
+	// the other calls to the service back off too
+	called = 0
+	if err := c.Call(context.Background(), req, nil, WithAddress("10.1.10.1"), WithRetries(0)); err == nil {
+		t.Fatal("Expected the call to fail")
+	}
+	if err := c.Call(context.Background(), req, nil, WithAddress("10.1.10.1"), WithRetries(0)); errors.FromError(err).Code != 429 || called != 1 {
+		t.Fatalf("Expected the call throttled by the client got %v after %d calls", err, called)
+	}
+}
+
 func TestCallWrapper(t *testing.T) {
 	var called bool
 	id := "test.1"
@@ -3,9 +3,12 @@ package client
 import (
 	"bytes"
 	errs "errors"
+	"strings"
 
 	"github.com/micro/go-micro/v2/codec"
+	"github.com/micro/go-micro/v2/codec/avro"
 	raw "github.com/micro/go-micro/v2/codec/bytes"
+	"github.com/micro/go-micro/v2/codec/flatbuffers"
 	"github.com/micro/go-micro/v2/codec/grpc"
 	"github.com/micro/go-micro/v2/codec/json"
 	"github.com/micro/go-micro/v2/codec/jsonrpc"
@@ -37,6 +40,11 @@ type rpcCodec struct {
 	client transport.Client
 	codec  codec.Codec
 
+	// the codec of the responses which the server may negotiate
+	rcodec      codec.Codec
+	contentType string
+	newCodec    func(string) (codec.NewCodec, error)
+
 	req *transport.Message
 	buf *readWriteCloser
 
@@ -53,6 +61,8 @@ var (
 	DefaultContentType = "application/protobuf"
 
 	DefaultCodecs = map[string]codec.NewCodec{
+		"application/avro":         avro.NewCodec,
+		"application/flatbuffers":  flatbuffers.NewCodec,
 		"application/grpc":         grpc.NewCodec,
 		"application/grpc+json":    grpc.NewCodec,
 		"application/grpc+proto":   grpc.NewCodec,
@@ -127,6 +137,14 @@ func setHeaders(m *codec.Message, stream string) {
 	}
 }
 
+// accept returns the accept header of the request
+func accept(req Request, opts CallOptions) string {
+	if len(opts.Accept) == 0 {
+		return req.ContentType()
+	}
+	return strings.Join(opts.Accept, ", ")
+}
+
 // setupProtocol sets up the old protocol
 func setupProtocol(msg *transport.Message, node *registry.Node) codec.NewCodec {
 	protocol := node.Metadata["protocol"]
@@ -153,17 +171,23 @@ func setupProtocol(msg *transport.Message, node *registry.Node) codec.NewCodec {
 	return defaultCodecs[msg.Header["Content-Type"]]
 }
 
-func newRpcCodec(req *transport.Message, client transport.Client, c codec.NewCodec, stream string) codec.Codec {
+// newRpcCodec returns the codec of the request. The responses are read with
+// the codec of their content type from newCodec if it's not nil.
+func newRpcCodec(req *transport.Message, client transport.Client, c codec.NewCodec, stream string, newCodec func(string) (codec.NewCodec, error)) codec.Codec {
 	rwc := &readWriteCloser{
 		wbuf: bytes.NewBuffer(nil),
 		rbuf: bytes.NewBuffer(nil),
 	}
+	cc := c(rwc)
 	r := &rpcCodec{
-		buf:    rwc,
-		client: client,
-		codec:  c(rwc),
-		req:    req,
-		stream: stream,
+		buf:         rwc,
+		client:      client,
+		codec:       cc,
+		rcodec:      cc,
+		contentType: req.Header["Content-Type"],
+		newCodec:    newCodec,
+		req:         req,
+		stream:      stream,
 	}
 	return r
 }
@@ -194,8 +218,10 @@ func (c *rpcCodec) Write(m *codec.Message, body interface{}) error {
 			if err := c.codec.Write(m, body); err != nil {
 				return errors.InternalServerError("go.micro.client.codec", err.Error())
 			}
-			// set body
-			m.Body = c.buf.wbuf.Bytes()
+			// copy the body as the buffer is reused while the
+			// message may still be held by the transport
+			m.Body = make([]byte, c.buf.wbuf.Len())
+			copy(m.Body, c.buf.wbuf.Bytes())
 		}
 	}
 
@@ -227,8 +253,18 @@ func (c *rpcCodec) ReadHeader(m *codec.Message, r codec.MessageType) error {
 	// set headers from transport
 	m.Header = tm.Header
 
+	// the server responds with the content type it negotiated
+	if ct := tm.Header["Content-Type"]; len(ct) > 0 && ct != c.contentType && c.newCodec != nil {
+		cf, err := c.newCodec(ct)
+		if err != nil {
+			return errors.InternalServerError("go.micro.client.codec", err.Error())
+		}
+		c.rcodec = cf(c.buf)
+		c.contentType = ct
+	}
+
 	// read header
-	err := c.codec.ReadHeader(m, r)
+	err := c.rcodec.ReadHeader(m, r)
 
 	// get headers
 	getHeaders(m)
@@ -249,7 +285,7 @@ func (c *rpcCodec) ReadBody(b interface{}) error {
 		return nil
 	}
 
-	if err := c.codec.ReadBody(b); err != nil {
+	if err := c.rcodec.ReadBody(b); err != nil {
 		return errors.InternalServerError("go.micro.client.codec", err.Error())
 	}
 	return nil
@@ -258,6 +294,9 @@ func (c *rpcCodec) ReadBody(b interface{}) error {
 func (c *rpcCodec) Close() error {
 	c.buf.Close()
 	c.codec.Close()
+	if c.rcodec != c.codec {
+		c.rcodec.Close()
+	}
 	if err := c.client.Close(); err != nil {
 		return errors.InternalServerError("go.micro.client.transport", err.Error())
 	}
@@ -3,6 +3,7 @@ package client
 import (
 	"context"
 	"io"
+	"strconv"
 	"sync"
 
 	"github.com/micro/go-micro/v2/codec"
@@ -22,6 +23,12 @@ type rpcStream struct {
 	// signal whether we should send EOS
 	sendEOS bool
 
+	// window of the messages the server sends before we
+	// acknowledge them, zero if the stream isn't flow controlled
+	window int
+	// messages received since the last acknowledgement
+	received int
+
 	// release releases the connection back to the pool
 	release func(err error)
 }
@@ -95,6 +102,11 @@ func (r *rpcStream) Recv(msg interface{}) error {
 		return err
 	}
 
+	// keep the headers of the last response
+	if rsp, ok := r.response.(*rpcResponse); ok {
+		rsp.header = resp.Header
+	}
+
 	switch {
 	case len(resp.Error) > 0:
 		// We've got an error response. Give this to the request;
@@ -117,12 +129,39 @@ func (r *rpcStream) Recv(msg interface{}) error {
 		r.Lock()
 		if err != nil {
 			r.err = err
+		} else if r.window > 0 {
+			r.ack()
 		}
 	}
 
 	return r.err
 }
 
+// ack acknowledges the messages received in batches of half the window
+func (r *rpcStream) ack() {
+	r.received++
+	if r.received < (r.window+1)/2 {
+		return
+	}
+
+	err := r.codec.Write(&codec.Message{
+		Id:       r.id,
+		Target:   r.request.Service(),
+		Method:   r.request.Method(),
+		Endpoint: r.request.Endpoint(),
+		Type:     codec.Request,
+		Header: map[string]string{
+			"Micro-Window-Ack": strconv.Itoa(r.received),
+		},
+	}, nil)
+	if err != nil {
+		r.err = err
+		return
+	}
+
+	r.received = 0
+}
+
 func (r *rpcStream) Error() error {
 	r.RLock()
 	defer r.RUnlock()
@@ -0,0 +1,189 @@
+// Package rules is a router of requests with rules. The rules match the
+// requests by endpoint, headers and a percentage of them, and route them to a
+// version of the service or a subset of its nodes e.g for canary releases or
+// routing by header. The rules can be loaded from the config and reloaded.
+package rules
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"strings"
+	"sync"
+
+	"github.com/micro/go-micro/v2/client"
+	"github.com/micro/go-micro/v2/client/selector"
+	"github.com/micro/go-micro/v2/config"
+	"github.com/micro/go-micro/v2/config/reader"
+	"github.com/micro/go-micro/v2/metadata"
+)
+
+// Rule routes the requests it matches
+type Rule struct {
+	// Service of the requests, any service if blank
+	Service string `json:"service"`
+	// Endpoint of the requests e.g Foo.Bar or Foo.* for every
+	// endpoint of Foo, any endpoint if blank
+	Endpoint string `json:"endpoint"`
+	// Headers the metadata of the requests must have
+	Headers map[string]string `json:"headers"`
+	// Percent of the requests matched routed, from 0 to 100.
+	// Zero routes all of them.
+	Percent float64 `json:"percent"`
+
+	// Version of the service routed to
+	Version string `json:"version"`
+	// Labels of the node metadata routed to
+	Labels map[string]string `json:"labels"`
+}
+
+// Router routes the requests with the first rule matching them
+type Router struct {
+	sync.RWMutex
+	rules []Rule
+}
+
+func matchEndpoint(pattern, endpoint string) bool {
+	if len(pattern) == 0 || pattern == "*" {
+		return true
+	}
+	if strings.HasSuffix(pattern, "*") {
+		return strings.HasPrefix(endpoint, strings.TrimSuffix(pattern, "*"))
+	}
+	return pattern == endpoint
+}
+
+// Match returns whether the rule matches the request, the
+// percentage of the requests matched is random
+func (r Rule) Match(ctx context.Context, req client.Request) bool {
+	if len(r.Service) > 0 && r.Service != req.Service() {
+		return false
+	}
+	if !matchEndpoint(r.Endpoint, req.Endpoint()) {
+		return false
+	}
+	for k, v := range r.Headers {
+		if hv, ok := metadata.Get(ctx, k); !ok || hv != v {
+			return false
+		}
+	}
+	if r.Percent > 0 && r.Percent < 100 && rand.Float64()*100 >= r.Percent {
+		return false
+	}
+	return true
+}
+
+// Filters returns the select filters of the nodes routed to
+func (r Rule) Filters() []selector.Filter {
+	var filters []selector.Filter
+	if len(r.Version) > 0 {
+		filters = append(filters, selector.FilterVersion(r.Version))
+	}
+	for k, v := range r.Labels {
+		filters = append(filters, selector.FilterLabel(k, v))
+	}
+	return filters
+}
+
+// Validate checks the rule routes to nodes
+func (r Rule) Validate() error {
+	if r.Percent < 0 || r.Percent > 100 {
+		return fmt.Errorf("invalid percent %v", r.Percent)
+	}
+	if len(r.Version) == 0 && len(r.Labels) == 0 {
+		return fmt.Errorf("rule of %s %s routes to no version or labels", r.Service, r.Endpoint)
+	}
+	return nil
+}
+
+// Set replaces the rules of the router
+func (r *Router) Set(rules ...Rule) error {
+	for _, rule := range rules {
+		if err := rule.Validate(); err != nil {
+			return err
+		}
+	}
+
+	r.Lock()
+	r.rules = rules
+	r.Unlock()
+
+	return nil
+}
+
+// Rules returns the rules of the router
+func (r *Router) Rules() []Rule {
+	r.RLock()
+	defer r.RUnlock()
+	rules := make([]Rule, len(r.rules))
+	copy(rules, r.rules)
+	return rules
+}
+
+// Route returns the first rule matching the request
+func (r *Router) Route(ctx context.Context, req client.Request) (Rule, bool) {
+	r.RLock()
+	defer r.RUnlock()
+
+	for _, rule := range r.rules {
+		if rule.Match(ctx, req) {
+			return rule, true
+		}
+	}
+
+	return Rule{}, false
+}
+
+// Subscriber returns a config subscriber setting the rules of the router
+// to the list of rules at the path e.g
+//
+//	c.Subscribe(router.Subscriber("micro", "rules"))
+func (r *Router) Subscriber(path ...string) config.Subscriber {
+	return func(v reader.Values) error {
+		var rules []Rule
+		if err := v.Get(path...).Scan(&rules); err != nil {
+			return err
+		}
+		return r.Set(rules...)
+	}
+}
+
+type rulesClient struct {
+	client.Client
+	router *Router
+}
+
+func (c *rulesClient) route(ctx context.Context, req client.Request, opts []client.CallOption) []client.CallOption {
+	rule, ok := c.router.Route(ctx, req)
+	if !ok {
+		return opts
+	}
+	return append(opts, client.WithSelectOption(selector.WithFilter(rule.Filters()...)))
+}
+
+func (c *rulesClient) Call(ctx context.Context, req client.Request, rsp interface{}, opts ...client.CallOption) error {
+	return c.Client.Call(ctx, req, rsp, c.route(ctx, req, opts)...)
+}
+
+func (c *rulesClient) Stream(ctx context.Context, req client.Request, opts ...client.CallOption) (client.Stream, error) {
+	return c.Client.Stream(ctx, req, c.route(ctx, req, opts)...)
+}
+
+// NewRouter returns a router with the rules
+func NewRouter(rules ...Rule) (*Router, error) {
+	r := new(Router)
+	if err := r.Set(rules...); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Wrapper returns a client wrapper routing the calls with the router
+func Wrapper(r *Router) client.Wrapper {
+	return func(c client.Client) client.Client {
+		return &rulesClient{
+			Client: c,
+			router: r,
+		}
+	}
+}
@@ -0,0 +1,120 @@
+package rules
+
+import (
+	"context"
+	"testing"
+
+	"github.com/micro/go-micro/v2/client"
+	"github.com/micro/go-micro/v2/client/selector"
+	"github.com/micro/go-micro/v2/config"
+	"github.com/micro/go-micro/v2/config/source/memory"
+	"github.com/micro/go-micro/v2/metadata"
+	"github.com/micro/go-micro/v2/registry"
+	rmemory "github.com/micro/go-micro/v2/registry/memory"
+)
+
+func TestRouter(t *testing.T) {
+	r, err := NewRouter(
+		Rule{Service: "foo", Headers: map[string]string{"X-Beta": "true"}, Version: "2.0.0"},
+		Rule{Service: "foo", Endpoint: "Foo.*", Labels: map[string]string{"zone": "a"}},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := client.NewClient()
+
+	testData := []struct {
+		ctx     context.Context
+		req     client.Request
+		matched bool
+		version string
+	}{
+		{context.Background(), c.NewRequest("bar", "Foo.Bar", nil), false, ""},
+		{context.Background(), c.NewRequest("foo", "Bar.Foo", nil), false, ""},
+		{context.Background(), c.NewRequest("foo", "Foo.Bar", nil), true, ""},
+		{metadata.Set(context.Background(), "X-Beta", "true"), c.NewRequest("foo", "Bar.Foo", nil), true, "2.0.0"},
+	}
+
+	for _, d := range testData {
+		rule, ok := r.Route(d.ctx, d.req)
+		if ok != d.matched {
+			t.Fatalf("Expected %s %s matched %v got %v", d.req.Service(), d.req.Endpoint(), d.matched, ok)
+		}
+		if rule.Version != d.version {
+			t.Fatalf("Expected version %s got %s", d.version, rule.Version)
+		}
+	}
+
+	if _, err := NewRouter(Rule{Service: "foo"}); err == nil {
+		t.Fatal("Expected a rule without a route to be invalid")
+	}
+}
+
+func TestRouterSubscriber(t *testing.T) {
+	c, err := config.NewConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	if err := c.Load(memory.NewSource(memory.WithJSON([]byte(`{
+		"rules": [{"service": "foo", "version": "1.0.1"}]
+	}`)))); err != nil {
+		t.Fatal(err)
+	}
+
+	r, _ := NewRouter()
+	if err := c.Subscribe(r.Subscriber("rules")); err != nil {
+		t.Fatal(err)
+	}
+	if rules := r.Rules(); len(rules) != 1 || rules[0].Version != "1.0.1" {
+		t.Fatalf("Expected the rules of the config got %+v", rules)
+	}
+
+	// the calls are routed to the version of the rule
+	var nodes []string
+	wrap := func(cf client.CallFunc) client.CallFunc {
+		return func(ctx context.Context, node *registry.Node, req client.Request, rsp interface{}, opts client.CallOptions) error {
+			nodes = append(nodes, node.Id)
+			return nil
+		}
+	}
+
+	reg := rmemory.NewRegistry(rmemory.Services(map[string][]*registry.Service{
+		"foo": {
+			{Name: "foo", Version: "1.0.0", Nodes: []*registry.Node{{Id: "foo-1", Address: "localhost:9999"}}},
+			{Name: "foo", Version: "1.0.1", Nodes: []*registry.Node{{Id: "foo-2", Address: "localhost:9998"}}},
+		},
+	}))
+	cl := client.NewClient(client.Registry(reg), client.WrapCall(wrap))
+	cl.Options().Selector.Init(selector.Registry(reg))
+	cl = Wrapper(r)(cl)
+
+	for i := 0; i < 10; i++ {
+		if err := cl.Call(context.Background(), cl.NewRequest("foo", "Foo.Bar", nil), nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, id := range nodes {
+		if id != "foo-2" {
+			t.Fatalf("Expected the calls routed to foo-2 got %s", id)
+		}
+	}
+
+	// the rules are reloaded
+	if err := c.Stage().Set([]Rule{{Service: "foo", Version: "1.0.0"}}, "rules").Apply(); err != nil {
+		t.Fatal(err)
+	}
+	if rules := r.Rules(); len(rules) != 1 || rules[0].Version != "1.0.0" {
+		t.Fatalf("Expected the rules reloaded got %+v", rules)
+	}
+
+	// invalid rules are rejected
+	if err := c.Stage().Set([]Rule{{Service: "foo"}}, "rules").Apply(); err == nil {
+		t.Fatal("Expected the invalid rules to be rejected")
+	}
+	if rules := r.Rules(); len(rules) != 1 || rules[0].Version != "1.0.0" {
+		t.Fatalf("Expected the rules kept got %+v", rules)
+	}
+}
@@ -0,0 +1,152 @@
+package selector
+
+import (
+	"math"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/micro/go-micro/v2/registry"
+)
+
+// Balancer is a strategy balancing the load of the nodes
+// with the feedback of the calls made to them
+type Balancer interface {
+	// Strategy selects the nodes of the services
+	Strategy(services []*registry.Service) Next
+	// Mark the end of a call to a node selected
+	Mark(service string, node *registry.Node, err error)
+}
+
+// P2C is a balancer picking the least loaded of two random nodes. The load of
+// a node is its latency, a moving average which decays over time, weighted by
+// its calls in flight. Slow or saturated nodes are picked less often until
+// they recover.
+type P2C struct {
+	// Decay is the time it takes for the latency of
+	// a node to move most of the way to the latest one
+	Decay time.Duration
+	// Penalty is the latency of failed calls
+	Penalty time.Duration
+
+	sync.Mutex
+	nodes map[string]*nodeLoad
+	swept time.Time
+}
+
+type nodeLoad struct {
+	// moving average of the latency in nanoseconds
+	latency  float64
+	sampled  bool
+	updated  time.Time
+	inflight int
+	// start of the calls in flight
+	started []time.Time
+}
+
+func (p *P2C) load(node *registry.Node) *nodeLoad {
+	n, ok := p.nodes[node.Id]
+	if !ok {
+		n = &nodeLoad{updated: time.Now()}
+		p.nodes[node.Id] = n
+	}
+	return n
+}
+
+// cost of calling the node, the nodes not called yet are free
+func (n *nodeLoad) cost() float64 {
+	return (n.latency + 1) * float64(n.inflight+1)
+}
+
+// sweep removes the nodes without calls for a while
+func (p *P2C) sweep(now time.Time) {
+	if now.Sub(p.swept) < time.Minute {
+		return
+	}
+	p.swept = now
+	for id, n := range p.nodes {
+		if n.inflight == 0 && now.Sub(n.updated) > time.Minute {
+			delete(p.nodes, id)
+		}
+	}
+}
+
+func (p *P2C) Strategy(services []*registry.Service) Next {
+	nodes := make([]*registry.Node, 0, len(services))
+
+	for _, service := range services {
+		nodes = append(nodes, service.Nodes...)
+	}
+
+	return func() (*registry.Node, error) {
+		if len(nodes) == 0 {
+			return nil, ErrNoneAvailable
+		}
+
+		p.Lock()
+		defer p.Unlock()
+
+		node := nodes[0]
+		if len(nodes) > 1 {
+			i := rand.Intn(len(nodes))
+			j := rand.Intn(len(nodes) - 1)
+			if j >= i {
+				j++
+			}
+			node = nodes[i]
+			if p.load(nodes[j]).cost() < p.load(node).cost() {
+				node = nodes[j]
+			}
+		}
+
+		n := p.load(node)
+		n.inflight++
+		n.started = append(n.started, time.Now())
+
+		return node, nil
+	}
+}
+
+func (p *P2C) Mark(service string, node *registry.Node, err error) {
+	p.Lock()
+	defer p.Unlock()
+
+	n, ok := p.nodes[node.Id]
+	if !ok || n.inflight == 0 {
+		return
+	}
+
+	now := time.Now()
+
+	// the calls are assumed to end in the order they started
+	latency := now.Sub(n.started[0])
+	n.started = n.started[1:]
+	n.inflight--
+
+	if err != nil && latency < p.Penalty {
+		latency = p.Penalty
+	}
+
+	// the weight of the previous average decays with the time since
+	if n.sampled {
+		w := math.Exp(-float64(now.Sub(n.updated)) / float64(p.Decay))
+		n.latency = n.latency*w + float64(latency)*(1-w)
+	} else {
+		n.latency = float64(latency)
+		n.sampled = true
+	}
+	n.updated = now
+
+	p.sweep(now)
+}
+
+// NewP2C returns a power of two choices balancer with a decay of 10s
+// and a penalty of 1s
+func NewP2C() *P2C {
+	return &P2C{
+		Decay:   10 * time.Second,
+		Penalty: time.Second,
+		nodes:   make(map[string]*nodeLoad),
+		swept:   time.Now(),
+	}
+}
@@ -0,0 +1,68 @@
+package selector
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/micro/go-micro/v2/registry"
+)
+
+func TestP2C(t *testing.T) {
+	services := []*registry.Service{
+		{
+			Name: "test",
+			Nodes: []*registry.Node{
+				{Id: "test-1", Address: "10.0.0.1:1001"},
+				{Id: "test-2", Address: "10.0.0.2:1002"},
+			},
+		},
+	}
+
+	p := NewP2C()
+	next := p.Strategy(services)
+
+	// the failures of the first node are penalised
+	for i := 0; i < 10; i++ {
+		node, err := next()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if node.Id == "test-1" {
+			p.Mark("test", node, errors.New("failed"))
+		} else {
+			p.Mark("test", node, nil)
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	for i := 0; i < 10; i++ {
+		node, err := next()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if node.Id != "test-2" {
+			t.Fatalf("expected the healthy node, got %s", node.Id)
+		}
+		p.Mark("test", node, nil)
+	}
+
+	// the calls in flight spread across the nodes
+	p = NewP2C()
+	next = p.Strategy(services)
+	counts := make(map[string]int)
+	for i := 0; i < 10; i++ {
+		node, err := next()
+		if err != nil {
+			t.Fatal(err)
+		}
+		counts[node.Id]++
+	}
+	if counts["test-1"] != 5 || counts["test-2"] != 5 {
+		t.Fatalf("expected the calls in flight to be balanced, got %v", counts)
+	}
+
+	if _, err := p.Strategy(nil)(); err != ErrNoneAvailable {
+		t.Fatalf("expected %v, got %v", ErrNoneAvailable, err)
+	}
+}
@@ -78,10 +78,25 @@ func (c *registrySelector) Select(service string, opts ...SelectOption) (Next, e
 		return nil, ErrNoneAvailable
 	}
 
+	// apply the version constraints
+	if len(sopts.Constraints) > 0 {
+		services, err = filterConstraints(services, sopts.Constraints)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if kb, ok := c.so.Balancer.(KeyBalancer); ok && len(sopts.Key) > 0 {
+		return kb.Key(sopts.Key)(services), nil
+	}
+
 	return sopts.Strategy(services), nil
 }
 
 func (c *registrySelector) Mark(service string, node *registry.Node, err error) {
+	if c.so.Balancer != nil {
+		c.so.Balancer.Mark(service, node, err)
+	}
 }
 
 func (c *registrySelector) Reset(service string) {
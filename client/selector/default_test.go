@@ -30,3 +30,24 @@ func TestRegistrySelector(t *testing.T) {
 		t.Logf("Selector Counts %v", counts)
 	}
 }
+
+func TestRegistrySelectorVersionConstraint(t *testing.T) {
+	r := memory.NewRegistry(memory.Services(testData))
+	cache := NewSelector(Registry(r))
+
+	next, err := cache.Select("foo", WithVersionConstraint(">= 1.0.3"))
+	if err != nil {
+		t.Fatalf("Unexpected error calling cache select: %v", err)
+	}
+	node, err := next()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if node.Id != "foo-1.0.3-345" {
+		t.Fatalf("Expected node foo-1.0.3-345 got %s", node.Id)
+	}
+
+	if _, err := cache.Select("foo", WithVersionConstraint(">= 1.4")); err != ErrIncompatible {
+		t.Fatalf("Expected %v got %v", ErrIncompatible, err)
+	}
+}
@@ -71,3 +71,12 @@ func FilterVersion(version string) Filter {
 		return services
 	}
 }
+
+// FilterVersionConstraint is a version based Select Filter which will
+// only return services with a version satisfying the constraint e.g >= 1.4
+func FilterVersionConstraint(c string) Filter {
+	return func(old []*registry.Service) []*registry.Service {
+		services, _ := filterConstraints(old, []string{c})
+		return services
+	}
+}
@@ -237,3 +237,36 @@ func TestFilterVersion(t *testing.T) {
 		}
 	}
 }
+
+func TestFilterVersionConstraint(t *testing.T) {
+	services := []*registry.Service{
+		{Name: "test", Version: "1.3.9"},
+		{Name: "test", Version: "v1.4.0"},
+		{Name: "test", Version: "2.0.0-rc1"},
+		{Name: "test", Version: "latest"},
+	}
+
+	testData := []struct {
+		constraint string
+		versions   []string
+	}{
+		{">= 1.4", []string{"v1.4.0", "2.0.0-rc1"}},
+		{">=1.4, <2", []string{"v1.4.0"}},
+		{"1.3.9", []string{"1.3.9"}},
+		{"!= 1.3.9", []string{"v1.4.0", "2.0.0-rc1"}},
+		{"> 2", nil},
+		{"invalid", nil},
+	}
+
+	for _, data := range testData {
+		filtered := FilterVersionConstraint(data.constraint)(services)
+		if len(filtered) != len(data.versions) {
+			t.Fatalf("Expected %v for %q got %d services", data.versions, data.constraint, len(filtered))
+		}
+		for i, service := range filtered {
+			if service.Version != data.versions[i] {
+				t.Fatalf("Expected version %s for %q got %s", data.versions[i], data.constraint, service.Version)
+			}
+		}
+	}
+}
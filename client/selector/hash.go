@@ -0,0 +1,145 @@
+package selector
+
+import (
+	"hash/fnv"
+	"math"
+	"math/rand"
+	"sort"
+	"strconv"
+	"sync"
+
+	"github.com/micro/go-micro/v2/registry"
+)
+
+// KeyBalancer is a balancer routing the calls with the same key
+// to the same node
+type KeyBalancer interface {
+	Balancer
+	// Key returns the strategy selecting the nodes of the key
+	Key(key string) Strategy
+}
+
+// ConsistentHash is a balancer hashing the keys of the calls onto a ring of
+// the nodes, so the calls with the same key go to the same node and only the
+// keys of the nodes added or removed move. The load of the nodes is bounded,
+// the calls of a key go to the next node of the ring when its node has more
+// calls in flight than its share.
+type ConsistentHash struct {
+	// Replicas is the number of points of each node on the ring
+	Replicas int
+	// Load is the max calls in flight of a node relative to the
+	// average, it's at least 1
+	Load float64
+
+	sync.Mutex
+	inflight map[string]int
+	total    int
+}
+
+type point struct {
+	hash uint64
+	node *registry.Node
+}
+
+func hashKey(key string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(key))
+	return h.Sum64()
+}
+
+// Strategy spreads the calls without a key randomly over the ring
+func (c *ConsistentHash) Strategy(services []*registry.Service) Next {
+	return c.Key(strconv.FormatUint(rand.Uint64(), 36))(services)
+}
+
+func (c *ConsistentHash) Key(key string) Strategy {
+	return func(services []*registry.Service) Next {
+		var nodes []*registry.Node
+		var ring []point
+
+		for _, service := range services {
+			for _, node := range service.Nodes {
+				nodes = append(nodes, node)
+				for i := 0; i < c.Replicas; i++ {
+					ring = append(ring, point{hashKey(node.Id + "-" + strconv.Itoa(i)), node})
+				}
+			}
+		}
+
+		sort.Slice(ring, func(i, j int) bool {
+			return ring[i].hash < ring[j].hash
+		})
+
+		h := hashKey(key)
+		start := sort.Search(len(ring), func(i int) bool {
+			return ring[i].hash >= h
+		})
+
+		// the nodes returned, the next calls go to the other nodes
+		tried := make(map[string]bool)
+
+		return func() (*registry.Node, error) {
+			if len(ring) == 0 {
+				return nil, ErrNoneAvailable
+			}
+
+			c.Lock()
+			defer c.Unlock()
+
+			if len(tried) >= len(nodes) {
+				tried = make(map[string]bool)
+			}
+
+			load := c.Load
+			if load < 1 {
+				load = 1
+			}
+			max := int(math.Ceil(load * float64(c.total+1) / float64(len(nodes))))
+
+			var node *registry.Node
+			for i := 0; i < len(ring); i++ {
+				p := ring[(start+i)%len(ring)]
+				if tried[p.node.Id] {
+					continue
+				}
+				if node == nil {
+					node = p.node
+				}
+				if c.inflight[p.node.Id] < max {
+					node = p.node
+					break
+				}
+			}
+
+			tried[node.Id] = true
+			c.inflight[node.Id]++
+			c.total++
+
+			return node, nil
+		}
+	}
+}
+
+func (c *ConsistentHash) Mark(service string, node *registry.Node, err error) {
+	c.Lock()
+	defer c.Unlock()
+
+	if c.inflight[node.Id] == 0 {
+		return
+	}
+	c.inflight[node.Id]--
+	c.total--
+	if c.inflight[node.Id] == 0 {
+		delete(c.inflight, node.Id)
+	}
+}
+
+// NewConsistentHash returns a consistent hashing balancer with 100
+// replicas of each node and a load bound of 1.25 times the average
+func NewConsistentHash() *ConsistentHash {
+	return &ConsistentHash{
+		Replicas: 100,
+		Load:     1.25,
+		inflight: make(map[string]int),
+	}
+}
@@ -0,0 +1,80 @@
+package selector
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/micro/go-micro/v2/registry"
+)
+
+func TestConsistentHash(t *testing.T) {
+	newServices := func(n int) []*registry.Service {
+		service := &registry.Service{Name: "test"}
+		for i := 0; i < n; i++ {
+			service.Nodes = append(service.Nodes, &registry.Node{
+				Id:      fmt.Sprintf("test-%d", i),
+				Address: fmt.Sprintf("10.0.0.%d:1000", i),
+			})
+		}
+		return []*registry.Service{service}
+	}
+
+	c := NewConsistentHash()
+
+	route := func(services []*registry.Service, key string) string {
+		node, err := c.Key(key)(services)()
+		if err != nil {
+			t.Fatal(err)
+		}
+		c.Mark("test", node, nil)
+		return node.Id
+	}
+
+	// the keys go to the same node
+	services := newServices(5)
+	routes := make(map[string]string)
+	for i := 0; i < 100; i++ {
+		key := fmt.Sprintf("key-%d", i)
+		routes[key] = route(services, key)
+		if id := route(services, key); id != routes[key] {
+			t.Fatalf("expected key %s to go to %s, got %s", key, routes[key], id)
+		}
+	}
+
+	// only the keys of the node removed move
+	services[0].Nodes = services[0].Nodes[:4]
+	for key, id := range routes {
+		if moved := route(services, key); id != "test-4" && moved != id {
+			t.Fatalf("expected key %s to stay on %s, got %s", key, id, moved)
+		}
+	}
+
+	// the calls in flight of a key spread once the node is loaded
+	counts := make(map[string]int)
+	for i := 0; i < 8; i++ {
+		node, err := c.Key("key")(services)()
+		if err != nil {
+			t.Fatal(err)
+		}
+		counts[node.Id]++
+	}
+	for id, n := range counts {
+		if n > 3 {
+			t.Fatalf("expected at most 3 calls in flight on %s, got %d", id, n)
+		}
+	}
+
+	// the next nodes of a call are other nodes
+	next := c.Key("key")(services)
+	seen := make(map[string]bool)
+	for i := 0; i < 4; i++ {
+		node, err := next()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if seen[node.Id] {
+			t.Fatalf("expected a new node, got %s again", node.Id)
+		}
+		seen[node.Id] = true
+	}
+}
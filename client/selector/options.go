@@ -9,6 +9,8 @@ import (
 type Options struct {
 	Registry registry.Registry
 	Strategy Strategy
+	// Balancer is marked with the result of the calls
+	Balancer Balancer
 
 	// Domain to lookup services from within the registry
 	Domain string
@@ -21,6 +23,10 @@ type Options struct {
 type SelectOptions struct {
 	Filters  []Filter
 	Strategy Strategy
+	// Version constraints the service must satisfy e.g >= 1.4
+	Constraints []string
+	// Key routes the calls to a node with a key balancer
+	Key string
 
 	// Other options for implementations of the interface
 	// can be stored in a context
@@ -54,6 +60,15 @@ func SetStrategy(fn Strategy) Option {
 	}
 }
 
+// SetBalancer sets the balancer as the default strategy
+// and marks it with the result of the calls
+func SetBalancer(b Balancer) Option {
+	return func(o *Options) {
+		o.Strategy = b.Strategy
+		o.Balancer = b
+	}
+}
+
 // WithFilter adds a filter function to the list of filters
 // used during the Select call.
 func WithFilter(fn ...Filter) SelectOption {
@@ -62,6 +77,23 @@ func WithFilter(fn ...Filter) SelectOption {
 	}
 }
 
+// WithVersionConstraint requires the version of the service to satisfy
+// the constraint e.g ">= 1.4, < 2". Select returns ErrIncompatible when
+// the service is only available at other versions.
+func WithVersionConstraint(c string) SelectOption {
+	return func(o *SelectOptions) {
+		o.Constraints = append(o.Constraints, c)
+	}
+}
+
+// WithKey routes the calls with the same key to the same node
+// when the balancer of the selector is a KeyBalancer
+func WithKey(key string) SelectOption {
+	return func(o *SelectOptions) {
+		o.Key = key
+	}
+}
+
 // Strategy sets the selector strategy
 func WithStrategy(fn Strategy) SelectOption {
 	return func(o *SelectOptions) {
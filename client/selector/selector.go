@@ -40,4 +40,5 @@ var (
 
 	ErrNotFound      = errors.New("not found")
 	ErrNoneAvailable = errors.New("none available")
+	ErrIncompatible  = errors.New("no compatible version")
 )
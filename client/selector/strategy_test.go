@@ -56,3 +56,56 @@ func TestStrategies(t *testing.T) {
 		}
 	}
 }
+
+func TestWeightedStrategies(t *testing.T) {
+	testData := []*registry.Service{
+		{
+			Name:    "test1",
+			Version: "latest",
+			Nodes: []*registry.Node{
+				{
+					Id:       "test1-1",
+					Address:  "10.0.0.1:1001",
+					Metadata: map[string]string{"weight": "300"},
+				},
+				{
+					Id:      "test1-2",
+					Address: "10.0.0.2:1002",
+				},
+				{
+					Id:       "test1-3",
+					Address:  "10.0.0.3:1003",
+					Metadata: map[string]string{"weight": "0"},
+				},
+			},
+		},
+	}
+
+	for name, strategy := range map[string]Strategy{"random": WeightedRandom, "roundrobin": WeightedRoundRobin} {
+		next := strategy(testData)
+		counts := make(map[string]int)
+
+		for i := 0; i < 400; i++ {
+			node, err := next()
+			if err != nil {
+				t.Fatal(err)
+			}
+			counts[node.Id]++
+		}
+
+		if counts["test1-3"] > 0 {
+			t.Fatalf("%s: expected no calls to the node without weight, got %d", name, counts["test1-3"])
+		}
+		if counts["test1-1"] < 2*counts["test1-2"] {
+			t.Fatalf("%s: expected the calls in proportion to the weights, got %+v", name, counts)
+		}
+
+		if len(os.Getenv("IN_TRAVIS_CI")) == 0 {
+			t.Logf("%s: %+v\n", name, counts)
+		}
+	}
+
+	if _, err := WeightedRandom(nil)(); err != ErrNoneAvailable {
+		t.Fatalf("expected %v, got %v", ErrNoneAvailable, err)
+	}
+}
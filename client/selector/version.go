@@ -0,0 +1,150 @@
+package selector
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/micro/go-micro/v2/registry"
+)
+
+// constraint is a single version comparison e.g >= 1.4
+type constraint struct {
+	op      string
+	version []string
+}
+
+// parseConstraints parses a comma separated list of constraints which must
+// all be satisfied e.g ">= 1.4, < 2". A version without an operator must match.
+func parseConstraints(s string) ([]constraint, error) {
+	var cs []constraint
+
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if len(part) == 0 {
+			continue
+		}
+
+		op := "="
+		for _, o := range []string{">=", "<=", "!=", "==", ">", "<", "="} {
+			if strings.HasPrefix(part, o) {
+				op = o
+				part = strings.TrimSpace(part[len(o):])
+				break
+			}
+		}
+
+		v, ok := parseVersion(part)
+		if !ok {
+			return nil, fmt.Errorf("invalid version constraint %q", s)
+		}
+
+		cs = append(cs, constraint{op: op, version: v})
+	}
+
+	if len(cs) == 0 {
+		return nil, fmt.Errorf("invalid version constraint %q", s)
+	}
+
+	return cs, nil
+}
+
+// parseVersion splits a dotted version e.g v1.4.2 into its numeric parts
+func parseVersion(s string) ([]string, bool) {
+	s = strings.TrimPrefix(s, "v")
+	// ignore pre-release and build metadata
+	if i := strings.IndexAny(s, "-+"); i >= 0 {
+		s = s[:i]
+	}
+
+	parts := strings.Split(s, ".")
+	for _, p := range parts {
+		if _, err := strconv.ParseUint(p, 10, 64); err != nil {
+			return nil, false
+		}
+	}
+
+	return parts, true
+}
+
+// compareVersions returns -1, 0 or 1. Missing parts are treated as 0.
+func compareVersions(a, b []string) int {
+	for i := 0; i < len(a) || i < len(b); i++ {
+		var x, y uint64
+		if i < len(a) {
+			x, _ = strconv.ParseUint(a[i], 10, 64)
+		}
+		if i < len(b) {
+			y, _ = strconv.ParseUint(b[i], 10, 64)
+		}
+		switch {
+		case x < y:
+			return -1
+		case x > y:
+			return 1
+		}
+	}
+	return 0
+}
+
+func (c constraint) check(v []string) bool {
+	n := compareVersions(v, c.version)
+
+	switch c.op {
+	case ">":
+		return n > 0
+	case ">=":
+		return n >= 0
+	case "<":
+		return n < 0
+	case "<=":
+		return n <= 0
+	case "!=":
+		return n != 0
+	default:
+		return n == 0
+	}
+}
+
+// satisfies returns true if the version satisfies every constraint.
+// Versions which aren't dotted numbers e.g latest never do.
+func satisfies(version string, cs []constraint) bool {
+	v, ok := parseVersion(version)
+	if !ok {
+		return false
+	}
+
+	for _, c := range cs {
+		if !c.check(v) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// filterConstraints returns the services with a version satisfying the
+// constraints or ErrIncompatible if there are none
+func filterConstraints(old []*registry.Service, constraints []string) ([]*registry.Service, error) {
+	var cs []constraint
+	for _, c := range constraints {
+		parsed, err := parseConstraints(c)
+		if err != nil {
+			return nil, err
+		}
+		cs = append(cs, parsed...)
+	}
+
+	var services []*registry.Service
+	for _, service := range old {
+		if satisfies(service.Version, cs) {
+			services = append(services, service)
+		}
+	}
+
+	if len(services) == 0 {
+		return nil, ErrIncompatible
+	}
+
+	return services, nil
+}
@@ -0,0 +1,120 @@
+package selector
+
+import (
+	"math/rand"
+	"strconv"
+	"sync"
+
+	"github.com/micro/go-micro/v2/registry"
+)
+
+const (
+	// WeightKey is the node metadata key of its weight
+	// e.g server.Metadata(map[string]string{"weight": "5"})
+	WeightKey = "weight"
+	// DefaultWeight is the weight of the nodes without one
+	DefaultWeight = 100
+)
+
+// Weight returns the weight of the node, nodes with a weight of
+// zero are only selected when every node has a weight of zero
+func Weight(node *registry.Node) int {
+	v, ok := node.Metadata[WeightKey]
+	if !ok {
+		return DefaultWeight
+	}
+	w, err := strconv.Atoi(v)
+	if err != nil || w < 0 {
+		return DefaultWeight
+	}
+	return w
+}
+
+// weightedNodes returns the nodes and their weights
+func weightedNodes(services []*registry.Service) ([]*registry.Node, []int, int) {
+	var nodes []*registry.Node
+	var weights []int
+	var total int
+
+	for _, service := range services {
+		for _, node := range service.Nodes {
+			w := Weight(node)
+			nodes = append(nodes, node)
+			weights = append(weights, w)
+			total += w
+		}
+	}
+
+	// the nodes are equal without any weight
+	if total == 0 {
+		for i := range weights {
+			weights[i] = 1
+		}
+		total = len(weights)
+	}
+
+	return nodes, weights, total
+}
+
+// pick a random node in proportion to its weight
+func pick(nodes []*registry.Node, weights []int, total int) int {
+	r := rand.Intn(total)
+	for i, w := range weights {
+		if r < w {
+			return i
+		}
+		r -= w
+	}
+	return len(nodes) - 1
+}
+
+// WeightedRandom is a random strategy selecting the
+// nodes in proportion to their weight
+func WeightedRandom(services []*registry.Service) Next {
+	nodes, weights, total := weightedNodes(services)
+
+	return func() (*registry.Node, error) {
+		if len(nodes) == 0 {
+			return nil, ErrNoneAvailable
+		}
+
+		return nodes[pick(nodes, weights, total)], nil
+	}
+}
+
+// WeightedRoundRobin is a smooth weighted roundrobin strategy
+// selecting the nodes in proportion to their weight
+func WeightedRoundRobin(services []*registry.Service) Next {
+	nodes, weights, total := weightedNodes(services)
+
+	var mtx sync.Mutex
+	current := make([]int, len(nodes))
+	started := false
+
+	return func() (*registry.Node, error) {
+		if len(nodes) == 0 {
+			return nil, ErrNoneAvailable
+		}
+
+		mtx.Lock()
+		defer mtx.Unlock()
+
+		best := -1
+		for i, w := range weights {
+			current[i] += w
+			if w > 0 && (best < 0 || current[i] > current[best]) {
+				best = i
+			}
+		}
+
+		// the first node is random so the calls
+		// of each select are spread by weight
+		if !started {
+			started = true
+			best = pick(nodes, weights, total)
+		}
+		current[best] -= total
+
+		return nodes[best], nil
+	}
+}
@@ -0,0 +1,134 @@
+package client
+
+import (
+	"context"
+	"math/rand"
+	"reflect"
+
+	"github.com/micro/go-micro/v2/client/selector"
+	"github.com/micro/go-micro/v2/metadata"
+)
+
+// ShadowHeader is set on the requests mirrored to a shadow service
+// so it can tell them from the requests of the callers
+const ShadowHeader = "Micro-Shadow"
+
+// DefaultShadowCalls is the number of mirrored calls in flight of a
+// ShadowWrapper, the calls mirrored while it's reached are dropped
+var DefaultShadowCalls = 100
+
+// Shadow mirrors the calls to a shadow service to test it with the
+// traffic of the callers. The responses of the shadow are discarded.
+// The calls aren't mirrored unless the Service or Version is set to another
+// one than the service called, as it would be called twice otherwise.
+type Shadow struct {
+	// Service is the shadow service, the service called if blank
+	Service string
+	// Version of the shadow service, any version if blank
+	Version string
+	// Percent of the calls mirrored, from 0 to 100
+	Percent float64
+	// Calls is the number of mirrored calls in flight, DefaultShadowCalls
+	// if zero. It's only set by the shadow of the ShadowWrapper.
+	Calls int
+}
+
+type shadowKey struct{}
+
+// WithShadow mirrors the call to the shadow in place of the
+// shadow of the ShadowWrapper, a zero percent disables it
+func WithShadow(s Shadow) CallOption {
+	return func(o *CallOptions) {
+		if o.Context == nil {
+			o.Context = context.Background()
+		}
+		o.Context = context.WithValue(o.Context, shadowKey{}, s)
+	}
+}
+
+type shadowClient struct {
+	Client
+	shadow Shadow
+	// semaphore of the mirrored calls in flight
+	calls chan struct{}
+}
+
+func (s *shadowClient) Call(ctx context.Context, req Request, rsp interface{}, opts ...CallOption) error {
+	shadow := s.shadow
+
+	var options CallOptions
+	for _, o := range opts {
+		o(&options)
+	}
+	if options.Context != nil {
+		if sh, ok := options.Context.Value(shadowKey{}).(Shadow); ok {
+			shadow = sh
+		}
+	}
+
+	if shadow.Percent > 0 && shadow.other(req.Service()) && rand.Float64()*100 < shadow.Percent {
+		s.mirror(ctx, shadow, req, rsp)
+	}
+
+	return s.Client.Call(ctx, req, rsp, opts...)
+}
+
+// other returns true if the shadow isn't the service called
+func (s Shadow) other(service string) bool {
+	return (len(s.Service) > 0 && s.Service != service) || len(s.Version) > 0
+}
+
+// mirror the call to the shadow in the background
+func (s *shadowClient) mirror(ctx context.Context, shadow Shadow, req Request, rsp interface{}) {
+	service := shadow.Service
+	if len(service) == 0 {
+		service = req.Service()
+	}
+
+	// the mirrored call outlives the call of the caller
+	md, _ := metadata.FromContext(ctx)
+	md = metadata.Copy(md)
+	md[ShadowHeader] = "true"
+	sctx := metadata.NewContext(context.Background(), md)
+
+	sreq := s.Client.NewRequest(service, req.Endpoint(), req.Body(), WithContentType(req.ContentType()))
+
+	// a response of the type of the caller discarded once decoded
+	var srsp interface{}
+	if t := reflect.TypeOf(rsp); t != nil && t.Kind() == reflect.Ptr {
+		srsp = reflect.New(t.Elem()).Interface()
+	}
+
+	var opts []CallOption
+	if len(shadow.Version) > 0 {
+		opts = append(opts, WithSelectOption(selector.WithFilter(selector.FilterVersion(shadow.Version))))
+	}
+
+	// drop the call rather than pile up behind a slow shadow
+	select {
+	case s.calls <- struct{}{}:
+	default:
+		return
+	}
+
+	go func() {
+		defer func() { <-s.calls }()
+		s.Client.Call(sctx, sreq, srsp, opts...)
+	}()
+}
+
+// ShadowWrapper returns a wrapper mirroring the calls to the shadow
+func ShadowWrapper(shadow Shadow) Wrapper {
+	calls := shadow.Calls
+	if calls <= 0 {
+		calls = DefaultShadowCalls
+	}
+
+	return func(c Client) Client {
+		return &shadowClient{
+			Client: c,
+			shadow: shadow,
+			calls:  make(chan struct{}, calls),
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package client
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/micro/go-micro/v2/client/selector"
+	"github.com/micro/go-micro/v2/metadata"
+	"github.com/micro/go-micro/v2/registry"
+)
+
+func TestShadowWrapper(t *testing.T) {
+	shadowed := make(chan *registry.Node, 10)
+
+	wrap := func(cf CallFunc) CallFunc {
+		return func(ctx context.Context, node *registry.Node, req Request, rsp interface{}, opts CallOptions) error {
+			if _, ok := metadata.Get(ctx, ShadowHeader); ok {
+				*(rsp.(*string)) = "shadow"
+				shadowed <- node
+				return nil
+			}
+			*(rsp.(*string)) = node.Address
+			return nil
+		}
+	}
+
+	r := newTestRegistry()
+	c := NewClient(
+		Registry(r),
+		WrapCall(wrap),
+	)
+	c.Options().Selector.Init(selector.Registry(r))
+	c = ShadowWrapper(Shadow{Version: "1.0.1", Percent: 100})(c)
+
+	req := c.NewRequest("foo", "Foo.Bar", nil)
+
+	var rsp string
+	if err := c.Call(context.Background(), req, &rsp); err != nil {
+		t.Fatalf("Unexpected call err: %v", err)
+	}
+	if rsp == "shadow" {
+		t.Fatal("Expected the response of the service, got the shadow")
+	}
+
+	select {
+	case node := <-shadowed:
+		if node.Id != "foo-1.0.1-321" {
+			t.Fatalf("Expected the shadow version to be called, got %s", node.Id)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected the call to be mirrored")
+	}
+
+	// the call option disables the shadow and the service called isn't
+	// its own shadow
+	for _, shadow := range []Shadow{{}, {Percent: 100}, {Service: "foo", Percent: 100}} {
+		if err := c.Call(context.Background(), req, &rsp, WithShadow(shadow)); err != nil {
+			t.Fatalf("Unexpected call err: %v", err)
+		}
+	}
+
+	select {
+	case <-shadowed:
+		t.Fatal("Expected the call not to be mirrored")
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestShadowCalls(t *testing.T) {
+	release := make(chan bool)
+	mirrored := make(chan bool, 10)
+
+	wrap := func(cf CallFunc) CallFunc {
+		return func(ctx context.Context, node *registry.Node, req Request, rsp interface{}, opts CallOptions) error {
+			if _, ok := metadata.Get(ctx, ShadowHeader); ok {
+				mirrored <- true
+				<-release
+			}
+			return nil
+		}
+	}
+
+	r := newTestRegistry()
+	c := NewClient(
+		Registry(r),
+		WrapCall(wrap),
+	)
+	c.Options().Selector.Init(selector.Registry(r))
+	c = ShadowWrapper(Shadow{Version: "1.0.1", Percent: 100, Calls: 2})(c)
+
+	req := c.NewRequest("foo", "Foo.Bar", nil)
+
+	// the calls mirrored while the shadow is slow are dropped
+	for i := 0; i < 5; i++ {
+		var rsp string
+		if err := c.Call(context.Background(), req, &rsp); err != nil {
+			t.Fatalf("Unexpected call err: %v", err)
+		}
+	}
+
+	for i := 0; i < 2; i++ {
+		select {
+		case <-mirrored:
+		case <-time.After(time.Second):
+			t.Fatal("Expected the call to be mirrored")
+		}
+	}
+	select {
+	case <-mirrored:
+		t.Fatal("Expected the calls over the limit to be dropped")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	close(release)
+}
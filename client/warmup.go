@@ -0,0 +1,60 @@
+package client
+
+import (
+	"sync"
+
+	"github.com/micro/go-micro/v2/client/selector"
+	"github.com/micro/go-micro/v2/logger"
+	"github.com/micro/go-micro/v2/registry"
+)
+
+// DialFunc establishes a pooled connection to the node of a service
+type DialFunc func(service string, node *registry.Node) error
+
+// Warm resolves the services of the Warmup option with the selector and
+// dials each of their nodes, so the first calls to them don't pay for the
+// discovery and the connection. It's used by the clients on startup.
+func Warm(opts Options, dial DialFunc) {
+	var wg sync.WaitGroup
+
+	for _, service := range opts.Warmup {
+		wg.Add(1)
+
+		go func(service string) {
+			defer wg.Done()
+
+			var nodes []*registry.Node
+			seen := make(map[string]bool)
+
+			// collect the addresses the selector resolved
+			collect := func(services []*registry.Service) []*registry.Service {
+				for _, s := range services {
+					for _, node := range s.Nodes {
+						if !seen[node.Address] {
+							seen[node.Address] = true
+							nodes = append(nodes, node)
+						}
+					}
+				}
+				return services
+			}
+
+			if _, err := opts.Selector.Select(service, selector.WithFilter(collect)); err != nil {
+				if logger.V(logger.WarnLevel, logger.DefaultLogger) {
+					logger.Warnf("Warmup failed to resolve %s: %v", service, err)
+				}
+				return
+			}
+
+			for _, node := range nodes {
+				if err := dial(service, node); err != nil {
+					if logger.V(logger.WarnLevel, logger.DefaultLogger) {
+						logger.Warnf("Warmup failed to dial %s at %s: %v", service, node.Address, err)
+					}
+				}
+			}
+		}(service)
+	}
+
+	wg.Wait()
+}
@@ -0,0 +1,51 @@
+package client
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/micro/go-micro/v2/client/selector"
+	"github.com/micro/go-micro/v2/transport"
+)
+
+type testTransport struct {
+	sync.Mutex
+	transport.Transport
+	dialed map[string]bool
+}
+
+func (t *testTransport) Dial(addr string, opts ...transport.DialOption) (transport.Client, error) {
+	t.Lock()
+	t.dialed[addr] = true
+	t.Unlock()
+	return nil, errors.New("not connected")
+}
+
+func (t *testTransport) Dialed() int {
+	t.Lock()
+	defer t.Unlock()
+	return len(t.dialed)
+}
+
+func TestWarmup(t *testing.T) {
+	tr := &testTransport{dialed: make(map[string]bool)}
+
+	NewClient(
+		Transport(tr),
+		Selector(selector.NewSelector(selector.Registry(newTestRegistry()))),
+		Warmup("foo"),
+	)
+
+	// the nodes of every version of foo are dialed
+	for i := 0; i < 100 && tr.Dialed() < 3; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	tr.Lock()
+	defer tr.Unlock()
+	if len(tr.dialed) != 3 || !tr.dialed["localhost:9999"] || !tr.dialed["localhost:6666"] || !tr.dialed["localhost:8888"] {
+		t.Fatalf("Expected the nodes of foo dialed got %v", tr.dialed)
+	}
+}
@@ -0,0 +1,166 @@
+// Package avro provides an avro codec using a confluent compatible schema
+// registry. The payloads have the confluent wire format: a zero magic byte,
+// the 4 byte big endian id of the writer schema in the registry and the avro
+// binary encoding of the value. The values encoded implement Record and their
+// schema is registered under its full name. The values are decoded with the
+// writer schema of their id into structs, maps or a Datum.
+package avro
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"reflect"
+
+	"github.com/micro/go-micro/v2/codec"
+)
+
+const magic byte = 0
+
+var (
+	// DefaultRegistry is the schema registry of the codec and marshaler
+	DefaultRegistry = NewRegistry()
+
+	ErrInvalidData = errors.New("avro: invalid data, expected the confluent wire format")
+)
+
+// Record is a value encoded with its avro schema
+type Record interface {
+	AvroSchema() string
+}
+
+// Datum is a generic value and its avro schema. The values decoded into a
+// datum are nil, bool, int32, int64, float32, float64, []byte, string,
+// []interface{} or map[string]interface{} for the arrays, maps and records.
+type Datum struct {
+	Schema string
+	Value  interface{}
+}
+
+func (d *Datum) AvroSchema() string {
+	return d.Schema
+}
+
+// subject of the schema using the record name strategy
+func subject(s *schema) string {
+	if len(s.Name) > 0 {
+		return s.Name
+	}
+	return s.Type
+}
+
+func marshal(r Registry, v interface{}) ([]byte, error) {
+	rec, ok := v.(Record)
+	if !ok {
+		return nil, fmt.Errorf("avro: %T is not a record", v)
+	}
+
+	schema := rec.AvroSchema()
+	s, err := parseSchema(schema)
+	if err != nil {
+		return nil, err
+	}
+	id, err := r.Register(subject(s), schema)
+	if err != nil {
+		return nil, err
+	}
+
+	if d, ok := v.(*Datum); ok {
+		v = d.Value
+	}
+	data, err := encode(s, v)
+	if err != nil {
+		return nil, err
+	}
+
+	buf := make([]byte, 5, 5+len(data))
+	buf[0] = magic
+	binary.BigEndian.PutUint32(buf[1:], uint32(id))
+	return append(buf, data...), nil
+}
+
+func unmarshal(r Registry, data []byte, v interface{}) error {
+	if len(data) < 5 || data[0] != magic {
+		return ErrInvalidData
+	}
+
+	schema, err := r.Schema(int(binary.BigEndian.Uint32(data[1:5])))
+	if err != nil {
+		return err
+	}
+	s, err := parseSchema(schema)
+	if err != nil {
+		return err
+	}
+
+	if d, ok := v.(*Datum); ok {
+		d.Schema = schema
+		return decode(s, data[5:], &d.Value)
+	}
+	return decode(s, data[5:], v)
+}
+
+type Codec struct {
+	Conn     io.ReadWriteCloser
+	Registry Registry
+}
+
+func (c *Codec) ReadHeader(m *codec.Message, t codec.MessageType) error {
+	return nil
+}
+
+func (c *Codec) ReadBody(b interface{}) error {
+	buf, err := ioutil.ReadAll(c.Conn)
+	if err != nil {
+		return err
+	}
+	if b == nil || len(buf) == 0 {
+		return nil
+	}
+	if rv := reflect.ValueOf(b); rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return codec.ErrInvalidMessage
+	}
+	return unmarshal(c.Registry, buf, b)
+}
+
+func (c *Codec) Write(m *codec.Message, b interface{}) error {
+	if b == nil {
+		return nil
+	}
+	buf, err := marshal(c.Registry, b)
+	if err != nil {
+		return err
+	}
+	_, err = c.Conn.Write(buf)
+	return err
+}
+
+func (c *Codec) Close() error {
+	return c.Conn.Close()
+}
+
+func (c *Codec) String() string {
+	return "avro"
+}
+
+// NewCodec returns a codec using the DefaultRegistry
+func NewCodec(c io.ReadWriteCloser) codec.Codec {
+	return &Codec{
+		Conn:     c,
+		Registry: DefaultRegistry,
+	}
+}
+
+// WithRegistry returns the constructor of the codecs using the registry e.g
+//
+//	client.Codec("application/avro", avro.WithRegistry(reg))
+func WithRegistry(r Registry) codec.NewCodec {
+	return func(c io.ReadWriteCloser) codec.Codec {
+		return &Codec{
+			Conn:     c,
+			Registry: r,
+		}
+	}
+}
@@ -0,0 +1,204 @@
+package avro
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/micro/go-micro/v2/codec"
+)
+
+const userSchema = `{
+	"type": "record",
+	"name": "User",
+	"namespace": "go.micro.test",
+	"fields": [
+		{"name": "name", "type": "string"},
+		{"name": "age", "type": "int"},
+		{"name": "score", "type": "double"},
+		{"name": "email", "type": ["null", "string"], "default": null},
+		{"name": "role", "type": {"type": "enum", "name": "Role", "symbols": ["USER", "ADMIN"]}},
+		{"name": "tags", "type": {"type": "array", "items": "string"}},
+		{"name": "attrs", "type": {"type": "map", "values": "long"}},
+		{"name": "id", "type": {"type": "fixed", "name": "Id", "size": 4}},
+		{"name": "friend", "type": ["null", "User"]}
+	]
+}`
+
+type User struct {
+	Name   string           `json:"name"`
+	Age    int              `json:"age"`
+	Score  float64          `json:"score"`
+	Email  *string          `json:"email"`
+	Role   string           `json:"role"`
+	Tags   []string         `json:"tags"`
+	Attrs  map[string]int64 `json:"attrs"`
+	Id     [4]byte          `json:"id"`
+	Friend *User            `json:"friend"`
+}
+
+func (u *User) AvroSchema() string {
+	return userSchema
+}
+
+// UserV1 is an older reader of the users
+type UserV1 struct {
+	Name    string `avro:"name"`
+	Age     int32  `avro:"age"`
+	Country string `avro:"country"`
+}
+
+type testRegistry struct {
+	sync.Mutex
+	schemas  []string
+	subjects map[string]int
+}
+
+func newTestRegistry() *httptest.Server {
+	r := &testRegistry{subjects: make(map[string]int)}
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		r.Lock()
+		defer r.Unlock()
+
+		w.Header().Set("Content-Type", contentType)
+
+		var id int
+		if _, err := fmt.Sscanf(req.URL.Path, "/schemas/ids/%d", &id); err == nil {
+			if id < 1 || id > len(r.schemas) {
+				w.WriteHeader(http.StatusNotFound)
+				json.NewEncoder(w).Encode(registryError{Code: 40403, Message: "Schema not found"})
+				return
+			}
+			json.NewEncoder(w).Encode(map[string]string{"schema": r.schemas[id-1]})
+			return
+		}
+
+		if req.Method == "POST" && strings.HasPrefix(req.URL.Path, "/subjects/") {
+			var body map[string]string
+			json.NewDecoder(req.Body).Decode(&body)
+			key := req.URL.Path + body["schema"]
+			if _, ok := r.subjects[key]; !ok {
+				r.schemas = append(r.schemas, body["schema"])
+				r.subjects[key] = len(r.schemas)
+			}
+			json.NewEncoder(w).Encode(map[string]int{"id": r.subjects[key]})
+			return
+		}
+
+		w.WriteHeader(http.StatusNotFound)
+	}))
+}
+
+func TestMarshaler(t *testing.T) {
+	srv := newTestRegistry()
+	defer srv.Close()
+
+	m := Marshaler{Registry: NewRegistry(Address(srv.URL))}
+
+	email := "john@example.com"
+	u := &User{
+		Name:   "john",
+		Age:    42,
+		Score:  1.5,
+		Email:  &email,
+		Role:   "ADMIN",
+		Tags:   []string{"a", "b"},
+		Attrs:  map[string]int64{"logins": -3},
+		Id:     [4]byte{1, 2, 3, 4},
+		Friend: &User{Name: "jane", Role: "USER"},
+	}
+
+	b, err := m.Marshal(u)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b[0] != 0 || binary.BigEndian.Uint32(b[1:5]) != 1 {
+		t.Fatalf("Expected the confluent wire format with schema id 1 got %v", b[:5])
+	}
+
+	var got User
+	if err := m.Unmarshal(b, &got); err != nil {
+		t.Fatal(err)
+	}
+	// decoded as empty rather than nil
+	u.Friend.Tags = []string{}
+	u.Friend.Attrs = map[string]int64{}
+	if !reflect.DeepEqual(u, &got) {
+		t.Fatalf("Expected %+v got %+v", u, got)
+	}
+
+	// resolved against an older reader
+	var old UserV1
+	if err := m.Unmarshal(b, &old); err != nil {
+		t.Fatal(err)
+	}
+	if old.Name != "john" || old.Age != 42 || len(old.Country) > 0 {
+		t.Fatalf("Unexpected user %+v", old)
+	}
+
+	// generic datum with a writer schema of its own
+	d := &Datum{
+		Schema: `{"type": "record", "name": "Event", "fields": [{"name": "id", "type": "long"}]}`,
+		Value:  map[string]interface{}{"id": 7},
+	}
+	b, err = m.Marshal(d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if binary.BigEndian.Uint32(b[1:5]) != 2 {
+		t.Fatalf("Expected schema id 2 got %v", b[:5])
+	}
+	got2 := new(Datum)
+	if err := m.Unmarshal(b, got2); err != nil {
+		t.Fatal(err)
+	}
+	if got2.Schema != d.Schema || !reflect.DeepEqual(got2.Value, map[string]interface{}{"id": int64(7)}) {
+		t.Fatalf("Unexpected datum %+v", got2)
+	}
+
+	if err := m.Unmarshal([]byte("{}"), &got); err != ErrInvalidData {
+		t.Fatalf("Expected invalid data got %v", err)
+	}
+	if err := m.Unmarshal([]byte{0, 0, 0, 0, 9}, &got); err == nil {
+		t.Fatal("Expected an unknown schema id error")
+	}
+}
+
+type rwc struct {
+	*bytes.Buffer
+}
+
+func (rwc) Close() error {
+	return nil
+}
+
+func TestCodec(t *testing.T) {
+	srv := newTestRegistry()
+	defer srv.Close()
+
+	buf := rwc{new(bytes.Buffer)}
+	c := WithRegistry(NewRegistry(Address(srv.URL)))(buf)
+
+	if err := c.Write(&codec.Message{}, &User{Name: "john", Role: "USER"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Write(&codec.Message{}, "john"); err == nil {
+		t.Fatal("Expected an error writing a value with no schema")
+	}
+
+	var u User
+	if err := c.ReadBody(&u); err != nil {
+		t.Fatal(err)
+	}
+	if u.Name != "john" || u.Role != "USER" {
+		t.Fatalf("Unexpected user %+v", u)
+	}
+}
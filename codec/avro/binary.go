@@ -0,0 +1,630 @@
+package avro
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+var (
+	errShortBuffer = errors.New("avro: unexpected end of data")
+
+	// the struct field indexes by avro name of the types
+	structFields sync.Map
+)
+
+// fieldsOf returns the index of the struct fields by their name. The name is
+// the avro tag, else the json tag, else the lowercased field name.
+func fieldsOf(t reflect.Type) map[string]int {
+	if v, ok := structFields.Load(t); ok {
+		return v.(map[string]int)
+	}
+
+	fields := make(map[string]int)
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if len(f.PkgPath) > 0 {
+			continue
+		}
+		name := f.Tag.Get("avro")
+		if len(name) == 0 {
+			name = strings.Split(f.Tag.Get("json"), ",")[0]
+		}
+		if name == "-" {
+			continue
+		}
+		if len(name) == 0 {
+			name = strings.ToLower(f.Name)
+		}
+		fields[name] = i
+	}
+
+	structFields.Store(t, fields)
+	return fields
+}
+
+// lookup returns the struct field or map value of the record field
+func lookup(v reflect.Value, name string) (reflect.Value, bool) {
+	switch v.Kind() {
+	case reflect.Struct:
+		fields := fieldsOf(v.Type())
+		i, ok := fields[name]
+		if !ok {
+			i, ok = fields[strings.ToLower(name)]
+		}
+		if !ok {
+			return reflect.Value{}, false
+		}
+		return v.Field(i), true
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String {
+			return reflect.Value{}, false
+		}
+		f := v.MapIndex(reflect.ValueOf(name).Convert(v.Type().Key()))
+		return f, f.IsValid()
+	}
+	return reflect.Value{}, false
+}
+
+func indirect(v reflect.Value) reflect.Value {
+	for v.IsValid() && (v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface) {
+		if v.IsNil() {
+			return reflect.Value{}
+		}
+		v = v.Elem()
+	}
+	return v
+}
+
+type encoder struct {
+	buf *bytes.Buffer
+	tmp [binary.MaxVarintLen64]byte
+}
+
+func (e *encoder) writeLong(n int64) {
+	i := binary.PutUvarint(e.tmp[:], uint64((n<<1)^(n>>63)))
+	e.buf.Write(e.tmp[:i])
+}
+
+func (e *encoder) writeBytes(b []byte) {
+	e.writeLong(int64(len(b)))
+	e.buf.Write(b)
+}
+
+func toInt(v reflect.Value) (int64, bool) {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int(), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return int64(v.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return int64(v.Float()), true
+	}
+	return 0, false
+}
+
+func toFloat(v reflect.Value) (float64, bool) {
+	switch v.Kind() {
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), true
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return float64(v.Uint()), true
+	}
+	return 0, false
+}
+
+func toBytes(v reflect.Value) ([]byte, bool) {
+	switch v.Kind() {
+	case reflect.String:
+		return []byte(v.String()), true
+	case reflect.Slice:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			return v.Bytes(), true
+		}
+	case reflect.Array:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			b := make([]byte, v.Len())
+			reflect.Copy(reflect.ValueOf(b), v)
+			return b, true
+		}
+	}
+	return nil, false
+}
+
+// matches returns whether the value can be encoded as the branch of a union
+func matches(s *schema, v reflect.Value) bool {
+	if !v.IsValid() {
+		return s.Type == "null"
+	}
+	switch s.Type {
+	case "boolean":
+		return v.Kind() == reflect.Bool
+	case "int", "long":
+		_, ok := toInt(v)
+		return ok && v.Kind() != reflect.Float32 && v.Kind() != reflect.Float64
+	case "float", "double":
+		_, ok := toFloat(v)
+		return ok
+	case "bytes", "fixed":
+		_, ok := toBytes(v)
+		return ok && v.Kind() != reflect.String
+	case "string", "enum":
+		return v.Kind() == reflect.String
+	case "record":
+		return v.Kind() == reflect.Struct || v.Kind() == reflect.Map
+	case "array":
+		return v.Kind() == reflect.Slice || v.Kind() == reflect.Array
+	case "map":
+		return v.Kind() == reflect.Map
+	}
+	return false
+}
+
+func (e *encoder) encode(s *schema, v reflect.Value) error {
+	v = indirect(v)
+
+	if s.Type == "union" {
+		for i, b := range s.Union {
+			if matches(b, v) {
+				e.writeLong(int64(i))
+				return e.encode(b, v)
+			}
+		}
+		// fall back to the first non null branch e.g json numbers as longs
+		for i, b := range s.Union {
+			if b.Type != "null" && v.IsValid() {
+				e.writeLong(int64(i))
+				return e.encode(b, v)
+			}
+		}
+		return fmt.Errorf("avro: %v matches no branch of the union", v)
+	}
+
+	if s.Type == "null" {
+		return nil
+	}
+	if !v.IsValid() {
+		return fmt.Errorf("avro: nil value for %s", s.Type)
+	}
+
+	switch s.Type {
+	case "boolean":
+		if v.Kind() != reflect.Bool {
+			break
+		}
+		if v.Bool() {
+			e.buf.WriteByte(1)
+		} else {
+			e.buf.WriteByte(0)
+		}
+		return nil
+	case "int", "long":
+		n, ok := toInt(v)
+		if !ok {
+			break
+		}
+		if s.Type == "int" && (n > math.MaxInt32 || n < math.MinInt32) {
+			return fmt.Errorf("avro: %d overflows int", n)
+		}
+		e.writeLong(n)
+		return nil
+	case "float":
+		f, ok := toFloat(v)
+		if !ok {
+			break
+		}
+		binary.LittleEndian.PutUint32(e.tmp[:4], math.Float32bits(float32(f)))
+		e.buf.Write(e.tmp[:4])
+		return nil
+	case "double":
+		f, ok := toFloat(v)
+		if !ok {
+			break
+		}
+		binary.LittleEndian.PutUint64(e.tmp[:8], math.Float64bits(f))
+		e.buf.Write(e.tmp[:8])
+		return nil
+	case "bytes", "string":
+		b, ok := toBytes(v)
+		if !ok {
+			break
+		}
+		e.writeBytes(b)
+		return nil
+	case "fixed":
+		b, ok := toBytes(v)
+		if !ok {
+			break
+		}
+		if len(b) != s.Size {
+			return fmt.Errorf("avro: %d bytes for fixed %s of size %d", len(b), s.Name, s.Size)
+		}
+		e.buf.Write(b)
+		return nil
+	case "enum":
+		if v.Kind() == reflect.String {
+			for i, sym := range s.Symbols {
+				if sym == v.String() {
+					e.writeLong(int64(i))
+					return nil
+				}
+			}
+			return fmt.Errorf("avro: %s is not a symbol of enum %s", v.String(), s.Name)
+		}
+		n, ok := toInt(v)
+		if !ok {
+			break
+		}
+		if n < 0 || int(n) >= len(s.Symbols) {
+			return fmt.Errorf("avro: %d is not a symbol of enum %s", n, s.Name)
+		}
+		e.writeLong(n)
+		return nil
+	case "array":
+		if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+			break
+		}
+		if v.Len() > 0 {
+			e.writeLong(int64(v.Len()))
+			for i := 0; i < v.Len(); i++ {
+				if err := e.encode(s.Items, v.Index(i)); err != nil {
+					return err
+				}
+			}
+		}
+		e.writeLong(0)
+		return nil
+	case "map":
+		if v.Kind() != reflect.Map || v.Type().Key().Kind() != reflect.String {
+			break
+		}
+		if v.Len() > 0 {
+			e.writeLong(int64(v.Len()))
+			iter := v.MapRange()
+			for iter.Next() {
+				e.writeBytes([]byte(iter.Key().String()))
+				if err := e.encode(s.Values, iter.Value()); err != nil {
+					return err
+				}
+			}
+		}
+		e.writeLong(0)
+		return nil
+	case "record":
+		if v.Kind() != reflect.Struct && v.Kind() != reflect.Map {
+			break
+		}
+		for _, f := range s.Fields {
+			fv, ok := lookup(v, f.Name)
+			if !ok {
+				if !f.HasDefault {
+					return fmt.Errorf("avro: missing field %s of record %s", f.Name, s.Name)
+				}
+				fv = reflect.ValueOf(f.Default)
+			}
+			if err := e.encode(f.Type, fv); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	return fmt.Errorf("avro: cannot encode %s as %s", v.Type(), s.Type)
+}
+
+// encode the value with the avro schema
+func encode(s *schema, v interface{}) ([]byte, error) {
+	e := &encoder{buf: new(bytes.Buffer)}
+	if err := e.encode(s, reflect.ValueOf(v)); err != nil {
+		return nil, err
+	}
+	return e.buf.Bytes(), nil
+}
+
+type decoder struct {
+	r *bytes.Reader
+}
+
+func (d *decoder) readLong() (int64, error) {
+	u, err := binary.ReadUvarint(d.r)
+	if err != nil {
+		return 0, errShortBuffer
+	}
+	return int64(u>>1) ^ -int64(u&1), nil
+}
+
+func (d *decoder) readFixed(n int) ([]byte, error) {
+	if n < 0 || n > d.r.Len() {
+		return nil, errShortBuffer
+	}
+	b := make([]byte, n)
+	if _, err := io.ReadFull(d.r, b); err != nil {
+		return nil, errShortBuffer
+	}
+	return b, nil
+}
+
+func (d *decoder) readBytes() ([]byte, error) {
+	n, err := d.readLong()
+	if err != nil {
+		return nil, err
+	}
+	return d.readFixed(int(n))
+}
+
+// readBlock returns the count of the next block of an array or map
+func (d *decoder) readBlock() (int, error) {
+	n, err := d.readLong()
+	if err != nil {
+		return 0, err
+	}
+	if n < 0 {
+		// a negative count is followed by the size of the block
+		if _, err := d.readLong(); err != nil {
+			return 0, err
+		}
+		n = -n
+	}
+	if n > int64(d.r.Len()) {
+		return 0, errShortBuffer
+	}
+	return int(n), nil
+}
+
+// decode the generic value of the schema: nil, bool, int32, int64, float32,
+// float64, []byte, string, []interface{} and map[string]interface{} for the
+// arrays, maps and records
+func (d *decoder) decode(s *schema) (interface{}, error) {
+	switch s.Type {
+	case "null":
+		return nil, nil
+	case "boolean":
+		b, err := d.r.ReadByte()
+		if err != nil {
+			return nil, errShortBuffer
+		}
+		return b != 0, nil
+	case "int":
+		n, err := d.readLong()
+		return int32(n), err
+	case "long":
+		return d.readLong()
+	case "float":
+		b, err := d.readFixed(4)
+		if err != nil {
+			return nil, err
+		}
+		return math.Float32frombits(binary.LittleEndian.Uint32(b)), nil
+	case "double":
+		b, err := d.readFixed(8)
+		if err != nil {
+			return nil, err
+		}
+		return math.Float64frombits(binary.LittleEndian.Uint64(b)), nil
+	case "bytes":
+		return d.readBytes()
+	case "string":
+		b, err := d.readBytes()
+		return string(b), err
+	case "fixed":
+		return d.readFixed(s.Size)
+	case "enum":
+		n, err := d.readLong()
+		if err != nil {
+			return nil, err
+		}
+		if n < 0 || int(n) >= len(s.Symbols) {
+			return nil, fmt.Errorf("avro: invalid symbol %d of enum %s", n, s.Name)
+		}
+		return s.Symbols[n], nil
+	case "union":
+		n, err := d.readLong()
+		if err != nil {
+			return nil, err
+		}
+		if n < 0 || int(n) >= len(s.Union) {
+			return nil, fmt.Errorf("avro: invalid union branch %d", n)
+		}
+		return d.decode(s.Union[n])
+	case "array":
+		items := []interface{}{}
+		for {
+			n, err := d.readBlock()
+			if err != nil {
+				return nil, err
+			}
+			if n == 0 {
+				return items, nil
+			}
+			for i := 0; i < n; i++ {
+				item, err := d.decode(s.Items)
+				if err != nil {
+					return nil, err
+				}
+				items = append(items, item)
+			}
+		}
+	case "map":
+		values := make(map[string]interface{})
+		for {
+			n, err := d.readBlock()
+			if err != nil {
+				return nil, err
+			}
+			if n == 0 {
+				return values, nil
+			}
+			for i := 0; i < n; i++ {
+				k, err := d.readBytes()
+				if err != nil {
+					return nil, err
+				}
+				v, err := d.decode(s.Values)
+				if err != nil {
+					return nil, err
+				}
+				values[string(k)] = v
+			}
+		}
+	case "record":
+		record := make(map[string]interface{}, len(s.Fields))
+		for _, f := range s.Fields {
+			v, err := d.decode(f.Type)
+			if err != nil {
+				return nil, err
+			}
+			record[f.Name] = v
+		}
+		return record, nil
+	}
+	return nil, fmt.Errorf("avro: unknown type %s", s.Type)
+}
+
+// assign the generic value decoded to v. The fields of the records missing
+// from the struct are skipped and the struct fields missing from the record
+// are left as is, which resolves the writer schema against the reader type.
+func assign(v reflect.Value, src interface{}) error {
+	switch v.Kind() {
+	case reflect.Interface:
+		if src == nil {
+			v.Set(reflect.Zero(v.Type()))
+			return nil
+		}
+		sv := reflect.ValueOf(src)
+		if !sv.Type().AssignableTo(v.Type()) {
+			return fmt.Errorf("avro: cannot decode %T into %s", src, v.Type())
+		}
+		v.Set(sv)
+		return nil
+	case reflect.Ptr:
+		if src == nil {
+			v.Set(reflect.Zero(v.Type()))
+			return nil
+		}
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		return assign(v.Elem(), src)
+	}
+
+	if src == nil {
+		v.Set(reflect.Zero(v.Type()))
+		return nil
+	}
+	sv := reflect.ValueOf(src)
+
+	switch v.Kind() {
+	case reflect.Bool:
+		if sv.Kind() == reflect.Bool {
+			v.SetBool(sv.Bool())
+			return nil
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if n, ok := toInt(sv); ok && sv.Kind() != reflect.Float32 && sv.Kind() != reflect.Float64 {
+			if v.OverflowInt(n) {
+				return fmt.Errorf("avro: %d overflows %s", n, v.Type())
+			}
+			v.SetInt(n)
+			return nil
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if n, ok := toInt(sv); ok && sv.Kind() != reflect.Float32 && sv.Kind() != reflect.Float64 {
+			if n < 0 || v.OverflowUint(uint64(n)) {
+				return fmt.Errorf("avro: %d overflows %s", n, v.Type())
+			}
+			v.SetUint(uint64(n))
+			return nil
+		}
+	case reflect.Float32, reflect.Float64:
+		if f, ok := toFloat(sv); ok {
+			v.SetFloat(f)
+			return nil
+		}
+	case reflect.String:
+		if b, ok := toBytes(sv); ok {
+			v.SetString(string(b))
+			return nil
+		}
+	case reflect.Slice:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			if b, ok := toBytes(sv); ok {
+				v.SetBytes(b)
+				return nil
+			}
+		}
+		items, ok := src.([]interface{})
+		if !ok {
+			break
+		}
+		s := reflect.MakeSlice(v.Type(), len(items), len(items))
+		for i, item := range items {
+			if err := assign(s.Index(i), item); err != nil {
+				return err
+			}
+		}
+		v.Set(s)
+		return nil
+	case reflect.Array:
+		b, ok := src.([]byte)
+		if !ok || v.Type().Elem().Kind() != reflect.Uint8 || len(b) != v.Len() {
+			break
+		}
+		reflect.Copy(v, reflect.ValueOf(b))
+		return nil
+	case reflect.Map:
+		values, ok := src.(map[string]interface{})
+		if !ok || v.Type().Key().Kind() != reflect.String {
+			break
+		}
+		m := reflect.MakeMapWithSize(v.Type(), len(values))
+		for k, value := range values {
+			e := reflect.New(v.Type().Elem()).Elem()
+			if err := assign(e, value); err != nil {
+				return err
+			}
+			m.SetMapIndex(reflect.ValueOf(k).Convert(v.Type().Key()), e)
+		}
+		v.Set(m)
+		return nil
+	case reflect.Struct:
+		record, ok := src.(map[string]interface{})
+		if !ok {
+			break
+		}
+		for name, value := range record {
+			f, ok := lookup(v, name)
+			if !ok {
+				continue
+			}
+			if err := assign(f, value); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	return fmt.Errorf("avro: cannot decode %T into %s", src, v.Type())
+}
+
+// decode the data written with the avro schema into v
+func decode(s *schema, data []byte, v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return fmt.Errorf("avro: cannot decode into non pointer %T", v)
+	}
+
+	d := &decoder{r: bytes.NewReader(data)}
+	src, err := d.decode(s)
+	if err != nil {
+		return err
+	}
+	return assign(rv.Elem(), src)
+}
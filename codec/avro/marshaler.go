@@ -0,0 +1,26 @@
+package avro
+
+// Marshaler encodes the broker messages with the confluent wire format.
+// It uses the DefaultRegistry if the Registry isn't set.
+type Marshaler struct {
+	Registry Registry
+}
+
+func (m Marshaler) registry() Registry {
+	if m.Registry == nil {
+		return DefaultRegistry
+	}
+	return m.Registry
+}
+
+func (m Marshaler) Marshal(v interface{}) ([]byte, error) {
+	return marshal(m.registry(), v)
+}
+
+func (m Marshaler) Unmarshal(d []byte, v interface{}) error {
+	return unmarshal(m.registry(), d, v)
+}
+
+func (m Marshaler) String() string {
+	return "avro"
+}
@@ -0,0 +1,173 @@
+package avro
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Registry resolves the avro schemas by their id
+type Registry interface {
+	// Schema returns the schema of the id
+	Schema(id int) (string, error)
+	// Register the schema under the subject and return its id
+	Register(subject, schema string) (int, error)
+}
+
+// RegistryOptions of the http schema registry
+type RegistryOptions struct {
+	// Address of the registry e.g http://localhost:8081
+	Address  string
+	Username string
+	Password string
+	Timeout  time.Duration
+}
+
+type RegistryOption func(o *RegistryOptions)
+
+// Address of the schema registry
+func Address(addr string) RegistryOption {
+	return func(o *RegistryOptions) {
+		o.Address = addr
+	}
+}
+
+// BasicAuth sets the credentials of the schema registry
+func BasicAuth(username, password string) RegistryOption {
+	return func(o *RegistryOptions) {
+		o.Username = username
+		o.Password = password
+	}
+}
+
+// Timeout of the requests to the schema registry
+func Timeout(t time.Duration) RegistryOption {
+	return func(o *RegistryOptions) {
+		o.Timeout = t
+	}
+}
+
+const contentType = "application/vnd.schemaregistry.v1+json"
+
+type httpRegistry struct {
+	opts   RegistryOptions
+	client *http.Client
+
+	sync.RWMutex
+	// the schemas by id and the ids by subject and schema
+	schemas map[int]string
+	ids     map[string]int
+}
+
+type registryError struct {
+	Code    int    `json:"error_code"`
+	Message string `json:"message"`
+}
+
+func (r *httpRegistry) do(method, path string, in, out interface{}) error {
+	var body bytes.Buffer
+	if in != nil {
+		if err := json.NewEncoder(&body).Encode(in); err != nil {
+			return err
+		}
+	}
+
+	req, err := http.NewRequest(method, strings.TrimSuffix(r.opts.Address, "/")+path, &body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", contentType)
+	if in != nil {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if len(r.opts.Username) > 0 {
+		req.SetBasicAuth(r.opts.Username, r.opts.Password)
+	}
+
+	rsp, err := r.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer rsp.Body.Close()
+
+	if rsp.StatusCode != http.StatusOK {
+		var rerr registryError
+		if err := json.NewDecoder(rsp.Body).Decode(&rerr); err != nil || len(rerr.Message) == 0 {
+			return fmt.Errorf("schema registry: %s", rsp.Status)
+		}
+		return fmt.Errorf("schema registry: %s (%d)", rerr.Message, rerr.Code)
+	}
+
+	return json.NewDecoder(rsp.Body).Decode(out)
+}
+
+func (r *httpRegistry) Schema(id int) (string, error) {
+	r.RLock()
+	s, ok := r.schemas[id]
+	r.RUnlock()
+	if ok {
+		return s, nil
+	}
+
+	var rsp struct {
+		Schema string `json:"schema"`
+	}
+	if err := r.do("GET", fmt.Sprintf("/schemas/ids/%d", id), nil, &rsp); err != nil {
+		return "", err
+	}
+
+	r.Lock()
+	r.schemas[id] = rsp.Schema
+	r.Unlock()
+	return rsp.Schema, nil
+}
+
+func (r *httpRegistry) Register(subject, schema string) (int, error) {
+	key := subject + "/" + schema
+
+	r.RLock()
+	id, ok := r.ids[key]
+	r.RUnlock()
+	if ok {
+		return id, nil
+	}
+
+	req := map[string]string{"schema": schema}
+	var rsp struct {
+		Id int `json:"id"`
+	}
+	path := fmt.Sprintf("/subjects/%s/versions", url.PathEscape(subject))
+	if err := r.do("POST", path, req, &rsp); err != nil {
+		return 0, err
+	}
+
+	r.Lock()
+	r.ids[key] = rsp.Id
+	r.schemas[rsp.Id] = schema
+	r.Unlock()
+	return rsp.Id, nil
+}
+
+// NewRegistry returns a client of a confluent compatible schema registry.
+// The schemas and ids are cached as they're immutable.
+func NewRegistry(opts ...RegistryOption) Registry {
+	options := RegistryOptions{
+		Address: "http://localhost:8081",
+		Timeout: 10 * time.Second,
+	}
+	for _, o := range opts {
+		o(&options)
+	}
+
+	return &httpRegistry{
+		opts:    options,
+		client:  &http.Client{Timeout: options.Timeout},
+		schemas: make(map[int]string),
+		ids:     make(map[string]int),
+	}
+}
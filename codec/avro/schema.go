@@ -0,0 +1,192 @@
+package avro
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// schema is a parsed avro schema
+type schema struct {
+	// Type is the primitive type or record, enum, array, map, union or fixed
+	Type string
+	// Name is the full name of the named types
+	Name    string
+	Fields  []*field
+	Symbols []string
+	Items   *schema
+	Values  *schema
+	Union   []*schema
+	Size    int
+}
+
+type field struct {
+	Name       string
+	Type       *schema
+	Default    interface{}
+	HasDefault bool
+}
+
+var (
+	primitives = map[string]bool{
+		"null":    true,
+		"boolean": true,
+		"int":     true,
+		"long":    true,
+		"float":   true,
+		"double":  true,
+		"bytes":   true,
+		"string":  true,
+	}
+
+	// the parsed schemas by their json
+	schemas sync.Map
+)
+
+// parseSchema parses the json of an avro schema
+func parseSchema(s string) (*schema, error) {
+	if v, ok := schemas.Load(s); ok {
+		return v.(*schema), nil
+	}
+
+	var v interface{}
+	if err := json.Unmarshal([]byte(s), &v); err != nil {
+		return nil, fmt.Errorf("invalid avro schema: %v", err)
+	}
+
+	p := &parser{named: make(map[string]*schema)}
+	sc, err := p.parse(v, "")
+	if err != nil {
+		return nil, err
+	}
+
+	schemas.Store(s, sc)
+	return sc, nil
+}
+
+type parser struct {
+	named map[string]*schema
+}
+
+func fullName(name, namespace string) string {
+	if strings.Contains(name, ".") || len(namespace) == 0 {
+		return name
+	}
+	return namespace + "." + name
+}
+
+func (p *parser) parse(v interface{}, namespace string) (*schema, error) {
+	switch t := v.(type) {
+	case string:
+		if primitives[t] {
+			return &schema{Type: t}, nil
+		}
+		if s, ok := p.named[fullName(t, namespace)]; ok {
+			return s, nil
+		}
+		if s, ok := p.named[t]; ok {
+			return s, nil
+		}
+		return nil, fmt.Errorf("unknown avro type %s", t)
+	case []interface{}:
+		s := &schema{Type: "union"}
+		for _, b := range t {
+			bs, err := p.parse(b, namespace)
+			if err != nil {
+				return nil, err
+			}
+			s.Union = append(s.Union, bs)
+		}
+		return s, nil
+	case map[string]interface{}:
+		return p.parseComplex(t, namespace)
+	default:
+		return nil, fmt.Errorf("invalid avro schema %v", v)
+	}
+}
+
+func (p *parser) parseComplex(m map[string]interface{}, namespace string) (*schema, error) {
+	typ, _ := m["type"].(string)
+
+	switch typ {
+	case "record", "error", "enum", "fixed":
+	case "array":
+		items, err := p.parse(m["items"], namespace)
+		if err != nil {
+			return nil, err
+		}
+		return &schema{Type: "array", Items: items}, nil
+	case "map":
+		values, err := p.parse(m["values"], namespace)
+		if err != nil {
+			return nil, err
+		}
+		return &schema{Type: "map", Values: values}, nil
+	default:
+		// a primitive with attributes e.g a logical type
+		return p.parse(m["type"], namespace)
+	}
+
+	name, _ := m["name"].(string)
+	if len(name) == 0 {
+		return nil, fmt.Errorf("avro %s has no name", typ)
+	}
+	if ns, ok := m["namespace"].(string); ok && !strings.Contains(name, ".") {
+		namespace = ns
+	}
+	name = fullName(name, namespace)
+	if i := strings.LastIndex(name, "."); i > 0 {
+		namespace = name[:i]
+	}
+
+	s := &schema{Type: typ, Name: name}
+	if typ == "error" {
+		s.Type = "record"
+	}
+	// registered before the fields to allow recursive types
+	p.named[name] = s
+
+	switch s.Type {
+	case "enum":
+		symbols, _ := m["symbols"].([]interface{})
+		for _, sym := range symbols {
+			str, ok := sym.(string)
+			if !ok {
+				return nil, fmt.Errorf("invalid symbol %v of avro enum %s", sym, name)
+			}
+			s.Symbols = append(s.Symbols, str)
+		}
+	case "fixed":
+		size, ok := m["size"].(float64)
+		if !ok || size < 0 {
+			return nil, fmt.Errorf("invalid size of avro fixed %s", name)
+		}
+		s.Size = int(size)
+	case "record":
+		fields, _ := m["fields"].([]interface{})
+		for _, f := range fields {
+			fm, ok := f.(map[string]interface{})
+			if !ok {
+				return nil, fmt.Errorf("invalid field %v of avro record %s", f, name)
+			}
+			fname, _ := fm["name"].(string)
+			if len(fname) == 0 {
+				return nil, fmt.Errorf("avro record %s has a field with no name", name)
+			}
+			ft, err := p.parse(fm["type"], namespace)
+			if err != nil {
+				return nil, err
+			}
+			def, ok := fm["default"]
+			s.Fields = append(s.Fields, &field{
+				Name:       fname,
+				Type:       ft,
+				Default:    def,
+				HasDefault: ok,
+			})
+		}
+	}
+
+	return s, nil
+}
@@ -0,0 +1,151 @@
+// Package flatbuffers provides a flatbuffers codec. The bodies are read into
+// a Message as is and its tables are accessed in place e.g with the GetRootAs
+// functions generated by flatc, which avoids unmarshaling them. The messages
+// are written from their builder, which can be put back in a Pool once sent.
+package flatbuffers
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"sync"
+
+	"github.com/micro/go-micro/v2/codec"
+)
+
+// Builder builds a flatbuffer e.g *flatbuffers.Builder
+type Builder interface {
+	Reset()
+	FinishedBytes() []byte
+}
+
+// Pool of builders to reuse their buffers
+type Pool struct {
+	pool sync.Pool
+}
+
+// Get a builder from the pool
+func (p *Pool) Get() Builder {
+	return p.pool.Get().(Builder)
+}
+
+// Put the builder back in the pool
+func (p *Pool) Put(b Builder) {
+	b.Reset()
+	p.pool.Put(b)
+}
+
+// NewPool returns a pool of the builders created by fn e.g
+//
+//	pool := flatbuffers.NewPool(func() flatbuffers.Builder {
+//		return fb.NewBuilder(1024)
+//	})
+func NewPool(fn func() Builder) *Pool {
+	return &Pool{
+		pool: sync.Pool{
+			New: func() interface{} {
+				return fn()
+			},
+		},
+	}
+}
+
+// Message is the body of a request, response or event
+type Message struct {
+	// Data is the flatbuffer read or written, it must not be modified
+	Data []byte
+	// Builder of the flatbuffer to write. It's copied to
+	// the data and put back in the Pool once written.
+	Builder Builder
+	Pool    *Pool
+}
+
+// NewMessage returns a message with a builder from the pool
+func NewMessage(p *Pool) *Message {
+	return &Message{
+		Builder: p.Get(),
+		Pool:    p,
+	}
+}
+
+// Bytes returns the flatbuffer of the message
+func (m *Message) Bytes() []byte {
+	if m.Builder != nil {
+		return m.Builder.FinishedBytes()
+	}
+	return m.Data
+}
+
+// Release copies the flatbuffer of the builder to the
+// data and puts the builder back in the pool
+func (m *Message) Release() {
+	if m.Builder == nil || m.Pool == nil {
+		return
+	}
+	m.Data = append([]byte(nil), m.Builder.FinishedBytes()...)
+	m.Pool.Put(m.Builder)
+	m.Builder = nil
+}
+
+type Codec struct {
+	Conn io.ReadWriteCloser
+}
+
+func (c *Codec) ReadHeader(m *codec.Message, t codec.MessageType) error {
+	return nil
+}
+
+func (c *Codec) ReadBody(b interface{}) error {
+	buf, err := ioutil.ReadAll(c.Conn)
+	if err != nil {
+		return err
+	}
+
+	switch v := b.(type) {
+	case nil:
+	case *Message:
+		v.Data = buf
+	case *[]byte:
+		*v = buf
+	default:
+		return fmt.Errorf("failed to read body: %T is not a *flatbuffers.Message", b)
+	}
+	return nil
+}
+
+func (c *Codec) Write(m *codec.Message, b interface{}) error {
+	var buf []byte
+
+	switch v := b.(type) {
+	case nil:
+		return nil
+	case *Message:
+		v.Release()
+		buf = v.Bytes()
+	case Builder:
+		buf = v.FinishedBytes()
+	case []byte:
+		buf = v
+	case *[]byte:
+		buf = *v
+	default:
+		return fmt.Errorf("failed to write: %T is not a *flatbuffers.Message", b)
+	}
+
+	_, err := c.Conn.Write(buf)
+	return err
+}
+
+func (c *Codec) Close() error {
+	return c.Conn.Close()
+}
+
+func (c *Codec) String() string {
+	return "flatbuffers"
+}
+
+func NewCodec(c io.ReadWriteCloser) codec.Codec {
+	return &Codec{
+		Conn: c,
+	}
+}
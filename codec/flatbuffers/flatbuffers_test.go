@@ -0,0 +1,112 @@
+package flatbuffers_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/micro/go-micro/v2/broker"
+	bmemory "github.com/micro/go-micro/v2/broker/memory"
+	"github.com/micro/go-micro/v2/client"
+	"github.com/micro/go-micro/v2/codec/flatbuffers"
+	rmemory "github.com/micro/go-micro/v2/registry/memory"
+	"github.com/micro/go-micro/v2/server"
+	tmemory "github.com/micro/go-micro/v2/transport/memory"
+)
+
+// testBuilder stands in for a *flatbuffers.Builder
+type testBuilder struct {
+	buf []byte
+}
+
+func (b *testBuilder) Reset() {
+	b.buf = b.buf[:0]
+}
+
+func (b *testBuilder) FinishedBytes() []byte {
+	return b.buf
+}
+
+var pool = flatbuffers.NewPool(func() flatbuffers.Builder {
+	return &testBuilder{buf: make([]byte, 0, 64)}
+})
+
+type Echo struct{}
+
+func (e *Echo) Call(ctx context.Context, req *flatbuffers.Message, rsp *flatbuffers.Message) error {
+	b := pool.Get()
+	b.(*testBuilder).buf = append(b.(*testBuilder).buf, req.Data...)
+	rsp.Builder = b
+	rsp.Pool = pool
+	return nil
+}
+
+func TestCodec(t *testing.T) {
+	reg := rmemory.NewRegistry()
+	brk := bmemory.NewBroker(broker.Registry(reg))
+	tr := tmemory.NewTransport()
+
+	srv := server.NewServer(
+		server.Name("go.micro.service.flatbuffers"),
+		server.Registry(reg),
+		server.Broker(brk),
+		server.Transport(tr),
+	)
+	if err := srv.Handle(srv.NewHandler(&Echo{})); err != nil {
+		t.Fatal(err)
+	}
+	if err := srv.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer srv.Stop()
+
+	cli := client.NewClient(
+		client.Registry(reg),
+		client.Broker(brk),
+		client.Transport(tr),
+	)
+
+	for _, data := range []string{"hello", "world"} {
+		msg := flatbuffers.NewMessage(pool)
+		msg.Builder.(*testBuilder).buf = append(msg.Builder.(*testBuilder).buf, data...)
+
+		req := cli.NewRequest("go.micro.service.flatbuffers", "Echo.Call", msg, client.WithContentType("application/flatbuffers"))
+		rsp := new(flatbuffers.Message)
+		if err := cli.Call(context.Background(), req, rsp); err != nil {
+			t.Fatal(err)
+		}
+		if string(rsp.Data) != data {
+			t.Fatalf("Expected %s got %s", data, rsp.Data)
+		}
+		if msg.Builder != nil || string(msg.Data) != data {
+			t.Fatal("Expected the builder to be released once written")
+		}
+	}
+}
+
+func TestMarshaler(t *testing.T) {
+	var m flatbuffers.Marshaler
+
+	msg := flatbuffers.NewMessage(pool)
+	msg.Builder.(*testBuilder).buf = append(msg.Builder.(*testBuilder).buf, "foo"...)
+
+	b, err := m.Marshal(msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the released builder is reused without changing the data
+	reused := pool.Get().(*testBuilder)
+	reused.buf = append(reused.buf, "bar"...)
+
+	var got flatbuffers.Message
+	if err := m.Unmarshal(b, &got); err != nil {
+		t.Fatal(err)
+	}
+	if string(got.Data) != "foo" {
+		t.Fatalf("Expected foo got %s", got.Data)
+	}
+
+	if _, err := m.Marshal("foo"); err == nil {
+		t.Fatal("Expected an error marshaling a string")
+	}
+}
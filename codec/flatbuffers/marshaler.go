@@ -0,0 +1,37 @@
+package flatbuffers
+
+import (
+	"fmt"
+)
+
+// Marshaler of the flatbuffers of the broker messages
+type Marshaler struct{}
+
+func (m Marshaler) Marshal(v interface{}) ([]byte, error) {
+	switch b := v.(type) {
+	case *Message:
+		b.Release()
+		return b.Bytes(), nil
+	case Builder:
+		return b.FinishedBytes(), nil
+	case []byte:
+		return b, nil
+	}
+	return nil, fmt.Errorf("failed to marshal: %T is not a *flatbuffers.Message", v)
+}
+
+func (m Marshaler) Unmarshal(d []byte, v interface{}) error {
+	switch b := v.(type) {
+	case *Message:
+		b.Data = d
+	case *[]byte:
+		*b = d
+	default:
+		return fmt.Errorf("failed to unmarshal: %T is not a *flatbuffers.Message", v)
+	}
+	return nil
+}
+
+func (m Marshaler) String() string {
+	return "flatbuffers"
+}
@@ -0,0 +1,15 @@
+package protojson
+
+type Marshaler struct{}
+
+func (j Marshaler) Marshal(v interface{}) ([]byte, error) {
+	return Marshal(v)
+}
+
+func (j Marshaler) Unmarshal(d []byte, v interface{}) error {
+	return Unmarshal(d, v)
+}
+
+func (j Marshaler) String() string {
+	return "protojson"
+}
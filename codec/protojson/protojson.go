@@ -0,0 +1,91 @@
+// Package protojson provides a json codec encoding the proto messages with
+// the canonical proto3 json mapping e.g the enums by name, the 64 bit
+// integers as strings, the durations as "1.5s" and the timestamps in RFC 3339.
+// The other values are encoded with encoding/json. It changes the field names
+// and the 64 bit integers on the wire from the json codec so it's registered
+// for a content type by the services opting in e.g
+//
+//	server.Codec("application/json", protojson.NewCodec)
+package protojson
+
+import (
+	"encoding/json"
+	"io"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/micro/go-micro/v2/codec"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+var (
+	// MarshalOptions of the proto messages
+	MarshalOptions = protojson.MarshalOptions{}
+	// UnmarshalOptions of the proto messages, the unknown fields are
+	// discarded so the clients may be ahead of the services
+	UnmarshalOptions = protojson.UnmarshalOptions{DiscardUnknown: true}
+)
+
+// Marshal returns the json of v
+func Marshal(v interface{}) ([]byte, error) {
+	if pb, ok := v.(proto.Message); ok {
+		return MarshalOptions.Marshal(proto.MessageV2(pb))
+	}
+	return json.Marshal(v)
+}
+
+// Unmarshal the json into v
+func Unmarshal(d []byte, v interface{}) error {
+	if pb, ok := v.(proto.Message); ok {
+		return UnmarshalOptions.Unmarshal(d, proto.MessageV2(pb))
+	}
+	return json.Unmarshal(d, v)
+}
+
+type Codec struct {
+	Conn    io.ReadWriteCloser
+	Decoder *json.Decoder
+}
+
+func (c *Codec) ReadHeader(m *codec.Message, t codec.MessageType) error {
+	return nil
+}
+
+func (c *Codec) ReadBody(b interface{}) error {
+	if b == nil {
+		return nil
+	}
+	// read the next json value of the stream
+	var raw json.RawMessage
+	if err := c.Decoder.Decode(&raw); err != nil {
+		return err
+	}
+	return Unmarshal(raw, b)
+}
+
+func (c *Codec) Write(m *codec.Message, b interface{}) error {
+	if b == nil {
+		return nil
+	}
+	buf, err := Marshal(b)
+	if err != nil {
+		return err
+	}
+	// terminated by a newline like the json encoder
+	_, err = c.Conn.Write(append(buf, '\n'))
+	return err
+}
+
+func (c *Codec) Close() error {
+	return c.Conn.Close()
+}
+
+func (c *Codec) String() string {
+	return "protojson"
+}
+
+func NewCodec(c io.ReadWriteCloser) codec.Codec {
+	return &Codec{
+		Conn:    c,
+		Decoder: json.NewDecoder(c),
+	}
+}
@@ -0,0 +1,90 @@
+package protojson_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/golang/protobuf/ptypes/duration"
+	"github.com/golang/protobuf/ptypes/timestamp"
+	"github.com/micro/go-micro/v2/codec"
+	"github.com/micro/go-micro/v2/codec/protojson"
+	pb "github.com/micro/go-micro/v2/debug/service/proto"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+func TestMarshal(t *testing.T) {
+	testData := []struct {
+		msg      interface{}
+		expected string
+	}{
+		{&duration.Duration{Seconds: 1, Nanos: 500000000}, `"1.500s"`},
+		{&timestamp.Timestamp{Seconds: 1577934245}, `"2020-01-02T03:04:05Z"`},
+		{&healthpb.HealthCheckResponse{Status: healthpb.HealthCheckResponse_SERVING}, `{"status":"SERVING"}`},
+		{&pb.StatsResponse{Memory: 1 << 60}, `{"memory":"1152921504606846976"}`},
+		// not a proto message
+		{map[string]int{"count": 1}, `{"count":1}`},
+	}
+
+	for _, d := range testData {
+		b, err := protojson.Marshal(d.msg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// compacted as the spacing of protojson is unstable
+		var buf bytes.Buffer
+		if err := json.Compact(&buf, b); err != nil {
+			t.Fatal(err)
+		}
+		if buf.String() != d.expected {
+			t.Fatalf("Expected %s got %s", d.expected, buf.String())
+		}
+	}
+}
+
+func TestUnmarshal(t *testing.T) {
+	var rsp healthpb.HealthCheckResponse
+	// the enums by name or number and unknown fields discarded
+	if err := protojson.Unmarshal([]byte(`{"status": "NOT_SERVING", "unknown": 1}`), &rsp); err != nil {
+		t.Fatal(err)
+	}
+	if rsp.Status != healthpb.HealthCheckResponse_NOT_SERVING {
+		t.Fatalf("Expected NOT_SERVING got %v", rsp.Status)
+	}
+
+	var stats pb.StatsResponse
+	if err := protojson.Unmarshal([]byte(`{"memory": "1152921504606846976"}`), &stats); err != nil {
+		t.Fatal(err)
+	}
+	if stats.Memory != 1<<60 {
+		t.Fatalf("Expected %d got %d", uint64(1<<60), stats.Memory)
+	}
+}
+
+type rwc struct {
+	*bytes.Buffer
+}
+
+func (rwc) Close() error {
+	return nil
+}
+
+func TestCodec(t *testing.T) {
+	c := protojson.NewCodec(rwc{new(bytes.Buffer)})
+
+	// a stream of messages
+	for _, d := range []*duration.Duration{{Seconds: 1}, {Seconds: 2}} {
+		if err := c.Write(&codec.Message{}, d); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, expected := range []int64{1, 2} {
+		var d duration.Duration
+		if err := c.ReadBody(&d); err != nil {
+			t.Fatal(err)
+		}
+		if d.Seconds != expected {
+			t.Fatalf("Expected %ds got %ds", expected, d.Seconds)
+		}
+	}
+}
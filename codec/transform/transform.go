@@ -0,0 +1,193 @@
+// Package transform chains transforms of the payloads e.g compression,
+// encryption and signing around a codec. The transforms are applied in order
+// when writing and undone in reverse order when reading, and the Micro-Transform
+// header lists the ones applied e.g
+//
+//	cf := transform.NewCodec(json.NewCodec, transform.Compress("gzip"), transform.Encrypt(key))
+//	client.Codec("application/json", cf)
+//
+// The payloads are read with the transforms listed by their header, which must
+// include the ones required e.g encryption or signing. The responses are
+// written with the transforms the request used so the peers without the optional
+// ones e.g compression are served.
+package transform
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+
+	"github.com/micro/go-micro/v2/codec"
+)
+
+// Header lists the transforms applied to the payload in order
+const Header = "Micro-Transform"
+
+// Transform encodes the payloads and decodes them symmetrically
+type Transform interface {
+	Encode([]byte) ([]byte, error)
+	Decode([]byte) ([]byte, error)
+	// Required reports whether the payloads read must have been encoded
+	Required() bool
+	String() string
+}
+
+// rwc is the connection of the codec wrapped
+type rwc struct {
+	rbuf *bytes.Buffer
+	wbuf *bytes.Buffer
+}
+
+func (b *rwc) Read(p []byte) (int, error) {
+	return b.rbuf.Read(p)
+}
+
+func (b *rwc) Write(p []byte) (int, error) {
+	return b.wbuf.Write(p)
+}
+
+func (b *rwc) Close() error {
+	return nil
+}
+
+type transformCodec struct {
+	conn       io.ReadWriteCloser
+	buf        *rwc
+	codec      codec.Codec
+	transforms []Transform
+
+	// the transforms of the payload read, nil until one is read
+	read []Transform
+}
+
+func contains(ts []Transform, t Transform) bool {
+	for _, v := range ts {
+		if v.String() == t.String() {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *transformCodec) transform(name string) (Transform, bool) {
+	for _, t := range c.transforms {
+		if t.String() == name {
+			return t, true
+		}
+	}
+	return nil, false
+}
+
+func (c *transformCodec) ReadHeader(m *codec.Message, mt codec.MessageType) error {
+	data, err := ioutil.ReadAll(c.conn)
+	if err != nil {
+		return err
+	}
+
+	var header string
+	if m != nil && m.Header != nil {
+		header = m.Header[Header]
+	}
+
+	applied := []Transform{}
+	for _, name := range strings.Split(header, ",") {
+		name = strings.TrimSpace(name)
+		if len(name) == 0 {
+			continue
+		}
+		t, ok := c.transform(name)
+		if !ok {
+			return fmt.Errorf("unsupported payload transform %s", name)
+		}
+		applied = append(applied, t)
+	}
+
+	if len(data) > 0 {
+		// the payloads must have the required transforms
+		for _, t := range c.transforms {
+			if t.Required() && !contains(applied, t) {
+				return fmt.Errorf("payload transform %s required", t.String())
+			}
+		}
+
+		for i := len(applied); i > 0; i-- {
+			if data, err = applied[i-1].Decode(data); err != nil {
+				return fmt.Errorf("payload transform %s: %v", applied[i-1].String(), err)
+			}
+		}
+		c.read = applied
+	}
+
+	c.buf.rbuf.Reset()
+	c.buf.rbuf.Write(data)
+
+	return c.codec.ReadHeader(m, mt)
+}
+
+func (c *transformCodec) ReadBody(b interface{}) error {
+	return c.codec.ReadBody(b)
+}
+
+func (c *transformCodec) Write(m *codec.Message, b interface{}) error {
+	c.buf.wbuf.Reset()
+	if err := c.codec.Write(m, b); err != nil {
+		return err
+	}
+
+	data := c.buf.wbuf.Bytes()
+	if len(data) == 0 {
+		return nil
+	}
+
+	// respond with the transforms of the payload read
+	applied := c.transforms
+	if c.read != nil {
+		applied = c.read
+	}
+
+	names := make([]string, 0, len(applied))
+	for _, t := range applied {
+		var err error
+		if data, err = t.Encode(data); err != nil {
+			return fmt.Errorf("payload transform %s: %v", t.String(), err)
+		}
+		names = append(names, t.String())
+	}
+
+	if m != nil && len(names) > 0 {
+		if m.Header == nil {
+			m.Header = make(map[string]string)
+		}
+		m.Header[Header] = strings.Join(names, ",")
+	}
+
+	_, err := c.conn.Write(data)
+	return err
+}
+
+func (c *transformCodec) Close() error {
+	return c.conn.Close()
+}
+
+func (c *transformCodec) String() string {
+	return c.codec.String()
+}
+
+// NewCodec returns the constructor of the codecs applying the transforms to
+// the payloads of the codecs of c
+func NewCodec(c codec.NewCodec, transforms ...Transform) codec.NewCodec {
+	return func(conn io.ReadWriteCloser) codec.Codec {
+		buf := &rwc{
+			rbuf: new(bytes.Buffer),
+			wbuf: new(bytes.Buffer),
+		}
+		return &transformCodec{
+			conn:       conn,
+			buf:        buf,
+			codec:      c(buf),
+			transforms: transforms,
+		}
+	}
+}
@@ -0,0 +1,161 @@
+package transform_test
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/micro/go-micro/v2/broker"
+	bmemory "github.com/micro/go-micro/v2/broker/memory"
+	"github.com/micro/go-micro/v2/client"
+	"github.com/micro/go-micro/v2/codec"
+	"github.com/micro/go-micro/v2/codec/json"
+	"github.com/micro/go-micro/v2/codec/transform"
+	rmemory "github.com/micro/go-micro/v2/registry/memory"
+	"github.com/micro/go-micro/v2/server"
+	tmemory "github.com/micro/go-micro/v2/transport/memory"
+)
+
+type Msg struct {
+	Text string `json:"text"`
+}
+
+type Echo struct{}
+
+func (e *Echo) Call(ctx context.Context, req *Msg, rsp *Msg) error {
+	rsp.Text = req.Text
+	return nil
+}
+
+var key = []byte("0123456789abcdef")
+
+func TestCodec(t *testing.T) {
+	reg := rmemory.NewRegistry()
+	brk := bmemory.NewBroker(broker.Registry(reg))
+	tr := tmemory.NewTransport()
+
+	cf := transform.NewCodec(json.NewCodec, transform.Compress("gzip"), transform.Encrypt(key), transform.Sign(key))
+
+	srv := server.NewServer(
+		server.Name("go.micro.service.transform"),
+		server.Registry(reg),
+		server.Broker(brk),
+		server.Transport(tr),
+		server.Codec("application/json", cf),
+	)
+	if err := srv.Handle(srv.NewHandler(&Echo{})); err != nil {
+		t.Fatal(err)
+	}
+
+	events := make(chan string, 1)
+	if err := srv.Subscribe(srv.NewSubscriber("go.micro.topic.transform", func(ctx context.Context, msg *Msg) error {
+		events <- msg.Text
+		return nil
+	})); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := srv.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer srv.Stop()
+
+	cli := client.NewClient(
+		client.Registry(reg),
+		client.Broker(brk),
+		client.Transport(tr),
+		client.Codec("application/json", cf),
+	)
+
+	req := cli.NewRequest("go.micro.service.transform", "Echo.Call", &Msg{Text: "hello"}, client.WithContentType("application/json"))
+	rsp := new(Msg)
+	if err := cli.Call(context.Background(), req, rsp); err != nil {
+		t.Fatal(err)
+	}
+	if rsp.Text != "hello" {
+		t.Fatalf("Expected hello got %s", rsp.Text)
+	}
+
+	msg := cli.NewMessage("go.micro.topic.transform", &Msg{Text: "event"}, client.WithMessageContentType("application/json"))
+	if err := cli.Publish(context.Background(), msg); err != nil {
+		t.Fatal(err)
+	}
+	if text := <-events; text != "event" {
+		t.Fatalf("Expected event got %s", text)
+	}
+
+	// the payloads must be encrypted and signed
+	plain := client.NewClient(
+		client.Registry(reg),
+		client.Broker(brk),
+		client.Transport(tr),
+	)
+	if err := plain.Call(context.Background(), req, rsp, client.WithRetries(0)); err == nil || !strings.Contains(err.Error(), "required") {
+		t.Fatalf("Expected the plain request to be rejected got %v", err)
+	}
+}
+
+type rwc struct {
+	*bytes.Buffer
+}
+
+func (rwc) Close() error {
+	return nil
+}
+
+func TestNegotiate(t *testing.T) {
+	cf := transform.NewCodec(json.NewCodec, transform.Compress("snappy"))
+
+	// a request without the optional compression
+	req := rwc{bytes.NewBufferString(`{"text":"hello"}`)}
+	c := cf(req)
+
+	m := &codec.Message{Header: map[string]string{}}
+	if err := c.ReadHeader(m, codec.Request); err != nil {
+		t.Fatal(err)
+	}
+	var msg Msg
+	if err := c.ReadBody(&msg); err != nil {
+		t.Fatal(err)
+	}
+	if msg.Text != "hello" {
+		t.Fatalf("Expected hello got %s", msg.Text)
+	}
+
+	// is responded to without it
+	rsp := &codec.Message{Header: map[string]string{}}
+	if err := c.Write(rsp, &msg); err != nil {
+		t.Fatal(err)
+	}
+	if h := rsp.Header[transform.Header]; len(h) > 0 {
+		t.Fatalf("Expected no transforms got %s", h)
+	}
+	if s := strings.TrimSpace(req.String()); s != `{"text":"hello"}` {
+		t.Fatalf("Expected the plain response got %s", s)
+	}
+
+	// a request compressed by default
+	buf := rwc{new(bytes.Buffer)}
+	m = &codec.Message{Header: map[string]string{}}
+	if err := cf(buf).Write(m, &msg); err != nil {
+		t.Fatal(err)
+	}
+	if h := m.Header[transform.Header]; h != "snappy" {
+		t.Fatalf("Expected snappy got %s", h)
+	}
+	c = cf(buf)
+	if err := c.ReadHeader(m, codec.Response); err != nil {
+		t.Fatal(err)
+	}
+	msg = Msg{}
+	if err := c.ReadBody(&msg); err != nil || msg.Text != "hello" {
+		t.Fatalf("Expected hello got %s: %v", msg.Text, err)
+	}
+
+	// unknown transforms are rejected
+	m.Header[transform.Header] = "rot13"
+	if err := cf(rwc{bytes.NewBufferString("{}")}).ReadHeader(m, codec.Response); err == nil {
+		t.Fatal("Expected an unsupported transform error")
+	}
+}
@@ -0,0 +1,120 @@
+package transform
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/micro/go-micro/v2/util/compress"
+)
+
+type compressTransform struct {
+	compress.Compressor
+}
+
+func (c compressTransform) Encode(b []byte) ([]byte, error) {
+	return c.Compress(b)
+}
+
+func (c compressTransform) Decode(b []byte) ([]byte, error) {
+	return c.Decompress(b)
+}
+
+func (c compressTransform) Required() bool {
+	return false
+}
+
+// Compress the payloads with the encoding e.g gzip, snappy or zstd.
+// It panics if the encoding isn't supported.
+func Compress(encoding string) Transform {
+	c, ok := compress.Compressors[encoding]
+	if !ok {
+		panic(fmt.Sprintf("unsupported compression %s", encoding))
+	}
+	return compressTransform{c}
+}
+
+type encryptTransform struct {
+	aead cipher.AEAD
+}
+
+func (e encryptTransform) Encode(b []byte) ([]byte, error) {
+	nonce := make([]byte, e.aead.NonceSize(), e.aead.NonceSize()+len(b)+e.aead.Overhead())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+	return e.aead.Seal(nonce, nonce, b, nil), nil
+}
+
+func (e encryptTransform) Decode(b []byte) ([]byte, error) {
+	n := e.aead.NonceSize()
+	if len(b) < n {
+		return nil, errors.New("invalid ciphertext")
+	}
+	return e.aead.Open(nil, b[:n], b[n:], nil)
+}
+
+func (e encryptTransform) Required() bool {
+	return true
+}
+
+func (e encryptTransform) String() string {
+	return "aes-gcm"
+}
+
+// Encrypt the payloads with AES-GCM and the 16, 24 or 32 byte key.
+// It panics if the key is invalid.
+func Encrypt(key []byte) Transform {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		panic(err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		panic(err)
+	}
+	return encryptTransform{aead}
+}
+
+type signTransform struct {
+	key []byte
+}
+
+func (s signTransform) sum(b []byte) []byte {
+	mac := hmac.New(sha256.New, s.key)
+	mac.Write(b)
+	return mac.Sum(nil)
+}
+
+func (s signTransform) Encode(b []byte) ([]byte, error) {
+	return append(append(make([]byte, 0, len(b)+sha256.Size), b...), s.sum(b)...), nil
+}
+
+func (s signTransform) Decode(b []byte) ([]byte, error) {
+	if len(b) < sha256.Size {
+		return nil, errors.New("missing signature")
+	}
+	data, sig := b[:len(b)-sha256.Size], b[len(b)-sha256.Size:]
+	if !hmac.Equal(sig, s.sum(data)) {
+		return nil, errors.New("invalid signature")
+	}
+	return data, nil
+}
+
+func (s signTransform) Required() bool {
+	return true
+}
+
+func (s signTransform) String() string {
+	return "hmac-sha256"
+}
+
+// Sign the payloads with a HMAC-SHA256 of the key appended to them
+func Sign(key []byte) Transform {
+	return signTransform{key}
+}
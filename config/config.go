@@ -3,6 +3,7 @@ package config
 
 import (
 	"context"
+	"errors"
 
 	"github.com/micro/go-micro/v2/config/loader"
 	"github.com/micro/go-micro/v2/config/reader"
@@ -26,18 +27,38 @@ type Config interface {
 	Sync() error
 	// Watch a value for changes
 	Watch(path ...string) (Watcher, error)
+	// Stage changes to be validated and applied atomically
+	Stage() Stage
+	// Subscribe to changes. The subscriber is called with the
+	// current values and then with every change applied. Use
+	// OnChange to only be called with the changes under a path.
+	Subscribe(s Subscriber) error
+	// Origins of the loaded value at the path, the source it is read from
+	// followed by the sources it overrides
+	Origins(path ...string) ([]loader.Origin, error)
 }
 
 // Watcher is the config watcher
 type Watcher interface {
 	Next() (reader.Value, error)
+	// Changes between the values returned by the last two calls to Next,
+	// the first compared to the value when the watcher was created
+	Changes() []Change
 	Stop() error
 }
 
+var (
+	// ErrNoProvenance is returned by Origins when the loader doesn't
+	// track the sources of the values
+	ErrNoProvenance = errors.New("config: loader doesn't track the sources of the values")
+)
+
 type Options struct {
 	Loader loader.Loader
 	Reader reader.Reader
 	Source []source.Source
+	// Validators of changes
+	Validators []Validator
 
 	// for alternative data
 	Context context.Context
@@ -90,6 +111,11 @@ func Watch(path ...string) (Watcher, error) {
 	return DefaultConfig.Watch(path...)
 }
 
+// Origins of the value at the path
+func Origins(path ...string) ([]loader.Origin, error) {
+	return DefaultConfig.Origins(path...)
+}
+
 // LoadFile is short hand for creating a file source and loading it
 func LoadFile(path string) error {
 	return Load(file.NewSource(
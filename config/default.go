@@ -2,6 +2,7 @@ package config
 
 import (
 	"bytes"
+	"errors"
 	"sync"
 	"time"
 
@@ -10,6 +11,7 @@ import (
 	"github.com/micro/go-micro/v2/config/reader"
 	"github.com/micro/go-micro/v2/config/reader/json"
 	"github.com/micro/go-micro/v2/config/source"
+	"github.com/micro/go-micro/v2/logger"
 )
 
 type config struct {
@@ -21,13 +23,24 @@ type config struct {
 	snap *loader.Snapshot
 	// the current values
 	vals reader.Values
+	// subscribers to applied changes
+	subs []Subscriber
+	// watchers of applied changes
+	watchers map[*watcher]bool
+
+	// serialises applying changes
+	alock sync.Mutex
 }
 
 type watcher struct {
-	lw    loader.Watcher
-	rd    reader.Reader
+	c     *config
 	path  []string
 	value reader.Value
+	// changes of the last value
+	changes []Change
+	// latest values applied, not yet read
+	next chan reader.Values
+	exit chan bool
 }
 
 func newConfig(opts ...Option) (Config, error) {
@@ -84,20 +97,27 @@ func (c *config) run() {
 				return err
 			}
 
-			c.Lock()
+			c.alock.Lock()
 
-			if c.snap.Version >= snap.Version {
-				c.Unlock()
+			c.RLock()
+			stale := c.snap.Version >= snap.Version
+			c.RUnlock()
+
+			if stale {
+				c.alock.Unlock()
 				continue
 			}
 
-			// save
-			c.snap = snap
-
-			// set values
-			c.vals, _ = c.opts.Reader.Values(snap.ChangeSet)
+			// set values if validated and accepted by the subscribers
+			vals, err := c.opts.Reader.Values(snap.ChangeSet)
+			if err == nil {
+				err = c.commit(snap, vals)
+			}
+			if err != nil {
+				logger.Errorf("Rejected config change: %v", err)
+			}
 
-			c.Unlock()
+			c.alock.Unlock()
 		}
 	}
 
@@ -154,23 +174,24 @@ func (c *config) Sync() error {
 	if err := c.opts.Loader.Sync(); err != nil {
 		return err
 	}
+	return c.apply()
+}
+
+// apply the snapshot of the loader if validated and accepted by the
+// subscribers
+func (c *config) apply() error {
+	c.alock.Lock()
+	defer c.alock.Unlock()
 
 	snap, err := c.opts.Loader.Snapshot()
 	if err != nil {
 		return err
 	}
-
-	c.Lock()
-	defer c.Unlock()
-
-	c.snap = snap
 	vals, err := c.opts.Reader.Values(snap.ChangeSet)
 	if err != nil {
 		return err
 	}
-	c.vals = vals
-
-	return nil
+	return c.commit(snap, vals)
 }
 
 func (c *config) Close() error {
@@ -233,67 +254,118 @@ func (c *config) Load(sources ...source.Source) error {
 	if err := c.opts.Loader.Load(sources...); err != nil {
 		return err
 	}
+	return c.apply()
+}
 
-	snap, err := c.opts.Loader.Snapshot()
-	if err != nil {
-		return err
+// Watch the value at the path. The watcher is notified of the changes
+// applied to the config, whether loaded, synced or staged.
+func (c *config) Watch(path ...string) (Watcher, error) {
+	w := &watcher{
+		c:     c,
+		path:  path,
+		value: c.Get(path...),
+		next:  make(chan reader.Values, 1),
+		exit:  make(chan bool),
 	}
 
 	c.Lock()
-	defer c.Unlock()
-
-	c.snap = snap
-	vals, err := c.opts.Reader.Values(snap.ChangeSet)
-	if err != nil {
-		return err
+	if c.watchers == nil {
+		c.watchers = make(map[*watcher]bool)
 	}
-	c.vals = vals
+	c.watchers[w] = true
+	c.Unlock()
 
-	return nil
+	return w, nil
 }
 
-func (c *config) Watch(path ...string) (Watcher, error) {
-	value := c.Get(path...)
+func (c *config) Stage() Stage {
+	return &stage{c: c}
+}
 
-	w, err := c.opts.Loader.Watch(path...)
-	if err != nil {
-		return nil, err
+func (c *config) Subscribe(s Subscriber) error {
+	c.alock.Lock()
+	defer c.alock.Unlock()
+
+	c.RLock()
+	vals := c.vals
+	c.RUnlock()
+
+	// apply the current values
+	if vals != nil {
+		if err := s(vals); err != nil {
+			return err
+		}
 	}
 
-	return &watcher{
-		lw:    w,
-		rd:    c.opts.Reader,
-		path:  path,
-		value: value,
-	}, nil
+	c.Lock()
+	c.subs = append(c.subs, s)
+	c.Unlock()
+
+	return nil
+}
+
+// Origins of the value at the path. The values set or staged since the
+// sources were loaded have no origin.
+func (c *config) Origins(path ...string) ([]loader.Origin, error) {
+	p, ok := c.opts.Loader.(loader.Provenance)
+	if !ok {
+		return nil, ErrNoProvenance
+	}
+	return p.Origins(path...)
 }
 
 func (c *config) String() string {
 	return "config"
 }
 
+// notify the watcher of the values applied, replacing the ones not yet
+// read. It's only called while holding the apply lock.
+func (w *watcher) notify(vals reader.Values) {
+	select {
+	case w.next <- vals:
+	default:
+		select {
+		case <-w.next:
+		default:
+		}
+		w.next <- vals
+	}
+}
+
 func (w *watcher) Next() (reader.Value, error) {
 	for {
-		s, err := w.lw.Next()
-		if err != nil {
-			return nil, err
+		var vals reader.Values
+		select {
+		case vals = <-w.next:
+		case <-w.exit:
+			return nil, errors.New("watcher stopped")
 		}
 
 		// only process changes
-		if bytes.Equal(w.value.Bytes(), s.ChangeSet.Data) {
+		value := vals.Get(w.path...)
+		if bytes.Equal(w.value.Bytes(), value.Bytes()) {
 			continue
 		}
 
-		v, err := w.rd.Values(s.ChangeSet)
-		if err != nil {
-			return nil, err
-		}
-
-		w.value = v.Get()
+		w.changes = diff(w.path, decodeValue(w.value), decodeValue(value))
+		w.value = value
 		return w.value, nil
 	}
 }
 
+func (w *watcher) Changes() []Change {
+	return w.changes
+}
+
 func (w *watcher) Stop() error {
-	return w.lw.Stop()
+	w.c.Lock()
+	defer w.c.Unlock()
+
+	select {
+	case <-w.exit:
+	default:
+		close(w.exit)
+		delete(w.c.watchers, w)
+	}
+	return nil
 }
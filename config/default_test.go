@@ -1,6 +1,7 @@
 package config
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -9,6 +10,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/micro/go-micro/v2/config/reader"
 	"github.com/micro/go-micro/v2/config/source"
 	"github.com/micro/go-micro/v2/config/source/env"
 	"github.com/micro/go-micro/v2/config/source/file"
@@ -164,3 +166,268 @@ func TestConfigWatcherDirtyOverrite(t *testing.T) {
 		equalS(t, conf.Get(k).String(""), v)
 	}
 }
+
+func TestConfigStage(t *testing.T) {
+	c, err := NewConfig(
+		WithSource(memory.NewSource(memory.WithJSON([]byte(`{"limit": 10}`)))),
+		WithValidator(func(v reader.Values) error {
+			if v.Get("limit").Int(0) <= 0 {
+				return errors.New("limit must be positive")
+			}
+			return nil
+		}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	var first, second []int
+
+	c.Subscribe(func(v reader.Values) error {
+		first = append(first, v.Get("limit").Int(0))
+		return nil
+	})
+	c.Subscribe(func(v reader.Values) error {
+		limit := v.Get("limit").Int(0)
+		if limit > 100 {
+			return errors.New("limit too high")
+		}
+		second = append(second, limit)
+		return nil
+	})
+
+	if err := c.Stage().Set(20, "limit").Apply(); err != nil {
+		t.Fatal(err)
+	}
+	if v := c.Get("limit").Int(0); v != 20 {
+		t.Fatalf("Expected limit 20 got %d", v)
+	}
+
+	// rejected by the validator
+	if err := c.Stage().Set(-1, "limit").Apply(); err == nil {
+		t.Fatal("Expected validation error")
+	}
+
+	// rejected by the second subscriber so the first is rolled back
+	if err := c.Stage().Set(200, "limit").Set("bar", "foo").Apply(); err == nil {
+		t.Fatal("Expected subscriber error")
+	}
+	if v := c.Get("limit").Int(0); v != 20 {
+		t.Fatalf("Expected limit 20 got %d", v)
+	}
+	if v := c.Get("foo").String(""); v != "" {
+		t.Fatalf("Expected foo to be unset got %s", v)
+	}
+
+	if fmt.Sprint(first) != "[10 20 200 20]" {
+		t.Fatalf("Unexpected first subscriber values %v", first)
+	}
+	if fmt.Sprint(second) != "[10 20]" {
+		t.Fatalf("Unexpected second subscriber values %v", second)
+	}
+}
+
+func TestConfigOnChange(t *testing.T) {
+	c, err := NewConfig(
+		WithSource(memory.NewSource(memory.WithJSON([]byte(`{"limits": {"rps": 10}, "name": "foo"}`)))),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	var calls [][]Change
+
+	err = c.Subscribe(OnChange(func(ch []Change, v reader.Values) error {
+		if len(ch) > 0 && ch[0].New == float64(1000) {
+			return errors.New("rps too high")
+		}
+		calls = append(calls, ch)
+		return nil
+	}, "limits"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// changes outside the path aren't delivered
+	if err := c.Stage().Set("bar", "name").Apply(); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Stage().Set(20, "limits", "rps").Set(5, "limits", "burst").Apply(); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Stage().Set(1000, "limits", "rps").Apply(); err == nil {
+		t.Fatal("Expected subscriber error")
+	}
+	if err := c.Stage().Del("limits", "burst").Apply(); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{
+		"[limits.rps <nil> 10]",
+		"[limits.burst <nil> 5] [limits.rps 10 20]",
+		"[limits.burst 5 <nil>]",
+	}
+	if len(calls) != len(expected) {
+		t.Fatalf("Expected %d calls got %d: %v", len(expected), len(calls), calls)
+	}
+	for i, ch := range calls {
+		var s []string
+		for _, c := range ch {
+			s = append(s, fmt.Sprintf("[%s %v %v]", c, c.Old, c.New))
+		}
+		if got := strings.Join(s, " "); got != expected[i] {
+			t.Fatalf("Expected changes %s got %s", expected[i], got)
+		}
+	}
+}
+
+func TestConfigWatcherChanges(t *testing.T) {
+	src := memory.NewSource(memory.WithJSON([]byte(`{"limits": {"rps": 10}, "name": "foo"}`)))
+
+	c, err := NewConfig(WithSource(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	w, err := c.Watch("limits")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Stop()
+
+	// wait for the loader to watch the source
+	time.Sleep(100 * time.Millisecond)
+
+	src.Write(&source.ChangeSet{
+		Data:   []byte(`{"limits": {"rps": 20, "burst": 5}, "name": "foo"}`),
+		Format: "json",
+	})
+
+	v, err := w.Next()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var limits map[string]int
+	if err := v.Scan(&limits); err != nil {
+		t.Fatal(err)
+	}
+	if limits["rps"] != 20 {
+		t.Fatalf("Expected rps 20 got %d", limits["rps"])
+	}
+
+	ch := w.Changes()
+	if len(ch) != 2 {
+		t.Fatalf("Expected 2 changes got %v", ch)
+	}
+	if ch[0].String() != "limits.burst" || ch[0].Old != nil || ch[0].New != float64(5) {
+		t.Fatalf("Unexpected change %+v", ch[0])
+	}
+	if ch[1].String() != "limits.rps" || ch[1].Old != float64(10) || ch[1].New != float64(20) {
+		t.Fatalf("Unexpected change %+v", ch[1])
+	}
+}
+
+func TestConfigLayers(t *testing.T) {
+	remote := memory.NewSource(memory.WithJSON([]byte(`{"db": {"host": "remote"}}`)))
+	env := memory.NewSource(memory.WithJSON([]byte(`{"db": {"host": "env", "port": 5432}}`)))
+	defaults := memory.NewSource(memory.WithJSON([]byte(`{"db": {"host": "localhost", "port": 3306, "user": "root"}}`)))
+
+	c, err := NewConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	// loaded out of order
+	err = c.Load(
+		source.Layer(remote, source.PrecedenceRemote),
+		source.Layer(env, source.PrecedenceEnv),
+		defaults,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	equalS(t, c.Get("db", "host").String(""), "remote")
+	if v := c.Get("db", "port").Int(0); v != 5432 {
+		t.Fatalf("Expected port 5432 got %d", v)
+	}
+	equalS(t, c.Get("db", "user").String(""), "root")
+
+	origins, err := c.Origins("db", "host")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, o := range origins {
+		got = append(got, fmt.Sprintf("%d:%v", o.Precedence, o.Value))
+	}
+	if s := strings.Join(got, " "); s != "400:remote 200:env 0:localhost" {
+		t.Fatalf("Unexpected origins %s", s)
+	}
+
+	origins, err = c.Origins("db", "user")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(origins) != 1 || origins[0].Source != "memory" || origins[0].Value != "root" {
+		t.Fatalf("Unexpected origins %+v", origins)
+	}
+
+	origins, err = c.Origins("db", "missing")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(origins) != 0 {
+		t.Fatalf("Expected no origins got %+v", origins)
+	}
+}
+
+func TestConfigWatcherStage(t *testing.T) {
+	c, err := NewConfig(
+		WithSource(memory.NewSource(memory.WithJSON([]byte(`{"limit": 10}`)))),
+		WithValidator(func(v reader.Values) error {
+			if v.Get("limit").Int(0) <= 0 {
+				return errors.New("limit must be positive")
+			}
+			return nil
+		}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	w, err := c.Watch("limit")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Stop()
+
+	if err := c.Stage().Set(20, "limit").Apply(); err != nil {
+		t.Fatal(err)
+	}
+	v, err := w.Next()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v.Int(0) != 20 {
+		t.Fatalf("Expected the staged limit 20 got %d", v.Int(0))
+	}
+
+	// the loaded sources are validated
+	if err := c.Load(memory.NewSource(memory.WithJSON([]byte(`{"limit": -1}`)))); err == nil {
+		t.Fatal("Expected validation error")
+	}
+	if v := c.Get("limit").Int(0); v != 20 {
+		t.Fatalf("Expected limit 20 got %d", v)
+	}
+
+	w.Stop()
+	if _, err := w.Next(); err == nil {
+		t.Fatal("Expected the stopped watcher to return an error")
+	}
+}
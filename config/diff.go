@@ -0,0 +1,123 @@
+package config
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/micro/go-micro/v2/config/reader"
+)
+
+// Change of a config value. Old is nil if the value was added and New is nil
+// if it was deleted.
+type Change struct {
+	Path []string
+	Old  interface{}
+	New  interface{}
+}
+
+// String is the dot separated path of the change
+func (c Change) String() string {
+	return strings.Join(c.Path, ".")
+}
+
+// ChangeSubscriber applies the changes of the config values to a component.
+// Returning an error rejects the changes like a Subscriber.
+type ChangeSubscriber func(changes []Change, v reader.Values) error
+
+// Diff returns the changed leaf values between the prev and next values
+// under the path, ordered by path. Either values may be nil.
+func Diff(prev, next reader.Values, path ...string) []Change {
+	var a, b interface{}
+	if prev != nil {
+		a = decodeValue(prev.Get(path...))
+	}
+	if next != nil {
+		b = decodeValue(next.Get(path...))
+	}
+	return diff(path, a, b)
+}
+
+// OnChange returns a subscriber only calling fn with the changes under the
+// path e.g
+//
+//	c.Subscribe(config.OnChange(func(ch []config.Change, v reader.Values) error {
+//		return limiter.Set(v.Get("micro", "limits").Int(0))
+//	}, "micro", "limits"))
+//
+// It's first called with the current values as added values unless there
+// are none under the path.
+func OnChange(fn ChangeSubscriber, path ...string) Subscriber {
+	var prev reader.Values
+
+	// subscribers are called serially so prev isn't guarded
+	return func(v reader.Values) error {
+		changes := Diff(prev, v, path...)
+		if len(changes) == 0 {
+			prev = v
+			return nil
+		}
+		if err := fn(changes, v); err != nil {
+			return err
+		}
+		prev = v
+		return nil
+	}
+}
+
+// decodeValue decodes the json of the value, nil if it's not set
+func decodeValue(v reader.Value) interface{} {
+	var i interface{}
+	if err := v.Scan(&i); err != nil {
+		return nil
+	}
+	return i
+}
+
+func diff(path []string, a, b interface{}) []Change {
+	am, aok := a.(map[string]interface{})
+	bm, bok := b.(map[string]interface{})
+
+	// compare the values which aren't both maps as a whole
+	if !aok || !bok {
+		if reflect.DeepEqual(a, b) {
+			return nil
+		}
+		// a map replacing a value or vice versa changes each of its values
+		if aok || bok {
+			var changes []Change
+			if !aok && a != nil {
+				changes = append(changes, Change{Path: path, Old: a})
+			}
+			changes = append(changes, diff(path, am, bm)...)
+			if !bok && b != nil {
+				changes = append(changes, Change{Path: path, New: b})
+			}
+			return changes
+		}
+		return []Change{{Path: path, Old: a, New: b}}
+	}
+
+	keys := make([]string, 0, len(am)+len(bm))
+	for k := range am {
+		keys = append(keys, k)
+	}
+	for k := range bm {
+		if _, ok := am[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	var changes []Change
+
+	for _, k := range keys {
+		p := make([]string, len(path)+1)
+		copy(p, path)
+		p[len(path)] = k
+
+		changes = append(changes, diff(p, am[k], bm[k])...)
+	}
+
+	return changes
+}
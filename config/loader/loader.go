@@ -61,3 +61,20 @@ func Copy(s *Snapshot) *Snapshot {
 		Version:   s.Version,
 	}
 }
+
+// Origin of a config value
+type Origin struct {
+	// Source which supplied the value
+	Source string
+	// Precedence of the source
+	Precedence int
+	// Value supplied by the source
+	Value interface{}
+}
+
+// Provenance is implemented by loaders which track the sources of the values
+type Provenance interface {
+	// Origins of the value at the path. The first is the source the value
+	// is read from followed by the sources it overrides.
+	Origins(path ...string) ([]Origin, error)
+}
@@ -5,6 +5,7 @@ import (
 	"container/list"
 	"errors"
 	"fmt"
+	"sort"
 	"strings"
 	"sync"
 	"time"
@@ -62,7 +63,7 @@ func (m *memory) watch(idx int, s source.Source) {
 			m.sets[idx] = cs
 
 			// merge sets
-			set, err := m.opts.Reader.Merge(m.sets...)
+			set, err := m.opts.Reader.Merge(ordered(m.sources, m.sets)...)
 			if err != nil {
 				m.Unlock()
 				return err
@@ -133,7 +134,7 @@ func (m *memory) reload() error {
 	m.Lock()
 
 	// merge sets
-	set, err := m.opts.Reader.Merge(m.sets...)
+	set, err := m.opts.Reader.Merge(ordered(m.sources, m.sets)...)
 	if err != nil {
 		m.Unlock()
 		return err
@@ -215,17 +216,18 @@ func (m *memory) Sync() error {
 	// read the source
 	var gerr []string
 
-	for _, source := range m.sources {
+	for i, source := range m.sources {
 		ch, err := source.Read()
 		if err != nil {
 			gerr = append(gerr, err.Error())
-			continue
+		} else {
+			m.sets[i] = ch
 		}
 		sets = append(sets, ch)
 	}
 
 	// merge sets
-	set, err := m.opts.Reader.Merge(sets...)
+	set, err := m.opts.Reader.Merge(ordered(m.sources, sets)...)
 	if err != nil {
 		m.Unlock()
 		return err
@@ -423,6 +425,80 @@ func (w *watcher) Stop() error {
 	return nil
 }
 
+// Origins of the value at the path by the precedence of the sources
+func (m *memory) Origins(path ...string) ([]loader.Origin, error) {
+	if !m.loaded() {
+		if err := m.Sync(); err != nil {
+			return nil, err
+		}
+	}
+
+	m.RLock()
+	sources := make([]source.Source, len(m.sources))
+	copy(sources, m.sources)
+	sets := make([]*source.ChangeSet, len(m.sets))
+	copy(sets, m.sets)
+	m.RUnlock()
+
+	idx := order(sources)
+
+	var origins []loader.Origin
+
+	for i := len(idx) - 1; i >= 0; i-- {
+		s, set := sources[idx[i]], sets[idx[i]]
+		if set == nil || len(set.Data) == 0 {
+			continue
+		}
+
+		// decode the set alone
+		ch, err := m.opts.Reader.Merge(set)
+		if err != nil {
+			return nil, err
+		}
+		vals, err := m.opts.Reader.Values(ch)
+		if err != nil {
+			return nil, err
+		}
+
+		var v interface{}
+		if err := vals.Get(path...).Scan(&v); err != nil || v == nil {
+			continue
+		}
+
+		origins = append(origins, loader.Origin{
+			Source:     s.String(),
+			Precedence: source.Precedence(s),
+			Value:      v,
+		})
+	}
+
+	return origins, nil
+}
+
+// order returns the indexes of the sources by precedence. The sources
+// with the same precedence keep the order they were loaded in.
+func order(sources []source.Source) []int {
+	idx := make([]int, len(sources))
+	for i := range idx {
+		idx[i] = i
+	}
+	sort.SliceStable(idx, func(i, j int) bool {
+		return source.Precedence(sources[idx[i]]) < source.Precedence(sources[idx[j]])
+	})
+	return idx
+}
+
+// ordered returns the sets of the sources in the order they're merged
+func ordered(sources []source.Source, sets []*source.ChangeSet) []*source.ChangeSet {
+	o := make([]*source.ChangeSet, 0, len(sets))
+	for _, i := range order(sources) {
+		if i < len(sets) {
+			o = append(o, sets[i])
+		}
+	}
+	return o
+}
+
 func genVer() string {
 	return fmt.Sprintf("%d", time.Now().UnixNano())
 }
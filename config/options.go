@@ -26,3 +26,10 @@ func WithReader(r reader.Reader) Option {
 		o.Reader = r
 	}
 }
+
+// WithValidator adds a validator which checks changes before they're applied
+func WithValidator(v Validator) Option {
+	return func(o *Options) {
+		o.Validators = append(o.Validators, v)
+	}
+}
@@ -0,0 +1,87 @@
+// Package aesgcm is a config/secrets implementation that uses AES-GCM
+// to do symmetric encryption / verification
+package aesgcm
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+
+	"github.com/micro/go-micro/v2/config/secrets"
+	"github.com/pkg/errors"
+)
+
+type aesGCM struct {
+	options secrets.Options
+
+	aead cipher.AEAD
+}
+
+// NewSecrets returns an AES-GCM codec
+func NewSecrets(opts ...secrets.Option) secrets.Secrets {
+	a := &aesGCM{}
+	for _, o := range opts {
+		o(&a.options)
+	}
+	return a
+}
+
+// Init initialises the cipher. The key must be 16, 24 or 32 bytes
+// long to select AES-128, AES-192 or AES-256.
+func (a *aesGCM) Init(opts ...secrets.Option) error {
+	for _, o := range opts {
+		o(&a.options)
+	}
+	if len(a.options.Key) == 0 {
+		return errors.New("no secret key is defined")
+	}
+	block, err := aes.NewCipher(a.options.Key)
+	if err != nil {
+		return errors.Wrap(err, "secret key must be 16, 24 or 32 bytes long")
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return err
+	}
+	a.aead = aead
+	return nil
+}
+
+func (a *aesGCM) Options() secrets.Options {
+	return a.options
+}
+
+func (a *aesGCM) String() string {
+	return "aes-gcm"
+}
+
+func (a *aesGCM) Encrypt(in []byte, opts ...secrets.EncryptOption) ([]byte, error) {
+	// no opts are expected, so they are ignored
+	if a.aead == nil {
+		return []byte{}, errors.New("secrets not initialised")
+	}
+
+	// there must be a unique nonce for each message
+	nonce := make([]byte, a.aead.NonceSize(), a.aead.NonceSize()+len(in)+a.aead.Overhead())
+	if _, err := rand.Reader.Read(nonce); err != nil {
+		return []byte{}, errors.Wrap(err, "couldn't obtain a random nonce from crypto/rand")
+	}
+	return a.aead.Seal(nonce, nonce, in, nil), nil
+}
+
+func (a *aesGCM) Decrypt(in []byte, opts ...secrets.DecryptOption) ([]byte, error) {
+	// no options are expected, so they are ignored
+	if a.aead == nil {
+		return []byte{}, errors.New("secrets not initialised")
+	}
+
+	n := a.aead.NonceSize()
+	if len(in) < n {
+		return []byte{}, errors.New("decryption failed (the message is too short)")
+	}
+	decrypted, err := a.aead.Open(nil, in[:n], in[n:], nil)
+	if err != nil {
+		return []byte{}, errors.New("decryption failed (is the key set correctly?)")
+	}
+	return decrypted, nil
+}
@@ -0,0 +1,63 @@
+package aesgcm
+
+import (
+	"encoding/base64"
+	"reflect"
+	"testing"
+
+	"github.com/micro/go-micro/v2/config/secrets"
+)
+
+func TestAESGCM(t *testing.T) {
+	secretKey, err := base64.StdEncoding.DecodeString("4jbVgq8FsAV7vy+n8WqEZrl7BUtNqh3fYT5RXzXOPFY=")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := NewSecrets()
+
+	if err := s.Init(); err == nil {
+		t.Error("AES-GCM accepted an empty secret key")
+	}
+	if err := s.Init(secrets.Key([]byte("invalid"))); err == nil {
+		t.Error("AES-GCM accepted a secret key that is invalid")
+	}
+
+	if err := s.Init(secrets.Key(secretKey)); err != nil {
+		t.Fatal(err)
+	}
+
+	if s.String() != "aes-gcm" {
+		t.Error(s.String() + " should be aes-gcm")
+	}
+
+	message := []byte(`Can you hear me, Major Tom?`)
+
+	encrypted, err := s.Encrypt(message)
+	if err != nil {
+		t.Fatalf("Failed to encrypt message (%s)", err)
+	}
+
+	// a unique nonce is used for each message
+	again, err := s.Encrypt(message)
+	if err != nil {
+		t.Fatalf("Failed to encrypt message (%s)", err)
+	}
+	if reflect.DeepEqual(encrypted, again) {
+		t.Error("Encrypted messages are equal")
+	}
+
+	decrypted, err := s.Decrypt(encrypted)
+	if err != nil {
+		t.Fatalf("Failed to decrypt encrypted message (%s)", err)
+	}
+	if !reflect.DeepEqual(message, decrypted) {
+		t.Error("Decrypted message did not match encrypted message")
+	}
+
+	// tampered messages fail to decrypt
+	encrypted[len(encrypted)-1] ^= 1
+	if _, err := s.Decrypt(encrypted); err == nil {
+		t.Error("Tampered message was decrypted")
+	}
+}
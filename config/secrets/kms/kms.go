@@ -0,0 +1,107 @@
+// Package kms is a config/secrets implementation encrypting with a key of
+// the aws key management service. The values are limited to 4KB so it's
+// meant to wrap keys e.g as a master key of the store encryption.
+package kms
+
+import (
+	"context"
+	"errors"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/kms"
+	"github.com/aws/aws-sdk-go/service/kms/kmsiface"
+	"github.com/micro/go-micro/v2/config/secrets"
+)
+
+type kmsSecrets struct {
+	options secrets.Options
+
+	keyID  string
+	client kmsiface.KMSAPI
+}
+
+// NewSecrets returns a kms codec
+func NewSecrets(opts ...secrets.Option) secrets.Secrets {
+	k := &kmsSecrets{}
+	for _, o := range opts {
+		o(&k.options)
+	}
+	return k
+}
+
+// Init creates the kms client. The key id is only required to encrypt as
+// kms finds the key of the encrypted values.
+func (k *kmsSecrets) Init(opts ...secrets.Option) error {
+	for _, o := range opts {
+		o(&k.options)
+	}
+	ctx := k.options.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	k.keyID, _ = ctx.Value(keyIDKey{}).(string)
+
+	if c, ok := ctx.Value(clientKey{}).(kmsiface.KMSAPI); ok {
+		k.client = c
+		return nil
+	}
+
+	sess, ok := ctx.Value(sessionKey{}).(*session.Session)
+	if !ok {
+		s, err := session.NewSessionWithOptions(session.Options{
+			SharedConfigState: session.SharedConfigEnable,
+		})
+		if err != nil {
+			return err
+		}
+		sess = s
+	}
+
+	cfg := aws.NewConfig()
+	if r, ok := ctx.Value(regionKey{}).(string); ok {
+		cfg = cfg.WithRegion(r)
+	}
+
+	k.client = kms.New(sess, cfg)
+	return nil
+}
+
+func (k *kmsSecrets) Options() secrets.Options {
+	return k.options
+}
+
+func (k *kmsSecrets) String() string {
+	return "kms"
+}
+
+func (k *kmsSecrets) Encrypt(in []byte, opts ...secrets.EncryptOption) ([]byte, error) {
+	if k.client == nil {
+		return nil, errors.New("kms not initialised")
+	}
+	if len(k.keyID) == 0 {
+		return nil, errors.New("no kms key id is defined")
+	}
+	rsp, err := k.client.Encrypt(&kms.EncryptInput{
+		KeyId:     aws.String(k.keyID),
+		Plaintext: in,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return rsp.CiphertextBlob, nil
+}
+
+func (k *kmsSecrets) Decrypt(in []byte, opts ...secrets.DecryptOption) ([]byte, error) {
+	if k.client == nil {
+		return nil, errors.New("kms not initialised")
+	}
+	rsp, err := k.client.Decrypt(&kms.DecryptInput{
+		CiphertextBlob: in,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return rsp.Plaintext, nil
+}
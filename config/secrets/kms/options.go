@@ -0,0 +1,44 @@
+package kms
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/kms/kmsiface"
+	"github.com/micro/go-micro/v2/config/secrets"
+)
+
+type keyIDKey struct{}
+type sessionKey struct{}
+type regionKey struct{}
+type clientKey struct{}
+
+// KeyID of the kms key encrypting the values e.g an alias or arn
+func KeyID(id string) secrets.Option {
+	return setSecretsOption(keyIDKey{}, id)
+}
+
+// Session sets the aws session. Defaults to a session
+// configured from the environment and shared config.
+func Session(s *session.Session) secrets.Option {
+	return setSecretsOption(sessionKey{}, s)
+}
+
+// Region sets the aws region
+func Region(r string) secrets.Option {
+	return setSecretsOption(regionKey{}, r)
+}
+
+// Client sets the kms client e.g for tests
+func Client(c kmsiface.KMSAPI) secrets.Option {
+	return setSecretsOption(clientKey{}, c)
+}
+
+func setSecretsOption(k, v interface{}) secrets.Option {
+	return func(o *secrets.Options) {
+		if o.Context == nil {
+			o.Context = context.Background()
+		}
+		o.Context = context.WithValue(o.Context, k, v)
+	}
+}
@@ -0,0 +1,26 @@
+package provider
+
+import (
+	"github.com/micro/go-micro/v2/auth"
+)
+
+// AuthKeys returns the auth options of the public and private key secrets,
+// encoded as the auth expects e.g base64 pem for the jwt auth. The private
+// key is optional for the services which only inspect tokens.
+func AuthKeys(p Provider, public, private string) ([]auth.Option, error) {
+	pub, err := p.Get(public)
+	if err != nil {
+		return nil, err
+	}
+	opts := []auth.Option{auth.PublicKey(string(pub.Value))}
+
+	if len(private) == 0 {
+		return opts, nil
+	}
+	priv, err := p.Get(private)
+	if err != nil {
+		return nil, err
+	}
+
+	return append(opts, auth.PrivateKey(string(priv.Value))), nil
+}
@@ -0,0 +1,65 @@
+// Package env is a secrets provider reading the environment variables
+package env
+
+import (
+	"os"
+	"strings"
+
+	"github.com/micro/go-micro/v2/config/secrets/provider"
+)
+
+type env struct {
+	opts provider.Options
+}
+
+// NewProvider returns a provider of the environment variables. The name of
+// the variable of a secret is the upper cased prefix and name with the
+// slashes, dots and dashes replaced by underscores e.g db/password with the
+// prefix micro is MICRO_DB_PASSWORD.
+func NewProvider(opts ...provider.Option) provider.Provider {
+	return &env{opts: provider.NewOptions(opts...)}
+}
+
+func (e *env) Init(opts ...provider.Option) error {
+	for _, o := range opts {
+		o(&e.opts)
+	}
+	return nil
+}
+
+func (e *env) Options() provider.Options {
+	return e.opts
+}
+
+// key of the variable of the secret
+func (e *env) key(name string) string {
+	if len(e.opts.Prefix) > 0 {
+		name = e.opts.Prefix + "_" + name
+	}
+	return strings.ToUpper(strings.NewReplacer("/", "_", ".", "_", "-", "_").Replace(name))
+}
+
+func (e *env) Get(name string) (*provider.Secret, error) {
+	v, ok := os.LookupEnv(e.key(name))
+	if !ok {
+		return nil, provider.ErrNotFound
+	}
+	return &provider.Secret{
+		Name:    name,
+		Version: provider.Version([]byte(v)),
+		Value:   []byte(v),
+	}, nil
+}
+
+// Watch polls the variable as it may be set in process
+func (e *env) Watch(name string) (provider.Watcher, error) {
+	return provider.Poll(e, name, e.opts.Interval)
+}
+
+func (e *env) Rotate(name string, value []byte) (*provider.Secret, error) {
+	return nil, provider.ErrReadOnly
+}
+
+func (e *env) String() string {
+	return "env"
+}
@@ -0,0 +1,97 @@
+// Package file is a secrets provider reading files of a directory e.g
+// mounted kubernetes secrets
+package file
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/micro/go-micro/v2/config/secrets/provider"
+)
+
+type file struct {
+	opts provider.Options
+}
+
+// NewProvider returns a provider of the files in the prefix directory, the
+// name of a secret being the path of its file in the directory
+func NewProvider(opts ...provider.Option) provider.Provider {
+	return &file{opts: provider.NewOptions(opts...)}
+}
+
+func (f *file) Init(opts ...provider.Option) error {
+	for _, o := range opts {
+		o(&f.opts)
+	}
+	return nil
+}
+
+func (f *file) Options() provider.Options {
+	return f.opts
+}
+
+// path of the file of the secret. The names can't escape the directory.
+func (f *file) path(name string) string {
+	return filepath.Join(f.opts.Prefix, filepath.FromSlash(filepath.Clean("/"+name)))
+}
+
+func (f *file) Get(name string) (*provider.Secret, error) {
+	p := f.path(name)
+
+	b, err := ioutil.ReadFile(p)
+	if os.IsNotExist(err) {
+		return nil, provider.ErrNotFound
+	} else if err != nil {
+		return nil, err
+	}
+	fi, err := os.Stat(p)
+	if err != nil {
+		return nil, err
+	}
+
+	return &provider.Secret{
+		Name:    name,
+		Version: provider.Version(b),
+		Value:   b,
+		Updated: fi.ModTime(),
+	}, nil
+}
+
+// Watch polls the file. Mounted secrets are updated by swapping symlinks
+// which isn't reliably notified.
+func (f *file) Watch(name string) (provider.Watcher, error) {
+	return provider.Poll(f, name, f.opts.Interval)
+}
+
+// Rotate replaces the file so the readers never read a partial secret
+func (f *file) Rotate(name string, value []byte) (*provider.Secret, error) {
+	p := f.path(name)
+	if err := os.MkdirAll(filepath.Dir(p), 0700); err != nil {
+		return nil, err
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(p), "."+strings.TrimPrefix(filepath.Base(p), ".")+".tmp")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(value); err != nil {
+		tmp.Close()
+		return nil, err
+	}
+	if err := tmp.Close(); err != nil {
+		return nil, err
+	}
+	if err := os.Rename(tmp.Name(), p); err != nil {
+		return nil, err
+	}
+
+	return f.Get(name)
+}
+
+func (f *file) String() string {
+	return "file"
+}
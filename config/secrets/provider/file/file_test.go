@@ -0,0 +1,75 @@
+package file
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/micro/go-micro/v2/config/secrets/provider"
+)
+
+func TestFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "secrets")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	p := NewProvider(provider.Prefix(dir), provider.Interval(10*time.Millisecond))
+
+	if _, err := p.Get("db/password"); err != provider.ErrNotFound {
+		t.Fatalf("Expected %v got %v", provider.ErrNotFound, err)
+	}
+
+	v1, err := p.Rotate("db/password", []byte("hunter2"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := p.Get("db/password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(s.Value) != "hunter2" || s.Version != v1.Version {
+		t.Fatalf("Unexpected secret %+v", s)
+	}
+
+	// the names can't escape the directory
+	if _, err := p.Rotate("../escape", []byte("x")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "escape")); err != nil {
+		t.Fatalf("Expected the secret in the directory: %v", err)
+	}
+
+	w, err := p.Watch("db/password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Stop()
+
+	v2, err := p.Rotate("db/password", []byte("correct horse"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err = w.Next()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Version != v2.Version || s.Version == v1.Version {
+		t.Fatalf("Expected version %s got %s", v2.Version, s.Version)
+	}
+
+	if err := os.Remove(filepath.Join(dir, "db", "password")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Next(); err != provider.ErrNotFound {
+		t.Fatalf("Expected %v got %v", provider.ErrNotFound, err)
+	}
+
+	w.Stop()
+	if _, err := w.Next(); err != provider.ErrWatcherStopped {
+		t.Fatalf("Expected %v got %v", provider.ErrWatcherStopped, err)
+	}
+}
@@ -0,0 +1,24 @@
+package provider
+
+import (
+	"github.com/micro/go-micro/v2/config/secrets"
+	"github.com/micro/go-micro/v2/config/secrets/aesgcm"
+)
+
+// Key returns the version and an aes-gcm cipher of the current version of
+// a 16, 24 or 32 byte secret e.g for the keys of the store and broker
+// encryption, the version being the id of the key
+//
+//	id, key, err := provider.Key(p, "store/master")
+//	s := encrypt.NewStore(store, encrypt.MasterKey(id, key))
+func Key(p Provider, name string) (string, secrets.Secrets, error) {
+	s, err := p.Get(name)
+	if err != nil {
+		return "", nil, err
+	}
+	key := aesgcm.NewSecrets(secrets.Key(s.Value))
+	if err := key.Init(); err != nil {
+		return "", nil, err
+	}
+	return s.Version, key, nil
+}
@@ -0,0 +1,105 @@
+// Package provider is an interface for reading, watching and rotating
+// secrets such as keys, passwords and certificates held by a secret manager
+package provider
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"time"
+)
+
+var (
+	// ErrNotFound is returned when a secret doesn't exist
+	ErrNotFound = errors.New("secret not found")
+	// ErrReadOnly is returned when rotating the secrets of a read only provider
+	ErrReadOnly = errors.New("secrets are read only")
+	// ErrWatcherStopped is returned when calling Next on a stopped watcher
+	ErrWatcherStopped = errors.New("watcher stopped")
+
+	// DefaultInterval is the interval the secrets are polled at when watched
+	DefaultInterval = 30 * time.Second
+)
+
+// Provider of secrets
+type Provider interface {
+	// Init the provider
+	Init(...Option) error
+	// Options of the provider
+	Options() Options
+	// Get the current version of a secret
+	Get(name string) (*Secret, error)
+	// Watch a secret for new versions
+	Watch(name string) (Watcher, error)
+	// Rotate a secret to a new value, returning the new version
+	Rotate(name string, value []byte) (*Secret, error)
+	// String returns the name of the implementation
+	String() string
+}
+
+// Secret is a version of a secret
+type Secret struct {
+	// Name of the secret e.g db/password
+	Name string
+	// Version of the value, it changes when rotated
+	Version string
+	// Value of the secret
+	Value []byte
+	// Updated is the time the version was created if known
+	Updated time.Time
+}
+
+// Watcher returns the new versions of a secret
+type Watcher interface {
+	// Next blocks until a new version, or the secret is deleted
+	// in which case ErrNotFound is returned
+	Next() (*Secret, error)
+	// Stop watching
+	Stop() error
+}
+
+// Options of a provider
+type Options struct {
+	// Prefix of the names of the secrets
+	Prefix string
+	// Interval the secrets are polled at when watched
+	Interval time.Duration
+	// Context for other opts
+	Context context.Context
+}
+
+// Option sets values in Options
+type Option func(o *Options)
+
+// Prefix of the names of the secrets e.g a path or namespace
+func Prefix(p string) Option {
+	return func(o *Options) {
+		o.Prefix = p
+	}
+}
+
+// Interval the secrets are polled at when watched
+func Interval(d time.Duration) Option {
+	return func(o *Options) {
+		o.Interval = d
+	}
+}
+
+// NewOptions returns the options with the defaults set
+func NewOptions(opts ...Option) Options {
+	options := Options{
+		Interval: DefaultInterval,
+		Context:  context.Background(),
+	}
+	for _, o := range opts {
+		o(&options)
+	}
+	return options
+}
+
+// Version of a value for the providers which don't version secrets
+func Version(value []byte) string {
+	h := sha256.Sum256(value)
+	return hex.EncodeToString(h[:8])
+}
@@ -0,0 +1,123 @@
+package provider_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/micro/go-micro/v2/config/secrets/provider"
+	"github.com/micro/go-micro/v2/config/secrets/provider/file"
+)
+
+func newProvider(t *testing.T) (provider.Provider, func()) {
+	dir, err := ioutil.TempDir("", "secrets")
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := file.NewProvider(provider.Prefix(dir), provider.Interval(10*time.Millisecond))
+	return p, func() { os.RemoveAll(dir) }
+}
+
+func TestKey(t *testing.T) {
+	p, done := newProvider(t)
+	defer done()
+
+	s, err := p.Rotate("store/master", []byte("0123456789abcdef0123456789abcdef"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	id, key, err := provider.Key(p, "store/master")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id != s.Version {
+		t.Fatalf("Expected the key id %s got %s", s.Version, id)
+	}
+	enc, err := key.Encrypt([]byte("hello"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dec, err := key.Decrypt(enc); err != nil || string(dec) != "hello" {
+		t.Fatalf("Expected hello got %s %v", dec, err)
+	}
+
+	if _, err := p.Rotate("store/short", []byte("short")); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := provider.Key(p, "store/short"); err == nil {
+		t.Fatal("Expected an error for a key of an invalid size")
+	}
+}
+
+func rotateCertificate(t *testing.T, p provider.Provider, cn string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: cn},
+		NotBefore:    time.Now(),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	kder, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := p.Rotate("tls/key", pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: kder})); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := p.Rotate("tls/cert", pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCertificate(t *testing.T) {
+	p, done := newProvider(t)
+	defer done()
+
+	rotateCertificate(t, p, "v1")
+
+	c, err := provider.NewCertificate(p, "tls/cert", "tls/key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Stop()
+
+	commonName := func() string {
+		cert, err := c.GetCertificate(nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		x, err := x509.ParseCertificate(cert.Certificate[0])
+		if err != nil {
+			t.Fatal(err)
+		}
+		return x.Subject.CommonName
+	}
+	if cn := commonName(); cn != "v1" {
+		t.Fatalf("Expected v1 got %s", cn)
+	}
+
+	rotateCertificate(t, p, "v2")
+
+	for i := 0; i < 100 && commonName() != "v2"; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if cn := commonName(); cn != "v2" {
+		t.Fatalf("Expected the rotated certificate got %s", cn)
+	}
+}
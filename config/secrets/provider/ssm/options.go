@@ -0,0 +1,45 @@
+package ssm
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/ssm/ssmiface"
+	"github.com/micro/go-micro/v2/config/secrets/provider"
+)
+
+type sessionKey struct{}
+type regionKey struct{}
+type kmsKeyIDKey struct{}
+type clientKey struct{}
+
+// Session sets the aws session. Defaults to a session
+// configured from the environment and shared config.
+func Session(s *session.Session) provider.Option {
+	return setProviderOption(sessionKey{}, s)
+}
+
+// Region sets the aws region
+func Region(r string) provider.Option {
+	return setProviderOption(regionKey{}, r)
+}
+
+// KMSKeyID of the key encrypting the rotated secrets. Defaults to the
+// aws managed key of the account.
+func KMSKeyID(id string) provider.Option {
+	return setProviderOption(kmsKeyIDKey{}, id)
+}
+
+// Client sets the ssm client e.g for tests
+func Client(c ssmiface.SSMAPI) provider.Option {
+	return setProviderOption(clientKey{}, c)
+}
+
+func setProviderOption(k, v interface{}) provider.Option {
+	return func(o *provider.Options) {
+		if o.Context == nil {
+			o.Context = context.Background()
+		}
+		o.Context = context.WithValue(o.Context, k, v)
+	}
+}
@@ -0,0 +1,143 @@
+// Package ssm is a secrets provider of the secure string parameters of the
+// aws systems manager parameter store
+package ssm
+
+import (
+	"path"
+	"strconv"
+	"sync"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/ssm"
+	"github.com/aws/aws-sdk-go/service/ssm/ssmiface"
+	"github.com/micro/go-micro/v2/config/secrets/provider"
+)
+
+type ssmProvider struct {
+	opts provider.Options
+
+	sync.Mutex
+	client ssmiface.SSMAPI
+}
+
+// NewProvider returns a provider of the parameters under the prefix path
+func NewProvider(opts ...provider.Option) provider.Provider {
+	return &ssmProvider{opts: provider.NewOptions(opts...)}
+}
+
+func (s *ssmProvider) Init(opts ...provider.Option) error {
+	s.Lock()
+	for _, o := range opts {
+		o(&s.opts)
+	}
+	s.client = nil
+	s.Unlock()
+
+	_, err := s.ssm()
+	return err
+}
+
+func (s *ssmProvider) Options() provider.Options {
+	return s.opts
+}
+
+// ssm returns the client, creating it on first use
+func (s *ssmProvider) ssm() (ssmiface.SSMAPI, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	if s.client != nil {
+		return s.client, nil
+	}
+	if c, ok := s.opts.Context.Value(clientKey{}).(ssmiface.SSMAPI); ok {
+		s.client = c
+		return c, nil
+	}
+
+	sess, ok := s.opts.Context.Value(sessionKey{}).(*session.Session)
+	if !ok {
+		se, err := session.NewSessionWithOptions(session.Options{
+			SharedConfigState: session.SharedConfigEnable,
+		})
+		if err != nil {
+			return nil, err
+		}
+		sess = se
+	}
+
+	cfg := aws.NewConfig()
+	if r, ok := s.opts.Context.Value(regionKey{}).(string); ok {
+		cfg = cfg.WithRegion(r)
+	}
+
+	s.client = ssm.New(sess, cfg)
+	return s.client, nil
+}
+
+// name of the parameter of the secret
+func (s *ssmProvider) name(name string) string {
+	return path.Join("/", s.opts.Prefix, name)
+}
+
+func (s *ssmProvider) Get(name string) (*provider.Secret, error) {
+	c, err := s.ssm()
+	if err != nil {
+		return nil, err
+	}
+
+	rsp, err := c.GetParameter(&ssm.GetParameterInput{
+		Name:           aws.String(s.name(name)),
+		WithDecryption: aws.Bool(true),
+	})
+	if aerr, ok := err.(awserr.Error); ok && aerr.Code() == ssm.ErrCodeParameterNotFound {
+		return nil, provider.ErrNotFound
+	} else if err != nil {
+		return nil, err
+	}
+
+	return &provider.Secret{
+		Name:    name,
+		Version: strconv.FormatInt(aws.Int64Value(rsp.Parameter.Version), 10),
+		Value:   []byte(aws.StringValue(rsp.Parameter.Value)),
+		Updated: aws.TimeValue(rsp.Parameter.LastModifiedDate),
+	}, nil
+}
+
+func (s *ssmProvider) Watch(name string) (provider.Watcher, error) {
+	return provider.Poll(s, name, s.opts.Interval)
+}
+
+// Rotate overwrites the parameter as a secure string, creating a new version
+func (s *ssmProvider) Rotate(name string, value []byte) (*provider.Secret, error) {
+	c, err := s.ssm()
+	if err != nil {
+		return nil, err
+	}
+
+	input := &ssm.PutParameterInput{
+		Name:      aws.String(s.name(name)),
+		Value:     aws.String(string(value)),
+		Type:      aws.String(ssm.ParameterTypeSecureString),
+		Overwrite: aws.Bool(true),
+	}
+	if id, ok := s.opts.Context.Value(kmsKeyIDKey{}).(string); ok {
+		input.KeyId = aws.String(id)
+	}
+
+	rsp, err := c.PutParameter(input)
+	if err != nil {
+		return nil, err
+	}
+
+	return &provider.Secret{
+		Name:    name,
+		Version: strconv.FormatInt(aws.Int64Value(rsp.Version), 10),
+		Value:   value,
+	}, nil
+}
+
+func (s *ssmProvider) String() string {
+	return "ssm"
+}
@@ -0,0 +1,92 @@
+package provider
+
+import (
+	"crypto/tls"
+	"sync"
+
+	"github.com/micro/go-micro/v2/logger"
+)
+
+// Certificate is a tls certificate of a pem certificate and key secret,
+// reloaded when the certificate is rotated
+type Certificate struct {
+	p         Provider
+	cert, key string
+	w         Watcher
+
+	sync.RWMutex
+	tls *tls.Certificate
+}
+
+// NewCertificate loads the certificate and watches it. The key must be
+// rotated before the certificate.
+func NewCertificate(p Provider, cert, key string) (*Certificate, error) {
+	c := &Certificate{p: p, cert: cert, key: key}
+	if err := c.load(); err != nil {
+		return nil, err
+	}
+
+	w, err := p.Watch(cert)
+	if err != nil {
+		return nil, err
+	}
+	c.w = w
+
+	go c.run()
+
+	return c, nil
+}
+
+func (c *Certificate) load() error {
+	cert, err := c.p.Get(c.cert)
+	if err != nil {
+		return err
+	}
+	key, err := c.p.Get(c.key)
+	if err != nil {
+		return err
+	}
+	pair, err := tls.X509KeyPair(cert.Value, key.Value)
+	if err != nil {
+		return err
+	}
+
+	c.Lock()
+	c.tls = &pair
+	c.Unlock()
+
+	return nil
+}
+
+func (c *Certificate) run() {
+	for {
+		if _, err := c.w.Next(); err == ErrWatcherStopped {
+			return
+		} else if err != nil {
+			// deleted, the previous certificate is served
+			continue
+		}
+		if err := c.load(); err != nil {
+			logger.Errorf("Error reloading the certificate %s: %v", c.cert, err)
+		}
+	}
+}
+
+// GetCertificate returns the certificate for tls.Config of a server
+func (c *Certificate) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	c.RLock()
+	defer c.RUnlock()
+	return c.tls, nil
+}
+
+// GetClientCertificate returns the certificate for tls.Config of a client
+func (c *Certificate) GetClientCertificate(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+	c.RLock()
+	defer c.RUnlock()
+	return c.tls, nil
+}
+
+// Stop reloading the certificate
+func (c *Certificate) Stop() error {
+	return c.w.Stop()
+}
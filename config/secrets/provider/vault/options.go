@@ -0,0 +1,42 @@
+package vault
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/micro/go-micro/v2/config/secrets/provider"
+)
+
+type addressKey struct{}
+type tokenKey struct{}
+type mountKey struct{}
+type clientKey struct{}
+
+// Address of vault. Defaults to VAULT_ADDR or http://127.0.0.1:8200.
+func Address(addr string) provider.Option {
+	return setProviderOption(addressKey{}, addr)
+}
+
+// Token authenticating to vault. Defaults to VAULT_TOKEN.
+func Token(t string) provider.Option {
+	return setProviderOption(tokenKey{}, t)
+}
+
+// Mount of the kv version 2 secrets engine. Defaults to secret.
+func Mount(m string) provider.Option {
+	return setProviderOption(mountKey{}, m)
+}
+
+// HTTPClient used to call vault. Defaults to a client with a 10s timeout.
+func HTTPClient(c *http.Client) provider.Option {
+	return setProviderOption(clientKey{}, c)
+}
+
+func setProviderOption(k, v interface{}) provider.Option {
+	return func(o *provider.Options) {
+		if o.Context == nil {
+			o.Context = context.Background()
+		}
+		o.Context = context.WithValue(o.Context, k, v)
+	}
+}
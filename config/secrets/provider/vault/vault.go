@@ -0,0 +1,167 @@
+// Package vault is a secrets provider of the kv version 2 secrets engine of
+// hashicorp vault. The value of a secret is its value field.
+package vault
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/micro/go-micro/v2/config/secrets/provider"
+)
+
+type vault struct {
+	opts provider.Options
+
+	addr, token, mount string
+	client             *http.Client
+}
+
+// NewProvider returns a provider of the secrets in vault
+func NewProvider(opts ...provider.Option) provider.Provider {
+	v := &vault{opts: provider.NewOptions(opts...)}
+	v.configure()
+	return v
+}
+
+func (v *vault) configure() {
+	v.addr = os.Getenv("VAULT_ADDR")
+	if len(v.addr) == 0 {
+		v.addr = "http://127.0.0.1:8200"
+	}
+	v.token = os.Getenv("VAULT_TOKEN")
+	v.mount = "secret"
+	v.client = &http.Client{Timeout: 10 * time.Second}
+
+	ctx := v.opts.Context
+	if a, ok := ctx.Value(addressKey{}).(string); ok {
+		v.addr = a
+	}
+	if t, ok := ctx.Value(tokenKey{}).(string); ok {
+		v.token = t
+	}
+	if m, ok := ctx.Value(mountKey{}).(string); ok {
+		v.mount = m
+	}
+	if c, ok := ctx.Value(clientKey{}).(*http.Client); ok {
+		v.client = c
+	}
+	v.addr = strings.TrimSuffix(v.addr, "/")
+}
+
+func (v *vault) Init(opts ...provider.Option) error {
+	for _, o := range opts {
+		o(&v.opts)
+	}
+	v.configure()
+	return nil
+}
+
+func (v *vault) Options() provider.Options {
+	return v.opts
+}
+
+// data of a version of a secret
+type data struct {
+	Data     map[string]string `json:"data"`
+	Metadata metadata          `json:"metadata"`
+}
+
+type metadata struct {
+	CreatedTime time.Time `json:"created_time"`
+	Version     int       `json:"version"`
+}
+
+func (v *vault) do(method, name string, body, rsp interface{}) error {
+	var r bytes.Buffer
+	if body != nil {
+		if err := json.NewEncoder(&r).Encode(body); err != nil {
+			return err
+		}
+	}
+
+	url := v.addr + "/v1/" + path.Join(v.mount, "data", v.opts.Prefix, name)
+	req, err := http.NewRequest(method, url, &r)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("X-Vault-Token", v.token)
+	req.Header.Set("Content-Type", "application/json")
+
+	res, err := v.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	b, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return err
+	}
+	if res.StatusCode == http.StatusNotFound {
+		return provider.ErrNotFound
+	}
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return fmt.Errorf("vault %s %s: %s %s", method, name, res.Status, bytes.TrimSpace(b))
+	}
+
+	return json.Unmarshal(b, rsp)
+}
+
+func (v *vault) Get(name string) (*provider.Secret, error) {
+	var rsp struct {
+		Data data `json:"data"`
+	}
+	if err := v.do("GET", name, nil, &rsp); err != nil {
+		return nil, err
+	}
+
+	// the latest version is deleted
+	value, ok := rsp.Data.Data["value"]
+	if !ok {
+		return nil, provider.ErrNotFound
+	}
+
+	return &provider.Secret{
+		Name:    name,
+		Version: strconv.Itoa(rsp.Data.Metadata.Version),
+		Value:   []byte(value),
+		Updated: rsp.Data.Metadata.CreatedTime,
+	}, nil
+}
+
+func (v *vault) Watch(name string) (provider.Watcher, error) {
+	return provider.Poll(v, name, v.opts.Interval)
+}
+
+// Rotate writes a new version of the secret. The previous versions are
+// kept by vault.
+func (v *vault) Rotate(name string, value []byte) (*provider.Secret, error) {
+	var rsp struct {
+		Data metadata `json:"data"`
+	}
+	body := map[string]interface{}{
+		"data": map[string]string{"value": string(value)},
+	}
+	if err := v.do("POST", name, body, &rsp); err != nil {
+		return nil, err
+	}
+
+	return &provider.Secret{
+		Name:    name,
+		Version: strconv.Itoa(rsp.Data.Version),
+		Value:   value,
+		Updated: rsp.Data.CreatedTime,
+	}, nil
+}
+
+func (v *vault) String() string {
+	return "vault"
+}
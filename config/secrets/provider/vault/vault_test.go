@@ -0,0 +1,81 @@
+package vault
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/micro/go-micro/v2/config/secrets/provider"
+)
+
+func TestVault(t *testing.T) {
+	var mtx sync.Mutex
+	versions := map[string][]string{}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Token") != "root" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		mtx.Lock()
+		defer mtx.Unlock()
+
+		switch r.Method {
+		case "POST":
+			var body struct {
+				Data map[string]string `json:"data"`
+			}
+			json.NewDecoder(r.Body).Decode(&body)
+			versions[r.URL.Path] = append(versions[r.URL.Path], body.Data["value"])
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"data": map[string]interface{}{"version": len(versions[r.URL.Path])},
+			})
+		case "GET":
+			v := versions[r.URL.Path]
+			if len(v) == 0 {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"data": map[string]interface{}{
+					"data":     map[string]string{"value": v[len(v)-1]},
+					"metadata": map[string]interface{}{"version": len(v)},
+				},
+			})
+		}
+	}))
+	defer srv.Close()
+
+	p := NewProvider(Address(srv.URL), Token("root"), provider.Prefix("micro"))
+
+	if _, err := p.Get("db/password"); err != provider.ErrNotFound {
+		t.Fatalf("Expected %v got %v", provider.ErrNotFound, err)
+	}
+	if _, err := p.Rotate("db/password", []byte("hunter2")); err != nil {
+		t.Fatal(err)
+	}
+	s, err := p.Rotate("db/password", []byte("correct horse"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Version != "2" {
+		t.Fatalf("Expected version 2 got %s", s.Version)
+	}
+
+	s, err = p.Get("db/password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(s.Value) != "correct horse" || s.Version != "2" {
+		t.Fatalf("Unexpected secret %+v", s)
+	}
+	if _, ok := versions["/v1/secret/data/micro/db/password"]; !ok {
+		t.Fatalf("Expected the secret under the mount and prefix got %v", versions)
+	}
+
+	if _, err := NewProvider(Address(srv.URL), Token("invalid")).Get("db/password"); err == nil {
+		t.Fatal("Expected an error with an invalid token")
+	}
+}
@@ -0,0 +1,72 @@
+package provider
+
+import (
+	"sync"
+	"time"
+)
+
+type pollWatcher struct {
+	get      func() (*Secret, error)
+	interval time.Duration
+	version  string
+
+	once sync.Once
+	exit chan bool
+}
+
+// Poll returns a watcher getting the secret every interval for the
+// providers which can't watch. It returns the versions after the current.
+func Poll(p Provider, name string, interval time.Duration) (Watcher, error) {
+	w := &pollWatcher{
+		get:      func() (*Secret, error) { return p.Get(name) },
+		interval: interval,
+		exit:     make(chan bool),
+	}
+
+	s, err := w.get()
+	if err != nil && err != ErrNotFound {
+		return nil, err
+	}
+	if s != nil {
+		w.version = s.Version
+	}
+
+	return w, nil
+}
+
+func (w *pollWatcher) Next() (*Secret, error) {
+	t := time.NewTicker(w.interval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-t.C:
+		case <-w.exit:
+			return nil, ErrWatcherStopped
+		}
+
+		s, err := w.get()
+		if err == ErrNotFound {
+			if len(w.version) == 0 {
+				continue
+			}
+			w.version = ""
+			return nil, ErrNotFound
+		} else if err != nil {
+			// the provider may be unavailable, retried on the next tick
+			continue
+		}
+		if s.Version == w.version {
+			continue
+		}
+		w.version = s.Version
+		return s, nil
+	}
+}
+
+func (w *pollWatcher) Stop() error {
+	w.once.Do(func() {
+		close(w.exit)
+	})
+	return nil
+}
@@ -0,0 +1,170 @@
+// Package consul is a config source reading the consul kv store. The source
+// is watched with blocking queries so changes are loaded within seconds.
+package consul
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/micro/go-micro/v2/config/source"
+)
+
+var (
+	DefaultPrefix = "micro/config/"
+	// DefaultWaitTime of the blocking queries of a watch
+	DefaultWaitTime = 5 * time.Minute
+)
+
+type consul struct {
+	address     string
+	prefix      string
+	stripPrefix string
+	dc          string
+	token       string
+	wait        time.Duration
+	opts        source.Options
+	client      *http.Client
+}
+
+// list the keys with the prefix. A non zero index blocks until the keys
+// change after it or the wait time passes.
+func (c *consul) list(ctx context.Context, index uint64) ([]*kv, uint64, error) {
+	q := url.Values{}
+	q.Set("recurse", "true")
+	if len(c.dc) > 0 {
+		q.Set("dc", c.dc)
+	}
+	if index > 0 {
+		q.Set("index", strconv.FormatUint(index, 10))
+		q.Set("wait", fmt.Sprintf("%ds", int(c.wait.Seconds())))
+	}
+
+	u := fmt.Sprintf("%s/v1/kv/%s?%s", c.address, strings.TrimPrefix(c.prefix, "/"), q.Encode())
+	req, err := http.NewRequest("GET", u, nil)
+	if err != nil {
+		return nil, 0, err
+	}
+	req = req.WithContext(ctx)
+	if len(c.token) > 0 {
+		req.Header.Set("X-Consul-Token", c.token)
+	}
+
+	rsp, err := c.client.Do(req)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rsp.Body.Close()
+
+	idx, _ := strconv.ParseUint(rsp.Header.Get("X-Consul-Index"), 10, 64)
+
+	switch rsp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return nil, idx, nil
+	default:
+		return nil, 0, fmt.Errorf("consul: %s", rsp.Status)
+	}
+
+	var kvs []*kv
+	if err := json.NewDecoder(rsp.Body).Decode(&kvs); err != nil {
+		return nil, 0, err
+	}
+	return kvs, idx, nil
+}
+
+func (c *consul) changeSet(kvs []*kv) (*source.ChangeSet, error) {
+	data := makeMap(c.opts.Encoder, kvs, c.stripPrefix)
+
+	b, err := c.opts.Encoder.Encode(data)
+	if err != nil {
+		return nil, fmt.Errorf("error reading source: %v", err)
+	}
+
+	cs := &source.ChangeSet{
+		Timestamp: time.Now(),
+		Source:    c.String(),
+		Data:      b,
+		Format:    c.opts.Encoder.String(),
+	}
+	cs.Checksum = cs.Sum()
+
+	return cs, nil
+}
+
+func (c *consul) Read() (*source.ChangeSet, error) {
+	kvs, _, err := c.list(context.Background(), 0)
+	if err != nil {
+		return nil, err
+	}
+	if len(kvs) == 0 {
+		return nil, fmt.Errorf("source not found: %s", c.prefix)
+	}
+	return c.changeSet(kvs)
+}
+
+func (c *consul) Watch() (source.Watcher, error) {
+	kvs, index, err := c.list(context.Background(), 0)
+	if err != nil {
+		return nil, err
+	}
+	cs, err := c.changeSet(kvs)
+	if err != nil {
+		return nil, err
+	}
+	return newWatcher(c, cs, index), nil
+}
+
+func (c *consul) Write(cs *source.ChangeSet) error {
+	return nil
+}
+
+func (c *consul) String() string {
+	return "consul"
+}
+
+// NewSource returns a consul kv config source
+func NewSource(opts ...source.Option) source.Source {
+	options := source.NewOptions(opts...)
+
+	addr := "127.0.0.1:8500"
+	if a, ok := options.Context.Value(addressKey{}).(string); ok {
+		addr = a
+	}
+	if !strings.Contains(addr, "://") {
+		addr = "http://" + addr
+	}
+
+	c := &consul{
+		address: strings.TrimSuffix(addr, "/"),
+		prefix:  DefaultPrefix,
+		wait:    DefaultWaitTime,
+		opts:    options,
+	}
+
+	if p, ok := options.Context.Value(prefixKey{}).(string); ok {
+		c.prefix = p
+	}
+	if b, ok := options.Context.Value(stripPrefixKey{}).(bool); ok && b {
+		c.stripPrefix = strings.TrimPrefix(c.prefix, "/")
+	}
+	if dc, ok := options.Context.Value(dcKey{}).(string); ok {
+		c.dc = dc
+	}
+	if t, ok := options.Context.Value(tokenKey{}).(string); ok {
+		c.token = t
+	}
+	if d, ok := options.Context.Value(waitTimeKey{}).(time.Duration); ok {
+		c.wait = d
+	}
+
+	// the blocking queries outlast the wait time by up to 1/16th
+	c.client = &http.Client{Timeout: c.wait + c.wait/16 + 10*time.Second}
+
+	return c
+}
@@ -0,0 +1,99 @@
+package consul
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+type testConsul struct {
+	sync.Mutex
+	index   uint64
+	kvs     []*kv
+	changed chan bool
+}
+
+func (c *testConsul) set(kvs ...*kv) {
+	c.Lock()
+	c.index++
+	c.kvs = kvs
+	close(c.changed)
+	c.changed = make(chan bool)
+	c.Unlock()
+}
+
+func (c *testConsul) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/v1/kv/micro/config/" || r.URL.Query().Get("recurse") != "true" {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	c.Lock()
+	changed := c.changed
+	index := c.index
+	c.Unlock()
+
+	// block until the keys change after the index
+	if i, _ := strconv.ParseUint(r.URL.Query().Get("index"), 10, 64); i > 0 && i == index {
+		select {
+		case <-changed:
+		case <-time.After(time.Second):
+		}
+	}
+
+	c.Lock()
+	defer c.Unlock()
+	w.Header().Set("X-Consul-Index", strconv.FormatUint(c.index, 10))
+	json.NewEncoder(w).Encode(c.kvs)
+}
+
+func TestConsul(t *testing.T) {
+	tc := &testConsul{changed: make(chan bool)}
+	tc.set(
+		&kv{Key: "micro/config/"},
+		&kv{Key: "micro/config/database", Value: []byte(`{"host": "a", "port": 3306}`)},
+		&kv{Key: "micro/config/cache/address", Value: []byte(`plain`)},
+	)
+	srv := httptest.NewServer(tc)
+	defer srv.Close()
+
+	s := NewSource(WithAddress(srv.URL), StripPrefix(true))
+
+	cs, err := s.Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var data map[string]map[string]interface{}
+	if err := json.Unmarshal(cs.Data, &data); err != nil {
+		t.Fatal(err)
+	}
+	if data["database"]["host"] != "a" || data["cache"]["address"] != "plain" {
+		t.Fatalf("Unexpected data %s", cs.Data)
+	}
+
+	w, err := s.Watch()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Stop()
+
+	go tc.set(&kv{Key: "micro/config/database", Value: []byte(`{"host": "b"}`)})
+
+	cs, err = w.Next()
+	if err != nil {
+		t.Fatal(err)
+	}
+	data = nil
+	json.Unmarshal(cs.Data, &data)
+	if data["database"]["host"] != "b" || data["cache"] != nil {
+		t.Fatalf("Expected the changed data got %s", cs.Data)
+	}
+
+	if _, err := NewSource(WithAddress(srv.URL), WithPrefix("missing/")).Read(); err == nil {
+		t.Fatal("Expected a source not found error")
+	}
+}
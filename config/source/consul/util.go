@@ -0,0 +1,59 @@
+package consul
+
+import (
+	"strings"
+
+	"github.com/micro/go-micro/v2/config/encoder"
+)
+
+// kv is a key value of the consul kv api
+type kv struct {
+	Key         string
+	Value       []byte
+	ModifyIndex uint64
+}
+
+// makeMap maps the keys split on / to nested maps of their decoded values
+func makeMap(e encoder.Encoder, kvs []*kv, stripPrefix string) map[string]interface{} {
+	data := make(map[string]interface{})
+
+	for _, v := range kvs {
+		// folders have no value
+		if strings.HasSuffix(v.Key, "/") {
+			continue
+		}
+
+		// remove prefix if non empty, and ensure leading / is removed as well
+		vkey := strings.TrimPrefix(strings.TrimPrefix(v.Key, stripPrefix), "/")
+		keys := strings.Split(vkey, "/")
+
+		var vals interface{}
+		if err := e.Decode(v.Value, &vals); err != nil {
+			// plain values are kept as strings
+			vals = string(v.Value)
+		}
+
+		if len(keys) == 1 && len(keys[0]) == 0 {
+			// the prefix itself is the config
+			if m, ok := vals.(map[string]interface{}); ok {
+				for k, v := range m {
+					data[k] = v
+				}
+			}
+			continue
+		}
+
+		kvals := data
+		for _, k := range keys[:len(keys)-1] {
+			kval, ok := kvals[k].(map[string]interface{})
+			if !ok {
+				kval = make(map[string]interface{})
+				kvals[k] = kval
+			}
+			kvals = kval
+		}
+		kvals[keys[len(keys)-1]] = vals
+	}
+
+	return data
+}
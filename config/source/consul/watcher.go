@@ -0,0 +1,88 @@
+package consul
+
+import (
+	"context"
+	"time"
+
+	"github.com/micro/go-micro/v2/config/source"
+)
+
+type watcher struct {
+	consul *consul
+	cs     *source.ChangeSet
+	index  uint64
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	ch     chan *source.ChangeSet
+}
+
+func newWatcher(c *consul, cs *source.ChangeSet, index uint64) *watcher {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	w := &watcher{
+		consul: c,
+		cs:     cs,
+		index:  index,
+		ctx:    ctx,
+		cancel: cancel,
+		ch:     make(chan *source.ChangeSet),
+	}
+
+	go w.run()
+
+	return w
+}
+
+func (w *watcher) run() {
+	for {
+		kvs, index, err := w.consul.list(w.ctx, w.index)
+		if w.ctx.Err() != nil {
+			return
+		}
+		if err != nil || index == 0 {
+			// back off before retrying
+			select {
+			case <-time.After(time.Second):
+				continue
+			case <-w.ctx.Done():
+				return
+			}
+		}
+
+		// the index is reset if it goes backwards
+		if index < w.index {
+			index = 0
+		}
+		if index == w.index {
+			continue
+		}
+		w.index = index
+
+		cs, err := w.consul.changeSet(kvs)
+		if err != nil || cs.Checksum == w.cs.Checksum {
+			continue
+		}
+		w.cs = cs
+
+		select {
+		case w.ch <- cs:
+		case <-w.ctx.Done():
+			return
+		}
+	}
+}
+
+func (w *watcher) Next() (*source.ChangeSet, error) {
+	select {
+	case cs := <-w.ch:
+		return cs, nil
+	case <-w.ctx.Done():
+		return nil, source.ErrWatcherStopped
+	}
+}
+
+func (w *watcher) Stop() error {
+	w.cancel()
+	return nil
+}
@@ -0,0 +1,197 @@
+// Package decrypt is a config source wrapper which decrypts the encrypted
+// values of any source when it's read so sensitive settings can be kept in
+// files under version control. The encrypted values are strings of the
+// base64 ciphertext of the secrets prefixed with enc: or enc:<name>: e.g
+//
+//	{"database": {"password": "enc:c2VjcmV0..."}}
+//
+// The values are encrypted with Encrypt.
+package decrypt
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/micro/go-micro/v2/config/encoder"
+	"github.com/micro/go-micro/v2/config/encoder/json"
+	"github.com/micro/go-micro/v2/config/reader"
+	"github.com/micro/go-micro/v2/config/secrets"
+	"github.com/micro/go-micro/v2/config/source"
+)
+
+// Prefix of the encrypted values
+const Prefix = "enc:"
+
+var (
+	// ErrUnknownSecrets is returned when a value is encrypted by secrets which aren't set
+	ErrUnknownSecrets = errors.New("unknown secrets")
+)
+
+type decrypt struct {
+	source  source.Source
+	secrets map[string]secrets.Secrets
+	// the encoders of the change sets by format
+	encoding map[string]encoder.Encoder
+	json     encoder.Encoder
+}
+
+// Encrypt returns the config value of the encrypted plaintext. The name is
+// the one the secrets are set with, none for WithSecrets.
+func Encrypt(s secrets.Secrets, plaintext []byte, name ...string) (string, error) {
+	b, err := s.Encrypt(plaintext)
+	if err != nil {
+		return "", err
+	}
+	v := Prefix
+	if len(name) > 0 && len(name[0]) > 0 {
+		v += name[0] + ":"
+	}
+	return v + base64.StdEncoding.EncodeToString(b), nil
+}
+
+// value decrypts an encrypted value
+func (d *decrypt) value(path []string, v string) (string, error) {
+	v = strings.TrimPrefix(v, Prefix)
+
+	var name string
+	if i := strings.Index(v, ":"); i >= 0 {
+		name, v = v[:i], v[i+1:]
+	}
+
+	s, ok := d.secrets[name]
+	if !ok {
+		return "", fmt.Errorf("error decrypting %s: %v %s", strings.Join(path, "."), ErrUnknownSecrets, name)
+	}
+
+	b, err := base64.StdEncoding.DecodeString(v)
+	if err != nil {
+		return "", fmt.Errorf("error decrypting %s: %v", strings.Join(path, "."), err)
+	}
+	b, err = s.Decrypt(b)
+	if err != nil {
+		return "", fmt.Errorf("error decrypting %s: %v", strings.Join(path, "."), err)
+	}
+	return string(b), nil
+}
+
+// walk the values and decrypt the encrypted strings
+func (d *decrypt) walk(path []string, v interface{}) (interface{}, error) {
+	switch t := v.(type) {
+	case string:
+		if !strings.HasPrefix(t, Prefix) {
+			return t, nil
+		}
+		return d.value(path, t)
+	case map[string]interface{}:
+		for k, val := range t {
+			dv, err := d.walk(append(path, k), val)
+			if err != nil {
+				return nil, err
+			}
+			t[k] = dv
+		}
+	case []interface{}:
+		for i, val := range t {
+			dv, err := d.walk(append(path, fmt.Sprint(i)), val)
+			if err != nil {
+				return nil, err
+			}
+			t[i] = dv
+		}
+	}
+	return v, nil
+}
+
+// changeSet decrypts the values of the change set. The decrypted change set
+// is encoded as json.
+func (d *decrypt) changeSet(cs *source.ChangeSet) (*source.ChangeSet, error) {
+	if cs == nil || len(cs.Data) == 0 {
+		return cs, nil
+	}
+
+	enc, ok := d.encoding[cs.Format]
+	if !ok {
+		enc = d.json
+	}
+
+	var data map[string]interface{}
+	if err := enc.Decode(cs.Data, &data); err != nil {
+		return nil, err
+	}
+	if _, err := d.walk(nil, data); err != nil {
+		return nil, err
+	}
+
+	b, err := d.json.Encode(data)
+	if err != nil {
+		return nil, err
+	}
+
+	ncs := &source.ChangeSet{
+		Timestamp: time.Now(),
+		Source:    cs.Source,
+		Data:      b,
+		Format:    d.json.String(),
+	}
+	ncs.Checksum = ncs.Sum()
+
+	return ncs, nil
+}
+
+func (d *decrypt) Read() (*source.ChangeSet, error) {
+	cs, err := d.source.Read()
+	if err != nil {
+		return nil, err
+	}
+	return d.changeSet(cs)
+}
+
+func (d *decrypt) Write(cs *source.ChangeSet) error {
+	return d.source.Write(cs)
+}
+
+func (d *decrypt) Watch() (source.Watcher, error) {
+	w, err := d.source.Watch()
+	if err != nil {
+		return nil, err
+	}
+	return &watcher{decrypt: d, watcher: w}, nil
+}
+
+func (d *decrypt) String() string {
+	return d.source.String()
+}
+
+// NewSource returns a source decrypting the values of the source
+func NewSource(s source.Source, opts ...source.Option) source.Source {
+	options := source.NewOptions(opts...)
+
+	d := &decrypt{
+		source:   s,
+		encoding: reader.NewOptions().Encoding,
+		json:     json.NewEncoder(),
+	}
+	d.secrets, _ = options.Context.Value(secretsKey{}).(map[string]secrets.Secrets)
+
+	return d
+}
+
+type watcher struct {
+	decrypt *decrypt
+	watcher source.Watcher
+}
+
+func (w *watcher) Next() (*source.ChangeSet, error) {
+	cs, err := w.watcher.Next()
+	if err != nil {
+		return nil, err
+	}
+	return w.decrypt.changeSet(cs)
+}
+
+func (w *watcher) Stop() error {
+	return w.watcher.Stop()
+}
@@ -0,0 +1,64 @@
+package decrypt
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/micro/go-micro/v2/config"
+	"github.com/micro/go-micro/v2/config/secrets"
+	"github.com/micro/go-micro/v2/config/secrets/aesgcm"
+	"github.com/micro/go-micro/v2/config/source/memory"
+)
+
+func newSecrets(t *testing.T, key string) secrets.Secrets {
+	s := aesgcm.NewSecrets(secrets.Key([]byte(key)))
+	if err := s.Init(); err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestDecrypt(t *testing.T) {
+	dev := newSecrets(t, "0123456789abcdef0123456789abcdef")
+	prod := newSecrets(t, "fedcba9876543210fedcba9876543210")
+
+	password, err := Encrypt(dev, []byte("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, err := Encrypt(prod, []byte("token"), "prod")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(token, "enc:prod:") {
+		t.Fatalf("Unexpected value %s", token)
+	}
+
+	yaml := fmt.Sprintf("database:\n  user: admin\n  password: %s\napi:\n  tokens:\n  - %s\n", password, token)
+
+	c, _ := config.NewConfig()
+	err = c.Load(NewSource(memory.NewSource(memory.WithYAML([]byte(yaml))),
+		WithSecrets(dev),
+		WithNamedSecrets("prod", prod),
+	))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if v := c.Get("database", "password").String(""); v != "secret" {
+		t.Fatalf("Expected the decrypted password got %s", v)
+	}
+	if v := c.Get("database", "user").String(""); v != "admin" {
+		t.Fatalf("Expected the plain user got %s", v)
+	}
+	if v := c.Get("api", "tokens").StringSlice(nil); len(v) != 1 || v[0] != "token" {
+		t.Fatalf("Expected the decrypted token got %v", v)
+	}
+
+	// the prod secrets aren't set
+	_, err = NewSource(memory.NewSource(memory.WithYAML([]byte(yaml))), WithSecrets(dev)).Read()
+	if err == nil || !strings.Contains(err.Error(), "api.tokens.0") {
+		t.Fatalf("Expected an unknown secrets error got %v", err)
+	}
+}
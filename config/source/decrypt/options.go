@@ -0,0 +1,38 @@
+package decrypt
+
+import (
+	"context"
+
+	"github.com/micro/go-micro/v2/config/secrets"
+	"github.com/micro/go-micro/v2/config/source"
+)
+
+type secretsKey struct{}
+
+func withSecrets(o *source.Options, name string, s secrets.Secrets) {
+	if o.Context == nil {
+		o.Context = context.Background()
+	}
+	m, _ := o.Context.Value(secretsKey{}).(map[string]secrets.Secrets)
+	c := make(map[string]secrets.Secrets, len(m)+1)
+	for k, v := range m {
+		c[k] = v
+	}
+	c[name] = s
+	o.Context = context.WithValue(o.Context, secretsKey{}, c)
+}
+
+// WithSecrets sets the initialised secrets decrypting the enc:<base64> values
+func WithSecrets(s secrets.Secrets) source.Option {
+	return func(o *source.Options) {
+		withSecrets(o, "", s)
+	}
+}
+
+// WithNamedSecrets adds initialised secrets decrypting the
+// enc:<name>:<base64> values e.g to use a key per environment
+func WithNamedSecrets(name string, s secrets.Secrets) source.Option {
+	return func(o *source.Options) {
+		withSecrets(o, name, s)
+	}
+}
@@ -75,7 +75,10 @@ func (w *watcher) handle(evs []*cetcd.Event) {
 	w.Unlock()
 
 	// send update
-	w.ch <- cs
+	select {
+	case w.ch <- cs:
+	case <-w.exit:
+	}
 }
 
 func (w *watcher) run(wc cetcd.Watcher, ch cetcd.WatchChan) {
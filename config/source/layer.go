@@ -0,0 +1,47 @@
+package source
+
+// Precedences of the usual layers of config. The values of a source with a
+// higher precedence override the values of the sources with a lower one.
+const (
+	PrecedenceDefaults = iota * 100
+	PrecedenceFile
+	PrecedenceEnv
+	PrecedenceFlag
+	PrecedenceRemote
+)
+
+// Layered is implemented by sources with a precedence. The sources which
+// don't implement it have the precedence of the defaults.
+type Layered interface {
+	Precedence() int
+}
+
+// Precedence of the source
+func Precedence(s Source) int {
+	if l, ok := s.(Layered); ok {
+		return l.Precedence()
+	}
+	return PrecedenceDefaults
+}
+
+// Layer returns the source with the precedence so it's merged in a
+// deterministic order whatever the order it's loaded in e.g
+//
+//	config.Load(
+//		source.Layer(env.NewSource(), source.PrecedenceEnv),
+//		source.Layer(file.NewSource(), source.PrecedenceFile),
+//	)
+//
+// The sources with the same precedence are merged in the order they're loaded.
+func Layer(s Source, precedence int) Source {
+	return &layer{Source: s, precedence: precedence}
+}
+
+type layer struct {
+	Source
+	precedence int
+}
+
+func (l *layer) Precedence() int {
+	return l.precedence
+}
@@ -31,7 +31,7 @@ func (m *service) Read() (set *source.ChangeSet, err error) {
 		Namespace: m.namespace,
 		Path:      m.path,
 	})
-	if verr, ok := err.(*errors.Error); ok && verr.Code == http.StatusNotFound {
+	if errors.Code(err) == http.StatusNotFound {
 		return nil, nil
 	} else if err != nil {
 		return nil, err
@@ -0,0 +1,112 @@
+package vault
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// errNotFound is returned when a secret doesn't exist
+var errNotFound = errors.New("secret not found")
+
+// client of the vault http api
+type client struct {
+	address   string
+	token     string
+	namespace string
+	http      *http.Client
+}
+
+// secret returned by vault
+type secret struct {
+	LeaseID       string                 `json:"lease_id"`
+	LeaseDuration int                    `json:"lease_duration"`
+	Renewable     bool                   `json:"renewable"`
+	Data          map[string]interface{} `json:"data"`
+}
+
+func (c *client) do(method, path string, in, out interface{}) error {
+	var body bytes.Buffer
+	if in != nil {
+		if err := json.NewEncoder(&body).Encode(in); err != nil {
+			return err
+		}
+	}
+
+	req, err := http.NewRequest(method, strings.TrimSuffix(c.address, "/")+"/v1/"+strings.TrimPrefix(path, "/"), &body)
+	if err != nil {
+		return err
+	}
+	if len(c.token) > 0 {
+		req.Header.Set("X-Vault-Token", c.token)
+	}
+	if len(c.namespace) > 0 {
+		req.Header.Set("X-Vault-Namespace", c.namespace)
+	}
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	rsp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer rsp.Body.Close()
+
+	if rsp.StatusCode == http.StatusNotFound {
+		return errNotFound
+	}
+	if rsp.StatusCode >= 300 {
+		var verr struct {
+			Errors []string `json:"errors"`
+		}
+		if err := json.NewDecoder(rsp.Body).Decode(&verr); err != nil || len(verr.Errors) == 0 {
+			return fmt.Errorf("vault: %s", rsp.Status)
+		}
+		return fmt.Errorf("vault: %s", strings.Join(verr.Errors, ", "))
+	}
+
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(rsp.Body).Decode(out)
+}
+
+// readKV reads the latest version of a KV v2 secret
+func (c *client) readKV(mount, path string) (map[string]interface{}, error) {
+	var rsp struct {
+		Data struct {
+			Data map[string]interface{} `json:"data"`
+		} `json:"data"`
+	}
+	if err := c.do("GET", mount+"/data/"+path, nil, &rsp); err != nil {
+		return nil, err
+	}
+	return rsp.Data.Data, nil
+}
+
+// read a secret e.g a dynamic secret
+func (c *client) read(path string) (*secret, error) {
+	var s secret
+	if err := c.do("GET", path, nil, &s); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// renew the lease and return its new duration
+func (c *client) renew(leaseID string, increment time.Duration) (time.Duration, error) {
+	req := map[string]interface{}{
+		"lease_id":  leaseID,
+		"increment": int(increment.Seconds()),
+	}
+	var s secret
+	if err := c.do("PUT", "sys/leases/renew", req, &s); err != nil {
+		return 0, err
+	}
+	return time.Duration(s.LeaseDuration) * time.Second, nil
+}
@@ -0,0 +1,93 @@
+package vault
+
+import (
+	"context"
+	"time"
+
+	"github.com/micro/go-micro/v2/config/source"
+)
+
+type addressKey struct{}
+type tokenKey struct{}
+type namespaceKey struct{}
+type kvKey struct{}
+type dynamicKey struct{}
+type intervalKey struct{}
+type rotateKey struct{}
+
+type kvPath struct {
+	mount, path string
+}
+
+// RotateFunc is called with the new data of a dynamic secret whose lease
+// couldn't be renewed
+type RotateFunc func(path string, data map[string]interface{})
+
+func withValue(o *source.Options, k, v interface{}) {
+	if o.Context == nil {
+		o.Context = context.Background()
+	}
+	o.Context = context.WithValue(o.Context, k, v)
+}
+
+// WithAddress sets the vault address, it defaults to VAULT_ADDR or
+// http://127.0.0.1:8200
+func WithAddress(addr string) source.Option {
+	return func(o *source.Options) {
+		withValue(o, addressKey{}, addr)
+	}
+}
+
+// WithToken sets the vault token, it defaults to VAULT_TOKEN
+func WithToken(token string) source.Option {
+	return func(o *source.Options) {
+		withValue(o, tokenKey{}, token)
+	}
+}
+
+// WithNamespace sets the vault enterprise namespace
+func WithNamespace(ns string) source.Option {
+	return func(o *source.Options) {
+		withValue(o, namespaceKey{}, ns)
+	}
+}
+
+// WithKV reads the latest version of a KV v2 secret. Its data is set under
+// the keys of its path split on / e.g conf.Get("myapp", "db").
+func WithKV(mount, path string) source.Option {
+	return func(o *source.Options) {
+		var kv []kvPath
+		if o.Context != nil {
+			kv, _ = o.Context.Value(kvKey{}).([]kvPath)
+		}
+		withValue(o, kvKey{}, append(kv, kvPath{mount, path}))
+	}
+}
+
+// WithDynamic reads a dynamic secret e.g database/creds/readonly. Its lease
+// is renewed while watched and the secret is read again once the lease
+// can't be renewed. Its data is set under the keys of its path split on /.
+func WithDynamic(path string) source.Option {
+	return func(o *source.Options) {
+		var paths []string
+		if o.Context != nil {
+			paths, _ = o.Context.Value(dynamicKey{}).([]string)
+		}
+		withValue(o, dynamicKey{}, append(paths, path))
+	}
+}
+
+// WithPollInterval sets the interval the KV secrets are read at while
+// watched, it defaults to 30 seconds
+func WithPollInterval(d time.Duration) source.Option {
+	return func(o *source.Options) {
+		withValue(o, intervalKey{}, d)
+	}
+}
+
+// OnRotate sets the function called when a dynamic secret is rotated
+func OnRotate(fn RotateFunc) source.Option {
+	return func(o *source.Options) {
+		withValue(o, rotateKey{}, fn)
+	}
+}
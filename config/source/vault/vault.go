@@ -0,0 +1,251 @@
+// Package vault is a config source reading KV v2 and dynamic secrets from
+// HashiCorp Vault. The leases of the dynamic secrets are renewed while the
+// source is watched and the secrets are read again once their leases can't
+// be renewed e.g at their max TTL.
+package vault
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/micro/go-micro/v2/config/source"
+)
+
+var (
+	// DefaultAddress of vault if VAULT_ADDR isn't set
+	DefaultAddress = "http://127.0.0.1:8200"
+	// DefaultPollInterval of the KV secrets while watched
+	DefaultPollInterval = 30 * time.Second
+)
+
+type vault struct {
+	opts     source.Options
+	client   *client
+	kv       []kvPath
+	dynamic  []string
+	interval time.Duration
+	onRotate RotateFunc
+
+	sync.Mutex
+	// the leases of the dynamic secrets by path
+	leases map[string]*lease
+}
+
+type lease struct {
+	secret   *secret
+	duration time.Duration
+	// time the lease is renewed at, zero if it's not leased
+	renewAt time.Time
+	expires time.Time
+}
+
+func newLease(s *secret) *lease {
+	l := &lease{
+		secret:   s,
+		duration: time.Duration(s.LeaseDuration) * time.Second,
+	}
+	if l.duration > 0 {
+		l.extend(l.duration)
+	}
+	return l
+}
+
+// extend the lease by d and renew it after two thirds of it
+func (l *lease) extend(d time.Duration) {
+	now := time.Now()
+	l.expires = now.Add(d)
+	l.renewAt = now.Add(d * 2 / 3)
+}
+
+// set the data under the keys of the path
+func set(data map[string]interface{}, path string, v map[string]interface{}) {
+	keys := strings.Split(strings.Trim(path, "/"), "/")
+	for _, k := range keys[:len(keys)-1] {
+		m, ok := data[k].(map[string]interface{})
+		if !ok {
+			m = make(map[string]interface{})
+			data[k] = m
+		}
+		data = m
+	}
+	data[keys[len(keys)-1]] = v
+}
+
+// secret returns the data of a dynamic secret, read again if its lease expired
+func (v *vault) secret(path string) (map[string]interface{}, error) {
+	v.Lock()
+	defer v.Unlock()
+
+	if l, ok := v.leases[path]; ok && (l.renewAt.IsZero() || time.Now().Before(l.expires)) {
+		return l.secret.Data, nil
+	}
+
+	s, err := v.client.read(path)
+	if err != nil {
+		return nil, err
+	}
+	v.leases[path] = newLease(s)
+	return s.Data, nil
+}
+
+// next is the time the next lease is renewed at
+func (v *vault) next() time.Time {
+	v.Lock()
+	defer v.Unlock()
+
+	var next time.Time
+	for _, l := range v.leases {
+		if l.renewAt.IsZero() {
+			continue
+		}
+		if next.IsZero() || l.renewAt.Before(next) {
+			next = l.renewAt
+		}
+	}
+	return next
+}
+
+// renew the leases due and rotate the secrets whose leases can't be renewed
+func (v *vault) renew() {
+	v.Lock()
+	var due []string
+	now := time.Now()
+	for path, l := range v.leases {
+		if !l.renewAt.IsZero() && !now.Before(l.renewAt) {
+			due = append(due, path)
+		}
+	}
+	v.Unlock()
+
+	for _, path := range due {
+		v.Lock()
+		l := v.leases[path]
+		v.Unlock()
+
+		if l.secret.Renewable {
+			d, err := v.client.renew(l.secret.LeaseID, l.duration)
+			// a shorter lease is capped by its max TTL
+			if err == nil && d >= l.duration/2 {
+				v.Lock()
+				l.extend(d)
+				v.Unlock()
+				continue
+			}
+		}
+
+		s, err := v.client.read(path)
+		if err != nil {
+			// retry before the lease expires
+			v.Lock()
+			l.renewAt = now.Add(time.Until(l.expires) / 2)
+			if time.Until(l.renewAt) < time.Second {
+				l.renewAt = now.Add(time.Second)
+			}
+			v.Unlock()
+			continue
+		}
+
+		v.Lock()
+		v.leases[path] = newLease(s)
+		v.Unlock()
+
+		if v.onRotate != nil {
+			v.onRotate(path, s.Data)
+		}
+	}
+}
+
+func (v *vault) Read() (*source.ChangeSet, error) {
+	data := make(map[string]interface{})
+
+	for _, kv := range v.kv {
+		d, err := v.client.readKV(kv.mount, kv.path)
+		if err == errNotFound {
+			continue
+		} else if err != nil {
+			return nil, fmt.Errorf("error reading %s/%s: %v", kv.mount, kv.path, err)
+		}
+		set(data, kv.path, d)
+	}
+
+	for _, path := range v.dynamic {
+		d, err := v.secret(path)
+		if err != nil {
+			return nil, fmt.Errorf("error reading %s: %v", path, err)
+		}
+		set(data, path, d)
+	}
+
+	b, err := v.opts.Encoder.Encode(data)
+	if err != nil {
+		return nil, fmt.Errorf("error reading source: %v", err)
+	}
+
+	cs := &source.ChangeSet{
+		Timestamp: time.Now(),
+		Source:    v.String(),
+		Data:      b,
+		Format:    v.opts.Encoder.String(),
+	}
+	cs.Checksum = cs.Sum()
+
+	return cs, nil
+}
+
+func (v *vault) Watch() (source.Watcher, error) {
+	cs, err := v.Read()
+	if err != nil {
+		return nil, err
+	}
+	return newWatcher(v, cs), nil
+}
+
+func (v *vault) Write(cs *source.ChangeSet) error {
+	return nil
+}
+
+func (v *vault) String() string {
+	return "vault"
+}
+
+// NewSource returns a vault config source
+func NewSource(opts ...source.Option) source.Source {
+	options := source.NewOptions(opts...)
+
+	c := &client{
+		address: os.Getenv("VAULT_ADDR"),
+		token:   os.Getenv("VAULT_TOKEN"),
+		http:    &http.Client{Timeout: 10 * time.Second},
+	}
+	if len(c.address) == 0 {
+		c.address = DefaultAddress
+	}
+	if a, ok := options.Context.Value(addressKey{}).(string); ok {
+		c.address = a
+	}
+	if t, ok := options.Context.Value(tokenKey{}).(string); ok {
+		c.token = t
+	}
+	if ns, ok := options.Context.Value(namespaceKey{}).(string); ok {
+		c.namespace = ns
+	}
+
+	v := &vault{
+		opts:     options,
+		client:   c,
+		interval: DefaultPollInterval,
+		leases:   make(map[string]*lease),
+	}
+	v.kv, _ = options.Context.Value(kvKey{}).([]kvPath)
+	v.dynamic, _ = options.Context.Value(dynamicKey{}).([]string)
+	if d, ok := options.Context.Value(intervalKey{}).(time.Duration); ok {
+		v.interval = d
+	}
+	v.onRotate, _ = options.Context.Value(rotateKey{}).(RotateFunc)
+
+	return v
+}
@@ -0,0 +1,129 @@
+package vault
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+type testVault struct {
+	sync.Mutex
+	creds  int
+	renews int
+	kv     map[string]interface{}
+}
+
+func (v *testVault) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	v.Lock()
+	defer v.Unlock()
+
+	if r.Header.Get("X-Vault-Token") != "token" {
+		w.WriteHeader(http.StatusForbidden)
+		json.NewEncoder(w).Encode(map[string][]string{"errors": {"permission denied"}})
+		return
+	}
+
+	switch r.URL.Path {
+	case "/v1/secret/data/myapp/api":
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"data": map[string]interface{}{"data": v.kv},
+		})
+	case "/v1/database/creds/readonly":
+		v.creds++
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"lease_id":       fmt.Sprintf("database/creds/readonly/%d", v.creds),
+			"lease_duration": 1,
+			"renewable":      true,
+			"data":           map[string]interface{}{"username": fmt.Sprintf("user%d", v.creds)},
+		})
+	case "/v1/sys/leases/renew":
+		// the max TTL is reached on the second renewal
+		v.renews++
+		d := 1
+		if v.renews > 1 {
+			d = 0
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"lease_duration": d})
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func get(b []byte, path ...string) interface{} {
+	var v interface{}
+	json.Unmarshal(b, &v)
+	for _, p := range path {
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		v = m[p]
+	}
+	return v
+}
+
+func TestVault(t *testing.T) {
+	tv := &testVault{kv: map[string]interface{}{"key": "a"}}
+	srv := httptest.NewServer(tv)
+	defer srv.Close()
+
+	rotated := make(chan string, 1)
+
+	s := NewSource(
+		WithAddress(srv.URL),
+		WithToken("token"),
+		WithKV("secret", "myapp/api"),
+		WithKV("secret", "missing"),
+		WithDynamic("database/creds/readonly"),
+		WithPollInterval(time.Hour),
+		OnRotate(func(path string, data map[string]interface{}) {
+			rotated <- data["username"].(string)
+		}),
+	)
+
+	cs, err := s.Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if get(cs.Data, "myapp", "api", "key") != "a" || get(cs.Data, "database", "creds", "readonly", "username") != "user1" {
+		t.Fatalf("Unexpected data %s", cs.Data)
+	}
+
+	w, err := s.Watch()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Stop()
+
+	// the lease is renewed once then the secret is rotated
+	select {
+	case u := <-rotated:
+		if u != "user2" {
+			t.Fatalf("Expected user2 got %s", u)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected the secret to be rotated")
+	}
+
+	cs, err = w.Next()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if get(cs.Data, "database", "creds", "readonly", "username") != "user2" {
+		t.Fatalf("Expected the rotated credentials got %s", cs.Data)
+	}
+
+	tv.Lock()
+	if tv.renews != 2 || tv.creds != 2 {
+		t.Fatalf("Expected 2 renewals and 2 credentials got %d %d", tv.renews, tv.creds)
+	}
+	tv.Unlock()
+
+	if _, err := NewSource(WithAddress(srv.URL), WithKV("secret", "myapp/api")).Read(); err == nil {
+		t.Fatal("Expected a permission denied error")
+	}
+}
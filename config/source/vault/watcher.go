@@ -0,0 +1,78 @@
+package vault
+
+import (
+	"time"
+
+	"github.com/micro/go-micro/v2/config/source"
+)
+
+type watcher struct {
+	vault *vault
+	cs    *source.ChangeSet
+
+	ch   chan *source.ChangeSet
+	exit chan bool
+}
+
+func newWatcher(v *vault, cs *source.ChangeSet) *watcher {
+	w := &watcher{
+		vault: v,
+		cs:    cs,
+		ch:    make(chan *source.ChangeSet),
+		exit:  make(chan bool),
+	}
+
+	go w.run()
+
+	return w
+}
+
+func (w *watcher) run() {
+	for {
+		// wake for the next poll or lease renewal
+		wait := w.vault.interval
+		if next := w.vault.next(); !next.IsZero() && time.Until(next) < wait {
+			wait = time.Until(next)
+		}
+
+		t := time.NewTimer(wait)
+		select {
+		case <-t.C:
+		case <-w.exit:
+			t.Stop()
+			return
+		}
+
+		w.vault.renew()
+
+		cs, err := w.vault.Read()
+		if err != nil || cs.Checksum == w.cs.Checksum {
+			continue
+		}
+		w.cs = cs
+
+		select {
+		case w.ch <- cs:
+		case <-w.exit:
+			return
+		}
+	}
+}
+
+func (w *watcher) Next() (*source.ChangeSet, error) {
+	select {
+	case cs := <-w.ch:
+		return cs, nil
+	case <-w.exit:
+		return nil, source.ErrWatcherStopped
+	}
+}
+
+func (w *watcher) Stop() error {
+	select {
+	case <-w.exit:
+	default:
+		close(w.exit)
+	}
+	return nil
+}
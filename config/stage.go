@@ -0,0 +1,119 @@
+package config
+
+import (
+	"github.com/micro/go-micro/v2/config/loader"
+	"github.com/micro/go-micro/v2/config/reader"
+	"github.com/micro/go-micro/v2/config/source"
+	"github.com/micro/go-micro/v2/logger"
+)
+
+// Validator checks proposed config values before they're applied.
+// Returning an error rejects the change.
+type Validator func(reader.Values) error
+
+// Subscriber applies config values to a component e.g routing rules or limits.
+// Returning an error rejects the change, in which case the subscribers which
+// already accepted it are called again with the previous values.
+type Subscriber func(reader.Values) error
+
+// Stage is a set of proposed changes which are validated and applied to the
+// config and its subscribers atomically
+type Stage interface {
+	// Set a value
+	Set(val interface{}, path ...string) Stage
+	// Delete a value
+	Del(path ...string) Stage
+	// Apply the changes. Nothing is applied if a validator or subscriber rejects them.
+	Apply() error
+}
+
+type stage struct {
+	c   *config
+	ops []func(reader.Values)
+}
+
+func (s *stage) Set(val interface{}, path ...string) Stage {
+	s.ops = append(s.ops, func(v reader.Values) {
+		v.Set(val, path...)
+	})
+	return s
+}
+
+func (s *stage) Del(path ...string) Stage {
+	s.ops = append(s.ops, func(v reader.Values) {
+		v.Del(path...)
+	})
+	return s
+}
+
+func (s *stage) Apply() error {
+	c := s.c
+
+	c.alock.Lock()
+	defer c.alock.Unlock()
+
+	// copy the current values
+	c.RLock()
+	ch := &source.ChangeSet{Format: "json"}
+	if c.snap != nil && c.snap.ChangeSet != nil {
+		ch.Format = c.snap.ChangeSet.Format
+	}
+	if c.vals != nil {
+		ch.Data = c.vals.Bytes()
+	}
+	c.RUnlock()
+
+	vals, err := c.opts.Reader.Values(ch)
+	if err != nil {
+		return err
+	}
+
+	for _, op := range s.ops {
+		op(vals)
+	}
+
+	return c.commit(nil, vals)
+}
+
+// commit validates the values and applies them to the subscribers before
+// swapping them in and notifying the watchers. The caller must hold the
+// apply lock.
+func (c *config) commit(snap *loader.Snapshot, vals reader.Values) error {
+	for _, v := range c.opts.Validators {
+		if err := v(vals); err != nil {
+			return err
+		}
+	}
+
+	c.RLock()
+	prev := c.vals
+	subs := make([]Subscriber, len(c.subs))
+	copy(subs, c.subs)
+	c.RUnlock()
+
+	for i, sub := range subs {
+		err := sub(vals)
+		if err == nil {
+			continue
+		}
+		// roll back the subscribers which accepted the change
+		for j := i - 1; j >= 0; j-- {
+			if rerr := subs[j](prev); rerr != nil {
+				logger.Errorf("Error rolling back config subscriber: %v", rerr)
+			}
+		}
+		return err
+	}
+
+	c.Lock()
+	if snap != nil {
+		c.snap = snap
+	}
+	c.vals = vals
+	for w := range c.watchers {
+		w.notify(vals)
+	}
+	c.Unlock()
+
+	return nil
+}
@@ -0,0 +1,163 @@
+// Package cost provides per request cost accounting
+package cost
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// DurationHeader is the time spent in the handler in nanoseconds
+	DurationHeader = "Micro-Cost-Duration"
+	// RequestBytesHeader is the size of the request body
+	RequestBytesHeader = "Micro-Cost-Request-Bytes"
+	// ResponseBytesHeader is the size of the response body
+	ResponseBytesHeader = "Micro-Cost-Response-Bytes"
+	// CallsHeader is the number of downstream calls made to serve the request
+	CallsHeader = "Micro-Cost-Calls"
+)
+
+// Cost is the resource usage of a single request. Go does not expose the cpu
+// time of a goroutine so the time spent in the handler is used instead.
+type Cost struct {
+	// Time spent in the handler
+	Duration time.Duration
+	// Size of the request body in bytes
+	RequestBytes int64
+	// Size of the response body in bytes
+	ResponseBytes int64
+	// Number of downstream calls
+	Calls int64
+}
+
+// Header encodes the cost as response headers
+func (c *Cost) Header() map[string]string {
+	return map[string]string{
+		DurationHeader:      strconv.FormatInt(int64(c.Duration), 10),
+		RequestBytesHeader:  strconv.FormatInt(c.RequestBytes, 10),
+		ResponseBytesHeader: strconv.FormatInt(c.ResponseBytes, 10),
+		CallsHeader:         strconv.FormatInt(atomic.LoadInt64(&c.Calls), 10),
+	}
+}
+
+// FromHeader decodes a cost from response headers. It returns
+// false if the server did not report a cost.
+func FromHeader(hdr map[string]string) (*Cost, bool) {
+	// headers may have been lower cased by the transport
+	md := make(map[string]string, len(hdr))
+	for k, v := range hdr {
+		md[strings.ToLower(k)] = v
+	}
+
+	d, ok := md[strings.ToLower(DurationHeader)]
+	if !ok {
+		return nil, false
+	}
+
+	parse := func(k string) int64 {
+		v, _ := strconv.ParseInt(md[strings.ToLower(k)], 10, 64)
+		return v
+	}
+
+	dur, _ := strconv.ParseInt(d, 10, 64)
+
+	return &Cost{
+		Duration:      time.Duration(dur),
+		RequestBytes:  parse(RequestBytesHeader),
+		ResponseBytes: parse(ResponseBytesHeader),
+		Calls:         parse(CallsHeader),
+	}, true
+}
+
+// Add adds the values of another cost
+func (c *Cost) Add(o *Cost) {
+	c.Duration += o.Duration
+	c.RequestBytes += o.RequestBytes
+	c.ResponseBytes += o.ResponseBytes
+	c.Calls += o.Calls
+}
+
+type costKey struct{}
+
+// NewContext returns a context which tracks the cost of a request
+func NewContext(ctx context.Context, c *Cost) context.Context {
+	return context.WithValue(ctx, costKey{}, c)
+}
+
+// FromContext returns the cost of the request being served
+func FromContext(ctx context.Context) (*Cost, bool) {
+	c, ok := ctx.Value(costKey{}).(*Cost)
+	return c, ok
+}
+
+// AddCall increments the downstream calls of the request being served
+func AddCall(ctx context.Context) {
+	if c, ok := FromContext(ctx); ok {
+		atomic.AddInt64(&c.Calls, 1)
+	}
+}
+
+// Usage is the aggregated cost of requests to an endpoint
+type Usage struct {
+	// Service called
+	Service string
+	// Endpoint called
+	Endpoint string
+	// Number of requests which reported a cost
+	Requests int64
+	// Total cost of the requests
+	Total Cost
+}
+
+// Ledger aggregates the costs reported by servers per service endpoint
+type Ledger struct {
+	sync.RWMutex
+	usage map[string]*Usage
+}
+
+// NewLedger returns an empty ledger
+func NewLedger() *Ledger {
+	return &Ledger{
+		usage: make(map[string]*Usage),
+	}
+}
+
+// Record adds the cost of a request to a service endpoint
+func (l *Ledger) Record(service, endpoint string, c *Cost) {
+	key := service + "." + endpoint
+
+	l.Lock()
+	defer l.Unlock()
+
+	u, ok := l.usage[key]
+	if !ok {
+		u = &Usage{Service: service, Endpoint: endpoint}
+		l.usage[key] = u
+	}
+	u.Requests++
+	u.Total.Add(c)
+}
+
+// Read returns a copy of the usage recorded so far
+func (l *Ledger) Read() []*Usage {
+	l.RLock()
+	defer l.RUnlock()
+
+	usage := make([]*Usage, 0, len(l.usage))
+	for _, u := range l.usage {
+		cp := *u
+		usage = append(usage, &cp)
+	}
+	return usage
+}
+
+// Reset clears the recorded usage
+func (l *Ledger) Reset() {
+	l.Lock()
+	l.usage = make(map[string]*Usage)
+	l.Unlock()
+}
@@ -0,0 +1,364 @@
+// Package dashboard serves a web dashboard of a service for local development
+package dashboard
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/micro/go-micro/v2/auth"
+	"github.com/micro/go-micro/v2/debug/size"
+	"github.com/micro/go-micro/v2/debug/stats"
+	"github.com/micro/go-micro/v2/debug/trace"
+	"github.com/micro/go-micro/v2/logger"
+	"github.com/micro/go-micro/v2/registry"
+	"github.com/micro/go-micro/v2/server"
+)
+
+var (
+	// DefaultAddress of the dashboard. Only local connections are accepted.
+	DefaultAddress = "127.0.0.1:8082"
+
+	// DefaultRequests is the number of recent requests shown
+	DefaultRequests = 50
+
+	// tokenCookie stores the token of the browser
+	tokenCookie = "micro-dashboard-token"
+
+	// ErrNoopAuth is returned by Start when the auth accepts any token
+	ErrNoopAuth = errors.New("dashboard: the noop auth accepts any token, use AllowNoopAuth to start anyway")
+)
+
+// Dashboard serves the services in the registry, the recent requests, broker
+// subscriptions, payload sizes, config values and health of the server. Every request must
+// have a token accepted by auth as a bearer token, token query param or cookie.
+// It isn't served with the noop auth unless it's explicitly allowed.
+type Dashboard struct {
+	opts Options
+	mux  *http.ServeMux
+
+	sync.Mutex
+	srv *http.Server
+}
+
+type service struct {
+	Name     string            `json:"name"`
+	Version  string            `json:"version"`
+	Nodes    []*registry.Node  `json:"nodes"`
+	Metadata map[string]string `json:"metadata,omitempty"`
+}
+
+type request struct {
+	Trace    string            `json:"trace"`
+	Name     string            `json:"name"`
+	Started  time.Time         `json:"started"`
+	Duration string            `json:"duration"`
+	Metadata map[string]string `json:"metadata,omitempty"`
+}
+
+type subscription struct {
+	Service string `json:"service"`
+	Topic   string `json:"topic"`
+	Handler string `json:"handler"`
+}
+
+type health struct {
+	Service    string      `json:"service"`
+	Id         string      `json:"id"`
+	Registered bool        `json:"registered"`
+	Stats      *stats.Stat `json:"stats,omitempty"`
+}
+
+func (d *Dashboard) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	token := r.URL.Query().Get("token")
+	if len(token) == 0 {
+		token = strings.TrimPrefix(r.Header.Get("Authorization"), auth.BearerScheme)
+	}
+	if len(token) == 0 {
+		if c, err := r.Cookie(tokenCookie); err == nil {
+			token = c.Value
+		}
+	}
+
+	if d.noopAuth() {
+		http.Error(w, ErrNoopAuth.Error(), http.StatusForbidden)
+		return
+	}
+	if len(token) == 0 {
+		http.Error(w, "token required", http.StatusUnauthorized)
+		return
+	}
+	if _, err := d.opts.Auth.Inspect(token); err != nil {
+		http.Error(w, "invalid token", http.StatusUnauthorized)
+		return
+	}
+
+	// the page fetches the api with the cookie
+	if r.URL.Query().Get("token") != "" {
+		http.SetCookie(w, &http.Cookie{
+			Name:     tokenCookie,
+			Value:    token,
+			Path:     "/",
+			HttpOnly: true,
+			SameSite: http.SameSiteStrictMode,
+		})
+	}
+
+	d.mux.ServeHTTP(w, r)
+}
+
+// noopAuth returns true if the auth accepts any token and isn't allowed
+func (d *Dashboard) noopAuth() bool {
+	return d.opts.Auth.String() == "noop" && !d.opts.AllowNoopAuth
+}
+
+// Start listening on the address
+func (d *Dashboard) Start() error {
+	d.Lock()
+	defer d.Unlock()
+
+	if d.srv != nil {
+		return nil
+	}
+	if d.noopAuth() {
+		return ErrNoopAuth
+	}
+
+	l, err := net.Listen("tcp", d.opts.Address)
+	if err != nil {
+		return err
+	}
+
+	d.srv = &http.Server{Handler: d}
+
+	if logger.V(logger.InfoLevel, logger.DefaultLogger) {
+		logger.Infof("Dashboard listening on %s", l.Addr().String())
+	}
+
+	go d.srv.Serve(l)
+
+	return nil
+}
+
+// Stop the server
+func (d *Dashboard) Stop() error {
+	d.Lock()
+	defer d.Unlock()
+
+	if d.srv == nil {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	err := d.srv.Shutdown(ctx)
+	d.srv = nil
+	return err
+}
+
+func (d *Dashboard) index(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/" {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write([]byte(page))
+}
+
+func (d *Dashboard) services(w http.ResponseWriter, r *http.Request) {
+	list, err := d.opts.Registry.ListServices()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	var services []*service
+	for _, s := range list {
+		// the list may not include the nodes
+		versions, err := d.opts.Registry.GetService(s.Name)
+		if err != nil {
+			versions = []*registry.Service{s}
+		}
+		for _, v := range versions {
+			services = append(services, &service{
+				Name:     v.Name,
+				Version:  v.Version,
+				Nodes:    v.Nodes,
+				Metadata: v.Metadata,
+			})
+		}
+	}
+
+	sort.Slice(services, func(i, j int) bool {
+		if services[i].Name == services[j].Name {
+			return services[i].Version < services[j].Version
+		}
+		return services[i].Name < services[j].Name
+	})
+
+	writeJSON(w, services)
+}
+
+func (d *Dashboard) requests(w http.ResponseWriter, r *http.Request) {
+	spans, err := d.opts.Tracer.Read()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	requests := make([]*request, 0, len(spans))
+	for _, s := range spans {
+		if s.Type != trace.SpanTypeRequestInbound && s.Type != trace.SpanTypeMessageInbound {
+			continue
+		}
+		requests = append(requests, &request{
+			Trace:    s.Trace,
+			Name:     s.Name,
+			Started:  s.Started,
+			Duration: s.Duration.String(),
+			Metadata: s.Metadata,
+		})
+	}
+
+	// most recent first
+	sort.Slice(requests, func(i, j int) bool {
+		return requests[i].Started.After(requests[j].Started)
+	})
+
+	if len(requests) > d.opts.Requests {
+		requests = requests[:d.opts.Requests]
+	}
+
+	writeJSON(w, requests)
+}
+
+func (d *Dashboard) subscriptions(w http.ResponseWriter, r *http.Request) {
+	name := d.opts.Server.Options().Name
+
+	services, err := d.opts.Registry.GetService(name)
+	if err != nil && err != registry.ErrNotFound {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	subs := []*subscription{}
+	seen := make(map[string]bool)
+
+	for _, s := range services {
+		for _, ep := range s.Endpoints {
+			if ep.Metadata["subscriber"] != "true" {
+				continue
+			}
+			key := ep.Metadata["topic"] + "/" + ep.Name
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			subs = append(subs, &subscription{
+				Service: s.Name,
+				Topic:   ep.Metadata["topic"],
+				Handler: ep.Name,
+			})
+		}
+	}
+
+	writeJSON(w, subs)
+}
+
+// sizes returns the payload sizes per endpoint and the largest recent
+// payloads. The number of payloads is set by the n query param.
+func (d *Dashboard) sizes(w http.ResponseWriter, r *http.Request) {
+	n, err := strconv.Atoi(r.URL.Query().Get("n"))
+	if err != nil || n <= 0 {
+		n = 10
+	}
+
+	writeJSON(w, map[string]interface{}{
+		"endpoints": d.opts.Sizes.Read(),
+		"top":       d.opts.Sizes.Top(n),
+	})
+}
+
+func (d *Dashboard) config(w http.ResponseWriter, r *http.Request) {
+	if d.opts.Config == nil {
+		writeJSON(w, map[string]interface{}{})
+		return
+	}
+	writeJSON(w, d.opts.Config.Map())
+}
+
+func (d *Dashboard) health(w http.ResponseWriter, r *http.Request) {
+	opts := d.opts.Server.Options()
+
+	h := &health{
+		Service: opts.Name,
+		Id:      opts.Id,
+	}
+
+	// the server is registered if its node is in the registry
+	services, _ := d.opts.Registry.GetService(opts.Name)
+	for _, s := range services {
+		for _, n := range s.Nodes {
+			if n.Id == opts.Name+"-"+opts.Id {
+				h.Registered = true
+			}
+		}
+	}
+
+	if st, err := d.opts.Stats.Read(); err == nil && len(st) > 0 {
+		h.Stats = st[len(st)-1]
+	}
+
+	writeJSON(w, h)
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(b)
+}
+
+// NewDashboard returns a dashboard. Unset options use the defaults.
+func NewDashboard(opts ...Option) *Dashboard {
+	options := Options{
+		Address:  DefaultAddress,
+		Auth:     auth.DefaultAuth,
+		Registry: registry.DefaultRegistry,
+		Server:   server.DefaultServer,
+		Tracer:   trace.DefaultTracer,
+		Stats:    stats.DefaultStats,
+		Sizes:    size.DefaultRecorder,
+		Requests: DefaultRequests,
+	}
+
+	for _, o := range opts {
+		o(&options)
+	}
+
+	d := &Dashboard{
+		opts: options,
+		mux:  http.NewServeMux(),
+	}
+
+	d.mux.HandleFunc("/", d.index)
+	d.mux.HandleFunc("/api/services", d.services)
+	d.mux.HandleFunc("/api/requests", d.requests)
+	d.mux.HandleFunc("/api/subscriptions", d.subscriptions)
+	d.mux.HandleFunc("/api/sizes", d.sizes)
+	d.mux.HandleFunc("/api/config", d.config)
+	d.mux.HandleFunc("/api/health", d.health)
+
+	return d
+}
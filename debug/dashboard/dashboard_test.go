@@ -0,0 +1,99 @@
+package dashboard
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/micro/go-micro/v2/auth"
+	"github.com/micro/go-micro/v2/debug/size"
+	"github.com/micro/go-micro/v2/registry"
+	"github.com/micro/go-micro/v2/registry/memory"
+	"github.com/micro/go-micro/v2/server"
+)
+
+func TestDashboard(t *testing.T) {
+	r := memory.NewRegistry()
+	r.Register(&registry.Service{
+		Name:    "foo",
+		Version: "1.0.0",
+		Nodes: []*registry.Node{
+			{Id: "foo-1", Address: "127.0.0.1:9000", Metadata: map[string]string{}},
+		},
+		Endpoints: []*registry.Endpoint{
+			{Name: "Foo.Handle", Metadata: map[string]string{"subscriber": "true", "topic": "events"}},
+		},
+	})
+
+	sizes := size.NewRecorder(10)
+	sizes.Record(size.Sample{Endpoint: "Foo.Bar", Request: 100, Response: 10})
+
+	// the noop auth accepts any token
+	d := NewDashboard(Auth(auth.NewAuth()), Address("127.0.0.1:0"))
+	if err := d.Start(); err != ErrNoopAuth {
+		t.Fatalf("Expected %v got %v", ErrNoopAuth, err)
+	}
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/api/services", nil)
+	req.Header.Set("Authorization", auth.BearerScheme+"token")
+	d.ServeHTTP(w, req)
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("Expected status 403 got %d", w.Code)
+	}
+
+	d = NewDashboard(
+		Auth(auth.NewAuth()),
+		AllowNoopAuth(),
+		Sizes(sizes),
+		Registry(r),
+		Server(server.NewServer(server.Name("foo"), server.Id("1"))),
+	)
+
+	// requests without a token are rejected
+	w = httptest.NewRecorder()
+	d.ServeHTTP(w, httptest.NewRequest("GET", "/api/services", nil))
+	if w.Code != http.StatusUnauthorized {
+		t.Fatalf("Expected status 401 got %d", w.Code)
+	}
+
+	get := func(path string, v interface{}) {
+		req := httptest.NewRequest("GET", path, nil)
+		req.Header.Set("Authorization", auth.BearerScheme+"token")
+		w := httptest.NewRecorder()
+		d.ServeHTTP(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status 200 for %s got %d", path, w.Code)
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), v); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var services []*service
+	get("/api/services", &services)
+	if len(services) != 1 || services[0].Name != "foo" || len(services[0].Nodes) != 1 {
+		t.Fatalf("Unexpected services %+v", services)
+	}
+
+	var subs []*subscription
+	get("/api/subscriptions", &subs)
+	if len(subs) != 1 || subs[0].Topic != "events" || subs[0].Handler != "Foo.Handle" {
+		t.Fatalf("Unexpected subscriptions %+v", subs)
+	}
+
+	var payloads struct {
+		Endpoints []*size.Endpoint
+		Top       []size.Sample
+	}
+	get("/api/sizes?n=5", &payloads)
+	if len(payloads.Endpoints) != 1 || len(payloads.Top) != 1 || payloads.Top[0].Request != 100 {
+		t.Fatalf("Unexpected sizes %+v", payloads)
+	}
+
+	var h health
+	get("/api/health", &h)
+	if !h.Registered {
+		t.Fatal("Expected the server to be registered")
+	}
+}
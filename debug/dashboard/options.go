@@ -0,0 +1,108 @@
+package dashboard
+
+import (
+	"github.com/micro/go-micro/v2/auth"
+	"github.com/micro/go-micro/v2/config"
+	"github.com/micro/go-micro/v2/debug/size"
+	"github.com/micro/go-micro/v2/debug/stats"
+	"github.com/micro/go-micro/v2/debug/trace"
+	"github.com/micro/go-micro/v2/registry"
+	"github.com/micro/go-micro/v2/server"
+)
+
+type Options struct {
+	// Address to listen on
+	Address string
+	// Auth used to inspect the token of requests
+	Auth auth.Auth
+	// AllowNoopAuth serves the dashboard with the noop auth, which accepts
+	// any token
+	AllowNoopAuth bool
+	// Registry listing the services
+	Registry registry.Registry
+	// Server the dashboard reports on
+	Server server.Server
+	// Tracer with the recent requests
+	Tracer trace.Tracer
+	// Stats of the process
+	Stats stats.Stats
+	// Config values
+	Config config.Config
+	// Requests is the number of recent requests shown
+	Requests int
+	// Sizes of the request and response payloads
+	Sizes *size.Recorder
+}
+
+type Option func(o *Options)
+
+// Address to listen on
+func Address(a string) Option {
+	return func(o *Options) {
+		o.Address = a
+	}
+}
+
+// Auth used to inspect the token of requests
+func Auth(a auth.Auth) Option {
+	return func(o *Options) {
+		o.Auth = a
+	}
+}
+
+// AllowNoopAuth serves the dashboard with the noop auth e.g for local
+// development. Any token is accepted.
+func AllowNoopAuth() Option {
+	return func(o *Options) {
+		o.AllowNoopAuth = true
+	}
+}
+
+// Registry listing the services
+func Registry(r registry.Registry) Option {
+	return func(o *Options) {
+		o.Registry = r
+	}
+}
+
+// Server the dashboard reports on
+func Server(s server.Server) Option {
+	return func(o *Options) {
+		o.Server = s
+	}
+}
+
+// Tracer with the recent requests
+func Tracer(t trace.Tracer) Option {
+	return func(o *Options) {
+		o.Tracer = t
+	}
+}
+
+// Stats of the process
+func Stats(s stats.Stats) Option {
+	return func(o *Options) {
+		o.Stats = s
+	}
+}
+
+// Config values shown
+func Config(c config.Config) Option {
+	return func(o *Options) {
+		o.Config = c
+	}
+}
+
+// Sizes sets the recorder of payload sizes
+func Sizes(r *size.Recorder) Option {
+	return func(o *Options) {
+		o.Sizes = r
+	}
+}
+
+// Requests sets the number of recent requests shown
+func Requests(n int) Option {
+	return func(o *Options) {
+		o.Requests = n
+	}
+}
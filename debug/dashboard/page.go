@@ -0,0 +1,92 @@
+package dashboard
+
+// page renders the api responses. Values are set as text to escape them.
+var page = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Micro Dashboard</title>
+<style>
+body { font-family: sans-serif; margin: 2em; color: #333; }
+h2 { border-bottom: 1px solid #ddd; padding-bottom: 0.25em; }
+table { border-collapse: collapse; width: 100%; margin-bottom: 1em; }
+th, td { text-align: left; padding: 0.3em 0.6em; border-bottom: 1px solid #eee; vertical-align: top; }
+pre { background: #f6f6f6; padding: 1em; overflow: auto; }
+</style>
+</head>
+<body>
+<h1>Micro Dashboard</h1>
+<h2>Health</h2><pre id="health"></pre>
+<h2>Services</h2><table id="services"></table>
+<h2>Recent Requests</h2><table id="requests"></table>
+<h2>Payload Sizes</h2><table id="sizes"></table>
+<h2>Largest Payloads</h2><table id="top"></table>
+<h2>Subscriptions</h2><table id="subscriptions"></table>
+<h2>Config</h2><pre id="config"></pre>
+<script>
+function table(id, cols, rows) {
+  var t = document.getElementById(id);
+  t.innerHTML = "";
+  var tr = t.insertRow();
+  cols.forEach(function(c) {
+    var th = document.createElement("th");
+    th.textContent = c[0];
+    tr.appendChild(th);
+  });
+  (rows || []).forEach(function(r) {
+    var tr = t.insertRow();
+    cols.forEach(function(c) { tr.insertCell().textContent = c[1](r); });
+  });
+}
+
+function get(path, fn) {
+  fetch(path, {credentials: "same-origin"})
+    .then(function(rsp) { return rsp.json(); })
+    .then(fn);
+}
+
+function nodes(s) {
+  return (s.nodes || []).map(function(n) { return n.id + " " + n.address; }).join("\n");
+}
+
+function refresh() {
+  get("/api/health", function(h) {
+    document.getElementById("health").textContent = JSON.stringify(h, null, 2);
+  });
+  get("/api/services", function(s) {
+    table("services", [["Name", function(r) { return r.name; }],
+      ["Version", function(r) { return r.version; }],
+      ["Nodes", nodes]], s);
+  });
+  get("/api/requests", function(s) {
+    table("requests", [["Started", function(r) { return r.started; }],
+      ["Name", function(r) { return r.name; }],
+      ["Duration", function(r) { return r.duration; }],
+      ["Trace", function(r) { return r.trace; }]], s);
+  });
+  get("/api/sizes", function(s) {
+    table("sizes", [["Endpoint", function(r) { return r.Name; }],
+      ["Requests", function(r) { return r.Requests; }],
+      ["Request p50/p90/p99/max", function(r) { return [r.Request.P50, r.Request.P90, r.Request.P99, r.Request.Max].join(" / "); }],
+      ["Response p50/p90/p99/max", function(r) { return [r.Response.P50, r.Response.P90, r.Response.P99, r.Response.Max].join(" / "); }]], s.endpoints);
+    table("top", [["Time", function(r) { return r.Time; }],
+      ["Endpoint", function(r) { return r.Endpoint; }],
+      ["Caller", function(r) { return r.Caller; }],
+      ["Request", function(r) { return r.Request; }],
+      ["Response", function(r) { return r.Response; }]], s.top);
+  });
+  get("/api/subscriptions", function(s) {
+    table("subscriptions", [["Topic", function(r) { return r.topic; }],
+      ["Handler", function(r) { return r.handler; }]], s);
+  });
+  get("/api/config", function(c) {
+    document.getElementById("config").textContent = JSON.stringify(c, null, 2);
+  });
+}
+
+refresh();
+setInterval(refresh, 5000);
+</script>
+</body>
+</html>
+`
@@ -0,0 +1,188 @@
+// Package size tracks the size of request and response payloads per endpoint
+package size
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+var (
+	// DefaultWindow is the number of recent samples kept per endpoint
+	DefaultWindow = 1000
+
+	// DefaultRecorder used by services
+	DefaultRecorder = NewRecorder(DefaultWindow)
+)
+
+// Sample is the size of a single request and its response
+type Sample struct {
+	// Endpoint called e.g Foo.Bar
+	Endpoint string
+	// Caller is the service which made the request if known
+	Caller string
+	// Size of the request in bytes
+	Request int64
+	// Size of the response in bytes
+	Response int64
+	// Time of the request
+	Time time.Time
+}
+
+// Percentiles of payload sizes in bytes
+type Percentiles struct {
+	P50 int64
+	P90 int64
+	P99 int64
+	Max int64
+}
+
+// Endpoint is the distribution of payload sizes of an endpoint
+type Endpoint struct {
+	Name string
+	// Total requests recorded
+	Requests int64
+	// Sizes of the recent requests
+	Request Percentiles
+	// Sizes of the recent responses
+	Response Percentiles
+}
+
+type window struct {
+	total   int64
+	next    int
+	samples []Sample
+}
+
+// Recorder keeps a window of recent samples per endpoint
+type Recorder struct {
+	size int
+
+	sync.RWMutex
+	endpoints map[string]*window
+}
+
+// Record a sample
+func (r *Recorder) Record(s Sample) {
+	if s.Time.IsZero() {
+		s.Time = time.Now()
+	}
+
+	r.Lock()
+	defer r.Unlock()
+
+	w, ok := r.endpoints[s.Endpoint]
+	if !ok {
+		w = &window{samples: make([]Sample, 0, r.size)}
+		r.endpoints[s.Endpoint] = w
+	}
+
+	w.total++
+	if len(w.samples) < r.size {
+		w.samples = append(w.samples, s)
+		return
+	}
+	w.samples[w.next] = s
+	w.next = (w.next + 1) % r.size
+}
+
+// Read returns the distribution of payload sizes per endpoint
+func (r *Recorder) Read() []*Endpoint {
+	r.RLock()
+	defer r.RUnlock()
+
+	endpoints := make([]*Endpoint, 0, len(r.endpoints))
+
+	for name, w := range r.endpoints {
+		reqs := make([]int64, len(w.samples))
+		rsps := make([]int64, len(w.samples))
+		for i, s := range w.samples {
+			reqs[i] = s.Request
+			rsps[i] = s.Response
+		}
+
+		endpoints = append(endpoints, &Endpoint{
+			Name:     name,
+			Requests: w.total,
+			Request:  percentiles(reqs),
+			Response: percentiles(rsps),
+		})
+	}
+
+	sort.Slice(endpoints, func(i, j int) bool {
+		return endpoints[i].Name < endpoints[j].Name
+	})
+
+	return endpoints
+}
+
+// Top returns the n largest recent samples by the larger
+// of the request and response size
+func (r *Recorder) Top(n int) []Sample {
+	r.RLock()
+	var samples []Sample
+	for _, w := range r.endpoints {
+		samples = append(samples, w.samples...)
+	}
+	r.RUnlock()
+
+	largest := func(s Sample) int64 {
+		if s.Request > s.Response {
+			return s.Request
+		}
+		return s.Response
+	}
+
+	sort.Slice(samples, func(i, j int) bool {
+		return largest(samples[i]) > largest(samples[j])
+	})
+
+	if len(samples) > n {
+		samples = samples[:n]
+	}
+
+	return samples
+}
+
+// Reset clears the recorded samples
+func (r *Recorder) Reset() {
+	r.Lock()
+	r.endpoints = make(map[string]*window)
+	r.Unlock()
+}
+
+// percentiles sorts the sizes and returns their percentiles
+func percentiles(sizes []int64) Percentiles {
+	if len(sizes) == 0 {
+		return Percentiles{}
+	}
+
+	sort.Slice(sizes, func(i, j int) bool {
+		return sizes[i] < sizes[j]
+	})
+
+	at := func(p float64) int64 {
+		i := int(p * float64(len(sizes)-1))
+		return sizes[i]
+	}
+
+	return Percentiles{
+		P50: at(0.50),
+		P90: at(0.90),
+		P99: at(0.99),
+		Max: sizes[len(sizes)-1],
+	}
+}
+
+// NewRecorder returns a recorder keeping the most
+// recent samples of each endpoint
+func NewRecorder(size int) *Recorder {
+	if size <= 0 {
+		size = DefaultWindow
+	}
+
+	return &Recorder{
+		size:      size,
+		endpoints: make(map[string]*window),
+	}
+}
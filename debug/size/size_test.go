@@ -0,0 +1,33 @@
+package size
+
+import (
+	"testing"
+)
+
+func TestRecorder(t *testing.T) {
+	r := NewRecorder(100)
+
+	for i := int64(1); i <= 200; i++ {
+		r.Record(Sample{Endpoint: "Foo.Bar", Caller: "client", Request: i, Response: i * 2})
+	}
+	r.Record(Sample{Endpoint: "Foo.Baz", Request: 10000, Response: 1})
+
+	endpoints := r.Read()
+	if len(endpoints) != 2 {
+		t.Fatalf("Expected 2 endpoints got %d", len(endpoints))
+	}
+
+	e := endpoints[0]
+	if e.Name != "Foo.Bar" || e.Requests != 200 {
+		t.Fatalf("Unexpected endpoint %+v", e)
+	}
+	// only the last 100 samples are kept
+	if e.Request.P50 != 150 || e.Request.Max != 200 || e.Response.Max != 400 {
+		t.Fatalf("Unexpected percentiles %+v %+v", e.Request, e.Response)
+	}
+
+	top := r.Top(2)
+	if len(top) != 2 || top[0].Endpoint != "Foo.Baz" || top[1].Response != 400 {
+		t.Fatalf("Unexpected top samples %+v", top)
+	}
+}
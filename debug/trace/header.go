@@ -0,0 +1,63 @@
+package trace
+
+import (
+	"context"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/micro/go-micro/v2/metadata"
+)
+
+const (
+	requestIDKey = "Micro-Request-Id"
+)
+
+// RequestID returns the request id from the context
+func RequestID(ctx context.Context) (string, bool) {
+	md, ok := metadata.FromContext(ctx)
+	if !ok {
+		return "", false
+	}
+	id, ok := md[requestIDKey]
+	return id, ok && len(id) > 0
+}
+
+// Inject copies the trace context and request id of ctx into the headers of
+// a message so the consumer of the message is part of the same trace. A
+// request id is generated if the context doesn't have one.
+func Inject(ctx context.Context, header map[string]string) {
+	md, _ := metadata.FromContext(ctx)
+
+	for _, k := range []string{traceIDKey, spanIDKey} {
+		if v, ok := md[k]; ok && len(v) > 0 {
+			header[k] = v
+		}
+	}
+
+	id, ok := md[requestIDKey]
+	if !ok || len(id) == 0 {
+		id = uuid.New().String()
+	}
+	header[requestIDKey] = id
+}
+
+// Extract returns a context with the trace context and request id in the
+// headers of a message. Header keys are matched case insensitively as some
+// transports lower case them.
+func Extract(ctx context.Context, header map[string]string) context.Context {
+	md := make(metadata.Metadata)
+
+	for k, v := range header {
+		for _, key := range []string{traceIDKey, spanIDKey, requestIDKey} {
+			if strings.EqualFold(k, key) && len(v) > 0 {
+				md[key] = v
+			}
+		}
+	}
+
+	if len(md) == 0 {
+		return ctx
+	}
+
+	return metadata.MergeContext(ctx, md, true)
+}
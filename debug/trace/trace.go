@@ -26,6 +26,10 @@ const (
 	SpanTypeRequestInbound SpanType = iota
 	// SpanTypeRequestOutbound is a span created when making a service call
 	SpanTypeRequestOutbound
+	// SpanTypeMessageInbound is a span created when processing a message
+	SpanTypeMessageInbound
+	// SpanTypeMessageOutbound is a span created when publishing a message
+	SpanTypeMessageOutbound
 )
 
 // Span is used to record an entry
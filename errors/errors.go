@@ -4,8 +4,11 @@ package errors
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
+	"reflect"
+	"time"
 )
 
 //go:generate protoc -I. --go_out=paths=source_relative:. errors.proto
@@ -15,6 +18,122 @@ func (e *Error) Error() string {
 	return string(b)
 }
 
+// Is reports whether the error has the code of the target, and its id if the
+// target has one, so the errors are matched with errors.Is e.g
+//
+//	errors.Is(err, &Error{Code: http.StatusNotFound})
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	if !ok || t == nil || e == nil {
+		return false
+	}
+	if len(t.Id) > 0 && t.Id != e.Id {
+		return false
+	}
+	return t.Code == e.Code
+}
+
+// AddDetail attaches the JSON encoded payload to the error
+func (e *Error) AddDetail(v interface{}) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	e.Details = append(e.Details, &Detail{
+		Type:  detailType(v),
+		Value: string(b),
+	})
+	return nil
+}
+
+// ScanDetail decodes the first payload of the type of v into v. It returns
+// false if the error has no payload of the type.
+func (e *Error) ScanDetail(v interface{}) bool {
+	typ := detailType(v)
+	for _, d := range e.Details {
+		if d.Type != typ {
+			continue
+		}
+		return json.Unmarshal([]byte(d.Value), v) == nil
+	}
+	return false
+}
+
+// detailType returns the name of the type of the payload
+func detailType(v interface{}) string {
+	t := reflect.TypeOf(v)
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == nil {
+		return ""
+	}
+	if len(t.PkgPath()) == 0 {
+		return t.String()
+	}
+	return t.PkgPath() + "." + t.Name()
+}
+
+// wrapError is an error with a code wrapping the error it's caused by
+type wrapError struct {
+	err   *Error
+	cause error
+}
+
+func (w *wrapError) Error() string {
+	return w.err.Error()
+}
+
+func (w *wrapError) Unwrap() error {
+	return w.cause
+}
+
+func (w *wrapError) Is(target error) bool {
+	return w.err.Is(target)
+}
+
+func (w *wrapError) As(target interface{}) bool {
+	if t, ok := target.(**Error); ok {
+		*t = w.err
+		return true
+	}
+	return false
+}
+
+// Wrap returns an error with the code whose detail is the message of err.
+// The error unwraps to err so errors.Is and errors.As match both of them.
+func Wrap(err error, id string, code int32) error {
+	if err == nil {
+		return nil
+	}
+	return &wrapError{
+		err: &Error{
+			Id:     id,
+			Code:   code,
+			Detail: err.Error(),
+			Status: http.StatusText(int(code)),
+		},
+		cause: err,
+	}
+}
+
+// As returns the *Error in the chain of err
+func As(err error) (*Error, bool) {
+	var verr *Error
+	if errors.As(err, &verr) && verr != nil {
+		return verr, true
+	}
+	return nil, false
+}
+
+// Code returns the code of the error, 0 if it has none
+func Code(err error) int32 {
+	if err == nil {
+		return 0
+	}
+	return FromError(err).Code
+}
+
 // New generates a custom error.
 func New(id, detail string, code int32) error {
 	return &Error{
@@ -106,6 +225,16 @@ func Conflict(id, format string, a ...interface{}) error {
 	}
 }
 
+// PreconditionFailed generates a 412 error.
+func PreconditionFailed(id, format string, a ...interface{}) error {
+	return &Error{
+		Id:     id,
+		Code:   412,
+		Detail: fmt.Sprintf(format, a...),
+		Status: http.StatusText(412),
+	}
+}
+
 // InternalServerError generates a 500 error.
 func InternalServerError(id, format string, a ...interface{}) error {
 	return &Error{
@@ -116,10 +245,73 @@ func InternalServerError(id, format string, a ...interface{}) error {
 	}
 }
 
+// NotImplemented generates a 501 error.
+func NotImplemented(id, format string, a ...interface{}) error {
+	return &Error{
+		Id:     id,
+		Code:   501,
+		Detail: fmt.Sprintf(format, a...),
+		Status: http.StatusText(501),
+	}
+}
+
+// Unavailable generates a 503 error.
+func Unavailable(id, format string, a ...interface{}) error {
+	return &Error{
+		Id:     id,
+		Code:   503,
+		Detail: fmt.Sprintf(format, a...),
+		Status: http.StatusText(503),
+	}
+}
+
+// DeadlineExceeded generates a 504 error.
+func DeadlineExceeded(id, format string, a ...interface{}) error {
+	return &Error{
+		Id:     id,
+		Code:   504,
+		Detail: fmt.Sprintf(format, a...),
+		Status: http.StatusText(504),
+	}
+}
+
+// TooManyRequests generates a 429 error telling the caller to retry after the duration.
+func TooManyRequests(id string, retryAfter time.Duration, format string, a ...interface{}) error {
+	return &Error{
+		Id:         id,
+		Code:       429,
+		Detail:     fmt.Sprintf(format, a...),
+		Status:     http.StatusText(429),
+		RetryAfter: retryAfter.Milliseconds(),
+	}
+}
+
+// Overloaded generates a 503 error for requests rejected to shed load. The load of
+// the server from 0 to 1 is returned along with the duration to retry after.
+func Overloaded(id string, retryAfter time.Duration, load float32, format string, a ...interface{}) error {
+	return &Error{
+		Id:         id,
+		Code:       503,
+		Detail:     fmt.Sprintf(format, a...),
+		Status:     http.StatusText(503),
+		RetryAfter: retryAfter.Milliseconds(),
+		Load:       load,
+	}
+}
+
+// RetryAfter returns the duration the server asked the caller to wait
+// before retrying. It returns 0 if the error has no hint.
+func RetryAfter(err error) time.Duration {
+	if err == nil {
+		return 0
+	}
+	return time.Duration(FromError(err).RetryAfter) * time.Millisecond
+}
+
 // Equal tries to compare errors
 func Equal(err1 error, err2 error) bool {
-	verr1, ok1 := err1.(*Error)
-	verr2, ok2 := err2.(*Error)
+	verr1, ok1 := As(err1)
+	verr2, ok2 := As(err2)
 
 	if ok1 != ok2 {
 		return false
@@ -138,7 +330,7 @@ func Equal(err1 error, err2 error) bool {
 
 // FromError try to convert go error to *Error
 func FromError(err error) *Error {
-	if verr, ok := err.(*Error); ok && verr != nil {
+	if verr, ok := As(err); ok {
 		return verr
 	}
 
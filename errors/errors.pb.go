@@ -21,13 +21,19 @@ var _ = math.Inf
 const _ = proto.ProtoPackageIsVersion3 // please upgrade the proto package
 
 type Error struct {
-	Id                   string   `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
-	Code                 int32    `protobuf:"varint,2,opt,name=code,proto3" json:"code,omitempty"`
-	Detail               string   `protobuf:"bytes,3,opt,name=detail,proto3" json:"detail,omitempty"`
-	Status               string   `protobuf:"bytes,4,opt,name=status,proto3" json:"status,omitempty"`
-	XXX_NoUnkeyedLiteral struct{} `json:"-"`
-	XXX_unrecognized     []byte   `json:"-"`
-	XXX_sizecache        int32    `json:"-"`
+	Id     string `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Code   int32  `protobuf:"varint,2,opt,name=code,proto3" json:"code,omitempty"`
+	Detail string `protobuf:"bytes,3,opt,name=detail,proto3" json:"detail,omitempty"`
+	Status string `protobuf:"bytes,4,opt,name=status,proto3" json:"status,omitempty"`
+	// milliseconds to wait before retrying
+	RetryAfter int64 `protobuf:"varint,5,opt,name=retry_after,json=retryAfter,proto3" json:"retry_after,omitempty"`
+	// load of the server from 0 to 1
+	Load float32 `protobuf:"fixed32,6,opt,name=load,proto3" json:"load,omitempty"`
+	// structured payloads of the error
+	Details              []*Detail `protobuf:"bytes,7,rep,name=details,proto3" json:"details,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}  `json:"-"`
+	XXX_unrecognized     []byte    `json:"-"`
+	XXX_sizecache        int32     `json:"-"`
 }
 
 func (m *Error) Reset()         { *m = Error{} }
@@ -83,20 +89,96 @@ func (m *Error) GetStatus() string {
 	return ""
 }
 
+func (m *Error) GetRetryAfter() int64 {
+	if m != nil {
+		return m.RetryAfter
+	}
+	return 0
+}
+
+func (m *Error) GetLoad() float32 {
+	if m != nil {
+		return m.Load
+	}
+	return 0
+}
+
+func (m *Error) GetDetails() []*Detail {
+	if m != nil {
+		return m.Details
+	}
+	return nil
+}
+
+type Detail struct {
+	// type of the payload
+	Type string `protobuf:"bytes,1,opt,name=type,proto3" json:"type,omitempty"`
+	// JSON encoded payload
+	Value                string   `protobuf:"bytes,2,opt,name=value,proto3" json:"value,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *Detail) Reset()         { *m = Detail{} }
+func (m *Detail) String() string { return proto.CompactTextString(m) }
+func (*Detail) ProtoMessage()    {}
+func (*Detail) Descriptor() ([]byte, []int) {
+	return fileDescriptor_85c4eef3398a32b2, []int{1}
+}
+
+func (m *Detail) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Detail.Unmarshal(m, b)
+}
+func (m *Detail) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Detail.Marshal(b, m, deterministic)
+}
+func (m *Detail) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Detail.Merge(m, src)
+}
+func (m *Detail) XXX_Size() int {
+	return xxx_messageInfo_Detail.Size(m)
+}
+func (m *Detail) XXX_DiscardUnknown() {
+	xxx_messageInfo_Detail.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Detail proto.InternalMessageInfo
+
+func (m *Detail) GetType() string {
+	if m != nil {
+		return m.Type
+	}
+	return ""
+}
+
+func (m *Detail) GetValue() string {
+	if m != nil {
+		return m.Value
+	}
+	return ""
+}
+
 func init() {
 	proto.RegisterType((*Error)(nil), "errors.Error")
+	proto.RegisterType((*Detail)(nil), "errors.Detail")
 }
 
 func init() { proto.RegisterFile("errors/errors.proto", fileDescriptor_85c4eef3398a32b2) }
 
 var fileDescriptor_85c4eef3398a32b2 = []byte{
-	// 118 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xe2, 0x12, 0x4e, 0x2d, 0x2a, 0xca,
-	0x2f, 0x2a, 0xd6, 0x87, 0x50, 0x7a, 0x05, 0x45, 0xf9, 0x25, 0xf9, 0x42, 0x6c, 0x10, 0x9e, 0x52,
-	0x34, 0x17, 0xab, 0x2b, 0x88, 0x25, 0xc4, 0xc7, 0xc5, 0x94, 0x99, 0x22, 0xc1, 0xa8, 0xc0, 0xa8,
-	0xc1, 0x19, 0xc4, 0x94, 0x99, 0x22, 0x24, 0xc4, 0xc5, 0x92, 0x9c, 0x9f, 0x92, 0x2a, 0xc1, 0xa4,
-	0xc0, 0xa8, 0xc1, 0x1a, 0x04, 0x66, 0x0b, 0x89, 0x71, 0xb1, 0xa5, 0xa4, 0x96, 0x24, 0x66, 0xe6,
-	0x48, 0x30, 0x83, 0xd5, 0x41, 0x79, 0x20, 0xf1, 0xe2, 0x92, 0xc4, 0x92, 0xd2, 0x62, 0x09, 0x16,
-	0x88, 0x38, 0x84, 0x97, 0xc4, 0x06, 0xb6, 0xcb, 0x18, 0x10, 0x00, 0x00, 0xff, 0xff, 0xca, 0xc3,
-	0xcb, 0x29, 0x82, 0x00, 0x00, 0x00,
+	// 203 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x34, 0x8f, 0x41, 0x6a, 0xc5, 0x20,
+	0x10, 0x86, 0xd1, 0x3c, 0x7d, 0xbc, 0x09, 0x64, 0x31, 0x2d, 0xc5, 0x5d, 0x25, 0x2b, 0x57, 0x29,
+	0xa4, 0x27, 0x28, 0xb4, 0x17, 0xf0, 0x02, 0xc5, 0x56, 0x0b, 0x81, 0x80, 0x41, 0x4d, 0x21, 0x57,
+	0xeb, 0xe9, 0x8a, 0x4e, 0xde, 0xca, 0xff, 0xfb, 0x94, 0x71, 0x7e, 0x78, 0x08, 0x29, 0xc5, 0x94,
+	0x5f, 0xe8, 0x98, 0xb6, 0x14, 0x4b, 0x44, 0x49, 0x34, 0xfe, 0x31, 0x10, 0x1f, 0x35, 0xe2, 0x00,
+	0x7c, 0xf1, 0x8a, 0x69, 0x66, 0x6e, 0x96, 0x2f, 0x1e, 0x11, 0x2e, 0xdf, 0xd1, 0x07, 0xc5, 0x35,
+	0x33, 0xc2, 0xb6, 0x8c, 0x4f, 0x20, 0x7d, 0x28, 0x6e, 0x59, 0x55, 0xd7, 0xde, 0x9d, 0x54, 0x7d,
+	0x2e, 0xae, 0xec, 0x59, 0x5d, 0xc8, 0x13, 0xe1, 0x33, 0xf4, 0x29, 0x94, 0x74, 0x7c, 0xba, 0x9f,
+	0x12, 0x92, 0x12, 0x9a, 0x99, 0xce, 0x42, 0x53, 0x6f, 0xd5, 0xd4, 0x4f, 0xd6, 0xe8, 0xbc, 0x92,
+	0x9a, 0x19, 0x6e, 0x5b, 0x46, 0x03, 0x57, 0x1a, 0x9b, 0xd5, 0x55, 0x77, 0xa6, 0x9f, 0x87, 0xe9,
+	0x5c, 0xfd, 0xbd, 0x69, 0x7b, 0xbf, 0x1e, 0x67, 0x90, 0xa4, 0xea, 0x9c, 0x72, 0x6c, 0xe1, 0x5c,
+	0xbf, 0x65, 0x7c, 0x04, 0xf1, 0xeb, 0xd6, 0x9d, 0x1a, 0xdc, 0x2c, 0xc1, 0x97, 0x6c, 0xfd, 0x5f,
+	0xff, 0x07, 0x00, 0xeb, 0xca, 0x05, 0x82, 0x16, 0x01, 0x00, 0x00,
 }
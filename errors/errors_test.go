@@ -2,8 +2,12 @@ package errors
 
 import (
 	er "errors"
+	"fmt"
 	"net/http"
 	"testing"
+	"time"
+
+	"github.com/golang/protobuf/proto"
 )
 
 func TestFromError(t *testing.T) {
@@ -75,3 +79,104 @@ func TestErrors(t *testing.T) {
 		}
 	}
 }
+
+func TestRetryAfter(t *testing.T) {
+	err := Overloaded("go.micro.test", 2*time.Second, 0.5, "too busy")
+
+	// the hints survive encoding
+	pe := Parse(err.Error())
+	if pe.Code != 503 || pe.Load != 0.5 {
+		t.Fatalf("Expected 503 with load 0.5 got %d with %v", pe.Code, pe.Load)
+	}
+	if d := RetryAfter(er.New(err.Error())); d != 2*time.Second {
+		t.Fatalf("Expected retry after 2s got %v", d)
+	}
+
+	b, perr := proto.Marshal(err.(*Error))
+	if perr != nil {
+		t.Fatal(perr)
+	}
+	ue := new(Error)
+	if perr := proto.Unmarshal(b, ue); perr != nil {
+		t.Fatal(perr)
+	}
+	if ue.RetryAfter != 2000 || ue.Load != 0.5 {
+		t.Fatalf("Expected hints to be decoded got %v", ue)
+	}
+
+	if d := RetryAfter(TooManyRequests("go.micro.test", time.Second, "slow down")); d != time.Second {
+		t.Fatalf("Expected retry after 1s got %v", d)
+	}
+	if d := RetryAfter(NotFound("go.micro.test", "not found")); d != 0 {
+		t.Fatalf("Expected no retry after got %v", d)
+	}
+}
+
+func TestIsAs(t *testing.T) {
+	err := fmt.Errorf("getting user: %w", NotFound("go.micro.test", "user not found"))
+
+	if !er.Is(err, &Error{Code: 404}) {
+		t.Fatal("Expected the wrapped error to match its code")
+	}
+	if !er.Is(err, &Error{Id: "go.micro.test", Code: 404}) || er.Is(err, &Error{Id: "go.micro.other", Code: 404}) {
+		t.Fatal("Expected the wrapped error to match its id")
+	}
+	if er.Is(err, &Error{Code: 500}) {
+		t.Fatal("Expected the wrapped error not to match another code")
+	}
+	if verr, ok := As(err); !ok || verr.Code != 404 {
+		t.Fatalf("Expected the wrapped error got %v", verr)
+	}
+	if Code(err) != 404 || FromError(err).Detail != "user not found" {
+		t.Fatalf("Expected the code of the wrapped error got %v", FromError(err))
+	}
+
+	cause := er.New("connection refused")
+	werr := Wrap(cause, "go.micro.test", 503)
+	if !er.Is(werr, cause) || !er.Is(werr, &Error{Code: 503}) {
+		t.Fatal("Expected the error to match its code and cause")
+	}
+	var verr *Error
+	if !er.As(werr, &verr) || verr.Detail != "connection refused" {
+		t.Fatalf("Expected the error with the code got %v", verr)
+	}
+	if pe := Parse(werr.Error()); pe.Code != 503 {
+		t.Fatalf("Expected the code to be encoded got %v", pe)
+	}
+}
+
+type badField struct {
+	Field  string `json:"field"`
+	Reason string `json:"reason"`
+}
+
+func TestDetails(t *testing.T) {
+	err := BadRequest("go.micro.test", "invalid request").(*Error)
+	if derr := err.AddDetail(badField{Field: "name", Reason: "empty"}); derr != nil {
+		t.Fatal(derr)
+	}
+
+	// the details survive json and proto encoding
+	var field badField
+	if pe := Parse(err.Error()); !pe.ScanDetail(&field) || field.Field != "name" {
+		t.Fatalf("Expected the detail to be decoded got %v", field)
+	}
+
+	b, perr := proto.Marshal(err)
+	if perr != nil {
+		t.Fatal(perr)
+	}
+	ue := new(Error)
+	if perr := proto.Unmarshal(b, ue); perr != nil {
+		t.Fatal(perr)
+	}
+	field = badField{}
+	if !ue.ScanDetail(&field) || field.Reason != "empty" {
+		t.Fatalf("Expected the detail to be decoded got %v", ue)
+	}
+
+	var other struct{ Foo string }
+	if ue.ScanDetail(&other) {
+		t.Fatal("Expected no detail of another type")
+	}
+}
@@ -0,0 +1,26 @@
+package flags
+
+import (
+	"hash/fnv"
+	"math/rand"
+
+	"github.com/micro/go-micro/v2/metadata"
+)
+
+// buckets of the percentages, a hundredth of a percent each
+const buckets = 10000
+
+// bucket of the request as a percentage. The requests with the same value
+// of the key of the flag are in the same bucket.
+func bucket(f *Flag, md metadata.Metadata) float64 {
+	v, ok := md.Get(f.Key)
+	if len(f.Key) == 0 || !ok {
+		return float64(rand.Intn(buckets)) * 100 / buckets
+	}
+
+	h := fnv.New32a()
+	h.Write([]byte(f.Name))
+	h.Write([]byte{0})
+	h.Write([]byte(v))
+	return float64(h.Sum32()%buckets) * 100 / buckets
+}
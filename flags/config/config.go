@@ -0,0 +1,186 @@
+// Package config is a flags implementation reading the flags from a config.
+// The flags are a map of flag by name at the path e.g
+//
+//	{
+//		"flags": {
+//			"checkout": {"enabled": true, "percentage": 10, "key": "Micro-Account"}
+//		}
+//	}
+//
+// Set and Delete stage the changes in the config, they're not written to
+// its sources nor returned by Watch which watches the sources.
+package config
+
+import (
+	"context"
+	"sort"
+
+	"github.com/micro/go-micro/v2/config"
+	"github.com/micro/go-micro/v2/flags"
+	"github.com/micro/go-micro/v2/metadata"
+)
+
+// DefaultPath of the flags in the config
+var DefaultPath = []string{"flags"}
+
+type configFlags struct {
+	opts   flags.Options
+	config config.Config
+	path   []string
+}
+
+// NewFlags returns flags read from a config, the default config unless set
+func NewFlags(opts ...flags.Option) flags.Flags {
+	c := &configFlags{
+		opts: flags.NewOptions(opts...),
+	}
+	c.configure()
+	return c
+}
+
+func (c *configFlags) configure() {
+	c.config, c.path = config.DefaultConfig, DefaultPath
+
+	if cfg, ok := c.opts.Context.Value(configKey{}).(config.Config); ok {
+		c.config = cfg
+	}
+	if p, ok := c.opts.Context.Value(pathKey{}).([]string); ok {
+		c.path = p
+	}
+}
+
+// at is the path of the flag
+func (c *configFlags) at(name string) []string {
+	return append(append([]string{}, c.path...), name)
+}
+
+func (c *configFlags) Init(opts ...flags.Option) error {
+	for _, o := range opts {
+		o(&c.opts)
+	}
+	c.configure()
+	return nil
+}
+
+func (c *configFlags) Options() flags.Options {
+	return c.opts
+}
+
+func (c *configFlags) Enabled(ctx context.Context, name string) bool {
+	f, err := c.Get(name)
+	if err != nil {
+		return false
+	}
+	md, _ := metadata.FromContext(ctx)
+	return flags.Eval(f, md)
+}
+
+func (c *configFlags) Get(name string) (*flags.Flag, error) {
+	var f *flags.Flag
+	if err := c.config.Get(c.at(name)...).Scan(&f); err != nil {
+		return nil, err
+	}
+	if f == nil {
+		return nil, flags.ErrNotFound
+	}
+	f.Name = name
+	return f, nil
+}
+
+func (c *configFlags) Set(f *flags.Flag) error {
+	return c.config.Stage().Set(f, c.at(f.Name)...).Apply()
+}
+
+func (c *configFlags) Delete(name string) error {
+	if _, err := c.Get(name); err != nil {
+		return err
+	}
+	return c.config.Stage().Del(c.at(name)...).Apply()
+}
+
+func (c *configFlags) List() ([]*flags.Flag, error) {
+	var m map[string]*flags.Flag
+	if err := c.config.Get(c.path...).Scan(&m); err != nil {
+		return nil, err
+	}
+
+	list := make([]*flags.Flag, 0, len(m))
+	for name, f := range m {
+		if f == nil {
+			continue
+		}
+		f.Name = name
+		list = append(list, f)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Name < list[j].Name
+	})
+
+	return list, nil
+}
+
+func (c *configFlags) Watch() (flags.Watcher, error) {
+	w, err := c.config.Watch(c.path...)
+	if err != nil {
+		return nil, err
+	}
+	return &watcher{c: c, w: w, exit: make(chan bool)}, nil
+}
+
+func (c *configFlags) String() string {
+	return "config"
+}
+
+type watcher struct {
+	c    *configFlags
+	w    config.Watcher
+	exit chan bool
+	// events of the last change not yet returned
+	events []*flags.Event
+}
+
+func (w *watcher) Next() (*flags.Event, error) {
+	for len(w.events) == 0 {
+		if _, err := w.w.Next(); err != nil {
+			select {
+			case <-w.exit:
+				return nil, flags.ErrWatcherStopped
+			default:
+				return nil, err
+			}
+		}
+
+		// an event per flag changed
+		seen := make(map[string]bool)
+		for _, ch := range w.w.Changes() {
+			if len(ch.Path) <= len(w.c.path) {
+				continue
+			}
+			name := ch.Path[len(w.c.path)]
+			if seen[name] {
+				continue
+			}
+			seen[name] = true
+
+			f, err := w.c.Get(name)
+			if err != nil {
+				w.events = append(w.events, &flags.Event{Type: flags.Deleted, Flag: &flags.Flag{Name: name}})
+				continue
+			}
+			w.events = append(w.events, &flags.Event{Type: flags.Updated, Flag: f})
+		}
+	}
+
+	ev := w.events[0]
+	w.events = w.events[1:]
+	return ev, nil
+}
+
+func (w *watcher) Stop() {
+	select {
+	case <-w.exit:
+	default:
+		close(w.exit)
+		w.w.Stop()
+	}
+}
@@ -0,0 +1,47 @@
+package config
+
+import (
+	"context"
+	"testing"
+
+	"github.com/micro/go-micro/v2/config"
+	"github.com/micro/go-micro/v2/config/source/memory"
+	"github.com/micro/go-micro/v2/flags"
+)
+
+func TestConfigFlags(t *testing.T) {
+	c, err := config.NewConfig(config.WithSource(memory.NewSource(memory.WithJSON([]byte(
+		`{"flags": {"checkout": {"enabled": true}, "search": {"enabled": false}}}`,
+	)))))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	f := NewFlags(WithConfig(c))
+
+	if !f.Enabled(context.Background(), "checkout") {
+		t.Fatal("Expected checkout to be enabled")
+	}
+	if f.Enabled(context.Background(), "search") {
+		t.Fatal("Expected search to be disabled")
+	}
+
+	if err := f.Set(&flags.Flag{Name: "search", Enabled: true}); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Delete("checkout"); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Delete("checkout"); err != flags.ErrNotFound {
+		t.Fatalf("Expected not found got %v", err)
+	}
+
+	list, err := f.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 1 || list[0].Name != "search" || !list[0].Enabled {
+		t.Fatalf("Unexpected flags %+v", list)
+	}
+}
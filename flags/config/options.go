@@ -0,0 +1,31 @@
+package config
+
+import (
+	"context"
+
+	"github.com/micro/go-micro/v2/config"
+	"github.com/micro/go-micro/v2/flags"
+)
+
+type configKey struct{}
+type pathKey struct{}
+
+// WithConfig sets the config of the flags
+func WithConfig(c config.Config) flags.Option {
+	return func(o *flags.Options) {
+		if o.Context == nil {
+			o.Context = context.Background()
+		}
+		o.Context = context.WithValue(o.Context, configKey{}, c)
+	}
+}
+
+// WithPath sets the path of the flags in the config
+func WithPath(path ...string) flags.Option {
+	return func(o *flags.Options) {
+		if o.Context == nil {
+			o.Context = context.Background()
+		}
+		o.Context = context.WithValue(o.Context, pathKey{}, path)
+	}
+}
@@ -0,0 +1,144 @@
+// Package flags is an interface for feature flags
+package flags
+
+import (
+	"context"
+	"errors"
+
+	"github.com/micro/go-micro/v2/metadata"
+)
+
+var (
+	// ErrNotFound is returned when a flag doesn't exist
+	ErrNotFound = errors.New("flag not found")
+	// ErrWatcherStopped is returned by Next when the watcher is stopped
+	ErrWatcherStopped = errors.New("watcher stopped")
+
+	// DefaultFlags is the default flags, every flag is disabled
+	DefaultFlags Flags = new(noopFlags)
+)
+
+// Flags manages feature flags and evaluates them for requests
+type Flags interface {
+	// Initialise options
+	Init(...Option) error
+	// Return the options
+	Options() Options
+	// Enabled evaluates the flag for the request with the metadata of the
+	// context. It's false if the flag doesn't exist.
+	Enabled(ctx context.Context, name string) bool
+	// Get a flag
+	Get(name string) (*Flag, error)
+	// Set a flag
+	Set(f *Flag) error
+	// Delete a flag
+	Delete(name string) error
+	// List the flags
+	List() ([]*Flag, error)
+	// Watch the changes of the flags
+	Watch() (Watcher, error)
+	// Flags implementation
+	String() string
+}
+
+// Flag is a feature flag. A disabled flag is disabled for every request,
+// otherwise it's enabled by the first matching target, or else for the
+// percentage of the requests.
+type Flag struct {
+	Name string `json:"name"`
+	// Enabled switches the flag on or off for every request
+	Enabled bool `json:"enabled"`
+	// Percentage of the requests the flag is enabled for, all if it's not
+	// set and none if it's zero
+	Percentage *float64 `json:"percentage,omitempty"`
+	// Key of the metadata the requests are bucketed by for the percentage
+	// so a user gets the same result every request e.g Micro-Account.
+	// The requests without it are bucketed randomly.
+	Key string `json:"key,omitempty"`
+	// Targets enabling or disabling the flag for matching requests
+	Targets []Target `json:"targets,omitempty"`
+}
+
+// Target matches requests by the value of a metadata key
+type Target struct {
+	// Key of the metadata
+	Key string `json:"key"`
+	// Values matched
+	Values []string `json:"values"`
+	// Enabled for the matching requests
+	Enabled bool `json:"enabled"`
+}
+
+// EventType is the type of a change of a flag
+type EventType int
+
+const (
+	// Updated is emitted when a flag is created or updated
+	Updated EventType = iota
+	// Deleted is emitted when a flag is deleted
+	Deleted
+)
+
+func (t EventType) String() string {
+	switch t {
+	case Updated:
+		return "update"
+	case Deleted:
+		return "delete"
+	default:
+		return "unknown"
+	}
+}
+
+// Event is a change of a flag
+type Event struct {
+	Type EventType
+	// Flag updated, only the name is set if deleted
+	Flag *Flag
+}
+
+// Watcher returns the changes of the flags
+type Watcher interface {
+	// Next is a blocking call
+	Next() (*Event, error)
+	// Stop the watcher
+	Stop()
+}
+
+// Enabled evaluates the flag of the default flags for the request
+func Enabled(ctx context.Context, name string) bool {
+	return DefaultFlags.Enabled(ctx, name)
+}
+
+// Eval evaluates the flag for the request with the metadata
+func Eval(f *Flag, md metadata.Metadata) bool {
+	if f == nil || !f.Enabled {
+		return false
+	}
+
+	for _, t := range f.Targets {
+		v, ok := md.Get(t.Key)
+		if !ok {
+			continue
+		}
+		for _, tv := range t.Values {
+			if tv == v {
+				return t.Enabled
+			}
+		}
+	}
+
+	switch {
+	case f.Percentage == nil || *f.Percentage >= 100:
+		return true
+	case *f.Percentage <= 0:
+		return false
+	}
+
+	return bucket(f, md) < *f.Percentage
+}
+
+// Percent returns the percentage of the requests a flag is enabled for
+func Percent(p float64) *float64 {
+	return &p
+}
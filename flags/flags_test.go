@@ -0,0 +1,61 @@
+package flags
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/micro/go-micro/v2/metadata"
+)
+
+func TestEval(t *testing.T) {
+	f := &Flag{
+		Name:    "checkout",
+		Enabled: true,
+		Targets: []Target{
+			{Key: "Micro-Account", Values: []string{"beta"}, Enabled: true},
+			{Key: "Micro-Region", Values: []string{"eu", "us"}, Enabled: false},
+		},
+		Percentage: Percent(25),
+		Key:        "Micro-Account",
+	}
+
+	if !Eval(f, metadata.Metadata{"Micro-Account": "beta", "Micro-Region": "eu"}) {
+		t.Fatal("Expected the first matching target to enable the flag")
+	}
+	if Eval(f, metadata.Metadata{"Micro-Region": "us"}) {
+		t.Fatal("Expected the target to disable the flag")
+	}
+
+	// the same account always gets the same result
+	var enabled int
+	for i := 0; i < 1000; i++ {
+		md := metadata.Metadata{"Micro-Account": fmt.Sprintf("account-%d", i)}
+		v := Eval(f, md)
+		for j := 0; j < 3; j++ {
+			if Eval(f, md) != v {
+				t.Fatalf("Expected a stable result for %v", md)
+			}
+		}
+		if v {
+			enabled++
+		}
+	}
+	if enabled < 150 || enabled > 350 {
+		t.Fatalf("Expected about 25%% of the accounts enabled got %d", enabled)
+	}
+
+	f.Enabled = false
+	if Eval(f, metadata.Metadata{"Micro-Account": "beta"}) {
+		t.Fatal("Expected a disabled flag to be disabled for every request")
+	}
+
+	if !Eval(&Flag{Name: "on", Enabled: true}, nil) {
+		t.Fatal("Expected an enabled flag without a percentage to be enabled")
+	}
+	if Eval(&Flag{Name: "off", Enabled: true, Percentage: Percent(0)}, metadata.Metadata{"Micro-Account": "account-1"}) {
+		t.Fatal("Expected an enabled flag of 0% to be disabled")
+	}
+	if Eval(nil, nil) {
+		t.Fatal("Expected a missing flag to be disabled")
+	}
+}
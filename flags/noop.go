@@ -0,0 +1,63 @@
+package flags
+
+import "context"
+
+type noopFlags struct {
+	opts Options
+}
+
+func (n *noopFlags) Init(opts ...Option) error {
+	for _, o := range opts {
+		o(&n.opts)
+	}
+	return nil
+}
+
+func (n *noopFlags) Options() Options {
+	return n.opts
+}
+
+func (n *noopFlags) Enabled(ctx context.Context, name string) bool {
+	return false
+}
+
+func (n *noopFlags) Get(name string) (*Flag, error) {
+	return nil, ErrNotFound
+}
+
+func (n *noopFlags) Set(f *Flag) error {
+	return nil
+}
+
+func (n *noopFlags) Delete(name string) error {
+	return nil
+}
+
+func (n *noopFlags) List() ([]*Flag, error) {
+	return nil, nil
+}
+
+func (n *noopFlags) Watch() (Watcher, error) {
+	return &noopWatcher{exit: make(chan bool)}, nil
+}
+
+func (n *noopFlags) String() string {
+	return "noop"
+}
+
+type noopWatcher struct {
+	exit chan bool
+}
+
+func (w *noopWatcher) Next() (*Event, error) {
+	<-w.exit
+	return nil, ErrWatcherStopped
+}
+
+func (w *noopWatcher) Stop() {
+	select {
+	case <-w.exit:
+	default:
+		close(w.exit)
+	}
+}
@@ -0,0 +1,21 @@
+package flags
+
+import "context"
+
+type Options struct {
+	// Context should contain all implementation specific options
+	Context context.Context
+}
+
+type Option func(o *Options)
+
+// NewOptions returns the options with the defaults set
+func NewOptions(opts ...Option) Options {
+	options := Options{
+		Context: context.Background(),
+	}
+	for _, o := range opts {
+		o(&options)
+	}
+	return options
+}
@@ -0,0 +1,31 @@
+package store
+
+import (
+	"context"
+
+	"github.com/micro/go-micro/v2/flags"
+	"github.com/micro/go-micro/v2/store"
+)
+
+type storeKey struct{}
+type prefixKey struct{}
+
+// WithStore sets the store of the flags
+func WithStore(s store.Store) flags.Option {
+	return func(o *flags.Options) {
+		if o.Context == nil {
+			o.Context = context.Background()
+		}
+		o.Context = context.WithValue(o.Context, storeKey{}, s)
+	}
+}
+
+// WithPrefix sets the prefix of the keys of the flags in the store
+func WithPrefix(p string) flags.Option {
+	return func(o *flags.Options) {
+		if o.Context == nil {
+			o.Context = context.Background()
+		}
+		o.Context = context.WithValue(o.Context, prefixKey{}, p)
+	}
+}
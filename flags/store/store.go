@@ -0,0 +1,168 @@
+// Package store is a flags implementation keeping the flags in a store.
+// Wrap a remote store with a cache to evaluate the flags without a round
+// trip every request.
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+
+	"github.com/micro/go-micro/v2/flags"
+	"github.com/micro/go-micro/v2/metadata"
+	"github.com/micro/go-micro/v2/store"
+	"github.com/micro/go-micro/v2/store/memory"
+)
+
+// DefaultPrefix of the keys of the flags
+var DefaultPrefix = "flags/"
+
+type storeFlags struct {
+	opts   flags.Options
+	store  store.Store
+	prefix string
+}
+
+// NewFlags returns flags kept in a store, a memory store by default
+func NewFlags(opts ...flags.Option) flags.Flags {
+	s := &storeFlags{
+		opts: flags.NewOptions(opts...),
+	}
+	s.configure()
+	return s
+}
+
+func (s *storeFlags) configure() {
+	s.store, s.prefix = nil, DefaultPrefix
+
+	if st, ok := s.opts.Context.Value(storeKey{}).(store.Store); ok {
+		s.store = st
+	}
+	if p, ok := s.opts.Context.Value(prefixKey{}).(string); ok {
+		s.prefix = p
+	}
+	if s.store == nil {
+		s.store = memory.NewStore()
+	}
+}
+
+func (s *storeFlags) Init(opts ...flags.Option) error {
+	for _, o := range opts {
+		o(&s.opts)
+	}
+	s.configure()
+	return nil
+}
+
+func (s *storeFlags) Options() flags.Options {
+	return s.opts
+}
+
+func (s *storeFlags) Enabled(ctx context.Context, name string) bool {
+	f, err := s.Get(name)
+	if err != nil {
+		return false
+	}
+	md, _ := metadata.FromContext(ctx)
+	return flags.Eval(f, md)
+}
+
+func (s *storeFlags) Get(name string) (*flags.Flag, error) {
+	recs, err := s.store.Read(s.prefix + name)
+	if err == store.ErrNotFound {
+		return nil, flags.ErrNotFound
+	} else if err != nil {
+		return nil, err
+	}
+	if len(recs) == 0 {
+		return nil, flags.ErrNotFound
+	}
+	return decode(recs[0])
+}
+
+func (s *storeFlags) Set(f *flags.Flag) error {
+	b, err := json.Marshal(f)
+	if err != nil {
+		return err
+	}
+	return s.store.Write(&store.Record{Key: s.prefix + f.Name, Value: b})
+}
+
+func (s *storeFlags) Delete(name string) error {
+	err := s.store.Delete(s.prefix + name)
+	if err == store.ErrNotFound {
+		return flags.ErrNotFound
+	}
+	return err
+}
+
+func (s *storeFlags) List() ([]*flags.Flag, error) {
+	recs, err := s.store.Read(s.prefix, store.ReadPrefix())
+	if err != nil && err != store.ErrNotFound {
+		return nil, err
+	}
+
+	list := make([]*flags.Flag, 0, len(recs))
+	for _, r := range recs {
+		f, err := decode(r)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, f)
+	}
+
+	return list, nil
+}
+
+func (s *storeFlags) Watch() (flags.Watcher, error) {
+	w, err := store.Watch(s.store, store.WatchPrefix(s.prefix))
+	if err != nil {
+		return nil, err
+	}
+	return &watcher{w: w, prefix: s.prefix}, nil
+}
+
+func (s *storeFlags) String() string {
+	return "store"
+}
+
+func decode(r *store.Record) (*flags.Flag, error) {
+	f := new(flags.Flag)
+	if err := json.Unmarshal(r.Value, f); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+type watcher struct {
+	w      store.Watcher
+	prefix string
+}
+
+func (w *watcher) Next() (*flags.Event, error) {
+	for {
+		ev, err := w.w.Next()
+		if err == store.ErrWatcherStopped {
+			return nil, flags.ErrWatcherStopped
+		} else if err != nil {
+			return nil, err
+		}
+
+		switch ev.Type {
+		case store.Created, store.Updated:
+			f, err := decode(ev.Record)
+			if err != nil {
+				// not a flag
+				continue
+			}
+			return &flags.Event{Type: flags.Updated, Flag: f}, nil
+		case store.Deleted, store.Expired:
+			name := strings.TrimPrefix(ev.Key, w.prefix)
+			return &flags.Event{Type: flags.Deleted, Flag: &flags.Flag{Name: name}}, nil
+		}
+	}
+}
+
+func (w *watcher) Stop() {
+	w.w.Stop()
+}
@@ -0,0 +1,84 @@
+package store
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/micro/go-micro/v2/flags"
+	"github.com/micro/go-micro/v2/metadata"
+)
+
+func TestStoreFlags(t *testing.T) {
+	f := NewFlags()
+
+	w, err := f.Watch()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Stop()
+
+	err = f.Set(&flags.Flag{
+		Name:    "checkout",
+		Enabled: true,
+		Targets: []flags.Target{{Key: "Micro-Account", Values: []string{"beta"}, Enabled: true}},
+		// nobody else
+		Percentage: flags.Percent(0.01),
+		Key:        "Micro-Account",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := metadata.NewContext(context.Background(), metadata.Metadata{"Micro-Account": "beta"})
+	if !f.Enabled(ctx, "checkout") {
+		t.Fatal("Expected checkout to be enabled for the beta account")
+	}
+	if f.Enabled(ctx, "missing") {
+		t.Fatal("Expected a missing flag to be disabled")
+	}
+
+	list, err := f.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 1 || list[0].Name != "checkout" {
+		t.Fatalf("Unexpected flags %+v", list)
+	}
+
+	if err := f.Delete("checkout"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.Get("checkout"); err != flags.ErrNotFound {
+		t.Fatalf("Expected not found got %v", err)
+	}
+
+	for _, typ := range []flags.EventType{flags.Updated, flags.Deleted} {
+		ev := next(t, w)
+		if ev.Type != typ || ev.Flag.Name != "checkout" {
+			t.Fatalf("Expected %s of checkout got %s of %s", typ, ev.Type, ev.Flag.Name)
+		}
+	}
+}
+
+func next(t *testing.T, w flags.Watcher) *flags.Event {
+	ch := make(chan *flags.Event, 1)
+	go func() {
+		ev, err := w.Next()
+		if err != nil {
+			t.Error(err)
+		}
+		ch <- ev
+	}()
+
+	select {
+	case ev := <-ch:
+		if ev == nil {
+			t.FailNow()
+		}
+		return ev
+	case <-time.After(time.Second):
+		t.Fatal("Timed out waiting for an event")
+	}
+	return nil
+}
@@ -0,0 +1,73 @@
+// Package metrics is an interface for reporting metrics
+package metrics
+
+import (
+	"time"
+)
+
+// Tags are the labels of a metric e.g the remote service
+type Tags map[string]string
+
+// Reporter reports metrics to a backend
+type Reporter interface {
+	// Count adds the value to a counter
+	Count(id string, value int64, tags Tags) error
+	// Gauge sets the value of a gauge
+	Gauge(id string, value float64, tags Tags) error
+	// Timing records the duration of an event
+	Timing(id string, value time.Duration, tags Tags) error
+	String() string
+}
+
+// Deleter is implemented by the reporters which delete the metrics of the
+// tags e.g the gauge of a connection which is closed, so the series of
+// the tags which are gone aren't kept
+type Deleter interface {
+	Delete(id string, tags Tags) error
+}
+
+var (
+	// DefaultReporter discards the metrics
+	DefaultReporter Reporter = new(noopReporter)
+)
+
+// Status tag of the outcome of an operation
+func Status(err error) string {
+	if err != nil {
+		return "failure"
+	}
+	return "success"
+}
+
+// Count adds the value to a counter of the default reporter
+func Count(id string, value int64, tags Tags) error {
+	return DefaultReporter.Count(id, value, tags)
+}
+
+// Gauge sets the value of a gauge of the default reporter
+func Gauge(id string, value float64, tags Tags) error {
+	return DefaultReporter.Gauge(id, value, tags)
+}
+
+// Timing records the duration of an event with the default reporter
+func Timing(id string, value time.Duration, tags Tags) error {
+	return DefaultReporter.Timing(id, value, tags)
+}
+
+type noopReporter struct{}
+
+func (n *noopReporter) Count(id string, value int64, tags Tags) error {
+	return nil
+}
+
+func (n *noopReporter) Gauge(id string, value float64, tags Tags) error {
+	return nil
+}
+
+func (n *noopReporter) Timing(id string, value time.Duration, tags Tags) error {
+	return nil
+}
+
+func (n *noopReporter) String() string {
+	return "noop"
+}
@@ -0,0 +1,40 @@
+package prometheus
+
+import (
+	prom "github.com/prometheus/client_golang/prometheus"
+)
+
+// Options of the prometheus reporter
+type Options struct {
+	// Registry the metrics are registered in. Defaults to a registry of
+	// the go runtime and process metrics.
+	Registry *prom.Registry
+	// Namespace prefixes the names of the metrics
+	Namespace string
+	// Buckets of the timings in seconds
+	Buckets []float64
+}
+
+// Option sets values in Options
+type Option func(o *Options)
+
+// Registry the metrics are registered in
+func Registry(r *prom.Registry) Option {
+	return func(o *Options) {
+		o.Registry = r
+	}
+}
+
+// Namespace prefixes the names of the metrics e.g the service name
+func Namespace(ns string) Option {
+	return func(o *Options) {
+		o.Namespace = ns
+	}
+}
+
+// Buckets of the timings in seconds
+func Buckets(b ...float64) Option {
+	return func(o *Options) {
+		o.Buckets = b
+	}
+}
@@ -0,0 +1,223 @@
+// Package prometheus is a metrics reporter exporting the metrics to
+// prometheus. The counters are suffixed with _total and the timings are
+// histograms in seconds suffixed with _seconds.
+package prometheus
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/micro/go-micro/v2/metrics"
+	prom "github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Reporter reports the metrics to a registry scraped by prometheus
+type Reporter interface {
+	metrics.Reporter
+	// Handler serves the metrics to prometheus
+	Handler() http.Handler
+}
+
+type reporter struct {
+	opts Options
+
+	sync.Mutex
+	// vectors and their label names by name
+	vecs   map[string]interface{}
+	labels map[string][]string
+}
+
+// NewReporter returns a prometheus reporter
+func NewReporter(opts ...Option) Reporter {
+	options := Options{
+		Buckets: prom.DefBuckets,
+	}
+	for _, o := range opts {
+		o(&options)
+	}
+	if options.Registry == nil {
+		options.Registry = prom.NewRegistry()
+		options.Registry.MustRegister(
+			prom.NewGoCollector(),
+			prom.NewProcessCollector(prom.ProcessCollectorOpts{}),
+		)
+	}
+
+	return &reporter{
+		opts:   options,
+		vecs:   make(map[string]interface{}),
+		labels: make(map[string][]string),
+	}
+}
+
+// sanitize the name of a metric or label
+func sanitize(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '_' || r == ':' {
+			return r
+		}
+		return '_'
+	}, s)
+}
+
+// vec returns the vector of the name, created by fn with the label names
+// of the tags if it doesn't exist, and the labels of the tags
+func (r *reporter) vec(name string, tags metrics.Tags, fn func(names []string) prom.Collector) (interface{}, prom.Labels, error) {
+	name = sanitize(name)
+
+	labels := make(prom.Labels, len(tags))
+	for k, v := range tags {
+		labels[sanitize(k)] = v
+	}
+
+	r.Lock()
+	defer r.Unlock()
+
+	if v, ok := r.vecs[name]; ok {
+		if !sameNames(r.labels[name], labels) {
+			return nil, nil, fmt.Errorf("metric %s has the labels %v", name, r.labels[name])
+		}
+		return v, labels, nil
+	}
+
+	names := make([]string, 0, len(labels))
+	for k := range labels {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+
+	c := fn(names)
+	if err := r.opts.Registry.Register(c); err != nil {
+		return nil, nil, err
+	}
+	r.vecs[name] = c
+	r.labels[name] = names
+
+	return c, labels, nil
+}
+
+func sameNames(names []string, labels prom.Labels) bool {
+	if len(names) != len(labels) {
+		return false
+	}
+	for _, n := range names {
+		if _, ok := labels[n]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+func (r *reporter) Count(id string, value int64, tags metrics.Tags) error {
+	name := id
+	if !strings.HasSuffix(name, "_total") {
+		name += "_total"
+	}
+	v, labels, err := r.vec(name, tags, func(names []string) prom.Collector {
+		return prom.NewCounterVec(prom.CounterOpts{
+			Namespace: r.opts.Namespace,
+			Name:      sanitize(name),
+			Help:      id,
+		}, names)
+	})
+	if err != nil {
+		return err
+	}
+	vec, ok := v.(*prom.CounterVec)
+	if !ok {
+		return fmt.Errorf("metric %s isn't a counter", name)
+	}
+	c, err := vec.GetMetricWith(labels)
+	if err != nil {
+		return err
+	}
+	c.Add(float64(value))
+	return nil
+}
+
+func (r *reporter) Gauge(id string, value float64, tags metrics.Tags) error {
+	v, labels, err := r.vec(id, tags, func(names []string) prom.Collector {
+		return prom.NewGaugeVec(prom.GaugeOpts{
+			Namespace: r.opts.Namespace,
+			Name:      sanitize(id),
+			Help:      id,
+		}, names)
+	})
+	if err != nil {
+		return err
+	}
+	vec, ok := v.(*prom.GaugeVec)
+	if !ok {
+		return fmt.Errorf("metric %s isn't a gauge", id)
+	}
+	g, err := vec.GetMetricWith(labels)
+	if err != nil {
+		return err
+	}
+	g.Set(value)
+	return nil
+}
+
+func (r *reporter) Timing(id string, value time.Duration, tags metrics.Tags) error {
+	name := id
+	if !strings.HasSuffix(name, "_seconds") {
+		name += "_seconds"
+	}
+	v, labels, err := r.vec(name, tags, func(names []string) prom.Collector {
+		return prom.NewHistogramVec(prom.HistogramOpts{
+			Namespace: r.opts.Namespace,
+			Name:      sanitize(name),
+			Help:      id,
+			Buckets:   r.opts.Buckets,
+		}, names)
+	})
+	if err != nil {
+		return err
+	}
+	vec, ok := v.(*prom.HistogramVec)
+	if !ok {
+		return fmt.Errorf("metric %s isn't a histogram", name)
+	}
+	h, err := vec.GetMetricWith(labels)
+	if err != nil {
+		return err
+	}
+	h.Observe(value.Seconds())
+	return nil
+}
+
+// Delete the series of the tags of the metric e.g the gauge of an address
+// which is gone. The counters and timings are deleted by their id too.
+func (r *reporter) Delete(id string, tags metrics.Tags) error {
+	labels := make(prom.Labels, len(tags))
+	for k, v := range tags {
+		labels[sanitize(k)] = v
+	}
+
+	r.Lock()
+	defer r.Unlock()
+
+	for _, name := range []string{id, id + "_total", id + "_seconds"} {
+		v, ok := r.vecs[sanitize(name)]
+		if !ok {
+			continue
+		}
+		if d, ok := v.(interface{ Delete(prom.Labels) bool }); ok {
+			d.Delete(labels)
+		}
+	}
+	return nil
+}
+
+func (r *reporter) Handler() http.Handler {
+	return promhttp.HandlerFor(r.opts.Registry, promhttp.HandlerOpts{})
+}
+
+func (r *reporter) String() string {
+	return "prometheus"
+}
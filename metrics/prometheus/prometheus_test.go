@@ -0,0 +1,86 @@
+package prometheus
+
+import (
+	"io/ioutil"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/micro/go-micro/v2/metrics"
+)
+
+func scrape(t *testing.T, r Reporter) string {
+	rsp := httptest.NewRecorder()
+	r.Handler().ServeHTTP(rsp, httptest.NewRequest("GET", "/metrics", nil))
+	b, err := ioutil.ReadAll(rsp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
+
+func TestReporter(t *testing.T) {
+	r := NewReporter(Namespace("notes"))
+	tags := metrics.Tags{"endpoint": "Notes.List", "status": "success"}
+
+	for i := 0; i < 2; i++ {
+		if err := r.Count("micro_server_requests", 1, tags); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := r.Gauge("micro.pool-idle", 3, metrics.Tags{"address": "10.0.0.1:8080"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Timing("micro_server_request_duration", 50*time.Millisecond, tags); err != nil {
+		t.Fatal(err)
+	}
+
+	// the labels of a metric can't change
+	if err := r.Count("micro_server_requests", 1, metrics.Tags{"endpoint": "Notes.List"}); err == nil {
+		t.Fatal("Expected an error for different labels")
+	}
+
+	out := scrape(t, r)
+	for _, line := range []string{
+		`notes_micro_server_requests_total{endpoint="Notes.List",status="success"} 2`,
+		`notes_micro_pool_idle{address="10.0.0.1:8080"} 3`,
+		`notes_micro_server_request_duration_seconds_count{endpoint="Notes.List",status="success"} 1`,
+		`go_goroutines`,
+	} {
+		if !strings.Contains(out, line) {
+			t.Fatalf("Expected %s in\n%s", line, out)
+		}
+	}
+}
+
+func TestReporterTypes(t *testing.T) {
+	r := NewReporter()
+	tags := metrics.Tags{"address": "10.0.0.1:8080"}
+
+	if err := r.Count("micro_conns", 1, tags); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Timing("micro_dial", time.Millisecond, tags); err != nil {
+		t.Fatal(err)
+	}
+
+	// a metric of the same name but another type is an error
+	if err := r.Gauge("micro_conns_total", 1, tags); err == nil {
+		t.Fatal("Expected an error for the gauge of a counter")
+	}
+	if err := r.Gauge("micro_dial_seconds", 1, tags); err == nil {
+		t.Fatal("Expected an error for the gauge of a timing")
+	}
+
+	// the series of the tags are deleted
+	if err := r.Gauge("micro_idle", 2, tags); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.(metrics.Deleter).Delete("micro_idle", tags); err != nil {
+		t.Fatal(err)
+	}
+	if out := scrape(t, r); strings.Contains(out, "micro_idle{") {
+		t.Fatalf("Expected the gauge to be deleted in\n%s", out)
+	}
+}
@@ -13,6 +13,7 @@ import (
 	"github.com/micro/go-micro/v2/config/cmd"
 	"github.com/micro/go-micro/v2/debug/profile"
 	"github.com/micro/go-micro/v2/debug/trace"
+	"github.com/micro/go-micro/v2/metrics"
 	"github.com/micro/go-micro/v2/registry"
 	"github.com/micro/go-micro/v2/router"
 	"github.com/micro/go-micro/v2/runtime"
@@ -47,6 +48,13 @@ type Options struct {
 	Context context.Context
 
 	Signal bool
+
+	// Address of the dashboard. It's only served when set.
+	Dashboard string
+
+	// Timeouts of the shutdown stages. The empty
+	// stage name sets the timeout of every stage.
+	ShutdownTimeouts map[string]time.Duration
 }
 
 func newOptions(opts ...Option) Options {
@@ -113,6 +121,50 @@ func HandleSignal(b bool) Option {
 	}
 }
 
+// Stages of the shutdown in the order they're run
+const (
+	// StageBeforeStop runs the before stop funcs
+	StageBeforeStop = "before_stop"
+	// StageServer stops accepting requests and drains the server, it
+	// deregisters the servers which aren't a server.Deregisterer
+	StageServer = "server"
+	// StageBroker flushes publishes and disconnects the broker
+	StageBroker = "broker"
+	// StageStore closes the store
+	StageStore = "store"
+	// StageConfig closes the config watchers
+	StageConfig = "config"
+	// StageDeregister deregisters the server
+	StageDeregister = "deregister"
+	// StageAfterStop runs the after stop funcs
+	StageAfterStop = "after_stop"
+)
+
+// ShutdownTimeout sets the timeout of the named shutdown stages
+// or every stage if none are named
+func ShutdownTimeout(d time.Duration, stages ...string) Option {
+	return func(o *Options) {
+		if o.ShutdownTimeouts == nil {
+			o.ShutdownTimeouts = make(map[string]time.Duration)
+		}
+		if len(stages) == 0 {
+			stages = []string{""}
+		}
+		for _, s := range stages {
+			o.ShutdownTimeouts[s] = d
+		}
+	}
+}
+
+// Dashboard serves the development dashboard on the address e.g 127.0.0.1:8082.
+// Requests must have a token accepted by the auth of the service, the
+// service fails to run with the noop auth.
+func Dashboard(addr string) Option {
+	return func(o *Options) {
+		o.Dashboard = addr
+	}
+}
+
 // Profile to be used for debug profile
 func Profile(p profile.Profile) Option {
 	return func(o *Options) {
@@ -154,6 +206,31 @@ func Tracer(t trace.Tracer) Option {
 	}
 }
 
+// Metrics reports the rate, errors and duration of the requests and
+// messages handled and sent, the registry operations, the transport and the
+// connection pool e.g to prometheus
+//
+//	r := prometheus.NewReporter()
+//	service := micro.NewService(micro.Metrics(r))
+//	go http.ListenAndServe(":9090", r.Handler())
+//
+// The components set by the options after it aren't instrumented.
+func Metrics(r metrics.Reporter) Option {
+	return func(o *Options) {
+		Registry(registry.Instrument(o.Registry, r))(o)
+		Transport(transport.Instrument(o.Transport, r))(o)
+		WrapBroker(func(b broker.Broker) broker.Broker {
+			return broker.Instrument(b, r)
+		})(o)
+		WrapHandler(server.InstrumentHandler(r))(o)
+		WrapSubscriber(server.InstrumentSubscriber(r))(o)
+		o.Client.Init(client.PoolMetrics(r))
+		WrapClient(func(c client.Client) client.Client {
+			return client.Instrument(c, r)
+		})(o)
+	}
+}
+
 // Auth sets the auth for the service
 func Auth(a auth.Auth) Option {
 	return func(o *Options) {
@@ -271,6 +348,25 @@ func WrapCall(w ...client.CallWrapper) Option {
 	}
 }
 
+// WrapBroker is a convenience method for wrapping a Broker with
+// some middleware component e.g tracing or encryption. The broker
+// is updated on the client and server.
+func WrapBroker(w ...broker.Wrapper) Option {
+	return func(o *Options) {
+		// apply in reverse
+		for i := len(w); i > 0; i-- {
+			o.Broker = w[i-1](o.Broker)
+		}
+		o.Client.Init(client.Broker(o.Broker))
+		o.Server.Init(server.Broker(o.Broker))
+	}
+}
+
+// WrapPublish is a convenience method for wrapping Broker Publish
+func WrapPublish(w ...broker.PublishWrapper) Option {
+	return WrapBroker(broker.WrapPublish(w...))
+}
+
 // WrapHandler adds a handler Wrapper to a list of options passed into the server
 func WrapHandler(w ...server.HandlerWrapper) Option {
 	return func(o *Options) {
@@ -285,6 +381,20 @@ func WrapHandler(w ...server.HandlerWrapper) Option {
 	}
 }
 
+// WrapStream adds a stream Wrapper to a list of options passed into the server
+func WrapStream(w ...server.StreamWrapper) Option {
+	return func(o *Options) {
+		var wrappers []server.Option
+
+		for _, wrap := range w {
+			wrappers = append(wrappers, server.WrapStream(wrap))
+		}
+
+		// Init once
+		o.Server.Init(wrappers...)
+	}
+}
+
 // WrapSubscriber adds a subscriber Wrapper to a list of options passed into the server
 func WrapSubscriber(w ...server.SubscriberWrapper) Option {
 	return func(o *Options) {
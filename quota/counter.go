@@ -0,0 +1,66 @@
+package quota
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/micro/go-micro/v2/store"
+)
+
+// DefaultPrefix of the keys of the counts in the store
+var DefaultPrefix = "quota/"
+
+type storeCounter struct {
+	store  store.Store
+	prefix string
+}
+
+// NewStoreCounter returns a counter of the records of the store. The counts
+// are compare and swapped if the store supports it, else they're only
+// accurate for the requests of a single instance.
+func NewStoreCounter(s store.Store, prefix string) Counter {
+	return &storeCounter{store: s, prefix: prefix}
+}
+
+func (c *storeCounter) read(key string) (int64, []byte, error) {
+	recs, err := c.store.Read(c.prefix + key)
+	if err == store.ErrNotFound || (err == nil && len(recs) == 0) {
+		return 0, nil, nil
+	} else if err != nil {
+		return 0, nil, err
+	}
+	n, err := strconv.ParseInt(string(recs[0].Value), 10, 64)
+	if err != nil {
+		return 0, nil, err
+	}
+	return n, recs[0].Value, nil
+}
+
+func (c *storeCounter) Incr(key string, n int64, ttl time.Duration) (int64, error) {
+	for {
+		count, old, err := c.read(key)
+		if err != nil {
+			return 0, err
+		}
+		count += n
+
+		r := &store.Record{
+			Key:    c.prefix + key,
+			Value:  []byte(strconv.FormatInt(count, 10)),
+			Expiry: ttl,
+		}
+		err = store.CompareAndSwap(c.store, r, old)
+		if err == store.ErrCASNotSupported {
+			err = c.store.Write(r)
+		}
+		if err == store.ErrConflict {
+			continue
+		}
+		return count, err
+	}
+}
+
+func (c *storeCounter) Get(key string) (int64, error) {
+	n, _, err := c.read(key)
+	return n, err
+}
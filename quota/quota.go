@@ -0,0 +1,238 @@
+// Package quota enforces quotas of the requests of each caller over rolling
+// windows e.g 1000 requests per hour per account, counted in a store shared
+// by the instances of a service
+package quota
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/micro/go-micro/v2/auth"
+)
+
+// ErrExceeded is returned when a quota of the caller is exceeded
+var ErrExceeded = errors.New("quota exceeded")
+
+// Quota of the requests of each account over a rolling window
+type Quota struct {
+	// ID of the quota
+	ID string `json:"id"`
+	// Account the quota applies to, "*" applies to every account
+	Account string `json:"account,omitempty"`
+	// Scope applies the quota to the accounts with the scope
+	Scope string `json:"scope,omitempty"`
+	// Service and Endpoint globs of the requests counted, empty is any
+	Service  string `json:"service,omitempty"`
+	Endpoint string `json:"endpoint,omitempty"`
+	// Limit of the requests of each account in the window
+	Limit int64 `json:"limit"`
+	// Window the requests are counted over
+	Window time.Duration `json:"window"`
+}
+
+// Usage of a quota by an account
+type Usage struct {
+	// Quota is the id of the quota
+	Quota string `json:"quota"`
+	// Limit of the requests in the window
+	Limit int64 `json:"limit"`
+	// Remaining requests in the window
+	Remaining int64 `json:"remaining"`
+	// Reset is the time the window ends, when at least the requests of
+	// the previous window are available again
+	Reset time.Time `json:"reset"`
+}
+
+// Counter counts the requests of the windows
+type Counter interface {
+	// Incr adds n to the count of the key, which expires after the ttl,
+	// and returns the count
+	Incr(key string, n int64, ttl time.Duration) (int64, error)
+	// Get the count of the key, zero if it doesn't exist
+	Get(key string) (int64, error)
+}
+
+// Enforcer of quotas
+type Enforcer interface {
+	// Load validates and replaces the quotas
+	Load(quotas ...*Quota) error
+	// Quotas loaded
+	Quotas() []*Quota
+	// Allow counts the request of the account against its quotas and
+	// returns the usage of the quotas, the exceeded one first with
+	// ErrExceeded. The request isn't counted if a quota is exceeded.
+	Allow(ctx context.Context, acc *auth.Account, service, endpoint string) ([]*Usage, error)
+}
+
+type enforcer struct {
+	counter Counter
+
+	sync.RWMutex
+	quotas []*Quota
+}
+
+// NewEnforcer returns an enforcer counting the requests with the counter
+func NewEnforcer(c Counter, quotas ...*Quota) (Enforcer, error) {
+	e := &enforcer{counter: c}
+	if err := e.Load(quotas...); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// Validate the quota
+func (q *Quota) Validate() error {
+	if len(q.ID) == 0 {
+		return errors.New("quota has no id")
+	}
+	if len(q.Account) == 0 && len(q.Scope) == 0 {
+		return fmt.Errorf("quota %s applies to no account or scope", q.ID)
+	}
+	if q.Limit < 0 || q.Window <= 0 {
+		return fmt.Errorf("quota %s has an invalid limit or window", q.ID)
+	}
+	for _, p := range []string{q.Service, q.Endpoint} {
+		if _, err := path.Match(p, ""); err != nil {
+			return fmt.Errorf("quota %s has an invalid glob %s", q.ID, p)
+		}
+	}
+	return nil
+}
+
+// Match returns true if the quota applies to the request of the account
+func (q *Quota) Match(acc *auth.Account, service, endpoint string) bool {
+	if acc == nil {
+		return false
+	}
+	if len(q.Account) > 0 && q.Account != "*" && q.Account != acc.ID {
+		return false
+	}
+	if len(q.Scope) > 0 && !include(acc.Scopes, q.Scope) {
+		return false
+	}
+	return glob(q.Service, service) && glob(q.Endpoint, endpoint)
+}
+
+func (e *enforcer) Load(quotas ...*Quota) error {
+	ids := make(map[string]bool, len(quotas))
+	for _, q := range quotas {
+		if err := q.Validate(); err != nil {
+			return err
+		}
+		if ids[q.ID] {
+			return fmt.Errorf("duplicate quota %s", q.ID)
+		}
+		ids[q.ID] = true
+	}
+
+	sorted := make([]*Quota, len(quotas))
+	copy(sorted, quotas)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].ID < sorted[j].ID
+	})
+
+	e.Lock()
+	e.quotas = sorted
+	e.Unlock()
+
+	return nil
+}
+
+func (e *enforcer) Quotas() []*Quota {
+	e.RLock()
+	defer e.RUnlock()
+	return e.quotas
+}
+
+// window of a quota at a time
+type window struct {
+	key, prev string
+	// weight of the count of the previous window
+	weight float64
+	end    time.Time
+	// the keys live until the window after theirs ends
+	ttl time.Duration
+}
+
+func newWindow(q *Quota, acc *auth.Account, now time.Time) window {
+	n := now.UnixNano() / int64(q.Window)
+	start := time.Unix(0, n*int64(q.Window))
+	return window{
+		key:    fmt.Sprintf("%s/%s/%d", q.ID, acc.ID, n),
+		prev:   fmt.Sprintf("%s/%s/%d", q.ID, acc.ID, n-1),
+		weight: 1 - float64(now.Sub(start))/float64(q.Window),
+		end:    start.Add(q.Window),
+		ttl:    2 * q.Window,
+	}
+}
+
+// Allow approximates the rolling windows by weighting the count of the
+// previous fixed window by its overlap with the rolling window
+func (e *enforcer) Allow(ctx context.Context, acc *auth.Account, service, endpoint string) ([]*Usage, error) {
+	var usage []*Usage
+	var counted []window
+
+	// uncount the request if a later quota is exceeded
+	undo := func() {
+		for _, w := range counted {
+			e.counter.Incr(w.key, -1, w.ttl)
+		}
+	}
+
+	now := time.Now()
+
+	for _, q := range e.Quotas() {
+		if !q.Match(acc, service, endpoint) {
+			continue
+		}
+
+		w := newWindow(q, acc, now)
+		prev, err := e.counter.Get(w.prev)
+		if err != nil {
+			undo()
+			return nil, err
+		}
+		count, err := e.counter.Incr(w.key, 1, w.ttl)
+		if err != nil {
+			undo()
+			return nil, err
+		}
+		counted = append(counted, w)
+
+		used := count + int64(float64(prev)*w.weight)
+		u := &Usage{Quota: q.ID, Limit: q.Limit, Remaining: q.Limit - used, Reset: w.end}
+		if u.Remaining < 0 {
+			u.Remaining = 0
+		}
+
+		if used > q.Limit {
+			undo()
+			return append([]*Usage{u}, usage...), ErrExceeded
+		}
+		usage = append(usage, u)
+	}
+
+	return usage, nil
+}
+
+func glob(pattern, s string) bool {
+	if len(pattern) == 0 {
+		return true
+	}
+	ok, _ := path.Match(pattern, s)
+	return ok
+}
+
+func include(slice []string, v string) bool {
+	for _, s := range slice {
+		if s == v {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,108 @@
+package quota
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/micro/go-micro/v2/auth"
+	"github.com/micro/go-micro/v2/errors"
+	"github.com/micro/go-micro/v2/server"
+	"github.com/micro/go-micro/v2/store/memory"
+)
+
+type testRequest struct {
+	server.Request
+}
+
+func (r testRequest) Service() string {
+	return "go.micro.srv.notes"
+}
+
+func (r testRequest) Endpoint() string {
+	return "Notes.List"
+}
+
+func TestAllow(t *testing.T) {
+	e, err := NewEnforcer(NewStoreCounter(memory.NewStore(), DefaultPrefix),
+		&Quota{ID: "default", Account: "*", Limit: 3, Window: time.Hour},
+		&Quota{ID: "free", Scope: "free", Service: "go.micro.srv.*", Endpoint: "Notes.*", Limit: 2, Window: time.Hour},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	alice := &auth.Account{ID: "alice", Scopes: []string{"free"}}
+	bob := &auth.Account{ID: "bob"}
+
+	for i := 0; i < 2; i++ {
+		usage, err := e.Allow(context.TODO(), alice, "go.micro.srv.notes", "Notes.List")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(usage) != 2 {
+			t.Fatalf("Expected the usage of both quotas got %d", len(usage))
+		}
+	}
+
+	usage, err := e.Allow(context.TODO(), alice, "go.micro.srv.notes", "Notes.List")
+	if err != ErrExceeded {
+		t.Fatalf("Expected %v got %v", ErrExceeded, err)
+	}
+	if usage[0].Quota != "free" || usage[0].Remaining != 0 || !usage[0].Reset.After(time.Now()) {
+		t.Fatalf("Expected the free quota to be exceeded got %+v", usage[0])
+	}
+
+	// the rejected request wasn't counted against the default quota
+	usage, err = e.Allow(context.TODO(), alice, "go.micro.srv.users", "Users.Read")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(usage) != 1 || usage[0].Remaining != 0 {
+		t.Fatalf("Expected the last request of the default quota got %+v", usage)
+	}
+
+	// the quotas are per account
+	if _, err := e.Allow(context.TODO(), bob, "go.micro.srv.notes", "Notes.List"); err != nil {
+		t.Fatal(err)
+	}
+	// the anonymous requests have no quota
+	if usage, err := e.Allow(context.TODO(), nil, "go.micro.srv.notes", "Notes.List"); err != nil || len(usage) > 0 {
+		t.Fatalf("Expected no quota got %v %v", usage, err)
+	}
+
+	if err := e.Load(&Quota{ID: "invalid", Account: "*"}); err == nil {
+		t.Fatal("Expected a quota without a window to be invalid")
+	}
+}
+
+func TestHandlerWrapper(t *testing.T) {
+	e, err := NewEnforcer(NewStoreCounter(memory.NewStore(), DefaultPrefix),
+		&Quota{ID: "default", Account: "*", Limit: 1, Window: time.Minute},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	h := NewHandlerWrapper(e)(func(ctx context.Context, req server.Request, rsp interface{}) error {
+		return nil
+	})
+	ctx := auth.ContextWithAccount(context.TODO(), &auth.Account{ID: "alice"})
+
+	rctx := server.NewResponseMetadataContext(ctx)
+	if err := h(rctx, testRequest{}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if md := server.ResponseMetadata(rctx); md[LimitHeader] != "1" || md[RemainingHeader] != "0" || len(md[ResetHeader]) == 0 {
+		t.Fatalf("Unexpected quota metadata %v", md)
+	}
+
+	err = h(ctx, testRequest{}, nil)
+	if errors.Code(err) != 429 || errors.RetryAfter(err) <= 0 {
+		t.Fatalf("Expected a 429 error with a retry after got %v", err)
+	}
+	var u Usage
+	if !errors.FromError(err).ScanDetail(&u) || u.Quota != "default" {
+		t.Fatalf("Expected the usage of the quota in the error got %+v", u)
+	}
+}
@@ -0,0 +1,54 @@
+// Package redis is a quota counter of redis keys incremented atomically
+package redis
+
+import (
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+	"github.com/micro/go-micro/v2/quota"
+)
+
+type counter struct {
+	pool   *redis.Pool
+	prefix string
+}
+
+// NewCounter returns a counter of the keys with the prefix in the redis of
+// the pool e.g
+//
+//	pool := &redis.Pool{Dial: func() (redis.Conn, error) {
+//		return redis.Dial("tcp", "127.0.0.1:6379")
+//	}}
+//	c := redis.NewCounter(pool, quota.DefaultPrefix)
+func NewCounter(pool *redis.Pool, prefix string) quota.Counter {
+	return &counter{pool: pool, prefix: prefix}
+}
+
+func (c *counter) Incr(key string, n int64, ttl time.Duration) (int64, error) {
+	conn := c.pool.Get()
+	defer conn.Close()
+
+	key = c.prefix + key
+
+	// the expiry is extended by every increment like the store counter, the
+	// windows are keyed by time so they're only incremented while current
+	conn.Send("MULTI")
+	conn.Send("INCRBY", key, n)
+	conn.Send("PEXPIRE", key, ttl.Milliseconds())
+	values, err := redis.Values(conn.Do("EXEC"))
+	if err != nil {
+		return 0, err
+	}
+	return redis.Int64(values[0], nil)
+}
+
+func (c *counter) Get(key string) (int64, error) {
+	conn := c.pool.Get()
+	defer conn.Close()
+
+	n, err := redis.Int64(conn.Do("GET", c.prefix+key))
+	if err == redis.ErrNil {
+		return 0, nil
+	}
+	return n, err
+}
@@ -0,0 +1,60 @@
+package quota
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/micro/go-micro/v2/auth"
+	"github.com/micro/go-micro/v2/errors"
+	"github.com/micro/go-micro/v2/logger"
+	"github.com/micro/go-micro/v2/server"
+)
+
+var (
+	// LimitHeader is the response metadata of the limit of the quota with
+	// the fewest remaining requests
+	LimitHeader = "Micro-Quota-Limit"
+	// RemainingHeader is the response metadata of its remaining requests
+	RemainingHeader = "Micro-Quota-Remaining"
+	// ResetHeader is the response metadata of the unix time its window ends
+	ResetHeader = "Micro-Quota-Reset"
+)
+
+// NewHandlerWrapper enforces the quotas of the accounts set in the context by
+// the auth wrappers, so it must wrap the handler after them. An exceeded
+// quota returns a 429 error with the usage of the quota as a detail, which
+// callers read with ScanDetail. The requests are allowed if the counter
+// fails.
+func NewHandlerWrapper(e Enforcer) server.HandlerWrapper {
+	return func(h server.HandlerFunc) server.HandlerFunc {
+		return func(ctx context.Context, req server.Request, rsp interface{}) error {
+			acc, _ := auth.AccountFromContext(ctx)
+
+			usage, err := e.Allow(ctx, acc, req.Service(), req.Endpoint())
+			if err == ErrExceeded {
+				u := usage[0]
+				qerr := errors.TooManyRequests(req.Service(), time.Until(u.Reset), "quota %s of %d requests exceeded by %s", u.Quota, u.Limit, acc.ID).(*errors.Error)
+				qerr.AddDetail(u)
+				return qerr
+			} else if err != nil {
+				logger.Errorf("Error counting the quotas of %s: %v", acc.ID, err)
+				return h(ctx, req, rsp)
+			}
+
+			if len(usage) > 0 {
+				u := usage[0]
+				for _, v := range usage[1:] {
+					if v.Remaining < u.Remaining {
+						u = v
+					}
+				}
+				server.SetResponseMetadata(ctx, LimitHeader, strconv.FormatInt(u.Limit, 10))
+				server.SetResponseMetadata(ctx, RemainingHeader, strconv.FormatInt(u.Remaining, 10))
+				server.SetResponseMetadata(ctx, ResetHeader, strconv.FormatInt(u.Reset.Unix(), 10))
+			}
+
+			return h(ctx, req, rsp)
+		}
+	}
+}
@@ -203,6 +203,11 @@ func (e *etcdRegistry) registerNode(s *registry.Service, node *registry.Node, op
 					continue
 				}
 
+				// another node holds the id at a different address
+				if err := registry.CheckCollision(s, srv.Nodes); err != nil {
+					return err
+				}
+
 				// create hash of service; uint64
 				h, err := hash.Hash(srv.Nodes[0], nil)
 				if err != nil {
@@ -312,7 +312,7 @@ func (m *mdnsRegistry) Register(service *Service, opts ...RegisterOption) error
 	return gerr
 }
 
-func (m *mdnsRegistry) Deregister(service *Service, opts ...DeregisterOption) error {
+func (m *mdnsRegistry) Deregister(service *Service, opts ...DeregisterOption) (err error) {
 	// parse the options
 	var options DeregisterOptions
 	for _, o := range opts {
@@ -322,11 +322,12 @@ func (m *mdnsRegistry) Deregister(service *Service, opts ...DeregisterOption) er
 		options.Domain = m.defaultDomain
 	}
 
-	// register in the global Domain
-	var err error
+	// deregister the duplicate in the global Domain
 	if options.Domain != m.globalDomain {
 		defer func() {
-			err = m.Deregister(service, append(opts, DeregisterDomain(m.globalDomain))...)
+			if gerr := m.Deregister(service, append(opts, DeregisterDomain(m.globalDomain))...); gerr != nil {
+				err = gerr
+			}
 		}()
 	}
 
@@ -372,7 +373,9 @@ func (m *mdnsRegistry) Deregister(service *Service, opts ...DeregisterOption) er
 	}
 
 	// last entry is the wildcard for list queries. Remove it.
-	newEntries[0].node.Shutdown()
+	if len(newEntries) == 1 {
+		newEntries[0].node.Shutdown()
+	}
 	delete(m.domains[options.Domain], service.Name)
 
 	// check to see if we can delete the domain entry
@@ -184,6 +184,17 @@ func (m *Registry) Register(s *registry.Service, opts ...registry.RegisterOption
 		s.Metadata["domain"] = options.Domain
 	}
 
+	// reject nodes reusing an id registered at another address
+	var existing []*registry.Node
+	for _, rec := range srvs[s.Name] {
+		for _, n := range rec.Nodes {
+			existing = append(existing, n.Node)
+		}
+	}
+	if err := registry.CheckCollision(s, existing); err != nil {
+		return err
+	}
+
 	// ensure the service name exists
 	r := serviceToRecord(s, options.TTL)
 	if _, ok := srvs[s.Name]; !ok {
@@ -280,3 +280,38 @@ func TestMemoryWildcard(t *testing.T) {
 		t.Errorf("Expected 2 records, got %v", len(recs))
 	}
 }
+
+func TestMemoryRegistryNodeCollision(t *testing.T) {
+	m := NewRegistry()
+	md := map[string]string{"foo": "bar"}
+
+	srv := &registry.Service{
+		Name:    "foo",
+		Version: "1.0.0",
+		Nodes:   []*registry.Node{{Id: "foo-1", Address: "localhost:9999", Metadata: md}},
+	}
+	if err := m.Register(srv); err != nil {
+		t.Fatalf("Register err: %v", err)
+	}
+
+	// re-registering the same node refreshes it
+	if err := m.Register(srv); err != nil {
+		t.Fatalf("Register err: %v", err)
+	}
+
+	dup := &registry.Service{
+		Name:    "foo",
+		Version: "1.0.1",
+		Nodes:   []*registry.Node{{Id: "foo-1", Address: "localhost:8888", Metadata: md}},
+	}
+	err := m.Register(dup)
+	if !registry.IsNodeCollision(err) {
+		t.Fatalf("Expected node collision error, got %v", err)
+	}
+	if e := err.(*registry.NodeCollisionError); e.Existing != "localhost:9999" {
+		t.Fatalf("Expected existing address localhost:9999, got %s", e.Existing)
+	}
+	if !registry.IsNodeCollision(fmt.Errorf("register: %w", err)) {
+		t.Fatal("Expected the wrapped error to be a node collision")
+	}
+}
@@ -0,0 +1,74 @@
+package registry
+
+import (
+	"time"
+
+	"github.com/micro/go-micro/v2/metrics"
+)
+
+const (
+	// MetricOperations is the count of the operations by name and status
+	MetricOperations = "micro_registry_operations"
+	// MetricOperationDuration is the timing of the operations
+	MetricOperationDuration = "micro_registry_operation_duration"
+)
+
+type metricsRegistry struct {
+	Registry
+	r metrics.Reporter
+}
+
+func (m *metricsRegistry) report(op string, start time.Time, err error) {
+	// a service not found isn't a failure of the registry
+	if err == ErrNotFound {
+		err = nil
+	}
+	tags := metrics.Tags{
+		"registry":  m.Registry.String(),
+		"operation": op,
+		"status":    metrics.Status(err),
+	}
+	m.r.Count(MetricOperations, 1, tags)
+	m.r.Timing(MetricOperationDuration, time.Since(start), tags)
+}
+
+func (m *metricsRegistry) Register(s *Service, opts ...RegisterOption) error {
+	start := time.Now()
+	err := m.Registry.Register(s, opts...)
+	m.report("register", start, err)
+	return err
+}
+
+func (m *metricsRegistry) Deregister(s *Service, opts ...DeregisterOption) error {
+	start := time.Now()
+	err := m.Registry.Deregister(s, opts...)
+	m.report("deregister", start, err)
+	return err
+}
+
+func (m *metricsRegistry) GetService(name string, opts ...GetOption) ([]*Service, error) {
+	start := time.Now()
+	services, err := m.Registry.GetService(name, opts...)
+	m.report("get_service", start, err)
+	return services, err
+}
+
+func (m *metricsRegistry) ListServices(opts ...ListOption) ([]*Service, error) {
+	start := time.Now()
+	services, err := m.Registry.ListServices(opts...)
+	m.report("list_services", start, err)
+	return services, err
+}
+
+func (m *metricsRegistry) Watch(opts ...WatchOption) (Watcher, error) {
+	start := time.Now()
+	w, err := m.Registry.Watch(opts...)
+	m.report("watch", start, err)
+	return w, err
+}
+
+// Instrument returns a registry reporting the rate, errors and duration of
+// its operations
+func Instrument(reg Registry, r metrics.Reporter) Registry {
+	return &metricsRegistry{Registry: reg, r: r}
+}
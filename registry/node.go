@@ -0,0 +1,91 @@
+package registry
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+)
+
+var (
+	// machineIdFiles are checked in order when resolving the machine id
+	machineIdFiles = []string{
+		"/etc/machine-id",
+		"/var/lib/dbus/machine-id",
+	}
+)
+
+// NodeCollisionError is returned by Register when a node id is already
+// registered for the service with a different address
+type NodeCollisionError struct {
+	// Service the node belongs to
+	Service string
+	// Id of the node
+	Id string
+	// Address of the node being registered
+	Address string
+	// Existing address registered with the id
+	Existing string
+}
+
+func (e *NodeCollisionError) Error() string {
+	return fmt.Sprintf("node id %s of service %s already registered at %s, refusing to register %s",
+		e.Id, e.Service, e.Existing, e.Address)
+}
+
+// IsNodeCollision returns true if the error is or wraps a NodeCollisionError
+func IsNodeCollision(err error) bool {
+	var e *NodeCollisionError
+	return errors.As(err, &e)
+}
+
+// MachineId returns an identifier for the host. It uses the systemd/dbus
+// machine id when available and falls back to the hostname.
+func MachineId() string {
+	for _, f := range machineIdFiles {
+		b, err := ioutil.ReadFile(f)
+		if err != nil {
+			continue
+		}
+		if id := strings.TrimSpace(string(b)); len(id) > 0 {
+			return id
+		}
+	}
+
+	host, _ := os.Hostname()
+	return host
+}
+
+// NodeId derives a stable node id from the machine id and the address the
+// node is advertised on. Unlike a random uuid the id survives restarts so
+// long as the node is started on the same host and address.
+func NodeId(address string) string {
+	h := sha256.New()
+	h.Write([]byte(MachineId()))
+	h.Write([]byte{0})
+	h.Write([]byte(address))
+	return hex.EncodeToString(h.Sum(nil))[:32]
+}
+
+// CheckCollision checks the nodes of a service against the nodes already
+// registered and returns a NodeCollisionError if an id is reused for a
+// different address.
+func CheckCollision(s *Service, existing []*Node) error {
+	for _, n := range s.Nodes {
+		for _, e := range existing {
+			if n.Id != e.Id || n.Address == e.Address {
+				continue
+			}
+			return &NodeCollisionError{
+				Service:  s.Name,
+				Id:       n.Id,
+				Address:  n.Address,
+				Existing: e.Address,
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,43 @@
+package redis
+
+import (
+	"context"
+
+	"github.com/micro/go-micro/v2/registry"
+)
+
+type passwordKey struct{}
+
+type databaseKey struct{}
+
+type prefixKey struct{}
+
+// Password to authenticate with the redis server
+func Password(p string) registry.Option {
+	return func(o *registry.Options) {
+		if o.Context == nil {
+			o.Context = context.Background()
+		}
+		o.Context = context.WithValue(o.Context, passwordKey{}, p)
+	}
+}
+
+// Database selects the redis database to use
+func Database(db int) registry.Option {
+	return func(o *registry.Options) {
+		if o.Context == nil {
+			o.Context = context.Background()
+		}
+		o.Context = context.WithValue(o.Context, databaseKey{}, db)
+	}
+}
+
+// Prefix sets the prefix of the keys and channel used by the registry
+func Prefix(p string) registry.Option {
+	return func(o *registry.Options) {
+		if o.Context == nil {
+			o.Context = context.Background()
+		}
+		o.Context = context.WithValue(o.Context, prefixKey{}, p)
+	}
+}
@@ -0,0 +1,526 @@
+// Package redis provides a redis service registry
+package redis
+
+import (
+	"encoding/json"
+	"errors"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+	"github.com/micro/go-micro/v2/logger"
+	"github.com/micro/go-micro/v2/registry"
+	hash "github.com/mitchellh/hashstructure"
+)
+
+const (
+	defaultPrefix  = "micro:registry"
+	defaultDomain  = "micro"
+	defaultAddress = "127.0.0.1:6379"
+)
+
+type redisRegistry struct {
+	options registry.Options
+	pool    *redis.Pool
+	prefix  string
+
+	// register is the hash of the nodes registered, keyed by node key
+	sync.RWMutex
+	register map[string]uint64
+}
+
+// event is published on every change to the registry
+type event struct {
+	Action  string            `json:"action"`
+	Domain  string            `json:"domain"`
+	Service *registry.Service `json:"service"`
+}
+
+// NewRegistry returns an initialized redis registry. Nodes are stored as keys
+// expiring with the register ttl and changes are published on a channel.
+func NewRegistry(opts ...registry.Option) registry.Registry {
+	r := &redisRegistry{
+		register: make(map[string]uint64),
+	}
+	configure(r, opts...)
+	return r
+}
+
+func configure(r *redisRegistry, opts ...registry.Option) error {
+	for _, o := range opts {
+		o(&r.options)
+	}
+
+	if r.options.Timeout == 0 {
+		r.options.Timeout = 5 * time.Second
+	}
+
+	r.prefix = defaultPrefix
+	if r.options.Context != nil {
+		if p, ok := r.options.Context.Value(prefixKey{}).(string); ok && len(p) > 0 {
+			r.prefix = p
+		}
+	}
+
+	if r.pool != nil {
+		r.pool.Close()
+	}
+
+	r.pool = &redis.Pool{
+		MaxIdle:     16,
+		IdleTimeout: time.Minute,
+		Dial: func() (redis.Conn, error) {
+			return r.dial(r.options.Timeout)
+		},
+	}
+
+	return nil
+}
+
+// dial connects to the first redis address. A zero read
+// timeout is used for connections which block on pub/sub.
+func (r *redisRegistry) dial(readTimeout time.Duration) (redis.Conn, error) {
+	address := defaultAddress
+	for _, a := range r.options.Addrs {
+		if len(a) > 0 {
+			address = a
+			break
+		}
+	}
+
+	dOpts := []redis.DialOption{
+		redis.DialConnectTimeout(r.options.Timeout),
+		redis.DialReadTimeout(readTimeout),
+		redis.DialWriteTimeout(r.options.Timeout),
+	}
+
+	if ctx := r.options.Context; ctx != nil {
+		if p, ok := ctx.Value(passwordKey{}).(string); ok && len(p) > 0 {
+			dOpts = append(dOpts, redis.DialPassword(p))
+		}
+		if db, ok := ctx.Value(databaseKey{}).(int); ok {
+			dOpts = append(dOpts, redis.DialDatabase(db))
+		}
+	}
+
+	if r.options.Secure || r.options.TLSConfig != nil {
+		dOpts = append(dOpts, redis.DialUseTLS(true))
+		if r.options.TLSConfig != nil {
+			dOpts = append(dOpts, redis.DialTLSConfig(r.options.TLSConfig))
+		} else {
+			dOpts = append(dOpts, redis.DialTLSSkipVerify(true))
+		}
+	}
+
+	if strings.HasPrefix(address, "redis://") || strings.HasPrefix(address, "rediss://") {
+		return redis.DialURL(address, dOpts...)
+	}
+
+	if _, _, err := net.SplitHostPort(address); err != nil {
+		address = net.JoinHostPort(address, "6379")
+	}
+
+	return redis.Dial("tcp", address, dOpts...)
+}
+
+// escape makes a key component safe to join with ':'
+func escape(s string) string {
+	s = strings.Replace(s, "%", "%25", -1)
+	return strings.Replace(s, ":", "%3A", -1)
+}
+
+func unescape(s string) string {
+	s = strings.Replace(s, "%3A", ":", -1)
+	return strings.Replace(s, "%25", "%", -1)
+}
+
+// escapeGlob escapes the characters matched by a scan pattern
+func escapeGlob(s string) string {
+	r := strings.NewReplacer(`\`, `\\`, `*`, `\*`, `?`, `\?`, `[`, `\[`, `]`, `\]`)
+	return r.Replace(s)
+}
+
+// nodeKey returns the key of a node in the form prefix:domain:service:version:node
+func (r *redisRegistry) nodeKey(domain, service, version, id string) string {
+	return strings.Join([]string{r.prefix, escape(domain), escape(service), escape(version), escape(id)}, ":")
+}
+
+// parseKey returns the domain, service, version and node id of a node key
+func (r *redisRegistry) parseKey(key string) (domain, service, version, id string, ok bool) {
+	if !strings.HasPrefix(key, r.prefix+":") {
+		return
+	}
+	parts := strings.Split(strings.TrimPrefix(key, r.prefix+":"), ":")
+	if len(parts) != 4 {
+		return
+	}
+	return unescape(parts[0]), unescape(parts[1]), unescape(parts[2]), unescape(parts[3]), true
+}
+
+// pattern returns a scan pattern for the given domain and service
+func (r *redisRegistry) pattern(domain, service string) string {
+	d := "*"
+	if domain != registry.WildcardDomain {
+		d = escapeGlob(escape(domain))
+	}
+	s := "*"
+	if len(service) > 0 {
+		s = escapeGlob(escape(service))
+	}
+	return escapeGlob(r.prefix) + ":" + d + ":" + s + ":*"
+}
+
+func (r *redisRegistry) channel() string {
+	return r.prefix + ":events"
+}
+
+func encode(s *registry.Service) []byte {
+	b, _ := json.Marshal(s)
+	return b
+}
+
+func decode(b []byte) *registry.Service {
+	var s *registry.Service
+	if err := json.Unmarshal(b, &s); err != nil {
+		return nil
+	}
+	return s
+}
+
+// scan returns the key values matching the pattern
+func scan(conn redis.Conn, pattern string) (map[string][]byte, error) {
+	var keys []string
+	cursor := 0
+
+	for {
+		vals, err := redis.Values(conn.Do("SCAN", cursor, "MATCH", pattern, "COUNT", 100))
+		if err != nil {
+			return nil, err
+		}
+		if len(vals) != 2 {
+			return nil, errors.New("unexpected scan reply")
+		}
+		cursor, _ = redis.Int(vals[0], nil)
+		k, err := redis.Strings(vals[1], nil)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, k...)
+		if cursor == 0 {
+			break
+		}
+	}
+
+	results := make(map[string][]byte, len(keys))
+	if len(keys) == 0 {
+		return results, nil
+	}
+
+	args := make([]interface{}, len(keys))
+	for i, k := range keys {
+		args[i] = k
+	}
+
+	values, err := redis.ByteSlices(conn.Do("MGET", args...))
+	if err != nil {
+		return nil, err
+	}
+
+	for i, v := range values {
+		// the key expired between the scan and get
+		if v == nil {
+			continue
+		}
+		results[keys[i]] = v
+	}
+
+	return results, nil
+}
+
+func (r *redisRegistry) registerNode(conn redis.Conn, s *registry.Service, node *registry.Node, options registry.RegisterOptions) error {
+	key := r.nodeKey(options.Domain, s.Name, s.Version, node.Id)
+
+	h, err := hash.Hash(node, nil)
+	if err != nil {
+		return err
+	}
+
+	r.RLock()
+	v, registered := r.register[key]
+	r.RUnlock()
+
+	// the node is unchanged so only refresh the ttl
+	if registered && v == h {
+		var exists bool
+		if options.TTL > 0 {
+			exists, err = redis.Bool(conn.Do("PEXPIRE", key, options.TTL.Milliseconds()))
+		} else {
+			exists, err = redis.Bool(conn.Do("EXISTS", key))
+		}
+		if err != nil {
+			return err
+		}
+		if exists {
+			if logger.V(logger.TraceLevel, logger.DefaultLogger) {
+				logger.Tracef("Service %s node %s unchanged skipping registration", s.Name, node.Id)
+			}
+			return nil
+		}
+	}
+
+	// check the id isn't used by another node of the service
+	if !registered {
+		existing, err := scan(conn, r.pattern(options.Domain, s.Name))
+		if err != nil {
+			return err
+		}
+		for k, b := range existing {
+			_, _, _, id, ok := r.parseKey(k)
+			if !ok || id != node.Id {
+				continue
+			}
+			if srv := decode(b); srv != nil {
+				if err := registry.CheckCollision(s, srv.Nodes); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	service := &registry.Service{
+		Name:      s.Name,
+		Version:   s.Version,
+		Metadata:  s.Metadata,
+		Endpoints: s.Endpoints,
+		Nodes:     []*registry.Node{node},
+	}
+
+	args := []interface{}{key, encode(service)}
+	if options.TTL > 0 {
+		args = append(args, "PX", options.TTL.Milliseconds())
+	}
+
+	exists, err := redis.Bool(conn.Do("EXISTS", key))
+	if err != nil {
+		return err
+	}
+
+	if logger.V(logger.TraceLevel, logger.DefaultLogger) {
+		logger.Tracef("Registering %s id %s with ttl %v", service.Name, node.Id, options.TTL)
+	}
+
+	if _, err := conn.Do("SET", args...); err != nil {
+		return err
+	}
+
+	action := "create"
+	if exists {
+		action = "update"
+	}
+
+	if err := r.publish(conn, action, options.Domain, service); err != nil {
+		return err
+	}
+
+	r.Lock()
+	r.register[key] = h
+	r.Unlock()
+
+	return nil
+}
+
+func (r *redisRegistry) publish(conn redis.Conn, action, domain string, s *registry.Service) error {
+	b, err := json.Marshal(&event{Action: action, Domain: domain, Service: s})
+	if err != nil {
+		return err
+	}
+	_, err = conn.Do("PUBLISH", r.channel(), b)
+	return err
+}
+
+func (r *redisRegistry) Init(opts ...registry.Option) error {
+	return configure(r, opts...)
+}
+
+func (r *redisRegistry) Options() registry.Options {
+	return r.options
+}
+
+func (r *redisRegistry) Register(s *registry.Service, opts ...registry.RegisterOption) error {
+	if len(s.Nodes) == 0 {
+		return errors.New("Require at least one node")
+	}
+
+	var options registry.RegisterOptions
+	for _, o := range opts {
+		o(&options)
+	}
+	if len(options.Domain) == 0 {
+		options.Domain = defaultDomain
+	}
+
+	// add domain to the service metadata so it can be determined when doing wildcard queries
+	if s.Metadata == nil {
+		s.Metadata = map[string]string{"domain": options.Domain}
+	} else {
+		s.Metadata["domain"] = options.Domain
+	}
+
+	conn := r.pool.Get()
+	defer conn.Close()
+
+	var gerr error
+
+	// register each node individually
+	for _, node := range s.Nodes {
+		if err := r.registerNode(conn, s, node, options); err != nil {
+			gerr = err
+		}
+	}
+
+	return gerr
+}
+
+func (r *redisRegistry) Deregister(s *registry.Service, opts ...registry.DeregisterOption) error {
+	if len(s.Nodes) == 0 {
+		return errors.New("Require at least one node")
+	}
+
+	var options registry.DeregisterOptions
+	for _, o := range opts {
+		o(&options)
+	}
+	if len(options.Domain) == 0 {
+		options.Domain = defaultDomain
+	}
+
+	conn := r.pool.Get()
+	defer conn.Close()
+
+	for _, node := range s.Nodes {
+		key := r.nodeKey(options.Domain, s.Name, s.Version, node.Id)
+
+		r.Lock()
+		delete(r.register, key)
+		r.Unlock()
+
+		if logger.V(logger.TraceLevel, logger.DefaultLogger) {
+			logger.Tracef("Deregistering %s id %s", s.Name, node.Id)
+		}
+
+		if _, err := conn.Do("DEL", key); err != nil {
+			return err
+		}
+
+		service := &registry.Service{
+			Name:      s.Name,
+			Version:   s.Version,
+			Metadata:  s.Metadata,
+			Endpoints: s.Endpoints,
+			Nodes:     []*registry.Node{node},
+		}
+
+		if err := r.publish(conn, "delete", options.Domain, service); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// services groups the nodes stored in the key values by domain, service and version
+func (r *redisRegistry) services(kvs map[string][]byte) []*registry.Service {
+	versions := make(map[string]*registry.Service)
+
+	for k, b := range kvs {
+		domain, _, _, _, ok := r.parseKey(k)
+		if !ok {
+			continue
+		}
+
+		sn := decode(b)
+		if sn == nil {
+			continue
+		}
+
+		// if a service name exists in two seperate domains it's returned twice
+		// for wildcard queries since the endpoints / metadata could differ
+		key := domain + ":" + sn.Name + ":" + sn.Version
+
+		s, ok := versions[key]
+		if !ok {
+			versions[key] = sn
+			continue
+		}
+
+		s.Nodes = append(s.Nodes, sn.Nodes...)
+	}
+
+	services := make([]*registry.Service, 0, len(versions))
+	for _, service := range versions {
+		services = append(services, service)
+	}
+
+	return services
+}
+
+func (r *redisRegistry) GetService(name string, opts ...registry.GetOption) ([]*registry.Service, error) {
+	var options registry.GetOptions
+	for _, o := range opts {
+		o(&options)
+	}
+	if len(options.Domain) == 0 {
+		options.Domain = defaultDomain
+	}
+
+	conn := r.pool.Get()
+	defer conn.Close()
+
+	kvs, err := scan(conn, r.pattern(options.Domain, name))
+	if err != nil {
+		return nil, err
+	}
+
+	services := r.services(kvs)
+	if len(services) == 0 {
+		return nil, registry.ErrNotFound
+	}
+
+	return services, nil
+}
+
+func (r *redisRegistry) ListServices(opts ...registry.ListOption) ([]*registry.Service, error) {
+	var options registry.ListOptions
+	for _, o := range opts {
+		o(&options)
+	}
+	if len(options.Domain) == 0 {
+		options.Domain = defaultDomain
+	}
+
+	conn := r.pool.Get()
+	defer conn.Close()
+
+	kvs, err := scan(conn, r.pattern(options.Domain, ""))
+	if err != nil {
+		return nil, err
+	}
+
+	services := r.services(kvs)
+
+	// sort the services
+	sort.Slice(services, func(i, j int) bool { return services[i].Name < services[j].Name })
+
+	return services, nil
+}
+
+func (r *redisRegistry) Watch(opts ...registry.WatchOption) (registry.Watcher, error) {
+	return newRedisWatcher(r, opts...)
+}
+
+func (r *redisRegistry) String() string {
+	return "redis"
+}
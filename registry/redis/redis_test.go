@@ -0,0 +1,58 @@
+package redis
+
+import (
+	"testing"
+
+	"github.com/micro/go-micro/v2/registry"
+)
+
+func TestNodeKey(t *testing.T) {
+	r := NewRegistry().(*redisRegistry)
+
+	testData := []struct {
+		domain  string
+		service string
+		version string
+		id      string
+	}{
+		{"micro", "go.micro.service.foo", "latest", "foo-123"},
+		{"micro", "foo", "1.0.0", "node:with:colons"},
+		{"a:b", "foo%3A", "v1", "100%"},
+	}
+
+	for _, d := range testData {
+		key := r.nodeKey(d.domain, d.service, d.version, d.id)
+		domain, service, version, id, ok := r.parseKey(key)
+		if !ok {
+			t.Fatalf("Failed to parse key %s", key)
+		}
+		if domain != d.domain || service != d.service || version != d.version || id != d.id {
+			t.Fatalf("Expected %s %s %s %s got %s %s %s %s", d.domain, d.service, d.version, d.id, domain, service, version, id)
+		}
+	}
+
+	if _, _, _, _, ok := r.parseKey("other:micro:foo:latest:1"); ok {
+		t.Fatal("Expected key without the prefix not to parse")
+	}
+}
+
+func TestPattern(t *testing.T) {
+	r := NewRegistry(Prefix("test")).(*redisRegistry)
+
+	testData := []struct {
+		domain  string
+		service string
+		pattern string
+	}{
+		{"micro", "foo", "test:micro:foo:*"},
+		{"micro", "", "test:micro:*:*"},
+		{registry.WildcardDomain, "foo", "test:*:foo:*"},
+		{"micro", "foo*", `test:micro:foo\*:*`},
+	}
+
+	for _, d := range testData {
+		if p := r.pattern(d.domain, d.service); p != d.pattern {
+			t.Fatalf("Expected pattern %s got %s", d.pattern, p)
+		}
+	}
+}
@@ -0,0 +1,126 @@
+package redis
+
+import (
+	"encoding/json"
+	"errors"
+	"sync"
+
+	"github.com/gomodule/redigo/redis"
+	"github.com/micro/go-micro/v2/registry"
+)
+
+// expiredPattern matches the keyspace notifications for expired keys. These
+// are only sent when enabled on the server e.g notify-keyspace-events Ex
+const expiredPattern = "__keyevent@*__:expired"
+
+type redisWatcher struct {
+	r    *redisRegistry
+	wo   registry.WatchOptions
+	conn redis.PubSubConn
+
+	sync.Mutex
+	stopped bool
+}
+
+func newRedisWatcher(r *redisRegistry, opts ...registry.WatchOption) (registry.Watcher, error) {
+	var wo registry.WatchOptions
+	for _, o := range opts {
+		o(&wo)
+	}
+	if len(wo.Domain) == 0 {
+		wo.Domain = defaultDomain
+	}
+
+	if wo.Domain == registry.WildcardDomain && len(wo.Service) > 0 {
+		return nil, errors.New("Cannot watch a service accross domains")
+	}
+
+	// pub/sub connections block on receive so don't use a read timeout
+	c, err := r.dial(0)
+	if err != nil {
+		return nil, err
+	}
+
+	conn := redis.PubSubConn{Conn: c}
+
+	if err := conn.Subscribe(r.channel()); err != nil {
+		c.Close()
+		return nil, err
+	}
+	if err := conn.PSubscribe(expiredPattern); err != nil {
+		c.Close()
+		return nil, err
+	}
+
+	return &redisWatcher{
+		r:    r,
+		wo:   wo,
+		conn: conn,
+	}, nil
+}
+
+// match returns true if the change is for a watched domain and service
+func (w *redisWatcher) match(domain, service string) bool {
+	if w.wo.Domain != registry.WildcardDomain && w.wo.Domain != domain {
+		return false
+	}
+	if len(w.wo.Service) > 0 && w.wo.Service != service {
+		return false
+	}
+	return true
+}
+
+func (w *redisWatcher) Next() (*registry.Result, error) {
+	for {
+		switch v := w.conn.ReceiveWithTimeout(0).(type) {
+		case redis.Message:
+			// a node expired
+			if len(v.Pattern) > 0 {
+				domain, service, version, id, ok := w.r.parseKey(string(v.Data))
+				if !ok || !w.match(domain, service) {
+					continue
+				}
+				return &registry.Result{
+					Action: "delete",
+					Service: &registry.Service{
+						Name:     service,
+						Version:  version,
+						Metadata: map[string]string{"domain": domain},
+						Nodes:    []*registry.Node{{Id: id}},
+					},
+				}, nil
+			}
+
+			var ev event
+			if err := json.Unmarshal(v.Data, &ev); err != nil || ev.Service == nil {
+				continue
+			}
+			if !w.match(ev.Domain, ev.Service.Name) {
+				continue
+			}
+			return &registry.Result{
+				Action:  ev.Action,
+				Service: ev.Service,
+			}, nil
+		case error:
+			w.Lock()
+			stopped := w.stopped
+			w.Unlock()
+			if stopped {
+				return nil, registry.ErrWatcherStopped
+			}
+			return nil, v
+		}
+	}
+}
+
+func (w *redisWatcher) Stop() {
+	w.Lock()
+	defer w.Unlock()
+
+	if w.stopped {
+		return
+	}
+	w.stopped = true
+	w.conn.Close()
+}
@@ -119,7 +119,7 @@ func (s *serviceRegistry) GetService(name string, opts ...registry.GetOption) ([
 		Service: name, Options: &pb.Options{Domain: options.Domain},
 	}, s.callOpts()...)
 
-	if verr, ok := err.(*errors.Error); ok && verr.Code == 404 {
+	if errors.Code(err) == 404 {
 		return nil, registry.ErrNotFound
 	} else if err != nil {
 		return nil, err
@@ -2,11 +2,22 @@ package server
 
 import (
 	"context"
+	"crypto/tls"
 	"sync"
 )
 
 type serverKey struct{}
 
+type responseMetadataKey struct{}
+
+type tlsKey struct{}
+
+// responseMetadata holds the headers returned to the caller with a response
+type responseMetadata struct {
+	sync.Mutex
+	md map[string]string
+}
+
 func wait(ctx context.Context) *sync.WaitGroup {
 	if ctx == nil {
 		return nil
@@ -26,3 +37,53 @@ func FromContext(ctx context.Context) (Server, bool) {
 func NewContext(ctx context.Context, s Server) context.Context {
 	return context.WithValue(ctx, serverKey{}, s)
 }
+
+// NewTLSContext returns a context with the state of the tls connection the
+// request is served over. It's used by server implementations so handler
+// wrappers can identify the peer by its certificate.
+func NewTLSContext(ctx context.Context, state *tls.ConnectionState) context.Context {
+	return context.WithValue(ctx, tlsKey{}, state)
+}
+
+// TLSFromContext returns the state of the tls connection the request is
+// served over
+func TLSFromContext(ctx context.Context) (*tls.ConnectionState, bool) {
+	state, ok := ctx.Value(tlsKey{}).(*tls.ConnectionState)
+	return state, ok && state != nil
+}
+
+// NewResponseMetadataContext returns a context in which handlers can set
+// response metadata. It's used by server implementations for each request.
+func NewResponseMetadataContext(ctx context.Context) context.Context {
+	return context.WithValue(ctx, responseMetadataKey{}, &responseMetadata{
+		md: make(map[string]string),
+	})
+}
+
+// ResponseMetadata returns a copy of the response metadata set in the context
+func ResponseMetadata(ctx context.Context) map[string]string {
+	rmd, ok := ctx.Value(responseMetadataKey{}).(*responseMetadata)
+	if !ok {
+		return nil
+	}
+	rmd.Lock()
+	defer rmd.Unlock()
+	md := make(map[string]string, len(rmd.md))
+	for k, v := range rmd.md {
+		md[k] = v
+	}
+	return md
+}
+
+// SetResponseMetadata sets a header which is returned to the caller along with the
+// response. It returns false if the context does not belong to a server request.
+func SetResponseMetadata(ctx context.Context, k, v string) bool {
+	rmd, ok := ctx.Value(responseMetadataKey{}).(*responseMetadata)
+	if !ok {
+		return false
+	}
+	rmd.Lock()
+	rmd.md[k] = v
+	rmd.Unlock()
+	return true
+}
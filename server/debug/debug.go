@@ -0,0 +1,276 @@
+// Package debug serves the runtime debug endpoints of a service: the pprof
+// profiles, goroutine dumps, garbage collector stats and build info. They're
+// served by the servers with the Debug option and over http with Handler.
+package debug
+
+import (
+	"bytes"
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"net/http"
+	"net/http/pprof"
+	"runtime"
+	rdebug "runtime/debug"
+	rpprof "runtime/pprof"
+	"time"
+
+	"github.com/micro/go-micro/v2/errors"
+	"github.com/micro/go-micro/v2/metadata"
+)
+
+// TokenHeader is the header of the token of the debug requests
+const TokenHeader = "Micro-Debug-Token"
+
+var (
+	// DefaultProfileSeconds is the duration of the cpu profiles if not set
+	DefaultProfileSeconds = 10
+	// MaxProfileSeconds is the max duration of the cpu profiles
+	MaxProfileSeconds = 60
+)
+
+// ProfileRequest for a pprof profile e.g heap, goroutine, allocs, block,
+// mutex, threadcreate or cpu
+type ProfileRequest struct {
+	Name string `json:"name"`
+	// Seconds the cpu profile lasts
+	Seconds int `json:"seconds"`
+	// Debug writes the profile as text rather than in the pprof format if > 0
+	Debug int `json:"debug"`
+}
+
+// ProfileResponse is the profile in the pprof format
+type ProfileResponse struct {
+	Data []byte `json:"data"`
+}
+
+// GoroutinesRequest for the dump of the goroutines
+type GoroutinesRequest struct{}
+
+// GoroutinesResponse is the stack traces of the goroutines
+type GoroutinesResponse struct {
+	Count int    `json:"count"`
+	Dump  string `json:"dump"`
+}
+
+// GCRequest for the garbage collector stats
+type GCRequest struct{}
+
+// GCResponse is the garbage collector and memory stats
+type GCResponse struct {
+	NumGC       int64           `json:"num_gc"`
+	LastGC      time.Time       `json:"last_gc"`
+	PauseTotal  time.Duration   `json:"pause_total"`
+	Pauses      []time.Duration `json:"pauses"`
+	HeapAlloc   uint64          `json:"heap_alloc"`
+	HeapSys     uint64          `json:"heap_sys"`
+	HeapObjects uint64          `json:"heap_objects"`
+	NextGC      uint64          `json:"next_gc"`
+	Goroutines  int             `json:"goroutines"`
+}
+
+// BuildRequest for the build info
+type BuildRequest struct{}
+
+// BuildResponse is the build info of the service
+type BuildResponse struct {
+	Service   string `json:"service"`
+	Version   string `json:"version"`
+	GoVersion string `json:"go_version"`
+	// Path and version of the main module
+	Path          string `json:"path"`
+	ModuleVersion string `json:"module_version"`
+	// Deps are the versions of the modules of the dependencies
+	Deps map[string]string `json:"deps"`
+}
+
+// Runtime is a handler serving the debug endpoints. It's JSON encoded.
+type Runtime struct {
+	service string
+	version string
+	token   string
+}
+
+func (r *Runtime) authorize(ctx context.Context) error {
+	// the endpoints are never served without a token
+	if len(r.token) == 0 {
+		return errors.Unauthorized("go.micro.server", "debug token not set")
+	}
+	token, _ := metadata.Get(ctx, TokenHeader)
+	if subtle.ConstantTimeCompare([]byte(token), []byte(r.token)) != 1 {
+		return errors.Unauthorized("go.micro.server", "invalid debug token")
+	}
+	return nil
+}
+
+func (r *Runtime) profile(ctx context.Context, req *ProfileRequest) ([]byte, error) {
+	buf := new(bytes.Buffer)
+
+	if req.Name != "cpu" && req.Name != "profile" {
+		p := rpprof.Lookup(req.Name)
+		if p == nil {
+			return nil, errors.NotFound("go.micro.server", "unknown profile %s", req.Name)
+		}
+		if err := p.WriteTo(buf, req.Debug); err != nil {
+			return nil, errors.InternalServerError("go.micro.server", "writing profile: %v", err)
+		}
+		return buf.Bytes(), nil
+	}
+
+	secs := req.Seconds
+	if secs <= 0 {
+		secs = DefaultProfileSeconds
+	}
+	if secs > MaxProfileSeconds {
+		secs = MaxProfileSeconds
+	}
+
+	if err := rpprof.StartCPUProfile(buf); err != nil {
+		return nil, errors.Conflict("go.micro.server", "starting cpu profile: %v", err)
+	}
+
+	// the profile is cut short if the caller gives up
+	t := time.NewTimer(time.Duration(secs) * time.Second)
+	select {
+	case <-t.C:
+	case <-ctx.Done():
+		t.Stop()
+	}
+	rpprof.StopCPUProfile()
+
+	return buf.Bytes(), nil
+}
+
+// Profile returns the pprof profile of the request
+func (r *Runtime) Profile(ctx context.Context, req *ProfileRequest, rsp *ProfileResponse) error {
+	if err := r.authorize(ctx); err != nil {
+		return err
+	}
+	data, err := r.profile(ctx, req)
+	if err != nil {
+		return err
+	}
+	rsp.Data = data
+	return nil
+}
+
+// Goroutines returns the stack traces of all the goroutines
+func (r *Runtime) Goroutines(ctx context.Context, req *GoroutinesRequest, rsp *GoroutinesResponse) error {
+	if err := r.authorize(ctx); err != nil {
+		return err
+	}
+	buf := new(bytes.Buffer)
+	if err := rpprof.Lookup("goroutine").WriteTo(buf, 2); err != nil {
+		return errors.InternalServerError("go.micro.server", "writing goroutines: %v", err)
+	}
+	rsp.Count = runtime.NumGoroutine()
+	rsp.Dump = buf.String()
+	return nil
+}
+
+// GC returns the garbage collector and memory stats
+func (r *Runtime) GC(ctx context.Context, req *GCRequest, rsp *GCResponse) error {
+	if err := r.authorize(ctx); err != nil {
+		return err
+	}
+
+	var gc rdebug.GCStats
+	rdebug.ReadGCStats(&gc)
+
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+
+	rsp.NumGC = gc.NumGC
+	rsp.LastGC = gc.LastGC
+	rsp.PauseTotal = gc.PauseTotal
+	rsp.Pauses = gc.Pause
+	rsp.HeapAlloc = mem.HeapAlloc
+	rsp.HeapSys = mem.HeapSys
+	rsp.HeapObjects = mem.HeapObjects
+	rsp.NextGC = mem.NextGC
+	rsp.Goroutines = runtime.NumGoroutine()
+	return nil
+}
+
+// Build returns the build info of the service
+func (r *Runtime) Build(ctx context.Context, req *BuildRequest, rsp *BuildResponse) error {
+	if err := r.authorize(ctx); err != nil {
+		return err
+	}
+
+	rsp.Service = r.service
+	rsp.Version = r.version
+	rsp.GoVersion = runtime.Version()
+
+	if info, ok := rdebug.ReadBuildInfo(); ok {
+		rsp.Path = info.Main.Path
+		rsp.ModuleVersion = info.Main.Version
+		rsp.Deps = make(map[string]string, len(info.Deps))
+		for _, dep := range info.Deps {
+			rsp.Deps[dep.Path] = dep.Version
+		}
+	}
+	return nil
+}
+
+// NewRuntime returns a debug handler of the service. The requests must have
+// the token in their Micro-Debug-Token metadata, they're all rejected if
+// it's blank.
+func NewRuntime(service, version, token string) *Runtime {
+	return &Runtime{
+		service: service,
+		version: version,
+		token:   token,
+	}
+}
+
+// Handler serves the pprof profiles at /debug/pprof/ and the other debug
+// endpoints of the runtime at /debug/runtime/goroutines, gc and build over
+// http e.g on the http transport or a mux listener. The requests must have
+// the token of the runtime in their Micro-Debug-Token header.
+func Handler(r *Runtime) http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+
+	serve := func(fn func(ctx context.Context) (interface{}, error)) http.HandlerFunc {
+		return func(w http.ResponseWriter, req *http.Request) {
+			rsp, err := fn(req.Context())
+			if err != nil {
+				verr := errors.FromError(err)
+				http.Error(w, verr.Detail, int(verr.Code))
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(rsp)
+		}
+	}
+
+	mux.HandleFunc("/debug/runtime/goroutines", serve(func(ctx context.Context) (interface{}, error) {
+		rsp := new(GoroutinesResponse)
+		return rsp, r.Goroutines(ctx, &GoroutinesRequest{}, rsp)
+	}))
+	mux.HandleFunc("/debug/runtime/gc", serve(func(ctx context.Context) (interface{}, error) {
+		rsp := new(GCResponse)
+		return rsp, r.GC(ctx, &GCRequest{}, rsp)
+	}))
+	mux.HandleFunc("/debug/runtime/build", serve(func(ctx context.Context) (interface{}, error) {
+		rsp := new(BuildResponse)
+		return rsp, r.Build(ctx, &BuildRequest{}, rsp)
+	}))
+
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		ctx := metadata.NewContext(req.Context(), map[string]string{
+			TokenHeader: req.Header.Get(TokenHeader),
+		})
+		if err := r.authorize(ctx); err != nil {
+			http.Error(w, "invalid debug token", http.StatusUnauthorized)
+			return
+		}
+		mux.ServeHTTP(w, req.WithContext(ctx))
+	})
+}
@@ -0,0 +1,134 @@
+package debug_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/micro/go-micro/v2/broker"
+	bmemory "github.com/micro/go-micro/v2/broker/memory"
+	"github.com/micro/go-micro/v2/client"
+	"github.com/micro/go-micro/v2/errors"
+	"github.com/micro/go-micro/v2/metadata"
+	rmemory "github.com/micro/go-micro/v2/registry/memory"
+	"github.com/micro/go-micro/v2/server"
+	"github.com/micro/go-micro/v2/server/debug"
+	tmemory "github.com/micro/go-micro/v2/transport/memory"
+)
+
+func TestRuntime(t *testing.T) {
+	reg := rmemory.NewRegistry()
+	brk := bmemory.NewBroker(broker.Registry(reg))
+	tr := tmemory.NewTransport()
+
+	srv := server.NewServer(
+		server.Name("go.micro.service.debug"),
+		server.Version("1.0.0"),
+		server.Registry(reg),
+		server.Broker(brk),
+		server.Transport(tr),
+		server.Debug("secret"),
+	)
+	if err := srv.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer srv.Stop()
+
+	cli := client.NewClient(
+		client.Registry(reg),
+		client.Broker(brk),
+		client.Transport(tr),
+	)
+
+	req := cli.NewRequest("go.micro.service.debug", "Runtime.Build", &debug.BuildRequest{}, client.WithContentType("application/json"))
+
+	// the token is required
+	rsp := new(debug.BuildResponse)
+	if err := cli.Call(context.Background(), req, rsp); errors.Code(err) != 401 {
+		t.Fatalf("Expected a 401 error got %v", err)
+	}
+
+	ctx := metadata.Set(context.Background(), debug.TokenHeader, "secret")
+	if err := cli.Call(ctx, req, rsp); err != nil {
+		t.Fatal(err)
+	}
+	if rsp.Service != "go.micro.service.debug" || rsp.Version != "1.0.0" || len(rsp.GoVersion) == 0 {
+		t.Fatalf("Unexpected build info %+v", rsp)
+	}
+
+	// a heap profile
+	preq := cli.NewRequest("go.micro.service.debug", "Runtime.Profile", &debug.ProfileRequest{Name: "heap"}, client.WithContentType("application/json"))
+	prsp := new(debug.ProfileResponse)
+	if err := cli.Call(ctx, preq, prsp); err != nil {
+		t.Fatal(err)
+	}
+	if len(prsp.Data) == 0 {
+		t.Fatal("Expected the heap profile")
+	}
+}
+
+func TestHandler(t *testing.T) {
+	h := debug.Handler(debug.NewRuntime("foo", "1.0.0", "secret"))
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/debug/runtime/gc", nil))
+	if w.Code != http.StatusUnauthorized {
+		t.Fatalf("Expected a 401 got %d", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	r := httptest.NewRequest("GET", "/debug/runtime/gc", nil)
+	r.Header.Set(debug.TokenHeader, "secret")
+	h.ServeHTTP(w, r)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected a 200 got %d: %s", w.Code, w.Body.String())
+	}
+
+	var rsp debug.GCResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &rsp); err != nil {
+		t.Fatal(err)
+	}
+	if rsp.Goroutines == 0 || rsp.HeapAlloc == 0 {
+		t.Fatalf("Unexpected gc stats %+v", rsp)
+	}
+}
+
+func TestBlankToken(t *testing.T) {
+	h := debug.Handler(debug.NewRuntime("foo", "1.0.0", ""))
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/debug/pprof/", nil))
+	if w.Code != http.StatusUnauthorized {
+		t.Fatalf("Expected a 401 got %d", w.Code)
+	}
+
+	reg := rmemory.NewRegistry()
+	brk := bmemory.NewBroker(broker.Registry(reg))
+	tr := tmemory.NewTransport()
+
+	srv := server.NewServer(
+		server.Name("go.micro.service.debug"),
+		server.Registry(reg),
+		server.Broker(brk),
+		server.Transport(tr),
+		server.Debug(""),
+	)
+	if err := srv.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer srv.Stop()
+
+	cli := client.NewClient(
+		client.Registry(reg),
+		client.Broker(brk),
+		client.Transport(tr),
+	)
+
+	// the endpoints aren't served
+	req := cli.NewRequest("go.micro.service.debug", "Runtime.Build", &debug.BuildRequest{}, client.WithContentType("application/json"))
+	if err := cli.Call(context.Background(), req, new(debug.BuildResponse)); err == nil {
+		t.Fatal("Expected the debug endpoints not to be served")
+	}
+}
@@ -5,6 +5,7 @@ import (
 
 	"github.com/micro/go-micro/v2/errors"
 	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 func microError(err *errors.Error) codes.Code {
@@ -32,11 +33,30 @@ func microError(err *errors.Error) codes.Code {
 		return codes.FailedPrecondition
 	case http.StatusNotImplemented:
 		return codes.Unimplemented
+	case http.StatusTooManyRequests:
+		return codes.ResourceExhausted
 	case http.StatusInternalServerError:
 		return codes.Internal
 	case http.StatusServiceUnavailable:
 		return codes.Unavailable
+	case http.StatusGatewayTimeout:
+		return codes.DeadlineExceeded
 	}
 
 	return codes.Unknown
 }
+
+// statusError converts the error to a grpc status with the error in its details,
+// the message of the status being the detail of the error in interop mode
+func statusError(err error, interop bool) error {
+	verr := errors.FromError(err)
+	msg := verr.Error()
+	if interop {
+		msg = verr.Detail
+	}
+	st, serr := status.New(microError(verr), msg).WithDetails(verr)
+	if serr != nil {
+		return serr
+	}
+	return st.Err()
+}
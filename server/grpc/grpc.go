@@ -21,6 +21,8 @@ import (
 	meta "github.com/micro/go-micro/v2/metadata"
 	"github.com/micro/go-micro/v2/registry"
 	"github.com/micro/go-micro/v2/server"
+	mdebug "github.com/micro/go-micro/v2/server/debug"
+	"github.com/micro/go-micro/v2/server/reflection"
 	"github.com/micro/go-micro/v2/util/addr"
 	"github.com/micro/go-micro/v2/util/backoff"
 	mgrpc "github.com/micro/go-micro/v2/util/grpc"
@@ -31,6 +33,8 @@ import (
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/encoding"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
 	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/peer"
 	"google.golang.org/grpc/status"
@@ -51,6 +55,11 @@ type grpcServer struct {
 	srv  *grpc.Server
 	exit chan chan error
 	wg   *sync.WaitGroup
+	// workers handling the unary requests, nil
+	// if they're handled by the grpc goroutines
+	workers *server.WorkerPool
+	// grpc.health.v1 health checking
+	health *health.Server
 
 	sync.RWMutex
 	opts        server.Options
@@ -84,6 +93,7 @@ func newGRPCServer(opts ...server.Option) server.Server {
 		subscribers: make(map[*subscriber][]broker.Subscriber),
 		exit:        make(chan chan error),
 		wg:          wait(options.Context),
+		health:      health.NewServer(),
 	}
 
 	// configure the grpc server
@@ -118,6 +128,13 @@ func (g *grpcServer) configure(opts ...server.Option) {
 		o(&g.opts)
 	}
 
+	g.rsvc = nil
+
+	// the options are reloaded without replacing the grpc server serving
+	if g.started {
+		return
+	}
+
 	maxMsgSize := g.getMaxMsgSize()
 
 	gopts := []grpc.ServerOption{
@@ -134,8 +151,10 @@ func (g *grpcServer) configure(opts ...server.Option) {
 		gopts = append(gopts, opts...)
 	}
 
-	g.rsvc = nil
 	g.srv = grpc.NewServer(gopts...)
+
+	// serve the standard health checking protocol
+	healthpb.RegisterHealthServer(g.srv, g.health)
 }
 
 func (g *grpcServer) getMaxMsgSize() int {
@@ -171,6 +190,22 @@ func (g *grpcServer) getGrpcOptions() []grpc.ServerOption {
 	return opts
 }
 
+func (g *grpcServer) interop() bool {
+	if g.opts.Context == nil {
+		return false
+	}
+	v, _ := g.opts.Context.Value(interopKey{}).(bool)
+	return v
+}
+
+// statusMessage returns the message of the grpc status of the error
+func (g *grpcServer) statusMessage(err *errors.Error) string {
+	if g.interop() {
+		return err.Detail
+	}
+	return err.Error()
+}
+
 func (g *grpcServer) getListener() net.Listener {
 	if g.opts.Context == nil {
 		return nil
@@ -184,6 +219,9 @@ func (g *grpcServer) getListener() net.Listener {
 }
 
 func (g *grpcServer) handler(srv interface{}, stream grpc.ServerStream) error {
+	// the time the request was received to measure its queue delay
+	received := time.Now()
+
 	if g.wg != nil {
 		g.wg.Add(1)
 		defer g.wg.Done()
@@ -245,8 +283,20 @@ func (g *grpcServer) handler(srv interface{}, stream grpc.ServerStream) error {
 		}
 	}
 
+	// the options may be reloaded while the request is served
+	g.RLock()
+	opts := g.opts
+	g.RUnlock()
+
+	// rate limit the requests and shed load
+	release, err := opts.Limiter.Acquire(ctx, fmt.Sprintf("%s.%s", serviceName, methodName), received)
+	if err != nil {
+		return statusError(err, g.interop())
+	}
+	defer release()
+
 	// process via router
-	if g.opts.Router != nil {
+	if opts.Router != nil {
 		cc, err := g.newGRPCCodec(ct)
 		if err != nil {
 			return errors.InternalServerError("go.micro.server", err.Error())
@@ -254,7 +304,7 @@ func (g *grpcServer) handler(srv interface{}, stream grpc.ServerStream) error {
 		codec := &grpcCodec{
 			method:   fmt.Sprintf("%s.%s", serviceName, methodName),
 			endpoint: fmt.Sprintf("%s.%s", serviceName, methodName),
-			target:   g.opts.Name,
+			target:   opts.Name,
 			s:        stream,
 			c:        cc,
 		}
@@ -275,12 +325,12 @@ func (g *grpcServer) handler(srv interface{}, stream grpc.ServerStream) error {
 
 		// create a wrapped function
 		handler := func(ctx context.Context, req server.Request, rsp interface{}) error {
-			return g.opts.Router.ServeRequest(ctx, req, rsp.(server.Response))
+			return opts.Router.ServeRequest(ctx, req, rsp.(server.Response))
 		}
 
 		// execute the wrapper for it
-		for i := len(g.opts.HdlrWrappers); i > 0; i-- {
-			handler = g.opts.HdlrWrappers[i-1](handler)
+		for i := len(opts.HdlrWrappers); i > 0; i-- {
+			handler = opts.HdlrWrappers[i-1](handler)
 		}
 
 		r := grpcRouter{h: handler}
@@ -312,14 +362,29 @@ func (g *grpcServer) handler(srv interface{}, stream grpc.ServerStream) error {
 
 	// process unary
 	if !mtype.stream {
-		return g.processRequest(stream, service, mtype, ct, ctx)
+		g.RLock()
+		workers := g.workers
+		g.RUnlock()
+
+		if workers == nil {
+			return g.processRequest(stream, service, mtype, ct, ctx, opts)
+		}
+
+		// handle the request with a worker
+		errCh := make(chan error, 1)
+		if err := workers.Go(func() {
+			errCh <- g.processRequest(stream, service, mtype, ct, ctx, opts)
+		}); err != nil {
+			return statusError(err, g.interop())
+		}
+		return <-errCh
 	}
 
 	// process stream
-	return g.processStream(stream, service, mtype, ct, ctx)
+	return g.processStream(stream, service, mtype, ct, ctx, opts)
 }
 
-func (g *grpcServer) processRequest(stream grpc.ServerStream, service *service, mtype *methodType, ct string, ctx context.Context) error {
+func (g *grpcServer) processRequest(stream grpc.ServerStream, service *service, mtype *methodType, ct string, ctx context.Context, opts server.Options) error {
 	for {
 		var argv, replyv reflect.Value
 
@@ -345,7 +410,6 @@ func (g *grpcServer) processRequest(stream grpc.ServerStream, service *service,
 		replyv = reflect.New(mtype.ReplyType.Elem())
 
 		function := mtype.method.Func
-		var returnValues []reflect.Value
 
 		cc, err := g.newGRPCCodec(ct)
 		if err != nil {
@@ -358,13 +422,16 @@ func (g *grpcServer) processRequest(stream grpc.ServerStream, service *service,
 
 		// create a client.Request
 		r := &rpcRequest{
-			service:     g.opts.Name,
+			service:     opts.Name,
 			contentType: ct,
 			method:      fmt.Sprintf("%s.%s", service.name, mtype.method.Name),
 			body:        b,
 			payload:     argv.Interface(),
 		}
 
+		// collect headers set by the handler for the response
+		ctx = server.NewResponseMetadataContext(ctx)
+
 		// define the handler func
 		fn := func(ctx context.Context, req server.Request, rsp interface{}) (err error) {
 			defer func() {
@@ -376,7 +443,14 @@ func (g *grpcServer) processRequest(stream grpc.ServerStream, service *service,
 					err = errors.InternalServerError("go.micro.server", "panic recovered: %v", r)
 				}
 			}()
-			returnValues = function.Call([]reflect.Value{service.rcvr, mtype.prepareContext(ctx), reflect.ValueOf(argv.Interface()), reflect.ValueOf(rsp)})
+
+			// don't start work the caller has given up on
+			if err := ctx.Err(); err != nil {
+				return errors.Timeout("go.micro.server", "deadline exceeded before handling the request: %v", err)
+			}
+
+			// local as the handler may outlive the request once timed out
+			returnValues := function.Call([]reflect.Value{service.rcvr, mtype.prepareContext(ctx), reflect.ValueOf(argv.Interface()), reflect.ValueOf(rsp)})
 
 			// The return value for the method is an error.
 			if rerr := returnValues[0].Interface(); rerr != nil {
@@ -386,20 +460,27 @@ func (g *grpcServer) processRequest(stream grpc.ServerStream, service *service,
 			return err
 		}
 
+		// enforce the execution timeout of the endpoint
+		fn = server.TimeoutHandler(opts.Timeout(r.Endpoint()))(fn)
+
 		// wrap the handler func
-		for i := len(g.opts.HdlrWrappers); i > 0; i-- {
-			fn = g.opts.HdlrWrappers[i-1](fn)
+		for i := len(opts.HdlrWrappers); i > 0; i-- {
+			fn = opts.HdlrWrappers[i-1](fn)
 		}
 		statusCode := codes.OK
 		statusDesc := ""
 		// execute the handler
 		if appErr := fn(ctx, r, replyv.Interface()); appErr != nil {
 			var errStatus *status.Status
+			// send the code and details of the error it wraps
+			if verr, ok := errors.As(appErr); ok {
+				appErr = verr
+			}
 			switch verr := appErr.(type) {
 			case *errors.Error:
 				// micro.Error now proto based and we can attach it to grpc status
 				statusCode = microError(verr)
-				statusDesc = verr.Error()
+				statusDesc = g.statusMessage(verr)
 				errStatus, err = status.New(statusCode, statusDesc).WithDetails(verr)
 				if err != nil {
 					return err
@@ -422,6 +503,13 @@ func (g *grpcServer) processRequest(stream grpc.ServerStream, service *service,
 			return errStatus.Err()
 		}
 
+		// set the response metadata
+		if md := server.ResponseMetadata(ctx); len(md) > 0 {
+			if err := stream.SetHeader(metadata.New(md)); err != nil {
+				return err
+			}
+		}
+
 		if err := stream.SendMsg(replyv.Interface()); err != nil {
 			return err
 		}
@@ -429,8 +517,7 @@ func (g *grpcServer) processRequest(stream grpc.ServerStream, service *service,
 	}
 }
 
-func (g *grpcServer) processStream(stream grpc.ServerStream, service *service, mtype *methodType, ct string, ctx context.Context) error {
-	opts := g.opts
+func (g *grpcServer) processStream(stream grpc.ServerStream, service *service, mtype *methodType, ct string, ctx context.Context, opts server.Options) error {
 
 	r := &rpcRequest{
 		service:     opts.Name,
@@ -439,11 +526,15 @@ func (g *grpcServer) processStream(stream grpc.ServerStream, service *service, m
 		stream:      true,
 	}
 
-	ss := &rpcStream{
+	var ss server.Stream = &rpcStream{
 		request: r,
 		s:       stream,
 	}
 
+	for i := len(opts.StreamWrappers); i > 0; i-- {
+		ss = opts.StreamWrappers[i-1](ss)
+	}
+
 	function := mtype.method.Func
 	var returnValues []reflect.Value
 
@@ -467,11 +558,15 @@ func (g *grpcServer) processStream(stream grpc.ServerStream, service *service, m
 	if appErr := fn(ctx, r, ss); appErr != nil {
 		var err error
 		var errStatus *status.Status
+		// send the code and details of the error it wraps
+		if verr, ok := errors.As(appErr); ok {
+			appErr = verr
+		}
 		switch verr := appErr.(type) {
 		case *errors.Error:
 			// micro.Error now proto based and we can attach it to grpc status
 			statusCode = microError(verr)
-			statusDesc = verr.Error()
+			statusDesc = g.statusMessage(verr)
 			errStatus, err = status.New(statusCode, statusDesc).WithDetails(verr)
 			if err != nil {
 				return err
@@ -566,6 +661,53 @@ func (g *grpcServer) Subscribe(sb server.Subscriber) error {
 	return nil
 }
 
+// endpoints returns the endpoints advertised by the server, the lock is held by the caller
+func (g *grpcServer) endpoints() []*registry.Endpoint {
+	// Maps are ordered randomly, sort the keys for consistency
+	var handlerList []string
+	for n, e := range g.handlers {
+		// Only advertise non internal handlers
+		if !e.Options().Internal {
+			handlerList = append(handlerList, n)
+		}
+	}
+	sort.Strings(handlerList)
+
+	var subscriberList []*subscriber
+	for e := range g.subscribers {
+		// Only advertise non internal subscribers
+		if !e.Options().Internal {
+			subscriberList = append(subscriberList, e)
+		}
+	}
+	sort.Slice(subscriberList, func(i, j int) bool {
+		return subscriberList[i].topic > subscriberList[j].topic
+	})
+
+	endpoints := make([]*registry.Endpoint, 0, len(handlerList)+len(subscriberList))
+	for _, n := range handlerList {
+		endpoints = append(endpoints, g.handlers[n].Endpoints()...)
+	}
+	for _, e := range subscriberList {
+		endpoints = append(endpoints, e.Endpoints()...)
+	}
+
+	return endpoints
+}
+
+// describe returns the service served with its endpoints and metadata
+func (g *grpcServer) describe() *registry.Service {
+	g.RLock()
+	defer g.RUnlock()
+
+	return &registry.Service{
+		Name:      g.opts.Name,
+		Version:   g.opts.Version,
+		Metadata:  meta.Copy(g.opts.Metadata),
+		Endpoints: g.endpoints(),
+	}
+}
+
 func (g *grpcServer) Register() error {
 	g.RLock()
 	rsvc := g.rsvc
@@ -655,34 +797,7 @@ func (g *grpcServer) Register() error {
 	node.Metadata["protocol"] = "grpc"
 
 	g.RLock()
-	// Maps are ordered randomly, sort the keys for consistency
-	var handlerList []string
-	for n, e := range g.handlers {
-		// Only advertise non internal handlers
-		if !e.Options().Internal {
-			handlerList = append(handlerList, n)
-		}
-	}
-	sort.Strings(handlerList)
-
-	var subscriberList []*subscriber
-	for e := range g.subscribers {
-		// Only advertise non internal subscribers
-		if !e.Options().Internal {
-			subscriberList = append(subscriberList, e)
-		}
-	}
-	sort.Slice(subscriberList, func(i, j int) bool {
-		return subscriberList[i].topic > subscriberList[j].topic
-	})
-
-	endpoints := make([]*registry.Endpoint, 0, len(handlerList)+len(subscriberList))
-	for _, n := range handlerList {
-		endpoints = append(endpoints, g.handlers[n].Endpoints()...)
-	}
-	for _, e := range subscriberList {
-		endpoints = append(endpoints, e.Endpoints()...)
-	}
+	endpoints := g.endpoints()
 	g.RUnlock()
 
 	service := &registry.Service{
@@ -730,6 +845,19 @@ func (g *grpcServer) Register() error {
 			opts = append(opts, broker.DisableAutoAck())
 		}
 
+		if topic := sb.Options().DeadLetterTopic; len(topic) > 0 {
+			attempts := sb.Options().MaxAttempts
+			// the attempts are retried by the server with a backoff
+			if sb.Options().Backoff != nil {
+				attempts = 1
+			}
+			opts = append(opts, broker.DeadLetter(topic, attempts))
+		}
+
+		if sb.Options().Ordered {
+			opts = append(opts, broker.Ordered())
+		}
+
 		if logger.V(logger.InfoLevel, logger.DefaultLogger) {
 			logger.Infof("Subscribing to topic: %s", sb.Topic())
 		}
@@ -809,7 +937,14 @@ func (g *grpcServer) Deregister() error {
 	}
 
 	g.registered = false
+	g.unsubscribe()
+
+	g.Unlock()
+	return nil
+}
 
+// unsubscribe closes the subscribers, the lock must be held
+func (g *grpcServer) unsubscribe() {
 	wg := sync.WaitGroup{}
 	for sb, subs := range g.subscribers {
 		for _, sub := range subs {
@@ -825,9 +960,6 @@ func (g *grpcServer) Deregister() error {
 		g.subscribers[sb] = nil
 	}
 	wg.Wait()
-
-	g.Unlock()
-	return nil
 }
 
 func (g *grpcServer) Start() error {
@@ -871,6 +1003,12 @@ func (g *grpcServer) Start() error {
 	}
 	g.Lock()
 	g.opts.Address = ts.Addr().String()
+	if g.opts.StableId {
+		g.opts.Id = registry.NodeId(g.opts.Address)
+		if len(g.opts.Advertise) > 0 {
+			g.opts.Id = registry.NodeId(g.opts.Advertise)
+		}
+	}
 	g.Unlock()
 
 	// only connect if we're subscribed
@@ -888,6 +1026,36 @@ func (g *grpcServer) Start() error {
 		}
 	}
 
+	// serve the description of the service unless a
+	// handler with the same name has been registered
+	g.RLock()
+	_, ok := g.handlers["Reflection"]
+	g.RUnlock()
+	if !ok {
+		if err := g.Handle(g.NewHandler(reflection.NewReflection(g.describe), server.InternalHandler(true))); err != nil {
+			if logger.V(logger.ErrorLevel, logger.DefaultLogger) {
+				logger.Errorf("Server reflection handler error: %v", err)
+			}
+		}
+	}
+
+	// serve the runtime debug endpoints if enabled
+	g.RLock()
+	_, ok = g.handlers["Runtime"]
+	g.RUnlock()
+	if config.Debug && !ok && len(config.DebugToken) == 0 {
+		if logger.V(logger.ErrorLevel, logger.DefaultLogger) {
+			logger.Error("Server debug endpoints not served without a token")
+		}
+	} else if config.Debug && !ok {
+		rt := mdebug.NewRuntime(config.Name, config.Version, config.DebugToken)
+		if err := g.Handle(g.NewHandler(rt, server.InternalHandler(true))); err != nil {
+			if logger.V(logger.ErrorLevel, logger.DefaultLogger) {
+				logger.Errorf("Server debug handler error: %v", err)
+			}
+		}
+	}
+
 	// announce self to the world
 	if err := g.Register(); err != nil {
 		if logger.V(logger.ErrorLevel, logger.DefaultLogger) {
@@ -895,6 +1063,18 @@ func (g *grpcServer) Start() error {
 		}
 	}
 
+	// report the server and service as serving
+	g.health.Resume()
+	g.health.SetServingStatus("", healthpb.HealthCheckResponse_SERVING)
+	g.health.SetServingStatus(config.Name, healthpb.HealthCheckResponse_SERVING)
+
+	// handle the requests with a pool of workers
+	if config.Workers > 0 {
+		g.Lock()
+		g.workers = server.NewWorkerPool(config.Workers, config.WorkerQueue, config.WorkerQueueTimeout)
+		g.Unlock()
+	}
+
 	// micro: go ts.Accept(s.accept)
 	go func() {
 		if err := g.srv.Serve(ts); err != nil {
@@ -932,34 +1112,54 @@ func (g *grpcServer) Start() error {
 			}
 		}
 
-		// deregister self
-		if err := g.Deregister(); err != nil {
+		// fail health checks while draining
+		g.health.Shutdown()
+
+		g.RLock()
+		keep := g.opts.KeepRegistered
+		g.RUnlock()
+		if keep {
+			// the node is deregistered by the caller of stop
+			g.Lock()
+			g.unsubscribe()
+			g.Unlock()
+		} else if err := g.Deregister(); err != nil {
+			// deregister self
 			if logger.V(logger.ErrorLevel, logger.DefaultLogger) {
 				logger.Error("Server deregister error: ", err)
 			}
 		}
 
-		// wait for waitgroup
-		if g.wg != nil {
-			g.wg.Wait()
-		}
-
-		// stop the grpc server
+		// stop accepting requests and wait for the ones in flight
 		exit := make(chan bool)
 
 		go func() {
 			g.srv.GracefulStop()
+			if g.wg != nil {
+				g.wg.Wait()
+			}
 			close(exit)
 		}()
 
+		var after <-chan time.Time
+		if config.DrainTimeout > 0 {
+			after = time.After(config.DrainTimeout)
+		}
+
 		select {
 		case <-exit:
-		case <-time.After(time.Second):
+		case <-after:
+			if logger.V(logger.WarnLevel, logger.DefaultLogger) {
+				logger.Warn("Server drain timeout exceeded, closing the connections")
+			}
 			g.srv.Stop()
 		}
 
-		// close transport
-		ch <- nil
+		// stop the workers
+		g.Lock()
+		g.workers.Stop()
+		g.workers = nil
+		g.Unlock()
 
 		if logger.V(logger.InfoLevel, logger.DefaultLogger) {
 			logger.Infof("Broker [%s] Disconnected from %s", config.Broker.String(), config.Broker.Address())
@@ -970,6 +1170,9 @@ func (g *grpcServer) Start() error {
 				logger.Errorf("Broker [%s] disconnect error: %v", config.Broker.String(), err)
 			}
 		}
+
+		// close transport
+		ch <- nil
 	}()
 
 	// mark the server as started
@@ -3,6 +3,7 @@ package grpc_test
 import (
 	"context"
 	"fmt"
+	"net"
 	"testing"
 
 	"github.com/micro/go-micro/v2"
@@ -15,6 +16,9 @@ import (
 	gsrv "github.com/micro/go-micro/v2/server/grpc"
 	tgrpc "github.com/micro/go-micro/v2/transport/grpc"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
 	"google.golang.org/grpc/status"
 
 	pb "github.com/micro/go-micro/v2/server/grpc/proto"
@@ -201,3 +205,95 @@ func TestGRPCServer(t *testing.T) {
 		}
 	}
 }
+
+func TestGRPCHealth(t *testing.T) {
+	r := rmemory.NewRegistry()
+	s := gsrv.NewServer(
+		server.Broker(bmemory.NewBroker()),
+		server.Name("foo"),
+		server.Registry(r),
+	)
+
+	if err := s.Start(); err != nil {
+		t.Fatalf("failed to start: %v", err)
+	}
+	defer s.Stop()
+
+	cc, err := grpc.Dial(s.Options().Address, grpc.WithInsecure())
+	if err != nil {
+		t.Fatalf("failed to dial server: %v", err)
+	}
+	defer cc.Close()
+
+	hc := healthpb.NewHealthClient(cc)
+
+	for _, service := range []string{"", "foo"} {
+		rsp, err := hc.Check(context.Background(), &healthpb.HealthCheckRequest{Service: service})
+		if err != nil {
+			t.Fatalf("health check of %q failed: %v", service, err)
+		}
+		if rsp.Status != healthpb.HealthCheckResponse_SERVING {
+			t.Fatalf("Expected %q to be serving got %v", service, rsp.Status)
+		}
+	}
+
+	_, err = hc.Check(context.Background(), &healthpb.HealthCheckRequest{Service: "bar"})
+	if status.Code(err) != codes.NotFound {
+		t.Fatalf("Expected not found for unknown service got %v", err)
+	}
+}
+
+func TestGRPCInterop(t *testing.T) {
+	r := rmemory.NewRegistry()
+	s := gsrv.NewServer(
+		server.Broker(bmemory.NewBroker()),
+		server.Name("foo"),
+		server.Registry(r),
+		gsrv.Interop(),
+	)
+	pb.RegisterTestHandler(s, &testServer{})
+
+	if err := s.Start(); err != nil {
+		t.Fatalf("failed to start: %v", err)
+	}
+	defer s.Stop()
+
+	cc, err := grpc.Dial(s.Options().Address, grpc.WithInsecure())
+	if err != nil {
+		t.Fatalf("failed to dial server: %v", err)
+	}
+	defer cc.Close()
+
+	// the vanilla clients read the detail of the errors
+	err = cc.Invoke(context.Background(), "/test.Test/Call", &pb.Request{Name: "Error"}, &pb.Response{})
+	if st, ok := status.FromError(err); !ok || st.Message() != "detail" {
+		t.Fatalf("Expected the detail of the error got %v", err)
+	}
+
+	// the micro clients call the vanilla servers
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	gs := grpc.NewServer()
+	hs := health.NewServer()
+	hs.SetServingStatus("bar", healthpb.HealthCheckResponse_SERVING)
+	healthpb.RegisterHealthServer(gs, hs)
+	go gs.Serve(lis)
+	defer gs.Stop()
+
+	c := gcli.NewClient(client.Registry(r))
+	req := c.NewRequest("grpc.health.v1", "Health.Check", &healthpb.HealthCheckRequest{Service: "bar"})
+	rsp := new(healthpb.HealthCheckResponse)
+	if err := c.Call(context.Background(), req, rsp, client.WithAddress(lis.Addr().String())); err != nil {
+		t.Fatal(err)
+	}
+	if rsp.Status != healthpb.HealthCheckResponse_SERVING {
+		t.Fatalf("Expected the vanilla server to be serving got %v", rsp.Status)
+	}
+
+	req = c.NewRequest("grpc.health.v1", "Health.Check", &healthpb.HealthCheckRequest{Service: "baz"})
+	if err := c.Call(context.Background(), req, rsp, client.WithAddress(lis.Addr().String())); errors.Code(err) != 404 {
+		t.Fatalf("Expected a 404 error got %v", err)
+	}
+}
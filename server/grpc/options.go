@@ -21,6 +21,7 @@ type netListener struct{}
 type maxMsgSizeKey struct{}
 type maxConnKey struct{}
 type tlsAuth struct{}
+type interopKey struct{}
 
 // gRPC Codec to be used to encode/decode requests for a given content type
 func Codec(contentType string, c encoding.Codec) server.Option {
@@ -52,6 +53,13 @@ func Listener(l net.Listener) server.Option {
 	return setServerOption(netListener{}, l)
 }
 
+// Interop sets the message of the grpc statuses of the errors to their detail
+// rather than the JSON encoded error so they're readable by vanilla grpc clients.
+// The error is still sent in the details of the statuses for the micro clients.
+func Interop() server.Option {
+	return setServerOption(interopKey{}, true)
+}
+
 // Options to be used to configure gRPC options
 func Options(opts ...grpc.ServerOption) server.Option {
 	return setServerOption(grpcOptions{}, opts)
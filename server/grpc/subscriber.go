@@ -8,6 +8,7 @@ import (
 	"strings"
 
 	"github.com/micro/go-micro/v2/broker"
+	"github.com/micro/go-micro/v2/debug/trace"
 	"github.com/micro/go-micro/v2/errors"
 	"github.com/micro/go-micro/v2/logger"
 	"github.com/micro/go-micro/v2/metadata"
@@ -201,6 +202,7 @@ func (g *grpcServer) createSubHandler(sb *subscriber, opts server.Options) broke
 		}
 		delete(hdr, "Content-Type")
 		ctx := metadata.NewContext(context.Background(), hdr)
+		ctx = trace.Extract(ctx, hdr)
 
 		results := make(chan error, len(sb.handlers))
 
@@ -246,6 +248,8 @@ func (g *grpcServer) createSubHandler(sb *subscriber, opts server.Options) broke
 				fn = opts.SubWrappers[i-1](fn)
 			}
 
+			fn = server.RetrySubscriber(sb.opts)(fn)
+
 			if g.wg != nil {
 				g.wg.Add(1)
 			}
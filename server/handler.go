@@ -1,6 +1,9 @@
 package server
 
-import "context"
+import (
+	"context"
+	"time"
+)
 
 type HandlerOption func(*HandlerOptions)
 
@@ -17,7 +20,17 @@ type SubscriberOptions struct {
 	AutoAck  bool
 	Queue    string
 	Internal bool
-	Context  context.Context
+	// DeadLetterTopic receives the messages the handler
+	// failed to process MaxAttempts times.
+	DeadLetterTopic string
+	MaxAttempts     int
+	// Backoff returns the delay before retrying the handler
+	// failing on the attempt. The handler isn't retried if nil.
+	Backoff func(attempt int) time.Duration
+	// Ordered handles the messages with the same
+	// partition key one at a time in order.
+	Ordered bool
+	Context context.Context
 }
 
 // EndpointMetadata is a Handler option that allows metadata to be added to
@@ -72,6 +85,36 @@ func SubscriberQueue(n string) SubscriberOption {
 	}
 }
 
+// SubscriberDeadLetter publishes the messages the subscriber failed to
+// process after the number of attempts to the dead letter topic
+func SubscriberDeadLetter(topic string, attempts int) SubscriberOption {
+	return func(o *SubscriberOptions) {
+		o.DeadLetterTopic = topic
+		o.MaxAttempts = attempts
+	}
+}
+
+// SubscriberRetry retries the handler failing to process a message up to the
+// number of attempts, waiting for the backoff between them e.g
+//
+//	server.SubscriberRetry(5, broker.ExponentialBackoff(time.Second, time.Minute))
+//
+// The message is then nacked, or dead lettered if the subscriber has a dead letter topic.
+func SubscriberRetry(attempts int, backoff func(attempt int) time.Duration) SubscriberOption {
+	return func(o *SubscriberOptions) {
+		o.MaxAttempts = attempts
+		o.Backoff = backoff
+	}
+}
+
+// SubscriberOrdered handles the messages with the same partition key
+// one at a time in the order they're received
+func SubscriberOrdered() SubscriberOption {
+	return func(o *SubscriberOptions) {
+		o.Ordered = true
+	}
+}
+
 // SubscriberContext set context options to allow broker SubscriberOption passed
 func SubscriberContext(ctx context.Context) SubscriberOption {
 	return func(o *SubscriberOptions) {
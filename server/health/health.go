@@ -0,0 +1,55 @@
+// Package health bridges the grpc.health.v1 health checking protocol to the rpc server
+package health
+
+import (
+	"context"
+	"sync"
+
+	"github.com/micro/go-micro/v2/errors"
+	pb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// Health is a handler serving grpc.health.v1.Health.Check. Services are
+// unknown until their status is set. The empty service name is the
+// status of the server as a whole.
+type Health struct {
+	sync.RWMutex
+	status map[string]pb.HealthCheckResponse_ServingStatus
+}
+
+// SetServingStatus sets the status of a service
+func (h *Health) SetServingStatus(service string, status pb.HealthCheckResponse_ServingStatus) {
+	h.Lock()
+	h.status[service] = status
+	h.Unlock()
+}
+
+// Shutdown sets every service as not serving
+func (h *Health) Shutdown() {
+	h.Lock()
+	for service := range h.status {
+		h.status[service] = pb.HealthCheckResponse_NOT_SERVING
+	}
+	h.Unlock()
+}
+
+// Check returns the status of the requested service
+func (h *Health) Check(ctx context.Context, req *pb.HealthCheckRequest, rsp *pb.HealthCheckResponse) error {
+	h.RLock()
+	status, ok := h.status[req.Service]
+	h.RUnlock()
+
+	if !ok {
+		return errors.NotFound("grpc.health.v1.Health", "unknown service %s", req.Service)
+	}
+
+	rsp.Status = status
+	return nil
+}
+
+// NewHealth returns a health handler
+func NewHealth() *Health {
+	return &Health{
+		status: make(map[string]pb.HealthCheckResponse_ServingStatus),
+	}
+}
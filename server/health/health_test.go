@@ -0,0 +1,35 @@
+package health
+
+import (
+	"context"
+	"testing"
+
+	"github.com/micro/go-micro/v2/errors"
+	pb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+func TestHealth(t *testing.T) {
+	h := NewHealth()
+
+	var rsp pb.HealthCheckResponse
+	err := h.Check(context.TODO(), &pb.HealthCheckRequest{Service: "foo"}, &rsp)
+	if verr, ok := err.(*errors.Error); !ok || verr.Code != 404 {
+		t.Fatalf("Expected not found got %v", err)
+	}
+
+	h.SetServingStatus("foo", pb.HealthCheckResponse_SERVING)
+	if err := h.Check(context.TODO(), &pb.HealthCheckRequest{Service: "foo"}, &rsp); err != nil {
+		t.Fatal(err)
+	}
+	if rsp.Status != pb.HealthCheckResponse_SERVING {
+		t.Fatalf("Expected serving got %v", rsp.Status)
+	}
+
+	h.Shutdown()
+	if err := h.Check(context.TODO(), &pb.HealthCheckRequest{Service: "foo"}, &rsp); err != nil {
+		t.Fatal(err)
+	}
+	if rsp.Status != pb.HealthCheckResponse_NOT_SERVING {
+		t.Fatalf("Expected not serving got %v", rsp.Status)
+	}
+}
@@ -0,0 +1,240 @@
+package server
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/micro/go-micro/v2/errors"
+	"golang.org/x/time/rate"
+)
+
+// DefaultShedRetryAfter is the time the callers of an overloaded
+// server are asked to wait before retrying if not set
+var DefaultShedRetryAfter = time.Second
+
+// Limit is the limit of the requests served
+type Limit struct {
+	// Rate is the max number of requests per second, zero is unlimited
+	Rate float64 `json:"rate"`
+	// Burst is the number of requests which can be served at once above the rate
+	Burst int `json:"burst"`
+	// MaxInFlight is the max number of requests in progress, zero is unlimited
+	MaxInFlight int `json:"max_in_flight"`
+}
+
+// Shedding rejects the requests while the server is overloaded
+type Shedding struct {
+	// MaxConcurrency is the max number of requests handled at once. The
+	// others queue until they can be handled, zero is unlimited.
+	MaxConcurrency int
+	// MaxQueueDelay is the max time requests wait to be handled before
+	// they're rejected. Zero rejects the requests above the concurrency
+	// without queueing them.
+	MaxQueueDelay time.Duration
+	// RetryAfter is the time the callers are asked to wait before retrying
+	RetryAfter time.Duration
+}
+
+// Limiter enforces the limits of the requests to each endpoint
+// and sheds load when the server is overloaded
+type Limiter struct {
+	sync.Mutex
+	limits    map[string]Limit
+	endpoints map[string]*endpointLimiter
+	shedding  Shedding
+	// requests being handled while shedding
+	running chan struct{}
+}
+
+type endpointLimiter struct {
+	rate     *rate.Limiter
+	inflight chan struct{}
+}
+
+// Set the limit of the requests to the endpoint e.g Foo.Bar.
+// The limit of the "*" endpoint applies to all the requests.
+func (l *Limiter) Set(endpoint string, limit Limit) {
+	l.Lock()
+	defer l.Unlock()
+	l.limits[endpoint] = limit
+	delete(l.endpoints, endpoint)
+}
+
+// Reset replaces the limits of all the endpoints
+func (l *Limiter) Reset(limits map[string]Limit) {
+	l.Lock()
+	defer l.Unlock()
+	l.limits = make(map[string]Limit, len(limits))
+	for endpoint, limit := range limits {
+		l.limits[endpoint] = limit
+	}
+	l.endpoints = make(map[string]*endpointLimiter)
+}
+
+// Shed the load of the server with the thresholds
+func (l *Limiter) Shed(s Shedding) {
+	l.Lock()
+	defer l.Unlock()
+	if s.RetryAfter <= 0 {
+		s.RetryAfter = DefaultShedRetryAfter
+	}
+	l.shedding = s
+	l.running = nil
+	if s.MaxConcurrency > 0 {
+		l.running = make(chan struct{}, s.MaxConcurrency)
+	}
+}
+
+func (l *Limiter) get(endpoint string) *endpointLimiter {
+	l.Lock()
+	defer l.Unlock()
+
+	if e, ok := l.endpoints[endpoint]; ok {
+		return e
+	}
+
+	// endpoints without a limit are stored as nil
+	var e *endpointLimiter
+	if limit, ok := l.limits[endpoint]; ok {
+		e = new(endpointLimiter)
+		if limit.Rate > 0 {
+			burst := limit.Burst
+			if burst < 1 {
+				burst = 1
+			}
+			e.rate = rate.NewLimiter(rate.Limit(limit.Rate), burst)
+		}
+		if limit.MaxInFlight > 0 {
+			e.inflight = make(chan struct{}, limit.MaxInFlight)
+		}
+	}
+	l.endpoints[endpoint] = e
+
+	return e
+}
+
+func (e *endpointLimiter) acquire(endpoint string) (func(), error) {
+	if e == nil {
+		return noRelease, nil
+	}
+
+	if e.rate != nil {
+		if r := e.rate.Reserve(); !r.OK() || r.Delay() > 0 {
+			d := r.Delay()
+			r.Cancel()
+			return nil, errors.TooManyRequests("go.micro.server", d, "rate limit of %s exceeded", endpoint)
+		}
+	}
+
+	if e.inflight == nil {
+		return noRelease, nil
+	}
+
+	select {
+	case e.inflight <- struct{}{}:
+	default:
+		return nil, errors.TooManyRequests("go.micro.server", 0, "max in flight requests of %s exceeded", endpoint)
+	}
+
+	return func() {
+		<-e.inflight
+	}, nil
+}
+
+// shed waits for the request received at the time to be handled
+// or rejects it if the server is overloaded
+func (l *Limiter) shed(ctx context.Context, received time.Time) (func(), error) {
+	l.Lock()
+	s := l.shedding
+	running := l.running
+	l.Unlock()
+
+	overloaded := func() error {
+		var load float32 = 1
+		if running != nil {
+			load = float32(len(running)) / float32(cap(running))
+		}
+		return errors.Overloaded("go.micro.server", s.RetryAfter, load, "server overloaded")
+	}
+
+	// the request already waited too long
+	delay := s.MaxQueueDelay - time.Since(received)
+	if s.MaxQueueDelay > 0 && delay <= 0 {
+		return nil, overloaded()
+	}
+
+	if running == nil {
+		return noRelease, nil
+	}
+
+	release := func() {
+		<-running
+	}
+
+	select {
+	case running <- struct{}{}:
+		return release, nil
+	default:
+	}
+
+	if s.MaxQueueDelay <= 0 {
+		return nil, overloaded()
+	}
+
+	// queue the request up to the max delay
+	t := time.NewTimer(delay)
+	defer t.Stop()
+
+	select {
+	case running <- struct{}{}:
+		return release, nil
+	case <-t.C:
+		return nil, overloaded()
+	case <-ctx.Done():
+		return nil, errors.Timeout("go.micro.server", "queued request: %v", ctx.Err())
+	}
+}
+
+func noRelease() {}
+
+// Acquire the permission to handle a request to the endpoint received at
+// the time. The func returned releases it once the request is handled.
+func (l *Limiter) Acquire(ctx context.Context, endpoint string, received time.Time) (func(), error) {
+	if l == nil {
+		return noRelease, nil
+	}
+
+	var releases []func()
+	release := func() {
+		for _, r := range releases {
+			r()
+		}
+	}
+
+	for _, e := range []string{"*", endpoint} {
+		r, err := l.get(e).acquire(endpoint)
+		if err != nil {
+			release()
+			return nil, err
+		}
+		releases = append(releases, r)
+	}
+
+	r, err := l.shed(ctx, received)
+	if err != nil {
+		release()
+		return nil, err
+	}
+	releases = append(releases, r)
+
+	return release, nil
+}
+
+// NewLimiter returns a limiter without any limits
+func NewLimiter() *Limiter {
+	return &Limiter{
+		limits:    make(map[string]Limit),
+		endpoints: make(map[string]*endpointLimiter),
+	}
+}
@@ -0,0 +1,72 @@
+package server
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/micro/go-micro/v2/errors"
+)
+
+func TestLimiterLimits(t *testing.T) {
+	l := NewLimiter()
+	l.Set("Foo.Bar", Limit{Rate: 1})
+	l.Set("*", Limit{MaxInFlight: 2})
+
+	release, err := l.Acquire(context.TODO(), "Foo.Bar", time.Now())
+	if err != nil {
+		t.Fatalf("Unexpected err: %v", err)
+	}
+
+	// rejected above the rate of the endpoint
+	_, err = l.Acquire(context.TODO(), "Foo.Bar", time.Now())
+	if e := errors.FromError(err); e.Code != 429 || e.RetryAfter <= 0 {
+		t.Fatalf("Expected a 429 error with a retry after got %v", err)
+	}
+
+	// rejected above the in flight requests of the server
+	if _, err := l.Acquire(context.TODO(), "Foo.Baz", time.Now()); err != nil {
+		t.Fatalf("Unexpected err: %v", err)
+	}
+	if _, err := l.Acquire(context.TODO(), "Foo.Baz", time.Now()); errors.FromError(err).Code != 429 {
+		t.Fatalf("Expected a 429 error got %v", err)
+	}
+
+	release()
+	if _, err := l.Acquire(context.TODO(), "Foo.Baz", time.Now()); err != nil {
+		t.Fatalf("Unexpected err: %v", err)
+	}
+}
+
+func TestLimiterShed(t *testing.T) {
+	l := NewLimiter()
+	l.Shed(Shedding{MaxConcurrency: 1, MaxQueueDelay: 50 * time.Millisecond})
+
+	release, err := l.Acquire(context.TODO(), "Foo.Bar", time.Now())
+	if err != nil {
+		t.Fatalf("Unexpected err: %v", err)
+	}
+
+	// queued until the request running is done
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		release()
+	}()
+	release, err = l.Acquire(context.TODO(), "Foo.Bar", time.Now())
+	if err != nil {
+		t.Fatalf("Expected the request to be queued got %v", err)
+	}
+
+	// rejected once queued for too long
+	_, err = l.Acquire(context.TODO(), "Foo.Bar", time.Now())
+	if e := errors.FromError(err); e.Code != 503 || e.Load != 1 || e.RetryAfter != DefaultShedRetryAfter.Milliseconds() {
+		t.Fatalf("Expected a 503 error with the load got %v", err)
+	}
+	release()
+
+	// rejected if received too long ago
+	_, err = l.Acquire(context.TODO(), "Foo.Bar", time.Now().Add(-time.Second))
+	if errors.FromError(err).Code != 503 {
+		t.Fatalf("Expected a 503 error got %v", err)
+	}
+}
@@ -0,0 +1,60 @@
+package server
+
+import (
+	"context"
+	"time"
+
+	"github.com/micro/go-micro/v2/metrics"
+)
+
+const (
+	// MetricRequests is the count of the requests handled by endpoint and status
+	MetricRequests = "micro_server_requests"
+	// MetricRequestDuration is the timing of the requests handled
+	MetricRequestDuration = "micro_server_request_duration"
+	// MetricMessages is the count of the messages handled by topic and status
+	MetricMessages = "micro_server_messages"
+	// MetricMessageDuration is the timing of the messages handled
+	MetricMessageDuration = "micro_server_message_duration"
+)
+
+// InstrumentHandler reports the rate, errors and duration of the requests
+// handled by each endpoint
+func InstrumentHandler(r metrics.Reporter) HandlerWrapper {
+	return func(h HandlerFunc) HandlerFunc {
+		return func(ctx context.Context, req Request, rsp interface{}) error {
+			start := time.Now()
+			err := h(ctx, req, rsp)
+
+			tags := metrics.Tags{
+				"service":  req.Service(),
+				"endpoint": req.Endpoint(),
+				"status":   metrics.Status(err),
+			}
+			r.Count(MetricRequests, 1, tags)
+			r.Timing(MetricRequestDuration, time.Since(start), tags)
+
+			return err
+		}
+	}
+}
+
+// InstrumentSubscriber reports the rate, errors and duration of the
+// messages handled by each topic
+func InstrumentSubscriber(r metrics.Reporter) SubscriberWrapper {
+	return func(fn SubscriberFunc) SubscriberFunc {
+		return func(ctx context.Context, msg Message) error {
+			start := time.Now()
+			err := fn(ctx, msg)
+
+			tags := metrics.Tags{
+				"topic":  msg.Topic(),
+				"status": metrics.Status(err),
+			}
+			r.Count(MetricMessages, 1, tags)
+			r.Timing(MetricMessageDuration, time.Since(start), tags)
+
+			return err
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/micro/go-micro/v2/metrics"
+)
+
+type testReporter struct {
+	sync.Mutex
+	counts  map[string]int64
+	timings int
+}
+
+func (r *testReporter) Count(id string, value int64, tags metrics.Tags) error {
+	r.Lock()
+	defer r.Unlock()
+	r.counts[id+":"+tags["endpoint"]+tags["topic"]+":"+tags["status"]] += value
+	return nil
+}
+
+func (r *testReporter) Gauge(id string, value float64, tags metrics.Tags) error {
+	return nil
+}
+
+func (r *testReporter) Timing(id string, value time.Duration, tags metrics.Tags) error {
+	r.Lock()
+	defer r.Unlock()
+	r.timings++
+	return nil
+}
+
+func (r *testReporter) String() string {
+	return "test"
+}
+
+type testMessage struct {
+	Message
+}
+
+func (m testMessage) Topic() string {
+	return "notes.created"
+}
+
+func TestInstrument(t *testing.T) {
+	r := &testReporter{counts: make(map[string]int64)}
+
+	fail := errors.New("failed")
+	h := InstrumentHandler(r)(func(ctx context.Context, req Request, rsp interface{}) error {
+		if req.Endpoint() == "Notes.Delete" {
+			return fail
+		}
+		return nil
+	})
+	h(context.TODO(), &rpcRequest{service: "notes", endpoint: "Notes.List"}, nil)
+	h(context.TODO(), &rpcRequest{service: "notes", endpoint: "Notes.List"}, nil)
+	if err := h(context.TODO(), &rpcRequest{service: "notes", endpoint: "Notes.Delete"}, nil); err != fail {
+		t.Fatalf("Expected the error of the handler got %v", err)
+	}
+
+	s := InstrumentSubscriber(r)(func(ctx context.Context, msg Message) error {
+		return nil
+	})
+	s(context.TODO(), testMessage{})
+
+	for k, v := range map[string]int64{
+		MetricRequests + ":Notes.List:success":    2,
+		MetricRequests + ":Notes.Delete:failure":  1,
+		MetricMessages + ":notes.created:success": 1,
+	} {
+		if r.counts[k] != v {
+			t.Fatalf("Expected %s to be %d got %d", k, v, r.counts[k])
+		}
+	}
+	if r.timings != 4 {
+		t.Fatalf("Expected 4 timings got %d", r.timings)
+	}
+}
@@ -0,0 +1,92 @@
+// Package mux serves the rpc protocol, grpc and plain http on a single
+// listener by sniffing the first bytes of the connections e.g
+//
+//	m, _ := mux.Listen(":8080")
+//
+//	rpc := server.NewServer(server.Transport(transport.NewTransport(thttp.Listener(m.RPC()))))
+//	srv := grpc.NewServer(grpc.Listener(m.GRPC()))
+//	go http.Serve(m.HTTP(), handler)
+//
+//	go m.Serve()
+package mux
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/soheilhy/cmux"
+)
+
+// Mux splits the connections of a listener by protocol
+type Mux struct {
+	cmux cmux.CMux
+	grpc net.Listener
+	rpc  net.Listener
+	http net.Listener
+}
+
+// matchRPC matches the http requests of the rpc protocol which have the micro headers
+func matchRPC(r io.Reader) bool {
+	req, err := http.ReadRequest(bufio.NewReader(r))
+	if err != nil {
+		return false
+	}
+	for k := range req.Header {
+		if strings.HasPrefix(k, "Micro-") || strings.HasPrefix(k, "X-Micro-") {
+			return true
+		}
+	}
+	return false
+}
+
+// GRPC returns the listener of the grpc connections
+// to serve with the grpc server Listener option
+func (m *Mux) GRPC() net.Listener {
+	return m.grpc
+}
+
+// RPC returns the listener of the connections of the rpc protocol
+// to serve with the http transport Listener option
+func (m *Mux) RPC() net.Listener {
+	return m.rpc
+}
+
+// HTTP returns the listener of the other connections e.g plain http
+func (m *Mux) HTTP() net.Listener {
+	return m.http
+}
+
+// Serve accepts the connections and passes them to the listener of their
+// protocol until the listener is closed. Closing any of the listeners of the
+// protocols closes the listener.
+func (m *Mux) Serve() error {
+	err := m.cmux.Serve()
+	if err != nil && strings.Contains(err.Error(), "use of closed network connection") {
+		return nil
+	}
+	return err
+}
+
+// New returns a mux of the connections accepted by the listener
+func New(l net.Listener) *Mux {
+	m := cmux.New(l)
+
+	return &Mux{
+		cmux: m,
+		grpc: m.MatchWithWriters(cmux.HTTP2MatchHeaderFieldPrefixSendSettings("content-type", "application/grpc")),
+		rpc:  m.Match(matchRPC),
+		http: m.Match(cmux.Any()),
+	}
+}
+
+// Listen on the tcp address and return a mux of its connections
+func Listen(addr string) (*Mux, error) {
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	return New(l), nil
+}
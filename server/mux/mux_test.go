@@ -0,0 +1,101 @@
+package mux
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"testing"
+
+	"github.com/micro/go-micro/v2/broker"
+	bmemory "github.com/micro/go-micro/v2/broker/memory"
+	"github.com/micro/go-micro/v2/client"
+	rmemory "github.com/micro/go-micro/v2/registry/memory"
+	"github.com/micro/go-micro/v2/server"
+	gsrv "github.com/micro/go-micro/v2/server/grpc"
+	"github.com/micro/go-micro/v2/transport"
+	thttp "github.com/micro/go-micro/v2/transport/http"
+	"google.golang.org/grpc"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+func TestMux(t *testing.T) {
+	m, err := Listen("127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	reg := rmemory.NewRegistry()
+	brk := bmemory.NewBroker(broker.Registry(reg))
+	tr := transport.NewTransport(thttp.Listener(m.RPC()))
+
+	rpc := server.NewServer(
+		server.Name("go.micro.service.rpc"),
+		server.Registry(reg),
+		server.Broker(brk),
+		server.Transport(tr),
+	)
+	if err := rpc.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer rpc.Stop()
+
+	srv := gsrv.NewServer(
+		server.Name("go.micro.service.grpc"),
+		server.Registry(reg),
+		server.Broker(brk),
+		gsrv.Listener(m.GRPC()),
+	)
+	if err := srv.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer srv.Stop()
+
+	go http.Serve(m.HTTP(), http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+
+	go m.Serve()
+
+	addr := m.RPC().Addr().String()
+
+	// the rpc protocol
+	cli := client.NewClient(
+		client.Registry(reg),
+		client.Broker(brk),
+		client.Transport(transport.NewTransport()),
+	)
+	req := cli.NewRequest("go.micro.service.rpc", "Health.Check", &healthpb.HealthCheckRequest{})
+	rsp := new(healthpb.HealthCheckResponse)
+	if err := cli.Call(context.Background(), req, rsp, client.WithAddress(addr)); err != nil {
+		t.Fatal(err)
+	}
+	if rsp.Status != healthpb.HealthCheckResponse_SERVING {
+		t.Fatalf("Expected the rpc server to be serving got %v", rsp.Status)
+	}
+
+	// grpc
+	cc, err := grpc.Dial(addr, grpc.WithInsecure())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cc.Close()
+
+	grsp, err := healthpb.NewHealthClient(cc).Check(context.Background(), &healthpb.HealthCheckRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if grsp.Status != healthpb.HealthCheckResponse_SERVING {
+		t.Fatalf("Expected the grpc server to be serving got %v", grsp.Status)
+	}
+
+	// plain http
+	hrsp, err := http.Get("http://" + addr + "/debug")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer hrsp.Body.Close()
+	b, _ := ioutil.ReadAll(hrsp.Body)
+	if string(b) != "ok" {
+		t.Fatalf("Expected the http response got %s", b)
+	}
+}
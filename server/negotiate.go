@@ -0,0 +1,98 @@
+package server
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// parseAccept returns the content types of an accept header sorted by
+// their quality and then order, without the ones of quality zero
+func parseAccept(accept string) []string {
+	type accepted struct {
+		contentType string
+		q           float64
+	}
+
+	var types []accepted
+	for _, part := range strings.Split(accept, ",") {
+		params := strings.Split(part, ";")
+		ct := strings.TrimSpace(params[0])
+		if len(ct) == 0 {
+			continue
+		}
+		q := 1.0
+		for _, p := range params[1:] {
+			p = strings.TrimSpace(p)
+			if !strings.HasPrefix(p, "q=") {
+				continue
+			}
+			if v, err := strconv.ParseFloat(p[2:], 64); err == nil {
+				q = v
+			}
+		}
+		if q <= 0 {
+			continue
+		}
+		types = append(types, accepted{ct, q})
+	}
+
+	sort.SliceStable(types, func(i, j int) bool {
+		return types[i].q > types[j].q
+	})
+
+	cts := make([]string, 0, len(types))
+	for _, t := range types {
+		cts = append(cts, t.contentType)
+	}
+	return cts
+}
+
+// negotiate returns the content type of the response to a request of the
+// content type. It's the first accepted content type which is supported and
+// offered by the endpoint if it has a list, else the content type of the
+// request if offered, else the first offered by the endpoint.
+func negotiate(accept, contentType string, offered []string, supported func(string) bool) string {
+	ok := func(ct string) bool {
+		if len(offered) == 0 {
+			return supported(ct)
+		}
+		for _, o := range offered {
+			if o == ct {
+				return supported(ct)
+			}
+		}
+		return false
+	}
+
+	for _, ct := range parseAccept(accept) {
+		if ct == "*/*" {
+			break
+		}
+		if ok(ct) {
+			return ct
+		}
+	}
+
+	if len(offered) == 0 || ok(contentType) {
+		return contentType
+	}
+	for _, ct := range offered {
+		if supported(ct) {
+			return ct
+		}
+	}
+	return contentType
+}
+
+// negotiate returns the content type of the response to a request of the endpoint
+func (s *rpcServer) negotiate(endpoint, contentType, accept string) string {
+	s.RLock()
+	offered := s.opts.EndpointContentTypes[endpoint]
+	s.RUnlock()
+
+	return negotiate(accept, contentType, offered, func(ct string) bool {
+		_, err := s.newCodec(ct)
+		return err == nil
+	})
+}
@@ -0,0 +1,82 @@
+package server_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/micro/go-micro/v2/broker"
+	bmemory "github.com/micro/go-micro/v2/broker/memory"
+	"github.com/micro/go-micro/v2/client"
+	"github.com/micro/go-micro/v2/metadata"
+	rmemory "github.com/micro/go-micro/v2/registry/memory"
+	"github.com/micro/go-micro/v2/server"
+	tmemory "github.com/micro/go-micro/v2/transport/memory"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+func TestNegotiate(t *testing.T) {
+	reg := rmemory.NewRegistry()
+	brk := bmemory.NewBroker(broker.Registry(reg))
+	tr := tmemory.NewTransport()
+
+	srv := server.NewServer(
+		server.Name("go.micro.service.negotiate"),
+		server.Registry(reg),
+		server.Broker(brk),
+		server.Transport(tr),
+	)
+	if err := srv.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer srv.Stop()
+
+	cli := client.NewClient(
+		client.Registry(reg),
+		client.Broker(brk),
+		client.Transport(tr),
+	)
+
+	call := func(accept []string) string {
+		req := cli.NewRequest("go.micro.service.negotiate", "Health.Check", &healthpb.HealthCheckRequest{}, client.WithContentType("application/protobuf"))
+		rsp := new(healthpb.HealthCheckResponse)
+
+		var md metadata.Metadata
+		if err := cli.Call(context.Background(), req, rsp, client.WithResponseMetadata(&md), client.WithAccept(accept...)); err != nil {
+			t.Fatalf("%v: %v", accept, err)
+		}
+		if rsp.Status != healthpb.HealthCheckResponse_SERVING {
+			t.Fatalf("%v: expected serving got %v", accept, rsp.Status)
+		}
+		return md["Content-Type"]
+	}
+
+	testData := []struct {
+		accept      []string
+		contentType string
+	}{
+		// the content type of the request by default
+		{nil, "application/protobuf"},
+		{[]string{"application/json"}, "application/json"},
+		{[]string{"application/unknown", "application/json;q=0.5"}, "application/json"},
+		{[]string{"application/json;q=0.1", "application/grpc+proto;q=0.9"}, "application/grpc+proto"},
+		{[]string{"application/json;q=0"}, "application/protobuf"},
+		{[]string{"application/unknown"}, "application/protobuf"},
+	}
+
+	for _, d := range testData {
+		if ct := call(d.accept); ct != d.contentType {
+			t.Fatalf("%v: expected %s got %s", d.accept, d.contentType, ct)
+		}
+	}
+
+	// the endpoint overrides the content types
+	if err := srv.Init(server.EndpointContentType("Health.Check", "application/json")); err != nil {
+		t.Fatal(err)
+	}
+	if ct := call(nil); ct != "application/json" {
+		t.Fatalf("Expected application/json got %s", ct)
+	}
+	if ct := call([]string{"application/grpc+proto"}); ct != "application/json" {
+		t.Fatalf("Expected application/json got %s", ct)
+	}
+}
@@ -30,7 +30,12 @@ type Options struct {
 	Version      string
 	HdlrWrappers []HandlerWrapper
 	SubWrappers  []SubscriberWrapper
+	// StreamWrappers wrap the streams of the handlers
+	// to intercept the messages sent and received
+	StreamWrappers []StreamWrapper
 
+	// StableId derives the id from the host and address on start
+	StableId bool
 	// RegisterCheck runs a check function before registering the service
 	RegisterCheck func(context.Context) error
 	// The register expiry time
@@ -41,6 +46,42 @@ type Options struct {
 	// The router for requests
 	Router Router
 
+	// Limits of the requests to each endpoint and load shedding
+	Limiter *Limiter
+
+	// HandlerTimeout is the max execution time of the handlers, zero is unlimited
+	HandlerTimeout time.Duration
+	// EndpointTimeouts are the execution timeouts of endpoints overriding the HandlerTimeout
+	EndpointTimeouts map[string]time.Duration
+	// EndpointContentTypes are the content types the endpoints respond with
+	EndpointContentTypes map[string][]string
+
+	// Workers is the max number of requests handled at once by a pool of
+	// workers, zero handles each request in its own goroutine
+	Workers int
+	// WorkerQueue is the number of requests queued for a free worker
+	WorkerQueue int
+	// WorkerQueueTimeout is the time requests wait for room in a full queue
+	// before they're rejected, zero rejects them at once
+	WorkerQueueTimeout time.Duration
+
+	// Debug serves the runtime debug endpoints e.g the pprof profiles,
+	// the requests must have the DebugToken which mustn't be blank
+	Debug      bool
+	DebugToken string
+
+	// DrainTimeout is the time to wait for the requests in
+	// flight on shutdown, zero waits for them without limit
+	DrainTimeout time.Duration
+	// KeepRegistered leaves the node registered when the server stops
+	KeepRegistered bool
+
+	// StreamBuffer is the number of messages buffered by each stream
+	StreamBuffer int
+	// StreamNoWait fails the sends of the streams whose clients didn't
+	// acknowledge the messages of their window rather than blocking them
+	StreamNoWait bool
+
 	// TLSConfig specifies tls.Config for secure serving
 	TLSConfig *tls.Config
 
@@ -55,6 +96,7 @@ func newOptions(opt ...Option) Options {
 		Metadata:         map[string]string{},
 		RegisterInterval: DefaultRegisterInterval,
 		RegisterTTL:      DefaultRegisterTTL,
+		DrainTimeout:     DefaultDrainTimeout,
 	}
 
 	for _, o := range opt {
@@ -121,6 +163,14 @@ func Id(id string) Option {
 	}
 }
 
+// StableId derives the server id from the machine id and the advertised
+// address when the server starts, so that it is preserved across restarts
+func StableId() Option {
+	return func(o *Options) {
+		o.StableId = true
+	}
+}
+
 // Version of the service
 func Version(v string) Option {
 	return func(o *Options) {
@@ -214,6 +264,124 @@ func RegisterTTL(t time.Duration) Option {
 	}
 }
 
+// StreamBuffer sets the number of messages buffered by each stream
+func StreamBuffer(n int) Option {
+	return func(o *Options) {
+		o.StreamBuffer = n
+	}
+}
+
+// StreamNoWait fails the sends of streams with socket.ErrFull when the client
+// flow controlling the stream hasn't acknowledged the messages of its window,
+// rather than blocking until it does
+func StreamNoWait() Option {
+	return func(o *Options) {
+		o.StreamNoWait = true
+	}
+}
+
+// Limits sets the rate limit and the max in flight requests of the endpoint.
+// The limit of the "*" endpoint applies to all the requests to the server.
+func Limits(endpoint string, l Limit) Option {
+	return func(o *Options) {
+		if o.Limiter == nil {
+			o.Limiter = NewLimiter()
+		}
+		o.Limiter.Set(endpoint, l)
+	}
+}
+
+// LoadShedding rejects the requests with a 503 error while the server is
+// handling too many of them at once or they are queued for too long
+func LoadShedding(s Shedding) Option {
+	return func(o *Options) {
+		if o.Limiter == nil {
+			o.Limiter = NewLimiter()
+		}
+		o.Limiter.Shed(s)
+	}
+}
+
+// HandlerTimeout sets the max execution time of the handlers. The context of
+// the handlers running for longer is canceled and a 504 error is returned.
+// Streams aren't limited.
+func HandlerTimeout(d time.Duration) Option {
+	return func(o *Options) {
+		o.HandlerTimeout = d
+	}
+}
+
+// EndpointTimeout sets the max execution time of the endpoint e.g Foo.Bar,
+// overriding the HandlerTimeout. Zero is unlimited.
+func EndpointTimeout(endpoint string, d time.Duration) Option {
+	return func(o *Options) {
+		// copied as the requests being served may be reading it
+		timeouts := make(map[string]time.Duration, len(o.EndpointTimeouts)+1)
+		for k, v := range o.EndpointTimeouts {
+			timeouts[k] = v
+		}
+		timeouts[endpoint] = d
+		o.EndpointTimeouts = timeouts
+	}
+}
+
+// EndpointContentType sets the content types the endpoint e.g Foo.Bar responds
+// with in order of preference. The first is used if the request accepts none.
+func EndpointContentType(endpoint string, ct ...string) Option {
+	return func(o *Options) {
+		if o.EndpointContentTypes == nil {
+			o.EndpointContentTypes = make(map[string][]string)
+		}
+		o.EndpointContentTypes[endpoint] = ct
+	}
+}
+
+// Workers handles the requests with a pool of size workers rather than a
+// goroutine per request, bounding the requests handled at once. Streams
+// are still served in their own goroutines as they may be long lived.
+func Workers(size int) Option {
+	return func(o *Options) {
+		o.Workers = size
+	}
+}
+
+// WorkerQueue sets the number of requests queued for a free worker and the
+// time they wait for room in a full queue before they're rejected with a 503
+func WorkerQueue(size int, timeout time.Duration) Option {
+	return func(o *Options) {
+		o.WorkerQueue = size
+		o.WorkerQueueTimeout = timeout
+	}
+}
+
+// Debug serves the runtime debug endpoints, the pprof profiles, goroutine dumps,
+// gc stats and build info, with the Runtime handler. The requests must have
+// the token in their Micro-Debug-Token metadata. The endpoints aren't served
+// if the token is blank.
+func Debug(token string) Option {
+	return func(o *Options) {
+		o.Debug = true
+		o.DebugToken = token
+	}
+}
+
+// DrainTimeout sets the time to wait for the requests in flight on shutdown
+// before the connections are closed, zero waits for them without limit
+func DrainTimeout(t time.Duration) Option {
+	return func(o *Options) {
+		o.DrainTimeout = t
+	}
+}
+
+// KeepRegistered leaves the node registered when the server stops so the
+// caller deregisters it with Deregister e.g once the other subsystems of the
+// service are stopped. The subscribers are still stopped.
+func KeepRegistered(b bool) Option {
+	return func(o *Options) {
+		o.KeepRegistered = b
+	}
+}
+
 // Register the service with at interval
 func RegisterInterval(t time.Duration) Option {
 	return func(o *Options) {
@@ -271,6 +439,13 @@ func WrapHandler(w HandlerWrapper) Option {
 	}
 }
 
+// Adds a stream Wrapper to a list of options passed into the server
+func WrapStream(w StreamWrapper) Option {
+	return func(o *Options) {
+		o.StreamWrappers = append(o.StreamWrappers, w)
+	}
+}
+
 // Adds a subscriber Wrapper to a list of options passed into the server
 func WrapSubscriber(w SubscriberWrapper) Option {
 	return func(o *Options) {
@@ -0,0 +1,59 @@
+// Package reflection serves the description of a service, its endpoints with
+// the schemas of their requests and responses and its metadata, so unknown
+// services can be introspected e.g by the CLI or the API gateway.
+package reflection
+
+import (
+	"context"
+
+	"github.com/micro/go-micro/v2/client"
+	"github.com/micro/go-micro/v2/errors"
+	"github.com/micro/go-micro/v2/registry"
+)
+
+// Endpoint of the description of the services
+const Endpoint = "Reflection.Describe"
+
+// DescribeFunc returns the description of the service
+type DescribeFunc func() *registry.Service
+
+// Request for the description of the service
+type Request struct{}
+
+// Response is the description of the service
+type Response struct {
+	Service *registry.Service `json:"service"`
+}
+
+// Reflection is a handler serving Reflection.Describe. It's JSON encoded
+// as the schemas are extracted from the handlers by reflection.
+type Reflection struct {
+	describe DescribeFunc
+}
+
+// Describe returns the service with its endpoints and metadata
+func (r *Reflection) Describe(ctx context.Context, req *Request, rsp *Response) error {
+	svc := r.describe()
+	if svc == nil {
+		return errors.NotFound("go.micro.server", "service not described")
+	}
+	rsp.Service = svc
+	return nil
+}
+
+// NewReflection returns a reflection handler describing the service with fn
+func NewReflection(fn DescribeFunc) *Reflection {
+	return &Reflection{
+		describe: fn,
+	}
+}
+
+// Describe returns the description of the service calling its reflection endpoint
+func Describe(ctx context.Context, c client.Client, service string, opts ...client.CallOption) (*registry.Service, error) {
+	req := c.NewRequest(service, Endpoint, &Request{}, client.WithContentType("application/json"))
+	rsp := new(Response)
+	if err := c.Call(ctx, req, rsp, opts...); err != nil {
+		return nil, err
+	}
+	return rsp.Service, nil
+}
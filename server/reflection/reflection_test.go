@@ -0,0 +1,79 @@
+package reflection_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/micro/go-micro/v2/broker"
+	bmemory "github.com/micro/go-micro/v2/broker/memory"
+	"github.com/micro/go-micro/v2/client"
+	rmemory "github.com/micro/go-micro/v2/registry/memory"
+	"github.com/micro/go-micro/v2/server"
+	"github.com/micro/go-micro/v2/server/reflection"
+	tmemory "github.com/micro/go-micro/v2/transport/memory"
+)
+
+type GreetRequest struct {
+	Name string `json:"name"`
+}
+
+type GreetResponse struct {
+	Greeting string `json:"greeting"`
+}
+
+type Greeter struct{}
+
+func (g *Greeter) Hello(ctx context.Context, req *GreetRequest, rsp *GreetResponse) error {
+	rsp.Greeting = "Hello " + req.Name
+	return nil
+}
+
+func TestDescribe(t *testing.T) {
+	reg := rmemory.NewRegistry()
+	brk := bmemory.NewBroker(broker.Registry(reg))
+	tr := tmemory.NewTransport()
+
+	srv := server.NewServer(
+		server.Broker(brk),
+		server.Registry(reg),
+		server.Name("go.micro.service.greeter"),
+		server.Version("1.0.0"),
+		server.Metadata(map[string]string{"team": "core"}),
+		server.Address("127.0.0.1:0"),
+		server.Transport(tr),
+	)
+	if err := srv.Handle(srv.NewHandler(new(Greeter))); err != nil {
+		t.Fatal(err)
+	}
+	if err := srv.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer srv.Stop()
+
+	cli := client.NewClient(
+		client.Registry(reg),
+		client.Broker(brk),
+		client.Transport(tr),
+	)
+
+	svc, err := reflection.Describe(context.Background(), cli, "go.micro.service.greeter")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if svc.Name != "go.micro.service.greeter" || svc.Version != "1.0.0" || svc.Metadata["team"] != "core" {
+		t.Fatalf("Unexpected service %+v", svc)
+	}
+
+	// only the endpoints of the handlers are described
+	if len(svc.Endpoints) != 1 {
+		t.Fatalf("Expected 1 endpoint got %d", len(svc.Endpoints))
+	}
+	ep := svc.Endpoints[0]
+	if ep.Name != "Greeter.Hello" || ep.Request.Name != "GreetRequest" || ep.Response.Name != "GreetResponse" {
+		t.Fatalf("Unexpected endpoint %+v", ep)
+	}
+	if len(ep.Request.Values) != 1 || ep.Request.Values[0].Name != "name" || ep.Request.Values[0].Type != "string" {
+		t.Fatalf("Unexpected request schema %+v", ep.Request.Values)
+	}
+}
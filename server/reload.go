@@ -0,0 +1,122 @@
+package server
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/micro/go-micro/v2/config"
+	"github.com/micro/go-micro/v2/config/reader"
+	"github.com/micro/go-micro/v2/logger"
+)
+
+// Reload is the subset of the options which can be changed while the server
+// is running. The durations are strings e.g "5s" and the blank values are
+// left unchanged.
+type Reload struct {
+	// LogLevel of the default logger e.g debug
+	LogLevel string `json:"log_level"`
+	// HandlerTimeout is the max execution time of the handlers
+	HandlerTimeout string `json:"handler_timeout"`
+	// EndpointTimeouts replace the execution timeouts of the endpoints
+	EndpointTimeouts map[string]string `json:"endpoint_timeouts"`
+	// Limits replace the limits of the requests to the endpoints, "*" for
+	// all of them
+	Limits map[string]Limit `json:"limits"`
+}
+
+func parseDuration(name, v string) (time.Duration, error) {
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s %q: %v", name, v, err)
+	}
+	return d, nil
+}
+
+// Options returns the server options of the reload
+func (r Reload) Options() ([]Option, error) {
+	var opts []Option
+
+	if len(r.HandlerTimeout) > 0 {
+		d, err := parseDuration("handler timeout", r.HandlerTimeout)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, HandlerTimeout(d))
+	}
+
+	if r.EndpointTimeouts != nil {
+		timeouts := make(map[string]time.Duration, len(r.EndpointTimeouts))
+		for endpoint, v := range r.EndpointTimeouts {
+			d, err := parseDuration("timeout of "+endpoint, v)
+			if err != nil {
+				return nil, err
+			}
+			timeouts[endpoint] = d
+		}
+		opts = append(opts, func(o *Options) {
+			o.EndpointTimeouts = timeouts
+		})
+	}
+
+	if r.Limits != nil {
+		opts = append(opts, func(o *Options) {
+			if o.Limiter == nil {
+				o.Limiter = NewLimiter()
+			}
+			o.Limiter.Reset(r.Limits)
+		})
+	}
+
+	return opts, nil
+}
+
+// Apply the reload to the running server
+func (r Reload) Apply(s Server) error {
+	opts, err := r.Options()
+	if err != nil {
+		return err
+	}
+
+	if len(r.LogLevel) > 0 {
+		level, err := logger.GetLevel(r.LogLevel)
+		if err != nil {
+			return err
+		}
+		if err := logger.Init(logger.WithLevel(level)); err != nil {
+			return err
+		}
+	}
+
+	if len(opts) == 0 {
+		return nil
+	}
+
+	return s.Init(opts...)
+}
+
+// Reloader returns a config subscriber applying the reload at the path to
+// the server every time the config changes e.g
+//
+//	c.Subscribe(server.Reloader(srv, "micro", "server"))
+//
+// The limits are cleared once removed from the config.
+func Reloader(s Server, path ...string) config.Subscriber {
+	// whether the limits were set by the last reload, the subscribers
+	// are called serially so it isn't guarded
+	var limited bool
+
+	return func(v reader.Values) error {
+		var r Reload
+		if err := v.Get(path...).Scan(&r); err != nil {
+			return err
+		}
+		if r.Limits == nil && limited {
+			r.Limits = map[string]Limit{}
+		}
+		if err := r.Apply(s); err != nil {
+			return err
+		}
+		limited = len(r.Limits) > 0
+		return nil
+	}
+}
@@ -0,0 +1,88 @@
+package server
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/micro/go-micro/v2/config"
+	"github.com/micro/go-micro/v2/config/source/memory"
+	"github.com/micro/go-micro/v2/logger"
+)
+
+func TestReloader(t *testing.T) {
+	c, err := config.NewConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	if err := c.Load(memory.NewSource(memory.WithJSON([]byte(`{
+		"server": {
+			"log_level": "debug",
+			"handler_timeout": "5s",
+			"endpoint_timeouts": {"Foo.Bar": "100ms"},
+			"limits": {"*": {"rate": 10, "burst": 1}}
+		}
+	}`)))); err != nil {
+		t.Fatal(err)
+	}
+
+	level := logger.DefaultLogger.Options().Level
+	defer logger.Init(logger.WithLevel(level))
+
+	srv := newRpcServer()
+	if err := srv.Subscribe(srv.NewSubscriber("foo", func(ctx context.Context, e *RetryEvent) error { return nil })); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := c.Subscribe(Reloader(srv, "server")); err != nil {
+		t.Fatal(err)
+	}
+
+	opts := srv.Options()
+	if d := opts.Timeout("Foo.Baz"); d != 5*time.Second {
+		t.Fatalf("Expected the handler timeout of the config got %v", d)
+	}
+	if d := opts.Timeout("Foo.Bar"); d != 100*time.Millisecond {
+		t.Fatalf("Expected the endpoint timeout of the config got %v", d)
+	}
+	if opts.Limiter == nil {
+		t.Fatal("Expected the limits of the config")
+	}
+	if l := logger.DefaultLogger.Options().Level; l != logger.DebugLevel {
+		t.Fatalf("Expected the debug log level got %v", l)
+	}
+
+	// the subscribers are kept by the router
+	if subs := srv.(*rpcServer).router.subscribers["foo"]; len(subs) != 1 {
+		t.Fatal("Expected the subscriber to be kept")
+	}
+
+	acquire := func() error {
+		release, err := srv.Options().Limiter.Acquire(context.Background(), "Foo.Bar", time.Now())
+		if err == nil {
+			release()
+		}
+		return err
+	}
+	acquire()
+	if err := acquire(); err == nil {
+		t.Fatal("Expected the rate limit of the config")
+	}
+
+	// the limits removed from the config are cleared
+	if err := c.Stage().Del("server", "limits").Apply(); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		if err := acquire(); err != nil {
+			t.Fatalf("Expected the limits to be cleared got %v", err)
+		}
+	}
+
+	// invalid durations are rejected
+	if err := (Reload{HandlerTimeout: "5"}).Apply(srv); err == nil {
+		t.Fatal("Expected an invalid timeout to be rejected")
+	}
+}
@@ -0,0 +1,48 @@
+package server
+
+import (
+	"context"
+	"time"
+
+	"github.com/micro/go-micro/v2/broker"
+	"github.com/micro/go-micro/v2/logger"
+)
+
+// RetrySubscriber wraps a subscriber handler to retry it up to the max attempts
+// of the options, waiting for their backoff between the attempts. The error of
+// the last attempt is returned so the broker nacks or dead letters the message.
+// The handler is invoked once if the options have no backoff.
+func RetrySubscriber(opts SubscriberOptions) SubscriberWrapper {
+	return func(fn SubscriberFunc) SubscriberFunc {
+		if opts.Backoff == nil {
+			return fn
+		}
+
+		attempts := opts.MaxAttempts
+		if attempts <= 0 {
+			attempts = broker.DefaultMaxAttempts
+		}
+
+		return func(ctx context.Context, msg Message) error {
+			for i := 1; ; i++ {
+				err := fn(ctx, msg)
+				if err == nil || i >= attempts {
+					return err
+				}
+
+				d := opts.Backoff(i)
+				if logger.V(logger.DebugLevel, logger.DefaultLogger) {
+					logger.Debugf("Subscriber of %s failed attempt %d, retrying in %v: %v", msg.Topic(), i, d, err)
+				}
+
+				t := time.NewTimer(d)
+				select {
+				case <-t.C:
+				case <-ctx.Done():
+					t.Stop()
+					return err
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/micro/go-micro/v2/broker"
+	bmemory "github.com/micro/go-micro/v2/broker/memory"
+	"github.com/micro/go-micro/v2/codec/json"
+	rmemory "github.com/micro/go-micro/v2/registry/memory"
+	tmemory "github.com/micro/go-micro/v2/transport/memory"
+)
+
+func TestRetrySubscriber(t *testing.T) {
+	var attempts int
+	fn := func(ctx context.Context, msg Message) error {
+		attempts++
+		if attempts < 3 {
+			return fmt.Errorf("attempt %d failed", attempts)
+		}
+		return nil
+	}
+	backoff := func(int) time.Duration { return time.Millisecond }
+
+	// succeeds on the third attempt
+	opts := NewSubscriberOptions(SubscriberRetry(3, backoff))
+	if err := RetrySubscriber(opts)(fn)(context.Background(), &rpcMessage{topic: "test"}); err != nil {
+		t.Fatal(err)
+	}
+	if attempts != 3 {
+		t.Fatalf("Expected 3 attempts got %d", attempts)
+	}
+
+	// the attempts are exhausted
+	attempts = 0
+	opts = NewSubscriberOptions(SubscriberRetry(2, backoff))
+	if err := RetrySubscriber(opts)(fn)(context.Background(), &rpcMessage{topic: "test"}); err == nil {
+		t.Fatal("Expected the error of the last attempt")
+	}
+	if attempts != 2 {
+		t.Fatalf("Expected 2 attempts got %d", attempts)
+	}
+
+	// not retried without a backoff
+	attempts = 0
+	RetrySubscriber(NewSubscriberOptions())(fn)(context.Background(), &rpcMessage{topic: "test"})
+	if attempts != 1 {
+		t.Fatalf("Expected 1 attempt got %d", attempts)
+	}
+}
+
+type RetryEvent struct {
+	Name string
+}
+
+type RetryHandler struct {
+	attempts chan int
+	n        int
+}
+
+func (h *RetryHandler) Handle(ctx context.Context, e *RetryEvent) error {
+	h.n++
+	h.attempts <- h.n
+	return fmt.Errorf("failed to handle %s", e.Name)
+}
+
+func TestSubscriberDeadLetter(t *testing.T) {
+	reg := rmemory.NewRegistry()
+	brk := bmemory.NewBroker(broker.Registry(reg))
+
+	srv := NewServer(
+		Broker(brk),
+		Registry(reg),
+		Transport(tmemory.NewTransport()),
+		Name("go.micro.service.retry"),
+	)
+
+	h := &RetryHandler{attempts: make(chan int, 10)}
+	sub := srv.NewSubscriber("retry", h,
+		SubscriberDeadLetter("retry.dead", 0),
+		SubscriberRetry(3, func(int) time.Duration { return time.Millisecond }),
+	)
+	if err := srv.Subscribe(sub); err != nil {
+		t.Fatal(err)
+	}
+	if err := srv.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer srv.Stop()
+
+	dead := make(chan *broker.Message, 1)
+	if _, err := brk.Subscribe("retry.dead", func(e broker.Event) error {
+		dead <- e.Message()
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	body, _ := json.Marshaler{}.Marshal(&RetryEvent{Name: "foo"})
+	if err := brk.Publish("retry", &broker.Message{
+		Header: map[string]string{
+			"Content-Type": "application/json",
+			"Micro-Topic":  "retry",
+		},
+		Body: body,
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case msg := <-dead:
+		if v := msg.Header[broker.DeadLetterAttemptsHeader]; v != "1" {
+			t.Fatalf("Expected the message dead lettered by the broker once got %s", v)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected the message to be dead lettered")
+	}
+
+	// the handler is retried by the server only
+	if n := len(h.attempts); n != 3 {
+		t.Fatalf("Expected 3 attempts got %d", n)
+	}
+}
@@ -5,13 +5,16 @@ import (
 	"sync"
 
 	"github.com/micro/go-micro/v2/codec"
+	"github.com/micro/go-micro/v2/codec/avro"
 	raw "github.com/micro/go-micro/v2/codec/bytes"
+	"github.com/micro/go-micro/v2/codec/flatbuffers"
 	"github.com/micro/go-micro/v2/codec/grpc"
 	"github.com/micro/go-micro/v2/codec/json"
 	"github.com/micro/go-micro/v2/codec/jsonrpc"
 	"github.com/micro/go-micro/v2/codec/proto"
 	"github.com/micro/go-micro/v2/codec/protorpc"
 	"github.com/micro/go-micro/v2/transport"
+	"github.com/micro/go-micro/v2/util/socket"
 	"github.com/oxtoacart/bpool"
 	"github.com/pkg/errors"
 )
@@ -21,6 +24,10 @@ type rpcCodec struct {
 	codec    codec.Codec
 	protocol string
 
+	// the codec and content type of the responses if negotiated
+	wcodec      codec.Codec
+	contentType string
+
 	req *transport.Message
 	buf *readWriteCloser
 
@@ -39,6 +46,8 @@ var (
 	DefaultContentType = "application/protobuf"
 
 	DefaultCodecs = map[string]codec.NewCodec{
+		"application/avro":         avro.NewCodec,
+		"application/flatbuffers":  flatbuffers.NewCodec,
 		"application/grpc":         grpc.NewCodec,
 		"application/grpc+json":    grpc.NewCodec,
 		"application/grpc+proto":   grpc.NewCodec,
@@ -310,23 +319,27 @@ func (c *rpcCodec) Write(r *codec.Message, b interface{}) error {
 	} else if len(r.Body) > 0 {
 		body = r.Body
 		// write the body to codec
-	} else if err := c.codec.Write(m, b); err != nil {
+	} else if err := c.writer().Write(m, b); err != nil {
 		c.buf.wbuf.Reset()
 
 		// write an error if it failed
 		m.Error = errors.Wrapf(err, "Unable to encode body").Error()
 		m.Header["Micro-Error"] = m.Error
 		// no body to write
-		if err := c.codec.Write(m, nil); err != nil {
+		if err := c.writer().Write(m, nil); err != nil {
 			return err
 		}
 	} else {
-		// set the body
-		body = c.buf.wbuf.Bytes()
+		// copy the body as the buffer is reused while the
+		// message may still be buffered by the socket
+		body = make([]byte, c.buf.wbuf.Len())
+		copy(body, c.buf.wbuf.Bytes())
 	}
 
 	// Set content type if theres content
-	if len(body) > 0 {
+	if len(body) > 0 && len(c.contentType) > 0 {
+		m.Header["Content-Type"] = c.contentType
+	} else if len(body) > 0 {
 		m.Header["Content-Type"] = c.req.Header["Content-Type"]
 	}
 
@@ -337,9 +350,34 @@ func (c *rpcCodec) Write(r *codec.Message, b interface{}) error {
 	})
 }
 
+// Acquire waits for the window of the stream to send a message
+func (c *rpcCodec) Acquire() error {
+	if s, ok := c.socket.(*socket.Socket); ok {
+		return s.Acquire()
+	}
+	return nil
+}
+
+// respond with the codec of the content type negotiated
+func (c *rpcCodec) respond(contentType string, cf codec.NewCodec) {
+	c.wcodec = cf(c.buf)
+	c.contentType = contentType
+}
+
+// writer returns the codec of the responses
+func (c *rpcCodec) writer() codec.Codec {
+	if c.wcodec != nil {
+		return c.wcodec
+	}
+	return c.codec
+}
+
 func (c *rpcCodec) Close() error {
 	// close the codec
 	c.codec.Close()
+	if c.wcodec != nil {
+		c.wcodec.Close()
+	}
 	// close the socket
 	err := c.socket.Close()
 	// put back the buffers
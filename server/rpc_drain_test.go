@@ -0,0 +1,124 @@
+package server_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/micro/go-micro/v2/broker"
+	bmemory "github.com/micro/go-micro/v2/broker/memory"
+	"github.com/micro/go-micro/v2/client"
+	"github.com/micro/go-micro/v2/registry"
+	rmemory "github.com/micro/go-micro/v2/registry/memory"
+	"github.com/micro/go-micro/v2/server"
+	"github.com/micro/go-micro/v2/transport"
+)
+
+type DrainReq struct{}
+
+type DrainRsp struct{}
+
+type TestDrain struct {
+	started chan bool
+	release chan bool
+}
+
+func (h *TestDrain) Wait(ctx context.Context, req *DrainReq, rsp *DrainRsp) error {
+	h.started <- true
+	<-h.release
+	return nil
+}
+
+func newDrainServer(t *testing.T, opts ...server.Option) (*TestDrain, server.Server, registry.Registry, client.Client) {
+	reg := rmemory.NewRegistry()
+	brk := bmemory.NewBroker(broker.Registry(reg))
+	// the connections outlive the listener unlike the memory transport
+	tr := transport.NewTransport()
+
+	srv := server.NewServer(append([]server.Option{
+		server.Broker(brk),
+		server.Registry(reg),
+		server.Name("go.micro.service.drain"),
+		server.Address("127.0.0.1:0"),
+		server.Transport(tr),
+	}, opts...)...)
+
+	h := &TestDrain{started: make(chan bool, 1), release: make(chan bool)}
+	if err := srv.Handle(srv.NewHandler(h)); err != nil {
+		t.Fatal(err)
+	}
+	if err := srv.Start(); err != nil {
+		t.Fatal(err)
+	}
+
+	cli := client.NewClient(
+		client.Registry(reg),
+		client.Broker(brk),
+		client.Transport(tr),
+		client.RequestTimeout(5*time.Second),
+	)
+
+	return h, srv, reg, cli
+}
+
+func TestStopDrain(t *testing.T) {
+	h, srv, reg, cli := newDrainServer(t)
+
+	req := cli.NewRequest("go.micro.service.drain", "TestDrain.Wait", &DrainReq{}, client.WithContentType("application/json"))
+	errs := make(chan error, 1)
+	go func() {
+		errs <- cli.Call(context.Background(), req, &DrainRsp{})
+	}()
+	<-h.started
+
+	stopped := make(chan error, 1)
+	go func() {
+		stopped <- srv.Stop()
+	}()
+
+	// the server is deregistered while the request is in flight
+	for i := 0; ; i++ {
+		if svcs, _ := reg.GetService("go.micro.service.drain"); len(svcs) == 0 {
+			break
+		}
+		if i == 100 {
+			t.Fatal("Expected the server to be deregistered")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	select {
+	case <-stopped:
+		t.Fatal("Expected the server to wait for the request in flight")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(h.release)
+
+	if err := <-errs; err != nil {
+		t.Fatalf("Expected the request in flight to succeed got %v", err)
+	}
+	if err := <-stopped; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestStopDrainTimeout(t *testing.T) {
+	h, srv, _, cli := newDrainServer(t, server.DrainTimeout(100*time.Millisecond))
+	defer close(h.release)
+
+	req := cli.NewRequest("go.micro.service.drain", "TestDrain.Wait", &DrainReq{}, client.WithContentType("application/json"))
+	go cli.Call(context.Background(), req, &DrainRsp{})
+	<-h.started
+
+	stopped := make(chan error, 1)
+	go func() {
+		stopped <- srv.Stop()
+	}()
+
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		t.Fatal("Expected the server to stop after the drain timeout")
+	}
+}
@@ -16,6 +16,15 @@ func (e *event) Ack() error {
 	return nil
 }
 
+func (e *event) Nack() error {
+	// there is no redelivery support
+	return nil
+}
+
+func (e *event) Requeue() error {
+	return nil
+}
+
 func (e *event) Message() *broker.Message {
 	return e.message
 }
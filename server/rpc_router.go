@@ -15,9 +15,11 @@ import (
 	"runtime/debug"
 	"strings"
 	"sync"
+	"time"
 	"unicode"
 	"unicode/utf8"
 
+	"github.com/micro/go-micro/v2/broker"
 	"github.com/micro/go-micro/v2/codec"
 	merrors "github.com/micro/go-micro/v2/errors"
 )
@@ -71,8 +73,12 @@ type router struct {
 
 	// handler wrappers
 	hdlrWrappers []HandlerWrapper
+	// execution timeout of the endpoints
+	timeout func(endpoint string) time.Duration
 	// subscriber wrappers
 	subWrappers []SubscriberWrapper
+	// stream wrappers
+	streamWrappers []StreamWrapper
 
 	su          sync.RWMutex
 	subscribers map[string][]*subscriber
@@ -185,9 +191,10 @@ func prepareMethod(method reflect.Method) *methodType {
 	return &methodType{method: method, ArgType: argType, ReplyType: replyType, ContextType: contextType, stream: stream}
 }
 
-func (router *router) sendResponse(sending sync.Locker, req *request, reply interface{}, cc codec.Writer, last bool) error {
+func (router *router) sendResponse(sending sync.Locker, req *request, reply interface{}, hdr map[string]string, cc codec.Writer, last bool) error {
 	msg := new(codec.Message)
 	msg.Type = codec.Response
+	msg.Header = hdr
 	resp := router.getResponse()
 	resp.msg = msg
 
@@ -220,8 +227,17 @@ func (s *service) call(ctx context.Context, router *router, sending *sync.Mutex,
 	}
 
 	if !mtype.stream {
+		// collect headers set by the handler for the response
+		ctx = NewResponseMetadataContext(ctx)
+
 		fn := func(ctx context.Context, req Request, rsp interface{}) error {
-			returnValues = function.Call([]reflect.Value{s.rcvr, mtype.prepareContext(ctx), reflect.ValueOf(argv.Interface()), reflect.ValueOf(rsp)})
+			// don't start work the caller has given up on
+			if err := ctx.Err(); err != nil {
+				return merrors.Timeout("go.micro.server", "deadline exceeded before handling the request: %v", err)
+			}
+
+			// local as the handler may outlive the request once timed out
+			returnValues := function.Call([]reflect.Value{s.rcvr, mtype.prepareContext(ctx), reflect.ValueOf(argv.Interface()), reflect.ValueOf(rsp)})
 
 			// The return value for the method is an error.
 			if err := returnValues[0].Interface(); err != nil {
@@ -231,6 +247,11 @@ func (s *service) call(ctx context.Context, router *router, sending *sync.Mutex,
 			return nil
 		}
 
+		// enforce the execution timeout of the endpoint
+		if router.timeout != nil {
+			fn = TimeoutHandler(router.timeout(r.Endpoint()))(fn)
+		}
+
 		// wrap the handler
 		for i := len(router.hdlrWrappers); i > 0; i-- {
 			fn = router.hdlrWrappers[i-1](fn)
@@ -242,7 +263,7 @@ func (s *service) call(ctx context.Context, router *router, sending *sync.Mutex,
 		}
 
 		// send response
-		return router.sendResponse(sending, req, replyv.Interface(), cc, true)
+		return router.sendResponse(sending, req, replyv.Interface(), ResponseMetadata(ctx), cc, true)
 	}
 
 	// declare a local error to see if we errored out already
@@ -274,11 +295,17 @@ func (s *service) call(ctx context.Context, router *router, sending *sync.Mutex,
 		fn = router.hdlrWrappers[i-1](fn)
 	}
 
+	// wrap the stream
+	var stream Stream = rawStream
+	for i := len(router.streamWrappers); i > 0; i-- {
+		stream = router.streamWrappers[i-1](stream)
+	}
+
 	// client.Stream request
 	r.stream = true
 
 	// execute handler
-	return fn(ctx, r, rawStream)
+	return fn(ctx, r, stream)
 }
 
 func (m *methodType) prepareContext(ctx context.Context) reflect.Value {
@@ -515,11 +542,16 @@ func (router *router) ProcessMessage(ctx context.Context, msg Message) (err erro
 	}()
 
 	router.su.RLock()
-	// get the subscribers by topic
-	subs, ok := router.subscribers[msg.Topic()]
+	// get the subscribers by topic or topic pattern
+	var subs []*subscriber
+	for topic, s := range router.subscribers {
+		if broker.MatchTopic(topic, msg.Topic()) {
+			subs = append(subs, s...)
+		}
+	}
 	// unlock since we only need to get the subs
 	router.su.RUnlock()
-	if !ok {
+	if len(subs) == 0 {
 		return nil
 	}
 
@@ -551,7 +583,7 @@ func (router *router) ProcessMessage(ctx context.Context, msg Message) (err erro
 			cc := msg.Codec()
 
 			// read the header. mostly a noop
-			if err = cc.ReadHeader(&codec.Message{}, codec.Event); err != nil {
+			if err = cc.ReadHeader(&codec.Message{Header: msg.Header()}, codec.Event); err != nil {
 				return err
 			}
 
@@ -586,6 +618,9 @@ func (router *router) ProcessMessage(ctx context.Context, msg Message) (err erro
 				fn = router.subWrappers[i-1](fn)
 			}
 
+			// retry the handler failing with a backoff
+			fn = RetrySubscriber(sub.opts)(fn)
+
 			// create new rpc message
 			rpcMsg := &rpcMessage{
 				topic:       msg.Topic(),
@@ -15,14 +15,20 @@ import (
 	"github.com/micro/go-micro/v2/broker"
 	"github.com/micro/go-micro/v2/codec"
 	raw "github.com/micro/go-micro/v2/codec/bytes"
+	"github.com/micro/go-micro/v2/debug/trace"
+	"github.com/micro/go-micro/v2/errors"
 	"github.com/micro/go-micro/v2/logger"
 	"github.com/micro/go-micro/v2/metadata"
 	"github.com/micro/go-micro/v2/registry"
+	mdebug "github.com/micro/go-micro/v2/server/debug"
+	"github.com/micro/go-micro/v2/server/health"
+	"github.com/micro/go-micro/v2/server/reflection"
 	"github.com/micro/go-micro/v2/transport"
 	"github.com/micro/go-micro/v2/util/addr"
 	"github.com/micro/go-micro/v2/util/backoff"
 	mnet "github.com/micro/go-micro/v2/util/net"
 	"github.com/micro/go-micro/v2/util/socket"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
 )
 
 type rpcServer struct {
@@ -41,6 +47,15 @@ type rpcServer struct {
 	subscriber broker.Subscriber
 	// graceful exit
 	wg *sync.WaitGroup
+	// draining rejects the new requests while shutting down
+	draining bool
+	// connections being served
+	conns map[transport.Socket]bool
+	// workers handling the requests, nil if
+	// each request has its own goroutine
+	workers *WorkerPool
+	// grpc.health.v1 health checking bridge
+	health *health.Health
 
 	rsvc *registry.Service
 }
@@ -50,6 +65,14 @@ func newRpcServer(opts ...Option) Server {
 	router := newRpcRouter()
 	router.hdlrWrappers = options.HdlrWrappers
 	router.subWrappers = options.SubWrappers
+	router.streamWrappers = options.StreamWrappers
+	router.timeout = options.Timeout
+
+	// in flight requests are waited for on shutdown
+	wg := wait(options.Context)
+	if wg == nil {
+		wg = new(sync.WaitGroup)
+	}
 
 	return &rpcServer{
 		opts:        options,
@@ -57,7 +80,9 @@ func newRpcServer(opts ...Option) Server {
 		handlers:    make(map[string]Handler),
 		subscribers: make(map[Subscriber][]broker.Subscriber),
 		exit:        make(chan chan error),
-		wg:          wait(options.Context),
+		wg:          wg,
+		conns:       make(map[transport.Socket]bool),
+		health:      health.NewHealth(),
 	}
 }
 
@@ -95,6 +120,7 @@ func (s *rpcServer) HandleEvent(e broker.Event) error {
 
 	// create context
 	ctx := metadata.NewContext(context.Background(), hdr)
+	ctx = trace.Extract(ctx, hdr)
 
 	// TODO: inspect message header
 	// Micro-Service means a request
@@ -129,16 +155,31 @@ func (s *rpcServer) HandleEvent(e broker.Event) error {
 	return r.ProcessMessage(ctx, rpcMsg)
 }
 
+// errorString of the error sent to the caller. The wrapped micro errors are
+// unwrapped so their code and details are received.
+func errorString(err error) string {
+	if verr, ok := errors.As(err); ok {
+		return verr.Error()
+	}
+	return err.Error()
+}
+
 // ServeConn serves a single connection
 func (s *rpcServer) ServeConn(sock transport.Socket) {
 	// global error tracking
 	var gerr error
 	// streams are multiplexed on Micro-Stream or Micro-Id header
-	pool := socket.NewPool()
+	var popts []socket.Option
+	if s.opts.StreamBuffer > 0 {
+		popts = append(popts, socket.Buffer(s.opts.StreamBuffer))
+	}
+	pool := socket.NewPool(popts...)
 
 	// get global waitgroup
 	s.Lock()
 	gg := s.wg
+	workers := s.workers
+	s.conns[sock] = true
 	s.Unlock()
 
 	// waitgroup to wait for processing to finish
@@ -159,6 +200,10 @@ func (s *rpcServer) ServeConn(sock transport.Socket) {
 		// close underlying socket
 		sock.Close()
 
+		s.Lock()
+		delete(s.conns, sock)
+		s.Unlock()
+
 		// recover any panics
 		if r := recover(); r != nil {
 			if logger.V(logger.ErrorLevel, log) {
@@ -187,7 +232,7 @@ func (s *rpcServer) ServeConn(sock transport.Socket) {
 			ev := newEvent(msg)
 			// TODO: handle the error event
 			if err := s.HandleEvent(ev); err != nil {
-				msg.Header["Micro-Error"] = err.Error()
+				msg.Header["Micro-Error"] = errorString(err)
 			}
 			// write back some 200
 			if err := sock.Send(&transport.Message{
@@ -235,6 +280,14 @@ func (s *rpcServer) ServeConn(sock transport.Socket) {
 
 		// got an existing socket already
 		if ok {
+			// the client acknowledged the messages of its window
+			if v := msg.Header["Micro-Window-Ack"]; len(v) > 0 {
+				if n, err := strconv.Atoi(v); err == nil {
+					psock.Ack(n)
+				}
+				continue
+			}
+
 			// we're starting processing
 			wg.Add(1)
 
@@ -256,9 +309,19 @@ func (s *rpcServer) ServeConn(sock transport.Socket) {
 		psock.SetLocal(sock.Local())
 		psock.SetRemote(sock.Remote())
 
+		// the client flow controls the messages of the stream
+		if v := msg.Header["Micro-Window"]; stream && len(v) > 0 {
+			if n, err := strconv.Atoi(v); err == nil && n > 0 {
+				psock.SetWindow(n, !s.opts.StreamNoWait)
+			}
+		}
+
 		// load the socket with the current message
 		psock.Accept(&msg)
 
+		// the time the request was received to measure its queue delay
+		received := time.Now()
+
 		// now walk the usual path
 
 		// we use this Timeout header to set a server deadline
@@ -279,6 +342,11 @@ func (s *rpcServer) ServeConn(sock transport.Socket) {
 		// create new context with the metadata
 		ctx := metadata.NewContext(context.Background(), hdr)
 
+		// the peer may be identified by the certificate it presented
+		if ts, ok := sock.(transport.TLSSocket); ok && ts.ConnectionState() != nil {
+			ctx = NewTLSContext(ctx, ts.ConnectionState())
+		}
+
 		// set the timeout from the header if we have it
 		if len(to) > 0 {
 			if n, err := strconv.ParseUint(to, 10, 64); err == nil {
@@ -296,6 +364,8 @@ func (s *rpcServer) ServeConn(sock transport.Socket) {
 
 		// setup old protocol
 		cf := setupProtocol(&msg)
+		// the old protocol responds with the content type of the request
+		legacy := cf != nil
 
 		// no legacy codec needed
 		if cf == nil {
@@ -337,6 +407,14 @@ func (s *rpcServer) ServeConn(sock transport.Socket) {
 			stream:      stream,
 		}
 
+		// respond with the content type negotiated with the accept header
+		if !legacy && protocol != "grpc" {
+			if rct := s.negotiate(request.Endpoint(), ct, msg.Header["Accept"]); rct != ct {
+				rcf, _ := s.newCodec(rct)
+				rcodec.(*rpcCodec).respond(rct, rcf)
+			}
+		}
+
 		// internal response
 		response := &rpcResponse{
 			header: make(map[string]string),
@@ -344,19 +422,22 @@ func (s *rpcServer) ServeConn(sock transport.Socket) {
 			codec:  rcodec,
 		}
 
-		// set router
+		// the options may be reloaded while the request is served
+		s.RLock()
+		opts := s.opts
 		r := Router(s.router)
+		s.RUnlock()
 
 		// if not nil use the router specified
-		if s.opts.Router != nil {
+		if opts.Router != nil {
 			// create a wrapped function
 			handler := func(ctx context.Context, req Request, rsp interface{}) error {
-				return s.opts.Router.ServeRequest(ctx, req, rsp.(Response))
+				return opts.Router.ServeRequest(ctx, req, rsp.(Response))
 			}
 
 			// execute the wrapper for it
-			for i := len(s.opts.HdlrWrappers); i > 0; i-- {
-				handler = s.opts.HdlrWrappers[i-1](handler)
+			for i := len(opts.HdlrWrappers); i > 0; i-- {
+				handler = opts.HdlrWrappers[i-1](handler)
 			}
 
 			// set the router
@@ -364,10 +445,7 @@ func (s *rpcServer) ServeConn(sock transport.Socket) {
 		}
 
 		// process the outbound messages from the socket
-		go func(id string, psock *socket.Socket) {
-			// wait for processing to exit
-			wg.Add(1)
-
+		process := func() {
 			defer func() {
 				// TODO: don't hack this but if its grpc just break out of the stream
 				// We do this because the underlying connection is h2 and its a stream
@@ -401,13 +479,28 @@ func (s *rpcServer) ServeConn(sock transport.Socket) {
 					return
 				}
 			}
-		}(id, psock)
+		}
 
-		// serve the request in a go routine as this may be a stream
-		go func(id string, psock *socket.Socket) {
-			// add to the waitgroup
-			wg.Add(1)
+		// write an error response
+		writeError := func(serveRequestError error) {
+			writeError := rcodec.Write(&codec.Message{
+				Header: msg.Header,
+				Error:  errorString(serveRequestError),
+				Type:   codec.Error,
+			}, nil)
+
+			// if the server request is an EOS error we let the socket know
+			// sometimes the socket is already closed on the other side, so we can ignore that error
+			alreadyClosed := serveRequestError == lastStreamResponseError && writeError == io.EOF
 
+			// could not write error response
+			if writeError != nil && !alreadyClosed {
+				log.Debugf("rpc: unable to write error response: %v", writeError)
+			}
+		}
+
+		// serve the request
+		serve := func() {
 			defer func() {
 				// release the socket
 				pool.Release(psock)
@@ -422,27 +515,93 @@ func (s *rpcServer) ServeConn(sock transport.Socket) {
 			}()
 
 			// serve the actual request using the request router
-			if serveRequestError := r.ServeRequest(ctx, request, response); serveRequestError != nil {
-				// write an error response
-				writeError := rcodec.Write(&codec.Message{
-					Header: msg.Header,
-					Error:  serveRequestError.Error(),
-					Type:   codec.Error,
-				}, nil)
-
-				// if the server request is an EOS error we let the socket know
-				// sometimes the socket is already closed on the other side, so we can ignore that error
-				alreadyClosed := serveRequestError == lastStreamResponseError && writeError == io.EOF
-
-				// could not write error response
-				if writeError != nil && !alreadyClosed {
-					log.Debugf("rpc: unable to write error response: %v", writeError)
-				}
+			// unless the server is shutting down
+			var serveRequestError error
+			if s.isDraining() {
+				serveRequestError = errors.Unavailable("go.micro.server", "server %s is shutting down", s.opts.Name)
+			} else if release, err := opts.Limiter.Acquire(ctx, request.Endpoint(), received); err != nil {
+				// rate limited or shedding load
+				serveRequestError = err
+			} else {
+				serveRequestError = r.ServeRequest(ctx, request, response)
+				release()
+			}
+
+			if serveRequestError != nil {
+				writeError(serveRequestError)
 			}
-		}(id, psock)
+		}
+
+		// wait for processing to exit
+		wg.Add(2)
+
+		// streams are served in their own go routines as they may be long lived
+		if stream || workers == nil {
+			go process()
+			go serve()
+			continue
+		}
+
+		// serve the request and send the response with a worker
+		if err := workers.Go(func() {
+			serve()
+			process()
+		}); err != nil {
+			// the request is rejected
+			writeError(err)
+			pool.Release(psock)
+			wg.Done()
+			// send the error response
+			process()
+		}
 	}
 }
 
+// isDraining returns whether the server is shutting down
+func (s *rpcServer) isDraining() bool {
+	s.RLock()
+	defer s.RUnlock()
+	return s.draining
+}
+
+// drain waits for the requests in flight up to the drain timeout
+// and then closes the connections still being served
+func (s *rpcServer) drain() {
+	s.Lock()
+	s.draining = true
+	swg := s.wg
+	timeout := s.opts.DrainTimeout
+	s.Unlock()
+
+	done := make(chan bool)
+	go func() {
+		swg.Wait()
+		close(done)
+	}()
+
+	var after <-chan time.Time
+	if timeout > 0 {
+		t := time.NewTimer(timeout)
+		defer t.Stop()
+		after = t.C
+	}
+
+	select {
+	case <-done:
+	case <-after:
+		if logger.V(logger.WarnLevel, logger.DefaultLogger) {
+			log.Warnf("Server %s-%s drain timeout exceeded, closing the connections", s.opts.Name, s.opts.Id)
+		}
+	}
+
+	s.Lock()
+	for sock := range s.conns {
+		sock.Close()
+	}
+	s.draining = false
+	s.Unlock()
+}
+
 func (s *rpcServer) newCodec(contentType string) (codec.NewCodec, error) {
 	if cf, ok := s.opts.Codecs[contentType]; ok {
 		return cf, nil
@@ -473,6 +632,9 @@ func (s *rpcServer) Init(opts ...Option) error {
 		r.hdlrWrappers = s.opts.HdlrWrappers
 		r.serviceMap = s.router.serviceMap
 		r.subWrappers = s.opts.SubWrappers
+		r.streamWrappers = s.opts.StreamWrappers
+		r.subscribers = s.router.subscribers
+		r.timeout = s.opts.Timeout
 		s.router = r
 	}
 
@@ -514,6 +676,57 @@ func (s *rpcServer) Subscribe(sb Subscriber) error {
 	return nil
 }
 
+// endpoints returns the endpoints advertised by the server, the lock is held by the caller
+func (s *rpcServer) endpoints() []*registry.Endpoint {
+	// Maps are ordered randomly, sort the keys for consistency
+	var handlerList []string
+	for n, e := range s.handlers {
+		// Only advertise non internal handlers
+		if !e.Options().Internal {
+			handlerList = append(handlerList, n)
+		}
+	}
+
+	sort.Strings(handlerList)
+
+	var subscriberList []Subscriber
+	for e := range s.subscribers {
+		// Only advertise non internal subscribers
+		if !e.Options().Internal {
+			subscriberList = append(subscriberList, e)
+		}
+	}
+
+	sort.Slice(subscriberList, func(i, j int) bool {
+		return subscriberList[i].Topic() > subscriberList[j].Topic()
+	})
+
+	endpoints := make([]*registry.Endpoint, 0, len(handlerList)+len(subscriberList))
+
+	for _, n := range handlerList {
+		endpoints = append(endpoints, s.handlers[n].Endpoints()...)
+	}
+
+	for _, e := range subscriberList {
+		endpoints = append(endpoints, e.Endpoints()...)
+	}
+
+	return endpoints
+}
+
+// describe returns the service served with its endpoints and metadata
+func (s *rpcServer) describe() *registry.Service {
+	s.RLock()
+	defer s.RUnlock()
+
+	return &registry.Service{
+		Name:      s.opts.Name,
+		Version:   s.opts.Version,
+		Metadata:  metadata.Copy(s.opts.Metadata),
+		Endpoints: s.endpoints(),
+	}
+}
+
 func (s *rpcServer) Register() error {
 	s.RLock()
 	rsvc := s.rsvc
@@ -609,44 +822,11 @@ func (s *rpcServer) Register() error {
 
 	s.RLock()
 
-	// Maps are ordered randomly, sort the keys for consistency
-	var handlerList []string
-	for n, e := range s.handlers {
-		// Only advertise non internal handlers
-		if !e.Options().Internal {
-			handlerList = append(handlerList, n)
-		}
-	}
-
-	sort.Strings(handlerList)
-
-	var subscriberList []Subscriber
-	for e := range s.subscribers {
-		// Only advertise non internal subscribers
-		if !e.Options().Internal {
-			subscriberList = append(subscriberList, e)
-		}
-	}
-
-	sort.Slice(subscriberList, func(i, j int) bool {
-		return subscriberList[i].Topic() > subscriberList[j].Topic()
-	})
-
-	endpoints := make([]*registry.Endpoint, 0, len(handlerList)+len(subscriberList))
-
-	for _, n := range handlerList {
-		endpoints = append(endpoints, s.handlers[n].Endpoints()...)
-	}
-
-	for _, e := range subscriberList {
-		endpoints = append(endpoints, e.Endpoints()...)
-	}
-
 	service := &registry.Service{
 		Name:      config.Name,
 		Version:   config.Version,
 		Nodes:     []*registry.Node{node},
-		Endpoints: endpoints,
+		Endpoints: s.endpoints(),
 	}
 
 	// get registered value
@@ -703,6 +883,19 @@ func (s *rpcServer) Register() error {
 			opts = append(opts, broker.DisableAutoAck())
 		}
 
+		if topic := sb.Options().DeadLetterTopic; len(topic) > 0 {
+			attempts := sb.Options().MaxAttempts
+			// the attempts are retried by the server with a backoff
+			if sb.Options().Backoff != nil {
+				attempts = 1
+			}
+			opts = append(opts, broker.DeadLetter(topic, attempts))
+		}
+
+		if sb.Options().Ordered {
+			opts = append(opts, broker.Ordered())
+		}
+
 		sub, err := config.Broker.Subscribe(sb.Topic(), s.HandleEvent, opts...)
 		if err != nil {
 			return err
@@ -784,7 +977,14 @@ func (s *rpcServer) Deregister() error {
 	}
 
 	s.registered = false
+	s.unsubscribe(node.Id)
+
+	s.Unlock()
+	return nil
+}
 
+// unsubscribe closes the subscribers of the node, the lock must be held
+func (s *rpcServer) unsubscribe(id string) {
 	// close the subscriber
 	if s.subscriber != nil {
 		s.subscriber.Unsubscribe()
@@ -794,15 +994,12 @@ func (s *rpcServer) Deregister() error {
 	for sb, subs := range s.subscribers {
 		for _, sub := range subs {
 			if logger.V(logger.InfoLevel, logger.DefaultLogger) {
-				log.Infof("Unsubscribing %s from topic: %s", node.Id, sub.Topic())
+				log.Infof("Unsubscribing %s from topic: %s", id, sub.Topic())
 			}
 			sub.Unsubscribe()
 		}
 		s.subscribers[sb] = nil
 	}
-
-	s.Unlock()
-	return nil
 }
 
 func (s *rpcServer) Start() error {
@@ -829,8 +1026,57 @@ func (s *rpcServer) Start() error {
 	s.Lock()
 	addr := s.opts.Address
 	s.opts.Address = ts.Addr()
+	if s.opts.StableId {
+		s.opts.Id = registry.NodeId(s.opts.Address)
+		if len(s.opts.Advertise) > 0 {
+			s.opts.Id = registry.NodeId(s.opts.Advertise)
+		}
+	}
 	s.Unlock()
 
+	// bridge the grpc health checking protocol unless a
+	// handler with the same name has been registered
+	s.RLock()
+	_, ok := s.handlers["Health"]
+	s.RUnlock()
+	if !ok {
+		if err := s.Handle(s.NewHandler(s.health, InternalHandler(true))); err != nil {
+			if logger.V(logger.ErrorLevel, logger.DefaultLogger) {
+				log.Errorf("Server health handler error: %v", err)
+			}
+		}
+	}
+
+	// serve the description of the service unless a
+	// handler with the same name has been registered
+	s.RLock()
+	_, ok = s.handlers["Reflection"]
+	s.RUnlock()
+	if !ok {
+		if err := s.Handle(s.NewHandler(reflection.NewReflection(s.describe), InternalHandler(true))); err != nil {
+			if logger.V(logger.ErrorLevel, logger.DefaultLogger) {
+				log.Errorf("Server reflection handler error: %v", err)
+			}
+		}
+	}
+
+	// serve the runtime debug endpoints if enabled
+	s.RLock()
+	_, ok = s.handlers["Runtime"]
+	s.RUnlock()
+	if config.Debug && !ok && len(config.DebugToken) == 0 {
+		if logger.V(logger.ErrorLevel, logger.DefaultLogger) {
+			log.Error("Server debug endpoints not served without a token")
+		}
+	} else if config.Debug && !ok {
+		rt := mdebug.NewRuntime(config.Name, config.Version, config.DebugToken)
+		if err := s.Handle(s.NewHandler(rt, InternalHandler(true))); err != nil {
+			if logger.V(logger.ErrorLevel, logger.DefaultLogger) {
+				log.Errorf("Server debug handler error: %v", err)
+			}
+		}
+	}
+
 	bname := config.Broker.String()
 
 	// connect to the broker
@@ -859,6 +1105,13 @@ func (s *rpcServer) Start() error {
 		}
 	}
 
+	// handle the requests with a pool of workers
+	if config.Workers > 0 {
+		s.Lock()
+		s.workers = NewWorkerPool(config.Workers, config.WorkerQueue, config.WorkerQueueTimeout)
+		s.Unlock()
+	}
+
 	exit := make(chan bool)
 
 	go func() {
@@ -939,11 +1192,20 @@ func (s *rpcServer) Start() error {
 			}
 		}
 
+		// fail health checks while draining
+		s.health.Shutdown()
+
 		s.RLock()
 		registered := s.registered
+		keep := s.opts.KeepRegistered
 		s.RUnlock()
-		if registered {
-			// deregister self
+		if registered && keep {
+			// the node is deregistered by the caller of stop
+			s.Lock()
+			s.unsubscribe(config.Name + "-" + config.Id)
+			s.Unlock()
+		} else if registered {
+			// deregister self so no new requests are routed to us
 			if err := s.Deregister(); err != nil {
 				if logger.V(logger.ErrorLevel, logger.DefaultLogger) {
 					log.Errorf("Server %s-%s deregister error: %s", config.Name, config.Id, err)
@@ -951,17 +1213,17 @@ func (s *rpcServer) Start() error {
 			}
 		}
 
-		s.Lock()
-		swg := s.wg
-		s.Unlock()
+		// stop accepting connections
+		err := ts.Close()
 
-		// wait for requests to finish
-		if swg != nil {
-			swg.Wait()
-		}
+		// wait for requests to finish and close the connections
+		s.drain()
 
-		// close transport listener
-		ch <- ts.Close()
+		// stop the workers
+		s.Lock()
+		s.workers.Stop()
+		s.workers = nil
+		s.Unlock()
 
 		if logger.V(logger.InfoLevel, logger.DefaultLogger) {
 			log.Infof("Broker [%s] Disconnected from %s", bname, config.Broker.Address())
@@ -977,8 +1239,14 @@ func (s *rpcServer) Start() error {
 		s.Lock()
 		s.opts.Address = addr
 		s.Unlock()
+
+		ch <- err
 	}()
 
+	// report the server and service as serving
+	s.health.SetServingStatus("", healthpb.HealthCheckResponse_SERVING)
+	s.health.SetServingStatus(config.Name, healthpb.HealthCheckResponse_SERVING)
+
 	// mark the server as started
 	s.Lock()
 	s.started = true
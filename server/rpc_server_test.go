@@ -0,0 +1,21 @@
+package server
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/micro/go-micro/v2/errors"
+)
+
+func TestErrorString(t *testing.T) {
+	err := fmt.Errorf("finding the note: %w", errors.NotFound("go.micro.srv.notes", "note %s not found", "1"))
+
+	verr := errors.Parse(errorString(err))
+	if verr.Code != 404 || verr.Detail != "note 1 not found" || verr.Id != "go.micro.srv.notes" {
+		t.Fatalf("Expected the wrapped error to be sent got %+v", verr)
+	}
+
+	if s := errorString(fmt.Errorf("plain")); s != "plain" {
+		t.Fatalf("Expected the error string got %s", s)
+	}
+}
@@ -28,7 +28,19 @@ func (r *rpcStream) Request() Request {
 	return r.request
 }
 
+// windowed is a codec flow controlled by the client
+type windowed interface {
+	Acquire() error
+}
+
 func (r *rpcStream) Send(msg interface{}) error {
+	// wait for the client to acknowledge the messages sent
+	if w, ok := r.codec.(windowed); ok {
+		if err := w.Acquire(); err != nil {
+			return err
+		}
+	}
+
 	r.Lock()
 	defer r.Unlock()
 
@@ -0,0 +1,132 @@
+package server_test
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/micro/go-micro/v2/broker"
+	bmemory "github.com/micro/go-micro/v2/broker/memory"
+	"github.com/micro/go-micro/v2/client"
+	rmemory "github.com/micro/go-micro/v2/registry/memory"
+	"github.com/micro/go-micro/v2/server"
+	tmemory "github.com/micro/go-micro/v2/transport/memory"
+	"github.com/micro/go-micro/v2/util/socket"
+)
+
+type WindowReq struct{}
+
+type WindowRsp struct {
+	Seq int64
+}
+
+type TestWindow struct {
+	sent int64
+	errs chan error
+}
+
+func (h *TestWindow) Watch(ctx context.Context, stream server.Stream) error {
+	var req WindowReq
+	if err := stream.Recv(&req); err != nil {
+		return err
+	}
+	for i := 0; i < 20; i++ {
+		if err := stream.Send(&WindowRsp{Seq: int64(i)}); err != nil {
+			h.errs <- err
+			return err
+		}
+		atomic.AddInt64(&h.sent, 1)
+	}
+	return nil
+}
+
+func newWindowServer(t *testing.T, opts ...server.Option) (*TestWindow, client.Client, func()) {
+	reg := rmemory.NewRegistry()
+	brk := bmemory.NewBroker(broker.Registry(reg))
+	tr := tmemory.NewTransport()
+
+	srv := server.NewServer(append([]server.Option{
+		server.Broker(brk),
+		server.Registry(reg),
+		server.Name("go.micro.service.window"),
+		server.Address("127.0.0.1:0"),
+		server.Transport(tr),
+	}, opts...)...)
+
+	h := &TestWindow{errs: make(chan error, 1)}
+	if err := srv.Handle(srv.NewHandler(h)); err != nil {
+		t.Fatal(err)
+	}
+	if err := srv.Start(); err != nil {
+		t.Fatal(err)
+	}
+
+	cli := client.NewClient(
+		client.Registry(reg),
+		client.Broker(brk),
+		client.Transport(tr),
+	)
+
+	return h, cli, func() { srv.Stop() }
+}
+
+func TestStreamWindow(t *testing.T) {
+	h, cli, stop := newWindowServer(t)
+	defer stop()
+
+	req := cli.NewRequest("go.micro.service.window", "TestWindow.Watch", &WindowReq{}, client.WithContentType("application/json"))
+	stream, err := cli.Stream(context.Background(), req, client.WithStreamWindow(4))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stream.Close()
+
+	if err := stream.Send(&WindowReq{}); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 20; i++ {
+		// the server waits for the messages of the window to be received
+		time.Sleep(10 * time.Millisecond)
+		if sent := atomic.LoadInt64(&h.sent); sent > int64(i+4) {
+			t.Fatalf("Expected at most %d messages sent got %d", i+4, sent)
+		}
+
+		var rsp WindowRsp
+		if err := stream.Recv(&rsp); err != nil {
+			t.Fatal(err)
+		}
+		if rsp.Seq != int64(i) {
+			t.Fatalf("Expected message %d got %d", i, rsp.Seq)
+		}
+	}
+}
+
+func TestStreamWindowNoWait(t *testing.T) {
+	h, cli, stop := newWindowServer(t, server.StreamNoWait())
+	defer stop()
+
+	req := cli.NewRequest("go.micro.service.window", "TestWindow.Watch", &WindowReq{}, client.WithContentType("application/json"))
+	stream, err := cli.Stream(context.Background(), req, client.WithStreamWindow(4))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stream.Close()
+
+	if err := stream.Send(&WindowReq{}); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case err := <-h.errs:
+		if err != socket.ErrFull {
+			t.Fatalf("Expected %v got %v", socket.ErrFull, err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected the send to fail")
+	}
+	if sent := atomic.LoadInt64(&h.sent); sent != 4 {
+		t.Fatalf("Expected the 4 messages of the window sent got %d", sent)
+	}
+}
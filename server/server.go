@@ -36,6 +36,12 @@ type Server interface {
 	String() string
 }
 
+// Deregisterer is implemented by the servers which can be deregistered
+// apart from Stop, see KeepRegistered
+type Deregisterer interface {
+	Deregister() error
+}
+
 // Router handle serving messages
 type Router interface {
 	// ProcessMessage processes a message
@@ -111,12 +117,11 @@ type Stream interface {
 //
 // Example:
 //
-//      type Greeter struct {}
-//
-//      func (g *Greeter) Hello(context, request, response) error {
-//              return nil
-//      }
+//	type Greeter struct {}
 //
+//	func (g *Greeter) Hello(context, request, response) error {
+//	        return nil
+//	}
 type Handler interface {
 	Name() string
 	Handler() interface{}
@@ -146,6 +151,7 @@ var (
 	DefaultRegisterCheck           = func(context.Context) error { return nil }
 	DefaultRegisterInterval        = time.Second * 30
 	DefaultRegisterTTL             = time.Second * 90
+	DefaultDrainTimeout            = time.Second * 10
 
 	// NewServer creates a new server
 	NewServer func(...Option) Server = newRpcServer
@@ -185,7 +191,6 @@ func NewSubscriber(topic string, h interface{}, opts ...SubscriberOption) Subscr
 //	func (f *Foo) Bar(ctx, req, rsp) error {
 //		return nil
 //	}
-//
 func NewHandler(h interface{}, opts ...HandlerOption) Handler {
 	return DefaultServer.NewHandler(h, opts...)
 }
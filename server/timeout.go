@@ -0,0 +1,61 @@
+package server
+
+import (
+	"context"
+	"runtime/debug"
+	"time"
+
+	"github.com/micro/go-micro/v2/errors"
+	"github.com/micro/go-micro/v2/logger"
+)
+
+// Timeout returns the execution timeout of the endpoint, zero if it has none
+func (o Options) Timeout(endpoint string) time.Duration {
+	if d, ok := o.EndpointTimeouts[endpoint]; ok {
+		return d
+	}
+	return o.HandlerTimeout
+}
+
+// TimeoutHandler wraps a handler to cancel its context and return a 504 error
+// once it runs for longer than the timeout. The handler runs in its own
+// goroutine so the request is freed even if the handler ignores its context.
+func TimeoutHandler(d time.Duration) HandlerWrapper {
+	return func(h HandlerFunc) HandlerFunc {
+		if d <= 0 {
+			return h
+		}
+
+		return func(ctx context.Context, req Request, rsp interface{}) error {
+			hctx, cancel := context.WithTimeout(ctx, d)
+			defer cancel()
+
+			done := make(chan error, 1)
+
+			go func() {
+				defer func() {
+					if r := recover(); r != nil {
+						if logger.V(logger.ErrorLevel, logger.DefaultLogger) {
+							logger.Error("panic recovered: ", r)
+							logger.Error(string(debug.Stack()))
+						}
+						done <- errors.InternalServerError("go.micro.server", "panic recovered: %v", r)
+					}
+				}()
+
+				done <- h(hctx, req, rsp)
+			}()
+
+			select {
+			case err := <-done:
+				return err
+			case <-hctx.Done():
+				// the caller gave up first
+				if err := ctx.Err(); err != nil {
+					return errors.Timeout("go.micro.server", "%s: %v", req.Endpoint(), err)
+				}
+				return errors.DeadlineExceeded("go.micro.server", "%s exceeded its timeout of %v", req.Endpoint(), d)
+			}
+		}
+	}
+}
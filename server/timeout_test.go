@@ -0,0 +1,68 @@
+package server
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/micro/go-micro/v2/errors"
+)
+
+type timeoutRequest struct {
+	Request
+}
+
+func (r timeoutRequest) Endpoint() string {
+	return "Foo.Bar"
+}
+
+func TestTimeoutHandler(t *testing.T) {
+	opts := newOptions(
+		HandlerTimeout(time.Second),
+		EndpointTimeout("Foo.Bar", 20*time.Millisecond),
+	)
+	if d := opts.Timeout("Foo.Baz"); d != time.Second {
+		t.Fatalf("Expected the default timeout got %v", d)
+	}
+
+	release := make(chan bool)
+	defer close(release)
+
+	// the handler ignores its context
+	canceled := make(chan bool, 1)
+	h := TimeoutHandler(opts.Timeout("Foo.Bar"))(func(ctx context.Context, req Request, rsp interface{}) error {
+		<-release
+		if ctx.Err() != nil {
+			canceled <- true
+		}
+		return nil
+	})
+
+	start := time.Now()
+	err := h(context.Background(), timeoutRequest{}, nil)
+	if errors.FromError(err).Code != 504 {
+		t.Fatalf("Expected a 504 error got %v", err)
+	}
+	if time.Since(start) > 500*time.Millisecond {
+		t.Fatalf("Expected the request to be freed after the timeout got %v", time.Since(start))
+	}
+
+	release <- true
+	select {
+	case <-canceled:
+	case <-time.After(time.Second):
+		t.Fatal("Expected the context of the handler to be canceled")
+	}
+
+	// the caller gives up first
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Millisecond)
+	defer cancel()
+	h = TimeoutHandler(time.Second)(func(ctx context.Context, req Request, rsp interface{}) error {
+		<-ctx.Done()
+		<-release
+		return nil
+	})
+	if err := h(ctx, timeoutRequest{}, nil); errors.FromError(err).Code != 408 {
+		t.Fatalf("Expected a 408 error got %v", err)
+	}
+}
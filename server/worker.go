@@ -0,0 +1,103 @@
+package server
+
+import (
+	"sync"
+	"time"
+
+	"github.com/micro/go-micro/v2/errors"
+)
+
+// WorkerPool is a bounded pool of goroutines handling the requests
+// rather than a goroutine per request
+type WorkerPool struct {
+	timeout time.Duration
+	queue   chan func()
+	exit    chan bool
+	once    sync.Once
+}
+
+func (w *WorkerPool) run() {
+	for {
+		select {
+		case task := <-w.queue:
+			task()
+		case <-w.exit:
+			// run the tasks left in the queue
+			for {
+				select {
+				case task := <-w.queue:
+					task()
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+// Go runs the task with a worker. The task is queued until a worker is free,
+// it waits up to the queue timeout for room in the queue and is rejected
+// with a 503 if the queue is still full. A nil pool runs it in a goroutine.
+func (w *WorkerPool) Go(task func()) error {
+	if w == nil {
+		go task()
+		return nil
+	}
+
+	select {
+	case <-w.exit:
+		return errors.Unavailable("go.micro.server", "workers stopped")
+	default:
+	}
+
+	select {
+	case w.queue <- task:
+		return nil
+	default:
+	}
+
+	overloaded := func() error {
+		return errors.Overloaded("go.micro.server", DefaultShedRetryAfter, 1, "server overloaded, request queue full")
+	}
+
+	if w.timeout <= 0 {
+		return overloaded()
+	}
+
+	t := time.NewTimer(w.timeout)
+	defer t.Stop()
+
+	select {
+	case w.queue <- task:
+		return nil
+	case <-t.C:
+		return overloaded()
+	case <-w.exit:
+		return errors.Unavailable("go.micro.server", "workers stopped")
+	}
+}
+
+// Stop the workers once they've run the tasks queued
+func (w *WorkerPool) Stop() {
+	if w == nil {
+		return
+	}
+	w.once.Do(func() {
+		close(w.exit)
+	})
+}
+
+// NewWorkerPool starts size workers running the tasks queued. The queue
+// holds up to queue tasks waiting for a free worker, and the tasks wait
+// up to the timeout for room in a full queue before being rejected.
+func NewWorkerPool(size, queue int, timeout time.Duration) *WorkerPool {
+	w := &WorkerPool{
+		timeout: timeout,
+		queue:   make(chan func(), queue),
+		exit:    make(chan bool),
+	}
+	for i := 0; i < size; i++ {
+		go w.run()
+	}
+	return w
+}
@@ -0,0 +1,78 @@
+package server_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/micro/go-micro/v2/client"
+	"github.com/micro/go-micro/v2/errors"
+	"github.com/micro/go-micro/v2/server"
+)
+
+func TestWorkerPool(t *testing.T) {
+	w := server.NewWorkerPool(1, 1, 50*time.Millisecond)
+	defer w.Stop()
+
+	release := make(chan bool)
+	done := make(chan bool, 3)
+	task := func() {
+		<-release
+		done <- true
+	}
+
+	// one task running and one queued
+	if err := w.Go(task); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Go(task); err != nil {
+		t.Fatal(err)
+	}
+
+	// the queue is full
+	if err := w.Go(task); errors.Code(err) != 503 {
+		t.Fatalf("Expected a 503 error, got %v", err)
+	}
+
+	// room is made in the queue within the timeout
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		release <- true
+	}()
+	if err := w.Go(task); err != nil {
+		t.Fatal(err)
+	}
+
+	close(release)
+	for i := 0; i < 3; i++ {
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			t.Fatal("Expected the tasks to be run")
+		}
+	}
+}
+
+func TestServerWorkers(t *testing.T) {
+	h, srv, _, cli := newDrainServer(t, server.Workers(1))
+	defer srv.Stop()
+
+	req := cli.NewRequest("go.micro.service.drain", "TestDrain.Wait", &DrainReq{}, client.WithContentType("application/json"))
+
+	errs := make(chan error, 1)
+	go func() {
+		errs <- cli.Call(context.Background(), req, &DrainRsp{})
+	}()
+	<-h.started
+
+	// the only worker is busy and there's no queue
+	err := cli.Call(context.Background(), req, &DrainRsp{}, client.WithRetries(0))
+	if errors.Code(err) != 503 {
+		t.Fatalf("Expected a 503 error, got %v", err)
+	}
+
+	h.release <- true
+	if err := <-errs; err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,67 @@
+package server_test
+
+import (
+	"context"
+	"io"
+	"sync/atomic"
+	"testing"
+
+	"github.com/micro/go-micro/v2/client"
+	"github.com/micro/go-micro/v2/server"
+)
+
+type countStream struct {
+	server.Stream
+	sent *int64
+	recv *int64
+}
+
+func (s *countStream) Send(msg interface{}) error {
+	atomic.AddInt64(s.sent, 1)
+	return s.Stream.Send(msg)
+}
+
+func (s *countStream) Recv(msg interface{}) error {
+	atomic.AddInt64(s.recv, 1)
+	return s.Stream.Recv(msg)
+}
+
+func TestWrapStream(t *testing.T) {
+	var sent, recv, opened int64
+	wrap := func(s server.Stream) server.Stream {
+		atomic.AddInt64(&opened, 1)
+		return &countStream{Stream: s, sent: &sent, recv: &recv}
+	}
+
+	_, cli, stop := newWindowServer(t, server.WrapStream(wrap))
+	defer stop()
+
+	req := cli.NewRequest("go.micro.service.window", "TestWindow.Watch", &WindowReq{}, client.WithContentType("application/json"))
+	stream, err := cli.Stream(context.Background(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stream.Close()
+
+	if err := stream.Send(&WindowReq{}); err != nil {
+		t.Fatal(err)
+	}
+	for {
+		var rsp WindowRsp
+		if err := stream.Recv(&rsp); err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if n := atomic.LoadInt64(&opened); n != 1 {
+		t.Fatalf("Expected the stream to be wrapped once got %d", n)
+	}
+	if n := atomic.LoadInt64(&recv); n != 1 {
+		t.Fatalf("Expected 1 message received got %d", n)
+	}
+	if n := atomic.LoadInt64(&sent); n != 20 {
+		t.Fatalf("Expected 20 messages sent got %d", n)
+	}
+}
@@ -1,6 +1,7 @@
 package micro
 
 import (
+	"context"
 	"os"
 	"os/signal"
 	rtime "runtime"
@@ -10,13 +11,16 @@ import (
 	"github.com/micro/go-micro/v2/auth"
 	"github.com/micro/go-micro/v2/client"
 	"github.com/micro/go-micro/v2/config/cmd"
+	"github.com/micro/go-micro/v2/debug/dashboard"
 	"github.com/micro/go-micro/v2/debug/service/handler"
+	"github.com/micro/go-micro/v2/debug/size"
 	"github.com/micro/go-micro/v2/debug/stats"
 	"github.com/micro/go-micro/v2/debug/trace"
 	"github.com/micro/go-micro/v2/logger"
 	"github.com/micro/go-micro/v2/plugin"
 	"github.com/micro/go-micro/v2/server"
 	"github.com/micro/go-micro/v2/store"
+	"github.com/micro/go-micro/v2/util/shutdown"
 	signalutil "github.com/micro/go-micro/v2/util/signal"
 	"github.com/micro/go-micro/v2/util/wrapper"
 )
@@ -47,8 +51,10 @@ func newService(opts ...Option) Service {
 	// wrap the server to provide handler stats
 	options.Server.Init(
 		server.WrapHandler(wrapper.HandlerStats(stats.DefaultStats)),
+		server.WrapHandler(wrapper.SizeHandler(size.DefaultRecorder)),
 		server.WrapHandler(wrapper.TraceHandler(trace.DefaultTracer)),
 		server.WrapHandler(wrapper.AuthHandler(authFn)),
+		server.WrapSubscriber(wrapper.TraceSubscriber(trace.DefaultTracer)),
 	)
 
 	// set opts
@@ -139,6 +145,13 @@ func (s *service) Start() error {
 		}
 	}
 
+	// the server is deregistered last on stop
+	if _, ok := s.opts.Server.(server.Deregisterer); ok {
+		if err := s.opts.Server.Init(server.KeepRegistered(true)); err != nil {
+			return err
+		}
+	}
+
 	if err := s.opts.Server.Start(); err != nil {
 		return err
 	}
@@ -152,26 +165,73 @@ func (s *service) Start() error {
 	return nil
 }
 
+// Stop runs the shutdown stages in order. Every stage runs even if one
+// before it fails or times out so buffered data is flushed on exit.
 func (s *service) Stop() error {
-	var gerr error
+	c := shutdown.NewCoordinator(shutdown.Timeout(s.opts.ShutdownTimeouts[""]))
+
+	stage := func(name string, fn func() error) {
+		c.AddStage(shutdown.Stage{
+			Name:    name,
+			Timeout: s.opts.ShutdownTimeouts[name],
+			Stop: func(ctx context.Context) error {
+				return fn()
+			},
+		})
+	}
 
-	for _, fn := range s.opts.BeforeStop {
-		if err := fn(); err != nil {
-			gerr = err
+	stage(StageBeforeStop, func() error {
+		var gerr error
+		for _, fn := range s.opts.BeforeStop {
+			if err := fn(); err != nil {
+				gerr = err
+			}
 		}
+		return gerr
+	})
+	stage(StageServer, s.opts.Server.Stop)
+	if s.opts.Broker != nil {
+		stage(StageBroker, s.opts.Broker.Disconnect)
 	}
-
-	if err := s.opts.Server.Stop(); err != nil {
-		return err
+	if s.opts.Store != nil {
+		stage(StageStore, s.opts.Store.Close)
+	}
+	if s.opts.Config != nil {
+		stage(StageConfig, s.opts.Config.Close)
+	}
+	if d, ok := s.opts.Server.(server.Deregisterer); ok && s.opts.Server.Options().KeepRegistered {
+		stage(StageDeregister, d.Deregister)
 	}
+	stage(StageAfterStop, func() error {
+		var gerr error
+		for _, fn := range s.opts.AfterStop {
+			if err := fn(); err != nil {
+				gerr = err
+			}
+		}
+		return gerr
+	})
 
-	for _, fn := range s.opts.AfterStop {
-		if err := fn(); err != nil {
-			gerr = err
+	report := c.Shutdown(context.Background())
+
+	for _, res := range report {
+		switch {
+		case res.TimedOut():
+			if logger.V(logger.ErrorLevel, logger.DefaultLogger) {
+				logger.Errorf("Shutdown stage %s timed out after %v", res.Stage, res.Duration)
+			}
+		case res.Error != nil:
+			if logger.V(logger.ErrorLevel, logger.DefaultLogger) {
+				logger.Errorf("Shutdown stage %s failed after %v: %v", res.Stage, res.Duration, res.Error)
+			}
+		default:
+			if logger.V(logger.DebugLevel, logger.DefaultLogger) {
+				logger.Debugf("Shutdown stage %s finished in %v", res.Stage, res.Duration)
+			}
 		}
 	}
 
-	return gerr
+	return report.Err()
 }
 
 func (s *service) Run() error {
@@ -204,6 +264,21 @@ func (s *service) Run() error {
 		return err
 	}
 
+	// start the dashboard
+	if len(s.opts.Dashboard) > 0 {
+		d := dashboard.NewDashboard(
+			dashboard.Address(s.opts.Dashboard),
+			dashboard.Auth(s.opts.Auth),
+			dashboard.Registry(s.opts.Registry),
+			dashboard.Server(s.opts.Server),
+			dashboard.Config(s.opts.Config),
+		)
+		if err := d.Start(); err != nil {
+			return err
+		}
+		defer d.Stop()
+	}
+
 	ch := make(chan os.Signal, 1)
 	if s.opts.Signal {
 		signal.Notify(ch, signalutil.Shutdown()...)
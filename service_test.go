@@ -6,9 +6,15 @@ import (
 	"sync"
 	"testing"
 
+	"github.com/micro/go-micro/v2/broker"
+	bmemory "github.com/micro/go-micro/v2/broker/memory"
 	"github.com/micro/go-micro/v2/client"
+	"github.com/micro/go-micro/v2/config"
 	proto "github.com/micro/go-micro/v2/debug/service/proto"
+	"github.com/micro/go-micro/v2/registry"
 	"github.com/micro/go-micro/v2/registry/memory"
+	"github.com/micro/go-micro/v2/store"
+	smemory "github.com/micro/go-micro/v2/store/memory"
 	"github.com/micro/go-micro/v2/util/test"
 )
 
@@ -95,6 +101,99 @@ func TestService(t *testing.T) {
 	}
 }
 
+type shutdownOrder struct {
+	sync.Mutex
+	stages []string
+}
+
+func (o *shutdownOrder) add(stage string) {
+	o.Lock()
+	o.stages = append(o.stages, stage)
+	o.Unlock()
+}
+
+type orderBroker struct {
+	broker.Broker
+	order *shutdownOrder
+}
+
+func (b *orderBroker) Disconnect() error {
+	b.order.add(StageBroker)
+	return b.Broker.Disconnect()
+}
+
+type orderStore struct {
+	store.Store
+	order *shutdownOrder
+}
+
+func (s *orderStore) Close() error {
+	s.order.add(StageStore)
+	return s.Store.Close()
+}
+
+type orderConfig struct {
+	config.Config
+	order *shutdownOrder
+}
+
+func (c *orderConfig) Close() error {
+	c.order.add(StageConfig)
+	return c.Config.Close()
+}
+
+type orderRegistry struct {
+	registry.Registry
+	order *shutdownOrder
+}
+
+func (r *orderRegistry) Deregister(s *registry.Service, opts ...registry.DeregisterOption) error {
+	r.order.add(StageDeregister)
+	return r.Registry.Deregister(s, opts...)
+}
+
+// TestShutdownOrder tests the service is deregistered once stopped
+func TestShutdownOrder(t *testing.T) {
+	order := new(shutdownOrder)
+
+	c, err := config.NewConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	service := newService(
+		Name("test.shutdown"),
+		Broker(&orderBroker{bmemory.NewBroker(), order}),
+		Registry(&orderRegistry{memory.NewRegistry(), order}),
+		Store(&orderStore{smemory.NewStore(), order}),
+		Config(&orderConfig{c, order}),
+	).(*service)
+
+	if err := service.Start(); err != nil {
+		t.Fatal(err)
+	}
+	if err := service.Stop(); err != nil {
+		t.Fatal(err)
+	}
+
+	order.Lock()
+	defer order.Unlock()
+
+	// the server disconnects its broker too
+	seen := make(map[string]int)
+	for _, stage := range order.stages {
+		seen[stage]++
+	}
+	for _, stage := range []string{StageBroker, StageStore, StageConfig} {
+		if seen[stage] == 0 {
+			t.Fatalf("Expected the %s stage to run, got %v", stage, order.stages)
+		}
+	}
+	if n := len(order.stages); seen[StageDeregister] != 1 || order.stages[n-1] != StageDeregister {
+		t.Fatalf("Expected the service to be deregistered last, got %v", order.stages)
+	}
+}
+
 func benchmarkService(b *testing.B, n int, name string) {
 	// stop the timer
 	b.StopTimer()
@@ -0,0 +1,158 @@
+// Package backup exports the records of a store to a portable snapshot and
+// imports them into another store e.g to move from the file store to
+// cockroach. The snapshot is newline delimited json: a header followed by a
+// record per line so it's written and read as a stream.
+package backup
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/micro/go-micro/v2/store"
+)
+
+// Version of the snapshot format
+const Version = 1
+
+// Header is the first line of a snapshot
+type Header struct {
+	Version  int    `json:"version"`
+	Database string `json:"database"`
+	Table    string `json:"table"`
+}
+
+// record of a snapshot. The expiry is a timestamp so it stays correct
+// however long after the export the snapshot is imported.
+type record struct {
+	Key      string                 `json:"key"`
+	Value    []byte                 `json:"value"`
+	Metadata map[string]interface{} `json:"metadata,omitempty"`
+	Expires  *time.Time             `json:"expires,omitempty"`
+}
+
+// Export writes the records of the store matching the options to w and
+// returns the number of records written. The records are read a page at a
+// time, the list limit sets the size of the pages.
+func Export(s store.Store, w io.Writer, opts ...store.ListOption) (int, error) {
+	var listOpts store.ListOptions
+	for _, o := range opts {
+		o(&listOpts)
+	}
+
+	h := Header{
+		Version:  Version,
+		Database: listOpts.Database,
+		Table:    listOpts.Table,
+	}
+	if len(h.Database) == 0 {
+		h.Database = s.Options().Database
+	}
+	if len(h.Table) == 0 {
+		h.Table = s.Options().Table
+	}
+
+	enc := json.NewEncoder(w)
+	if err := enc.Encode(h); err != nil {
+		return 0, err
+	}
+
+	var n int
+
+	it := store.Iterate(s, "", opts...)
+	for {
+		r, err := it.Next()
+		if err == store.ErrIteratorDone {
+			break
+		} else if err != nil {
+			return n, err
+		}
+
+		rec := &record{
+			Key:      r.Key,
+			Value:    r.Value,
+			Metadata: r.Metadata,
+		}
+		if r.Expiry > 0 {
+			t := time.Now().Add(r.Expiry)
+			rec.Expires = &t
+		}
+		if err := enc.Encode(rec); err != nil {
+			return n, err
+		}
+		n++
+	}
+
+	return n, nil
+}
+
+// Import writes the records of the snapshot read from r to the store and
+// returns the number of records written. The records are written to the
+// database and table of the snapshot unless set by the options. The records
+// which expired since the export are skipped.
+func Import(s store.Store, r io.Reader, opts ...store.WriteOption) (int, error) {
+	dec := json.NewDecoder(bufio.NewReader(r))
+
+	var h Header
+	if err := dec.Decode(&h); err != nil {
+		return 0, fmt.Errorf("invalid snapshot header: %v", err)
+	}
+	if h.Version != Version {
+		return 0, fmt.Errorf("unsupported snapshot version %d", h.Version)
+	}
+
+	var writeOpts store.WriteOptions
+	for _, o := range opts {
+		o(&writeOpts)
+	}
+	database, table := h.Database, h.Table
+	if len(writeOpts.Database) > 0 || len(writeOpts.Table) > 0 {
+		database, table = writeOpts.Database, writeOpts.Table
+	}
+
+	var n int
+
+	for {
+		var rec record
+		if err := dec.Decode(&rec); err == io.EOF {
+			return n, nil
+		} else if err != nil {
+			return n, fmt.Errorf("invalid snapshot record %d: %v", n+1, err)
+		}
+
+		wopts := []store.WriteOption{store.WriteTo(database, table)}
+		if rec.Expires != nil {
+			if !rec.Expires.After(time.Now()) {
+				continue
+			}
+			wopts = append(wopts, store.WriteExpiry(*rec.Expires))
+		}
+
+		if err := s.Write(&store.Record{
+			Key:      rec.Key,
+			Value:    rec.Value,
+			Metadata: rec.Metadata,
+		}, wopts...); err != nil {
+			return n, err
+		}
+		n++
+	}
+}
+
+// Copy the records of a store matching the options to another store through
+// a snapshot streamed between them
+func Copy(from, to store.Store, opts ...store.ListOption) (int, error) {
+	pr, pw := io.Pipe()
+
+	go func() {
+		_, err := Export(from, pw, opts...)
+		pw.CloseWithError(err)
+	}()
+
+	n, err := Import(to, pr)
+	// unblock the export if the import failed
+	pr.CloseWithError(err)
+	return n, err
+}
@@ -0,0 +1,64 @@
+package backup
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/micro/go-micro/v2/store"
+	"github.com/micro/go-micro/v2/store/memory"
+)
+
+func TestExportImport(t *testing.T) {
+	from := memory.NewStore()
+	from.Write(&store.Record{Key: "foo", Value: []byte("bar"), Metadata: map[string]interface{}{"n": "1"}})
+	from.Write(&store.Record{Key: "baz", Value: []byte("qux"), Expiry: time.Hour})
+	from.Write(&store.Record{Key: "old", Value: []byte("x"), Expiry: time.Millisecond * 50})
+	from.Write(&store.Record{Key: "other", Value: []byte("y")}, store.WriteTo("micro", "other"))
+
+	var buf bytes.Buffer
+	n, err := Export(from, &buf)
+	if err != nil || n != 3 {
+		t.Fatalf("Expected 3 records exported got %d %v", n, err)
+	}
+	if !strings.HasPrefix(buf.String(), `{"version":1,"database":"micro","table":"micro"}`) {
+		t.Fatalf("Unexpected header %s", buf.String())
+	}
+
+	time.Sleep(time.Millisecond * 100)
+
+	to := memory.NewStore()
+	n, err = Import(to, bytes.NewReader(buf.Bytes()), store.WriteTo("backup", "micro"))
+	if err != nil || n != 2 {
+		t.Fatalf("Expected 2 unexpired records imported got %d %v", n, err)
+	}
+
+	recs, err := to.Read("foo", store.ReadFrom("backup", "micro"))
+	if err != nil || string(recs[0].Value) != "bar" || recs[0].Metadata["n"] != "1" {
+		t.Fatalf("Expected the imported record got %v %v", recs, err)
+	}
+	recs, err = to.Read("baz", store.ReadFrom("backup", "micro"))
+	if err != nil || recs[0].Expiry < time.Minute*59 {
+		t.Fatalf("Expected the expiry to be kept got %v %v", recs, err)
+	}
+
+	if _, err := Import(to, strings.NewReader(`{"version":2}`)); err == nil {
+		t.Fatal("Expected an unsupported version error")
+	}
+}
+
+func TestCopy(t *testing.T) {
+	from, to := memory.NewStore(), memory.NewStore()
+	for _, k := range []string{"a", "b", "c"} {
+		from.Write(&store.Record{Key: k, Value: []byte(k)}, store.WriteTo("micro", "users"))
+	}
+
+	n, err := Copy(from, to, store.ListFrom("micro", "users"))
+	if err != nil || n != 3 {
+		t.Fatalf("Expected 3 records copied got %d %v", n, err)
+	}
+	if recs, err := to.Read("b", store.ReadFrom("micro", "users")); err != nil || string(recs[0].Value) != "b" {
+		t.Fatalf("Expected the copied record got %v %v", recs, err)
+	}
+}
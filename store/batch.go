@@ -0,0 +1,87 @@
+package store
+
+// Batcher is implemented by stores which write, read and delete records in
+// batches natively e.g within a single transaction or round trip
+type Batcher interface {
+	// BatchWrite writes the records
+	BatchWrite(recs []*Record, opts ...WriteOption) error
+	// BatchRead returns the records of the keys which exist
+	BatchRead(keys []string, opts ...ReadOption) ([]*Record, error)
+	// BatchDelete removes the records of the keys
+	BatchDelete(keys []string, opts ...DeleteOption) error
+}
+
+// BatchWrite writes the records to the store. The stores which don't
+// implement Batcher write them in a transaction if they're Transactional or
+// one by one otherwise.
+func BatchWrite(s Store, recs []*Record, opts ...WriteOption) error {
+	if b, ok := s.(Batcher); ok {
+		return b.BatchWrite(recs, opts...)
+	}
+	if _, ok := s.(Transactional); ok {
+		return Update(s, func(tx Tx) error {
+			for _, r := range recs {
+				if err := tx.Write(r, opts...); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	}
+	for _, r := range recs {
+		if err := s.Write(r, opts...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// BatchRead returns the records of the keys which exist in the order of the
+// keys. Prefix and suffix read options are ignored.
+func BatchRead(s Store, keys []string, opts ...ReadOption) ([]*Record, error) {
+	if b, ok := s.(Batcher); ok {
+		return b.BatchRead(keys, opts...)
+	}
+
+	var readOpts ReadOptions
+	for _, o := range opts {
+		o(&readOpts)
+	}
+
+	var results []*Record
+	for _, k := range keys {
+		recs, err := s.Read(k, ReadFrom(readOpts.Database, readOpts.Table))
+		if err == ErrNotFound {
+			continue
+		} else if err != nil {
+			return results, err
+		}
+		results = append(results, recs...)
+	}
+	return results, nil
+}
+
+// BatchDelete removes the records of the keys from the store. The stores
+// which don't implement Batcher delete them in a transaction if they're
+// Transactional or one by one otherwise.
+func BatchDelete(s Store, keys []string, opts ...DeleteOption) error {
+	if b, ok := s.(Batcher); ok {
+		return b.BatchDelete(keys, opts...)
+	}
+	if _, ok := s.(Transactional); ok {
+		return Update(s, func(tx Tx) error {
+			for _, k := range keys {
+				if err := tx.Delete(k, opts...); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	}
+	for _, k := range keys {
+		if err := s.Delete(k, opts...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
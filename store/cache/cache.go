@@ -1,4 +1,5 @@
 // Package cache implements a faulting style read cache on top of multiple micro stores
+// and a tiered store caching a remote store in a local one
 package cache
 
 import (
@@ -0,0 +1,339 @@
+package cache
+
+import (
+	"fmt"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/micro/go-micro/v2/store"
+	"github.com/pkg/errors"
+)
+
+var (
+	// DefaultTTL is the time the records are cached in the local store
+	DefaultTTL = time.Minute
+	// DefaultFlushInterval is the interval the writes are flushed to the
+	// remote store in the write back mode
+	DefaultFlushInterval = time.Second
+)
+
+// Tiered is a store caching the records of a remote store in a local store
+type Tiered interface {
+	store.Store
+	// Flush writes the pending writes and deletes to the remote store
+	Flush() error
+}
+
+// TieredOptions of the tiered store
+type TieredOptions struct {
+	// TTL of the records in the local store. They're read again from the
+	// remote store once expired.
+	TTL time.Duration
+	// WriteBack writes to the remote store in the background rather than
+	// on every write
+	WriteBack bool
+	// FlushInterval of the writes in the write back mode
+	FlushInterval time.Duration
+}
+
+// TieredOption sets values in TieredOptions
+type TieredOption func(o *TieredOptions)
+
+// TTL of the records in the local store
+func TTL(d time.Duration) TieredOption {
+	return func(o *TieredOptions) {
+		o.TTL = d
+	}
+}
+
+// WriteBack writes to the remote store every interval rather than on every
+// write. The writes not yet flushed are lost if the process dies.
+func WriteBack(interval time.Duration) TieredOption {
+	return func(o *TieredOptions) {
+		o.WriteBack = true
+		o.FlushInterval = interval
+	}
+}
+
+type tiered struct {
+	local, remote store.Store
+	opts          TieredOptions
+
+	sync.Mutex
+	// the writes and deletes not yet flushed by database, table and key
+	pending map[string]*pendingWrite
+	// serialises the flushes so an older write can't land after a newer one
+	flushing sync.Mutex
+	exit     chan bool
+}
+
+type pendingWrite struct {
+	database, table string
+	key             string
+	// record written, nil if deleted
+	record    *store.Record
+	expiresAt time.Time
+}
+
+// NewTiered returns a store reading through a fast local store to a remote
+// store e.g a memory or file store in front of a cockroach store. The writes
+// go through to the remote store unless WriteBack is set.
+func NewTiered(local, remote store.Store, opts ...TieredOption) Tiered {
+	options := TieredOptions{
+		TTL:           DefaultTTL,
+		FlushInterval: DefaultFlushInterval,
+	}
+	for _, o := range opts {
+		o(&options)
+	}
+
+	t := &tiered{
+		local:   local,
+		remote:  remote,
+		opts:    options,
+		pending: make(map[string]*pendingWrite),
+		exit:    make(chan bool),
+	}
+
+	if options.WriteBack {
+		go t.run()
+	}
+
+	return t
+}
+
+func (t *tiered) run() {
+	ticker := time.NewTicker(t.opts.FlushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			t.Flush()
+		case <-t.exit:
+			return
+		}
+	}
+}
+
+// ttl of a record in the local store
+func (t *tiered) ttl(expiry time.Duration) time.Duration {
+	if expiry > 0 && expiry < t.opts.TTL {
+		return expiry
+	}
+	return t.opts.TTL
+}
+
+func (t *tiered) cache(database, table string, r *store.Record) error {
+	return t.local.Write(r, store.WriteTo(database, table), store.WriteTTL(t.ttl(r.Expiry)))
+}
+
+func (t *tiered) Flush() error {
+	t.flushing.Lock()
+	defer t.flushing.Unlock()
+
+	t.Lock()
+	pending := t.pending
+	t.pending = make(map[string]*pendingWrite)
+	t.Unlock()
+
+	var failed []string
+	var ferr error
+
+	for k, p := range pending {
+		var err error
+		if p.record == nil {
+			err = t.remote.Delete(p.key, store.DeleteFrom(p.database, p.table))
+		} else {
+			var opts []store.WriteOption
+			opts = append(opts, store.WriteTo(p.database, p.table))
+			if !p.expiresAt.IsZero() {
+				if !p.expiresAt.After(time.Now()) {
+					// expired before it was flushed
+					continue
+				}
+				opts = append(opts, store.WriteExpiry(p.expiresAt))
+			}
+			err = t.remote.Write(p.record, opts...)
+		}
+		if err != nil {
+			failed = append(failed, k)
+			if ferr == nil {
+				ferr = errors.Wrapf(err, "could not flush %s to %s", p.key, t.remote.String())
+			}
+		}
+	}
+
+	if len(failed) == 0 {
+		return nil
+	}
+
+	// retry the failed writes on the next flush unless written again since
+	t.Lock()
+	for _, k := range failed {
+		if _, ok := t.pending[k]; !ok {
+			t.pending[k] = pending[k]
+		}
+	}
+	t.Unlock()
+
+	return ferr
+}
+
+func (t *tiered) Init(opts ...store.Option) error {
+	if err := t.local.Init(opts...); err != nil {
+		return err
+	}
+	return t.remote.Init(opts...)
+}
+
+func (t *tiered) Options() store.Options {
+	return t.remote.Options()
+}
+
+func (t *tiered) Read(key string, opts ...store.ReadOption) ([]*store.Record, error) {
+	var readOpts store.ReadOptions
+	for _, o := range opts {
+		o(&readOpts)
+	}
+
+	if !readOpts.Prefix && !readOpts.Suffix {
+		r, err := t.readOne(readOpts.Database, readOpts.Table, key)
+		if err != nil {
+			return nil, err
+		}
+		return []*store.Record{r}, nil
+	}
+
+	// the remote store has every key once flushed
+	if err := t.Flush(); err != nil {
+		return nil, err
+	}
+	recs, err := t.remote.Read(key, opts...)
+	if err != nil {
+		return recs, err
+	}
+	for _, r := range recs {
+		t.cache(readOpts.Database, readOpts.Table, r)
+	}
+	return recs, nil
+}
+
+func (t *tiered) readOne(database, table, key string) (*store.Record, error) {
+	t.Lock()
+	p, ok := t.pending[filepath.Join(database, table, key)]
+	t.Unlock()
+
+	if ok {
+		if p.record == nil || (!p.expiresAt.IsZero() && !p.expiresAt.After(time.Now())) {
+			return nil, store.ErrNotFound
+		}
+		r := *p.record
+		if !p.expiresAt.IsZero() {
+			r.Expiry = time.Until(p.expiresAt)
+		}
+		return &r, nil
+	}
+
+	if recs, err := t.local.Read(key, store.ReadFrom(database, table)); err == nil && len(recs) > 0 {
+		return recs[0], nil
+	}
+
+	recs, err := t.remote.Read(key, store.ReadFrom(database, table))
+	if err != nil {
+		return nil, err
+	}
+	if len(recs) == 0 {
+		return nil, store.ErrNotFound
+	}
+	if err := t.cache(database, table, recs[0]); err != nil {
+		return nil, errors.Wrapf(err, "could not cache %s in %s", key, t.local.String())
+	}
+	return recs[0], nil
+}
+
+func (t *tiered) Write(r *store.Record, opts ...store.WriteOption) error {
+	var writeOpts store.WriteOptions
+	for _, o := range opts {
+		o(&writeOpts)
+	}
+
+	// the expiry of the record with the options applied
+	rec := *r
+	if !writeOpts.Expiry.IsZero() {
+		rec.Expiry = time.Until(writeOpts.Expiry)
+	}
+	if writeOpts.TTL != 0 {
+		rec.Expiry = writeOpts.TTL
+	}
+
+	if !t.opts.WriteBack {
+		if err := t.remote.Write(&rec, store.WriteTo(writeOpts.Database, writeOpts.Table)); err != nil {
+			return err
+		}
+		return t.cache(writeOpts.Database, writeOpts.Table, &rec)
+	}
+
+	p := &pendingWrite{
+		database: writeOpts.Database,
+		table:    writeOpts.Table,
+		key:      r.Key,
+		record:   &rec,
+	}
+	if rec.Expiry > 0 {
+		p.expiresAt = time.Now().Add(rec.Expiry)
+	}
+
+	t.Lock()
+	t.pending[filepath.Join(p.database, p.table, p.key)] = p
+	t.Unlock()
+
+	return t.cache(writeOpts.Database, writeOpts.Table, &rec)
+}
+
+func (t *tiered) Delete(key string, opts ...store.DeleteOption) error {
+	var deleteOpts store.DeleteOptions
+	for _, o := range opts {
+		o(&deleteOpts)
+	}
+
+	if !t.opts.WriteBack {
+		if err := t.remote.Delete(key, opts...); err != nil {
+			return err
+		}
+		return t.local.Delete(key, opts...)
+	}
+
+	t.Lock()
+	t.pending[filepath.Join(deleteOpts.Database, deleteOpts.Table, key)] = &pendingWrite{
+		database: deleteOpts.Database,
+		table:    deleteOpts.Table,
+		key:      key,
+	}
+	t.Unlock()
+
+	return t.local.Delete(key, opts...)
+}
+
+func (t *tiered) List(opts ...store.ListOption) ([]string, error) {
+	if err := t.Flush(); err != nil {
+		return nil, err
+	}
+	return t.remote.List(opts...)
+}
+
+// Close flushes the pending writes. The stores are not closed.
+func (t *tiered) Close() error {
+	select {
+	case <-t.exit:
+		return nil
+	default:
+		close(t.exit)
+	}
+	return t.Flush()
+}
+
+func (t *tiered) String() string {
+	return fmt.Sprintf("tiered [%s %s]", t.local.String(), t.remote.String())
+}
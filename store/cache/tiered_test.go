@@ -0,0 +1,116 @@
+package cache
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/micro/go-micro/v2/store"
+	"github.com/micro/go-micro/v2/store/memory"
+)
+
+func TestTieredWriteThrough(t *testing.T) {
+	local, remote := memory.NewStore(), memory.NewStore()
+	s := NewTiered(local, remote, TTL(time.Millisecond*50))
+	defer s.Close()
+
+	if err := s.Write(&store.Record{Key: "foo", Value: []byte("bar")}); err != nil {
+		t.Fatal(err)
+	}
+	if recs, err := remote.Read("foo"); err != nil || string(recs[0].Value) != "bar" {
+		t.Fatalf("Expected the record written through got %v %v", recs, err)
+	}
+
+	// the local copy is read until it expires
+	remote.Write(&store.Record{Key: "foo", Value: []byte("baz")})
+	if recs, err := s.Read("foo"); err != nil || string(recs[0].Value) != "bar" {
+		t.Fatalf("Expected the cached record got %v %v", recs, err)
+	}
+	time.Sleep(time.Millisecond * 100)
+	if recs, err := s.Read("foo"); err != nil || string(recs[0].Value) != "baz" {
+		t.Fatalf("Expected the remote record got %v %v", recs, err)
+	}
+	if _, err := local.Read("foo"); err != nil {
+		t.Fatalf("Expected the record read through to be cached got %v", err)
+	}
+
+	s.Delete("foo")
+	if _, err := s.Read("foo"); err != store.ErrNotFound {
+		t.Fatalf("Expected not found got %v", err)
+	}
+}
+
+func TestTieredWriteBack(t *testing.T) {
+	local, remote := memory.NewStore(), memory.NewStore()
+	s := NewTiered(local, remote, WriteBack(time.Hour))
+
+	s.Write(&store.Record{Key: "foo", Value: []byte("bar")})
+	s.Write(&store.Record{Key: "baz", Value: []byte("qux")})
+	if _, err := remote.Read("foo"); err != store.ErrNotFound {
+		t.Fatalf("Expected the write to be pending got %v", err)
+	}
+	if recs, err := s.Read("foo"); err != nil || string(recs[0].Value) != "bar" {
+		t.Fatalf("Expected the pending record got %v %v", recs, err)
+	}
+
+	if err := s.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if recs, err := remote.Read("foo"); err != nil || string(recs[0].Value) != "bar" {
+		t.Fatalf("Expected the flushed record got %v %v", recs, err)
+	}
+
+	// the pending delete hides the remote record
+	s.Delete("foo")
+	if _, err := s.Read("foo"); err != store.ErrNotFound {
+		t.Fatalf("Expected not found got %v", err)
+	}
+
+	// closing flushes the pending writes
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if keys, err := remote.List(); err != nil || len(keys) != 1 || keys[0] != "baz" {
+		t.Fatalf("Expected the baz key got %v %v", keys, err)
+	}
+}
+
+// slowStore blocks the first write until released
+type slowStore struct {
+	store.Store
+	writes           int32
+	started, release chan bool
+}
+
+func (s *slowStore) Write(r *store.Record, opts ...store.WriteOption) error {
+	if atomic.AddInt32(&s.writes, 1) == 1 {
+		s.started <- true
+		<-s.release
+	}
+	return s.Store.Write(r, opts...)
+}
+
+func TestTieredFlushOrder(t *testing.T) {
+	remote := &slowStore{Store: memory.NewStore(), started: make(chan bool), release: make(chan bool)}
+	s := NewTiered(memory.NewStore(), remote, WriteBack(time.Hour))
+
+	s.Write(&store.Record{Key: "foo", Value: []byte("old")})
+	done := make(chan error, 2)
+	go func() { done <- s.Flush() }()
+	<-remote.started
+
+	// the newer write is flushed once the older one landed
+	s.Write(&store.Record{Key: "foo", Value: []byte("new")})
+	go func() { done <- s.Flush() }()
+	time.Sleep(10 * time.Millisecond)
+	close(remote.release)
+
+	for i := 0; i < 2; i++ {
+		if err := <-done; err != nil {
+			t.Fatal(err)
+		}
+	}
+	if recs, err := remote.Read("foo"); err != nil || string(recs[0].Value) != "new" {
+		t.Fatalf("Expected the newer record got %v %v", recs, err)
+	}
+}
@@ -0,0 +1,70 @@
+package store
+
+// Capability is an optional feature of a store. The stores which support a
+// capability implement its interface.
+type Capability int
+
+const (
+	// CapTransactions are multi-key atomic writes, see Transactional
+	CapTransactions Capability = iota
+	// CapCompareAndSwap are conditional writes, see Swapper
+	CapCompareAndSwap
+	// CapQuery is querying records by indexed fields, see Querier
+	CapQuery
+	// CapWatch is notifying the changes of records, see Watchable. The
+	// other stores are watched by polling.
+	CapWatch
+	// CapBatch is writing, reading and deleting records in batches
+	// natively, see Batcher
+	CapBatch
+	// CapPaging is reading the records a page at a time, see Pager
+	CapPaging
+)
+
+func (c Capability) String() string {
+	switch c {
+	case CapTransactions:
+		return "transactions"
+	case CapCompareAndSwap:
+		return "compare-and-swap"
+	case CapQuery:
+		return "query"
+	case CapWatch:
+		return "watch"
+	case CapBatch:
+		return "batch"
+	case CapPaging:
+		return "paging"
+	}
+	return "unknown"
+}
+
+// Supports returns whether the store supports the capability e.g to fall
+// back to a lease when it can't compare and swap
+//
+//	if !store.Supports(s, store.CapCompareAndSwap) {
+//		...
+//	}
+func Supports(s Store, c Capability) bool {
+	switch c {
+	case CapTransactions:
+		_, ok := s.(Transactional)
+		return ok
+	case CapCompareAndSwap:
+		_, ok := s.(Swapper)
+		return ok
+	case CapQuery:
+		_, ok := s.(Querier)
+		return ok
+	case CapWatch:
+		_, ok := s.(Watchable)
+		return ok
+	case CapBatch:
+		_, ok := s.(Batcher)
+		return ok
+	case CapPaging:
+		_, ok := s.(Pager)
+		return ok
+	}
+	return false
+}
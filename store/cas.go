@@ -0,0 +1,31 @@
+package store
+
+import "errors"
+
+var (
+	// ErrCASNotSupported is returned when swapping a record of a store which
+	// doesn't implement Swapper
+	ErrCASNotSupported = errors.New("compare and swap not supported")
+	// ErrConflict is returned when the current value of a record isn't the
+	// one expected by a compare and swap
+	ErrConflict = errors.New("conflict")
+)
+
+// Swapper is implemented by stores which support conditional writes
+type Swapper interface {
+	// CompareAndSwap writes the record if the current value of its key is old.
+	// A nil old value only writes the record if the key doesn't exist.
+	CompareAndSwap(r *Record, old []byte, opts ...WriteOption) error
+}
+
+// CompareAndSwap writes the record if the current value of its key is old
+// and returns ErrConflict otherwise e.g to take a lock
+//
+//	err := store.CompareAndSwap(s, &store.Record{Key: "lock", Value: id, Expiry: ttl}, nil)
+func CompareAndSwap(s Store, r *Record, old []byte, opts ...WriteOption) error {
+	c, ok := s.(Swapper)
+	if !ok {
+		return ErrCASNotSupported
+	}
+	return c.CompareAndSwap(r, old, opts...)
+}
@@ -30,6 +30,7 @@ var (
 		"list":       "SELECT key, value, metadata, expiry FROM %s.%s;",
 		"read":       "SELECT key, value, metadata, expiry FROM %s.%s WHERE key = $1;",
 		"readMany":   "SELECT key, value, metadata, expiry FROM %s.%s WHERE key LIKE $1;",
+		"page":       "SELECT key, value, metadata, expiry FROM %s.%s WHERE key > $1 AND key LIKE $2 ORDER BY key LIMIT $3;",
 		"readOffset": "SELECT key, value, metadata, expiry FROM %s.%s WHERE key LIKE $1 ORDER BY key DESC LIMIT $2 OFFSET $3;",
 		"write":      "INSERT INTO %s.%s(key, value, metadata, expiry) VALUES ($1, $2::bytea, $3, $4) ON CONFLICT (key) DO UPDATE SET value = EXCLUDED.value, metadata = EXCLUDED.metadata, expiry = EXCLUDED.expiry;",
 		"delete":     "DELETE FROM %s.%s WHERE key = $1;",
@@ -128,9 +129,42 @@ func (s *sqlStore) initDB(database, table string) error {
 		return err
 	}
 
+	// Create the secondary indexes on computed columns of the metadata
+	for _, idx := range s.options.Indexes {
+		column := indexColumn(idx.Field)
+		expr := fmt.Sprintf("metadata->>%s", pq.QuoteLiteral(idx.Field))
+		if idx.Type == store.IndexNumber {
+			expr = fmt.Sprintf("(%s)::DECIMAL", expr)
+		}
+
+		_, err = s.db.Exec(fmt.Sprintf(`ALTER TABLE %s.%s ADD COLUMN IF NOT EXISTS %s %s AS (%s) STORED;`,
+			database, table, column, indexColumnType(idx.Type), expr))
+		if err != nil {
+			return errors.Wrap(err, "Couldn't create index column "+column)
+		}
+
+		_, err = s.db.Exec(fmt.Sprintf(`CREATE INDEX IF NOT EXISTS "%s" ON %s.%s (%s, key);`,
+			column+"_index_"+table, database, table, column))
+		if err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
+// indexColumn returns the name of the computed column for an indexed field
+func indexColumn(field string) string {
+	return "idx_" + re.ReplaceAllString(field, "_")
+}
+
+func indexColumnType(t store.IndexType) string {
+	if t == store.IndexNumber {
+		return "DECIMAL"
+	}
+	return "TEXT"
+}
+
 func (s *sqlStore) configure() error {
 	if len(s.options.Nodes) == 0 {
 		s.options.Nodes = []string{"postgresql://root@localhost:26257?sslmode=disable"}
@@ -398,6 +432,85 @@ func (s *sqlStore) read(key string, options store.ReadOptions) ([]*store.Record,
 	return records, nil
 }
 
+// Page returns the records after the cursor in key order, read with the
+// primary key index
+func (s *sqlStore) Page(cursor string, limit uint, opts ...store.ListOption) ([]*store.Record, error) {
+	var options store.ListOptions
+	for _, o := range opts {
+		o(&options)
+	}
+
+	// create the db if not exists
+	if err := s.createDB(options.Database, options.Table); err != nil {
+		return nil, err
+	}
+
+	st, err := s.prepare(options.Database, options.Table, "page")
+	if err != nil {
+		return nil, err
+	}
+	defer st.Close()
+
+	pattern := options.Prefix + "%" + options.Suffix
+
+	var records []*store.Record
+
+	// fill the page as the expired records are skipped, there's no limit
+	// if it's zero
+	for limit == 0 || uint(len(records)) < limit {
+		var n interface{}
+		if limit > 0 {
+			n = limit - uint(len(records))
+		}
+		rows, err := st.Query(cursor, pattern, n)
+		if err != nil {
+			return records, err
+		}
+
+		var scanned uint
+		var timehelper pq.NullTime
+
+		for rows.Next() {
+			record := &store.Record{}
+			metadata := make(Metadata)
+
+			if err := rows.Scan(&record.Key, &record.Value, &metadata, &timehelper); err != nil {
+				rows.Close()
+				return records, err
+			}
+			scanned++
+			cursor = record.Key
+
+			// set the metadata
+			record.Metadata = toMetadata(&metadata)
+
+			if timehelper.Valid {
+				if timehelper.Time.Before(time.Now()) {
+					// record has expired
+					go s.Delete(record.Key, store.DeleteFrom(options.Database, options.Table))
+					continue
+				}
+				record.Expiry = time.Until(timehelper.Time)
+			}
+			records = append(records, record)
+		}
+		if err := rows.Err(); err != nil {
+			rows.Close()
+			return records, err
+		}
+		if err := rows.Close(); err != nil {
+			return records, err
+		}
+
+		// the last rows were read
+		if n == nil || scanned < n.(uint) {
+			break
+		}
+	}
+
+	return records, nil
+}
+
 // Write records
 func (s *sqlStore) Write(r *store.Record, opts ...store.WriteOption) error {
 	var options store.WriteOptions
@@ -465,6 +578,104 @@ func (s *sqlStore) Delete(key string, opts ...store.DeleteOption) error {
 	return nil
 }
 
+// Query records by an indexed metadata field
+func (s *sqlStore) Query(opts ...store.QueryOption) ([]*store.Record, error) {
+	var options store.QueryOptions
+	for _, o := range opts {
+		o(&options)
+	}
+
+	idx, err := store.LookupIndex(s.options.Indexes, options.Field)
+	if err != nil {
+		return nil, err
+	}
+
+	// create the db if not exists
+	if err := s.createDB(options.Database, options.Table); err != nil {
+		return nil, err
+	}
+
+	database, table := s.getDB(options.Database, options.Table)
+	column := indexColumn(idx.Field)
+
+	var where []string
+	var args []interface{}
+
+	for _, c := range []struct {
+		op    string
+		value interface{}
+	}{
+		{"=", options.Equal},
+		{">=", options.Min},
+		{"<=", options.Max},
+	} {
+		if c.value == nil {
+			continue
+		}
+		v, err := store.IndexValue(idx.Type, c.value)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, v)
+		where = append(where, fmt.Sprintf("%s %s $%d", column, c.op, len(args)))
+	}
+
+	q := fmt.Sprintf("SELECT key, value, metadata, expiry FROM %s.%s", database, table)
+	if len(where) > 0 {
+		q += " WHERE " + strings.Join(where, " AND ")
+	} else {
+		q += fmt.Sprintf(" WHERE %s IS NOT NULL", column)
+	}
+	q += fmt.Sprintf(" ORDER BY %s, key", column)
+
+	if options.Limit > 0 {
+		args = append(args, options.Limit)
+		q += fmt.Sprintf(" LIMIT $%d", len(args))
+	}
+	if options.Offset > 0 {
+		args = append(args, options.Offset)
+		q += fmt.Sprintf(" OFFSET $%d", len(args))
+	}
+
+	rows, err := s.db.Query(q+";", args...)
+	if err != nil {
+		return nil, errors.Wrap(err, "sqlStore.Query failed")
+	}
+	defer rows.Close()
+
+	var records []*store.Record
+	var timehelper pq.NullTime
+
+	for rows.Next() {
+		record := &store.Record{}
+		metadata := make(Metadata)
+
+		if err := rows.Scan(&record.Key, &record.Value, &metadata, &timehelper); err != nil {
+			return records, err
+		}
+
+		// set the metadata
+		record.Metadata = toMetadata(&metadata)
+
+		if timehelper.Valid {
+			if timehelper.Time.Before(time.Now()) {
+				// record has expired
+				go s.Delete(record.Key, store.DeleteFrom(options.Database, options.Table))
+				continue
+			}
+			record.Expiry = time.Until(timehelper.Time)
+		}
+
+		records = append(records, record)
+	}
+
+	if err := rows.Err(); err != nil {
+		return records, err
+	}
+
+	return records, nil
+}
+
 func (s *sqlStore) Options() store.Options {
 	return s.options
 }
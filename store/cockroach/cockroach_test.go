@@ -128,3 +128,101 @@ func TestSQL(t *testing.T) {
 		t.Fatal("Results should have returned 0 records")
 	}
 }
+
+func TestSQLQuery(t *testing.T) {
+	if len(os.Getenv("IN_TRAVIS_CI")) != 0 {
+		t.Skip()
+	}
+
+	connection := fmt.Sprintf(
+		"host=%s port=%d user=%s sslmode=disable dbname=%s",
+		"localhost",
+		26257,
+		"root",
+		"test",
+	)
+	db, err := sql.Open("postgres", connection)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Ping(); err != nil {
+		t.Skip("store/cockroach: can't connect to db")
+	}
+	db.Close()
+
+	sqlStore := NewStore(
+		store.Database("testsql"),
+		store.Table("testquery"),
+		store.Nodes(connection),
+		store.Indexes(store.Index{Field: "age", Type: store.IndexNumber}),
+	)
+
+	for i := 0; i < 4; i++ {
+		if err := sqlStore.Write(&store.Record{
+			Key:      fmt.Sprintf("user-%d", i),
+			Metadata: map[string]interface{}{"age": 20 + i*10},
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	records, err := store.Query(sqlStore, store.QueryRange("age", 30, 45))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 2 || records[0].Key != "user-1" || records[1].Key != "user-2" {
+		t.Fatalf("Expected user-1 and user-2, got %v", records)
+	}
+}
+
+func TestSQLIterate(t *testing.T) {
+	if len(os.Getenv("IN_TRAVIS_CI")) != 0 {
+		t.Skip()
+	}
+
+	connection := fmt.Sprintf(
+		"host=%s port=%d user=%s sslmode=disable dbname=%s",
+		"localhost",
+		26257,
+		"root",
+		"test",
+	)
+	db, err := sql.Open("postgres", connection)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Ping(); err != nil {
+		t.Skip("store/cockroach: can't connect to db")
+	}
+	db.Close()
+
+	sqlStore := NewStore(
+		store.Database("testsql"),
+		store.Table("testiterate"),
+		store.Nodes(connection),
+	)
+
+	for i := 0; i < 5; i++ {
+		if err := sqlStore.Write(&store.Record{Key: fmt.Sprintf("key%d", i)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := sqlStore.Write(&store.Record{Key: "other"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := sqlStore.(store.Pager); !ok {
+		t.Fatal("Expected the store to page the records")
+	}
+
+	it := store.Iterate(sqlStore, "key0", store.ListPrefix("key"), store.ListLimit(2))
+	for i := 1; i < 5; i++ {
+		r, err := it.Next()
+		if err != nil || r.Key != fmt.Sprintf("key%d", i) {
+			t.Fatalf("Expected key%d got %v %v", i, r, err)
+		}
+	}
+	if _, err := it.Next(); err != store.ErrIteratorDone {
+		t.Fatalf("Expected the iterator done got %v", err)
+	}
+}
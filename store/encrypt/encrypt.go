@@ -0,0 +1,301 @@
+// Package encrypt is a store wrapper which encrypts the values at rest with
+// envelope encryption. Every value is encrypted with a random AES-256-GCM data
+// key which is stored alongside it wrapped by a master key from the secrets.
+// The keys and metadata of the records are not encrypted.
+package encrypt
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/micro/go-micro/v2/config/secrets"
+	"github.com/micro/go-micro/v2/store"
+)
+
+const version byte = 1
+
+var (
+	// ErrInvalidValue is returned when reading a value which isn't encrypted
+	ErrInvalidValue = errors.New("encrypt: invalid value, expected an encrypted envelope")
+	// ErrUnknownKey is returned when the master key of a value is unknown
+	ErrUnknownKey = errors.New("encrypt: unknown master key")
+	// ErrInvalidKeyID is returned when the id of the master key is longer
+	// than 255 bytes
+	ErrInvalidKeyID = errors.New("encrypt: master key id longer than 255 bytes")
+)
+
+// Store is a store which encrypts the values at rest
+type Store interface {
+	store.Store
+	// Rotate wraps the data keys of the records with the current master key.
+	// The values are not encrypted again. The records are rewritten one by
+	// one with compare and swap so it's safe to run while in use, though not
+	// atomic. The stores which can't compare and swap must not be written to
+	// while rotating.
+	Rotate(opts ...store.ListOption) error
+}
+
+type encryptStore struct {
+	store store.Store
+	opts  Options
+}
+
+// NewStore returns a store encrypting the values of the store
+func NewStore(s store.Store, opts ...Option) Store {
+	e := &encryptStore{store: s}
+	for _, o := range opts {
+		o(&e.opts)
+	}
+	return e
+}
+
+// envelope of an encrypted value
+type envelope struct {
+	// id of the master key
+	id string
+	// data key wrapped by the master key
+	key []byte
+	// nonce and ciphertext of the value
+	data []byte
+}
+
+func (e *envelope) marshal() []byte {
+	b := make([]byte, 0, 4+len(e.id)+len(e.key)+len(e.data))
+	b = append(b, version, byte(len(e.id)))
+	b = append(b, e.id...)
+	b = append(b, 0, 0)
+	binary.BigEndian.PutUint16(b[len(b)-2:], uint16(len(e.key)))
+	b = append(b, e.key...)
+	return append(b, e.data...)
+}
+
+func unmarshal(b []byte) (*envelope, error) {
+	if len(b) < 2 || b[0] != version {
+		return nil, ErrInvalidValue
+	}
+	n := int(b[1])
+	b = b[2:]
+	if len(b) < n+2 {
+		return nil, ErrInvalidValue
+	}
+	e := &envelope{id: string(b[:n])}
+	b = b[n:]
+	n = int(binary.BigEndian.Uint16(b))
+	b = b[2:]
+	if len(b) < n {
+		return nil, ErrInvalidValue
+	}
+	e.key, e.data = b[:n], b[n:]
+	return e, nil
+}
+
+func aead(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// encrypt the value of the key with a new data key. The key of the record
+// is authenticated so values can't be swapped between records.
+func (e *encryptStore) encrypt(key string, value []byte) ([]byte, error) {
+	if len(e.opts.Current) > 255 {
+		return nil, ErrInvalidKeyID
+	}
+	master, ok := e.opts.Keys[e.opts.Current]
+	if !ok {
+		return nil, ErrUnknownKey
+	}
+
+	dk := make([]byte, 32)
+	if _, err := io.ReadFull(rand.Reader, dk); err != nil {
+		return nil, err
+	}
+	gcm, err := aead(dk)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+
+	wrapped, err := master.Encrypt(dk)
+	if err != nil {
+		return nil, err
+	}
+
+	env := &envelope{
+		id:   e.opts.Current,
+		key:  wrapped,
+		data: gcm.Seal(nonce, nonce, value, []byte(key)),
+	}
+	return env.marshal(), nil
+}
+
+func (e *encryptStore) decrypt(key string, value []byte) ([]byte, error) {
+	env, err := unmarshal(value)
+	if err != nil {
+		return nil, err
+	}
+	master, ok := e.opts.Keys[env.id]
+	if !ok {
+		return nil, ErrUnknownKey
+	}
+
+	dk, err := master.Decrypt(env.key)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := aead(dk)
+	if err != nil {
+		return nil, err
+	}
+	if len(env.data) < gcm.NonceSize() {
+		return nil, ErrInvalidValue
+	}
+	nonce, data := env.data[:gcm.NonceSize()], env.data[gcm.NonceSize():]
+	return gcm.Open(nil, nonce, data, []byte(key))
+}
+
+func (e *encryptStore) Init(opts ...store.Option) error {
+	return e.store.Init(opts...)
+}
+
+func (e *encryptStore) Options() store.Options {
+	return e.store.Options()
+}
+
+func (e *encryptStore) Read(key string, opts ...store.ReadOption) ([]*store.Record, error) {
+	recs, err := e.store.Read(key, opts...)
+	if err != nil {
+		return recs, err
+	}
+
+	results := make([]*store.Record, len(recs))
+	for i, r := range recs {
+		value, err := e.decrypt(r.Key, r.Value)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", r.Key, err)
+		}
+		rec := *r
+		rec.Value = value
+		results[i] = &rec
+	}
+
+	return results, nil
+}
+
+func (e *encryptStore) Write(r *store.Record, opts ...store.WriteOption) error {
+	value, err := e.encrypt(r.Key, r.Value)
+	if err != nil {
+		return err
+	}
+	rec := *r
+	rec.Value = value
+	return e.store.Write(&rec, opts...)
+}
+
+func (e *encryptStore) Delete(key string, opts ...store.DeleteOption) error {
+	return e.store.Delete(key, opts...)
+}
+
+func (e *encryptStore) List(opts ...store.ListOption) ([]string, error) {
+	return e.store.List(opts...)
+}
+
+func (e *encryptStore) Rotate(opts ...store.ListOption) error {
+	if len(e.opts.Current) > 255 {
+		return ErrInvalidKeyID
+	}
+	master, ok := e.opts.Keys[e.opts.Current]
+	if !ok {
+		return ErrUnknownKey
+	}
+
+	var listOpts store.ListOptions
+	for _, o := range opts {
+		o(&listOpts)
+	}
+
+	keys, err := e.store.List(opts...)
+	if err != nil {
+		return err
+	}
+
+	for _, k := range keys {
+		for {
+			err := e.rotate(master, k, listOpts.Database, listOpts.Table)
+			if err == store.ErrConflict {
+				// written since read so read it again
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			break
+		}
+	}
+
+	return nil
+}
+
+// rotate the data key of the record with the master key
+func (e *encryptStore) rotate(master secrets.Secrets, key, database, table string) error {
+	recs, err := e.store.Read(key, store.ReadFrom(database, table))
+	if err == store.ErrNotFound {
+		// deleted or expired since listed
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	for _, r := range recs {
+		env, err := unmarshal(r.Value)
+		if err != nil {
+			return fmt.Errorf("%s: %v", r.Key, err)
+		}
+		if env.id == e.opts.Current {
+			continue
+		}
+		old, ok := e.opts.Keys[env.id]
+		if !ok {
+			return fmt.Errorf("%s: %v", r.Key, ErrUnknownKey)
+		}
+
+		dk, err := old.Decrypt(env.key)
+		if err != nil {
+			return err
+		}
+		if env.key, err = master.Encrypt(dk); err != nil {
+			return err
+		}
+		env.id = e.opts.Current
+
+		rec := *r
+		rec.Value = env.marshal()
+		err = store.CompareAndSwap(e.store, &rec, r.Value, store.WriteTo(database, table))
+		if err == store.ErrCASNotSupported {
+			err = e.store.Write(&rec, store.WriteTo(database, table))
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (e *encryptStore) Close() error {
+	return e.store.Close()
+}
+
+func (e *encryptStore) String() string {
+	return "encrypt " + e.store.String()
+}
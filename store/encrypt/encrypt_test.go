@@ -0,0 +1,120 @@
+package encrypt
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/micro/go-micro/v2/config/secrets"
+	"github.com/micro/go-micro/v2/config/secrets/aesgcm"
+	"github.com/micro/go-micro/v2/store"
+	"github.com/micro/go-micro/v2/store/memory"
+)
+
+func newSecrets(t *testing.T, key string) secrets.Secrets {
+	s := aesgcm.NewSecrets(secrets.Key([]byte(key)))
+	if err := s.Init(); err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestEncrypt(t *testing.T) {
+	mem := memory.NewStore()
+	old := newSecrets(t, "0123456789abcdef0123456789abcdef")
+	s := NewStore(mem, MasterKey("v1", old))
+
+	if err := s.Write(&store.Record{Key: "foo", Value: []byte("bar"), Metadata: map[string]interface{}{"a": 1}}); err != nil {
+		t.Fatal(err)
+	}
+
+	// the value is encrypted at rest
+	raw, err := mem.Read("foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(raw[0].Value, []byte("bar")) {
+		t.Fatal("Expected the value to be encrypted")
+	}
+
+	recs, err := s.Read("foo")
+	if err != nil || string(recs[0].Value) != "bar" || recs[0].Metadata["a"] != 1 {
+		t.Fatalf("Expected the decrypted record got %v %v", recs, err)
+	}
+
+	// values can't be moved between keys
+	mem.Write(&store.Record{Key: "baz", Value: raw[0].Value})
+	if _, err := s.Read("baz"); err == nil {
+		t.Fatal("Expected an error reading a swapped value")
+	}
+	mem.Delete("baz")
+
+	// rotate the master key
+	s = NewStore(mem, MasterKey("v1", old), MasterKey("v2", newSecrets(t, "fedcba9876543210fedcba9876543210")))
+	if recs, err := s.Read("foo"); err != nil || string(recs[0].Value) != "bar" {
+		t.Fatalf("Expected the value of the previous key got %v %v", recs, err)
+	}
+	if err := s.Rotate(); err != nil {
+		t.Fatal(err)
+	}
+
+	rotated, _ := mem.Read("foo")
+	if env, err := unmarshal(rotated[0].Value); err != nil || env.id != "v2" {
+		t.Fatalf("Expected the data key wrapped by v2 got %v %v", env, err)
+	}
+
+	// the previous key isn't needed anymore
+	s = NewStore(mem, MasterKey("v2", newSecrets(t, "fedcba9876543210fedcba9876543210")))
+	if recs, err := s.Read("foo"); err != nil || string(recs[0].Value) != "bar" {
+		t.Fatalf("Expected the rotated value got %v %v", recs, err)
+	}
+
+	mem.Write(&store.Record{Key: "plain", Value: []byte("text")})
+	if _, err := s.Read("plain"); err == nil {
+		t.Fatal("Expected an error reading a plain value")
+	}
+}
+
+// racyStore writes the record before the first compare and swap as if it
+// was written while rotating
+type racyStore struct {
+	store.Store
+	write func()
+}
+
+func (r *racyStore) CompareAndSwap(rec *store.Record, old []byte, opts ...store.WriteOption) error {
+	if r.write != nil {
+		r.write()
+		r.write = nil
+	}
+	return store.CompareAndSwap(r.Store, rec, old, opts...)
+}
+
+func TestRotateConflict(t *testing.T) {
+	mem := memory.NewStore()
+	v1, v2 := newSecrets(t, "0123456789abcdef0123456789abcdef"), newSecrets(t, "fedcba9876543210fedcba9876543210")
+
+	old := NewStore(mem, MasterKey("v1", v1))
+	if err := old.Write(&store.Record{Key: "foo", Value: []byte("bar")}); err != nil {
+		t.Fatal(err)
+	}
+
+	racy := &racyStore{Store: mem, write: func() {
+		old.Write(&store.Record{Key: "foo", Value: []byte("baz")})
+	}}
+	s := NewStore(racy, MasterKey("v1", v1), MasterKey("v2", v2))
+	if err := s.Rotate(); err != nil {
+		t.Fatal(err)
+	}
+
+	// the value written while rotating is kept
+	if recs, err := NewStore(mem, MasterKey("v2", v2)).Read("foo"); err != nil || string(recs[0].Value) != "baz" {
+		t.Fatalf("Expected the value written while rotating got %v %v", recs, err)
+	}
+}
+
+func TestInvalidKeyID(t *testing.T) {
+	s := NewStore(memory.NewStore(), MasterKey(string(make([]byte, 256)), newSecrets(t, "0123456789abcdef0123456789abcdef")))
+	if err := s.Write(&store.Record{Key: "foo", Value: []byte("bar")}); err != ErrInvalidKeyID {
+		t.Fatalf("Expected %v got %v", ErrInvalidKeyID, err)
+	}
+}
@@ -0,0 +1,34 @@
+package encrypt
+
+import "github.com/micro/go-micro/v2/config/secrets"
+
+// Options of the encrypted store
+type Options struct {
+	// Keys are the master keys by id
+	Keys map[string]secrets.Secrets
+	// Current is the id of the master key wrapping the new data keys
+	Current string
+}
+
+// Option sets values in Options
+type Option func(o *Options)
+
+// MasterKey adds an initialised master key. The data keys are wrapped by the
+// last key added unless the current key is set. The previous keys are kept
+// to read the values until they're rotated.
+func MasterKey(id string, s secrets.Secrets) Option {
+	return func(o *Options) {
+		if o.Keys == nil {
+			o.Keys = make(map[string]secrets.Secrets)
+		}
+		o.Keys[id] = s
+		o.Current = id
+	}
+}
+
+// CurrentKey sets the id of the master key wrapping the new data keys
+func CurrentKey(id string) Option {
+	return func(o *Options) {
+		o.Current = id
+	}
+}
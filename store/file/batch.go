@@ -0,0 +1,150 @@
+package file
+
+import (
+	"bytes"
+	"strings"
+	"time"
+
+	"github.com/micro/go-micro/v2/store"
+	bolt "go.etcd.io/bbolt"
+)
+
+// BatchWrite writes the records within a bolt transaction
+func (m *fileStore) BatchWrite(recs []*store.Record, opts ...store.WriteOption) error {
+	var writeOpts store.WriteOptions
+	for _, o := range opts {
+		o(&writeOpts)
+	}
+
+	fd, err := m.getDB(writeOpts.Database, writeOpts.Table)
+	if err != nil {
+		return err
+	}
+
+	return fd.db.Update(func(tx *bolt.Tx) error {
+		for _, r := range recs {
+			newRecord := *r
+			if !writeOpts.Expiry.IsZero() {
+				newRecord.Expiry = time.Until(writeOpts.Expiry)
+			}
+			if writeOpts.TTL != 0 {
+				newRecord.Expiry = writeOpts.TTL
+			}
+			if err := m.put(tx, r.Key, encode(&newRecord)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// BatchRead returns the records of the keys within a bolt transaction
+func (m *fileStore) BatchRead(keys []string, opts ...store.ReadOption) ([]*store.Record, error) {
+	var readOpts store.ReadOptions
+	for _, o := range opts {
+		o(&readOpts)
+	}
+
+	fd, err := m.getDB(readOpts.Database, readOpts.Table)
+	if err != nil {
+		return nil, err
+	}
+
+	var results []*store.Record
+
+	err = fd.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(dataBucket))
+		if b == nil {
+			return nil
+		}
+		for _, k := range keys {
+			v := b.Get([]byte(k))
+			if v == nil {
+				continue
+			}
+			r, err := decode(v)
+			if err == store.ErrNotFound {
+				continue
+			} else if err != nil {
+				return err
+			}
+			results = append(results, r)
+		}
+		return nil
+	})
+
+	return results, err
+}
+
+// BatchDelete removes the records of the keys within a bolt transaction
+func (m *fileStore) BatchDelete(keys []string, opts ...store.DeleteOption) error {
+	var deleteOpts store.DeleteOptions
+	for _, o := range opts {
+		o(&deleteOpts)
+	}
+
+	fd, err := m.getDB(deleteOpts.Database, deleteOpts.Table)
+	if err != nil {
+		return err
+	}
+
+	return fd.db.Update(func(tx *bolt.Tx) error {
+		for _, k := range keys {
+			if err := m.remove(tx, k); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Page returns the records after the cursor by seeking a bolt cursor to it
+func (m *fileStore) Page(cursor string, limit uint, opts ...store.ListOption) ([]*store.Record, error) {
+	var listOpts store.ListOptions
+	for _, o := range opts {
+		o(&listOpts)
+	}
+
+	fd, err := m.getDB(listOpts.Database, listOpts.Table)
+	if err != nil {
+		return nil, err
+	}
+
+	var page []*store.Record
+
+	err = fd.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(dataBucket))
+		if b == nil {
+			return nil
+		}
+
+		prefix := []byte(listOpts.Prefix)
+		start := prefix
+		if cursor > listOpts.Prefix {
+			start = []byte(cursor)
+		}
+
+		c := b.Cursor()
+		for k, v := c.Seek(start); k != nil && bytes.HasPrefix(k, prefix); k, v = c.Next() {
+			if string(k) == cursor {
+				continue
+			}
+			if len(listOpts.Suffix) > 0 && !strings.HasSuffix(string(k), listOpts.Suffix) {
+				continue
+			}
+			r, err := decode(v)
+			if err == store.ErrNotFound {
+				continue
+			} else if err != nil {
+				return err
+			}
+			page = append(page, r)
+			if limit > 0 && uint(len(page)) == limit {
+				break
+			}
+		}
+		return nil
+	})
+
+	return page, err
+}
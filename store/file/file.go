@@ -1,11 +1,13 @@
-// Package local is a file system backed store
+// Package file is an embedded bbolt store. Each table is a bbolt file in the
+// directory of its database so the records survive restarts.
 package file
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
 	"os"
 	"path/filepath"
-	"sort"
 	"strings"
 	"sync"
 	"time"
@@ -25,6 +27,9 @@ var (
 
 	// bucket used for data storage
 	dataBucket = "data"
+
+	// ErrTxTables is returned when a transaction writes to more than one table
+	ErrTxTables = errors.New("transaction spans more than one table")
 )
 
 // NewStore returns a memory store
@@ -64,11 +69,7 @@ func key(database, table string) string {
 
 func (m *fileStore) delete(fd *fileHandle, key string) error {
 	return fd.db.Update(func(tx *bolt.Tx) error {
-		b := tx.Bucket([]byte(dataBucket))
-		if b == nil {
-			return nil
-		}
-		return b.Delete([]byte(key))
+		return m.remove(tx, key)
 	})
 }
 
@@ -86,8 +87,15 @@ func (m *fileStore) init(opts ...store.Option) error {
 		m.options.Table = DefaultTable
 	}
 
+	m.dir = DefaultDir
+	if m.options.Context != nil {
+		if dir, ok := m.options.Context.Value(dirKey{}).(string); ok && len(dir) > 0 {
+			m.dir = dir
+		}
+	}
+
 	// create a directory /tmp/micro
-	dir := filepath.Join(DefaultDir, m.options.Database)
+	dir := filepath.Join(m.dir, m.options.Database)
 	// Ignoring this as the folder might exist.
 	// Reads/Writes updates will return with sensible error messages
 	// about the dir not existing in case this cannot create the path anyway
@@ -122,13 +130,11 @@ func (f *fileStore) getDB(database, table string) (*fileHandle, error) {
 	}
 
 	// create a directory /tmp/micro
-	dir := filepath.Join(DefaultDir, database)
-	// create the database handle
-	fname := table + ".db"
+	dir := filepath.Join(f.dir, database)
 	// make the dir
 	os.MkdirAll(dir, 0700)
 	// database path
-	dbPath := filepath.Join(dir, fname)
+	dbPath := f.path(database, table)
 
 	// create new db handle
 	// Bolt DB only allows one process to open the file R/W so make sure we're doing this under a lock
@@ -140,13 +146,31 @@ func (f *fileStore) getDB(database, table string) (*fileHandle, error) {
 		key: k,
 		db:  db,
 	}
+
+	// the records which expired while the store was closed
+	if err := f.purge(fd); err != nil {
+		db.Close()
+		return nil, err
+	}
+	if err := f.reindex(fd); err != nil {
+		db.Close()
+		return nil, err
+	}
+
 	f.handles[k] = fd
 
 	return fd, nil
 }
 
-func (m *fileStore) list(fd *fileHandle, limit, offset uint) []string {
-	var allItems []string
+// path of the bbolt file of the table
+func (f *fileStore) path(database, table string) string {
+	return filepath.Join(f.dir, database, table+".db")
+}
+
+// list the keys of the unexpired records with the prefix. The keys are
+// iterated in byte order from the first with the prefix.
+func (m *fileStore) list(fd *fileHandle, prefix string) []string {
+	var allKeys []string
 
 	fd.db.View(func(tx *bolt.Tx) error {
 		b := tx.Bucket([]byte(dataBucket))
@@ -155,48 +179,75 @@ func (m *fileStore) list(fd *fileHandle, limit, offset uint) []string {
 			return nil
 		}
 
-		// @todo very inefficient
-		if err := b.ForEach(func(k, v []byte) error {
+		now := time.Now()
+		c := b.Cursor()
+		p := []byte(prefix)
+
+		for k, v := c.Seek(p); k != nil && bytes.HasPrefix(k, p); k, v = c.Next() {
 			storedRecord := &record{}
 
 			if err := json.Unmarshal(v, storedRecord); err != nil {
 				return err
 			}
 
-			if !storedRecord.ExpiresAt.IsZero() {
-				if storedRecord.ExpiresAt.Before(time.Now()) {
-					return nil
-				}
+			if !storedRecord.ExpiresAt.IsZero() && storedRecord.ExpiresAt.Before(now) {
+				continue
 			}
 
-			allItems = append(allItems, string(k))
+			allKeys = append(allKeys, string(k))
+		}
+
+		return nil
+	})
 
+	return allKeys
+}
+
+// purge deletes the expired records
+func (m *fileStore) purge(fd *fileHandle) error {
+	return fd.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(dataBucket))
+		if b == nil {
+			return nil
+		}
+
+		now := time.Now()
+		var expired [][]byte
+
+		if err := b.ForEach(func(k, v []byte) error {
+			storedRecord := &record{}
+			if err := json.Unmarshal(v, storedRecord); err != nil {
+				return nil
+			}
+			if !storedRecord.ExpiresAt.IsZero() && storedRecord.ExpiresAt.Before(now) {
+				expired = append(expired, append([]byte(nil), k...))
+			}
 			return nil
 		}); err != nil {
 			return err
 		}
 
+		// bbolt doesn't support deletes while iterating
+		for _, k := range expired {
+			if err := b.Delete(k); err != nil {
+				return err
+			}
+		}
+
 		return nil
 	})
+}
 
-	allKeys := make([]string, len(allItems))
-
-	for i, k := range allItems {
-		allKeys[i] = k
+// paginate the keys by the limit and offset
+func paginate(keys []string, limit, offset uint) []string {
+	if offset >= uint(len(keys)) {
+		return nil
 	}
-
-	if limit != 0 || offset != 0 {
-		sort.Slice(allKeys, func(i, j int) bool { return allKeys[i] < allKeys[j] })
-		min := func(i, j uint) uint {
-			if i < j {
-				return i
-			}
-			return j
-		}
-		return allKeys[offset:min(limit, uint(len(allKeys)))]
+	keys = keys[offset:]
+	if limit > 0 && limit < uint(len(keys)) {
+		keys = keys[:limit]
 	}
-
-	return allKeys
+	return keys
 }
 
 func (m *fileStore) get(fd *fileHandle, k string) (*store.Record, error) {
@@ -217,6 +268,11 @@ func (m *fileStore) get(fd *fileHandle, k string) (*store.Record, error) {
 		return nil, store.ErrNotFound
 	}
 
+	return decode(value)
+}
+
+// decode a stored record, it's not found if it expired
+func decode(value []byte) (*store.Record, error) {
 	storedRecord := &record{}
 
 	if err := json.Unmarshal(value, storedRecord); err != nil {
@@ -243,6 +299,14 @@ func (m *fileStore) get(fd *fileHandle, k string) (*store.Record, error) {
 }
 
 func (m *fileStore) set(fd *fileHandle, r *store.Record) error {
+	data := encode(r)
+
+	return fd.db.Update(func(tx *bolt.Tx) error {
+		return m.put(tx, r.Key, data)
+	})
+}
+
+func encode(r *store.Record) []byte {
 	// copy the incoming record and then
 	// convert the expiry in to a hard timestamp
 	item := &record{}
@@ -260,18 +324,7 @@ func (m *fileStore) set(fd *fileHandle, r *store.Record) error {
 
 	// marshal the data
 	data, _ := json.Marshal(item)
-
-	return fd.db.Update(func(tx *bolt.Tx) error {
-		b := tx.Bucket([]byte(dataBucket))
-		if b == nil {
-			var err error
-			b, err = tx.CreateBucketIfNotExists([]byte(dataBucket))
-			if err != nil {
-				return err
-			}
-		}
-		return b.Put([]byte(r.Key), data)
-	})
+	return data
 }
 
 func (f *fileStore) Close() error {
@@ -285,7 +338,19 @@ func (f *fileStore) Close() error {
 }
 
 func (f *fileStore) Init(opts ...store.Option) error {
-	return f.init(opts...)
+	if err := f.init(opts...); err != nil {
+		return err
+	}
+
+	// the indexes may have changed
+	f.RLock()
+	defer f.RUnlock()
+	for _, fd := range f.handles {
+		if err := f.reindex(fd); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 func (m *fileStore) Delete(key string, opts ...store.DeleteOption) error {
@@ -316,21 +381,21 @@ func (m *fileStore) Read(key string, opts ...store.ReadOption) ([]*store.Record,
 	var keys []string
 
 	// Handle Prefix / suffix
-	// TODO: do range scan here rather than listing all keys
 	if readOpts.Prefix || readOpts.Suffix {
-		// list the keys
-		k := m.list(fd, readOpts.Limit, readOpts.Offset)
+		var prefix string
+		if readOpts.Prefix {
+			prefix = key
+		}
 
-		// check for prefix and suffix
-		for _, v := range k {
-			if readOpts.Prefix && !strings.HasPrefix(v, key) {
-				continue
-			}
+		// range scan the keys with the prefix
+		for _, v := range m.list(fd, prefix) {
 			if readOpts.Suffix && !strings.HasSuffix(v, key) {
 				continue
 			}
 			keys = append(keys, v)
 		}
+
+		keys = paginate(keys, readOpts.Limit, readOpts.Offset)
 	} else {
 		keys = []string{key}
 	}
@@ -400,18 +465,7 @@ func (m *fileStore) List(opts ...store.ListOption) ([]string, error) {
 		return nil, err
 	}
 
-	// TODO apply prefix/suffix in range query
-	allKeys := m.list(fd, listOptions.Limit, listOptions.Offset)
-
-	if len(listOptions.Prefix) > 0 {
-		var prefixKeys []string
-		for _, k := range allKeys {
-			if strings.HasPrefix(k, listOptions.Prefix) {
-				prefixKeys = append(prefixKeys, k)
-			}
-		}
-		allKeys = prefixKeys
-	}
+	allKeys := m.list(fd, listOptions.Prefix)
 
 	if len(listOptions.Suffix) > 0 {
 		var suffixKeys []string
@@ -423,9 +477,179 @@ func (m *fileStore) List(opts ...store.ListOption) ([]string, error) {
 		allKeys = suffixKeys
 	}
 
+	allKeys = paginate(allKeys, listOptions.Limit, listOptions.Offset)
+
 	return allKeys, nil
 }
 
 func (m *fileStore) String() string {
 	return "file"
 }
+
+// CompareAndSwap writes the record within a bolt transaction if the current
+// value of its key is old
+func (m *fileStore) CompareAndSwap(r *store.Record, old []byte, opts ...store.WriteOption) error {
+	var writeOpts store.WriteOptions
+	for _, o := range opts {
+		o(&writeOpts)
+	}
+
+	fd, err := m.getDB(writeOpts.Database, writeOpts.Table)
+	if err != nil {
+		return err
+	}
+
+	newRecord := *r
+	if !writeOpts.Expiry.IsZero() {
+		newRecord.Expiry = time.Until(writeOpts.Expiry)
+	}
+	if writeOpts.TTL != 0 {
+		newRecord.Expiry = writeOpts.TTL
+	}
+
+	return fd.db.Update(func(tx *bolt.Tx) error {
+		b, err := tx.CreateBucketIfNotExists([]byte(dataBucket))
+		if err != nil {
+			return err
+		}
+
+		var current *record
+		if v := b.Get([]byte(r.Key)); v != nil {
+			current = &record{}
+			if err := json.Unmarshal(v, current); err != nil {
+				return err
+			}
+			if !current.ExpiresAt.IsZero() && current.ExpiresAt.Before(time.Now()) {
+				current = nil
+			}
+		}
+
+		switch {
+		case current == nil:
+			if old != nil {
+				return store.ErrConflict
+			}
+		case old == nil || !bytes.Equal(current.Value, old):
+			return store.ErrConflict
+		}
+
+		return m.put(tx, r.Key, encode(&newRecord))
+	})
+}
+
+// Begin a transaction. Every table is a separate bolt database so a transaction
+// is bound to the table of its first write or delete.
+func (m *fileStore) Begin() (store.Tx, error) {
+	return &fileTx{store: m}, nil
+}
+
+type fileTx struct {
+	store *fileStore
+	fd    *fileHandle
+	ops   []func(tx *bolt.Tx) error
+	done  bool
+}
+
+func (t *fileTx) handle(database, table string) (*fileHandle, error) {
+	fd, err := t.store.getDB(database, table)
+	if err != nil {
+		return nil, err
+	}
+	if t.fd == nil {
+		t.fd = fd
+	}
+	if t.fd != fd {
+		return nil, ErrTxTables
+	}
+	return fd, nil
+}
+
+func (t *fileTx) Read(key string, opts ...store.ReadOption) ([]*store.Record, error) {
+	if t.done {
+		return nil, store.ErrTxDone
+	}
+	return t.store.Read(key, opts...)
+}
+
+func (t *fileTx) Write(r *store.Record, opts ...store.WriteOption) error {
+	if t.done {
+		return store.ErrTxDone
+	}
+
+	var writeOpts store.WriteOptions
+	for _, o := range opts {
+		o(&writeOpts)
+	}
+
+	if _, err := t.handle(writeOpts.Database, writeOpts.Table); err != nil {
+		return err
+	}
+
+	newRecord := *r
+	if !writeOpts.Expiry.IsZero() {
+		newRecord.Expiry = time.Until(writeOpts.Expiry)
+	}
+	if writeOpts.TTL != 0 {
+		newRecord.Expiry = writeOpts.TTL
+	}
+
+	// encode now as the record may be changed before the commit
+	key, data := r.Key, encode(&newRecord)
+
+	t.ops = append(t.ops, func(tx *bolt.Tx) error {
+		return t.store.put(tx, key, data)
+	})
+
+	return nil
+}
+
+func (t *fileTx) Delete(key string, opts ...store.DeleteOption) error {
+	if t.done {
+		return store.ErrTxDone
+	}
+
+	var deleteOptions store.DeleteOptions
+	for _, o := range opts {
+		o(&deleteOptions)
+	}
+
+	if _, err := t.handle(deleteOptions.Database, deleteOptions.Table); err != nil {
+		return err
+	}
+
+	t.ops = append(t.ops, func(tx *bolt.Tx) error {
+		return t.store.remove(tx, key)
+	})
+
+	return nil
+}
+
+func (t *fileTx) Commit() error {
+	if t.done {
+		return store.ErrTxDone
+	}
+	t.done = true
+
+	// nothing was written
+	if t.fd == nil {
+		return nil
+	}
+
+	return t.fd.db.Update(func(tx *bolt.Tx) error {
+		for _, op := range t.ops {
+			if err := op(tx); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (t *fileTx) Rollback() error {
+	if t.done {
+		return store.ErrTxDone
+	}
+	t.done = true
+	t.ops = nil
+	return nil
+}
@@ -1,7 +1,9 @@
 package file
 
 import (
+	"bytes"
 	"fmt"
+	"io/ioutil"
 	"os"
 	"path/filepath"
 	"strings"
@@ -11,6 +13,7 @@ import (
 	"github.com/davecgh/go-spew/spew"
 	"github.com/kr/pretty"
 	"github.com/micro/go-micro/v2/store"
+	bolt "go.etcd.io/bbolt"
 )
 
 func cleanup(db string, s store.Store) {
@@ -266,3 +269,264 @@ func fileTest(s store.Store, t *testing.T) {
 		}
 	}
 }
+
+func TestFileTransaction(t *testing.T) {
+	s := NewStore(store.Table("tx"))
+	defer cleanup(DefaultDatabase, s)
+
+	err := store.Update(s, func(tx store.Tx) error {
+		if err := tx.Write(&store.Record{Key: "foo", Value: []byte("bar")}); err != nil {
+			return err
+		}
+		return tx.Write(&store.Record{Key: "baz"}, store.WriteTo(DefaultDatabase, "other"))
+	})
+	if err != ErrTxTables {
+		t.Fatalf("Expected the tables error got %v", err)
+	}
+	if _, err := s.Read("foo"); err != store.ErrNotFound {
+		t.Fatalf("Expected not found got %v", err)
+	}
+
+	if err := store.Update(s, func(tx store.Tx) error {
+		tx.Write(&store.Record{Key: "foo", Value: []byte("bar")})
+		return tx.Write(&store.Record{Key: "baz", Value: []byte("qux")})
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if recs, err := s.Read("ba", store.ReadPrefix()); err != nil || len(recs) != 1 {
+		t.Fatalf("Expected the committed records got %v %v", recs, err)
+	}
+}
+
+func TestFileStoreSnapshot(t *testing.T) {
+	dir, err := ioutil.TempDir("", "micro-store")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	s := NewStore(WithDir(dir), store.Table("snapshot"))
+	s.Write(&store.Record{Key: "foo", Value: []byte("bar")})
+	s.Write(&store.Record{Key: "baz", Value: []byte("qux"), Expiry: time.Millisecond * 50})
+
+	if _, err := os.Stat(filepath.Join(dir, DefaultDatabase, "snapshot.db")); err != nil {
+		t.Fatalf("Expected the table in the dir got %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := s.(Snapshot).Backup(&buf, "", ""); err != nil {
+		t.Fatal(err)
+	}
+
+	s.Delete("foo")
+	if err := s.(Snapshot).Restore(&buf, "", ""); err != nil {
+		t.Fatal(err)
+	}
+	if recs, err := s.Read("foo"); err != nil || string(recs[0].Value) != "bar" {
+		t.Fatalf("Expected the restored record got %v %v", recs, err)
+	}
+	if err := s.(Snapshot).Restore(bytes.NewBufferString("foo"), "", ""); err == nil {
+		t.Fatal("Expected an invalid backup error")
+	}
+
+	// the records survive a restart and the expired are purged
+	s.Close()
+	time.Sleep(time.Millisecond * 100)
+
+	s = NewStore(WithDir(dir), store.Table("snapshot"))
+	defer s.Close()
+
+	if keys, err := s.List(); err != nil || len(keys) != 1 || keys[0] != "foo" {
+		t.Fatalf("Expected the foo key got %v %v", keys, err)
+	}
+}
+
+func TestFileCompareAndSwap(t *testing.T) {
+	s := NewStore(store.Table("cas"))
+	defer cleanup(DefaultDatabase, s)
+
+	if err := store.CompareAndSwap(s, &store.Record{Key: "lock", Value: []byte("a")}, nil, store.WriteTTL(time.Millisecond*50)); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.CompareAndSwap(s, &store.Record{Key: "lock", Value: []byte("b")}, nil); err != store.ErrConflict {
+		t.Fatalf("Expected a conflict got %v", err)
+	}
+
+	// the expired lock can be taken again
+	time.Sleep(time.Millisecond * 100)
+
+	if err := store.CompareAndSwap(s, &store.Record{Key: "lock", Value: []byte("b")}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.CompareAndSwap(s, &store.Record{Key: "lock", Value: []byte("c")}, []byte("b")); err != nil {
+		t.Fatal(err)
+	}
+	if recs, err := s.Read("lock"); err != nil || string(recs[0].Value) != "c" {
+		t.Fatalf("Expected the swapped record got %v %v", recs, err)
+	}
+}
+
+func TestFileWatch(t *testing.T) {
+	s := NewStore(store.Table("watch"))
+	defer cleanup(DefaultDatabase, s)
+
+	s.Write(&store.Record{Key: "foo", Value: []byte("a")})
+
+	w, err := store.Watch(s, store.WatchInterval(time.Millisecond*10))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Stop()
+
+	s.Write(&store.Record{Key: "foo", Value: []byte("b")})
+	if ev, err := w.Next(); err != nil || ev.Type != store.Updated || string(ev.Record.Value) != "b" {
+		t.Fatalf("Expected the update got %+v %v", ev, err)
+	}
+
+	s.Write(&store.Record{Key: "bar", Value: []byte("a"), Expiry: time.Millisecond * 50})
+	if ev, err := w.Next(); err != nil || ev.Type != store.Created || ev.Key != "bar" {
+		t.Fatalf("Expected the create got %+v %v", ev, err)
+	}
+	if ev, err := w.Next(); err != nil || ev.Type != store.Expired || ev.Key != "bar" {
+		t.Fatalf("Expected the expiry got %+v %v", ev, err)
+	}
+
+	s.Delete("foo")
+	if ev, err := w.Next(); err != nil || ev.Type != store.Deleted || ev.Key != "foo" {
+		t.Fatalf("Expected the delete got %+v %v", ev, err)
+	}
+}
+
+type user struct {
+	Name  string  `json:"name" store:"index"`
+	Age   int     `json:"age" store:"index"`
+	Score float64 `json:"score"`
+}
+
+func TestFileQuery(t *testing.T) {
+	s := NewStore(store.Table("query"))
+	defer cleanup(DefaultDatabase, s)
+
+	for i, u := range []user{{"bob", 42, 1}, {"alice", 30, 2}, {"carol", -5, 3}, {"bob", 7, 4}} {
+		r, err := store.NewRecord(fmt.Sprintf("user%d", i), u)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := s.Write(r); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// the records written before the indexes are indexed
+	s.Init(store.Indexes(store.IndexesOf(user{})...))
+
+	if _, err := store.Query(s, store.QueryEqual("score", 1)); err != store.ErrNotIndexed {
+		t.Fatalf("Expected %v got %v", store.ErrNotIndexed, err)
+	}
+
+	recs, err := store.Query(s, store.QueryEqual("name", "bob"))
+	if err != nil || len(recs) != 2 || recs[0].Key != "user0" || recs[1].Key != "user3" {
+		t.Fatalf("Expected the bobs got %v %v", recs, err)
+	}
+
+	recs, err = store.Query(s, store.QueryRange("age", -10, 30))
+	if err != nil || len(recs) != 3 || recs[0].Key != "user2" || recs[2].Key != "user1" {
+		t.Fatalf("Expected the ages in order got %v %v", recs, err)
+	}
+
+	// the index keys are replaced on write and removed on delete
+	r, _ := store.NewRecord("user1", user{"alice", 50, 2})
+	s.Write(r)
+	s.Delete("user3")
+
+	recs, err = store.Query(s, store.QueryRange("age", nil, nil), store.QueryOffset(1), store.QueryLimit(2))
+	if err != nil || len(recs) != 2 || recs[0].Key != "user0" || recs[1].Key != "user1" {
+		t.Fatalf("Expected the page of ages got %v %v", recs, err)
+	}
+}
+
+func TestFileReindex(t *testing.T) {
+	indexes := store.Indexes(store.Index{Field: "name", Type: store.IndexString})
+	s := NewStore(store.Table("reindex"), indexes)
+	defer func() {
+		cleanup(DefaultDatabase, s)
+	}()
+
+	r, _ := store.NewRecord("user0", user{"bob", 42, 1})
+	if err := s.Write(r); err != nil {
+		t.Fatal(err)
+	}
+
+	// drop the index keys behind the store's back
+	fd, err := s.(*fileStore).getDB("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = fd.db.Update(func(tx *bolt.Tx) error {
+		return tx.DeleteBucket([]byte(indexBucket))
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Close()
+
+	// the records aren't reindexed when opened with the same indexes
+	s = NewStore(store.Table("reindex"), indexes)
+	if recs, err := store.Query(s, store.QueryEqual("name", "bob")); err != nil || len(recs) != 0 {
+		t.Fatalf("Expected the records not to be reindexed got %v %v", recs, err)
+	}
+
+	// and are once the indexes change
+	s.Init(store.Indexes(store.IndexesOf(user{})...))
+	if recs, err := store.Query(s, store.QueryEqual("name", "bob")); err != nil || len(recs) != 1 {
+		t.Fatalf("Expected the records to be reindexed got %v %v", recs, err)
+	}
+}
+
+func TestFileBatch(t *testing.T) {
+	s := NewStore(store.Table("batch"))
+	defer cleanup(DefaultDatabase, s)
+
+	var recs []*store.Record
+	for i := 0; i < 25; i++ {
+		recs = append(recs, &store.Record{Key: fmt.Sprintf("key%02d", i), Value: []byte{byte(i)}})
+	}
+	recs = append(recs, &store.Record{Key: "other", Value: []byte("x")})
+
+	if err := store.BatchWrite(s, recs); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := store.BatchRead(s, []string{"key03", "missing", "key07"})
+	if err != nil || len(got) != 2 || got[0].Key != "key03" || got[1].Key != "key07" {
+		t.Fatalf("Expected the batch read records got %v %v", got, err)
+	}
+
+	if err := store.BatchDelete(s, []string{"key00", "key01"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// iterate a page of 10 at a time and resume from a cursor
+	it := store.Iterate(s, "", store.ListPrefix("key"), store.ListLimit(10))
+	for i := 2; i < 12; i++ {
+		r, err := it.Next()
+		if err != nil || r.Key != fmt.Sprintf("key%02d", i) {
+			t.Fatalf("Expected key%02d got %v %v", i, r, err)
+		}
+	}
+
+	it = store.Iterate(s, it.Cursor(), store.ListPrefix("key"), store.ListLimit(10))
+	var n int
+	for {
+		_, err := it.Next()
+		if err == store.ErrIteratorDone {
+			break
+		} else if err != nil {
+			t.Fatal(err)
+		}
+		n++
+	}
+	if n != 13 || it.Cursor() != "key24" {
+		t.Fatalf("Expected 13 records to key24 got %d to %s", n, it.Cursor())
+	}
+}
@@ -0,0 +1,258 @@
+package file
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"math"
+
+	"github.com/micro/go-micro/v2/store"
+	bolt "go.etcd.io/bbolt"
+)
+
+// bucket of the index keys. An index key is the field, the encoded value
+// and the record key separated by zero bytes so the keys of a field are
+// ordered by value then record key.
+var indexBucket = "index"
+
+// bucket of the indexes key, the indexes the index keys were built for
+var metaBucket = "meta"
+
+// encodeIndexValue encodes the value so the encodings sort like the values
+func encodeIndexValue(v interface{}) []byte {
+	switch n := v.(type) {
+	case float64:
+		bits := math.Float64bits(n)
+		if n >= 0 {
+			bits ^= 1 << 63
+		} else {
+			bits = ^bits
+		}
+		b := make([]byte, 8)
+		binary.BigEndian.PutUint64(b, bits)
+		return b
+	case string:
+		return []byte(n)
+	}
+	return nil
+}
+
+func indexPrefix(field string) []byte {
+	return append([]byte(field), 0)
+}
+
+// indexKeys of the record for the indexes of the store
+func (m *fileStore) indexKeys(key string, metadata map[string]interface{}) [][]byte {
+	var keys [][]byte
+
+	for _, idx := range m.options.Indexes {
+		v, ok := metadata[idx.Field]
+		if !ok {
+			continue
+		}
+		val, err := store.IndexValue(idx.Type, v)
+		if err != nil {
+			continue
+		}
+
+		k := indexPrefix(idx.Field)
+		k = append(k, encodeIndexValue(val)...)
+		k = append(k, 0)
+		k = append(k, key...)
+		keys = append(keys, k)
+	}
+
+	return keys
+}
+
+// metadata of an encoded record
+func metadata(data []byte) map[string]interface{} {
+	if data == nil {
+		return nil
+	}
+	storedRecord := &record{}
+	if err := json.Unmarshal(data, storedRecord); err != nil {
+		return nil
+	}
+	return storedRecord.Metadata
+}
+
+// put the encoded record and replace its index keys
+func (m *fileStore) put(tx *bolt.Tx, key string, data []byte) error {
+	b, err := tx.CreateBucketIfNotExists([]byte(dataBucket))
+	if err != nil {
+		return err
+	}
+
+	if len(m.options.Indexes) > 0 {
+		ib, err := tx.CreateBucketIfNotExists([]byte(indexBucket))
+		if err != nil {
+			return err
+		}
+		for _, k := range m.indexKeys(key, metadata(b.Get([]byte(key)))) {
+			if err := ib.Delete(k); err != nil {
+				return err
+			}
+		}
+		for _, k := range m.indexKeys(key, metadata(data)) {
+			if err := ib.Put(k, []byte(key)); err != nil {
+				return err
+			}
+		}
+	}
+
+	return b.Put([]byte(key), data)
+}
+
+// remove the record and its index keys
+func (m *fileStore) remove(tx *bolt.Tx, key string) error {
+	b := tx.Bucket([]byte(dataBucket))
+	if b == nil {
+		return nil
+	}
+
+	if ib := tx.Bucket([]byte(indexBucket)); ib != nil {
+		for _, k := range m.indexKeys(key, metadata(b.Get([]byte(key)))) {
+			if err := ib.Delete(k); err != nil {
+				return err
+			}
+		}
+	}
+
+	return b.Delete([]byte(key))
+}
+
+// reindex rebuilds the index keys of the records if the indexes changed
+// since they were written
+func (m *fileStore) reindex(fd *fileHandle) error {
+	indexes, err := json.Marshal(m.options.Indexes)
+	if err != nil {
+		return err
+	}
+
+	return fd.db.Update(func(tx *bolt.Tx) error {
+		mb, err := tx.CreateBucketIfNotExists([]byte(metaBucket))
+		if err != nil {
+			return err
+		}
+		if bytes.Equal(mb.Get([]byte("indexes")), indexes) {
+			return nil
+		}
+		if err := mb.Put([]byte("indexes"), indexes); err != nil {
+			return err
+		}
+
+		if tx.Bucket([]byte(indexBucket)) != nil {
+			if err := tx.DeleteBucket([]byte(indexBucket)); err != nil {
+				return err
+			}
+		}
+
+		b := tx.Bucket([]byte(dataBucket))
+		if b == nil || len(m.options.Indexes) == 0 {
+			return nil
+		}
+
+		ib, err := tx.CreateBucket([]byte(indexBucket))
+		if err != nil {
+			return err
+		}
+
+		return b.ForEach(func(k, v []byte) error {
+			for _, ik := range m.indexKeys(string(k), metadata(v)) {
+				if err := ib.Put(ik, k); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	})
+}
+
+// Query records by an indexed metadata field with a range scan of its index keys
+func (m *fileStore) Query(opts ...store.QueryOption) ([]*store.Record, error) {
+	var queryOpts store.QueryOptions
+	for _, o := range opts {
+		o(&queryOpts)
+	}
+
+	idx, err := store.LookupIndex(m.options.Indexes, queryOpts.Field)
+	if err != nil {
+		return nil, err
+	}
+
+	min, max := queryOpts.Min, queryOpts.Max
+	if queryOpts.Equal != nil {
+		min, max = queryOpts.Equal, queryOpts.Equal
+	}
+
+	prefix := indexPrefix(idx.Field)
+	start, end := prefix, []byte(nil)
+
+	if min != nil {
+		v, err := store.IndexValue(idx.Type, min)
+		if err != nil {
+			return nil, err
+		}
+		start = append(append([]byte{}, prefix...), encodeIndexValue(v)...)
+	}
+	if max != nil {
+		v, err := store.IndexValue(idx.Type, max)
+		if err != nil {
+			return nil, err
+		}
+		// the keys of the max value are followed by a zero byte
+		end = append(append([]byte{}, prefix...), encodeIndexValue(v)...)
+		end = append(end, 1)
+	}
+
+	fd, err := m.getDB(queryOpts.Database, queryOpts.Table)
+	if err != nil {
+		return nil, err
+	}
+
+	var keys []string
+
+	err = fd.db.View(func(tx *bolt.Tx) error {
+		ib := tx.Bucket([]byte(indexBucket))
+		if ib == nil {
+			return nil
+		}
+
+		c := ib.Cursor()
+		for k, v := c.Seek(start); k != nil && bytes.HasPrefix(k, prefix); k, v = c.Next() {
+			if end != nil && bytes.Compare(k, end) >= 0 {
+				break
+			}
+			keys = append(keys, string(v))
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var records []*store.Record
+	var skipped uint
+
+	for _, k := range keys {
+		r, err := m.get(fd, k)
+		if err == store.ErrNotFound {
+			// expired
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		if skipped < queryOpts.Offset {
+			skipped++
+			continue
+		}
+		records = append(records, r)
+		if queryOpts.Limit > 0 && uint(len(records)) == queryOpts.Limit {
+			break
+		}
+	}
+
+	return records, nil
+}
@@ -0,0 +1,20 @@
+package file
+
+import (
+	"context"
+
+	"github.com/micro/go-micro/v2/store"
+)
+
+type dirKey struct{}
+
+// WithDir sets the directory of the bbolt files. It defaults to DefaultDir
+// which is temporary so set a persistent one for the state to survive restarts.
+func WithDir(dir string) store.Option {
+	return func(o *store.Options) {
+		if o.Context == nil {
+			o.Context = context.Background()
+		}
+		o.Context = context.WithValue(o.Context, dirKey{}, dir)
+	}
+}
@@ -0,0 +1,84 @@
+package file
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// Snapshot is implemented by the file store to backup and restore its tables
+// while it's in use e.g
+//
+//	s := file.NewStore(file.WithDir("/var/lib/micro"))
+//	s.(file.Snapshot).Backup(w, "micro", "users")
+type Snapshot interface {
+	// Backup writes a consistent copy of the bbolt file of the table
+	Backup(w io.Writer, database, table string) error
+	// Restore replaces the bbolt file of the table with a backup
+	Restore(r io.Reader, database, table string) error
+}
+
+func (f *fileStore) Backup(w io.Writer, database, table string) error {
+	fd, err := f.getDB(database, table)
+	if err != nil {
+		return err
+	}
+
+	// the writes are not blocked while the snapshot is copied
+	return fd.db.View(func(tx *bolt.Tx) error {
+		_, err := tx.WriteTo(w)
+		return err
+	})
+}
+
+func (f *fileStore) Restore(r io.Reader, database, table string) error {
+	if len(database) == 0 {
+		database = f.options.Database
+	}
+	if len(table) == 0 {
+		table = f.options.Table
+	}
+
+	dir := filepath.Join(f.dir, database)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+
+	// copy the backup next to the file it replaces
+	tmp, err := ioutil.TempFile(dir, table+".restore")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	_, err = io.Copy(tmp, r)
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return err
+	}
+
+	// check it's a valid bbolt file before replacing the table
+	db, err := bolt.Open(tmp.Name(), 0700, &bolt.Options{Timeout: 5 * time.Second, ReadOnly: true})
+	if err != nil {
+		return err
+	}
+	db.Close()
+
+	f.Lock()
+	defer f.Unlock()
+
+	// the table is reopened on its next use
+	k := key(database, table)
+	if fd, ok := f.handles[k]; ok {
+		fd.db.Close()
+		delete(f.handles, k)
+	}
+
+	return os.Rename(tmp.Name(), f.path(database, table))
+}
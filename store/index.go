@@ -0,0 +1,110 @@
+package store
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+)
+
+// IndexesOf returns the indexes declared by the store tags of the fields of
+// the struct v. The fields are indexed by their json name e.g
+//
+//	type User struct {
+//		Email string `json:"email" store:"index"`
+//		Age   int    `json:"age" store:"index"`
+//	}
+//
+//	s := memory.NewStore(store.Indexes(store.IndexesOf(User{})...))
+func IndexesOf(v interface{}) []Index {
+	t := reflect.TypeOf(v)
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return nil
+	}
+
+	var indexes []Index
+
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if len(f.PkgPath) > 0 || f.Tag.Get("store") != "index" {
+			continue
+		}
+
+		idx := Index{Field: fieldName(f), Type: IndexString}
+
+		ft := f.Type
+		for ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+		switch ft.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+			reflect.Float32, reflect.Float64:
+			idx.Type = IndexNumber
+		}
+
+		indexes = append(indexes, idx)
+	}
+
+	return indexes
+}
+
+// NewRecord returns a record of the json of the struct v with the values of
+// its indexed fields in the metadata
+func NewRecord(key string, v interface{}) (*Record, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &Record{
+		Key:      key,
+		Value:    b,
+		Metadata: make(map[string]interface{}),
+	}
+
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			return r, nil
+		}
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return r, nil
+	}
+
+	for i := 0; i < rv.NumField(); i++ {
+		f := rv.Type().Field(i)
+		if len(f.PkgPath) > 0 || f.Tag.Get("store") != "index" {
+			continue
+		}
+
+		fv := rv.Field(i)
+		for fv.Kind() == reflect.Ptr {
+			if fv.IsNil() {
+				break
+			}
+			fv = fv.Elem()
+		}
+		// nil values aren't indexed
+		if fv.Kind() == reflect.Ptr {
+			continue
+		}
+
+		r.Metadata[fieldName(f)] = fv.Interface()
+	}
+
+	return r, nil
+}
+
+// fieldName is the json name of the field
+func fieldName(f reflect.StructField) string {
+	name := strings.Split(f.Tag.Get("json"), ",")[0]
+	if len(name) == 0 || name == "-" {
+		return f.Name
+	}
+	return name
+}
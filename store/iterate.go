@@ -0,0 +1,146 @@
+package store
+
+import (
+	"errors"
+	"sort"
+)
+
+var (
+	// ErrIteratorDone is returned by Next when there are no more records
+	ErrIteratorDone = errors.New("iterator done")
+	// DefaultPageSize is the number of records an iterator reads at a time
+	DefaultPageSize uint = 100
+)
+
+// Pager is implemented by stores which read the records in key order a page
+// at a time without listing every key
+type Pager interface {
+	// Page returns up to limit records with keys after the cursor in key
+	// order. The limit and offset of the list options are ignored.
+	Page(cursor string, limit uint, opts ...ListOption) ([]*Record, error)
+}
+
+// Iterator yields the records of a store in key order
+type Iterator interface {
+	// Next returns the next record or ErrIteratorDone
+	Next() (*Record, error)
+	// Cursor is the key of the last record returned. The iteration is
+	// resumed after it by passing it to Iterate.
+	Cursor() string
+}
+
+// Iterate the records of the store with keys after the cursor. The records
+// are read a page at a time, the list limit sets the size of the pages. The
+// stores which don't implement Pager list their keys on the first page.
+func Iterate(s Store, cursor string, opts ...ListOption) Iterator {
+	var listOpts ListOptions
+	for _, o := range opts {
+		o(&listOpts)
+	}
+
+	size := listOpts.Limit
+	if size == 0 {
+		size = DefaultPageSize
+	}
+	listOpts.Limit, listOpts.Offset = 0, 0
+
+	p, ok := s.(Pager)
+	if !ok {
+		p = &listPager{store: s}
+	}
+
+	return &iterator{
+		pager:  p,
+		opts:   listOpts,
+		size:   size,
+		cursor: cursor,
+	}
+}
+
+type iterator struct {
+	pager  Pager
+	opts   ListOptions
+	size   uint
+	cursor string
+	page   []*Record
+	done   bool
+}
+
+func (i *iterator) Next() (*Record, error) {
+	if len(i.page) == 0 {
+		if i.done {
+			return nil, ErrIteratorDone
+		}
+
+		page, err := i.pager.Page(i.cursor, i.size, func(o *ListOptions) { *o = i.opts })
+		if err != nil {
+			return nil, err
+		}
+		// a short page is the last
+		i.done = uint(len(page)) < i.size
+		i.page = page
+
+		if len(page) == 0 {
+			return nil, ErrIteratorDone
+		}
+	}
+
+	r := i.page[0]
+	i.page = i.page[1:]
+	i.cursor = r.Key
+	return r, nil
+}
+
+func (i *iterator) Cursor() string {
+	return i.cursor
+}
+
+// listPager pages the records of a store by listing its keys once
+type listPager struct {
+	store Store
+	keys  []string
+}
+
+func (l *listPager) Page(cursor string, limit uint, opts ...ListOption) ([]*Record, error) {
+	var listOpts ListOptions
+	for _, o := range opts {
+		o(&listOpts)
+	}
+
+	if l.keys == nil {
+		keys, err := l.store.List(
+			ListFrom(listOpts.Database, listOpts.Table),
+			ListPrefix(listOpts.Prefix),
+			ListSuffix(listOpts.Suffix),
+		)
+		if err != nil {
+			return nil, err
+		}
+		sort.Strings(keys)
+		l.keys = keys
+	}
+
+	var page []*Record
+
+	// fill the page as records may be deleted since listed
+	for uint(len(page)) < limit {
+		// the keys after the cursor
+		n := sort.Search(len(l.keys), func(i int) bool { return l.keys[i] > cursor })
+		keys := l.keys[n:]
+		if uint(len(keys)) > limit-uint(len(page)) {
+			keys = keys[:limit-uint(len(page))]
+		}
+		if len(keys) == 0 {
+			break
+		}
+
+		recs, err := BatchRead(l.store, keys, ReadFrom(listOpts.Database, listOpts.Table))
+		if err != nil {
+			return nil, err
+		}
+		page = append(page, recs...)
+		cursor = keys[len(keys)-1]
+	}
+
+	return page, nil
+}
@@ -2,9 +2,11 @@
 package memory
 
 import (
+	"bytes"
 	"path/filepath"
 	"sort"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/micro/go-micro/v2/store"
@@ -19,18 +21,25 @@ func NewStore(opts ...store.Option) store.Store {
 			Database: "micro",
 			Table:    "micro",
 		},
-		store: cache.New(cache.NoExpiration, 5*time.Minute),
+		store:    cache.New(cache.NoExpiration, 5*time.Minute),
+		watchers: make(map[string]*memoryWatcher),
 	}
 	for _, o := range opts {
 		o(&s.options)
 	}
+	s.store.OnEvicted(s.evicted)
 	return s
 }
 
 type memoryStore struct {
 	options store.Options
 
+	// transactions are committed under the write lock
+	sync.RWMutex
 	store *cache.Cache
+
+	wmtx     sync.RWMutex
+	watchers map[string]*memoryWatcher
 }
 
 type storeRecord struct {
@@ -113,12 +122,24 @@ func (m *memoryStore) set(prefix string, r *store.Record) {
 		i.metadata[k] = v
 	}
 
+	_, exists := m.store.Get(key)
 	m.store.Set(key, i, r.Expiry)
+
+	typ := store.Created
+	if exists {
+		typ = store.Updated
+	}
+	m.notify(key, &store.Event{Type: typ, Key: r.Key, Record: copyRecord(r, store.WriteOptions{})})
 }
 
 func (m *memoryStore) delete(prefix, key string) {
-	key = m.key(prefix, key)
-	m.store.Delete(key)
+	k := m.key(prefix, key)
+	_, exists := m.store.Get(k)
+	m.store.Delete(k)
+
+	if exists {
+		m.notify(k, &store.Event{Type: store.Deleted, Key: key})
+	}
 }
 
 func (m *memoryStore) list(prefix string, limit, offset uint) []string {
@@ -172,6 +193,9 @@ func (m *memoryStore) Read(key string, opts ...store.ReadOption) ([]*store.Recor
 
 	prefix := m.prefix(readOpts.Database, readOpts.Table)
 
+	m.RLock()
+	defer m.RUnlock()
+
 	var keys []string
 
 	// Handle Prefix / suffix
@@ -214,27 +238,12 @@ func (m *memoryStore) Write(r *store.Record, opts ...store.WriteOption) error {
 
 	prefix := m.prefix(writeOpts.Database, writeOpts.Table)
 
+	m.Lock()
+	defer m.Unlock()
+
 	if len(opts) > 0 {
 		// Copy the record before applying options, or the incoming record will be mutated
-		newRecord := store.Record{}
-		newRecord.Key = r.Key
-		newRecord.Value = make([]byte, len(r.Value))
-		newRecord.Metadata = make(map[string]interface{})
-		copy(newRecord.Value, r.Value)
-		newRecord.Expiry = r.Expiry
-
-		if !writeOpts.Expiry.IsZero() {
-			newRecord.Expiry = time.Until(writeOpts.Expiry)
-		}
-		if writeOpts.TTL != 0 {
-			newRecord.Expiry = writeOpts.TTL
-		}
-
-		for k, v := range r.Metadata {
-			newRecord.Metadata[k] = v
-		}
-
-		m.set(prefix, &newRecord)
+		m.set(prefix, copyRecord(r, writeOpts))
 		return nil
 	}
 
@@ -251,7 +260,10 @@ func (m *memoryStore) Delete(key string, opts ...store.DeleteOption) error {
 	}
 
 	prefix := m.prefix(deleteOptions.Database, deleteOptions.Table)
+
+	m.Lock()
 	m.delete(prefix, key)
+	m.Unlock()
 	return nil
 }
 
@@ -267,7 +279,10 @@ func (m *memoryStore) List(opts ...store.ListOption) ([]string, error) {
 	}
 
 	prefix := m.prefix(listOptions.Database, listOptions.Table)
+
+	m.RLock()
 	keys := m.list(prefix, listOptions.Limit, listOptions.Offset)
+	m.RUnlock()
 
 	if len(listOptions.Prefix) > 0 {
 		var prefixKeys []string
@@ -291,3 +306,198 @@ func (m *memoryStore) List(opts ...store.ListOption) ([]string, error) {
 
 	return keys, nil
 }
+
+func (m *memoryStore) Query(opts ...store.QueryOption) ([]*store.Record, error) {
+	queryOptions := store.QueryOptions{}
+	for _, o := range opts {
+		o(&queryOptions)
+	}
+
+	prefix := m.prefix(queryOptions.Database, queryOptions.Table)
+
+	m.RLock()
+	defer m.RUnlock()
+
+	var records []*store.Record
+	for _, k := range m.list(prefix, 0, 0) {
+		r, err := m.get(prefix, k)
+		if err != nil {
+			continue
+		}
+		records = append(records, r)
+	}
+
+	return store.Filter(records, m.options.Indexes, queryOptions)
+}
+
+// Page returns the records after the cursor in key order
+func (m *memoryStore) Page(cursor string, limit uint, opts ...store.ListOption) ([]*store.Record, error) {
+	var listOpts store.ListOptions
+	for _, o := range opts {
+		o(&listOpts)
+	}
+
+	prefix := m.prefix(listOpts.Database, listOpts.Table)
+
+	m.RLock()
+	defer m.RUnlock()
+
+	var keys []string
+	for _, k := range m.list(prefix, 0, 0) {
+		if k <= cursor || !strings.HasPrefix(k, listOpts.Prefix) || !strings.HasSuffix(k, listOpts.Suffix) {
+			continue
+		}
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var page []*store.Record
+	for _, k := range keys {
+		if limit > 0 && uint(len(page)) == limit {
+			break
+		}
+		// expired since listed
+		r, err := m.get(prefix, k)
+		if err != nil {
+			continue
+		}
+		page = append(page, r)
+	}
+
+	return page, nil
+}
+
+// CompareAndSwap writes the record under the write lock if the current value
+// of its key is old
+func (m *memoryStore) CompareAndSwap(r *store.Record, old []byte, opts ...store.WriteOption) error {
+	writeOpts := store.WriteOptions{}
+	for _, o := range opts {
+		o(&writeOpts)
+	}
+
+	prefix := m.prefix(writeOpts.Database, writeOpts.Table)
+
+	m.Lock()
+	defer m.Unlock()
+
+	current, err := m.get(prefix, r.Key)
+	switch {
+	case err == store.ErrNotFound:
+		if old != nil {
+			return store.ErrConflict
+		}
+	case err != nil:
+		return err
+	case old == nil || !bytes.Equal(current.Value, old):
+		return store.ErrConflict
+	}
+
+	m.set(prefix, copyRecord(r, writeOpts))
+	return nil
+}
+
+// Begin a transaction. Its writes and deletes are buffered and applied under
+// the write lock of the store on commit.
+func (m *memoryStore) Begin() (store.Tx, error) {
+	return &memoryTx{store: m}, nil
+}
+
+type memoryTx struct {
+	store *memoryStore
+	ops   []func()
+	done  bool
+}
+
+func (t *memoryTx) Read(key string, opts ...store.ReadOption) ([]*store.Record, error) {
+	if t.done {
+		return nil, store.ErrTxDone
+	}
+	return t.store.Read(key, opts...)
+}
+
+func (t *memoryTx) Write(r *store.Record, opts ...store.WriteOption) error {
+	if t.done {
+		return store.ErrTxDone
+	}
+
+	writeOpts := store.WriteOptions{}
+	for _, o := range opts {
+		o(&writeOpts)
+	}
+
+	prefix := t.store.prefix(writeOpts.Database, writeOpts.Table)
+	// copy the record as it's only written on commit
+	record := copyRecord(r, writeOpts)
+
+	t.ops = append(t.ops, func() {
+		t.store.set(prefix, record)
+	})
+
+	return nil
+}
+
+func (t *memoryTx) Delete(key string, opts ...store.DeleteOption) error {
+	if t.done {
+		return store.ErrTxDone
+	}
+
+	deleteOptions := store.DeleteOptions{}
+	for _, o := range opts {
+		o(&deleteOptions)
+	}
+
+	prefix := t.store.prefix(deleteOptions.Database, deleteOptions.Table)
+
+	t.ops = append(t.ops, func() {
+		t.store.delete(prefix, key)
+	})
+
+	return nil
+}
+
+func (t *memoryTx) Commit() error {
+	if t.done {
+		return store.ErrTxDone
+	}
+	t.done = true
+
+	t.store.Lock()
+	defer t.store.Unlock()
+
+	for _, op := range t.ops {
+		op()
+	}
+
+	return nil
+}
+
+func (t *memoryTx) Rollback() error {
+	if t.done {
+		return store.ErrTxDone
+	}
+	t.done = true
+	t.ops = nil
+	return nil
+}
+
+func copyRecord(r *store.Record, writeOpts store.WriteOptions) *store.Record {
+	newRecord := store.Record{}
+	newRecord.Key = r.Key
+	newRecord.Value = make([]byte, len(r.Value))
+	newRecord.Metadata = make(map[string]interface{})
+	copy(newRecord.Value, r.Value)
+	newRecord.Expiry = r.Expiry
+
+	if !writeOpts.Expiry.IsZero() {
+		newRecord.Expiry = time.Until(writeOpts.Expiry)
+	}
+	if writeOpts.TTL != 0 {
+		newRecord.Expiry = writeOpts.TTL
+	}
+
+	for k, v := range r.Metadata {
+		newRecord.Metadata[k] = v
+	}
+
+	return &newRecord
+}
@@ -3,6 +3,7 @@ package memory
 import (
 	"fmt"
 	"os"
+	"strings"
 	"testing"
 	"time"
 
@@ -276,3 +277,186 @@ func basictest(s store.Store, t *testing.T) {
 		}
 	}
 }
+
+func TestMemoryQuery(t *testing.T) {
+	s := NewStore(store.Indexes(
+		store.Index{Field: "name", Type: store.IndexString},
+		store.Index{Field: "age", Type: store.IndexNumber},
+	))
+
+	for i, name := range []string{"alice", "bob", "carol", "dave"} {
+		if err := s.Write(&store.Record{
+			Key:      fmt.Sprintf("user-%d", i),
+			Value:    []byte(name),
+			Metadata: map[string]interface{}{"name": name, "age": 20 + i*10},
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	records, err := store.Query(s, store.QueryEqual("name", "bob"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 1 || records[0].Key != "user-1" {
+		t.Fatalf("Expected user-1, got %v", records)
+	}
+
+	records, err = store.Query(s, store.QueryRange("age", 30, 45))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 2 || records[0].Key != "user-1" || records[1].Key != "user-2" {
+		t.Fatalf("Expected user-1 and user-2, got %v", records)
+	}
+
+	// unbounded ranges with pagination
+	records, err = store.Query(s, store.QueryRange("age", nil, nil), store.QueryOffset(1), store.QueryLimit(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 2 || records[0].Key != "user-1" || records[1].Key != "user-2" {
+		t.Fatalf("Expected user-1 and user-2, got %v", records)
+	}
+
+	if _, err := store.Query(s, store.QueryEqual("email", "foo")); err != store.ErrNotIndexed {
+		t.Fatalf("Expected %v, got %v", store.ErrNotIndexed, err)
+	}
+}
+
+func TestMemoryTransaction(t *testing.T) {
+	s := NewStore()
+
+	tx, err := store.Begin(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tx.Write(&store.Record{Key: "foo", Value: []byte("bar")})
+	tx.Write(&store.Record{Key: "baz", Value: []byte("qux")}, store.WriteTo("micro", "other"))
+
+	// writes aren't visible before the commit
+	if _, err := s.Read("foo"); err != store.ErrNotFound {
+		t.Fatalf("Expected not found got %v", err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	if recs, err := s.Read("foo"); err != nil || string(recs[0].Value) != "bar" {
+		t.Fatalf("Expected the committed record got %v %v", recs, err)
+	}
+	if _, err := s.Read("baz", store.ReadFrom("micro", "other")); err != nil {
+		t.Fatalf("Expected the committed record got %v", err)
+	}
+	if err := tx.Write(&store.Record{Key: "foo"}); err != store.ErrTxDone {
+		t.Fatalf("Expected tx done got %v", err)
+	}
+
+	tx, _ = store.Begin(s)
+	tx.Delete("foo")
+	tx.Rollback()
+	if _, err := s.Read("foo"); err != nil {
+		t.Fatalf("Expected the rolled back delete to be discarded got %v", err)
+	}
+}
+
+func TestMemoryCompareAndSwap(t *testing.T) {
+	s := NewStore()
+
+	if !store.Supports(s, store.CapCompareAndSwap) || !store.Supports(s, store.CapTransactions) {
+		t.Fatal("Expected the memory store to support compare and swap and transactions")
+	}
+
+	if err := store.CompareAndSwap(s, &store.Record{Key: "lock", Value: []byte("a")}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.CompareAndSwap(s, &store.Record{Key: "lock", Value: []byte("b")}, nil); err != store.ErrConflict {
+		t.Fatalf("Expected a conflict got %v", err)
+	}
+	if err := store.CompareAndSwap(s, &store.Record{Key: "lock", Value: []byte("b")}, []byte("c")); err != store.ErrConflict {
+		t.Fatalf("Expected a conflict got %v", err)
+	}
+	if err := store.CompareAndSwap(s, &store.Record{Key: "lock", Value: []byte("b")}, []byte("a")); err != nil {
+		t.Fatal(err)
+	}
+	if recs, err := s.Read("lock"); err != nil || string(recs[0].Value) != "b" {
+		t.Fatalf("Expected the swapped record got %v %v", recs, err)
+	}
+}
+
+func TestMemoryWatch(t *testing.T) {
+	s := NewStore()
+
+	w, err := store.Watch(s, store.WatchPrefix("foo"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Stop()
+
+	s.Write(&store.Record{Key: "foo", Value: []byte("a")})
+	s.Write(&store.Record{Key: "bar", Value: []byte("a")})
+	s.Write(&store.Record{Key: "foo", Value: []byte("b")})
+	s.Delete("foo")
+	s.Write(&store.Record{Key: "foobar", Value: []byte("a"), Expiry: time.Millisecond})
+
+	time.Sleep(time.Millisecond * 10)
+	s.(*memoryStore).store.DeleteExpired()
+
+	expected := []store.EventType{store.Created, store.Updated, store.Deleted, store.Created, store.Expired}
+	for i, typ := range expected {
+		ev, err := w.Next()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ev.Type != typ || !strings.HasPrefix(ev.Key, "foo") {
+			t.Fatalf("Expected event %d to be %v got %v %s", i, typ, ev.Type, ev.Key)
+		}
+	}
+
+	w.Stop()
+	if _, err := w.Next(); err != store.ErrWatcherStopped {
+		t.Fatalf("Expected the watcher stopped got %v", err)
+	}
+}
+
+func TestMemoryIterate(t *testing.T) {
+	s := NewStore()
+
+	var recs []*store.Record
+	for i := 0; i < 5; i++ {
+		recs = append(recs, &store.Record{Key: fmt.Sprintf("key%d", i), Value: []byte{byte(i)}})
+	}
+	if err := store.BatchWrite(s, recs); err != nil {
+		t.Fatal(err)
+	}
+	s.Write(&store.Record{Key: "other"}, store.WriteTo("micro", "other"))
+
+	it := store.Iterate(s, "key0", store.ListLimit(2))
+	for i := 1; i < 4; i++ {
+		r, err := it.Next()
+		if err != nil || r.Key != fmt.Sprintf("key%d", i) {
+			t.Fatalf("Expected key%d got %v %v", i, r, err)
+		}
+		// deleted after the keys were listed
+		if i == 2 {
+			s.Delete("key4")
+		}
+	}
+	if _, err := it.Next(); err != store.ErrIteratorDone {
+		t.Fatalf("Expected the iterator done got %v", err)
+	}
+
+	// the records are paged without listing the keys first
+	if _, ok := s.(store.Pager); !ok {
+		t.Fatal("Expected the store to page the records")
+	}
+	s.Write(&store.Record{Key: "key5"})
+	it = store.Iterate(s, "key2", store.ListPrefix("key"), store.ListLimit(2))
+	for _, k := range []string{"key3", "key5"} {
+		if r, err := it.Next(); err != nil || r.Key != k {
+			t.Fatalf("Expected %s got %v %v", k, r, err)
+		}
+	}
+	if _, err := it.Next(); err != store.ErrIteratorDone {
+		t.Fatalf("Expected the iterator done got %v", err)
+	}
+}
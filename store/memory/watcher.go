@@ -0,0 +1,98 @@
+package memory
+
+import (
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/micro/go-micro/v2/store"
+)
+
+// time an event waits for a slow watcher before it's dropped
+var sendEventTime = 10 * time.Millisecond
+
+type memoryWatcher struct {
+	id     string
+	prefix string
+	events chan *store.Event
+	exit   chan bool
+}
+
+func (w *memoryWatcher) Next() (*store.Event, error) {
+	select {
+	case ev := <-w.events:
+		return ev, nil
+	case <-w.exit:
+		return nil, store.ErrWatcherStopped
+	}
+}
+
+func (w *memoryWatcher) Stop() {
+	select {
+	case <-w.exit:
+	default:
+		close(w.exit)
+	}
+}
+
+// Watch the changes of the records. The records expired are notified once
+// they're evicted.
+func (m *memoryStore) Watch(opts ...store.WatchOption) (store.Watcher, error) {
+	var watchOpts store.WatchOptions
+	for _, o := range opts {
+		o(&watchOpts)
+	}
+
+	w := &memoryWatcher{
+		id:     uuid.New().String(),
+		prefix: m.prefix(watchOpts.Database, watchOpts.Table) + "/" + watchOpts.Prefix,
+		events: make(chan *store.Event, 64),
+		exit:   make(chan bool),
+	}
+
+	m.wmtx.Lock()
+	m.watchers[w.id] = w
+	m.wmtx.Unlock()
+
+	return w, nil
+}
+
+// evicted notifies the expired records evicted from the cache. The deleted
+// records are notified by delete.
+func (m *memoryStore) evicted(key string, v interface{}) {
+	i, ok := v.(*storeRecord)
+	if !ok || i.expiresAt.IsZero() || i.expiresAt.After(time.Now()) {
+		return
+	}
+	m.notify(key, &store.Event{Type: store.Expired, Key: i.key})
+}
+
+// notify the watchers of the key of a change of its record
+func (m *memoryStore) notify(key string, ev *store.Event) {
+	m.wmtx.RLock()
+	var watchers []*memoryWatcher
+	for _, w := range m.watchers {
+		if strings.HasPrefix(key, w.prefix) {
+			watchers = append(watchers, w)
+		}
+	}
+	m.wmtx.RUnlock()
+
+	ev.Timestamp = time.Now()
+
+	for _, w := range watchers {
+		select {
+		case <-w.exit:
+			m.wmtx.Lock()
+			delete(m.watchers, w.id)
+			m.wmtx.Unlock()
+		case w.events <- ev:
+		default:
+			select {
+			case w.events <- ev:
+			case <-w.exit:
+			case <-time.After(sendEventTime):
+			}
+		}
+	}
+}
@@ -17,6 +17,8 @@ type Options struct {
 	Database string
 	// Table is analagous to a table in database backends or a key prefix in KV backends
 	Table string
+	// Indexes are the secondary indexes on record metadata fields used by Query
+	Indexes []Index
 	// Context should contain all implementation specific options, using context.WithValue.
 	Context context.Context
 	// Client to use for RPC
@@ -0,0 +1,250 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+)
+
+var (
+	// ErrNotIndexed is returned when a query uses a metadata field which isn't indexed
+	ErrNotIndexed = errors.New("field not indexed")
+	// ErrQueryNotSupported is returned when querying a store which doesn't implement Querier
+	ErrQueryNotSupported = errors.New("query not supported")
+)
+
+// IndexType is the type of the values of an indexed field
+type IndexType int
+
+const (
+	// IndexString compares values as strings
+	IndexString IndexType = iota
+	// IndexNumber compares values as numbers
+	IndexNumber
+)
+
+// Index is a secondary index on a record metadata field
+type Index struct {
+	// Field of the record metadata
+	Field string
+	// Type of the field values
+	Type IndexType
+}
+
+// Querier is implemented by stores which support querying records by
+// their indexed metadata fields
+type Querier interface {
+	// Query returns the records matching the query ordered by the field value
+	Query(opts ...QueryOption) ([]*Record, error)
+}
+
+// Query the records of a store by an indexed metadata field
+func Query(s Store, opts ...QueryOption) ([]*Record, error) {
+	q, ok := s.(Querier)
+	if !ok {
+		return nil, ErrQueryNotSupported
+	}
+	return q.Query(opts...)
+}
+
+// Indexes declares the secondary indexes of the store
+func Indexes(idx ...Index) Option {
+	return func(o *Options) {
+		o.Indexes = idx
+	}
+}
+
+// QueryOptions configures an individual Query operation
+type QueryOptions struct {
+	Database, Table string
+	// Field is the indexed metadata field to query
+	Field string
+	// Equal matches records where the field equals the value
+	Equal interface{}
+	// Min and Max match records where the field is within the inclusive
+	// range. A nil value leaves that end of the range unbounded.
+	Min, Max interface{}
+	// Limit limits the number of returned records
+	Limit uint
+	// Offset when combined with Limit supports pagination
+	Offset uint
+}
+
+// QueryOption sets values in QueryOptions
+type QueryOption func(q *QueryOptions)
+
+// QueryFrom the database and table
+func QueryFrom(database, table string) QueryOption {
+	return func(q *QueryOptions) {
+		q.Database = database
+		q.Table = table
+	}
+}
+
+// QueryEqual matches records where the field equals the value
+func QueryEqual(field string, v interface{}) QueryOption {
+	return func(q *QueryOptions) {
+		q.Field = field
+		q.Equal = v
+	}
+}
+
+// QueryRange matches records where the field is between min and max inclusive.
+// Pass nil for min or max to leave that end unbounded.
+func QueryRange(field string, min, max interface{}) QueryOption {
+	return func(q *QueryOptions) {
+		q.Field = field
+		q.Min = min
+		q.Max = max
+	}
+}
+
+// QueryLimit limits the number of returned records to l
+func QueryLimit(l uint) QueryOption {
+	return func(q *QueryOptions) {
+		q.Limit = l
+	}
+}
+
+// QueryOffset starts returning records from o. Use in conjunction with Limit for pagination
+func QueryOffset(o uint) QueryOption {
+	return func(q *QueryOptions) {
+		q.Offset = o
+	}
+}
+
+// LookupIndex returns the index of a field
+func LookupIndex(indexes []Index, field string) (Index, error) {
+	for _, idx := range indexes {
+		if idx.Field == field {
+			return idx, nil
+		}
+	}
+	return Index{}, ErrNotIndexed
+}
+
+// IndexValue converts a value to the type of the index. Numbers are
+// returned as float64 and everything else as a string.
+func IndexValue(t IndexType, v interface{}) (interface{}, error) {
+	if t == IndexString {
+		if s, ok := v.(string); ok {
+			return s, nil
+		}
+		return fmt.Sprintf("%v", v), nil
+	}
+
+	switch n := v.(type) {
+	case float64:
+		return n, nil
+	case float32:
+		return float64(n), nil
+	case int:
+		return float64(n), nil
+	case int32:
+		return float64(n), nil
+	case int64:
+		return float64(n), nil
+	case uint:
+		return float64(n), nil
+	case uint32:
+		return float64(n), nil
+	case uint64:
+		return float64(n), nil
+	case string:
+		return strconv.ParseFloat(n, 64)
+	}
+
+	return nil, fmt.Errorf("value %v is not a number", v)
+}
+
+func compare(a, b interface{}) int {
+	switch av := a.(type) {
+	case float64:
+		bv := b.(float64)
+		if av < bv {
+			return -1
+		} else if av > bv {
+			return 1
+		}
+	case string:
+		bv := b.(string)
+		if av < bv {
+			return -1
+		} else if av > bv {
+			return 1
+		}
+	}
+	return 0
+}
+
+// Filter applies a query to the records by scanning them. It's used by
+// stores which can't query their backend natively.
+func Filter(records []*Record, indexes []Index, opts QueryOptions) ([]*Record, error) {
+	idx, err := LookupIndex(indexes, opts.Field)
+	if err != nil {
+		return nil, err
+	}
+
+	// convert the bounds of the query
+	var bounds [3]interface{}
+	for i, v := range []interface{}{opts.Equal, opts.Min, opts.Max} {
+		if v == nil {
+			continue
+		}
+		if bounds[i], err = IndexValue(idx.Type, v); err != nil {
+			return nil, err
+		}
+	}
+	equal, min, max := bounds[0], bounds[1], bounds[2]
+
+	type match struct {
+		value  interface{}
+		record *Record
+	}
+
+	var matches []match
+
+	for _, r := range records {
+		v, ok := r.Metadata[opts.Field]
+		if !ok {
+			continue
+		}
+		val, err := IndexValue(idx.Type, v)
+		if err != nil {
+			continue
+		}
+		if equal != nil && compare(val, equal) != 0 {
+			continue
+		}
+		if min != nil && compare(val, min) < 0 {
+			continue
+		}
+		if max != nil && compare(val, max) > 0 {
+			continue
+		}
+		matches = append(matches, match{val, r})
+	}
+
+	sort.Slice(matches, func(i, j int) bool {
+		if c := compare(matches[i].value, matches[j].value); c != 0 {
+			return c < 0
+		}
+		return matches[i].record.Key < matches[j].record.Key
+	})
+
+	if opts.Offset > uint(len(matches)) {
+		return []*Record{}, nil
+	}
+	matches = matches[opts.Offset:]
+	if opts.Limit > 0 && opts.Limit < uint(len(matches)) {
+		matches = matches[:opts.Limit]
+	}
+
+	results := make([]*Record, len(matches))
+	for i, m := range matches {
+		results[i] = m.record
+	}
+
+	return results, nil
+}
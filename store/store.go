@@ -1,5 +1,8 @@
 // Package store is an interface for distributed data storage.
 // The design document is located at https://github.com/micro/development/blob/master/design/store.md
+//
+// Transactions, compare and swap and queries are optional interfaces of the
+// stores which are discovered with Supports.
 package store
 
 import (
@@ -0,0 +1,55 @@
+package store
+
+import "errors"
+
+var (
+	// ErrTxNotSupported is returned when beginning a transaction on a store
+	// which doesn't implement Transactional
+	ErrTxNotSupported = errors.New("transactions not supported")
+	// ErrTxDone is returned when using a transaction which was committed or rolled back
+	ErrTxDone = errors.New("transaction already committed or rolled back")
+)
+
+// Tx is a store transaction. Its writes and deletes are applied atomically on
+// commit and discarded on rollback. Reads only return committed records.
+type Tx interface {
+	// Read records committed to the store
+	Read(key string, opts ...ReadOption) ([]*Record, error)
+	// Write a record within the transaction
+	Write(r *Record, opts ...WriteOption) error
+	// Delete a record within the transaction
+	Delete(key string, opts ...DeleteOption) error
+	// Commit applies the writes and deletes of the transaction
+	Commit() error
+	// Rollback discards the writes and deletes of the transaction
+	Rollback() error
+}
+
+// Transactional is implemented by stores which support transactions
+type Transactional interface {
+	// Begin a transaction
+	Begin() (Tx, error)
+}
+
+// Begin a transaction on the store
+func Begin(s Store) (Tx, error) {
+	t, ok := s.(Transactional)
+	if !ok {
+		return nil, ErrTxNotSupported
+	}
+	return t.Begin()
+}
+
+// Update runs the function within a transaction which is committed if the
+// function returns nil and rolled back otherwise
+func Update(s Store, fn func(tx Tx) error) error {
+	tx, err := Begin(s)
+	if err != nil {
+		return err
+	}
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
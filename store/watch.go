@@ -0,0 +1,224 @@
+package store
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"time"
+)
+
+var (
+	// ErrWatcherStopped is returned by Next when the watcher is stopped
+	ErrWatcherStopped = errors.New("watcher stopped")
+	// DefaultWatchInterval is the interval the stores which don't implement
+	// Watchable are polled at
+	DefaultWatchInterval = time.Second
+)
+
+// EventType is the type of a change of a record
+type EventType int
+
+const (
+	// Created is emitted when a record is written with a new key
+	Created EventType = iota
+	// Updated is emitted when an existing record is written
+	Updated
+	// Deleted is emitted when a record is deleted
+	Deleted
+	// Expired is emitted when a record expires
+	Expired
+)
+
+func (t EventType) String() string {
+	switch t {
+	case Created:
+		return "create"
+	case Updated:
+		return "update"
+	case Deleted:
+		return "delete"
+	case Expired:
+		return "expire"
+	default:
+		return "unknown"
+	}
+}
+
+// Event is a change of a record
+type Event struct {
+	// Type of the change
+	Type EventType
+	// Key of the record
+	Key string
+	// Record written, nil if deleted or expired
+	Record *Record
+	// Timestamp of the change
+	Timestamp time.Time
+}
+
+// Watcher returns the changes of the records of a store
+type Watcher interface {
+	// Next is a blocking call
+	Next() (*Event, error)
+	// Stop the watcher
+	Stop()
+}
+
+// Watchable is implemented by stores which notify the changes of their records
+type Watchable interface {
+	// Watch the changes of the records
+	Watch(opts ...WatchOption) (Watcher, error)
+}
+
+// WatchOptions configures a Watch
+type WatchOptions struct {
+	Database, Table string
+	// Prefix of the keys watched
+	Prefix string
+	// Interval the store is polled at if it's not Watchable
+	Interval time.Duration
+}
+
+// WatchOption sets values in WatchOptions
+type WatchOption func(w *WatchOptions)
+
+// WatchFrom the database and table
+func WatchFrom(database, table string) WatchOption {
+	return func(w *WatchOptions) {
+		w.Database = database
+		w.Table = table
+	}
+}
+
+// WatchPrefix only watches the keys with the prefix
+func WatchPrefix(p string) WatchOption {
+	return func(w *WatchOptions) {
+		w.Prefix = p
+	}
+}
+
+// WatchInterval is the interval the store is polled at if it's not Watchable
+func WatchInterval(d time.Duration) WatchOption {
+	return func(w *WatchOptions) {
+		w.Interval = d
+	}
+}
+
+// Watch the changes of the records of the store. The stores which don't
+// implement Watchable are polled and their changes found by comparing the
+// records between two polls.
+func Watch(s Store, opts ...WatchOption) (Watcher, error) {
+	if w, ok := s.(Watchable); ok {
+		return w.Watch(opts...)
+	}
+
+	options := WatchOptions{
+		Interval: DefaultWatchInterval,
+	}
+	for _, o := range opts {
+		o(&options)
+	}
+
+	w := &pollWatcher{
+		store:   s,
+		opts:    options,
+		records: make(map[string]*polledRecord),
+		exit:    make(chan bool),
+	}
+
+	// the changes are relative to the records at the start
+	if err := w.poll(time.Now()); err != nil {
+		return nil, err
+	}
+	w.events = nil
+
+	return w, nil
+}
+
+type polledRecord struct {
+	record    *Record
+	expiresAt time.Time
+}
+
+type pollWatcher struct {
+	store   Store
+	opts    WatchOptions
+	records map[string]*polledRecord
+	events  []*Event
+	exit    chan bool
+}
+
+// poll the records and queue their changes since the last poll
+func (w *pollWatcher) poll(now time.Time) error {
+	recs, err := w.store.Read(w.opts.Prefix,
+		ReadFrom(w.opts.Database, w.opts.Table),
+		ReadPrefix(),
+	)
+	if err != nil && err != ErrNotFound {
+		return err
+	}
+
+	seen := make(map[string]bool, len(recs))
+
+	for _, r := range recs {
+		seen[r.Key] = true
+
+		p := &polledRecord{record: r}
+		if r.Expiry > 0 {
+			p.expiresAt = now.Add(r.Expiry)
+		}
+
+		prev, ok := w.records[r.Key]
+		w.records[r.Key] = p
+
+		switch {
+		case !ok:
+			w.events = append(w.events, &Event{Type: Created, Key: r.Key, Record: r, Timestamp: now})
+		case !bytes.Equal(prev.record.Value, r.Value) || !reflect.DeepEqual(prev.record.Metadata, r.Metadata):
+			w.events = append(w.events, &Event{Type: Updated, Key: r.Key, Record: r, Timestamp: now})
+		}
+	}
+
+	for k, prev := range w.records {
+		if seen[k] {
+			continue
+		}
+		delete(w.records, k)
+
+		typ := Deleted
+		if !prev.expiresAt.IsZero() && !prev.expiresAt.After(now) {
+			typ = Expired
+		}
+		w.events = append(w.events, &Event{Type: typ, Key: k, Timestamp: now})
+	}
+
+	return nil
+}
+
+func (w *pollWatcher) Next() (*Event, error) {
+	t := time.NewTicker(w.opts.Interval)
+	defer t.Stop()
+
+	for len(w.events) == 0 {
+		select {
+		case <-w.exit:
+			return nil, ErrWatcherStopped
+		case now := <-t.C:
+			if err := w.poll(now); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	ev := w.events[0]
+	w.events = w.events[1:]
+	return ev, nil
+}
+
+func (w *pollWatcher) Stop() {
+	select {
+	case <-w.exit:
+	default:
+		close(w.exit)
+	}
+}
@@ -0,0 +1,132 @@
+package transport
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/micro/go-micro/v2/util/compress"
+)
+
+var (
+	// HeaderContentEncoding is the compression of the message body
+	HeaderContentEncoding = "Micro-Content-Encoding"
+	// HeaderAcceptEncoding are the compressions the sender supports
+	HeaderAcceptEncoding = "Micro-Accept-Encoding"
+
+	// DefaultCompressionThreshold is the min size of the bodies compressed
+	DefaultCompressionThreshold = 1024
+)
+
+// compressSocket compresses the messages sent once the other side advertised
+// a compression it supports and decompresses the messages received
+type compressSocket struct {
+	Socket
+	encodings []string
+	threshold int
+
+	sync.RWMutex
+	// encodings supported by the other side
+	accept string
+}
+
+// encoding returns the preferred encoding supported by the other side
+func (s *compressSocket) encoding() string {
+	s.RLock()
+	defer s.RUnlock()
+
+	for _, e := range s.encodings {
+		if _, ok := compress.Compressors[e]; !ok {
+			continue
+		}
+		for _, a := range strings.Split(s.accept, ",") {
+			if strings.TrimSpace(a) == e {
+				return e
+			}
+		}
+	}
+	return ""
+}
+
+func (s *compressSocket) Send(m *Message) error {
+	header := make(map[string]string, len(m.Header)+2)
+	for k, v := range m.Header {
+		header[k] = v
+	}
+	header[HeaderAcceptEncoding] = strings.Join(s.encodings, ",")
+
+	body := m.Body
+	if len(body) >= s.threshold {
+		if e := s.encoding(); len(e) > 0 {
+			b, err := compress.Compressors[e].Compress(body)
+			if err != nil {
+				return err
+			}
+			body = b
+			header[HeaderContentEncoding] = e
+		}
+	}
+
+	return s.Socket.Send(&Message{
+		Header: header,
+		Body:   body,
+	})
+}
+
+func (s *compressSocket) Recv(m *Message) error {
+	if err := s.Socket.Recv(m); err != nil {
+		return err
+	}
+
+	if a, ok := m.Header[HeaderAcceptEncoding]; ok {
+		s.Lock()
+		s.accept = a
+		s.Unlock()
+		delete(m.Header, HeaderAcceptEncoding)
+	}
+
+	e, ok := m.Header[HeaderContentEncoding]
+	if !ok {
+		return nil
+	}
+
+	c, ok := compress.Compressors[e]
+	if !ok {
+		return fmt.Errorf("unknown content encoding %s", e)
+	}
+	body, err := c.Decompress(m.Body)
+	if err != nil {
+		return fmt.Errorf("error decompressing message: %v", err)
+	}
+	m.Body = body
+	delete(m.Header, HeaderContentEncoding)
+
+	return nil
+}
+
+// Probe the connection of the socket if it's a prober
+func (s *compressSocket) Probe() error {
+	if p, ok := s.Socket.(Prober); ok {
+		return p.Probe()
+	}
+	return nil
+}
+
+// CompressSocket returns a socket compressing the messages with the compression
+// of the options. Transports wrap the sockets they dial and accept with it.
+func CompressSocket(s Socket, opts Options) Socket {
+	if len(opts.Compression) == 0 {
+		return s
+	}
+
+	threshold := opts.CompressionThreshold
+	if threshold <= 0 {
+		threshold = DefaultCompressionThreshold
+	}
+
+	return &compressSocket{
+		Socket:    s,
+		encodings: opts.Compression,
+		threshold: threshold,
+	}
+}
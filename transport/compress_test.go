@@ -0,0 +1,78 @@
+package transport
+
+import (
+	"bytes"
+	"testing"
+)
+
+// pipeSocket sends messages to the other end of the pipe
+type pipeSocket struct {
+	in  chan *Message
+	out chan *Message
+}
+
+func (p *pipeSocket) Recv(m *Message) error {
+	*m = *<-p.in
+	return nil
+}
+
+func (p *pipeSocket) Send(m *Message) error {
+	p.out <- m
+	return nil
+}
+
+func (p *pipeSocket) Close() error   { return nil }
+func (p *pipeSocket) Local() string  { return "local" }
+func (p *pipeSocket) Remote() string { return "remote" }
+
+func TestCompressSocket(t *testing.T) {
+	ab := make(chan *Message, 1)
+	ba := make(chan *Message, 1)
+
+	opts := Options{
+		Compression:          []string{"zstd", "gzip"},
+		CompressionThreshold: 10,
+	}
+	a := CompressSocket(&pipeSocket{in: ba, out: ab}, opts)
+	b := CompressSocket(&pipeSocket{in: ab, out: ba}, Options{Compression: []string{"gzip"}})
+
+	large := bytes.Repeat([]byte("hello world "), 100)
+
+	exchange := func(from, to Socket, body []byte, encoding string) {
+		if err := from.Send(&Message{Header: map[string]string{"foo": "bar"}, Body: body}); err != nil {
+			t.Fatal(err)
+		}
+
+		// the encoding of the message sent
+		var ch chan *Message
+		if from == a {
+			ch = ab
+		} else {
+			ch = ba
+		}
+		sent := <-ch
+		if e := sent.Header[HeaderContentEncoding]; e != encoding {
+			t.Fatalf("Expected content encoding %q got %q", encoding, e)
+		}
+		ch <- sent
+
+		var m Message
+		if err := to.Recv(&m); err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(m.Body, body) || m.Header["foo"] != "bar" {
+			t.Fatalf("Unexpected message %v", m.Header)
+		}
+		if _, ok := m.Header[HeaderAcceptEncoding]; ok {
+			t.Fatal("Expected the accept encoding header to be removed")
+		}
+	}
+
+	// the compressions of the other side aren't known yet
+	exchange(a, b, large, "")
+	// the compression supported by both sides is used
+	exchange(b, a, large, "gzip")
+	exchange(a, b, large, "gzip")
+	// small messages aren't compressed
+	exchange(a, b, []byte("hello"), "")
+}
@@ -13,6 +13,7 @@ import (
 
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/keepalive"
 
 	pb "github.com/micro/go-micro/v2/transport/grpc/proto"
 )
@@ -25,6 +26,7 @@ type grpcTransportListener struct {
 	listener net.Listener
 	secure   bool
 	tls      *tls.Config
+	opts     transport.Options
 }
 
 func getTLSConfig(addr string) (*tls.Config, error) {
@@ -75,11 +77,28 @@ func (t *grpcTransportListener) Accept(fn func(transport.Socket)) error {
 		opts = append(opts, grpc.Creds(creds))
 	}
 
+	// close idle and old connections with a GOAWAY
+	opts = append(opts, grpc.KeepaliveParams(keepalive.ServerParameters{
+		MaxConnectionIdle:     t.opts.IdleTimeout,
+		MaxConnectionAge:      t.opts.MaxConnectionAge,
+		MaxConnectionAgeGrace: t.opts.GracePeriod,
+		Time:                  t.opts.KeepAlive,
+		Timeout:               t.opts.KeepAliveTimeout,
+	}))
+
+	// permit the pings of clients with the same keepalive
+	if t.opts.KeepAlive > 0 {
+		opts = append(opts, grpc.KeepaliveEnforcementPolicy(keepalive.EnforcementPolicy{
+			MinTime:             t.opts.KeepAlive / 2,
+			PermitWithoutStream: true,
+		}))
+	}
+
 	// new service
 	srv := grpc.NewServer(opts...)
 
 	// register service
-	pb.RegisterTransportServer(srv, &microTransport{addr: t.listener.Addr().String(), fn: fn})
+	pb.RegisterTransportServer(srv, &microTransport{addr: t.listener.Addr().String(), fn: fn, opts: t.opts})
 
 	// start serving
 	return srv.Serve(t.listener)
@@ -94,8 +113,29 @@ func (t *grpcTransport) Dial(addr string, opts ...transport.DialOption) (transpo
 		opt(&dopts)
 	}
 
+	dialer := &net.Dialer{KeepAlive: t.opts.KeepAlive}
+
+	proxy := t.opts.Proxy
+	if len(dopts.Proxy) > 0 {
+		proxy = dopts.Proxy
+	}
+
 	options := []grpc.DialOption{
 		grpc.WithTimeout(dopts.Timeout),
+		grpc.WithContextDialer(func(ctx context.Context, addr string) (net.Conn, error) {
+			return transport.ProxyDial(func(addr string) (net.Conn, error) {
+				return dialer.DialContext(ctx, "tcp", addr)
+			}, proxy)(addr)
+		}),
+	}
+
+	// ping the server to detect broken connections
+	if t.opts.KeepAlive > 0 {
+		options = append(options, grpc.WithKeepaliveParams(keepalive.ClientParameters{
+			Time:                t.opts.KeepAlive,
+			Timeout:             t.opts.KeepAliveTimeout,
+			PermitWithoutStream: true,
+		}))
 	}
 
 	if t.opts.Secure || t.opts.TLSConfig != nil {
@@ -124,12 +164,12 @@ func (t *grpcTransport) Dial(addr string, opts ...transport.DialOption) (transpo
 	}
 
 	// return a client
-	return &grpcTransportClient{
+	return transport.CompressSocket(&grpcTransportClient{
 		conn:   conn,
 		stream: stream,
 		local:  "localhost",
 		remote: addr,
-	}, nil
+	}, t.opts), nil
 }
 
 func (t *grpcTransport) Listen(addr string, opts ...transport.ListenOption) (transport.Listener, error) {
@@ -139,7 +179,8 @@ func (t *grpcTransport) Listen(addr string, opts ...transport.ListenOption) (tra
 	}
 
 	ln, err := mnet.Listen(addr, func(addr string) (net.Listener, error) {
-		return net.Listen("tcp", addr)
+		lc := &net.ListenConfig{KeepAlive: t.opts.KeepAlive}
+		return lc.Listen(context.Background(), "tcp", addr)
 	})
 	if err != nil {
 		return nil, err
@@ -149,6 +190,7 @@ func (t *grpcTransport) Listen(addr string, opts ...transport.ListenOption) (tra
 		listener: ln,
 		tls:      t.opts.TLSConfig,
 		secure:   t.opts.Secure,
+		opts:     t.opts,
 	}, nil
 }
 
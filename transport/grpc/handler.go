@@ -14,6 +14,7 @@ import (
 type microTransport struct {
 	addr string
 	fn   func(transport.Socket)
+	opts transport.Options
 }
 
 func (m *microTransport) Stream(ts pb.Transport_StreamServer) (err error) {
@@ -37,7 +38,7 @@ func (m *microTransport) Stream(ts pb.Transport_StreamServer) (err error) {
 	}()
 
 	// execute socket func
-	m.fn(sock)
+	m.fn(transport.CompressSocket(sock, m.opts))
 
 	return err
 }
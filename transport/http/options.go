@@ -2,6 +2,7 @@ package http
 
 import (
 	"context"
+	"net"
 	"net/http"
 
 	"github.com/micro/go-micro/v2/transport"
@@ -21,3 +22,15 @@ func Handle(pattern string, handler http.Handler) transport.Option {
 		o.Context = context.WithValue(o.Context, "http_handlers", handlers)
 	}
 }
+
+// Listener serves the transport on the listener rather than listening on the
+// address e.g to share a port with other protocols. It's closed with the
+// transport listener.
+func Listener(l net.Listener) transport.Option {
+	return func(o *transport.Options) {
+		if o.Context == nil {
+			o.Context = context.Background()
+		}
+		o.Context = context.WithValue(o.Context, "http_listener", l)
+	}
+}
@@ -0,0 +1,518 @@
+// Package http3 provides a http transport which also serves and dials HTTP/3.
+// Servers advertise HTTP/3 with the Alt-Svc header of their responses and
+// clients dial HTTP/3 once it's advertised, falling back to HTTP/1.1 if it
+// can't be dialed. Streams always use HTTP/1.1 since a HTTP/3 request isn't
+// bidirectional.
+//
+// It's opt-in e.g service.Transport(http3.NewTransport()) rather than an
+// option of the http transport or registered in cmd, since the quic-go
+// release it uses panics at init on the go releases after 1.14.
+package http3
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+
+	quic "github.com/lucas-clemente/quic-go"
+	h3 "github.com/lucas-clemente/quic-go/http3"
+	"github.com/micro/go-micro/v2/transport"
+	maddr "github.com/micro/go-micro/v2/util/addr"
+	mls "github.com/micro/go-micro/v2/util/tls"
+)
+
+// nextProto is the ALPN of the HTTP/3 draft implemented by quic-go
+const nextProto = "h3-24"
+
+type http3Transport struct {
+	transport.Transport
+	opts transport.Options
+
+	sync.RWMutex
+	// the HTTP/3 addresses advertised by the dialed addresses
+	alts map[string]string
+}
+
+type http3Client struct {
+	t    *http3Transport
+	addr string
+	sess quic.Session
+	rt   *h3.RoundTripper
+	once sync.Once
+
+	// responses of the requests sent
+	rsp chan *response
+}
+
+type response struct {
+	m   *transport.Message
+	err error
+}
+
+type http3Listener struct {
+	transport.Listener
+	t      *http3Transport
+	pc     net.PacketConn
+	srv    *h3.Server
+	altSvc string
+}
+
+type http3Socket struct {
+	w http.ResponseWriter
+	r *http.Request
+	// the request is received once
+	ch chan *http.Request
+
+	once sync.Once
+	sent chan bool
+}
+
+// altSvcClient learns the HTTP/3 address advertised by the server
+type altSvcClient struct {
+	transport.Client
+	t    *http3Transport
+	addr string
+}
+
+// altSvcSocket advertises the HTTP/3 address of the server
+type altSvcSocket struct {
+	transport.Socket
+	altSvc string
+}
+
+// parseAltSvc returns the HTTP/3 address of an Alt-Svc header
+// e.g h3-24=":8443"; ma=2592000
+func parseAltSvc(addr, hdr string) (string, bool) {
+	for _, svc := range strings.Split(hdr, ",") {
+		parts := strings.SplitN(strings.SplitN(svc, ";", 2)[0], "=", 2)
+		if len(parts) != 2 || !strings.HasPrefix(strings.TrimSpace(parts[0]), "h3") {
+			continue
+		}
+		host, port, err := net.SplitHostPort(strings.Trim(strings.TrimSpace(parts[1]), `"`))
+		if err != nil {
+			continue
+		}
+		// the host of the server is used if not set
+		if len(host) == 0 {
+			if host, _, err = net.SplitHostPort(addr); err != nil {
+				continue
+			}
+		}
+		return net.JoinHostPort(host, port), true
+	}
+	return "", false
+}
+
+func (c *altSvcClient) Recv(m *transport.Message) error {
+	if err := c.Client.Recv(m); err != nil {
+		return err
+	}
+	if hdr, ok := m.Header["Alt-Svc"]; ok {
+		if alt, ok := parseAltSvc(c.addr, hdr); ok {
+			c.t.Lock()
+			c.t.alts[c.addr] = alt
+			c.t.Unlock()
+		}
+		delete(m.Header, "Alt-Svc")
+	}
+	return nil
+}
+
+// Probe the connection of the client if it's a prober
+func (c *altSvcClient) Probe() error {
+	if p, ok := c.Client.(transport.Prober); ok {
+		return p.Probe()
+	}
+	return nil
+}
+
+func (s *altSvcSocket) Send(m *transport.Message) error {
+	hdr := make(map[string]string, len(m.Header)+1)
+	for k, v := range m.Header {
+		hdr[k] = v
+	}
+	hdr["Alt-Svc"] = s.altSvc
+	return s.Socket.Send(&transport.Message{Header: hdr, Body: m.Body})
+}
+
+func (c *http3Client) Local() string {
+	return c.sess.LocalAddr().String()
+}
+
+func (c *http3Client) Remote() string {
+	return c.sess.RemoteAddr().String()
+}
+
+// Send makes a request and queues its response to be received
+func (c *http3Client) Send(m *transport.Message) error {
+	header := make(http.Header)
+	for k, v := range m.Header {
+		header.Set(k, v)
+	}
+
+	req := &http.Request{
+		Method: "POST",
+		URL: &url.URL{
+			Scheme: "https",
+			Host:   c.addr,
+			Path:   "/",
+		},
+		Header:        header,
+		Body:          ioutil.NopCloser(bytes.NewReader(m.Body)),
+		ContentLength: int64(len(m.Body)),
+		Host:          c.addr,
+	}
+
+	ctx := context.Background()
+	if c.t.opts.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.t.opts.Timeout)
+		defer cancel()
+	}
+
+	rsp, err := c.rt.RoundTrip(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer rsp.Body.Close()
+
+	b, err := ioutil.ReadAll(rsp.Body)
+	if err != nil {
+		return err
+	}
+
+	r := &response{m: &transport.Message{
+		Header: make(map[string]string, len(rsp.Header)),
+		Body:   b,
+	}}
+	if rsp.StatusCode != 200 {
+		r.err = errors.New(rsp.Status + ": " + string(b))
+	}
+	for k, v := range rsp.Header {
+		if len(v) > 0 {
+			r.m.Header[k] = v[0]
+		} else {
+			r.m.Header[k] = ""
+		}
+	}
+
+	c.rsp <- r
+	return nil
+}
+
+func (c *http3Client) Recv(m *transport.Message) error {
+	if m == nil {
+		return errors.New("message passed in is nil")
+	}
+
+	r, ok := <-c.rsp
+	if !ok {
+		return io.EOF
+	}
+	if r.err != nil {
+		return r.err
+	}
+
+	if m.Header == nil {
+		m.Header = make(map[string]string, len(r.m.Header))
+	}
+	for k, v := range r.m.Header {
+		m.Header[k] = v
+	}
+	m.Body = r.m.Body
+
+	return nil
+}
+
+// Probe checks the session wasn't closed
+func (c *http3Client) Probe() error {
+	select {
+	case <-c.sess.Context().Done():
+		return io.EOF
+	default:
+		return nil
+	}
+}
+
+func (c *http3Client) Close() error {
+	c.once.Do(func() {
+		c.rt.Close()
+		close(c.rsp)
+	})
+	return c.sess.Close()
+}
+
+func (s *http3Socket) Local() string {
+	return s.r.Host
+}
+
+func (s *http3Socket) Remote() string {
+	return s.r.RemoteAddr
+}
+
+func (s *http3Socket) Recv(m *transport.Message) error {
+	if m == nil {
+		return errors.New("message passed in is nil")
+	}
+
+	select {
+	case r := <-s.ch:
+		b, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			return err
+		}
+		r.Body.Close()
+
+		if m.Header == nil {
+			m.Header = make(map[string]string, len(r.Header))
+		}
+		for k, v := range r.Header {
+			if len(v) > 0 {
+				m.Header[k] = v[0]
+			} else {
+				m.Header[k] = ""
+			}
+		}
+		m.Body = b
+		return nil
+	default:
+	}
+
+	// a request has a single response after which the socket is done
+	select {
+	case <-s.sent:
+	case <-s.r.Context().Done():
+	}
+
+	return io.EOF
+}
+
+func (s *http3Socket) Send(m *transport.Message) error {
+	for k, v := range m.Header {
+		s.w.Header().Set(k, v)
+	}
+
+	_, err := s.w.Write(m.Body)
+
+	s.once.Do(func() {
+		close(s.sent)
+	})
+
+	return err
+}
+
+func (s *http3Socket) Close() error {
+	s.once.Do(func() {
+		close(s.sent)
+	})
+	return nil
+}
+
+func (l *http3Listener) Accept(fn func(transport.Socket)) error {
+	l.srv.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ch := make(chan *http.Request, 1)
+		ch <- r
+
+		fn(transport.CompressSocket(&http3Socket{
+			w:    w,
+			r:    r,
+			ch:   ch,
+			sent: make(chan bool),
+		}, l.t.opts))
+	})
+
+	errCh := make(chan error, 2)
+
+	go func() {
+		errCh <- l.srv.Serve(l.pc)
+	}()
+
+	go func() {
+		errCh <- l.Listener.Accept(func(sock transport.Socket) {
+			fn(&altSvcSocket{Socket: sock, altSvc: l.altSvc})
+		})
+	}()
+
+	return <-errCh
+}
+
+func (l *http3Listener) Close() error {
+	l.srv.Close()
+	l.pc.Close()
+	return l.Listener.Close()
+}
+
+// tlsConfig returns the tls config of the transport. A certificate
+// for the address is generated if there's no config.
+func (t *http3Transport) tlsConfig(addr string) (*tls.Config, error) {
+	if t.opts.TLSConfig != nil {
+		return t.opts.TLSConfig.Clone(), nil
+	}
+
+	hosts := []string{addr}
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		if len(host) == 0 || host == "::" || host == "0.0.0.0" {
+			hosts = maddr.IPs()
+		} else {
+			hosts = []string{host}
+		}
+	}
+
+	cert, err := mls.Certificate(hosts...)
+	if err != nil {
+		return nil, err
+	}
+	return &tls.Config{Certificates: []tls.Certificate{cert}}, nil
+}
+
+func (t *http3Transport) quicConfig() *quic.Config {
+	return &quic.Config{
+		IdleTimeout: t.opts.IdleTimeout,
+		KeepAlive:   t.opts.KeepAlive > 0,
+	}
+}
+
+// dial the HTTP/3 address of the server
+func (t *http3Transport) dial(alt string, opts transport.DialOptions) (*http3Client, error) {
+	config := &tls.Config{InsecureSkipVerify: true}
+	if t.opts.TLSConfig != nil {
+		config = t.opts.TLSConfig.Clone()
+	}
+	config.NextProtos = []string{nextProto}
+
+	qconf := t.quicConfig()
+	qconf.HandshakeTimeout = opts.Timeout
+	// the server can't open streams
+	qconf.MaxIncomingStreams = -1
+
+	sess, err := quic.DialAddr(alt, config, qconf)
+	if err != nil {
+		return nil, err
+	}
+
+	return &http3Client{
+		t:    t,
+		addr: alt,
+		sess: sess,
+		rt: &h3.RoundTripper{
+			// the transport compresses messages itself
+			DisableCompression: true,
+			Dial: func(network, addr string, tlsCfg *tls.Config, cfg *quic.Config) (quic.Session, error) {
+				return sess, nil
+			},
+		},
+		rsp: make(chan *response, 1),
+	}, nil
+}
+
+func (t *http3Transport) Dial(addr string, opts ...transport.DialOption) (transport.Client, error) {
+	dopts := transport.DialOptions{
+		Timeout: transport.DefaultDialTimeout,
+	}
+	for _, o := range opts {
+		o(&dopts)
+	}
+
+	t.RLock()
+	alt, ok := t.alts[addr]
+	t.RUnlock()
+
+	if ok && !dopts.Stream {
+		c, err := t.dial(alt, dopts)
+		if err == nil {
+			return transport.CompressSocket(c, t.opts), nil
+		}
+
+		// fall back to HTTP/1.1 until HTTP/3 is advertised again
+		t.Lock()
+		delete(t.alts, addr)
+		t.Unlock()
+	}
+
+	c, err := t.Transport.Dial(addr, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return &altSvcClient{Client: c, t: t, addr: addr}, nil
+}
+
+func (t *http3Transport) Listen(addr string, opts ...transport.ListenOption) (transport.Listener, error) {
+	l, err := t.Transport.Listen(addr, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	config, err := t.tlsConfig(l.Addr())
+	if err != nil {
+		l.Close()
+		return nil, err
+	}
+
+	// serve HTTP/3 on the udp port of the http listener
+	pc, err := net.ListenPacket("udp", l.Addr())
+	if err != nil {
+		l.Close()
+		return nil, err
+	}
+
+	srv := &h3.Server{
+		Server: &http.Server{
+			Addr:      l.Addr(),
+			TLSConfig: config,
+		},
+		QuicConfig: t.quicConfig(),
+	}
+
+	hdr := make(http.Header)
+	if err := srv.SetQuicHeaders(hdr); err != nil {
+		pc.Close()
+		l.Close()
+		return nil, err
+	}
+
+	return &http3Listener{
+		Listener: l,
+		t:        t,
+		pc:       pc,
+		srv:      srv,
+		altSvc:   hdr.Get("Alt-Svc"),
+	}, nil
+}
+
+func (t *http3Transport) Init(opts ...transport.Option) error {
+	for _, o := range opts {
+		o(&t.opts)
+	}
+	return t.Transport.Init(opts...)
+}
+
+func (t *http3Transport) Options() transport.Options {
+	return t.opts
+}
+
+func (t *http3Transport) String() string {
+	return "http3"
+}
+
+// NewTransport returns a http transport which also serves and dials HTTP/3
+func NewTransport(opts ...transport.Option) transport.Transport {
+	var options transport.Options
+	for _, o := range opts {
+		o(&options)
+	}
+
+	return &http3Transport{
+		Transport: transport.NewTransport(opts...),
+		opts:      options,
+		alts:      make(map[string]string),
+	}
+}
@@ -0,0 +1,109 @@
+package http3
+
+import (
+	"testing"
+
+	"github.com/micro/go-micro/v2/transport"
+)
+
+func TestParseAltSvc(t *testing.T) {
+	testData := []struct {
+		hdr string
+		alt string
+		ok  bool
+	}{
+		{`h3-24=":8443"; ma=2592000`, "127.0.0.1:8443", true},
+		{`h2=":443", h3-24="10.0.0.1:8443"`, "10.0.0.1:8443", true},
+		{`h2=":443"`, "", false},
+		{`h3-24="8443"`, "", false},
+	}
+
+	for _, d := range testData {
+		alt, ok := parseAltSvc("127.0.0.1:8080", d.hdr)
+		if alt != d.alt || ok != d.ok {
+			t.Fatalf("Expected %s %v for %s got %s %v", d.alt, d.ok, d.hdr, alt, ok)
+		}
+	}
+}
+
+func TestHTTP3Transport(t *testing.T) {
+	tr := NewTransport()
+
+	l, err := tr.Listen("127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Unexpected listen err: %v", err)
+	}
+	defer l.Close()
+
+	go func() {
+		l.Accept(func(sock transport.Socket) {
+			defer sock.Close()
+			for {
+				var m transport.Message
+				if err := sock.Recv(&m); err != nil {
+					return
+				}
+				m.Header["Protocol"] = "http/1.1"
+				if _, ok := sock.(*altSvcSocket); !ok {
+					m.Header["Protocol"] = "http/3"
+				}
+				if err := sock.Send(&m); err != nil {
+					return
+				}
+			}
+		})
+	}()
+
+	call := func() string {
+		c, err := tr.Dial(l.Addr())
+		if err != nil {
+			t.Fatalf("Unexpected dial err: %v", err)
+		}
+		defer c.Close()
+
+		m := &transport.Message{
+			Header: map[string]string{"Content-Type": "application/json"},
+			Body:   []byte(`{"message": "Hello World"}`),
+		}
+		if err := c.Send(m); err != nil {
+			t.Fatalf("Unexpected send err: %v", err)
+		}
+		var rsp transport.Message
+		if err := c.Recv(&rsp); err != nil {
+			t.Fatalf("Unexpected recv err: %v", err)
+		}
+		if string(rsp.Body) != string(m.Body) {
+			t.Fatalf("Expected %s got %s", m.Body, rsp.Body)
+		}
+		if _, ok := rsp.Header["Alt-Svc"]; ok {
+			t.Fatal("Expected the Alt-Svc header to be removed")
+		}
+		return rsp.Header["Protocol"]
+	}
+
+	// HTTP/3 is dialed once advertised
+	if p := call(); p != "http/1.1" {
+		t.Fatalf("Expected the first call over http/1.1 got %s", p)
+	}
+	if p := call(); p != "http/3" {
+		t.Fatalf("Expected the call over http/3 got %s", p)
+	}
+
+	// streams use HTTP/1.1
+	c, err := tr.Dial(l.Addr(), transport.WithStream())
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Close()
+	if _, ok := c.(*altSvcClient); !ok {
+		t.Fatalf("Expected a http/1.1 stream got %T", c)
+	}
+
+	// fall back to HTTP/1.1 if HTTP/3 can't be dialed
+	tr.(*http3Transport).Lock()
+	tr.(*http3Transport).alts[l.Addr()] = "127.0.0.1:1"
+	tr.(*http3Transport).Unlock()
+	if p := call(); p != "http/1.1" {
+		t.Fatalf("Expected the fallback to http/1.1 got %s", p)
+	}
+}
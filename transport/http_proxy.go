@@ -9,6 +9,9 @@ import (
 	"net/http"
 	"net/http/httputil"
 	"net/url"
+	"strings"
+
+	xproxy "golang.org/x/net/proxy"
 )
 
 const (
@@ -76,34 +79,67 @@ func proxyDial(conn net.Conn, addr string, proxyURL *url.URL) (_ net.Conn, err e
 	return &pbuffer{Conn: conn, r: br}, nil
 }
 
-// Creates a new connection
-func newConn(dial func(string) (net.Conn, error)) func(string) (net.Conn, error) {
+// dialer adapts a dial func to the dialer of socks5 proxies
+type dialer func(string) (net.Conn, error)
+
+func (d dialer) Dial(network, addr string) (net.Conn, error) {
+	return d(addr)
+}
+
+// proxyURL returns the url of the proxy to dial the address through.
+// The proxy of the environment is used if the proxy isn't set.
+func proxyURL(addr, proxy string) (*url.URL, error) {
+	if len(proxy) == 0 {
+		return getURL(addr)
+	}
+	// a host is a http proxy
+	if !strings.Contains(proxy, "://") {
+		proxy = "http://" + proxy
+	}
+	return url.Parse(proxy)
+}
+
+// ProxyDial returns a dial func which connects through the proxy with the
+// url. HTTP proxies are connected through with CONNECT and socks5 proxies
+// with the SOCKS5 protocol. The proxy of the environment is used if the
+// url is blank, connections are dialed directly if there's none.
+func ProxyDial(dial func(string) (net.Conn, error), proxy string) func(string) (net.Conn, error) {
 	return func(addr string) (net.Conn, error) {
 		// get the proxy url
-		proxyURL, err := getURL(addr)
+		u, err := proxyURL(addr, proxy)
 		if err != nil {
 			return nil, err
 		}
 
-		// set to addr
-		callAddr := addr
+		// no proxy
+		if u == nil {
+			return dial(addr)
+		}
 
-		// got proxy
-		if proxyURL != nil {
-			callAddr = proxyURL.Host
+		switch u.Scheme {
+		case "socks5", "socks5h":
+			var auth *xproxy.Auth
+			if u.User != nil {
+				p, _ := u.User.Password()
+				auth = &xproxy.Auth{User: u.User.Username(), Password: p}
+			}
+			d, err := xproxy.SOCKS5("tcp", u.Host, auth, dialer(dial))
+			if err != nil {
+				return nil, err
+			}
+			return d.Dial("tcp", addr)
+		case "http", "https":
+		default:
+			return nil, fmt.Errorf("unsupported proxy scheme %s", u.Scheme)
 		}
 
-		// dial the addr
-		c, err := dial(callAddr)
+		// dial the proxy
+		c, err := dial(u.Host)
 		if err != nil {
 			return nil, err
 		}
 
-		// do proxy connect if we have proxy url
-		if proxyURL != nil {
-			c, err = proxyDial(c, addr, proxyURL)
-		}
-
-		return c, err
+		// do proxy connect
+		return proxyDial(c, addr, u)
 	}
 }
@@ -0,0 +1,201 @@
+package transport
+
+import (
+	"bufio"
+	"encoding/binary"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"testing"
+)
+
+// pipe copies the data of the connections both ways
+func pipe(a, b net.Conn) {
+	go io.Copy(a, b)
+	io.Copy(b, a)
+	a.Close()
+	b.Close()
+}
+
+// connectProxy serves a http proxy requiring the basic auth of user:pass
+func connectProxy(t *testing.T) net.Listener {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	go func() {
+		for {
+			c, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				br := bufio.NewReader(c)
+				r, err := http.ReadRequest(br)
+				if err != nil {
+					c.Close()
+					return
+				}
+				if r.Method != http.MethodConnect || r.Header.Get(proxyAuthHeader) != "Basic dXNlcjpwYXNz" {
+					c.Write([]byte("HTTP/1.1 407 Proxy Authentication Required\r\n\r\n"))
+					c.Close()
+					return
+				}
+				tc, err := net.Dial("tcp", r.URL.Host)
+				if err != nil {
+					c.Write([]byte("HTTP/1.1 502 Bad Gateway\r\n\r\n"))
+					c.Close()
+					return
+				}
+				c.Write([]byte("HTTP/1.1 200 OK\r\n\r\n"))
+				pipe(&pbuffer{Conn: c, r: br}, tc)
+			}()
+		}
+	}()
+
+	return l
+}
+
+// socks5Proxy serves a socks5 proxy requiring the auth of user:pass
+func socks5Proxy(t *testing.T) net.Listener {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	handle := func(c net.Conn) error {
+		buf := make([]byte, 256)
+		// version and methods
+		if _, err := io.ReadFull(c, buf[:2]); err != nil {
+			return err
+		}
+		if _, err := io.ReadFull(c, buf[:buf[1]]); err != nil {
+			return err
+		}
+		// username and password auth
+		c.Write([]byte{5, 2})
+		if _, err := io.ReadFull(c, buf[:2]); err != nil {
+			return err
+		}
+		user := make([]byte, buf[1])
+		io.ReadFull(c, user)
+		io.ReadFull(c, buf[:1])
+		pass := make([]byte, buf[0])
+		io.ReadFull(c, pass)
+		if string(user) != "user" || string(pass) != "pass" {
+			c.Write([]byte{1, 1})
+			return io.EOF
+		}
+		c.Write([]byte{1, 0})
+
+		// connect request of an ipv4 or domain address
+		if _, err := io.ReadFull(c, buf[:4]); err != nil {
+			return err
+		}
+		var host string
+		switch buf[3] {
+		case 1:
+			io.ReadFull(c, buf[:4])
+			host = net.IP(buf[:4]).String()
+		case 3:
+			io.ReadFull(c, buf[:1])
+			name := make([]byte, buf[0])
+			io.ReadFull(c, name)
+			host = string(name)
+		default:
+			return io.EOF
+		}
+		io.ReadFull(c, buf[:2])
+		port := binary.BigEndian.Uint16(buf[:2])
+
+		tc, err := net.Dial("tcp", net.JoinHostPort(host, strconv.Itoa(int(port))))
+		if err != nil {
+			c.Write([]byte{5, 5, 0, 1, 0, 0, 0, 0, 0, 0})
+			return err
+		}
+		c.Write([]byte{5, 0, 0, 1, 0, 0, 0, 0, 0, 0})
+		pipe(c, tc)
+		return nil
+	}
+
+	go func() {
+		for {
+			c, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				if err := handle(c); err != nil {
+					c.Close()
+				}
+			}()
+		}
+	}()
+
+	return l
+}
+
+func TestHTTPTransportProxy(t *testing.T) {
+	cl := connectProxy(t)
+	defer cl.Close()
+	sl := socks5Proxy(t)
+	defer sl.Close()
+
+	tr := NewTransport()
+
+	l, err := tr.Listen("127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Unexpected listen err: %v", err)
+	}
+	defer l.Close()
+
+	go l.Accept(func(sock Socket) {
+		for {
+			var m Message
+			if err := sock.Recv(&m); err != nil {
+				return
+			}
+			if err := sock.Send(&m); err != nil {
+				return
+			}
+		}
+	})
+
+	testData := []struct {
+		proxy string
+		err   bool
+	}{
+		{"http://user:pass@" + cl.Addr().String(), false},
+		{cl.Addr().String(), true},
+		{"socks5://user:pass@" + sl.Addr().String(), false},
+		{"socks5://user:wrong@" + sl.Addr().String(), true},
+	}
+
+	for _, d := range testData {
+		c, err := tr.Dial(l.Addr(), WithProxy(d.proxy))
+		if d.err {
+			if err == nil {
+				c.Close()
+				t.Fatalf("Expected dialing through %s to fail", d.proxy)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("Unexpected dial err through %s: %v", d.proxy, err)
+		}
+
+		if err := c.Send(&Message{Body: []byte(`ping`)}); err != nil {
+			t.Fatalf("Unexpected send err: %v", err)
+		}
+		var m Message
+		if err := c.Recv(&m); err != nil {
+			t.Fatalf("Unexpected recv err: %v", err)
+		}
+		if string(m.Body) != "ping" {
+			t.Fatalf("Expected ping got %s", m.Body)
+		}
+		c.Close()
+	}
+}
@@ -3,6 +3,7 @@ package transport
 import (
 	"bufio"
 	"bytes"
+	"context"
 	"crypto/tls"
 	"errors"
 	"io"
@@ -170,6 +171,25 @@ func (h *httpTransportClient) Recv(m *Message) error {
 	return nil
 }
 
+// Probe checks the connection wasn't closed by the other side
+func (h *httpTransportClient) Probe() error {
+	h.Lock()
+	defer h.Unlock()
+
+	// nothing is sent on an idle connection so the read times out
+	h.conn.SetReadDeadline(time.Now().Add(time.Millisecond))
+	defer h.conn.SetReadDeadline(time.Time{})
+
+	if _, err := h.buff.Peek(1); err != nil {
+		if ne, ok := err.(net.Error); ok && ne.Timeout() {
+			return nil
+		}
+		return err
+	}
+
+	return nil
+}
+
 func (h *httpTransportClient) Close() error {
 	h.once.Do(func() {
 		h.Lock()
@@ -180,6 +200,11 @@ func (h *httpTransportClient) Close() error {
 	return h.conn.Close()
 }
 
+// ConnectionState of the tls connection the socket is served over
+func (h *httpTransportSocket) ConnectionState() *tls.ConnectionState {
+	return h.r.TLS
+}
+
 func (h *httpTransportSocket) Local() string {
 	return h.local
 }
@@ -201,6 +226,9 @@ func (h *httpTransportSocket) Recv(m *Message) error {
 		// set timeout if its greater than 0
 		if h.ht.opts.Timeout > time.Duration(0) {
 			h.conn.SetDeadline(time.Now().Add(h.ht.opts.Timeout))
+		} else if h.ht.opts.IdleTimeout > time.Duration(0) {
+			// close the connection if no request is sent within the idle timeout
+			h.conn.SetReadDeadline(time.Now().Add(h.ht.opts.IdleTimeout))
 		}
 
 		var r *http.Request
@@ -449,7 +477,7 @@ func (h *httpTransportListener) Accept(fn func(Socket)) error {
 		}
 
 		// execute the socket
-		fn(sock)
+		fn(CompressSocket(sock, h.ht.opts))
 	})
 
 	// get optional handlers
@@ -464,12 +492,15 @@ func (h *httpTransportListener) Accept(fn func(Socket)) error {
 
 	// default http2 server
 	srv := &http.Server{
-		Handler: mux,
+		Handler:     mux,
+		IdleTimeout: h.ht.opts.IdleTimeout,
 	}
 
 	// insecure connection use h2c
 	if !(h.ht.opts.Secure || h.ht.opts.TLSConfig != nil) {
-		srv.Handler = h2c.NewHandler(mux, &http2.Server{})
+		srv.Handler = h2c.NewHandler(mux, &http2.Server{
+			IdleTimeout: h.ht.opts.IdleTimeout,
+		})
 	}
 
 	// begin serving
@@ -488,6 +519,16 @@ func (h *httpTransport) Dial(addr string, opts ...DialOption) (Client, error) {
 	var conn net.Conn
 	var err error
 
+	dialer := &net.Dialer{
+		Timeout:   dopts.Timeout,
+		KeepAlive: h.opts.KeepAlive,
+	}
+
+	proxy := h.opts.Proxy
+	if len(dopts.Proxy) > 0 {
+		proxy = dopts.Proxy
+	}
+
 	// TODO: support dial option here rather than using internal config
 	if h.opts.Secure || h.opts.TLSConfig != nil {
 		config := h.opts.TLSConfig
@@ -497,20 +538,23 @@ func (h *httpTransport) Dial(addr string, opts ...DialOption) (Client, error) {
 			}
 		}
 		config.NextProtos = []string{"http/1.1"}
-		conn, err = newConn(func(addr string) (net.Conn, error) {
-			return tls.DialWithDialer(&net.Dialer{Timeout: dopts.Timeout}, "tcp", addr, config)
-		})(addr)
+		conn, err = ProxyDial(func(addr string) (net.Conn, error) {
+			return dialer.Dial("tcp", addr)
+		}, proxy)(addr)
+		if err == nil {
+			conn, err = handshake(conn, addr, config, dopts.Timeout)
+		}
 	} else {
-		conn, err = newConn(func(addr string) (net.Conn, error) {
-			return net.DialTimeout("tcp", addr, dopts.Timeout)
-		})(addr)
+		conn, err = ProxyDial(func(addr string) (net.Conn, error) {
+			return dialer.Dial("tcp", addr)
+		}, proxy)(addr)
 	}
 
 	if err != nil {
 		return nil, err
 	}
 
-	return &httpTransportClient{
+	return CompressSocket(&httpTransportClient{
 		ht:       h,
 		addr:     addr,
 		conn:     conn,
@@ -519,7 +563,37 @@ func (h *httpTransport) Dial(addr string, opts ...DialOption) (Client, error) {
 		r:        make(chan *http.Request, 1),
 		local:    conn.LocalAddr().String(),
 		remote:   conn.RemoteAddr().String(),
-	}, nil
+	}, h.opts), nil
+}
+
+// handshake secures the connection to the address, which may
+// be tunneled through a proxy, with a tls client handshake
+func handshake(conn net.Conn, addr string, config *tls.Config, timeout time.Duration) (net.Conn, error) {
+	if len(config.ServerName) == 0 {
+		host, _, err := net.SplitHostPort(addr)
+		if err != nil {
+			host = addr
+		}
+		config = config.Clone()
+		config.ServerName = host
+	}
+
+	tc := tls.Client(conn, config)
+	if timeout > 0 {
+		tc.SetDeadline(time.Now().Add(timeout))
+		defer tc.SetDeadline(time.Time{})
+	}
+	if err := tc.Handshake(); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return tc, nil
+}
+
+// listen on the tcp address with the keepalive period of accepted connections
+func listen(addr string, keepAlive time.Duration) (net.Listener, error) {
+	lc := &net.ListenConfig{KeepAlive: keepAlive}
+	return lc.Listen(context.Background(), "tcp", addr)
 }
 
 func (h *httpTransport) Listen(addr string, opts ...ListenOption) (Listener, error) {
@@ -528,6 +602,16 @@ func (h *httpTransport) Listen(addr string, opts ...ListenOption) (Listener, err
 		o(&options)
 	}
 
+	// serve on the listener provided
+	if h.opts.Context != nil {
+		if l, ok := h.opts.Context.Value("http_listener").(net.Listener); ok && l != nil {
+			return &httpTransportListener{
+				ht:       h,
+				listener: l,
+			}, nil
+		}
+	}
+
 	var l net.Listener
 	var err error
 
@@ -555,13 +639,17 @@ func (h *httpTransport) Listen(addr string, opts ...ListenOption) (Listener, err
 				}
 				config = &tls.Config{Certificates: []tls.Certificate{cert}}
 			}
-			return tls.Listen("tcp", addr, config)
+			l, err := listen(addr, h.opts.KeepAlive)
+			if err != nil {
+				return nil, err
+			}
+			return tls.NewListener(l, config), nil
 		}
 
 		l, err = mnet.Listen(addr, fn)
 	} else {
 		fn := func(addr string) (net.Listener, error) {
-			return net.Listen("tcp", addr)
+			return listen(addr, h.opts.KeepAlive)
 		}
 
 		l, err = mnet.Listen(addr, fn)
@@ -244,3 +244,66 @@ func TestHTTPTransportTimeout(t *testing.T) {
 
 	<-done
 }
+
+func TestHTTPTransportIdleTimeout(t *testing.T) {
+	tr := NewTransport(IdleTimeout(time.Millisecond*100), KeepAlive(time.Second))
+
+	l, err := tr.Listen("127.0.0.1:0")
+	if err != nil {
+		t.Errorf("Unexpected listen err: %v", err)
+	}
+	defer l.Close()
+
+	done := make(chan bool)
+
+	fn := func(sock Socket) {
+		defer func() {
+			sock.Close()
+			close(done)
+		}()
+
+		for {
+			var m Message
+			if err := sock.Recv(&m); err != nil {
+				return
+			}
+			if err := sock.Send(&m); err != nil {
+				return
+			}
+		}
+	}
+
+	go l.Accept(fn)
+
+	c, err := tr.Dial(l.Addr())
+	if err != nil {
+		t.Fatalf("Unexpected dial err: %v", err)
+	}
+	defer c.Close()
+
+	m := Message{
+		Header: map[string]string{
+			"Content-Type": "application/json",
+		},
+		Body: []byte(`{"message": "Hello World"}`),
+	}
+
+	// active connections are kept open
+	for i := 0; i < 3; i++ {
+		if err := c.Send(&m); err != nil {
+			t.Fatalf("Unexpected send err: %v", err)
+		}
+		var rsp Message
+		if err := c.Recv(&rsp); err != nil {
+			t.Fatalf("Unexpected recv err: %v", err)
+		}
+		time.Sleep(time.Millisecond * 50)
+	}
+
+	// idle connections are closed
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Expected the idle connection to be closed")
+	}
+}
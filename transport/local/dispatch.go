@@ -0,0 +1,279 @@
+package local
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/micro/go-micro/v2/client"
+	"github.com/micro/go-micro/v2/codec"
+	merrors "github.com/micro/go-micro/v2/errors"
+	"github.com/micro/go-micro/v2/metadata"
+	"github.com/micro/go-micro/v2/server"
+)
+
+var (
+	dmtx sync.RWMutex
+	// the started servers of the process by service name
+	servers = map[string]*localServer{}
+
+	typeOfContext = reflect.TypeOf((*context.Context)(nil)).Elem()
+	typeOfError   = reflect.TypeOf((*error)(nil)).Elem()
+)
+
+type localServer struct {
+	server.Server
+
+	sync.RWMutex
+	handlers map[string]server.Handler
+	// the calls in flight waited for on stop
+	wg       sync.WaitGroup
+	draining bool
+}
+
+type dispatchClient struct {
+	client.Client
+}
+
+type localRequest struct {
+	service     string
+	endpoint    string
+	contentType string
+	header      map[string]string
+	body        interface{}
+}
+
+func (r *localRequest) Service() string {
+	return r.service
+}
+
+func (r *localRequest) Method() string {
+	return r.endpoint
+}
+
+func (r *localRequest) Endpoint() string {
+	return r.endpoint
+}
+
+func (r *localRequest) ContentType() string {
+	return r.contentType
+}
+
+func (r *localRequest) Header() map[string]string {
+	return r.header
+}
+
+func (r *localRequest) Body() interface{} {
+	return r.body
+}
+
+// Read fails since the request was never encoded
+func (r *localRequest) Read() ([]byte, error) {
+	return nil, errors.New("request dispatched in process")
+}
+
+func (r *localRequest) Codec() codec.Reader {
+	return nil
+}
+
+func (r *localRequest) Stream() bool {
+	return false
+}
+
+func (s *localServer) Handle(h server.Handler) error {
+	if err := s.Server.Handle(h); err != nil {
+		return err
+	}
+	s.Lock()
+	s.handlers[h.Name()] = h
+	s.Unlock()
+	return nil
+}
+
+func (s *localServer) Start() error {
+	if err := s.Server.Start(); err != nil {
+		return err
+	}
+	dmtx.Lock()
+	servers[s.Options().Name] = s
+	dmtx.Unlock()
+	return nil
+}
+
+func (s *localServer) Stop() error {
+	dmtx.Lock()
+	if servers[s.Options().Name] == s {
+		delete(servers, s.Options().Name)
+	}
+	dmtx.Unlock()
+
+	s.drain()
+	return s.Server.Stop()
+}
+
+// add a call in flight unless the server is draining
+func (s *localServer) add() bool {
+	s.Lock()
+	defer s.Unlock()
+	if s.draining {
+		return false
+	}
+	s.wg.Add(1)
+	return true
+}
+
+// drain waits for the calls in flight up to the drain timeout
+func (s *localServer) drain() {
+	s.Lock()
+	s.draining = true
+	s.Unlock()
+
+	done := make(chan bool)
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+
+	var after <-chan time.Time
+	if timeout := s.Options().DrainTimeout; timeout > 0 {
+		t := time.NewTimer(timeout)
+		defer t.Stop()
+		after = t.C
+	}
+
+	select {
+	case <-done:
+	case <-after:
+	}
+
+	s.Lock()
+	s.draining = false
+	s.Unlock()
+}
+
+// handler returns the handler func of the endpoint if it takes the request
+// and response types of the call
+func (s *localServer) handler(endpoint string, req, rsp interface{}) (server.HandlerFunc, bool) {
+	parts := strings.SplitN(endpoint, ".", 2)
+	if len(parts) != 2 || req == nil || rsp == nil {
+		return nil, false
+	}
+
+	s.RLock()
+	h, ok := s.handlers[parts[0]]
+	s.RUnlock()
+	if !ok {
+		return nil, false
+	}
+
+	m := reflect.ValueOf(h.Handler()).MethodByName(parts[1])
+	if !m.IsValid() {
+		return nil, false
+	}
+	mt := m.Type()
+	if mt.NumIn() != 3 || mt.NumOut() != 1 || mt.In(0) != typeOfContext || mt.Out(0) != typeOfError {
+		return nil, false
+	}
+	if reflect.TypeOf(req) != mt.In(1) || reflect.TypeOf(rsp) != mt.In(2) {
+		return nil, false
+	}
+
+	fn := func(ctx context.Context, req server.Request, rsp interface{}) error {
+		out := m.Call([]reflect.Value{reflect.ValueOf(ctx), reflect.ValueOf(req.Body()), reflect.ValueOf(rsp)})
+		if err := out[0].Interface(); err != nil {
+			return err.(error)
+		}
+		return nil
+	}
+
+	// enforce the execution timeout and wrap the handler as the server does
+	opts := s.Options()
+	fn = server.TimeoutHandler(opts.Timeout(endpoint))(fn)
+
+	wrappers := opts.HdlrWrappers
+	for i := len(wrappers); i > 0; i-- {
+		fn = wrappers[i-1](fn)
+	}
+
+	return fn, true
+}
+
+// Call the handler of a service of the process directly if it takes the
+// request and response types of the call
+func (c *dispatchClient) Call(ctx context.Context, req client.Request, rsp interface{}, opts ...client.CallOption) error {
+	dmtx.RLock()
+	s, ok := servers[req.Service()]
+	dmtx.RUnlock()
+	if !ok || req.Stream() {
+		return c.Client.Call(ctx, req, rsp, opts...)
+	}
+
+	fn, ok := s.handler(req.Endpoint(), req.Body(), rsp)
+	if !ok {
+		return c.Client.Call(ctx, req, rsp, opts...)
+	}
+
+	// the server is called by the client once it's stopping
+	if !s.add() {
+		return c.Client.Call(ctx, req, rsp, opts...)
+	}
+	defer s.wg.Done()
+
+	options := c.Options().CallOptions
+	for _, o := range opts {
+		o(&options)
+	}
+	if options.RequestTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, options.RequestTimeout)
+		defer cancel()
+	}
+
+	// the handler gets a copy of the metadata as if it was sent
+	md, _ := metadata.FromContext(ctx)
+	md = metadata.Copy(md)
+	ctx = metadata.NewContext(ctx, md)
+
+	release, err := s.Options().Limiter.Acquire(ctx, req.Endpoint(), time.Now())
+	if err != nil {
+		return merrors.FromError(err)
+	}
+	defer release()
+
+	err = fn(ctx, &localRequest{
+		service:     req.Service(),
+		endpoint:    req.Endpoint(),
+		contentType: req.ContentType(),
+		header:      md,
+		body:        req.Body(),
+	}, rsp)
+	if err != nil {
+		// the errors are returned as if they were received
+		return merrors.FromError(err)
+	}
+	return nil
+}
+
+// NewServer returns a server whose handlers are called directly by the
+// clients of the process wrapped with NewClientWrapper once it's started
+func NewServer(s server.Server) server.Server {
+	return &localServer{
+		Server:   s,
+		handlers: make(map[string]server.Handler),
+	}
+}
+
+// NewClientWrapper calls the handlers of the servers of the process returned
+// by NewServer directly, without serializing the request or response. The
+// calls are limited, timed out, wrapped and drained on stop as the server does
+// with its requests. The handlers mustn't modify the request. The calls of
+// other services, of streams or whose types don't match the handler are made
+// by the client.
+func NewClientWrapper() client.Wrapper {
+	return func(c client.Client) client.Client {
+		return &dispatchClient{c}
+	}
+}
@@ -0,0 +1,339 @@
+// Package local is a transport which short-circuits the connections between
+// services of the same process. Messages are handed to the services in the
+// process without any networking and the connections of other services are
+// made with a network transport. It's used to compose services in a single
+// binary without loopback networking.
+//
+// The messages are still encoded by the client and server. The calls can
+// skip the serialization too by wrapping the servers with NewServer and the
+// clients with NewClientWrapper, which call the handlers directly.
+package local
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/micro/go-micro/v2/transport"
+	maddr "github.com/micro/go-micro/v2/util/addr"
+)
+
+var (
+	mtx sync.RWMutex
+	// the listeners of the process by address
+	listeners = map[string]*localListener{}
+)
+
+type localTransport struct {
+	opts transport.Options
+	// network transport of other processes
+	net    transport.Transport
+	noCopy bool
+}
+
+type localListener struct {
+	transport.Listener
+	t    *localTransport
+	key  string
+	conn chan *localSocket
+
+	once sync.Once
+	exit chan bool
+}
+
+// conn is shared by both sides of a connection
+type conn struct {
+	once sync.Once
+	exit chan bool
+}
+
+type localSocket struct {
+	*conn
+	send chan *transport.Message
+	recv chan *transport.Message
+	// listener exit
+	lexit chan bool
+
+	local  string
+	remote string
+
+	timeout time.Duration
+	noCopy  bool
+}
+
+type localClient struct {
+	*localSocket
+	opts transport.DialOptions
+}
+
+// key of the listener address. Listeners on every interface
+// are keyed by their port and dialed by any local address.
+func key(addr string) string {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return addr
+	}
+	if ip := net.ParseIP(host); len(host) == 0 || (ip != nil && ip.IsUnspecified()) {
+		return ":" + port
+	}
+	return addr
+}
+
+// lookup the listener of the process dialed with the address
+func lookup(addr string) (*localListener, bool) {
+	mtx.RLock()
+	defer mtx.RUnlock()
+
+	if l, ok := listeners[addr]; ok {
+		return l, true
+	}
+
+	_, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, false
+	}
+
+	l, ok := listeners[":"+port]
+	if !ok || !maddr.IsLocal(addr) {
+		return nil, false
+	}
+	return l, true
+}
+
+// copyMessage returns a copy of the message not sharing the header or body
+func copyMessage(m *transport.Message) *transport.Message {
+	cm := &transport.Message{
+		Header: make(map[string]string, len(m.Header)),
+		Body:   make([]byte, len(m.Body)),
+	}
+	for k, v := range m.Header {
+		cm.Header[k] = v
+	}
+	copy(cm.Body, m.Body)
+	return cm
+}
+
+func (s *localSocket) Local() string {
+	return s.local
+}
+
+func (s *localSocket) Remote() string {
+	return s.remote
+}
+
+func (s *localSocket) Recv(m *transport.Message) error {
+	if m == nil {
+		return errors.New("message passed in is nil")
+	}
+
+	var timeout <-chan time.Time
+	if s.timeout > 0 {
+		t := time.NewTimer(s.timeout)
+		defer t.Stop()
+		timeout = t.C
+	}
+
+	select {
+	case <-timeout:
+		return context.DeadlineExceeded
+	case <-s.exit:
+		return errors.New("connection closed")
+	case <-s.lexit:
+		return errors.New("server connection closed")
+	case cm := <-s.recv:
+		*m = *cm
+	}
+	return nil
+}
+
+func (s *localSocket) Send(m *transport.Message) error {
+	if !s.noCopy {
+		m = copyMessage(m)
+	}
+
+	var timeout <-chan time.Time
+	if s.timeout > 0 {
+		t := time.NewTimer(s.timeout)
+		defer t.Stop()
+		timeout = t.C
+	}
+
+	select {
+	case <-timeout:
+		return context.DeadlineExceeded
+	case <-s.exit:
+		return errors.New("connection closed")
+	case <-s.lexit:
+		return errors.New("server connection closed")
+	case s.send <- m:
+	}
+	return nil
+}
+
+func (s *localSocket) Close() error {
+	s.once.Do(func() {
+		close(s.exit)
+	})
+	return nil
+}
+
+// Probe checks the connection wasn't closed
+func (c *localClient) Probe() error {
+	select {
+	case <-c.exit:
+		return errors.New("connection closed")
+	case <-c.lexit:
+		return errors.New("server connection closed")
+	default:
+		return nil
+	}
+}
+
+func (l *localListener) Accept(fn func(transport.Socket)) error {
+	errCh := make(chan error, 1)
+
+	// connections of other processes
+	go func() {
+		errCh <- l.Listener.Accept(fn)
+	}()
+
+	for {
+		select {
+		case <-l.exit:
+			return nil
+		case err := <-errCh:
+			return err
+		case c := <-l.conn:
+			go fn(&localSocket{
+				conn:    c.conn,
+				send:    c.recv,
+				recv:    c.send,
+				lexit:   l.exit,
+				local:   c.remote,
+				remote:  c.local,
+				timeout: l.t.opts.Timeout,
+				noCopy:  l.t.noCopy,
+			})
+		}
+	}
+}
+
+func (l *localListener) Close() error {
+	l.once.Do(func() {
+		mtx.Lock()
+		if listeners[l.key] == l {
+			delete(listeners, l.key)
+		}
+		mtx.Unlock()
+		close(l.exit)
+	})
+	return l.Listener.Close()
+}
+
+func (t *localTransport) Dial(addr string, opts ...transport.DialOption) (transport.Client, error) {
+	l, ok := lookup(addr)
+	if !ok {
+		return t.net.Dial(addr, opts...)
+	}
+
+	var options transport.DialOptions
+	for _, o := range opts {
+		o(&options)
+	}
+
+	c := &localClient{
+		&localSocket{
+			conn:    &conn{exit: make(chan bool)},
+			send:    make(chan *transport.Message),
+			recv:    make(chan *transport.Message),
+			lexit:   l.exit,
+			local:   addr,
+			remote:  l.Addr(),
+			timeout: t.opts.Timeout,
+			noCopy:  t.noCopy,
+		},
+		options,
+	}
+
+	// pseudo connect
+	select {
+	case <-l.exit:
+		return nil, errors.New("connection error")
+	case l.conn <- c.localSocket:
+	}
+
+	return c, nil
+}
+
+func (t *localTransport) Listen(addr string, opts ...transport.ListenOption) (transport.Listener, error) {
+	nl, err := t.net.Listen(addr, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	l := &localListener{
+		Listener: nl,
+		t:        t,
+		key:      key(nl.Addr()),
+		conn:     make(chan *localSocket),
+		exit:     make(chan bool),
+	}
+
+	mtx.Lock()
+	listeners[l.key] = l
+	mtx.Unlock()
+
+	return l, nil
+}
+
+func (t *localTransport) Init(opts ...transport.Option) error {
+	for _, o := range opts {
+		o(&t.opts)
+	}
+	if nt, ok := t.opts.Context.Value(transportKey{}).(transport.Transport); ok {
+		t.net = nt
+	} else if err := t.net.Init(opts...); err != nil {
+		return err
+	}
+	if noCopy, ok := t.opts.Context.Value(noCopyKey{}).(bool); ok {
+		t.noCopy = noCopy
+	}
+	return nil
+}
+
+func (t *localTransport) Options() transport.Options {
+	return t.opts
+}
+
+func (t *localTransport) String() string {
+	return "local"
+}
+
+// NewTransport returns a transport short-circuiting the connections
+// between services of the process
+func NewTransport(opts ...transport.Option) transport.Transport {
+	options := transport.Options{
+		Context: context.Background(),
+	}
+	for _, o := range opts {
+		o(&options)
+	}
+
+	t := &localTransport{
+		opts: options,
+	}
+
+	if nt, ok := options.Context.Value(transportKey{}).(transport.Transport); ok {
+		t.net = nt
+	} else {
+		t.net = transport.NewTransport(opts...)
+	}
+
+	if noCopy, ok := options.Context.Value(noCopyKey{}).(bool); ok {
+		t.noCopy = noCopy
+	}
+
+	return t
+}
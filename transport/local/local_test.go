@@ -0,0 +1,261 @@
+package local
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/micro/go-micro/v2/client"
+	"github.com/micro/go-micro/v2/errors"
+	"github.com/micro/go-micro/v2/metadata"
+	"github.com/micro/go-micro/v2/registry/memory"
+	"github.com/micro/go-micro/v2/server"
+	"github.com/micro/go-micro/v2/transport"
+)
+
+func testEcho(t *testing.T, c transport.Client) {
+	m := &transport.Message{
+		Header: map[string]string{"Micro-Id": "1"},
+		Body:   []byte(`ping`),
+	}
+	if err := c.Send(m); err != nil {
+		t.Fatalf("Unexpected send err: %v", err)
+	}
+	var rsp transport.Message
+	if err := c.Recv(&rsp); err != nil {
+		t.Fatalf("Unexpected recv err: %v", err)
+	}
+	if string(rsp.Body) != "ping" || rsp.Header["Micro-Id"] != "1" {
+		t.Fatalf("Unexpected message %+v", rsp)
+	}
+}
+
+func TestLocalTransport(t *testing.T) {
+	tr := NewTransport()
+
+	l, err := tr.Listen(":0")
+	if err != nil {
+		t.Fatalf("Unexpected listen err: %v", err)
+	}
+
+	go l.Accept(func(sock transport.Socket) {
+		for {
+			var m transport.Message
+			if err := sock.Recv(&m); err != nil {
+				return
+			}
+			// modifying the message doesn't modify the client's
+			m.Header["Micro-Server"] = "true"
+			if err := sock.Send(&m); err != nil {
+				return
+			}
+		}
+	})
+
+	_, port, _ := net.SplitHostPort(l.Addr())
+	addr := net.JoinHostPort("127.0.0.1", port)
+
+	c, err := tr.Dial(addr)
+	if err != nil {
+		t.Fatalf("Unexpected dial err: %v", err)
+	}
+	if _, ok := c.(*localClient); !ok {
+		t.Fatalf("Expected a local connection got %T", c)
+	}
+	testEcho(t, c)
+	c.Close()
+
+	// services of other processes are dialed through the network
+	nc, err := NewTransport().(*localTransport).net.Dial(addr)
+	if err != nil {
+		t.Fatalf("Unexpected network dial err: %v", err)
+	}
+	testEcho(t, nc)
+	nc.Close()
+
+	l.Close()
+
+	if _, ok := lookup(addr); ok {
+		t.Fatal("Expected the closed listener to be removed")
+	}
+	if _, err := tr.Dial(addr); err == nil {
+		t.Fatal("Expected dialing the closed listener to fail")
+	}
+}
+
+type HelloRequest struct {
+	Name string
+}
+
+type HelloResponse struct {
+	Msg string
+}
+
+type Greeter struct {
+	req chan *HelloRequest
+}
+
+func (h *Greeter) Hello(ctx context.Context, req *HelloRequest, rsp *HelloResponse) error {
+	h.req <- req
+	if len(req.Name) == 0 {
+		return errors.BadRequest("go.micro.test", "name required")
+	}
+	from, _ := metadata.Get(ctx, "From")
+	rsp.Msg = "hello " + req.Name + " from " + from
+	return nil
+}
+
+type Waiter struct {
+	started chan bool
+	release chan bool
+}
+
+func (w *Waiter) Wait(ctx context.Context, req *HelloRequest, rsp *HelloResponse) error {
+	w.started <- true
+	select {
+	case <-w.release:
+	case <-ctx.Done():
+	}
+	return nil
+}
+
+func TestDispatchLimits(t *testing.T) {
+	r := memory.NewRegistry()
+
+	s := NewServer(server.NewServer(
+		server.Name("go.micro.limits"),
+		server.Address("127.0.0.1:0"),
+		server.Registry(r),
+		server.Transport(NewTransport()),
+		server.Limits("Waiter.Wait", server.Limit{MaxInFlight: 1}),
+		server.EndpointTimeout("Waiter.Wait", 200*time.Millisecond),
+	))
+
+	w := &Waiter{started: make(chan bool, 10), release: make(chan bool)}
+	if err := s.Handle(s.NewHandler(w)); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Start(); err != nil {
+		t.Fatal(err)
+	}
+
+	c := NewClientWrapper()(client.NewClient(
+		client.Registry(r),
+		client.Transport(NewTransport()),
+	))
+
+	call := func() error {
+		var rsp HelloResponse
+		return c.Call(context.Background(), c.NewRequest("go.micro.limits", "Waiter.Wait", &HelloRequest{}), &rsp)
+	}
+	code := func(err error) int32 {
+		if verr, ok := err.(*errors.Error); ok {
+			return verr.Code
+		}
+		return 0
+	}
+
+	// the calls over the limit are rejected and the calls are timed out
+	done := make(chan error, 1)
+	go func() { done <- call() }()
+	<-w.started
+
+	if err := call(); code(err) != 429 {
+		t.Fatalf("Expected the call over the limit to be rejected got %v", err)
+	}
+	if err := <-done; code(err) != 504 {
+		t.Fatalf("Expected the call to time out got %v", err)
+	}
+
+	// the calls in flight are drained on stop
+	go func() { done <- call() }()
+	<-w.started
+
+	stopped := make(chan bool)
+	go func() {
+		s.Stop()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+		t.Fatal("Expected stop to wait for the call in flight")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(w.release)
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	<-stopped
+}
+
+func TestDispatch(t *testing.T) {
+	r := memory.NewRegistry()
+
+	var wrapped int
+	s := NewServer(server.NewServer(
+		server.Name("go.micro.test"),
+		server.Address("127.0.0.1:0"),
+		server.Registry(r),
+		server.Transport(NewTransport()),
+		server.WrapHandler(func(fn server.HandlerFunc) server.HandlerFunc {
+			return func(ctx context.Context, req server.Request, rsp interface{}) error {
+				wrapped++
+				return fn(ctx, req, rsp)
+			}
+		}),
+	))
+
+	h := &Greeter{req: make(chan *HelloRequest, 1)}
+	if err := s.Handle(s.NewHandler(h)); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Stop()
+
+	c := NewClientWrapper()(client.NewClient(
+		client.Registry(r),
+		client.Transport(NewTransport()),
+	))
+
+	// the request is handed to the handler without serialization
+	req := &HelloRequest{Name: "john"}
+	var rsp HelloResponse
+	ctx := metadata.Set(context.Background(), "From", "test")
+	if err := c.Call(ctx, c.NewRequest("go.micro.test", "Greeter.Hello", req), &rsp); err != nil {
+		t.Fatal(err)
+	}
+	if got := <-h.req; got != req {
+		t.Fatal("Expected the request to be dispatched as is")
+	}
+	if rsp.Msg != "hello john from test" {
+		t.Fatalf("Unexpected response %s", rsp.Msg)
+	}
+	if wrapped != 1 {
+		t.Fatalf("Expected the handler to be wrapped once got %d", wrapped)
+	}
+
+	// errors are returned as if they were received
+	err := c.Call(ctx, c.NewRequest("go.micro.test", "Greeter.Hello", &HelloRequest{}), &rsp)
+	<-h.req
+	if verr, ok := err.(*errors.Error); !ok || verr.Code != 400 {
+		t.Fatalf("Expected a bad request error got %v", err)
+	}
+
+	// the calls of other services are made by the client
+	if err := c.Call(ctx, c.NewRequest("go.micro.other", "Greeter.Hello", req), &rsp); err == nil {
+		t.Fatal("Expected the unknown service call to fail")
+	}
+
+	s.Stop()
+	dmtx.RLock()
+	_, ok := servers["go.micro.test"]
+	dmtx.RUnlock()
+	if ok {
+		t.Fatal("Expected the stopped server to be removed")
+	}
+}
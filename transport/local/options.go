@@ -0,0 +1,31 @@
+package local
+
+import (
+	"context"
+
+	"github.com/micro/go-micro/v2/transport"
+)
+
+type transportKey struct{}
+type noCopyKey struct{}
+
+func setTransportOption(k, v interface{}) transport.Option {
+	return func(o *transport.Options) {
+		if o.Context == nil {
+			o.Context = context.Background()
+		}
+		o.Context = context.WithValue(o.Context, k, v)
+	}
+}
+
+// Transport used to listen for and dial services outside
+// of the process, defaults to the http transport
+func Transport(t transport.Transport) transport.Option {
+	return setTransportOption(transportKey{}, t)
+}
+
+// NoCopy hands messages to services in the process as is. By default
+// messages are copied so neither side can modify the other's message.
+func NoCopy() transport.Option {
+	return setTransportOption(noCopyKey{}, true)
+}
@@ -108,6 +108,18 @@ func (ms *memorySocket) Send(m *transport.Message) error {
 	return nil
 }
 
+// Probe checks the connection wasn't closed
+func (mc *memoryClient) Probe() error {
+	select {
+	case <-mc.exit:
+		return errors.New("connection closed")
+	case <-mc.lexit:
+		return errors.New("server connection closed")
+	default:
+		return nil
+	}
+}
+
 func (ms *memorySocket) Close() error {
 	ms.Lock()
 	defer ms.Unlock()
@@ -142,7 +154,7 @@ func (m *memoryListener) Accept(fn func(transport.Socket)) error {
 		case <-m.exit:
 			return nil
 		case c := <-m.conn:
-			go fn(&memorySocket{
+			go fn(transport.CompressSocket(&memorySocket{
 				lexit:   c.lexit,
 				exit:    c.exit,
 				send:    c.recv,
@@ -151,7 +163,7 @@ func (m *memoryListener) Accept(fn func(transport.Socket)) error {
 				remote:  c.Local(),
 				timeout: m.topts.Timeout,
 				ctx:     m.topts.Context,
-			})
+			}, m.topts))
 		}
 	}
 }
@@ -191,7 +203,7 @@ func (m *memoryTransport) Dial(addr string, opts ...transport.DialOption) (trans
 	case listener.conn <- client.memorySocket:
 	}
 
-	return client, nil
+	return transport.CompressSocket(client, m.opts), nil
 }
 
 func (m *memoryTransport) Listen(addr string, opts ...transport.ListenOption) (transport.Listener, error) {
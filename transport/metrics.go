@@ -0,0 +1,168 @@
+package transport
+
+import (
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/micro/go-micro/v2/metrics"
+)
+
+const (
+	// MetricBytesSent is the count of bytes sent. Only the message bodies are
+	// counted since transports add and strip headers of their own.
+	MetricBytesSent = "micro_transport_sent_bytes"
+	// MetricBytesReceived is the count of bytes received
+	MetricBytesReceived = "micro_transport_received_bytes"
+	// MetricConnections is the gauge of open connections
+	MetricConnections = "micro_transport_connections"
+	// MetricDialDuration is the timing of dials including the tls handshake
+	MetricDialDuration = "micro_transport_dial_duration"
+	// MetricDialErrors is the count of failed dials and handshakes
+	MetricDialErrors = "micro_transport_dial_errors"
+)
+
+type metricsTransport struct {
+	Transport
+	r metrics.Reporter
+
+	sync.Mutex
+	// open connections by tags
+	conns map[string]*int64
+}
+
+type metricsListener struct {
+	Listener
+	t *metricsTransport
+}
+
+type metricsSocket struct {
+	Socket
+	t    *metricsTransport
+	tags metrics.Tags
+	once sync.Once
+}
+
+// connections adds the delta to the open connections with the tags
+func (t *metricsTransport) connections(tags metrics.Tags, delta int64) {
+	key := tags["direction"] + ":" + tags["remote"]
+
+	t.Lock()
+	n, ok := t.conns[key]
+	if !ok {
+		n = new(int64)
+		t.conns[key] = n
+	}
+	t.Unlock()
+
+	t.r.Gauge(MetricConnections, float64(atomic.AddInt64(n, delta)), tags)
+}
+
+func (s *metricsSocket) Send(m *Message) error {
+	if err := s.Socket.Send(m); err != nil {
+		return err
+	}
+	s.t.r.Count(MetricBytesSent, int64(len(m.Body)), s.tags)
+	return nil
+}
+
+func (s *metricsSocket) Recv(m *Message) error {
+	if err := s.Socket.Recv(m); err != nil {
+		return err
+	}
+	s.t.r.Count(MetricBytesReceived, int64(len(m.Body)), s.tags)
+	return nil
+}
+
+func (s *metricsSocket) Close() error {
+	s.once.Do(func() {
+		s.t.connections(s.tags, -1)
+	})
+	return s.Socket.Close()
+}
+
+// Probe the connection of the socket if it's a prober
+func (s *metricsSocket) Probe() error {
+	if p, ok := s.Socket.(Prober); ok {
+		return p.Probe()
+	}
+	return nil
+}
+
+func (t *metricsTransport) newSocket(s Socket, tags metrics.Tags) *metricsSocket {
+	t.connections(tags, 1)
+	return &metricsSocket{
+		Socket: s,
+		t:      t,
+		tags:   tags,
+	}
+}
+
+func (t *metricsTransport) Dial(addr string, opts ...DialOption) (Client, error) {
+	var options DialOptions
+	for _, o := range opts {
+		o(&options)
+	}
+
+	// label by the service dialed if known
+	remote := options.Service
+	if len(remote) == 0 {
+		remote = addr
+	}
+	tags := metrics.Tags{
+		"transport": t.String(),
+		"direction": "outbound",
+		"remote":    remote,
+	}
+
+	start := time.Now()
+	c, err := t.Transport.Dial(addr, opts...)
+	if err != nil {
+		t.r.Count(MetricDialErrors, 1, tags)
+		return nil, err
+	}
+	t.r.Timing(MetricDialDuration, time.Since(start), tags)
+
+	return t.newSocket(c, tags), nil
+}
+
+func (t *metricsTransport) Listen(addr string, opts ...ListenOption) (Listener, error) {
+	l, err := t.Transport.Listen(addr, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &metricsListener{Listener: l, t: t}, nil
+}
+
+func (l *metricsListener) Accept(fn func(Socket)) error {
+	return l.Listener.Accept(func(sock Socket) {
+		// the service of clients isn't known and
+		// their port would make every connection unique
+		remote := sock.Remote()
+		if host, _, err := net.SplitHostPort(remote); err == nil {
+			remote = host
+		}
+
+		s := l.t.newSocket(sock, metrics.Tags{
+			"transport": l.t.String(),
+			"direction": "inbound",
+			"remote":    remote,
+		})
+		defer s.once.Do(func() {
+			l.t.connections(s.tags, -1)
+		})
+		fn(s)
+	})
+}
+
+// Instrument returns a transport reporting the bytes sent and received, the
+// open connections, the latency of dials and the failed dials. Metrics of
+// dialed connections are labeled by the service dialed.
+func Instrument(t Transport, r metrics.Reporter) Transport {
+	return &metricsTransport{
+		Transport: t,
+		r:         r,
+		conns:     make(map[string]*int64),
+	}
+}
@@ -0,0 +1,115 @@
+package transport
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/micro/go-micro/v2/metrics"
+)
+
+type testReporter struct {
+	sync.Mutex
+	counts map[string]int64
+	gauges map[string]float64
+	timing map[string]int
+}
+
+func (r *testReporter) Count(id string, value int64, tags metrics.Tags) error {
+	r.Lock()
+	r.counts[id+":"+tags["direction"]] += value
+	r.Unlock()
+	return nil
+}
+
+func (r *testReporter) Gauge(id string, value float64, tags metrics.Tags) error {
+	r.Lock()
+	r.gauges[id+":"+tags["direction"]] = value
+	r.Unlock()
+	return nil
+}
+
+func (r *testReporter) Timing(id string, value time.Duration, tags metrics.Tags) error {
+	r.Lock()
+	r.timing[id+":"+tags["remote"]]++
+	r.Unlock()
+	return nil
+}
+
+func (r *testReporter) String() string {
+	return "test"
+}
+
+func TestInstrument(t *testing.T) {
+	r := &testReporter{
+		counts: make(map[string]int64),
+		gauges: make(map[string]float64),
+		timing: make(map[string]int),
+	}
+	tr := Instrument(NewTransport(), r)
+
+	l, err := tr.Listen("127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Unexpected listen err: %v", err)
+	}
+	defer l.Close()
+
+	closed := make(chan bool)
+	go l.Accept(func(sock Socket) {
+		defer close(closed)
+		for {
+			var m Message
+			if err := sock.Recv(&m); err != nil {
+				return
+			}
+			if err := sock.Send(&m); err != nil {
+				return
+			}
+		}
+	})
+
+	c, err := tr.Dial(l.Addr(), WithService("go.micro.test"))
+	if err != nil {
+		t.Fatalf("Unexpected dial err: %v", err)
+	}
+
+	m := &Message{Body: []byte(`hello`)}
+	if err := c.Send(m); err != nil {
+		t.Fatalf("Unexpected send err: %v", err)
+	}
+	var rsp Message
+	if err := c.Recv(&rsp); err != nil {
+		t.Fatalf("Unexpected recv err: %v", err)
+	}
+
+	r.Lock()
+	if r.counts[MetricBytesSent+":outbound"] != 5 || r.counts[MetricBytesReceived+":inbound"] != 5 {
+		t.Fatalf("Unexpected byte counts %v", r.counts)
+	}
+	if r.gauges[MetricConnections+":outbound"] != 1 || r.gauges[MetricConnections+":inbound"] != 1 {
+		t.Fatalf("Unexpected connections %v", r.gauges)
+	}
+	if r.timing[MetricDialDuration+":go.micro.test"] != 1 {
+		t.Fatalf("Expected the dial to be timed by service got %v", r.timing)
+	}
+	r.Unlock()
+
+	c.Close()
+	<-closed
+
+	r.Lock()
+	if r.gauges[MetricConnections+":outbound"] != 0 || r.gauges[MetricConnections+":inbound"] != 0 {
+		t.Fatalf("Expected the connections to be closed got %v", r.gauges)
+	}
+	r.Unlock()
+
+	// failed dials are counted
+	if _, err := tr.Dial("127.0.0.1:1"); err == nil {
+		t.Fatal("Expected a dial error")
+	}
+	r.Lock()
+	defer r.Unlock()
+	if r.counts[MetricDialErrors+":outbound"] != 1 {
+		t.Fatalf("Expected a dial error to be counted got %v", r.counts)
+	}
+}
@@ -83,12 +83,12 @@ func (q *quicListener) Accept(fn func(transport.Socket)) error {
 		}
 
 		go func() {
-			fn(&quicSocket{
+			fn(transport.CompressSocket(&quicSocket{
 				s:   s,
 				st:  stream,
 				enc: gob.NewEncoder(stream),
 				dec: gob.NewDecoder(stream),
-			})
+			}, q.t.opts))
 		}()
 	}
 }
@@ -133,7 +133,7 @@ func (q *quicTransport) Dial(addr string, opts ...transport.DialOption) (transpo
 	enc := gob.NewEncoder(st)
 	dec := gob.NewDecoder(st)
 
-	return &quicClient{
+	return transport.CompressSocket(&quicClient{
 		&quicSocket{
 			s:   s,
 			st:  st,
@@ -142,7 +142,7 @@ func (q *quicTransport) Dial(addr string, opts ...transport.DialOption) (transpo
 		},
 		q,
 		options,
-	}, nil
+	}, q.opts), nil
 }
 
 func (q *quicTransport) Listen(addr string, opts ...transport.ListenOption) (transport.Listener, error) {
@@ -2,6 +2,7 @@
 package transport
 
 import (
+	"crypto/tls"
 	"time"
 )
 
@@ -33,6 +34,20 @@ type Client interface {
 	Socket
 }
 
+// Prober is implemented by clients which can check their connection is alive
+// without sending a message e.g to evict broken connections from a pool.
+// It mustn't be called concurrently with Recv.
+type Prober interface {
+	Probe() error
+}
+
+// TLSSocket is implemented by the sockets which can be served over tls e.g
+// to identify the peer by its certificate. The state is nil if the
+// connection isn't secure.
+type TLSSocket interface {
+	ConnectionState() *tls.ConnectionState
+}
+
 type Listener interface {
 	Addr() string
 	Close() error
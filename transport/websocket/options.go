@@ -0,0 +1,46 @@
+package websocket
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/micro/go-micro/v2/transport"
+)
+
+type pathKey struct{}
+type compressionKey struct{}
+type keepAliveKey struct{}
+type checkOriginKey struct{}
+
+func setTransportOption(k, v interface{}) transport.Option {
+	return func(o *transport.Options) {
+		if o.Context == nil {
+			o.Context = context.Background()
+		}
+		o.Context = context.WithValue(o.Context, k, v)
+	}
+}
+
+// Path the websocket is served on, defaults to DefaultPath
+func Path(p string) transport.Option {
+	return setTransportOption(pathKey{}, p)
+}
+
+// Compression negotiates permessage-deflate compression of the messages
+func Compression() transport.Option {
+	return setTransportOption(compressionKey{}, true)
+}
+
+// KeepAlive sets the interval of pings sent to the other side of a
+// connection. Connections are closed if no pong is received within
+// twice the interval. Zero disables the pings.
+func KeepAlive(d time.Duration) transport.Option {
+	return setTransportOption(keepAliveKey{}, d)
+}
+
+// CheckOrigin sets the func checking the origin of the upgrade requests.
+// By default requests from browsers of a different origin are rejected.
+func CheckOrigin(fn func(r *http.Request) bool) transport.Option {
+	return setTransportOption(checkOriginKey{}, fn)
+}
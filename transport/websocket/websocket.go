@@ -0,0 +1,345 @@
+// Package websocket provides a websocket transport. Messages are sent as json
+// text frames so services can be connected to through L7 proxies and from
+// browsers.
+package websocket
+
+import (
+	"crypto/tls"
+	"errors"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/micro/go-micro/v2/transport"
+	maddr "github.com/micro/go-micro/v2/util/addr"
+	mnet "github.com/micro/go-micro/v2/util/net"
+	mls "github.com/micro/go-micro/v2/util/tls"
+)
+
+var (
+	// DefaultPath the websocket is served on
+	DefaultPath = "/"
+	// DefaultKeepAlive is the interval of pings
+	DefaultKeepAlive = 30 * time.Second
+)
+
+type wsTransport struct {
+	opts transport.Options
+}
+
+type wsSocket struct {
+	conn    *websocket.Conn
+	timeout time.Duration
+	// time to wait for a pong
+	pongWait time.Duration
+
+	// writes are serialised
+	sync.Mutex
+
+	once sync.Once
+	exit chan bool
+}
+
+type wsClient struct {
+	*wsSocket
+	opts transport.DialOptions
+}
+
+type wsListener struct {
+	t *wsTransport
+	l net.Listener
+}
+
+func newSocket(conn *websocket.Conn, timeout, keepAlive time.Duration) *wsSocket {
+	s := &wsSocket{
+		conn:    conn,
+		timeout: timeout,
+		exit:    make(chan bool),
+	}
+
+	if keepAlive > 0 {
+		s.pongWait = 2 * keepAlive
+		// any ping or pong shows the other side is alive
+		conn.SetPongHandler(func(string) error {
+			return conn.SetReadDeadline(time.Now().Add(s.pongWait))
+		})
+		conn.SetPingHandler(func(data string) error {
+			conn.SetReadDeadline(time.Now().Add(s.pongWait))
+			err := conn.WriteControl(websocket.PongMessage, []byte(data), time.Now().Add(keepAlive))
+			if err == websocket.ErrCloseSent {
+				return nil
+			}
+			return err
+		})
+		go s.ping(keepAlive)
+	}
+
+	return s
+}
+
+// ping the other side until the socket is closed
+func (s *wsSocket) ping(d time.Duration) {
+	t := time.NewTicker(d)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-s.exit:
+			return
+		case <-t.C:
+			if err := s.conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(d)); err != nil {
+				return
+			}
+		}
+	}
+}
+
+func (s *wsSocket) Recv(m *transport.Message) error {
+	if m == nil {
+		return errors.New("message passed in is nil")
+	}
+
+	// sockets without a pong in time are broken
+	if s.pongWait > 0 {
+		s.conn.SetReadDeadline(time.Now().Add(s.pongWait))
+	}
+
+	return s.conn.ReadJSON(m)
+}
+
+func (s *wsSocket) Send(m *transport.Message) error {
+	s.Lock()
+	defer s.Unlock()
+
+	if s.timeout > time.Duration(0) {
+		s.conn.SetWriteDeadline(time.Now().Add(s.timeout))
+	}
+
+	return s.conn.WriteJSON(m)
+}
+
+func (s *wsSocket) Close() error {
+	var err error
+	s.once.Do(func() {
+		close(s.exit)
+		err = s.conn.Close()
+	})
+	return err
+}
+
+func (s *wsSocket) Local() string {
+	return s.conn.LocalAddr().String()
+}
+
+func (s *wsSocket) Remote() string {
+	return s.conn.RemoteAddr().String()
+}
+
+// Probe pings the other side to check the connection isn't broken
+func (c *wsClient) Probe() error {
+	return c.conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(time.Second))
+}
+
+func (l *wsListener) Addr() string {
+	return l.l.Addr().String()
+}
+
+func (l *wsListener) Close() error {
+	return l.l.Close()
+}
+
+func (l *wsListener) Accept(fn func(transport.Socket)) error {
+	upgrader := &websocket.Upgrader{
+		EnableCompression: l.t.compression(),
+	}
+	if fn, ok := l.t.value(checkOriginKey{}).(func(r *http.Request) bool); ok {
+		upgrader.CheckOrigin = fn
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(l.t.path(), func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			// the upgrader replies with the error
+			return
+		}
+
+		sock := newSocket(conn, l.t.opts.Timeout, l.t.keepAlive())
+		defer sock.Close()
+
+		// execute the socket
+		fn(transport.CompressSocket(sock, l.t.opts))
+	})
+
+	return http.Serve(l.l, mux)
+}
+
+func (t *wsTransport) value(k interface{}) interface{} {
+	if t.opts.Context == nil {
+		return nil
+	}
+	return t.opts.Context.Value(k)
+}
+
+func (t *wsTransport) path() string {
+	if p, ok := t.value(pathKey{}).(string); ok && len(p) > 0 {
+		return p
+	}
+	return DefaultPath
+}
+
+func (t *wsTransport) compression() bool {
+	c, _ := t.value(compressionKey{}).(bool)
+	return c
+}
+
+func (t *wsTransport) keepAlive() time.Duration {
+	if d, ok := t.value(keepAliveKey{}).(time.Duration); ok {
+		return d
+	}
+	if t.opts.KeepAlive > 0 {
+		return t.opts.KeepAlive
+	}
+	return DefaultKeepAlive
+}
+
+func (t *wsTransport) secure() bool {
+	return t.opts.Secure || t.opts.TLSConfig != nil
+}
+
+func (t *wsTransport) Init(opts ...transport.Option) error {
+	for _, o := range opts {
+		o(&t.opts)
+	}
+	return nil
+}
+
+func (t *wsTransport) Options() transport.Options {
+	return t.opts
+}
+
+func (t *wsTransport) Dial(addr string, opts ...transport.DialOption) (transport.Client, error) {
+	dopts := transport.DialOptions{
+		Timeout: transport.DefaultDialTimeout,
+	}
+
+	for _, opt := range opts {
+		opt(&dopts)
+	}
+
+	dialer := &websocket.Dialer{
+		HandshakeTimeout:  dopts.Timeout,
+		EnableCompression: t.compression(),
+		Proxy:             http.ProxyFromEnvironment,
+	}
+
+	proxy := t.opts.Proxy
+	if len(dopts.Proxy) > 0 {
+		proxy = dopts.Proxy
+	}
+
+	// dial through the proxy set rather than the one of the environment
+	if len(proxy) > 0 {
+		nd := &net.Dialer{Timeout: dopts.Timeout, KeepAlive: t.opts.KeepAlive}
+		dialer.Proxy = nil
+		dialer.NetDial = func(network, addr string) (net.Conn, error) {
+			return transport.ProxyDial(func(addr string) (net.Conn, error) {
+				return nd.Dial(network, addr)
+			}, proxy)(addr)
+		}
+	}
+
+	scheme := "ws://"
+	if t.secure() {
+		scheme = "wss://"
+		config := t.opts.TLSConfig
+		if config == nil {
+			config = &tls.Config{
+				InsecureSkipVerify: true,
+			}
+		}
+		dialer.TLSClientConfig = config
+	}
+
+	url := addr
+	if !strings.Contains(addr, "://") {
+		url = scheme + addr + t.path()
+	}
+
+	conn, _, err := dialer.Dial(url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return transport.CompressSocket(&wsClient{
+		wsSocket: newSocket(conn, t.opts.Timeout, t.keepAlive()),
+		opts:     dopts,
+	}, t.opts), nil
+}
+
+func (t *wsTransport) Listen(addr string, opts ...transport.ListenOption) (transport.Listener, error) {
+	var options transport.ListenOptions
+	for _, o := range opts {
+		o(&options)
+	}
+
+	fn := func(addr string) (net.Listener, error) {
+		return net.Listen("tcp", addr)
+	}
+
+	if t.secure() {
+		config := t.opts.TLSConfig
+
+		fn = func(addr string) (net.Listener, error) {
+			if config == nil {
+				hosts := []string{addr}
+
+				// check if its a valid host:port
+				if host, _, err := net.SplitHostPort(addr); err == nil {
+					if len(host) == 0 {
+						hosts = maddr.IPs()
+					} else {
+						hosts = []string{host}
+					}
+				}
+
+				// generate a certificate
+				cert, err := mls.Certificate(hosts...)
+				if err != nil {
+					return nil, err
+				}
+				config = &tls.Config{Certificates: []tls.Certificate{cert}}
+			}
+			return tls.Listen("tcp", addr, config)
+		}
+	}
+
+	l, err := mnet.Listen(addr, fn)
+	if err != nil {
+		return nil, err
+	}
+
+	return &wsListener{
+		t: t,
+		l: l,
+	}, nil
+}
+
+func (t *wsTransport) String() string {
+	return "websocket"
+}
+
+// NewTransport returns a websocket transport
+func NewTransport(opts ...transport.Option) transport.Transport {
+	var options transport.Options
+	for _, o := range opts {
+		o(&options)
+	}
+
+	return &wsTransport{
+		opts: options,
+	}
+}
@@ -0,0 +1,67 @@
+package websocket
+
+import (
+	"testing"
+	"time"
+
+	"github.com/micro/go-micro/v2/transport"
+)
+
+func testTransport(t *testing.T, tr transport.Transport) {
+	l, err := tr.Listen("127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Unexpected error listening %v", err)
+	}
+	defer l.Close()
+
+	// echo the messages
+	go l.Accept(func(sock transport.Socket) {
+		for {
+			var m transport.Message
+			if err := sock.Recv(&m); err != nil {
+				return
+			}
+			if err := sock.Send(&m); err != nil {
+				return
+			}
+		}
+	})
+
+	c, err := tr.Dial(l.Addr())
+	if err != nil {
+		t.Fatalf("Unexpected error dialing %v", err)
+	}
+	defer c.Close()
+
+	for i := 0; i < 3; i++ {
+		if err := c.Send(&transport.Message{
+			Header: map[string]string{"Content-Type": "application/json"},
+			Body:   []byte(`{"ping":true}`),
+		}); err != nil {
+			t.Fatalf("Unexpected error sending %v", err)
+		}
+
+		var m transport.Message
+		if err := c.Recv(&m); err != nil {
+			t.Fatalf("Unexpected error receiving %v", err)
+		}
+		if string(m.Body) != `{"ping":true}` || m.Header["Content-Type"] != "application/json" {
+			t.Fatalf("Unexpected message %v", m)
+		}
+
+		// idle connections are kept alive by the pings
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+func TestWebsocketTransport(t *testing.T) {
+	testTransport(t, NewTransport(KeepAlive(10*time.Millisecond)))
+}
+
+func TestWebsocketTransportOptions(t *testing.T) {
+	testTransport(t, NewTransport(
+		Path("/micro"),
+		Compression(),
+		transport.Secure(true),
+	))
+}
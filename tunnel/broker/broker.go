@@ -163,6 +163,14 @@ func (t *tunEvent) Ack() error {
 	return nil
 }
 
+func (t *tunEvent) Nack() error {
+	return nil
+}
+
+func (t *tunEvent) Requeue() error {
+	return nil
+}
+
 func (t *tunEvent) Error() error {
 	return nil
 }
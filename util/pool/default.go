@@ -5,40 +5,70 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/micro/go-micro/v2/metrics"
 	"github.com/micro/go-micro/v2/transport"
 )
 
 type pool struct {
-	size int
-	ttl  time.Duration
-	tr   transport.Transport
+	size        int
+	ttl         time.Duration
+	idleTimeout time.Duration
+	keepAlive   time.Duration
+	tr          transport.Transport
+	metrics     metrics.Reporter
 
 	sync.Mutex
 	conns map[string][]*poolConn
+	exit  chan bool
 }
 
+// MetricIdleConnections is the gauge of the idle connections of each address
+const MetricIdleConnections = "micro_pool_idle_connections"
+
 type poolConn struct {
 	transport.Client
 	id      string
 	created time.Time
+	// time the conn was released to the pool
+	released time.Time
 }
 
 func newPool(options Options) *pool {
-	return &pool{
-		size:  options.Size,
-		tr:    options.Transport,
-		ttl:   options.TTL,
-		conns: make(map[string][]*poolConn),
+	p := &pool{
+		size:        options.Size,
+		tr:          options.Transport,
+		ttl:         options.TTL,
+		idleTimeout: options.IdleTimeout,
+		keepAlive:   options.KeepAlive,
+		metrics:     options.Metrics,
+		conns:       make(map[string][]*poolConn),
+		exit:        make(chan bool),
+	}
+
+	// evict idle and broken conns in the background
+	interval := p.keepAlive
+	if interval <= 0 {
+		interval = p.idleTimeout
 	}
+	if interval > 0 {
+		go p.run(interval)
+	}
+
+	return p
 }
 
 func (p *pool) Close() error {
 	p.Lock()
+	select {
+	case <-p.exit:
+	default:
+		close(p.exit)
+	}
 	for k, c := range p.conns {
 		for _, conn := range c {
 			conn.Client.Close()
 		}
-		delete(p.conns, k)
+		p.report(k, 0)
 	}
 	p.Unlock()
 	return nil
@@ -57,9 +87,102 @@ func (p *poolConn) Created() time.Time {
 	return p.created
 }
 
+// report the number of idle conns of the address. It's called with the
+// lock held so the gauge is set in order. The addresses without idle conns
+// are removed along with their gauge.
+func (p *pool) report(addr string, n int) {
+	if n == 0 {
+		delete(p.conns, addr)
+	}
+	if p.metrics == nil {
+		return
+	}
+	tags := metrics.Tags{"address": addr}
+	if d, ok := p.metrics.(metrics.Deleter); ok && n == 0 {
+		d.Delete(MetricIdleConnections, tags)
+		return
+	}
+	p.metrics.Gauge(MetricIdleConnections, float64(n), tags)
+}
+
+// expired returns true if the conn is too old or was idle too long
+func (p *pool) expired(conn *poolConn) bool {
+	if time.Since(conn.Created()) > p.ttl {
+		return true
+	}
+	return p.idleTimeout > 0 && time.Since(conn.released) > p.idleTimeout
+}
+
+// run evicts the idle conns which expired or are broken
+func (p *pool) run(interval time.Duration) {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-p.exit:
+			return
+		case <-t.C:
+			p.evict()
+		}
+	}
+}
+
+func (p *pool) evict() {
+	// take the idle conns out of the pool while they're probed
+	p.Lock()
+	idle := p.conns
+	p.conns = make(map[string][]*poolConn, len(idle))
+	p.Unlock()
+
+	for addr, conns := range idle {
+		alive := conns[:0]
+		for _, conn := range conns {
+			if p.expired(conn) {
+				conn.Client.Close()
+				continue
+			}
+			if pr, ok := conn.Client.(transport.Prober); ok && p.keepAlive > 0 {
+				if err := pr.Probe(); err != nil {
+					conn.Client.Close()
+					continue
+				}
+			}
+			alive = append(alive, conn)
+		}
+		idle[addr] = alive
+	}
+
+	// put the conns which are alive back
+	p.Lock()
+	defer p.Unlock()
+
+	select {
+	case <-p.exit:
+		for _, conns := range idle {
+			for _, conn := range conns {
+				conn.Client.Close()
+			}
+		}
+		return
+	default:
+	}
+
+	for addr, conns := range idle {
+		for _, conn := range conns {
+			if len(p.conns[addr]) >= p.size {
+				conn.Client.Close()
+				continue
+			}
+			p.conns[addr] = append(p.conns[addr], conn)
+		}
+		p.report(addr, len(p.conns[addr]))
+	}
+}
+
 func (p *pool) Get(addr string, opts ...transport.DialOption) (Conn, error) {
 	p.Lock()
-	conns := p.conns[addr]
+	conns, ok := p.conns[addr]
 
 	// while we have conns check age and then return one
 	// otherwise we'll create a new conn
@@ -68,18 +191,22 @@ func (p *pool) Get(addr string, opts ...transport.DialOption) (Conn, error) {
 		conns = conns[:len(conns)-1]
 		p.conns[addr] = conns
 
-		// if conn is old kill it and move on
-		if d := time.Since(conn.Created()); d > p.ttl {
+		// if conn is old or idle too long kill it and move on
+		if p.expired(conn) {
 			conn.Client.Close()
 			continue
 		}
 
 		// we got a good conn, lets unlock and return it
+		p.report(addr, len(conns))
 		p.Unlock()
 
 		return conn, nil
 	}
 
+	if ok {
+		p.report(addr, 0)
+	}
 	p.Unlock()
 
 	// create new conn
@@ -100,14 +227,18 @@ func (p *pool) Release(conn Conn, err error) error {
 		return conn.(*poolConn).Client.Close()
 	}
 
+	pc := conn.(*poolConn)
+	pc.released = time.Now()
+
 	// otherwise put it back for reuse
 	p.Lock()
 	conns := p.conns[conn.Remote()]
 	if len(conns) >= p.size {
 		p.Unlock()
-		return conn.(*poolConn).Client.Close()
+		return pc.Client.Close()
 	}
-	p.conns[conn.Remote()] = append(conns, conn.(*poolConn))
+	p.conns[conn.Remote()] = append(conns, pc)
+	p.report(conn.Remote(), len(p.conns[conn.Remote()]))
 	p.Unlock()
 
 	return nil
@@ -1,9 +1,12 @@
 package pool
 
 import (
+	"errors"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/micro/go-micro/v2/metrics"
 	"github.com/micro/go-micro/v2/transport"
 	"github.com/micro/go-micro/v2/transport/memory"
 )
@@ -87,3 +90,148 @@ func TestClientPool(t *testing.T) {
 	testPool(t, 0, time.Minute)
 	testPool(t, 2, time.Minute)
 }
+
+func testEviction(t *testing.T, options Options, closeListener bool) {
+	tr := memory.NewTransport()
+	options.Transport = tr
+
+	p := newPool(options)
+	defer p.Close()
+
+	l, err := tr.Listen(":0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	go l.Accept(func(s transport.Socket) {
+		var msg transport.Message
+		s.Recv(&msg)
+	})
+
+	c, err := p.Get(l.Addr())
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.Release(c, nil)
+
+	p.Lock()
+	if i := len(p.conns[l.Addr()]); i != 1 {
+		p.Unlock()
+		t.Fatalf("Expected 1 conn in the pool got %d", i)
+	}
+	p.Unlock()
+
+	// break the conn
+	if closeListener {
+		l.Close()
+	}
+
+	time.Sleep(100 * time.Millisecond)
+
+	p.Lock()
+	defer p.Unlock()
+	if i := len(p.conns[l.Addr()]); i != 0 {
+		t.Fatalf("Expected the conn to be evicted got %d conns", i)
+	}
+}
+
+func TestPoolEviction(t *testing.T) {
+	// broken conns are evicted
+	testEviction(t, Options{
+		TTL:       time.Minute,
+		Size:      2,
+		KeepAlive: 10 * time.Millisecond,
+	}, true)
+
+	// idle conns are evicted
+	testEviction(t, Options{
+		TTL:         time.Minute,
+		Size:        2,
+		IdleTimeout: 20 * time.Millisecond,
+	}, false)
+}
+
+type testReporter struct {
+	metrics.Reporter
+	sync.Mutex
+	gauges map[string]float64
+}
+
+func (r *testReporter) Gauge(id string, value float64, tags metrics.Tags) error {
+	r.Lock()
+	defer r.Unlock()
+	r.gauges[tags["address"]] = value
+	return nil
+}
+
+func (r *testReporter) Delete(id string, tags metrics.Tags) error {
+	r.Lock()
+	defer r.Unlock()
+	delete(r.gauges, tags["address"])
+	return nil
+}
+
+func TestPoolMetrics(t *testing.T) {
+	tr := memory.NewTransport()
+	m := &testReporter{gauges: make(map[string]float64)}
+
+	p := newPool(Options{
+		Transport: tr,
+		Size:      2,
+		TTL:       time.Minute,
+		Metrics:   m,
+	})
+	defer p.Close()
+
+	l, err := tr.Listen(":0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	go l.Accept(func(s transport.Socket) {
+		var msg transport.Message
+		s.Recv(&msg)
+	})
+
+	gauge := func() (float64, bool) {
+		m.Lock()
+		defer m.Unlock()
+		v, ok := m.gauges[l.Addr()]
+		return v, ok
+	}
+
+	c1, err := p.Get(l.Addr())
+	if err != nil {
+		t.Fatal(err)
+	}
+	c2, err := p.Get(l.Addr())
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.Release(c1, nil)
+	p.Release(c2, nil)
+	if v, _ := gauge(); v != 2 {
+		t.Fatalf("Expected 2 idle conns got %v", v)
+	}
+
+	// the gauge of an address without idle conns is deleted
+	c1, _ = p.Get(l.Addr())
+	c2, _ = p.Get(l.Addr())
+	if v, ok := gauge(); ok {
+		t.Fatalf("Expected the gauge to be deleted got %v", v)
+	}
+	p.Lock()
+	_, ok := p.conns[l.Addr()]
+	p.Unlock()
+	if ok {
+		t.Fatal("Expected the address to be removed")
+	}
+
+	p.Release(c1, nil)
+	if v, _ := gauge(); v != 1 {
+		t.Fatalf("Expected 1 idle conn got %v", v)
+	}
+	p.Release(c2, errors.New("broken"))
+}
@@ -3,13 +3,23 @@ package pool
 import (
 	"time"
 
+	"github.com/micro/go-micro/v2/metrics"
 	"github.com/micro/go-micro/v2/transport"
 )
 
 type Options struct {
 	Transport transport.Transport
-	TTL       time.Duration
-	Size      int
+	// TTL is the max age of a connection
+	TTL time.Duration
+	// Size is the max number of idle connections per address
+	Size int
+	// IdleTimeout is the max time a connection is idle in the pool
+	IdleTimeout time.Duration
+	// KeepAlive is the interval idle connections are probed at. Broken
+	// connections of transports implementing transport.Prober are evicted.
+	KeepAlive time.Duration
+	// Metrics reports the idle connections of each address
+	Metrics metrics.Reporter
 }
 
 type Option func(*Options)
@@ -31,3 +41,24 @@ func TTL(t time.Duration) Option {
 		o.TTL = t
 	}
 }
+
+// IdleTimeout sets the max time a connection is idle in the pool
+func IdleTimeout(t time.Duration) Option {
+	return func(o *Options) {
+		o.IdleTimeout = t
+	}
+}
+
+// KeepAlive sets the interval idle connections are probed at
+func KeepAlive(t time.Duration) Option {
+	return func(o *Options) {
+		o.KeepAlive = t
+	}
+}
+
+// Metrics reports the idle connections of each address
+func Metrics(r metrics.Reporter) Option {
+	return func(o *Options) {
+		o.Metrics = r
+	}
+}
@@ -0,0 +1,202 @@
+// Package redact masks the sensitive fields of the payloads which are logged,
+// traced or served by the debug endpoints. The fields of structs are marked
+// with the redact tag e.g
+//
+//	type Request struct {
+//		Name     string `json:"name"`
+//		Password string `json:"password" redact:"true"`
+//	}
+//
+// The fields of the messages generated by protoc are marked with Register as
+// their tags can't be set e.g
+//
+//	redact.Register(&pb.LoginRequest{}, "password", "token")
+package redact
+
+import (
+	"encoding"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// Mask replaces the values of the sensitive fields
+const Mask = "[REDACTED]"
+
+// MaxLength is the max length of the payloads returned by String
+var MaxLength = 1024
+
+var (
+	// the registered fields by type
+	registered sync.Map
+	// the fields of the types by index
+	fields sync.Map
+)
+
+type structField struct {
+	index     int
+	name      string
+	sensitive bool
+}
+
+// Register marks the fields of the type of v as sensitive by their json name
+// e.g the fields of the protobuf messages
+func Register(v interface{}, names ...string) {
+	t := reflect.TypeOf(v)
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	set := make(map[string]bool, len(names))
+	if v, ok := registered.Load(t); ok {
+		for name := range v.(map[string]bool) {
+			set[name] = true
+		}
+	}
+	for _, name := range names {
+		set[name] = true
+	}
+
+	registered.Store(t, set)
+	// recompute the fields of the type
+	fields.Delete(t)
+}
+
+// fieldsOf returns the exported fields of the struct type with their json name
+func fieldsOf(t reflect.Type) []structField {
+	if v, ok := fields.Load(t); ok {
+		return v.([]structField)
+	}
+
+	var set map[string]bool
+	if v, ok := registered.Load(t); ok {
+		set = v.(map[string]bool)
+	}
+
+	var sf []structField
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if len(f.PkgPath) > 0 || strings.HasPrefix(f.Name, "XXX_") {
+			continue
+		}
+		name := strings.Split(f.Tag.Get("json"), ",")[0]
+		if name == "-" {
+			continue
+		}
+		if len(name) == 0 {
+			name = f.Name
+		}
+		tag := f.Tag.Get("redact")
+		sf = append(sf, structField{
+			index:     i,
+			name:      name,
+			sensitive: set[name] || set[f.Name] || (len(tag) > 0 && tag != "false"),
+		})
+	}
+
+	fields.Store(t, sf)
+	return sf
+}
+
+var (
+	jsonMarshaler = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	textMarshaler = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+// marshals returns whether the type is encoded as json with its own method
+func marshals(t reflect.Type) bool {
+	p := reflect.PtrTo(t)
+	return p.Implements(jsonMarshaler) || p.Implements(textMarshaler)
+}
+
+func sensitive(sf []structField) bool {
+	for _, f := range sf {
+		if f.sensitive {
+			return true
+		}
+	}
+	return false
+}
+
+// Redact returns a copy of v made of maps, slices and values with the
+// sensitive fields masked. It's encoded as json the same way as v.
+func Redact(v interface{}) interface{} {
+	return redact(reflect.ValueOf(v))
+}
+
+func redact(v reflect.Value) interface{} {
+	for v.IsValid() && (v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface) {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+	if !v.IsValid() {
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.Struct:
+		sf := fieldsOf(v.Type())
+		if marshals(v.Type()) && !sensitive(sf) {
+			// e.g time.Time
+			p := reflect.New(v.Type())
+			p.Elem().Set(v)
+			return p.Interface()
+		}
+		m := make(map[string]interface{})
+		for _, f := range sf {
+			if f.sensitive {
+				m[f.name] = Mask
+				continue
+			}
+			m[f.name] = redact(v.Field(f.index))
+		}
+		return m
+	case reflect.Map:
+		if v.IsNil() {
+			return nil
+		}
+		m := make(map[string]interface{}, v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			m[fmt.Sprint(iter.Key().Interface())] = redact(iter.Value())
+		}
+		return m
+	case reflect.Slice, reflect.Array:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			if v.Kind() == reflect.Slice {
+				return base64.StdEncoding.EncodeToString(v.Bytes())
+			}
+			return v.Interface()
+		}
+		if v.Kind() == reflect.Slice && v.IsNil() {
+			return nil
+		}
+		s := make([]interface{}, v.Len())
+		for i := 0; i < v.Len(); i++ {
+			s[i] = redact(v.Index(i))
+		}
+		return s
+	case reflect.Func, reflect.Chan, reflect.UnsafePointer:
+		return nil
+	}
+
+	return v.Interface()
+}
+
+// String returns the json of v with its sensitive fields masked, truncated
+// to the MaxLength
+func String(v interface{}) string {
+	b, err := json.Marshal(Redact(v))
+	if err != nil {
+		return fmt.Sprintf("<%T: %v>", v, err)
+	}
+	if MaxLength > 0 && len(b) > MaxLength {
+		return string(b[:MaxLength]) + "..."
+	}
+	return string(b)
+}
@@ -0,0 +1,73 @@
+package redact
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+type testCard struct {
+	Number string `json:"number" redact:"true"`
+	Expiry string `json:"expiry"`
+}
+
+type testRequest struct {
+	Name     string            `json:"name"`
+	Password string            `json:"password" redact:"true"`
+	Token    string            `json:"token"`
+	Cards    []*testCard       `json:"cards"`
+	Labels   map[string]string `json:"labels"`
+	Created  time.Time         `json:"created"`
+	Ignored  string            `json:"-"`
+	internal string
+}
+
+func TestRedact(t *testing.T) {
+	Register(&testRequest{}, "token")
+
+	created := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	req := &testRequest{
+		Name:     "john",
+		Password: "secret",
+		Token:    "abc",
+		Cards:    []*testCard{{Number: "4111", Expiry: "01/30"}},
+		Labels:   map[string]string{"team": "a"},
+		Created:  created,
+		Ignored:  "ignored",
+		internal: "internal",
+	}
+
+	var got map[string]interface{}
+	if err := json.Unmarshal([]byte(String(req)), &got); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]interface{}{
+		"name":     "john",
+		"password": Mask,
+		"token":    Mask,
+		"cards":    []interface{}{map[string]interface{}{"number": Mask, "expiry": "01/30"}},
+		"labels":   map[string]interface{}{"team": "a"},
+		"created":  created.Format(time.RFC3339Nano),
+	}
+	b1, _ := json.Marshal(expected)
+	b2, _ := json.Marshal(got)
+	if string(b1) != string(b2) {
+		t.Fatalf("Expected %s got %s", b1, b2)
+	}
+
+	// the payload is left as is
+	if req.Password != "secret" || req.Cards[0].Number != "4111" {
+		t.Fatal("Expected the payload to be unchanged")
+	}
+}
+
+func TestString(t *testing.T) {
+	if s := String(nil); s != "null" {
+		t.Fatalf("Expected null got %s", s)
+	}
+	if s := String(strings.Repeat("a", MaxLength*2)); len(s) != MaxLength+3 || !strings.HasSuffix(s, "...") {
+		t.Fatalf("Expected the payload to be truncated got %d bytes", len(s))
+	}
+}
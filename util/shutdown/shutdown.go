@@ -0,0 +1,169 @@
+// Package shutdown stops subsystems in order with a timeout per stage
+package shutdown
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	// DefaultTimeout of a stage
+	DefaultTimeout = 30 * time.Second
+)
+
+// Func stops a subsystem. It should return when the context is done.
+type Func func(ctx context.Context) error
+
+// Stage of the shutdown
+type Stage struct {
+	Name    string
+	Timeout time.Duration
+	Stop    Func
+}
+
+// Result of a stage
+type Result struct {
+	Stage    string
+	Duration time.Duration
+	Error    error
+}
+
+// TimedOut returns true if the stage didn't finish within its timeout
+func (r Result) TimedOut() bool {
+	return r.Error == context.DeadlineExceeded
+}
+
+// Report of the shutdown with the result of every stage in order
+type Report []Result
+
+// Err returns an error listing the stages which failed
+func (r Report) Err() error {
+	var errs []string
+	for _, res := range r {
+		if res.Error != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", res.Stage, res.Error))
+		}
+	}
+	if len(errs) == 0 {
+		return nil
+	}
+	return fmt.Errorf("shutdown failed: %s", strings.Join(errs, "; "))
+}
+
+type Option func(o *Options)
+
+type Options struct {
+	// Timeout of stages without their own
+	Timeout time.Duration
+}
+
+// Timeout sets the timeout of stages without their own
+func Timeout(d time.Duration) Option {
+	return func(o *Options) {
+		o.Timeout = d
+	}
+}
+
+// Coordinator runs the stages in the order they were added. A stage which
+// fails or times out doesn't stop the stages after it.
+type Coordinator struct {
+	opts Options
+
+	sync.Mutex
+	stages []Stage
+}
+
+// Add a stage with the default timeout
+func (c *Coordinator) Add(name string, fn Func) {
+	c.AddStage(Stage{Name: name, Stop: fn})
+}
+
+// AddStage adds a stage. A zero timeout uses the default.
+func (c *Coordinator) AddStage(s Stage) {
+	c.Lock()
+	c.stages = append(c.stages, s)
+	c.Unlock()
+}
+
+// Stages returns the stages in order
+func (c *Coordinator) Stages() []Stage {
+	c.Lock()
+	defer c.Unlock()
+	stages := make([]Stage, len(c.stages))
+	copy(stages, c.stages)
+	return stages
+}
+
+// Shutdown runs the stages in order. Cancelling the context
+// cancels the stage running and skips the rest.
+func (c *Coordinator) Shutdown(ctx context.Context) Report {
+	stages := c.Stages()
+	report := make(Report, 0, len(stages))
+
+	for _, s := range stages {
+		res := Result{Stage: s.Name}
+
+		if err := ctx.Err(); err != nil {
+			res.Error = err
+			report = append(report, res)
+			continue
+		}
+
+		timeout := s.Timeout
+		if timeout <= 0 {
+			timeout = c.opts.Timeout
+		}
+
+		start := time.Now()
+		res.Error = run(ctx, timeout, s.Stop)
+		res.Duration = time.Since(start)
+
+		report = append(report, res)
+	}
+
+	return report
+}
+
+// run calls the func and returns when it does or the timeout is reached
+func run(ctx context.Context, timeout time.Duration, fn Func) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- fn(ctx)
+	}()
+
+	select {
+	case err := <-errCh:
+		return err
+	case <-ctx.Done():
+		// prefer the result if the func returned in time
+		select {
+		case err := <-errCh:
+			return err
+		default:
+			return ctx.Err()
+		}
+	}
+}
+
+// NewCoordinator returns a coordinator without any stages
+func NewCoordinator(opts ...Option) *Coordinator {
+	options := Options{
+		Timeout: DefaultTimeout,
+	}
+	for _, o := range opts {
+		o(&options)
+	}
+	if options.Timeout <= 0 {
+		options.Timeout = DefaultTimeout
+	}
+
+	return &Coordinator{
+		opts: options,
+	}
+}
@@ -0,0 +1,73 @@
+package shutdown
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestCoordinator(t *testing.T) {
+	var order []string
+
+	c := NewCoordinator(Timeout(time.Second))
+	c.Add("server", func(ctx context.Context) error {
+		order = append(order, "server")
+		return nil
+	})
+	c.AddStage(Stage{
+		Name:    "broker",
+		Timeout: 10 * time.Millisecond,
+		Stop: func(ctx context.Context) error {
+			<-ctx.Done()
+			time.Sleep(10 * time.Millisecond)
+			return nil
+		},
+	})
+	c.Add("store", func(ctx context.Context) error {
+		order = append(order, "store")
+		return errors.New("close failed")
+	})
+	c.Add("registry", func(ctx context.Context) error {
+		order = append(order, "registry")
+		return nil
+	})
+
+	report := c.Shutdown(context.Background())
+
+	if len(order) != 3 || order[0] != "server" || order[1] != "store" || order[2] != "registry" {
+		t.Fatalf("Unexpected order %v", order)
+	}
+	if len(report) != 4 {
+		t.Fatalf("Expected 4 results got %d", len(report))
+	}
+	if !report[1].TimedOut() {
+		t.Fatalf("Expected the broker stage to time out got %v", report[1].Error)
+	}
+	if report[2].Error == nil || report[3].Error != nil {
+		t.Fatalf("Unexpected results %+v", report)
+	}
+	if err := report.Err(); err == nil || err.Error() != "shutdown failed: broker: context deadline exceeded; store: close failed" {
+		t.Fatalf("Unexpected error %v", err)
+	}
+}
+
+func TestCoordinatorCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	c := NewCoordinator()
+	c.Add("server", func(ctx context.Context) error {
+		cancel()
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	c.Add("store", func(ctx context.Context) error {
+		t.Fatal("Expected the stage to be skipped")
+		return nil
+	})
+
+	report := c.Shutdown(ctx)
+	if report[0].Error != context.Canceled || report[1].Error != context.Canceled {
+		t.Fatalf("Unexpected results %+v", report)
+	}
+}
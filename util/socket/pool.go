@@ -7,6 +7,18 @@ import (
 type Pool struct {
 	sync.RWMutex
 	pool map[string]*Socket
+	// messages buffered by the sockets
+	buffer int
+}
+
+// Option of the pool
+type Option func(*Pool)
+
+// Buffer sets the number of messages buffered by the sockets of the pool
+func Buffer(n int) Option {
+	return func(p *Pool) {
+		p.buffer = n
+	}
 }
 
 func (p *Pool) Get(id string) (*Socket, bool) {
@@ -28,7 +40,7 @@ func (p *Pool) Get(id string) (*Socket, bool) {
 		return socket, ok
 	}
 	// create new socket
-	socket = New(id)
+	socket = newSocket(id, p.buffer)
 	p.pool[id] = socket
 
 	// return socket
@@ -55,8 +67,13 @@ func (p *Pool) Close() {
 }
 
 // NewPool returns a new socket pool
-func NewPool() *Pool {
-	return &Pool{
-		pool: make(map[string]*Socket),
+func NewPool(opts ...Option) *Pool {
+	p := &Pool{
+		pool:   make(map[string]*Socket),
+		buffer: DefaultBuffer,
+	}
+	for _, o := range opts {
+		o(p)
 	}
+	return p
 }
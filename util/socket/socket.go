@@ -2,11 +2,19 @@
 package socket
 
 import (
+	"errors"
 	"io"
 
 	"github.com/micro/go-micro/v2/transport"
 )
 
+var (
+	// ErrFull is returned by Acquire when the window is full
+	ErrFull = errors.New("socket window full")
+	// DefaultBuffer is the number of messages buffered by the sockets
+	DefaultBuffer = 128
+)
+
 // Socket is our pseudo socket for transport.Socket
 type Socket struct {
 	id string
@@ -20,6 +28,11 @@ type Socket struct {
 	send chan *transport.Message
 	// recv chan
 	recv chan *transport.Message
+	// window of the messages which can be sent before
+	// the receiver acknowledges them, nil if unlimited
+	window chan struct{}
+	// wait for the window rather than fail
+	wait bool
 }
 
 func (s *Socket) SetLocal(l string) {
@@ -58,6 +71,58 @@ func (s *Socket) Process(m *transport.Message) error {
 	return nil
 }
 
+// SetWindow limits the messages sent to the size of the window until the
+// receiver acknowledges them with Ack. Acquire waits for the window to have
+// room or fails with ErrFull if wait is false. It's called before the socket
+// is used.
+func (s *Socket) SetWindow(size int, wait bool) {
+	s.window = make(chan struct{}, size)
+	for i := 0; i < size; i++ {
+		s.window <- struct{}{}
+	}
+	s.wait = wait
+}
+
+// Acquire a message of the window before sending it
+func (s *Socket) Acquire() error {
+	if s.window == nil {
+		return nil
+	}
+
+	if !s.wait {
+		select {
+		case <-s.window:
+			return nil
+		case <-s.closed:
+			return io.EOF
+		default:
+			return ErrFull
+		}
+	}
+
+	select {
+	case <-s.window:
+		return nil
+	case <-s.closed:
+		return io.EOF
+	}
+}
+
+// Ack returns the messages the receiver acknowledged to the window
+func (s *Socket) Ack(n int) {
+	if s.window == nil {
+		return
+	}
+	for i := 0; i < n; i++ {
+		select {
+		case s.window <- struct{}{}:
+		default:
+			// acknowledged more than sent
+			return
+		}
+	}
+}
+
 func (s *Socket) Remote() string {
 	return s.remote
 }
@@ -106,12 +171,16 @@ func (s *Socket) Close() error {
 // Messages are sent to the socket via Accept and receives from the socket via Process.
 // SetLocal/SetRemote should be called before using the socket.
 func New(id string) *Socket {
+	return newSocket(id, DefaultBuffer)
+}
+
+func newSocket(id string, buffer int) *Socket {
 	return &Socket{
 		id:     id,
 		closed: make(chan bool),
 		local:  "local",
 		remote: "remote",
-		send:   make(chan *transport.Message, 128),
-		recv:   make(chan *transport.Message, 128),
+		send:   make(chan *transport.Message, buffer),
+		recv:   make(chan *transport.Message, buffer),
 	}
 }
@@ -0,0 +1,268 @@
+package tls
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"io/ioutil"
+	"os"
+	"sync"
+	"time"
+)
+
+// Options of a tls config. The same config is used by transports, brokers and
+// registries to both serve and dial so mutual tls is configured consistently.
+type Options struct {
+	// CertFile and KeyFile are the pem encoded certificate presented
+	// to clients when serving and to servers when dialing
+	CertFile, KeyFile string
+	// CAFile is the pem encoded bundle of the certificate authorities
+	// verifying the certificates of servers and clients
+	CAFile string
+	// ServerName the certificates of servers are verified against
+	ServerName string
+	// MinVersion of tls e.g tls.VersionTLS12
+	MinVersion uint16
+	// CipherSuites enabled for tls 1.2 and below
+	CipherSuites []uint16
+	// ClientAuth requires clients to present a certificate
+	// verified by the certificate authorities
+	ClientAuth bool
+	// InsecureSkipVerify skips the verification of server certificates
+	InsecureSkipVerify bool
+	// Reload is the interval the files are checked for changes at so
+	// rotated certificates are used without restarting. Zero disables it.
+	Reload time.Duration
+}
+
+type Option func(o *Options)
+
+// KeyPair sets the files of the certificate and its private key
+func KeyPair(certFile, keyFile string) Option {
+	return func(o *Options) {
+		o.CertFile = certFile
+		o.KeyFile = keyFile
+	}
+}
+
+// CAFile sets the file of the certificate authorities
+func CAFile(f string) Option {
+	return func(o *Options) {
+		o.CAFile = f
+	}
+}
+
+// ServerName sets the name server certificates are verified against
+func ServerName(n string) Option {
+	return func(o *Options) {
+		o.ServerName = n
+	}
+}
+
+// MinVersion sets the min version of tls
+func MinVersion(v uint16) Option {
+	return func(o *Options) {
+		o.MinVersion = v
+	}
+}
+
+// CipherSuites sets the enabled cipher suites
+func CipherSuites(c ...uint16) Option {
+	return func(o *Options) {
+		o.CipherSuites = c
+	}
+}
+
+// ClientAuth requires and verifies client certificates
+func ClientAuth() Option {
+	return func(o *Options) {
+		o.ClientAuth = true
+	}
+}
+
+// InsecureSkipVerify skips the verification of server certificates
+func InsecureSkipVerify() Option {
+	return func(o *Options) {
+		o.InsecureSkipVerify = true
+	}
+}
+
+// Reload checks the files for changes on the interval
+func Reload(d time.Duration) Option {
+	return func(o *Options) {
+		o.Reload = d
+	}
+}
+
+// files loads the certificate and certificate authorities
+// and reloads them once they change
+type files struct {
+	opts Options
+
+	sync.Mutex
+	checked time.Time
+	modTime time.Time
+	cert    *tls.Certificate
+	cas     *x509.CertPool
+}
+
+// modified returns the latest modification time of the files
+func (f *files) modified() (time.Time, error) {
+	var t time.Time
+	for _, name := range []string{f.opts.CertFile, f.opts.KeyFile, f.opts.CAFile} {
+		if len(name) == 0 {
+			continue
+		}
+		fi, err := os.Stat(name)
+		if err != nil {
+			return t, err
+		}
+		if fi.ModTime().After(t) {
+			t = fi.ModTime()
+		}
+	}
+	return t, nil
+}
+
+func (f *files) load() error {
+	modTime, err := f.modified()
+	if err != nil {
+		return err
+	}
+
+	var cert *tls.Certificate
+	if len(f.opts.CertFile) > 0 {
+		c, err := tls.LoadX509KeyPair(f.opts.CertFile, f.opts.KeyFile)
+		if err != nil {
+			return err
+		}
+		cert = &c
+	}
+
+	var cas *x509.CertPool
+	if len(f.opts.CAFile) > 0 {
+		b, err := ioutil.ReadFile(f.opts.CAFile)
+		if err != nil {
+			return err
+		}
+		cas = x509.NewCertPool()
+		if !cas.AppendCertsFromPEM(b) {
+			return errors.New("no certificates found in " + f.opts.CAFile)
+		}
+	}
+
+	f.cert = cert
+	f.cas = cas
+	f.modTime = modTime
+	f.checked = time.Now()
+
+	return nil
+}
+
+// get returns the certificate and certificate authorities. They're reloaded
+// if the files changed, the previous ones are kept if reloading fails.
+func (f *files) get() (*tls.Certificate, *x509.CertPool) {
+	f.Lock()
+	defer f.Unlock()
+
+	if f.opts.Reload > 0 && time.Since(f.checked) > f.opts.Reload {
+		f.checked = time.Now()
+		if t, err := f.modified(); err == nil && t.After(f.modTime) {
+			f.load()
+		}
+	}
+
+	return f.cert, f.cas
+}
+
+// verify the certificate chain of the server against the certificate authorities
+func (f *files) verify(rawCerts [][]byte, verifiedChains [][]*x509.Certificate) error {
+	// servers verify the chains of clients before
+	if len(verifiedChains) > 0 || len(rawCerts) == 0 {
+		return nil
+	}
+
+	_, cas := f.get()
+
+	certs := make([]*x509.Certificate, 0, len(rawCerts))
+	for _, raw := range rawCerts {
+		c, err := x509.ParseCertificate(raw)
+		if err != nil {
+			return err
+		}
+		certs = append(certs, c)
+	}
+
+	opts := x509.VerifyOptions{
+		Roots:         cas,
+		DNSName:       f.opts.ServerName,
+		Intermediates: x509.NewCertPool(),
+	}
+	for _, c := range certs[1:] {
+		opts.Intermediates.AddCert(c)
+	}
+
+	_, err := certs[0].Verify(opts)
+	return err
+}
+
+// Config returns a tls config for serving and dialing. Set it with the
+// TLSConfig option of the transport, broker or registry.
+func Config(opts ...Option) (*tls.Config, error) {
+	var options Options
+	for _, o := range opts {
+		o(&options)
+	}
+
+	f := &files{opts: options}
+	if err := f.load(); err != nil {
+		return nil, err
+	}
+
+	config := &tls.Config{
+		ServerName:         options.ServerName,
+		MinVersion:         options.MinVersion,
+		CipherSuites:       options.CipherSuites,
+		InsecureSkipVerify: options.InsecureSkipVerify,
+		RootCAs:            f.cas,
+	}
+
+	if f.cert != nil {
+		config.GetCertificate = func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+			cert, _ := f.get()
+			return cert, nil
+		}
+		config.GetClientCertificate = func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			cert, _ := f.get()
+			return cert, nil
+		}
+	}
+
+	if options.ClientAuth {
+		config.ClientAuth = tls.RequireAndVerifyClientCert
+		config.ClientCAs = f.cas
+	}
+
+	if options.Reload <= 0 {
+		return config, nil
+	}
+
+	// servers verify client certificates with the reloaded authorities
+	if options.ClientAuth {
+		config.GetConfigForClient = func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			_, cas := f.get()
+			c := config.Clone()
+			c.GetConfigForClient = nil
+			c.ClientCAs = cas
+			return c, nil
+		}
+	}
+
+	// clients verify server certificates with the reloaded authorities
+	if len(options.CAFile) > 0 && !options.InsecureSkipVerify {
+		config.InsecureSkipVerify = true
+		config.VerifyPeerCertificate = f.verify
+	}
+
+	return config, nil
+}
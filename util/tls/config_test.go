@@ -0,0 +1,105 @@
+package tls
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeCertificate writes a self signed certificate and its key
+func writeCertificate(t *testing.T, dir string, modTime time.Time) tls.Certificate {
+	cert, err := Certificate("127.0.0.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := x509.MarshalECPrivateKey(cert.PrivateKey.(*ecdsa.PrivateKey))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	files := map[string]*pem.Block{
+		"cert.pem": {Type: "CERTIFICATE", Bytes: cert.Certificate[0]},
+		"key.pem":  {Type: "EC PRIVATE KEY", Bytes: key},
+	}
+	for name, block := range files {
+		path := filepath.Join(dir, name)
+		if err := ioutil.WriteFile(path, pem.EncodeToMemory(block), 0600); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(path, modTime, modTime); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return cert
+}
+
+func TestConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tls")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	cert := writeCertificate(t, dir, time.Now().Add(-time.Hour))
+	certFile := filepath.Join(dir, "cert.pem")
+	keyFile := filepath.Join(dir, "key.pem")
+
+	server, err := Config(KeyPair(certFile, keyFile), Reload(time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+	client, err := Config(CAFile(certFile), ServerName("127.0.0.1"), MinVersion(tls.VersionTLS12))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	l, err := tls.Listen("tcp", "127.0.0.1:0", server)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	go func() {
+		for {
+			c, err := l.Accept()
+			if err != nil {
+				return
+			}
+			c.(*tls.Conn).Handshake()
+			c.Close()
+		}
+	}()
+
+	// the server certificate is verified by the certificate authority
+	c, err := tls.Dial("tcp", l.Addr().String(), client)
+	if err != nil {
+		t.Fatalf("Unexpected error dialing %v", err)
+	}
+	c.Close()
+
+	// rotated certificates are reloaded
+	rotated := writeCertificate(t, dir, time.Now())
+	time.Sleep(10 * time.Millisecond)
+
+	got, err := server.GetCertificate(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Equal(got.Certificate[0], cert.Certificate[0]) || !bytes.Equal(got.Certificate[0], rotated.Certificate[0]) {
+		t.Fatal("Expected the rotated certificate")
+	}
+
+	// the rotated certificate isn't signed by the certificate authority
+	if c, err := tls.Dial("tcp", l.Addr().String(), client); err == nil {
+		c.Close()
+		t.Fatal("Expected a certificate verification error")
+	}
+}
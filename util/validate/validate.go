@@ -0,0 +1,273 @@
+// Package validate checks requests against their validation rules. Messages
+// generated by protoc-gen-validate are checked with their Validate method,
+// structs with the rules of their validate tags e.g
+//
+//	type Request struct {
+//		Name  string `json:"name" validate:"required,max=64"`
+//		Kind  string `json:"kind" validate:"oneof=user group"`
+//		Email string `json:"email" validate:"pattern=^[^@]+@[^@]+$"`
+//	}
+//
+// The rules are required, min, max, len, oneof and pattern, which must be the
+// last one. The rules other than required aren't checked for zero values so
+// optional fields can be omitted. min, max and len are the length of strings,
+// slices and maps and the value of numbers.
+package validate
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Violation of a rule by a field
+type Violation struct {
+	// Field of the request e.g user.name
+	Field string `json:"field"`
+	// Reason the field is invalid
+	Reason string `json:"reason"`
+}
+
+// Violations of the rules of a request. They're attached to the
+// BadRequest errors returned for invalid requests as a detail.
+type Violations []Violation
+
+func (v Violations) Error() string {
+	reasons := make([]string, 0, len(v))
+	for _, violation := range v {
+		reasons = append(reasons, violation.Field+": "+violation.Reason)
+	}
+	return strings.Join(reasons, ", ")
+}
+
+// Validator is implemented by the messages generated by protoc-gen-validate
+type Validator interface {
+	Validate() error
+}
+
+// fieldError is the error of a field returned by protoc-gen-validate
+type fieldError interface {
+	Field() string
+	Reason() string
+}
+
+// multiError is the error of every field returned by protoc-gen-validate
+type multiError interface {
+	AllErrors() []error
+}
+
+var patterns sync.Map
+
+// Validate returns the violations of the rules of v, nil if it's valid
+func Validate(v interface{}) Violations {
+	if val, ok := v.(Validator); ok {
+		return fromError(val.Validate())
+	}
+
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			return nil
+		}
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return nil
+	}
+
+	var violations Violations
+	validateStruct(rv, "", &violations)
+	return violations
+}
+
+// fromError returns the violations of the error of a Validate method
+func fromError(err error) Violations {
+	if err == nil {
+		return nil
+	}
+
+	errs := []error{err}
+	if m, ok := err.(multiError); ok {
+		errs = m.AllErrors()
+	}
+
+	violations := make(Violations, 0, len(errs))
+	for _, err := range errs {
+		if f, ok := err.(fieldError); ok {
+			violations = append(violations, Violation{Field: f.Field(), Reason: f.Reason()})
+			continue
+		}
+		violations = append(violations, Violation{Reason: err.Error()})
+	}
+	return violations
+}
+
+// fieldName returns the name of the field as it's encoded
+func fieldName(f reflect.StructField) string {
+	if tag := f.Tag.Get("json"); len(tag) > 0 {
+		if name := strings.Split(tag, ",")[0]; len(name) > 0 && name != "-" {
+			return name
+		}
+	}
+	return f.Name
+}
+
+func validateStruct(v reflect.Value, prefix string, violations *Violations) {
+	t := v.Type()
+
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		// unexported
+		if len(f.PkgPath) > 0 {
+			continue
+		}
+
+		name := prefix + fieldName(f)
+		fv := v.Field(i)
+
+		if tag := f.Tag.Get("validate"); len(tag) > 0 {
+			if reason := check(fv, tag); len(reason) > 0 {
+				*violations = append(*violations, Violation{Field: name, Reason: reason})
+				continue
+			}
+		}
+
+		// validate the nested structs
+		for fv.Kind() == reflect.Ptr && !fv.IsNil() {
+			fv = fv.Elem()
+		}
+		if fv.Kind() == reflect.Struct {
+			if fv.CanAddr() {
+				if val, ok := fv.Addr().Interface().(Validator); ok {
+					for _, violation := range fromError(val.Validate()) {
+						violation.Field = strings.TrimSuffix(name+"."+violation.Field, ".")
+						*violations = append(*violations, violation)
+					}
+					continue
+				}
+			}
+			validateStruct(fv, name+".", violations)
+		}
+	}
+}
+
+// check returns the reason the value breaks the rules of the tag
+func check(v reflect.Value, tag string) string {
+	rules := strings.Split(tag, ",")
+
+	for i := 0; i < len(rules); i++ {
+		rule := rules[i]
+		arg := ""
+		if idx := strings.Index(rule, "="); idx > 0 {
+			rule, arg = rule[:idx], rule[idx+1:]
+		}
+		// the pattern may contain commas
+		if rule == "pattern" {
+			arg = strings.Join(append([]string{arg}, rules[i+1:]...), ",")
+			i = len(rules)
+		}
+
+		if rule == "required" {
+			if isZero(v) {
+				return "is required"
+			}
+			continue
+		}
+
+		// optional values are checked when set
+		if isZero(v) {
+			return ""
+		}
+
+		if reason := checkRule(v, rule, arg); len(reason) > 0 {
+			return reason
+		}
+	}
+
+	return ""
+}
+
+func isZero(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Slice, reflect.Map:
+		return v.Len() == 0
+	}
+	return v.IsZero()
+}
+
+func checkRule(v reflect.Value, rule, arg string) string {
+	for v.Kind() == reflect.Ptr {
+		v = v.Elem()
+	}
+
+	switch rule {
+	case "min", "max", "len":
+		n, err := strconv.ParseFloat(arg, 64)
+		if err != nil {
+			return fmt.Sprintf("invalid rule %s=%s", rule, arg)
+		}
+
+		var size float64
+		what := "length"
+		switch v.Kind() {
+		case reflect.String, reflect.Slice, reflect.Map, reflect.Array:
+			size = float64(v.Len())
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			size, what = float64(v.Int()), "value"
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			size, what = float64(v.Uint()), "value"
+		case reflect.Float32, reflect.Float64:
+			size, what = v.Float(), "value"
+		default:
+			return fmt.Sprintf("invalid rule %s for %s", rule, v.Kind())
+		}
+
+		switch {
+		case rule == "min" && size < n:
+			return fmt.Sprintf("%s must be at least %s", what, arg)
+		case rule == "max" && size > n:
+			return fmt.Sprintf("%s must be at most %s", what, arg)
+		case rule == "len" && size != n:
+			return fmt.Sprintf("%s must be %s", what, arg)
+		}
+	case "oneof":
+		value := fmt.Sprint(v.Interface())
+		for _, option := range strings.Fields(arg) {
+			if option == value {
+				return ""
+			}
+		}
+		return fmt.Sprintf("must be one of %s", arg)
+	case "pattern":
+		if v.Kind() != reflect.String {
+			return fmt.Sprintf("invalid rule %s for %s", rule, v.Kind())
+		}
+		re, err := pattern(arg)
+		if err != nil {
+			return fmt.Sprintf("invalid pattern %s", arg)
+		}
+		if !re.MatchString(v.String()) {
+			return fmt.Sprintf("must match %s", arg)
+		}
+	default:
+		return fmt.Sprintf("unknown rule %s", rule)
+	}
+
+	return ""
+}
+
+// pattern returns the compiled regexp of the pattern
+func pattern(p string) (*regexp.Regexp, error) {
+	if re, ok := patterns.Load(p); ok {
+		return re.(*regexp.Regexp), nil
+	}
+	re, err := regexp.Compile(p)
+	if err != nil {
+		return nil, err
+	}
+	patterns.Store(p, re)
+	return re, nil
+}
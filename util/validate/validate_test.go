@@ -0,0 +1,105 @@
+package validate
+
+import (
+	"errors"
+	"testing"
+)
+
+type testAddress struct {
+	City string `json:"city" validate:"required"`
+}
+
+type testUser struct {
+	Name    string            `json:"name" validate:"required,max=5"`
+	Age     int               `json:"age" validate:"min=18"`
+	Kind    string            `json:"kind" validate:"oneof=user group"`
+	Code    string            `json:"code" validate:"pattern=^[a-z]{2,3}$"`
+	Tags    []string          `json:"tags" validate:"len=2"`
+	Address *testAddress      `json:"address"`
+	Labels  map[string]string `validate:"required"`
+}
+
+type fieldErr struct {
+	field, reason string
+}
+
+func (f fieldErr) Error() string  { return f.field + ": " + f.reason }
+func (f fieldErr) Field() string  { return f.field }
+func (f fieldErr) Reason() string { return f.reason }
+
+type multiErr []error
+
+func (m multiErr) Error() string      { return "invalid" }
+func (m multiErr) AllErrors() []error { return m }
+
+type testMessage struct {
+	err error
+}
+
+func (m *testMessage) Validate() error {
+	return m.err
+}
+
+func TestValidateTags(t *testing.T) {
+	valid := &testUser{
+		Name:    "bob",
+		Age:     20,
+		Kind:    "user",
+		Code:    "ab",
+		Tags:    []string{"a", "b"},
+		Address: &testAddress{City: "london"},
+		Labels:  map[string]string{"a": "b"},
+	}
+	if v := Validate(valid); v != nil {
+		t.Fatalf("Expected no violations got %v", v)
+	}
+
+	// optional fields can be omitted
+	if v := Validate(&testUser{Name: "bob", Labels: map[string]string{"a": "b"}}); v != nil {
+		t.Fatalf("Expected no violations got %v", v)
+	}
+
+	invalid := &testUser{
+		Name:    "robert",
+		Age:     10,
+		Kind:    "admin",
+		Code:    "a,b",
+		Tags:    []string{"a"},
+		Address: &testAddress{},
+	}
+	expected := Violations{
+		{"name", "length must be at most 5"},
+		{"age", "value must be at least 18"},
+		{"kind", "must be one of user group"},
+		{"code", "must match ^[a-z]{2,3}$"},
+		{"tags", "length must be 2"},
+		{"address.city", "is required"},
+		{"Labels", "is required"},
+	}
+
+	v := Validate(invalid)
+	if len(v) != len(expected) {
+		t.Fatalf("Expected %d violations got %v", len(expected), v)
+	}
+	for i, e := range expected {
+		if v[i] != e {
+			t.Errorf("Expected %v got %v", e, v[i])
+		}
+	}
+}
+
+func TestValidateMethod(t *testing.T) {
+	if v := Validate(&testMessage{}); v != nil {
+		t.Fatalf("Expected no violations got %v", v)
+	}
+
+	v := Validate(&testMessage{err: fieldErr{"name", "value length must be at most 5 runes"}})
+	if len(v) != 1 || v[0].Field != "name" || v[0].Reason != "value length must be at most 5 runes" {
+		t.Fatalf("Unexpected violations %v", v)
+	}
+
+	v = Validate(&testMessage{err: multiErr{fieldErr{"name", "required"}, errors.New("invalid message")}})
+	if len(v) != 2 || v[0].Field != "name" || v[1].Reason != "invalid message" {
+		t.Fatalf("Unexpected violations %v", v)
+	}
+}
@@ -0,0 +1,167 @@
+package wrapper
+
+import (
+	"context"
+	"math/rand"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/micro/go-micro/v2/auth"
+	"github.com/micro/go-micro/v2/debug/trace"
+	"github.com/micro/go-micro/v2/errors"
+	"github.com/micro/go-micro/v2/logger"
+	"github.com/micro/go-micro/v2/metadata"
+	"github.com/micro/go-micro/v2/server"
+)
+
+// AccessLogOptions of the access log
+type AccessLogOptions struct {
+	// Logger the requests are logged to
+	Logger logger.Logger
+	// Level the requests are logged at
+	Level logger.Level
+	// Sample is the rate of the requests logged by status code from 0 to
+	// 1. The rate of the code 0 applies to the codes without one, all the
+	// requests are logged if it's not set.
+	Sample map[int32]float64
+	// Slow requests are logged regardless of the sampling
+	Slow time.Duration
+}
+
+// AccessLogOption sets values in AccessLogOptions
+type AccessLogOption func(o *AccessLogOptions)
+
+// AccessLogger sets the logger the requests are logged to
+func AccessLogger(l logger.Logger) AccessLogOption {
+	return func(o *AccessLogOptions) {
+		o.Logger = l
+	}
+}
+
+// AccessLevel sets the level the requests are logged at
+func AccessLevel(l logger.Level) AccessLogOption {
+	return func(o *AccessLogOptions) {
+		o.Level = l
+	}
+}
+
+// SampleStatus logs the rate of the requests with the status code e.g 0.01
+// of the successful requests with the code 200. The code 0 sets the rate of
+// the codes without one.
+func SampleStatus(code int32, rate float64) AccessLogOption {
+	return func(o *AccessLogOptions) {
+		if o.Sample == nil {
+			o.Sample = make(map[int32]float64)
+		}
+		o.Sample[code] = rate
+	}
+}
+
+// SlowRequests always logs the requests taking longer than the duration
+func SlowRequests(d time.Duration) AccessLogOption {
+	return func(o *AccessLogOptions) {
+		o.Slow = d
+	}
+}
+
+// sampled returns true if a request with the status code is logged
+func (o AccessLogOptions) sampled(code int32) bool {
+	rate, ok := o.Sample[code]
+	if !ok {
+		if rate, ok = o.Sample[0]; !ok {
+			return true
+		}
+	}
+	return rate >= 1 || rand.Float64() < rate
+}
+
+// statusCode of the outcome of a request, 200 if it succeeded
+func statusCode(err error) int32 {
+	if err == nil {
+		return 200
+	}
+	if code := errors.FromError(err).Code; code > 0 {
+		return code
+	}
+	return 500
+}
+
+// payloadSize of a request or response, only known for protobuf messages
+func payloadSize(v interface{}) (int64, bool) {
+	if m, ok := v.(proto.Message); ok {
+		return int64(proto.Size(m)), true
+	}
+	return 0, false
+}
+
+// AccessLogHandler logs a structured entry of each request served with its
+// caller, endpoint, status, latency, payload sizes and trace id e.g to log
+// the failures and 1% of the successful requests
+//
+//	wrapper.AccessLogHandler(
+//		wrapper.SampleStatus(200, 0.01),
+//		wrapper.SlowRequests(time.Second),
+//	)
+func AccessLogHandler(opts ...AccessLogOption) server.HandlerWrapper {
+	options := AccessLogOptions{
+		Logger: logger.DefaultLogger,
+		Level:  logger.InfoLevel,
+	}
+	for _, o := range opts {
+		o(&options)
+	}
+
+	return func(h server.HandlerFunc) server.HandlerFunc {
+		return func(ctx context.Context, req server.Request, rsp interface{}) error {
+			if !logger.V(options.Level, options.Logger) {
+				return h(ctx, req, rsp)
+			}
+
+			started := time.Now()
+			err := h(ctx, req, rsp)
+			latency := time.Since(started)
+
+			code := statusCode(err)
+			slow := options.Slow > 0 && latency >= options.Slow
+			if !slow && !options.sampled(code) {
+				return err
+			}
+
+			fields := map[string]interface{}{
+				"service":  req.Service(),
+				"endpoint": req.Endpoint(),
+				"status":   code,
+				"latency":  latency,
+			}
+			if caller, ok := metadata.Get(ctx, HeaderPrefix+"From-Service"); ok {
+				fields["caller"] = caller
+			}
+			if acc, ok := auth.AccountFromContext(ctx); ok && acc != nil {
+				fields["account"] = acc.ID
+			}
+			// the trace id is set without a span id unless the caller traces
+			if id, _, _ := trace.FromContext(ctx); len(id) > 0 {
+				fields["trace_id"] = id
+			}
+			if id, ok := trace.RequestID(ctx); ok {
+				fields["request_id"] = id
+			}
+			if n, ok := payloadSize(req.Body()); ok {
+				fields["request_size"] = n
+			}
+			if n, ok := payloadSize(rsp); ok && err == nil {
+				fields["response_size"] = n
+			}
+			if err != nil {
+				fields["error"] = errors.FromError(err).Detail
+			}
+			if slow {
+				fields["slow"] = true
+			}
+
+			options.Logger.Fields(fields).Log(options.Level, "served request")
+
+			return err
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package wrapper
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/micro/go-micro/v2/auth"
+	debug "github.com/micro/go-micro/v2/debug/service/proto"
+	"github.com/micro/go-micro/v2/errors"
+	"github.com/micro/go-micro/v2/logger"
+	"github.com/micro/go-micro/v2/metadata"
+	"github.com/micro/go-micro/v2/server"
+)
+
+type accessTestLogger struct {
+	entries []map[string]interface{}
+	fields  map[string]interface{}
+	parent  *accessTestLogger
+
+	logger.Logger
+}
+
+func (l *accessTestLogger) Options() logger.Options {
+	return logger.Options{Level: logger.InfoLevel}
+}
+
+func (l *accessTestLogger) Fields(fields map[string]interface{}) logger.Logger {
+	return &accessTestLogger{fields: fields, parent: l}
+}
+
+func (l *accessTestLogger) Log(level logger.Level, v ...interface{}) {
+	l.parent.entries = append(l.parent.entries, l.fields)
+}
+
+func TestAccessLogHandler(t *testing.T) {
+	l := new(accessTestLogger)
+
+	var delay time.Duration
+	h := AccessLogHandler(
+		AccessLogger(l),
+		SampleStatus(200, 0),
+		SlowRequests(50*time.Millisecond),
+	)(func(ctx context.Context, req server.Request, rsp interface{}) error {
+		time.Sleep(delay)
+		if req.Endpoint() == "Debug.Fail" {
+			return errors.NotFound("go.micro.service.foo", "not found")
+		}
+		rsp.(*debug.HealthResponse).Status = "ok"
+		return nil
+	})
+
+	ctx := metadata.NewContext(context.TODO(), metadata.Metadata{
+		"Micro-From-Service": "go.micro.service.bar",
+		"Micro-Trace-Id":     "trace",
+	})
+	ctx = auth.ContextWithAccount(ctx, &auth.Account{ID: "alice"})
+	req := sizeTestRequest{
+		testRequest: testRequest{service: "go.micro.service.foo", endpoint: "Debug.Health"},
+		body:        &debug.HealthRequest{Service: "go.micro.service.foo"},
+	}
+
+	// the successful requests aren't sampled
+	if err := h(ctx, req, &debug.HealthResponse{}); err != nil {
+		t.Fatal(err)
+	}
+	if len(l.entries) != 0 {
+		t.Fatalf("Expected the request not to be logged got %v", l.entries)
+	}
+
+	req.endpoint = "Debug.Fail"
+	h(ctx, req, &debug.HealthResponse{})
+	if len(l.entries) != 1 {
+		t.Fatalf("Expected the failure to be logged got %d entries", len(l.entries))
+	}
+	e := l.entries[0]
+	if e["status"] != int32(404) || e["caller"] != "go.micro.service.bar" || e["account"] != "alice" || e["trace_id"] != "trace" {
+		t.Fatalf("Unexpected entry %v", e)
+	}
+	if e["request_size"] == nil || e["response_size"] != nil {
+		t.Fatalf("Expected only the request size of the failure got %v", e)
+	}
+
+	// the slow requests are always logged
+	req.endpoint = "Debug.Health"
+	delay = 60 * time.Millisecond
+	if err := h(ctx, req, &debug.HealthResponse{}); err != nil {
+		t.Fatal(err)
+	}
+	if len(l.entries) != 2 || l.entries[1]["slow"] != true || l.entries[1]["response_size"] == nil {
+		t.Fatalf("Expected the slow request to be logged got %v", l.entries)
+	}
+}
@@ -2,16 +2,28 @@ package wrapper
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"reflect"
 	"strings"
+	"sync"
+	"time"
 
+	"github.com/golang/protobuf/proto"
 	"github.com/micro/go-micro/v2/auth"
 	"github.com/micro/go-micro/v2/client"
+	"github.com/micro/go-micro/v2/debug/cost"
+	"github.com/micro/go-micro/v2/debug/size"
 	"github.com/micro/go-micro/v2/debug/stats"
 	"github.com/micro/go-micro/v2/debug/trace"
 	"github.com/micro/go-micro/v2/errors"
+	"github.com/micro/go-micro/v2/logger"
 	"github.com/micro/go-micro/v2/metadata"
 	"github.com/micro/go-micro/v2/server"
+	"github.com/micro/go-micro/v2/store"
+	"github.com/micro/go-micro/v2/util/redact"
+	"github.com/micro/go-micro/v2/util/validate"
 )
 
 type fromServiceWrapper struct {
@@ -71,17 +83,48 @@ func HandlerStats(stats stats.Stats) server.HandlerWrapper {
 	}
 }
 
+// TraceOptions of the tracing wrappers
+type TraceOptions struct {
+	// Payloads adds the requests and messages to the spans with their
+	// sensitive fields masked. Only the fields tagged as sensitive are
+	// masked so it's off by default.
+	Payloads bool
+}
+
+// TraceOption sets values in TraceOptions
+type TraceOption func(o *TraceOptions)
+
+// TracePayloads adds the requests and messages to the spans, masking the
+// fields tagged as sensitive
+func TracePayloads() TraceOption {
+	return func(o *TraceOptions) {
+		o.Payloads = true
+	}
+}
+
+func newTraceOptions(opts ...TraceOption) TraceOptions {
+	var options TraceOptions
+	for _, o := range opts {
+		o(&options)
+	}
+	return options
+}
+
 type traceWrapper struct {
 	client.Client
 
 	name  string
 	trace trace.Tracer
+	opts  TraceOptions
 }
 
 func (c *traceWrapper) Call(ctx context.Context, req client.Request, rsp interface{}, opts ...client.CallOption) error {
 	newCtx, s := c.trace.Start(ctx, req.Service()+"."+req.Endpoint())
 
 	s.Type = trace.SpanTypeRequestOutbound
+	if c.opts.Payloads {
+		s.Metadata["request"] = redact.String(req.Body())
+	}
 	err := c.Client.Call(newCtx, req, rsp, opts...)
 	if err != nil {
 		s.Metadata["error"] = err.Error()
@@ -93,17 +136,68 @@ func (c *traceWrapper) Call(ctx context.Context, req client.Request, rsp interfa
 	return err
 }
 
+func (c *traceWrapper) Publish(ctx context.Context, p client.Message, opts ...client.PublishOption) error {
+	newCtx, s := c.trace.Start(ctx, p.Topic())
+
+	s.Type = trace.SpanTypeMessageOutbound
+	if c.opts.Payloads {
+		s.Metadata["message"] = redact.String(p.Payload())
+	}
+	err := c.Client.Publish(newCtx, p, opts...)
+	if err != nil {
+		s.Metadata["error"] = err.Error()
+	}
+
+	// finish the trace
+	c.trace.Finish(s)
+
+	return err
+}
+
 // TraceCall is a call tracing wrapper
-func TraceCall(name string, t trace.Tracer, c client.Client) client.Client {
+func TraceCall(name string, t trace.Tracer, c client.Client, opts ...TraceOption) client.Client {
 	return &traceWrapper{
 		name:   name,
 		trace:  t,
+		opts:   newTraceOptions(opts...),
 		Client: c,
 	}
 }
 
+// LogHandler logs the requests served at the debug level with their payloads,
+// masking their sensitive fields
+func LogHandler() server.HandlerWrapper {
+	return func(h server.HandlerFunc) server.HandlerFunc {
+		return func(ctx context.Context, req server.Request, rsp interface{}) error {
+			if !logger.V(logger.DebugLevel, logger.DefaultLogger) {
+				return h(ctx, req, rsp)
+			}
+
+			started := time.Now()
+			err := h(ctx, req, rsp)
+
+			fields := map[string]interface{}{
+				"service":  req.Service(),
+				"endpoint": req.Endpoint(),
+				"request":  redact.String(req.Body()),
+				"duration": time.Since(started),
+			}
+			if err != nil {
+				fields["error"] = err.Error()
+			} else {
+				fields["response"] = redact.String(rsp)
+			}
+			logger.Fields(fields).Log(logger.DebugLevel, "served request")
+
+			return err
+		}
+	}
+}
+
 // TraceHandler wraps a server handler to perform tracing
-func TraceHandler(t trace.Tracer) server.HandlerWrapper {
+func TraceHandler(t trace.Tracer, opts ...TraceOption) server.HandlerWrapper {
+	options := newTraceOptions(opts...)
+
 	// return a handler wrapper
 	return func(h server.HandlerFunc) server.HandlerFunc {
 		// return a function that returns a function
@@ -116,6 +210,9 @@ func TraceHandler(t trace.Tracer) server.HandlerWrapper {
 			// get the span
 			newCtx, s := t.Start(ctx, req.Service()+"."+req.Endpoint())
 			s.Type = trace.SpanTypeRequestInbound
+			if options.Payloads {
+				s.Metadata["request"] = redact.String(req.Body())
+			}
 
 			err := h(newCtx, req, rsp)
 			if err != nil {
@@ -130,40 +227,79 @@ func TraceHandler(t trace.Tracer) server.HandlerWrapper {
 	}
 }
 
+// TraceSubscriber wraps a subscriber to perform tracing. Spans are part of
+// the trace of the request which published the message.
+func TraceSubscriber(t trace.Tracer, opts ...TraceOption) server.SubscriberWrapper {
+	options := newTraceOptions(opts...)
+
+	return func(fn server.SubscriberFunc) server.SubscriberFunc {
+		return func(ctx context.Context, msg server.Message) error {
+			// get the span
+			newCtx, s := t.Start(ctx, msg.Topic())
+			s.Type = trace.SpanTypeMessageInbound
+			if options.Payloads {
+				s.Metadata["message"] = redact.String(msg.Payload())
+			}
+
+			err := fn(newCtx, msg)
+			if err != nil {
+				s.Metadata["error"] = err.Error()
+			}
+
+			// finish
+			t.Finish(s)
+
+			return err
+		}
+	}
+}
+
 type authWrapper struct {
 	client.Client
 	auth func() auth.Auth
+
+	sync.Mutex
+	// delegated tokens by the token of the subject
+	delegated map[string]*auth.Token
 }
 
 func (a *authWrapper) Call(ctx context.Context, req client.Request, rsp interface{}, opts ...client.CallOption) error {
-	ctx = a.wrapContext(ctx, opts...)
+	ctx, err := a.wrapContext(ctx, opts...)
+	if err != nil {
+		return err
+	}
 	return a.Client.Call(ctx, req, rsp, opts...)
 }
 
 func (a *authWrapper) Stream(ctx context.Context, req client.Request, opts ...client.CallOption) (client.Stream, error) {
-	ctx = a.wrapContext(ctx, opts...)
+	ctx, err := a.wrapContext(ctx, opts...)
+	if err != nil {
+		return nil, err
+	}
 	return a.Client.Stream(ctx, req, opts...)
 }
 
-func (a *authWrapper) wrapContext(ctx context.Context, opts ...client.CallOption) context.Context {
+func (a *authWrapper) wrapContext(ctx context.Context, opts ...client.CallOption) (context.Context, error) {
 	// parse the options
 	var options client.CallOptions
 	for _, o := range opts {
 		o(&options)
 	}
 
-	// check to see if the authorization header has already been set.
-	// We dont't override the header unless the ServiceToken option has
-	// been specified or the header wasn't provided
-	if _, ok := metadata.Get(ctx, "Authorization"); ok && !options.ServiceToken {
-		return ctx
-	}
-
 	// if auth is nil we won't be able to get an access token, so we execute
 	// the request without one.
 	aa := a.auth()
 	if aa == nil {
-		return ctx
+		return ctx, nil
+	}
+
+	// check to see if the authorization header has already been set.
+	// We dont't override the header unless the ServiceToken option has
+	// been specified or the header wasn't provided. A header of another
+	// account e.g the end user is exchanged for a token delegated to this
+	// service if the auth supports it.
+	if header, ok := metadata.Get(ctx, "Authorization"); ok && !options.ServiceToken {
+		return a.delegate(ctx, aa, header)
 	}
 
 	// set the namespace header if it has not been set (e.g. on a service to service request)
@@ -175,16 +311,61 @@ func (a *authWrapper) wrapContext(ctx context.Context, opts ...client.CallOption
 	aaOpts := aa.Options()
 	if aaOpts.Token != nil && !aaOpts.Token.Expired() {
 		ctx = metadata.Set(ctx, "Authorization", auth.BearerScheme+aaOpts.Token.AccessToken)
-		return ctx
+		return ctx, nil
 	}
 
 	// call without an auth token
-	return ctx
+	return ctx, nil
 }
 
-// AuthClient wraps requests with the auth header
-func AuthClient(auth func() auth.Auth, c client.Client) client.Client {
-	return &authWrapper{c, auth}
+// delegate replaces the token in the header with one delegated to the
+// service. The header is forwarded as is if the auth can't exchange tokens
+// or the service has no token to act with.
+func (a *authWrapper) delegate(ctx context.Context, aa auth.Auth, header string) (context.Context, error) {
+	if _, ok := aa.(auth.Exchanger); !ok || !strings.HasPrefix(header, auth.BearerScheme) {
+		return ctx, nil
+	}
+	actor := aa.Options().Token
+	if actor == nil || actor.Expired() {
+		return ctx, nil
+	}
+	subject := strings.TrimPrefix(header, auth.BearerScheme)
+	// the service's own token isn't exchanged
+	if subject == actor.AccessToken {
+		return ctx, nil
+	}
+
+	a.Lock()
+	tok, ok := a.delegated[subject]
+	a.Unlock()
+
+	if !ok || tok.Expired() {
+		var err error
+		tok, err = auth.Exchange(aa, subject, auth.WithActor(actor.AccessToken))
+		if err != nil {
+			return ctx, errors.Unauthorized("go.micro.client", "could not exchange the token: %v", err)
+		}
+
+		a.Lock()
+		// drop the expired tokens so the subjects which stopped calling
+		// aren't kept
+		for k, t := range a.delegated {
+			if t.Expired() {
+				delete(a.delegated, k)
+			}
+		}
+		a.delegated[subject] = tok
+		a.Unlock()
+	}
+
+	return metadata.Set(ctx, "Authorization", auth.BearerScheme+tok.AccessToken), nil
+}
+
+// AuthClient wraps requests with the auth header. The token of another
+// account in the header is exchanged for a token delegated to the service
+// if the auth is an auth.Exchanger.
+func AuthClient(fn func() auth.Auth, c client.Client) client.Client {
+	return &authWrapper{Client: c, auth: fn, delegated: make(map[string]*auth.Token)}
 }
 
 // AuthHandler wraps a server handler to perform auth
@@ -213,7 +394,7 @@ func AuthHandler(fn func() auth.Auth) server.HandlerWrapper {
 			}
 
 			// Inspect the token and decode an account
-			account, _ := a.Inspect(token)
+			account, _ := auth.InspectContext(ctx, a, token)
 
 			// Extract the namespace header
 			ns, ok := metadata.Get(ctx, "Micro-Namespace")
@@ -323,3 +504,206 @@ func (s *staticClient) Stream(ctx context.Context, req client.Request, opts ...c
 func StaticClient(address string, c client.Client) client.Client {
 	return &staticClient{address, c}
 }
+
+// CostHandler wraps a server handler to report the cost of the request
+// to the caller in the response metadata
+func CostHandler() server.HandlerWrapper {
+	return func(h server.HandlerFunc) server.HandlerFunc {
+		return func(ctx context.Context, req server.Request, rsp interface{}) error {
+			c := new(cost.Cost)
+			ctx = cost.NewContext(ctx, c)
+
+			started := time.Now()
+			err := h(ctx, req, rsp)
+			c.Duration = time.Since(started)
+
+			// sizes are only known for protobuf messages
+			if m, ok := req.Body().(proto.Message); ok {
+				c.RequestBytes = int64(proto.Size(m))
+			}
+			if m, ok := rsp.(proto.Message); ok && err == nil {
+				c.ResponseBytes = int64(proto.Size(m))
+			}
+
+			for k, v := range c.Header() {
+				server.SetResponseMetadata(ctx, k, v)
+			}
+
+			return err
+		}
+	}
+}
+
+// SizeHandler wraps a server handler to record the size of the request
+// and response payloads of each endpoint
+func SizeHandler(r *size.Recorder) server.HandlerWrapper {
+	return func(h server.HandlerFunc) server.HandlerFunc {
+		return func(ctx context.Context, req server.Request, rsp interface{}) error {
+			err := h(ctx, req, rsp)
+
+			s := size.Sample{
+				Endpoint: req.Endpoint(),
+				Time:     time.Now(),
+			}
+			s.Caller, _ = metadata.Get(ctx, HeaderPrefix+"From-Service")
+
+			// sizes are only known for protobuf messages
+			s.Request, _ = payloadSize(req.Body())
+			if err == nil {
+				s.Response, _ = payloadSize(rsp)
+			}
+
+			r.Record(s)
+
+			return err
+		}
+	}
+}
+
+type costWrapper struct {
+	client.Client
+	ledger *cost.Ledger
+}
+
+func (c *costWrapper) Call(ctx context.Context, req client.Request, rsp interface{}, opts ...client.CallOption) error {
+	// count the call against the request being served
+	cost.AddCall(ctx)
+
+	var md metadata.Metadata
+	err := c.Client.Call(ctx, req, rsp, append(opts, client.WithResponseMetadata(&md))...)
+
+	if rc, ok := cost.FromHeader(md); ok {
+		c.ledger.Record(req.Service(), req.Endpoint(), rc)
+	}
+
+	return err
+}
+
+func (c *costWrapper) Stream(ctx context.Context, req client.Request, opts ...client.CallOption) (client.Stream, error) {
+	cost.AddCall(ctx)
+	return c.Client.Stream(ctx, req, opts...)
+}
+
+// CostClient wraps a client to record the costs reported by servers in the ledger
+func CostClient(l *cost.Ledger, c client.Client) client.Client {
+	return &costWrapper{c, l}
+}
+
+// idempotentRecord is stored for an idempotency key, it has no response while
+// the first request made with the key is in progress
+type idempotentRecord struct {
+	Hash     string          `json:"hash"`
+	Response json.RawMessage `json:"response,omitempty"`
+}
+
+// IdempotentHandler wraps a server handler to deduplicate the requests made
+// with an idempotency key. The keys are scoped to the account of the caller.
+// The response of the first request with a key is stored for the window and
+// returned to the requests made with the key after it. Requests made with the
+// key while the first is in progress are rejected with a conflict, and those
+// whose body differs from the first with an unprocessable entity error. Only
+// the successful responses are stored so the requests which failed can be
+// retried. The request in progress is marked with a compare and swap so the
+// duplicates handled by other instances are rejected, stores without compare
+// and swap only check the key before marking it.
+func IdempotentHandler(s store.Store, window time.Duration) server.HandlerWrapper {
+	return func(h server.HandlerFunc) server.HandlerFunc {
+		return func(ctx context.Context, req server.Request, rsp interface{}) error {
+			id, ok := metadata.Get(ctx, client.IdempotencyKeyHeader)
+			if !ok || len(id) == 0 {
+				return h(ctx, req, rsp)
+			}
+
+			var account string
+			if acc, ok := auth.AccountFromContext(ctx); ok {
+				account = acc.ID
+			}
+			key := "idempotency/" + account + "/" + req.Service() + "/" + req.Endpoint() + "/" + id
+
+			body, err := json.Marshal(req.Body())
+			if err != nil {
+				return errors.InternalServerError(req.Service(), "error encoding request %s: %v", id, err)
+			}
+			sum := sha256.Sum256(body)
+			hash := hex.EncodeToString(sum[:])
+
+			marker, _ := json.Marshal(&idempotentRecord{Hash: hash})
+			rec := &store.Record{Key: key, Value: marker, Expiry: window}
+
+			err = store.CompareAndSwap(s, rec, nil)
+			if err == store.ErrCASNotSupported {
+				if recs, rerr := s.Read(key); rerr == nil && len(recs) > 0 {
+					err = store.ErrConflict
+				} else {
+					err = s.Write(rec)
+				}
+			}
+
+			switch {
+			case err == store.ErrConflict:
+				recs, err := s.Read(key)
+				if err != nil || len(recs) == 0 {
+					return errors.Conflict(req.Service(), "request %s in progress", id)
+				}
+				var stored idempotentRecord
+				if err := json.Unmarshal(recs[0].Value, &stored); err != nil {
+					return errors.InternalServerError(req.Service(), "error decoding request %s: %v", id, err)
+				}
+				if stored.Hash != hash {
+					return errors.New(req.Service(), "idempotency key "+id+" reused for another request", 422)
+				}
+				if len(stored.Response) == 0 {
+					return errors.Conflict(req.Service(), "request %s in progress", id)
+				}
+				return json.Unmarshal(stored.Response, rsp)
+			case err != nil:
+				return errors.InternalServerError(req.Service(), "error storing request %s: %v", id, err)
+			}
+
+			if err := h(ctx, req, rsp); err != nil {
+				// release the key so the request can be retried
+				if derr := s.Delete(key); derr != nil {
+					logger.Errorf("Error releasing request %s: %v", id, derr)
+				}
+				return err
+			}
+
+			b, err := json.Marshal(rsp)
+			if err == nil {
+				rec.Value, err = json.Marshal(&idempotentRecord{Hash: hash, Response: b})
+			}
+			if err != nil {
+				logger.Errorf("Error encoding the response of request %s: %v", id, err)
+				if derr := s.Delete(key); derr != nil {
+					logger.Errorf("Error releasing request %s: %v", id, derr)
+				}
+				return nil
+			}
+			if err := s.Write(rec); err != nil {
+				logger.Errorf("Error storing the response of request %s: %v", id, err)
+			}
+
+			return nil
+		}
+	}
+}
+
+// ValidateHandler wraps a server handler to reject the requests breaking their
+// validation rules before the handler runs. The BadRequest error returned has
+// the validate.Violations of the fields as a detail.
+func ValidateHandler() server.HandlerWrapper {
+	return func(h server.HandlerFunc) server.HandlerFunc {
+		return func(ctx context.Context, req server.Request, rsp interface{}) error {
+			violations := validate.Validate(req.Body())
+			if len(violations) == 0 {
+				return h(ctx, req, rsp)
+			}
+
+			err := errors.BadRequest(req.Service(), "invalid request: %v", violations).(*errors.Error)
+			if derr := err.AddDetail(violations); derr != nil {
+				return errors.InternalServerError(req.Service(), "invalid request: %v", derr)
+			}
+			return err
+		}
+	}
+}
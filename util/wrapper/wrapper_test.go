@@ -4,14 +4,24 @@ import (
 	"context"
 	"net/http"
 	"reflect"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/micro/go-micro/v2/auth"
+	jwtAuth "github.com/micro/go-micro/v2/auth/jwt"
 	"github.com/micro/go-micro/v2/client"
+	"github.com/micro/go-micro/v2/debug/cost"
+	debug "github.com/micro/go-micro/v2/debug/service/proto"
+	"github.com/micro/go-micro/v2/debug/size"
+	"github.com/micro/go-micro/v2/debug/trace"
+	memTrace "github.com/micro/go-micro/v2/debug/trace/memory"
 	"github.com/micro/go-micro/v2/errors"
 	"github.com/micro/go-micro/v2/metadata"
 	"github.com/micro/go-micro/v2/server"
+	"github.com/micro/go-micro/v2/store"
+	"github.com/micro/go-micro/v2/store/memory"
+	"github.com/micro/go-micro/v2/util/validate"
 )
 
 func TestWrapper(t *testing.T) {
@@ -444,3 +454,395 @@ func TestCacheWrapper(t *testing.T) {
 		}
 	})
 }
+
+type authTestClient struct {
+	headers []string
+	client.Client
+}
+
+func (c *authTestClient) Call(ctx context.Context, req client.Request, rsp interface{}, opts ...client.CallOption) error {
+	header, _ := metadata.Get(ctx, "Authorization")
+	c.headers = append(c.headers, header)
+	return nil
+}
+
+func TestAuthClientDelegation(t *testing.T) {
+	a := jwtAuth.NewAuth(jwtAuth.RotateEvery(time.Hour))
+	user, err := a.Generate("alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	svc, err := a.Generate("go.micro.srv.notes", auth.WithType("service"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tok, err := a.Token(auth.WithCredentials(svc.ID, svc.Secret))
+	if err != nil {
+		t.Fatal(err)
+	}
+	a.Init(auth.ClientToken(tok))
+
+	cli := new(authTestClient)
+	w := AuthClient(func() auth.Auth { return a }, cli)
+	req := client.NewRequest("go.micro.srv.users", "Users.Read", nil)
+
+	// the user's token is exchanged once and reused
+	ctx := metadata.Set(context.TODO(), "Authorization", auth.BearerScheme+user.Secret)
+	for i := 0; i < 2; i++ {
+		if err := w.Call(ctx, req, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if cli.headers[0] == auth.BearerScheme+user.Secret || cli.headers[0] != cli.headers[1] {
+		t.Fatalf("Expected a cached delegated token got %v", cli.headers)
+	}
+	acc, err := a.Inspect(strings.TrimPrefix(cli.headers[0], auth.BearerScheme))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if actors := auth.Actors(acc); acc.ID != "alice" || len(actors) != 1 || actors[0] != svc.ID {
+		t.Fatalf("Expected alice through %v got %v through %v", svc.ID, acc.ID, actors)
+	}
+
+	// the service token is used without a user
+	if err := w.Call(context.TODO(), req, nil); err != nil {
+		t.Fatal(err)
+	}
+	if cli.headers[2] != auth.BearerScheme+tok.AccessToken {
+		t.Fatalf("Expected the service token got %v", cli.headers[2])
+	}
+
+	// an invalid token fails the call rather than being forwarded
+	ctx = metadata.Set(context.TODO(), "Authorization", auth.BearerScheme+"invalid")
+	if err := w.Call(ctx, req, nil); err == nil {
+		t.Fatal("Expected an error exchanging an invalid token")
+	}
+}
+
+type costTestClient struct {
+	header map[string]string
+	client.Client
+}
+
+func (c *costTestClient) Call(ctx context.Context, req client.Request, rsp interface{}, opts ...client.CallOption) error {
+	var options client.CallOptions
+	for _, o := range opts {
+		o(&options)
+	}
+	if options.ResponseMetadata != nil {
+		*options.ResponseMetadata = c.header
+	}
+	return nil
+}
+
+func TestCostWrapper(t *testing.T) {
+	req := client.NewRequest("go.micro.service.foo", "Foo.Bar", nil)
+	rc := &cost.Cost{Duration: time.Millisecond, RequestBytes: 10, ResponseBytes: 20, Calls: 1}
+
+	ledger := cost.NewLedger()
+	w := CostClient(ledger, &costTestClient{header: rc.Header()})
+
+	// calls made while serving a request are counted against it
+	served := new(cost.Cost)
+	ctx := cost.NewContext(context.TODO(), served)
+
+	for i := 0; i < 2; i++ {
+		if err := w.Call(ctx, req, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if served.Calls != 2 {
+		t.Errorf("Expected 2 downstream calls, got %d", served.Calls)
+	}
+
+	usage := ledger.Read()
+	if len(usage) != 1 {
+		t.Fatalf("Expected usage for 1 endpoint, got %d", len(usage))
+	}
+	if u := usage[0]; u.Requests != 2 || u.Total.Duration != 2*time.Millisecond || u.Total.ResponseBytes != 40 {
+		t.Errorf("Unexpected usage %+v", u)
+	}
+
+	// servers which don't report a cost are not recorded
+	ledger.Reset()
+	w = CostClient(ledger, &costTestClient{})
+	if err := w.Call(ctx, req, nil); err != nil {
+		t.Fatal(err)
+	}
+	if len(ledger.Read()) != 0 {
+		t.Errorf("Expected no usage to be recorded")
+	}
+}
+
+type testMessage struct {
+	server.Message
+	topic  string
+	header map[string]string
+}
+
+func (m *testMessage) Topic() string {
+	return m.topic
+}
+
+func (m *testMessage) Header() map[string]string {
+	return m.header
+}
+
+func (m *testMessage) Payload() interface{} {
+	return nil
+}
+
+func TestTraceSubscriber(t *testing.T) {
+	tr := memTrace.NewTracer()
+
+	// the span of the request publishing the message
+	ctx, span := tr.Start(context.TODO(), "Foo.Bar")
+	header := make(map[string]string)
+	trace.Inject(ctx, header)
+
+	if len(header["Micro-Request-Id"]) == 0 {
+		t.Fatal("Expected a request id to be injected")
+	}
+
+	// some transports lower case the header keys
+	lower := make(map[string]string)
+	for k, v := range header {
+		lower[strings.ToLower(k)] = v
+	}
+
+	sub := TraceSubscriber(tr)(func(ctx context.Context, msg server.Message) error {
+		if id, _ := trace.RequestID(ctx); id != header["Micro-Request-Id"] {
+			t.Errorf("Expected request id %s got %s", header["Micro-Request-Id"], id)
+		}
+		return nil
+	})
+
+	msg := &testMessage{topic: "foo.events", header: lower}
+	if err := sub(trace.Extract(context.TODO(), lower), msg); err != nil {
+		t.Fatal(err)
+	}
+
+	spans, err := tr.Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(spans) != 1 {
+		t.Fatalf("Expected 1 span got %d", len(spans))
+	}
+	if s := spans[0]; s.Trace != span.Trace || s.Parent != span.Id || s.Type != trace.SpanTypeMessageInbound {
+		t.Errorf("Expected a child span of %s in trace %s got %+v", span.Id, span.Trace, s)
+	}
+}
+
+type sizeTestRequest struct {
+	testRequest
+	body interface{}
+}
+
+func (r sizeTestRequest) Body() interface{} {
+	return r.body
+}
+
+func TestSizeHandler(t *testing.T) {
+	r := size.NewRecorder(10)
+
+	h := SizeHandler(r)(func(ctx context.Context, req server.Request, rsp interface{}) error {
+		rsp.(*debug.HealthResponse).Status = "ok"
+		return nil
+	})
+
+	ctx := metadata.NewContext(context.TODO(), metadata.Metadata{"Micro-From-Service": "go.micro.service.bar"})
+	req := sizeTestRequest{
+		testRequest: testRequest{service: "go.micro.service.foo", endpoint: "Debug.Health"},
+		body:        &debug.HealthRequest{Service: "go.micro.service.foo"},
+	}
+
+	if err := h(ctx, req, &debug.HealthResponse{}); err != nil {
+		t.Fatal(err)
+	}
+
+	top := r.Top(1)
+	if len(top) != 1 {
+		t.Fatalf("Expected 1 sample got %d", len(top))
+	}
+	if s := top[0]; s.Endpoint != "Debug.Health" || s.Caller != "go.micro.service.bar" || s.Request != 22 || s.Response != 4 {
+		t.Fatalf("Unexpected sample %+v", s)
+	}
+}
+
+type idempotentRsp struct {
+	Count int `json:"count"`
+}
+
+func TestIdempotentHandler(t *testing.T) {
+	var count int
+	var started, release chan bool
+	h := func(ctx context.Context, req server.Request, rsp interface{}) error {
+		if release != nil {
+			started <- true
+			<-release
+		}
+		count++
+		rsp.(*idempotentRsp).Count = count
+		return nil
+	}
+
+	handler := IdempotentHandler(memory.NewStore(), time.Minute)(h)
+	req := sizeTestRequest{
+		testRequest: testRequest{service: "svc.foo", endpoint: "Foo.Bar"},
+		body:        &validateReq{Name: "foo"},
+	}
+
+	call := func(ctx context.Context) (int, error) {
+		var rsp idempotentRsp
+		err := handler(ctx, req, &rsp)
+		return rsp.Count, err
+	}
+
+	// requests without a key aren't deduplicated
+	for i := 1; i <= 2; i++ {
+		if n, err := call(context.Background()); err != nil || n != i {
+			t.Fatalf("Expected count %d got %d, %v", i, n, err)
+		}
+	}
+
+	ctx := metadata.Set(context.Background(), client.IdempotencyKeyHeader, "abc")
+	for i := 0; i < 2; i++ {
+		if n, err := call(ctx); err != nil || n != 3 {
+			t.Fatalf("Expected the stored count 3 got %d, %v", n, err)
+		}
+	}
+
+	// duplicates of a request in progress conflict
+	started, release = make(chan bool), make(chan bool)
+	ctx = metadata.Set(context.Background(), client.IdempotencyKeyHeader, "def")
+	done := make(chan error)
+	go func() {
+		_, err := call(ctx)
+		done <- err
+	}()
+
+	<-started
+	_, err := call(ctx)
+	if verr := errors.FromError(err); verr.Code != http.StatusConflict {
+		t.Fatalf("Expected a conflict got %v", err)
+	}
+
+	close(release)
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+
+	// the keys are scoped to the account of the caller
+	release = nil
+	ctx = metadata.Set(context.Background(), client.IdempotencyKeyHeader, "abc")
+	ctx = auth.ContextWithAccount(ctx, &auth.Account{ID: "bob"})
+	want := count + 1
+	if n, err := call(ctx); err != nil || n != want {
+		t.Fatalf("Expected count %d got %d, %v", want, n, err)
+	}
+
+	// the key can't be reused for another request
+	req.body = &validateReq{Name: "bar"}
+	_, err = call(ctx)
+	if verr := errors.FromError(err); verr.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("Expected an unprocessable entity got %v", err)
+	}
+
+	// stores without compare and swap are checked before the request
+	handler = IdempotentHandler(noCASStore{memory.NewStore()}, time.Minute)(h)
+	ctx = metadata.Set(context.Background(), client.IdempotencyKeyHeader, "ghi")
+	want = count + 1
+	for i := 0; i < 2; i++ {
+		if n, err := call(ctx); err != nil || n != want {
+			t.Fatalf("Expected the stored count %d got %d, %v", want, n, err)
+		}
+	}
+}
+
+// noCASStore hides the compare and swap of the store
+type noCASStore struct {
+	store.Store
+}
+
+type validateReq struct {
+	Name string `json:"name" validate:"required"`
+}
+
+func TestValidateHandler(t *testing.T) {
+	var called bool
+	h := ValidateHandler()(func(ctx context.Context, req server.Request, rsp interface{}) error {
+		called = true
+		return nil
+	})
+
+	req := sizeTestRequest{
+		testRequest: testRequest{service: "go.micro.service.foo", endpoint: "Foo.Bar"},
+		body:        &validateReq{},
+	}
+
+	// the handler isn't called for invalid requests
+	err := h(context.TODO(), req, nil)
+	if called {
+		t.Fatal("Expected the handler not to be called")
+	}
+
+	var violations validate.Violations
+	if e := errors.Parse(err.Error()); e.Code != 400 || !e.ScanDetail(&violations) {
+		t.Fatalf("Expected a 400 error with the violations got %v", err)
+	}
+	if len(violations) != 1 || violations[0].Field != "name" {
+		t.Fatalf("Unexpected violations %v", violations)
+	}
+
+	req.body = &validateReq{Name: "foo"}
+	if err := h(context.TODO(), req, nil); err != nil || !called {
+		t.Fatalf("Expected the handler to be called got %v", err)
+	}
+}
+
+type loginReq struct {
+	User     string `json:"user"`
+	Password string `json:"password" redact:"true"`
+}
+
+func TestTraceHandlerRedact(t *testing.T) {
+	tr := memTrace.NewTracer()
+
+	fn := func(ctx context.Context, req server.Request, rsp interface{}) error {
+		return nil
+	}
+
+	req := sizeTestRequest{
+		testRequest: testRequest{service: "go.micro.service.foo", endpoint: "Auth.Login"},
+		body:        &loginReq{User: "john", Password: "secret"},
+	}
+	if err := TraceHandler(tr)(fn)(context.TODO(), req, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := TraceHandler(tr, TracePayloads())(fn)(context.TODO(), req, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	spans, err := tr.Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(spans) != 2 {
+		t.Fatalf("Expected 2 spans got %d", len(spans))
+	}
+
+	var payloads []string
+	for _, s := range spans {
+		if r, ok := s.Metadata["request"]; ok {
+			payloads = append(payloads, r)
+		}
+	}
+	if len(payloads) != 1 {
+		t.Fatalf("Expected only the request of the span with payloads got %v", payloads)
+	}
+	if payloads[0] != `{"password":"[REDACTED]","user":"john"}` {
+		t.Fatalf("Expected the redacted request got %s", payloads[0])
+	}
+}